@@ -0,0 +1,136 @@
+package vmwareify
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	// RemoveAudioDevices removes audio devices (see
+	// BasicConvertOptions.AudioDevices).
+	RemoveAudioDevices = "remove"
+
+	// HdAudioDevices converts audio devices to VMWare HD Audio sound
+	// cards.
+	HdAudioDevices = "hdaudio"
+
+	// Es1371AudioDevices converts audio devices to VMWare Ensoniq
+	// AudioPCI (ES1371) sound cards, which older guests support.
+	Es1371AudioDevices = "es1371"
+
+	// vmwHdAudioSubType and vmwEs1371SubType are the ResourceSubTypes
+	// of the VMWare HD Audio and Ensoniq AudioPCI (ES1371) sound cards.
+	vmwHdAudioSubType = "vmware.soundcard.hdaudio"
+	vmwEs1371SubType  = "vmware.soundcard.ensoniq1371"
+)
+
+var (
+	audioDeviceMatch = ovf.ItemMatch{
+		NamePrefix:   "sound",
+		Description:  "Sound Card",
+		ResourceType: ovf.SoundCardResourceType,
+	}
+)
+
+func isAudioDevice(item ovf.Item) bool {
+	return audioDeviceMatch.Matches(item)
+}
+
+// describeAudioDevice returns a description of an audio device for a
+// warning. The device is named by its ElementName, or its Caption or
+// InstanceID if it does not have one, as not every tool names devices.
+func describeAudioDevice(item ovf.Item) string {
+	var description string
+	switch {
+	case len(item.ElementName) > 0:
+		description = "'" + item.ElementName + "'"
+	case len(item.Caption) > 0:
+		description = "'" + item.Caption + "'"
+	default:
+		description = "Item with InstanceID '" + item.InstanceID + "'"
+	}
+
+	if len(item.ResourceSubType) > 0 {
+		description = description + " (" + item.ResourceSubType + ")"
+	}
+
+	return description
+}
+
+// RemoveAudioDevicesFunc returns an ovf.EditObjectFunc that will remove
+// audio devices.
+func RemoveAudioDevicesFunc() ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(ovf.Item)
+		if !ok || !isAudioDevice(o) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		return ovf.EditObjectResult{Action: ovf.Delete}
+	}
+}
+
+// ConvertAudioDevicesFunc returns an ovf.EditObjectFunc that will convert
+// audio devices to VMWare sound cards with the specified ResourceSubType
+// (e.g., 'vmware.soundcard.hdaudio').
+func ConvertAudioDevicesFunc(subType string) ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(ovf.Item)
+		if !ok || !isAudioDevice(o) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		o.ResourceType = ovf.SoundCardResourceType
+		o.ResourceSubType = subType
+		o.AutomaticAllocation = false
+		o.Parent = ""
+		o.AddressOnParent = ""
+
+		return ovf.EditObjectResult{
+			Action: ovf.Replace,
+			Object: &o,
+		}
+	}
+}
+
+// audioEdits returns the explained ovf.EditObjectFunc for the audio
+// devices chosen by the BasicConvertOptions.
+func audioEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, error) {
+	var subType string
+
+	switch strings.ToLower(options.AudioDevices) {
+	case "":
+		var devices []string
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if isAudioDevice(item) {
+				devices = append(devices, describeAudioDevice(item))
+			}
+		}
+
+		if len(devices) > 0 {
+			options.warn("the virtual machine has audio devices (" + strings.Join(devices, ", ") +
+				"), which ESXi rejects - remove them, or convert them to VMWare sound cards")
+		}
+
+		return nil, nil
+	case RemoveAudioDevices:
+		return []explainedFunc{{
+			f:      RemoveAudioDevicesFunc(),
+			reason: "audio devices are removed, as ESXi does not support them",
+		}}, nil
+	case HdAudioDevices:
+		subType = vmwHdAudioSubType
+	case Es1371AudioDevices:
+		subType = vmwEs1371SubType
+	default:
+		return nil, errors.New("unsupported audio option '" + options.AudioDevices + "' - must be '" +
+			RemoveAudioDevices + "', '" + HdAudioDevices + "', or '" + Es1371AudioDevices + "'")
+	}
+
+	return []explainedFunc{{
+		f:      ConvertAudioDevicesFunc(subType),
+		reason: "audio devices are converted to VMWare sound cards ('" + subType + "')",
+	}}, nil
+}
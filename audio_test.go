@@ -0,0 +1,121 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ensoniqSoundCard is the sound card that VirtualBox exports by default.
+const ensoniqSoundCard = `      <Item>
+        <rasd:AddressOnParent>3</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
+        <rasd:Caption>sound</rasd:Caption>
+        <rasd:Description>Sound Card</rasd:Description>
+        <rasd:ElementName>sound</rasd:ElementName>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
+        <rasd:ResourceType>35</rasd:ResourceType>
+      </Item>
+`
+
+func TestBasicConvertAudioDevices(t *testing.T) {
+	input := withHardwareItem(t, basicOvfFileContents, ensoniqSoundCard)
+
+	var warnings []string
+	unchanged, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	exp := "the virtual machine has audio devices ('sound' (ensoniq1371)), which ESXi rejects - " +
+		"remove them, or convert them to VMWare sound cards"
+	if len(warnings) != 1 || warnings[0] != exp {
+		t.Fatalf("Expected warning:\n'%s'\ngot %v", exp, warnings)
+	}
+
+	if !strings.Contains(unchanged.String(), "<rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>") {
+		t.Fatal("Audio device was modified without an audio option:\n'" + unchanged.String() + "'")
+	}
+
+	removed, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{AudioDevices: RemoveAudioDevices})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(removed.String(), "Sound Card") {
+		t.Fatal("Audio device was not removed:\n'" + removed.String() + "'")
+	}
+
+	for audio, subType := range map[string]string{
+		"hdaudio": "vmware.soundcard.hdaudio",
+		"es1371":  "vmware.soundcard.ensoniq1371",
+	} {
+		converted, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{AudioDevices: audio})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		parsed, err := ovf.ToOvf(strings.NewReader(converted.String()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var found bool
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if item.InstanceID == "9" {
+				found = true
+
+				if item.ResourceSubType != subType || item.ResourceType != ovf.SoundCardResourceType ||
+					len(item.AddressOnParent) > 0 {
+					t.Fatalf("Audio device was not converted to '%s' - %+v", subType, item)
+				}
+			}
+		}
+
+		if !found {
+			t.Fatal("Audio device was not kept:\n'" + converted.String() + "'")
+		}
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(input), BasicConvertOptions{AudioDevices: "junk"})
+	if err == nil || !strings.Contains(err.Error(), "'junk'") {
+		t.Fatalf("Expected an error for an unsupported audio option, got: %v", err)
+	}
+}
+
+func TestDescribeAudioDevice(t *testing.T) {
+	tests := []struct {
+		item ovf.Item
+		exp  string
+	}{
+		{
+			item: ovf.Item{ElementName: "sound", Caption: "Sound", InstanceID: "9", ResourceSubType: "ensoniq1371"},
+			exp:  "'sound' (ensoniq1371)",
+		},
+		{
+			item: ovf.Item{Caption: "Sound", InstanceID: "9", ResourceSubType: "ensoniq1371"},
+			exp:  "'Sound' (ensoniq1371)",
+		},
+		{
+			item: ovf.Item{InstanceID: "9", ResourceSubType: "hda"},
+			exp:  "Item with InstanceID '9' (hda)",
+		},
+		{
+			item: ovf.Item{InstanceID: "9"},
+			exp:  "Item with InstanceID '9'",
+		},
+	}
+
+	for _, test := range tests {
+		description := describeAudioDevice(test.item)
+		if description != test.exp {
+			t.Fatalf("expected '%s' - got '%s'", test.exp, description)
+		}
+	}
+}
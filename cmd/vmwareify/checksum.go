@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+const (
+	checksumArg = "checksum"
+)
+
+// checksumHashProvider creates the hashes used to calculate checksums, in
+// the same manner as the digests of an .ova's manifest.
+var checksumHashProvider = ova.StandardHashProvider
+
+// checkChecksumAlgorithm returns a non-nil error if the digest algorithm
+// chosen by the checksum flag is not supported.
+func checkChecksumAlgorithm(algorithm string) error {
+	_, err := checksumHashProvider.NewHash(algorithm)
+	if err != nil {
+		return errors.New("Unsupported -" + checksumArg + " algorithm '" + algorithm + "' - must be '" +
+			strings.ToLower(ova.Sha1Algorithm) + "', '" + strings.ToLower(ova.Sha256Algorithm) + "', or '" +
+			strings.ToLower(ova.Sha512Algorithm) + "'")
+	}
+
+	return nil
+}
+
+// saveChecksum writes the digest of a converted file next to it if a
+// checksum algorithm was chosen, and logs where it was saved.
+func saveChecksum(filePath string, algorithm string) error {
+	if len(algorithm) == 0 {
+		return nil
+	}
+
+	checksumFilePath, err := writeChecksumFile(filePath, algorithm)
+	if err != nil {
+		return fmt.Errorf("Failed to save checksum for '%s' - %w", filePath, err)
+	}
+
+	logInfo("Saved checksum to '" + checksumFilePath + "'")
+
+	return nil
+}
+
+// writeChecksumFile writes the digest of a converted file next to it
+// (e.g., '/some-vmware.ova.sha256'), in the format used by tools such as
+// sha256sum. The path of the checksum file is returned.
+func writeChecksumFile(filePath string, algorithm string) (string, error) {
+	entries, err := ova.DigestFiles([]string{filePath}, ova.DigestConfig{
+		Algorithm:    strings.ToUpper(algorithm),
+		Workers:      1,
+		HashProvider: checksumHashProvider,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	checksumFilePath := filePath + "." + strings.ToLower(algorithm)
+
+	err = os.WriteFile(checksumFilePath, []byte(checksumLine(entries[0].Digest, filePath)), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return checksumFilePath, nil
+}
+
+// checksumLine returns the line describing a file's digest in a checksum
+// file, which refers to the file by its base name.
+func checksumLine(digest string, filePath string) string {
+	return digest + "  " + filepath.Base(filePath) + "\n"
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+func TestChecksumLine(t *testing.T) {
+	line := checksumLine("abc123", filepath.Join("some", "dir", "test-vmware.ova"))
+	if line != "abc123  test-vmware.ova\n" {
+		t.Fatalf("got unexpected checksum line '%s'", line)
+	}
+}
+
+func TestWriteChecksumFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test-vmware.ova")
+
+	err := os.WriteFile(filePath, []byte("converted"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	checksumFilePath, err := writeChecksumFile(filePath, "sha256")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if checksumFilePath != filePath+".sha256" {
+		t.Fatalf("got unexpected checksum file path '%s'", checksumFilePath)
+	}
+
+	raw, err := os.ReadFile(checksumFilePath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	digest := sha256.Sum256([]byte("converted"))
+	exp := hex.EncodeToString(digest[:]) + "  test-vmware.ova\n"
+	if string(raw) != exp {
+		t.Fatalf("expected checksum file:\n'%s'\ngot:\n'%s'", exp, raw)
+	}
+}
+
+func TestChecksumHashProvider(t *testing.T) {
+	var algorithms []string
+	original := checksumHashProvider
+	checksumHashProvider = ova.HashProviderFunc(func(algorithm string) (hash.Hash, error) {
+		algorithms = append(algorithms, algorithm)
+		return ova.NewHash(algorithm)
+	})
+	defer func() {
+		checksumHashProvider = original
+	}()
+
+	err := checkChecksumAlgorithm("sha512")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = checkChecksumAlgorithm("md5")
+	if err == nil {
+		t.Fatal("expected an unsupported algorithm to fail")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "test-vmware.ova")
+
+	err = os.WriteFile(filePath, []byte("converted"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = writeChecksumFile(filePath, "sha1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(algorithms) < 3 {
+		t.Fatalf("expected the provider to create every hash - got %v", algorithms)
+	}
+}
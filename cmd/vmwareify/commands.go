@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+const (
+	inputFilePathArg   = "f"
+	outputFilePathArg  = "o"
+	guestOSArg         = "guest-os"
+	autoArg            = "auto"
+	nicArg             = "nic"
+	networkArg         = "network"
+	addNicArg          = "add-nic"
+	removeNetworkArg   = "remove-network"
+	scsiArg            = "scsi"
+	firmwareArg        = "firmware"
+	cpuHotAddArg       = "cpu-hot-add"
+	memoryHotAddArg    = "memory-hot-add"
+	mapDisplayArg      = "map-display"
+	bootOrderArg       = "boot-order"
+	vtpmArg            = "vtpm"
+	hardwareVersionArg = "hardware-version"
+	stripSnapshotsArg  = "strip-snapshots"
+	ideToSataArg       = "ide-to-sata"
+	keepIdeArg         = "keep-ide"
+	floppyArg          = "floppy"
+	audioArg           = "audio"
+	usbTabletArg       = "usb-tablet"
+	normalizeModesArg  = "normalize-modes"
+	stripOwnersArg     = "strip-owners"
+	reproducibleArg    = "reproducible"
+	provenanceArg      = "provenance"
+	scrubArg           = "scrub"
+	annotateFoldersArg = "annotate-shared-folders"
+	renameDisksArg     = "rename-disks"
+	companionFilesArg  = "companion-files"
+	normalizeHrefsArg  = "normalize-hrefs"
+	recomputeSizesArg  = "recompute-sizes"
+	diskCapacityArg    = "disk-capacity"
+	cpuAllocArg        = "cpu-allocation"
+	memoryAllocArg     = "memory-allocation"
+	ipSchemesArg       = "ip-schemes"
+	ipProtocolsArg     = "ip-protocols"
+	sourceDialectArg   = "source-dialect"
+	sortItemsArg       = "sort-items"
+	validateArg        = "validate"
+	keepBomArg         = "keep-bom"
+	localeArg          = "locale"
+	pruneReferencesArg = "prune-references"
+	maxOvaBufferArg    = "max-ova-buffer"
+	helpArg            = "h"
+	versionArg         = "version"
+
+	// stdioPath is the file path that refers to stdin or stdout.
+	stdioPath    = "-"
+	ovfExtension = ".ovf"
+	ovaExtension = ".ova"
+
+	// convertedSuffix is appended to the name of a converted file
+	// when an output file path is not specified.
+	convertedSuffix = "-vmware"
+)
+
+// command describes a single application subcommand. Usage text and shell
+// completion scripts are generated from these definitions.
+type command struct {
+	// name is the name used to invoke the command.
+	name string
+
+	// args describes the command's non-flag arguments.
+	args string
+
+	// summary is a one line description of the command.
+	summary string
+
+	// examples are example invocations of the command.
+	examples []string
+
+	// setup registers the command's flags and returns the function
+	// that runs the command. The function receives the remaining
+	// non-flag arguments.
+	setup func(flagSet *flag.FlagSet) func(args []string) error
+}
+
+// commands returns all of the application's subcommands.
+func commands() []command {
+	return []command{
+		convertCommand(),
+		explainCommand(),
+		validateCommand(),
+		enforceCommand(),
+		manifestCommand(),
+		packCommand(),
+		patchCommand(),
+		serveCommand(),
+		configCommand(),
+		helpCommand(),
+		completionCommand(),
+	}
+}
+
+func convertCommand() command {
+	return command{
+		name:    "convert",
+		args:    "[options] [additional .ovf or .ova files]",
+		summary: "Convert a .ovf or .ova file to a VMWare friendly file (the default command)",
+		examples: []string{
+			"vmwareify convert -f /some.ovf",
+			"vmwareify -f /some.ovf -o /my-awesome-vmware.ovf",
+			"vmwareify convert /first.ovf /second.ovf",
+			"vmwareify convert -guest-os windows -f /some.ovf",
+			"vmwareify convert -auto -nic VmxNet3 -f /some.ovf",
+			"vmwareify convert -f /some.ova",
+			"cat /some.ova | vmwareify convert -f - > /some-vmware.ova",
+			"vmwareify convert -auto -f /some.ovf -" + savePatchArg + " /changes.json",
+			"vmwareify convert -auto -" + watchArg + " /exports -o /converted",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf or .ova file to convert ('"+stdioPath+"' for stdin)")
+			outputFilePath := flagSet.String(outputFilePathArg, "", "The output file path for the converted file ('"+stdioPath+"' for stdout)")
+			options := convertOptionFlags(flagSet)
+			savePatch := flagSet.String(savePatchArg, "", "Save the changes made to the .ovf as a patch file, "+
+				"which can be applied to other .ovf files using the 'patch' command")
+			watchDir := flagSet.String(watchArg, "", "Watch a directory, and convert the .ovf and .ova files "+
+				"that appear in it until interrupted ('-"+outputFilePathArg+"' is the output directory)")
+			watchInterval := flagSet.Duration(watchIntervalArg, defaultWatchInterval, "The amount of time between "+
+				"checks of the watched directory")
+			checksum := flagSet.String(checksumArg, "", "Write the digest of each converted file next to it "+
+				"using the specified algorithm (e.g., 'sha256' writes '/some-vmware.ova.sha256'), or log it "+
+				"when writing to stdout")
+
+			return func(args []string) error {
+				if len(*checksum) > 0 {
+					err := checkChecksumAlgorithm(*checksum)
+					if err != nil {
+						return err
+					}
+				}
+
+				if len(*watchDir) > 0 {
+					if len(*inputFilePath) > 0 || len(args) > 0 {
+						return errors.New("Files to convert cannot be specified when watching a directory")
+					}
+
+					if len(*savePatch) > 0 {
+						return errors.New("A patch cannot be saved when watching a directory")
+					}
+
+					w := &watcher{
+						dir:       *watchDir,
+						outputDir: *outputFilePath,
+						interval:  *watchInterval,
+						convert: func(inputFilePath string, outputFilePath string) error {
+							convertOptions := options()
+							logConvertEvents(&convertOptions, inputFilePath)
+
+							err := convertFile(inputFilePath, outputFilePath, convertOptions, *checksum)
+							if err != nil {
+								return err
+							}
+
+							return saveChecksum(outputFilePath, *checksum)
+						},
+					}
+
+					ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+					defer stop()
+
+					return w.run(ctx)
+				}
+
+				inputFilePaths := args
+				if len(*inputFilePath) > 0 {
+					inputFilePaths = append([]string{*inputFilePath}, args...)
+				}
+
+				if len(inputFilePaths) == 0 {
+					return errors.New("Please specify a .ovf or .ova file to convert")
+				}
+
+				if len(*outputFilePath) > 0 && len(inputFilePaths) > 1 {
+					return errors.New("An output file path cannot be specified when converting multiple files")
+				}
+
+				if len(*savePatch) > 0 {
+					err := canSavePatch(inputFilePaths, *outputFilePath)
+					if err != nil {
+						return err
+					}
+				}
+
+				var errs []error
+
+				for _, inputFilePath := range inputFilePaths {
+					outputFilePath := *outputFilePath
+					if len(outputFilePath) == 0 {
+						outputFilePath = defaultOutputFilePath(inputFilePath)
+					}
+
+					convertOptions := options()
+					logConvertEvents(&convertOptions, inputFilePath)
+
+					logVerbose("Converting '" + inputFilePath + "' to '" + outputFilePath + "'")
+
+					err := convertFile(inputFilePath, outputFilePath, convertOptions, *checksum)
+					if err != nil {
+						err = fmt.Errorf("Failed to convert '%s' - %w", inputFilePath, err)
+						if len(inputFilePaths) > 1 {
+							logError(err.Error())
+						}
+					} else if outputFilePath != stdioPath {
+						logInfo("Saved converted file to '" + outputFilePath + "'")
+
+						err = saveChecksum(outputFilePath, *checksum)
+						if err != nil && len(inputFilePaths) > 1 {
+							logError(err.Error())
+						}
+					}
+
+					if err == nil && len(*savePatch) > 0 {
+						err = savePatchFile(inputFilePath, outputFilePath, *savePatch)
+						if err != nil {
+							err = fmt.Errorf("Failed to save patch for '%s' - %w", inputFilePath, err)
+						} else {
+							logInfo("Saved patch to '" + *savePatch + "'")
+						}
+					}
+
+					errs = append(errs, err)
+				}
+
+				if len(inputFilePaths) == 1 {
+					return errs[0]
+				}
+
+				return batchResult(errs)
+			}
+		},
+	}
+}
+
+// convertOptionFlags registers the flags that customize a conversion, and
+// returns a function that returns the resulting BasicConvertOptions once
+// the flags are parsed.
+func convertOptionFlags(flagSet *flag.FlagSet) func() vmwareify.BasicConvertOptions {
+	guestOS := flagSet.String(guestOSArg, "", "The guest OS profile to apply (e.g., 'windows', 'linux', "+
+		"'linux-legacy', 'bsd'), or a VirtualBox OS type (e.g., 'Windows10_64')")
+	auto := flagSet.Bool(autoArg, false, "Choose the network adapter, SCSI controller, and firmware based on "+
+		"the guest OS declared in the .ovf")
+	nic := flagSet.String(nicArg, "", "Override the network adapter model (e.g., 'E1000', 'E1000e', 'VmxNet3')")
+	networks := networkMappingsFlag{}
+	flagSet.Var(networks, networkArg, "Connect the adapters of a network to another network (e.g., "+
+		"'NAT=VM Network') - can be specified more than once")
+	var removeNetworks listFlag
+	flagSet.Var(&removeNetworks, removeNetworkArg, "Remove a network (e.g., 'HostOnly') and the network "+
+		"adapters connected to it - can be specified more than once")
+	var addNics listFlag
+	flagSet.Var(&addNics, addNicArg, "Add a network adapter connected to the specified network (e.g., "+
+		"'Management'), which is added if it does not exist - can be specified more than once")
+	scsi := flagSet.String(scsiArg, "", "Override the SCSI controller model (e.g., 'lsilogic', 'lsilogicsas', 'VirtualSCSI')")
+	firmware := flagSet.String(firmwareArg, "", "Override the firmware ('bios' or 'efi')")
+	cpuHotAdd := flagSet.Bool(cpuHotAddArg, false, "Allow CPUs to be added while the virtual machine is running")
+	memoryHotAdd := flagSet.Bool(memoryHotAddArg, false, "Allow memory to be added while the virtual machine is running")
+	mapDisplay := flagSet.Bool(mapDisplayArg, false, "Map the VirtualBox display settings (video memory, "+
+		"monitor count, and 3D acceleration) to VMWare")
+	bootOrder := flagSet.Bool(bootOrderArg, false, "Preserve the VirtualBox boot order")
+	hardwareVersion := flagSet.String(hardwareVersionArg, "", "The VMWare hardware version (e.g., 'vmx-13') - "+
+		"vmx-10 is used unless newer hardware is required")
+	vtpm := flagSet.Bool(vtpmArg, false, "Add a virtual TPM (requires EFI firmware and a vCenter key provider)")
+	ideToSata := flagSet.Bool(ideToSataArg, false, "Move the devices attached to IDE controllers to a SATA "+
+		"controller before the IDE controllers are removed")
+	keepIde := flagSet.Bool(keepIdeArg, false, "Keep the IDE controllers (for legacy guest OSes that require IDE), "+
+		"and set their model to one that ESXi supports")
+	floppy := flagSet.String(floppyArg, "", "Remove floppy drives ('"+vmwareify.RemoveFloppyDrives+
+		"'), or convert them to VMWare floppy drives ('"+vmwareify.KeepFloppyDrives+"')")
+	audio := flagSet.String(audioArg, "", "Remove audio devices, which ESXi rejects ('"+
+		vmwareify.RemoveAudioDevices+"'), or convert them to VMWare HD Audio ('"+vmwareify.HdAudioDevices+
+		"') or ES1371 ('"+vmwareify.Es1371AudioDevices+"') sound cards")
+	usbTablet := flagSet.Bool(usbTabletArg, false, "Add a USB tablet so that the pointer follows the mouse "+
+		"in the ESXi console (a USB controller is added or converted if needed)")
+	stripSnapshots := flagSet.Bool(stripSnapshotsArg, false, "Remove VirtualBox snapshot metadata "+
+		"(the disks must have been flattened when the virtual machine was exported)")
+	provenance := flagSet.Bool(provenanceArg, false, "Record the application version, the digest of the "+
+		"original .ovf, and the options used in a ProductSection of the converted .ovf")
+	scrub := flagSet.Bool(scrubArg, false, "Remove information that identifies the system the .ovf was "+
+		"exported from (the vbox:Machine, disk UUIDs, MAC addresses, and hostnames in ProductSection properties)")
+	annotateFolders := flagSet.Bool(annotateFoldersArg, false, "Describe the VirtualBox shared folders in the "+
+		"annotation of the converted .ovf, as a reminder to configure VMWare Shared Folders after importing it")
+	normalizeHrefs := flagSet.Bool(normalizeHrefsArg, false, "Convert absolute and backslash separated "+
+		"file references to relative, forward slash separated paths")
+	recomputeSizes := flagSet.Bool(recomputeSizesArg, false, "Recompute the file sizes and disk populated "+
+		"sizes in the .ovf from the referenced files")
+	sortItems := flagSet.Bool(sortItemsArg, false, "Order the hardware Items so that controllers precede "+
+		"their devices, and by InstanceID otherwise")
+	validate := flagSet.Bool(validateArg, false, "Check that the Parents, disk references, and file "+
+		"references in the converted .ovf resolve, and fail if they do not")
+	diskCapacity := flagSet.String(diskCapacityArg, "", "Grow the declared capacity of every disk (e.g., "+
+		"'100GiB'), or of specific disks (e.g., 'vmdisk1=100GiB,vmdisk2=1TiB')")
+	cpuAllocation := flagSet.String(cpuAllocArg, "", "Set the CPU reservation and limit in MHz, and the "+
+		"shares (e.g., 'reservation=2000,limit=4000,shares=2000')")
+	memoryAllocation := flagSet.String(memoryAllocArg, "", "Set the memory reservation and limit, and the "+
+		"shares (e.g., 'reservation=4GiB,limit=8GiB')")
+	ipSchemes := flagSet.String(ipSchemesArg, "", "Declare the IP assignment schemes that the virtual machine "+
+		"supports ('dhcp', 'ovfenv', or 'dhcp,ovfenv'), so that vCenter offers the matching IP allocation policies")
+	ipProtocols := flagSet.String(ipProtocolsArg, "", "The IP protocols declared with -"+ipSchemesArg+
+		" ('IPv4', 'IPv6', or 'IPv4,IPv6') - IPv4 is used if not specified")
+	sourceDialect := flagSet.String(sourceDialectArg, "", "The tool that exported the .ovf ('"+
+		vmwareify.VirtualBoxDialect+"', '"+vmwareify.ProxmoxDialect+"', '"+vmwareify.AhvDialect+"', or '"+
+		vmwareify.GenericDialect+"') - it is detected if not specified")
+	pruneReferences := flagSet.Bool(pruneReferencesArg, false, "Remove the files from the .ovf's References "+
+		"that are no longer used once it is converted")
+	locale := flagSet.String(localeArg, "", "Replace the localizable text of the .ovf with the messages "+
+		"of the specified locale (e.g., 'de-DE'), and remove its localized string bundles")
+	keepBom := flagSet.Bool(keepBomArg, false, "Start the converted .ovf with a UTF-8 byte order mark "+
+		"if the original .ovf started with one")
+	renameDisks := flagSet.String(renameDisksArg, "", "Append a suffix to the name of each disk file "+
+		"(e.g., '"+convertedSuffix+"'), and place the renamed disk files next to the converted .ovf")
+	companionFiles := flagSet.String(companionFilesArg, "", "Copy ('"+vmwareify.CopyCompanionFiles+
+		"') or symlink ('"+vmwareify.SymlinkCompanionFiles+"') the files referenced by the .ovf "+
+		"(e.g., disks) next to the converted .ovf")
+	normalizeModes := flagSet.Bool(normalizeModesArg, false, "Set the mode of every file in a converted .ova to 0644")
+	stripOwners := flagSet.Bool(stripOwnersArg, false, "Remove the user and group owners of every file in a converted .ova")
+	reproducible := flagSet.Bool(reproducibleArg, false, "Produce an identical .ova every time the same input "+
+		"is converted (implies -"+normalizeModesArg+" and -"+stripOwnersArg+", and fixes the file timestamps)")
+	maxOvaBuffer := flagSet.Int64(maxOvaBufferArg, ova.DefaultMaxBufferBytes, "The maximum size in bytes "+
+		"of the descriptor and manifest of an .ova, which are held in memory while the other files are streamed")
+
+	return func() vmwareify.BasicConvertOptions {
+		return vmwareify.BasicConvertOptions{
+			GuestOSProfile:        *guestOS,
+			AutoDetectGuestOS:     *auto,
+			NetworkAdapterSubType: *nic,
+			NetworkMappings:       networks,
+			RemoveNetworks:        removeNetworks,
+			AddNetworkAdapters:    addNics,
+			ScsiControllerSubType: *scsi,
+			Firmware:              *firmware,
+			CpuHotAdd:             *cpuHotAdd,
+			MemoryHotAdd:          *memoryHotAdd,
+			MapDisplay:            *mapDisplay,
+			PreserveBootOrder:     *bootOrder,
+			VirtualTPM:            *vtpm,
+			HardwareVersion:       *hardwareVersion,
+			MigrateIdeDevices:     *ideToSata,
+			KeepIdeControllers:    *keepIde,
+			FloppyDrives:          *floppy,
+			AudioDevices:          *audio,
+			UsbTablet:             *usbTablet,
+			StripSnapshotMetadata: *stripSnapshots,
+			EmbedProvenance:       *provenance,
+			Scrub:                 *scrub,
+			AnnotateSharedFolders: *annotateFolders,
+			NormalizeHrefs:        *normalizeHrefs,
+			RecomputeSizes:        *recomputeSizes,
+			SortItems:             *sortItems,
+			Validate:              *validate,
+			DiskCapacity:          *diskCapacity,
+			CpuAllocation:         *cpuAllocation,
+			MemoryAllocation:      *memoryAllocation,
+			IpAssignmentSchemes:   *ipSchemes,
+			IpProtocols:           *ipProtocols,
+			SourceDialect:         *sourceDialect,
+			Locale:                *locale,
+			PruneReferences:       *pruneReferences,
+			KeepByteOrderMark:     *keepBom,
+			DiskFileSuffix:        *renameDisks,
+			CompanionFiles:        *companionFiles,
+			NormalizeOvaModes:     *normalizeModes,
+			StripOvaOwnership:     *stripOwners,
+			Reproducible:          *reproducible,
+			MaxOvaBufferBytes:     *maxOvaBuffer,
+		}
+	}
+}
+
+// networkMappingsFlag is a repeatable flag.Value of network mappings in
+// the format '<old-name>=<new-name>'.
+type networkMappingsFlag map[string]string
+
+func (o networkMappingsFlag) String() string {
+	var mappings []string
+	for oldName, newName := range o {
+		mappings = append(mappings, oldName+"="+newName)
+	}
+
+	sort.Strings(mappings)
+
+	return strings.Join(mappings, ",")
+}
+
+func (o networkMappingsFlag) Set(s string) error {
+	oldName, newName, err := vmwareify.ParseNetworkMapping(s)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := o[oldName]; ok {
+		return errors.New("network '" + oldName + "' is mapped more than once")
+	}
+
+	o[oldName] = newName
+
+	return nil
+}
+
+// listFlag is a repeatable flag.Value that collects each of its values.
+type listFlag []string
+
+func (o *listFlag) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *listFlag) Set(s string) error {
+	*o = append(*o, s)
+
+	return nil
+}
+
+// convertFile converts a single .ovf or .ova. The path '-' refers to stdin
+// when used as the input, and stdout when used as the output. If the
+// checksum algorithm is non-empty, the digest of the output is logged
+// when writing to stdout (see saveChecksum for files).
+func convertFile(inputFilePath string, outputFilePath string, options vmwareify.BasicConvertOptions, checksum string) error {
+	if inputFilePath != stdioPath && outputFilePath != stdioPath &&
+		!strings.EqualFold(path.Ext(inputFilePath), ovaExtension) {
+		return vmwareify.BasicConvertWithOptions(inputFilePath, outputFilePath, options)
+	}
+
+	if len(options.CompanionFiles) > 0 {
+		return errors.New("companion files can only be placed when converting a .ovf file to a .ovf file")
+	}
+
+	if inputFilePath == outputFilePath && inputFilePath != stdioPath {
+		return errors.New("output file path cannot be the same as the input file path")
+	}
+
+	in := os.Stdin
+	if inputFilePath != stdioPath {
+		f, err := os.Open(inputFilePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if outputFilePath == stdioPath {
+		if len(checksum) == 0 {
+			_, err := vmwareify.Convert(in, os.Stdout, options)
+			return err
+		}
+
+		h, err := checksumHashProvider.NewHash(checksum)
+		if err != nil {
+			return err
+		}
+
+		_, err = vmwareify.Convert(in, io.MultiWriter(os.Stdout, h), options)
+		if err != nil {
+			return err
+		}
+
+		logInfo("The " + strings.ToLower(checksum) + " digest of the converted file is " +
+			hex.EncodeToString(h.Sum(nil)))
+
+		return nil
+	}
+
+	out, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = vmwareify.Convert(in, out, options)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputFilePath)
+		return err
+	}
+
+	return nil
+}
+
+// defaultOutputFilePath returns the output file path that is used when
+// one is not specified.
+func defaultOutputFilePath(inputFilePath string) string {
+	if inputFilePath == stdioPath {
+		return stdioPath
+	}
+
+	inputFilename := path.Base(inputFilePath)
+
+	return path.Dir(inputFilePath) + "/" + getFilenameWithoutExtension(inputFilename) + convertedSuffix + getFileExtension(inputFilename)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func completionCommand() command {
+	return command{
+		name:    "completion",
+		args:    "<bash|zsh|fish>",
+		summary: "Generate a shell completion script",
+		examples: []string{
+			"source <(vmwareify completion bash)",
+			"vmwareify completion zsh > \"${fpath[1]}/_vmwareify\"",
+			"vmwareify completion fish > ~/.config/fish/completions/vmwareify.fish",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if len(args) != 1 {
+					return errors.New("Please specify a shell (bash, zsh, or fish)")
+				}
+
+				return writeCompletion(os.Stdout, args[0])
+			}
+		},
+	}
+}
+
+// writeCompletion writes a completion script for the specified shell.
+// The script is generated from the command definitions.
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w)
+	case "zsh":
+		fmt.Fprintf(w, "#compdef %s\n\nautoload -U +X bashcompinit && bashcompinit\n\n", appName)
+		writeBashCompletion(w)
+	case "fish":
+		writeFishCompletion(w)
+	default:
+		return errors.New("Unsupported shell '" + shell + "' - must be bash, zsh, or fish")
+	}
+
+	return nil
+}
+
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands() {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintf(w, "_%s_completions() {\n", appName)
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `    local cmd="${COMP_WORDS[1]}"`)
+	fmt.Fprintln(w, `    if [ "${COMP_CWORD}" -eq 1 ] && [[ "${cur}" != -* ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"${cur}\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    if [[ "${cur}" != -* ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -f -- "${cur}"))`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    case "${cmd}" in`)
+	for _, c := range commands() {
+		fmt.Fprintf(w, "        %s)\n", c.name)
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"${cur}\")) ;;\n", strings.Join(commandFlagNames(c), " "))
+	}
+	fmt.Fprintln(w, `        *)`)
+	fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"${cur}\")) ;;\n", strings.Join(commandFlagNames(convertCommand()), " "))
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "\ncomplete -o filenames -F _%s_completions %s\n", appName, appName)
+}
+
+func writeFishCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands() {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintf(w, "complete -c %s -f -n '__fish_use_subcommand' -a '%s'\n", appName, strings.Join(names, " "))
+
+	for _, c := range commands() {
+		fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from %s' -d '%s'\n",
+			appName, c.name, strings.ReplaceAll(c.summary, "'", "\\'"))
+
+		commandFlagSet(c).VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from %s' -o %s -d '%s'\n",
+				appName, c.name, f.Name, strings.ReplaceAll(f.Usage, "'", "\\'"))
+		})
+	}
+}
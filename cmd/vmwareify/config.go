@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	configCommandName = "config"
+	configDirName     = "vmwareify"
+	configFileName    = "config.yaml"
+)
+
+// configFile contains the default flag values of each command, keyed by
+// command name and then flag name.
+//
+// The file is stored as a small subset of YAML, in which each command is
+// a top-level key that contains its flags as indented 'name: value' pairs:
+//
+//	convert:
+//	  hardware-version: vmx-14
+//	  nic: VmxNet3
+//
+// Comments are only supported on their own line.
+type configFile map[string]map[string]string
+
+func configCommand() command {
+	return command{
+		name:    configCommandName,
+		args:    "show | set <command>.<option> <value> | unset <command>.<option>",
+		summary: "Show or change the default options that are applied to each command",
+		examples: []string{
+			"vmwareify config show",
+			"vmwareify config set convert.hardware-version vmx-14",
+			"vmwareify config set convert.nic VmxNet3",
+			"vmwareify config unset convert.nic",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if len(args) == 0 {
+					return errors.New("Please specify 'show', 'set', or 'unset'")
+				}
+
+				configPath, err := configFilePath()
+				if err != nil {
+					return err
+				}
+
+				config, err := loadConfigFile(configPath)
+				if err != nil {
+					return err
+				}
+
+				switch args[0] {
+				case "show":
+					if len(args) != 1 {
+						return errors.New("'show' does not accept any arguments")
+					}
+
+					fmt.Println("# " + configPath)
+					os.Stdout.Write(config.bytes())
+
+					return nil
+				case "set":
+					if len(args) != 3 {
+						return errors.New("Please specify an option and a value (e.g., 'convert.nic VmxNet3')")
+					}
+
+					commandName, flagName, err := parseConfigKey(args[1])
+					if err != nil {
+						return err
+					}
+
+					err = config.set(commandName, flagName, args[2])
+					if err != nil {
+						return err
+					}
+				case "unset":
+					if len(args) != 2 {
+						return errors.New("Please specify an option (e.g., 'convert.nic')")
+					}
+
+					commandName, flagName, err := parseConfigKey(args[1])
+					if err != nil {
+						return err
+					}
+
+					config.unset(commandName, flagName)
+				default:
+					return errors.New("Unknown config action '" + args[0] + "'")
+				}
+
+				err = os.MkdirAll(filepath.Dir(configPath), 0755)
+				if err != nil {
+					return err
+				}
+
+				return os.WriteFile(configPath, config.bytes(), 0644)
+			}
+		},
+	}
+}
+
+// configFilePath returns the path of the configuration file, which is
+// stored in the user's configuration directory (e.g., '~/.config' or
+// '$XDG_CONFIG_HOME' on Linux).
+func configFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, configDirName, configFileName), nil
+}
+
+// applyConfigFile sets the flag values of a command that were not
+// specified on the command line using the configuration file, if it
+// exists. It must be called after the command's arguments are parsed.
+func applyConfigFile(c command, flagSet *flag.FlagSet) error {
+	configPath, err := configFilePath()
+	if err != nil {
+		// The configuration directory cannot be determined
+		// (e.g., $HOME is not set), so there is no file to load.
+		return nil
+	}
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	return config.apply(c.name, flagSet)
+}
+
+// loadConfigFile loads the configuration file. An empty configFile is
+// returned if the file does not exist.
+func loadConfigFile(configPath string) (configFile, error) {
+	f, err := os.Open(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return configFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := parseConfigFile(f)
+	if err != nil {
+		return nil, errors.New("Failed to parse '" + configPath + "' - " + err.Error())
+	}
+
+	return config, nil
+}
+
+func parseConfigFile(r io.Reader) (configFile, error) {
+	config := configFile{}
+	commandName := ""
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber = lineNumber + 1
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found || len(key) == 0 {
+			return nil, errors.New("line " + strconv.Itoa(lineNumber) + " is not a 'key: value' pair")
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if len(trimmed) == len(line) {
+			if len(value) > 0 {
+				return nil, errors.New("line " + strconv.Itoa(lineNumber) + " - command '" + key +
+					"' must contain indented options")
+			}
+
+			commandName = key
+			if config[commandName] == nil {
+				config[commandName] = make(map[string]string)
+			}
+			continue
+		}
+
+		if len(commandName) == 0 {
+			return nil, errors.New("line " + strconv.Itoa(lineNumber) + " - option '" + key +
+				"' is not inside of a command")
+		}
+
+		value, err := unquoteConfigValue(value)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(lineNumber) + " - " + err.Error())
+		}
+
+		config[commandName][key] = value
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func unquoteConfigValue(value string) (string, error) {
+	if len(value) < 2 {
+		return value, nil
+	}
+
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", errors.New("invalid quoted value " + value)
+		}
+		return unquoted, nil
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+
+	return value, nil
+}
+
+// parseConfigKey parses an option key in the format '<command>.<option>'.
+func parseConfigKey(key string) (string, string, error) {
+	commandName, flagName, found := strings.Cut(key, ".")
+	if !found || len(commandName) == 0 || len(flagName) == 0 {
+		return "", "", errors.New("Option '" + key + "' must be in the format '<command>.<option>'")
+	}
+
+	return commandName, flagName, nil
+}
+
+// set sets the default value of a command's flag after validating that
+// the flag exists and accepts the value.
+func (o configFile) set(commandName string, flagName string, value string) error {
+	c, isCommand := commandFor([]string{commandName})
+	if !isCommand {
+		return errors.New("Unknown command '" + commandName + "'")
+	}
+
+	flagSet := commandFlagSet(c)
+	flagSet.SetOutput(io.Discard)
+
+	if flagName == helpArg || flagName == versionArg || flagSet.Lookup(flagName) == nil {
+		return errors.New("Command '" + commandName + "' does not have a '" + flagName + "' option")
+	}
+
+	err := flagSet.Set(flagName, value)
+	if err != nil {
+		return errors.New("Invalid value for '" + commandName + "." + flagName + "' - " + err.Error())
+	}
+
+	if o[commandName] == nil {
+		o[commandName] = make(map[string]string)
+	}
+
+	o[commandName][flagName] = value
+
+	return nil
+}
+
+func (o configFile) unset(commandName string, flagName string) {
+	delete(o[commandName], flagName)
+
+	if len(o[commandName]) == 0 {
+		delete(o, commandName)
+	}
+}
+
+// apply sets the flag values of a command that were not set on the
+// command line. It must be called after the command's arguments are
+// parsed, so that the arguments take precedence over the configuration
+// file. A repeatable flag (e.g., '-network') that was specified on the
+// command line replaces the configuration file's value rather than
+// adding to it.
+func (o configFile) apply(commandName string, flagSet *flag.FlagSet) error {
+	setOnCommandLine := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	for flagName, value := range o[commandName] {
+		if flagName == helpArg || flagName == versionArg || flagSet.Lookup(flagName) == nil {
+			return errors.New("The configuration file contains unknown option '" + commandName + "." + flagName + "'")
+		}
+
+		if setOnCommandLine[flagName] {
+			continue
+		}
+
+		err := flagSet.Set(flagName, value)
+		if err != nil {
+			return errors.New("The configuration file contains an invalid value for '" +
+				commandName + "." + flagName + "' - " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (o configFile) bytes() []byte {
+	buff := bytes.NewBuffer(nil)
+
+	commandNames := make([]string, 0, len(o))
+	for commandName := range o {
+		commandNames = append(commandNames, commandName)
+	}
+	sort.Strings(commandNames)
+
+	for _, commandName := range commandNames {
+		if len(o[commandName]) == 0 {
+			continue
+		}
+
+		buff.WriteString(commandName + ":\n")
+
+		flagNames := make([]string, 0, len(o[commandName]))
+		for flagName := range o[commandName] {
+			flagNames = append(flagNames, flagName)
+		}
+		sort.Strings(flagNames)
+
+		for _, flagName := range flagNames {
+			buff.WriteString("  " + flagName + ": " + quoteConfigValue(o[commandName][flagName]) + "\n")
+		}
+	}
+
+	return buff.Bytes()
+}
+
+func quoteConfigValue(value string) string {
+	if len(value) == 0 || strings.TrimSpace(value) != value ||
+		strings.ContainsAny(value, "\"'#:") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	config, err := parseConfigFile(strings.NewReader(`# Defaults
+convert:
+  hardware-version: vmx-14
+  # The adapter model.
+  nic: "VmxNet3"
+  network: 'NAT=Bob''s Network'
+
+serve:
+  addr: 0.0.0.0:8080
+`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := configFile{
+		"convert": {
+			"hardware-version": "vmx-14",
+			"nic":              "VmxNet3",
+			"network":          "NAT=Bob's Network",
+		},
+		"serve": {
+			"addr": "0.0.0.0:8080",
+		},
+	}
+
+	if len(config) != len(expected) {
+		t.Fatalf("Got unexpected config: %v", config)
+	}
+
+	for commandName, flags := range expected {
+		if len(config[commandName]) != len(flags) {
+			t.Fatalf("Got unexpected '%s' options: %v", commandName, config[commandName])
+		}
+
+		for flagName, value := range flags {
+			if config[commandName][flagName] != value {
+				t.Fatalf("Got unexpected value for '%s.%s': '%s'", commandName, flagName, config[commandName][flagName])
+			}
+		}
+	}
+}
+
+func TestParseConfigFileInvalid(t *testing.T) {
+	for _, input := range []string{
+		"convert\n",
+		"  nic: VmxNet3\n",
+		"convert: VmxNet3\n",
+		"convert:\n  nic: \"VmxNet3\\q\"\n",
+	} {
+		_, err := parseConfigFile(strings.NewReader(input))
+		if err == nil {
+			t.Fatalf("Expected an error for:\n%s", input)
+		}
+	}
+}
+
+func TestQuoteConfigValue(t *testing.T) {
+	for _, value := range []string{"", "VmxNet3", " padded ", "NAT=VM Network", "a: b", "# comment",
+		`"quoted"`, "Bob's", "tab\there"} {
+		unquoted, err := unquoteConfigValue(quoteConfigValue(value))
+		if err != nil {
+			t.Fatalf("'%s' - %s", value, err)
+		}
+
+		if unquoted != value {
+			t.Fatalf("'%s' became '%s'", value, unquoted)
+		}
+	}
+
+	config, err := parseConfigFile(strings.NewReader(string(configFile{
+		"convert": {"network": "NAT=VM Network", "nic": " VmxNet3"},
+	}.bytes())))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if config["convert"]["network"] != "NAT=VM Network" || config["convert"]["nic"] != " VmxNet3" {
+		t.Fatalf("Got unexpected config: %v", config)
+	}
+}
+
+func TestConfigFileApplyCommandLineTakesPrecedence(t *testing.T) {
+	c, _ := commandFor([]string{"convert"})
+	flagSet := commandFlagSet(c)
+
+	err := flagSet.Parse([]string{"-" + networkArg, "NAT=Other", "-" + hardwareVersionArg, "vmx-13"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	config := configFile{
+		"convert": {
+			networkArg:         "NAT=VM Network",
+			hardwareVersionArg: "vmx-14",
+			nicArg:             "VmxNet3",
+		},
+	}
+
+	err = config.apply("convert", flagSet)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := map[string]string{
+		networkArg:         "NAT=Other",
+		hardwareVersionArg: "vmx-13",
+		nicArg:             "VmxNet3",
+	}
+
+	for flagName, value := range expected {
+		actual := flagSet.Lookup(flagName).Value.String()
+		if actual != value {
+			t.Fatalf("Expected '-%s' to be '%s', got '%s'", flagName, value, actual)
+		}
+	}
+
+	err = configFile{"convert": {"junk": "value"}}.apply("convert", commandFlagSet(c))
+	if err == nil {
+		t.Fatal("Expected an error for an unknown option")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	dryRunArg = "dry-run"
+)
+
+func enforceCommand() command {
+	return command{
+		name:    "enforce",
+		args:    "[options] [additional .ovf files]",
+		summary: "Check .ovf files against a policy, and fix the problems that can be fixed",
+		examples: []string{
+			"vmwareify enforce -" + policyArg + " corp.yaml appliances/*.ovf",
+			"vmwareify enforce -" + dryRunArg + " -" + maxMemoryArg + " 32GiB -f /some.ovf",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf file to enforce the policy on")
+			dryRun := flagSet.Bool(dryRunArg, false, "Report the problems that would be fixed without "+
+				"modifying the files")
+			chosenRules := ruleFlags(flagSet)
+
+			return func(args []string) error {
+				rules, err := chosenRules()
+				if err != nil {
+					return err
+				}
+
+				inputFilePaths := args
+				if len(*inputFilePath) > 0 {
+					inputFilePaths = append([]string{*inputFilePath}, args...)
+				}
+
+				if len(inputFilePaths) == 0 {
+					return errors.New("Please specify a .ovf file to enforce the policy on")
+				}
+
+				failed := 0
+				for _, inputFilePath := range inputFilePaths {
+					if inputFilePath == stdioPath {
+						return errors.New("Files are fixed in place, so stdin cannot be used")
+					}
+
+					result, err := enforceFile(inputFilePath, rules, *dryRun)
+					if err != nil {
+						return fmt.Errorf("Failed to enforce the policy on '%s' - %w", inputFilePath, err)
+					}
+
+					if len(result.Remaining) > 0 {
+						failed = failed + 1
+					}
+
+					err = printEnforceResult(os.Stdout, inputFilePath, result, *dryRun)
+					if err != nil {
+						return err
+					}
+				}
+
+				if failed > 0 {
+					return fmt.Errorf("%w - %d of %d files have problems that cannot be fixed",
+						errValidationFailed, failed, len(inputFilePaths))
+				}
+
+				logInfo("No problems remain")
+
+				return nil
+			}
+		},
+	}
+}
+
+// enforceFile checks a single .ovf using the specified rules, and
+// replaces it with the fixed .ovf if any problems were fixed, unless
+// dryRun is true.
+func enforceFile(inputFilePath string, rules *ovf.RuleSet, dryRun bool) (ovf.EnforceResult, error) {
+	raw, err := os.ReadFile(inputFilePath)
+	if err != nil {
+		return ovf.EnforceResult{}, err
+	}
+
+	info, err := os.Stat(inputFilePath)
+	if err != nil {
+		return ovf.EnforceResult{}, err
+	}
+
+	fixed, result, err := ovf.Enforce(bytes.NewReader(raw), ovf.Limits{}, rules)
+	if err != nil {
+		return ovf.EnforceResult{}, err
+	}
+
+	if dryRun || len(result.Fixed) == 0 {
+		return result, nil
+	}
+
+	err = os.WriteFile(inputFilePath, fixed.Bytes(), info.Mode().Perm())
+	if err != nil {
+		return ovf.EnforceResult{}, err
+	}
+
+	return result, nil
+}
+
+// printEnforceResult writes the problems fixed in, and remaining in, a
+// single file to w.
+func printEnforceResult(w io.Writer, inputFilePath string, result ovf.EnforceResult, dryRun bool) error {
+	action := "fixed"
+	if dryRun {
+		action = "would fix"
+	}
+
+	for _, finding := range result.Fixed {
+		_, err := fmt.Fprintln(w, inputFilePath+": "+action+": "+describeFinding(finding))
+		if err != nil {
+			return err
+		}
+	}
+
+	return printFindings(w, textFormat, inputFilePath, result.Remaining)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// Exit codes that allow scripts to branch on the class of failure.
+const (
+	exitSuccess            = 0
+	exitFailure            = 1
+	exitValidationFailure  = 2
+	exitUnsupportedInput   = 3
+	exitIoError            = 4
+	exitPartialBatchResult = 5
+)
+
+// partialSuccessError is returned when some, but not all, of the inputs
+// of a batch operation were processed successfully.
+type partialSuccessError struct {
+	failed int
+	total  int
+	errs   []error
+}
+
+func (o *partialSuccessError) Error() string {
+	return strconv.Itoa(o.failed) + " of " + strconv.Itoa(o.total) + " inputs failed"
+}
+
+func (o *partialSuccessError) Unwrap() []error {
+	return o.errs
+}
+
+// batchResult returns nil if none of the errors are non-nil, a
+// *partialSuccessError if only some of the errors are non-nil, or
+// the joined errors if all of them are non-nil.
+func batchResult(errs []error) error {
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case len(errs):
+		return errors.Join(failed...)
+	default:
+		return &partialSuccessError{
+			failed: len(failed),
+			total:  len(errs),
+			errs:   failed,
+		}
+	}
+}
+
+// exitCodeFor maps an error to the application's exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+
+	var partial *partialSuccessError
+	if errors.As(err, &partial) {
+		return exitPartialBatchResult
+	}
+
+	var formattingErr *xmlutil.FormattingError
+	if errors.As(err, &formattingErr) || errors.Is(err, errValidationFailed) {
+		return exitValidationFailure
+	}
+
+	var limitErr *ovf.LimitError
+	if errors.Is(err, vmwareify.ErrUnsupportedInput) || errors.Is(err, xmlutil.ErrDocumentTooLarge) ||
+		errors.As(err, &limitErr) || errors.Is(err, vmwareify.ErrSnapshots) ||
+		errors.Is(err, ova.ErrMultipleDescriptors) {
+		return exitUnsupportedInput
+	}
+
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	if errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr) {
+		return exitIoError
+	}
+
+	return exitFailure
+}
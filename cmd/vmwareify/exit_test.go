@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		exp  int
+	}{
+		{name: "success", err: nil, exp: exitSuccess},
+		{name: "failure", err: errors.New("something went wrong"), exp: exitFailure},
+		{name: "formatting error", err: &xmlutil.FormattingError{Line: 1, Column: 2, Err: errors.New("bad")}, exp: exitValidationFailure},
+		{name: "validation failed", err: fmt.Errorf("test.ovf: %w", errValidationFailed), exp: exitValidationFailure},
+		{name: "unsupported input", err: vmwareify.ErrUnsupportedInput, exp: exitUnsupportedInput},
+		{name: "document too large", err: fmt.Errorf("test.ovf: %w", xmlutil.ErrDocumentTooLarge), exp: exitUnsupportedInput},
+		{name: "too many items", err: fmt.Errorf("test.ovf: %w", &ovf.LimitError{Limit: ovf.MaxItemsLimit, Max: 1}), exp: exitUnsupportedInput},
+		{name: "snapshots", err: vmwareify.ErrSnapshots, exp: exitUnsupportedInput},
+		{name: "multiple descriptors", err: ova.ErrMultipleDescriptors, exp: exitUnsupportedInput},
+		{name: "path error", err: &fs.PathError{Op: "open", Path: "test.ovf", Err: fs.ErrNotExist}, exp: exitIoError},
+		{name: "link error", err: &os.LinkError{Op: "link", Old: "a", New: "b", Err: fs.ErrExist}, exp: exitIoError},
+		{name: "syscall error", err: os.NewSyscallError("write", errors.New("broken pipe")), exp: exitIoError},
+		{
+			name: "partial batch result",
+			err:  batchResult([]error{nil, vmwareify.ErrUnsupportedInput}),
+			exp:  exitPartialBatchResult,
+		},
+		{
+			name: "batch without failures",
+			err:  batchResult([]error{nil, nil}),
+			exp:  exitSuccess,
+		},
+		{
+			// Every input failing for the same reason is not a
+			// partial result.
+			name: "batch of failures",
+			err:  batchResult([]error{vmwareify.ErrUnsupportedInput, vmwareify.ErrSnapshots}),
+			exp:  exitUnsupportedInput,
+		},
+	}
+
+	for _, test := range tests {
+		code := exitCodeFor(test.err)
+		if code != test.exp {
+			t.Fatalf("%s - expected exit code %d, got %d", test.name, test.exp, code)
+		}
+	}
+}
+
+func TestBatchResult(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+
+	err := batchResult([]error{first, nil, second})
+
+	var partial *partialSuccessError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a *partialSuccessError - got: %v", err)
+	}
+
+	if err.Error() != "2 of 3 inputs failed" {
+		t.Fatalf("got unexpected message '%s'", err.Error())
+	}
+
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatal("partial success error does not wrap the failures")
+	}
+
+	err = batchResult([]error{first, second})
+	if errors.As(err, &partial) || !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("expected the joined failures - got: %v", err)
+	}
+
+	if batchResult(nil) != nil {
+		t.Fatal("expected an empty batch to succeed")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify"
+)
+
+func explainCommand() command {
+	return command{
+		name:    "explain",
+		args:    "[options]",
+		summary: "Print the edits that 'convert' would make to a .ovf or .ova file, and why",
+		examples: []string{
+			"vmwareify explain -f /some.ovf",
+			"vmwareify explain -auto -f /some.ova",
+			"vmwareify explain -guest-os windows-legacy -nic VmxNet3 -f /some.ovf",
+			"vmwareify explain -" + jsonArg + " -f /some.ovf",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf or .ova file to explain ('"+stdioPath+"' for stdin)")
+			options := convertOptionFlags(flagSet)
+			jsonOutput := flagSet.Bool(jsonArg, false, "Print a JSON report (see docs/json.md)")
+
+			return func(args []string) error {
+				if len(*inputFilePath) == 0 {
+					return errors.New("Please specify a .ovf or .ova file to explain")
+				}
+
+				in := os.Stdin
+				if *inputFilePath != stdioPath {
+					f, err := os.Open(*inputFilePath)
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					in = f
+				}
+
+				r := newReport(explainReportKind, *inputFilePath)
+
+				explainOptions := options()
+				explainOptions.OnWarning = func(warning string) {
+					if *jsonOutput {
+						r.Warnings = append(r.Warnings, warning)
+					} else {
+						logWarning(warning)
+					}
+				}
+
+				edits, err := vmwareify.Explain(in, explainOptions)
+				if err != nil {
+					return err
+				}
+
+				if *jsonOutput {
+					r.addEdits(edits)
+					return r.write(os.Stdout)
+				}
+
+				return printEdits(os.Stdout, edits)
+			}
+		},
+	}
+}
+
+// printEdits writes a description of each vmwareify.ConvertEdit to w.
+func printEdits(w io.Writer, edits []vmwareify.ConvertEdit) error {
+	if len(edits) == 0 {
+		_, err := fmt.Fprintln(w, "No edits would be made")
+		return err
+	}
+
+	for i, edit := range edits {
+		_, err := fmt.Fprintf(w, "%d. %s %s - %s\n", i+1, edit.Action, describeEditedObject(edit), edit.Reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describeEditedObject returns a description of the object affected by a
+// vmwareify.ConvertEdit (e.g., "Item 'sataController0' (InstanceID 5,
+// resource type 20, AHCI)").
+func describeEditedObject(edit vmwareify.ConvertEdit) string {
+	description := edit.Object.String()
+	if len(edit.ElementName) > 0 {
+		description = description + " '" + edit.ElementName + "'"
+	}
+
+	var details []string
+	if len(edit.InstanceID) > 0 {
+		details = append(details, "InstanceID "+edit.InstanceID)
+	}
+
+	if len(edit.ResourceType) > 0 {
+		details = append(details, "resource type "+edit.ResourceType)
+	}
+
+	if len(edit.ResourceSubType) > 0 {
+		details = append(details, edit.ResourceSubType)
+	}
+
+	if len(details) > 0 {
+		description = description + " (" + strings.Join(details, ", ") + ")"
+	}
+
+	return description
+}
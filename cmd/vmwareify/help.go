@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	appName = "vmwareify"
+)
+
+func helpCommand() command {
+	return command{
+		name:    "help",
+		args:    "[command]",
+		summary: "Display help for the application or a specific command",
+		examples: []string{
+			"vmwareify help",
+			"vmwareify help convert",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if len(args) == 0 {
+					printGeneralUsage(os.Stdout)
+					return nil
+				}
+
+				c, isCommand := commandFor(args)
+				if !isCommand {
+					return errors.New("Unknown command '" + args[0] + "'")
+				}
+
+				printCommandUsage(os.Stdout, c)
+
+				return nil
+			}
+		},
+	}
+}
+
+// printGeneralUsage writes the list of commands to the provided io.Writer.
+func printGeneralUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [options]\n\nCommands:\n", appName)
+
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+
+	fmt.Fprintf(w, "\nRun '%s help <command>' for more information about a command.\n", appName)
+
+	fmt.Fprintf(w, "\nExit codes:\n"+
+		"  %d  Success\n"+
+		"  %d  General failure (e.g., invalid arguments)\n"+
+		"  %d  Validation failure (the input is not well-formed)\n"+
+		"  %d  Unsupported input\n"+
+		"  %d  I/O error\n"+
+		"  %d  Partial success when processing multiple inputs\n",
+		exitSuccess, exitFailure, exitValidationFailure, exitUnsupportedInput, exitIoError, exitPartialBatchResult)
+}
+
+// printCommandUsage writes the usage text of a command, which is
+// generated from its definition, to the provided io.Writer.
+func printCommandUsage(w io.Writer, c command) {
+	fmt.Fprintf(w, "Usage: %s %s %s\n\n%s\n", appName, c.name, c.args, c.summary)
+
+	flagSet := commandFlagSet(c)
+	hasFlags := false
+	flagSet.VisitAll(func(*flag.Flag) {
+		hasFlags = true
+	})
+
+	if hasFlags {
+		fmt.Fprintln(w, "\nOptions:")
+		flagSet.SetOutput(w)
+		flagSet.PrintDefaults()
+	}
+
+	if len(c.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range c.examples {
+			fmt.Fprintln(w, "  "+example)
+		}
+	}
+}
+
+// commandFlagSet returns a flag.FlagSet containing all of the flags
+// defined by the command.
+func commandFlagSet(c command) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	flagSet.Bool(helpArg, false, "Display this help page")
+	flagSet.Bool(versionArg, false, "Display the application's version and exit")
+	logLevelFlags(flagSet)
+	c.setup(flagSet)
+
+	return flagSet
+}
+
+// commandFlagNames returns the names of the command's flags, each
+// prefixed with a '-'.
+func commandFlagNames(c command) []string {
+	var names []string
+
+	commandFlagSet(c).VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+
+	return names
+}
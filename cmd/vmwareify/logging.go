@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/stephen-fox/vmwareify"
+)
+
+const (
+	quietArg   = "quiet"
+	verboseArg = "verbose"
+	debugArg   = "debug"
+)
+
+const (
+	// quietLogLevel only logs errors.
+	quietLogLevel logLevel = iota
+
+	// normalLogLevel also logs warnings and the files that were
+	// saved.
+	normalLogLevel
+
+	// verboseLogLevel also logs the progress of each file.
+	verboseLogLevel
+
+	// debugLogLevel also logs every edit made by a conversion.
+	debugLogLevel
+)
+
+// logLevel controls which messages are logged.
+type logLevel int
+
+// currentLogLevel is the log level chosen for the running command.
+var currentLogLevel = normalLogLevel
+
+// logLevelFlags registers the flags that choose the log level, and
+// returns a function that returns the chosen logLevel once the flags
+// have been parsed.
+func logLevelFlags(flagSet *flag.FlagSet) func() (logLevel, error) {
+	quiet := flagSet.Bool(quietArg, false, "Only log errors")
+	verbose := flagSet.Bool(verboseArg, false, "Log the progress of each file")
+	debug := flagSet.Bool(debugArg, false, "Log every edit made to each file (implies -"+verboseArg+")")
+
+	return func() (logLevel, error) {
+		switch {
+		case *quiet && (*verbose || *debug):
+			return normalLogLevel, errors.New("-" + quietArg + " cannot be combined with -" + verboseArg +
+				" or -" + debugArg)
+		case *quiet:
+			return quietLogLevel, nil
+		case *debug:
+			return debugLogLevel, nil
+		case *verbose:
+			return verboseLogLevel, nil
+		}
+
+		return normalLogLevel, nil
+	}
+}
+
+// logError logs an error, regardless of the log level.
+func logError(message string) {
+	log.Println(message)
+}
+
+// logWarning logs a warning unless the log level is quietLogLevel.
+func logWarning(message string) {
+	logAt(normalLogLevel, "Warning: "+message)
+}
+
+// logInfo logs a message unless the log level is quietLogLevel.
+func logInfo(message string) {
+	logAt(normalLogLevel, message)
+}
+
+// logVerbose logs a message if the log level is verboseLogLevel or
+// higher.
+func logVerbose(message string) {
+	logAt(verboseLogLevel, message)
+}
+
+// logDebug logs a message if the log level is debugLogLevel.
+func logDebug(message string) {
+	logAt(debugLogLevel, message)
+}
+
+func logAt(level logLevel, message string) {
+	if currentLogLevel >= level {
+		log.Println(message)
+	}
+}
+
+// logConvertEvents sets the hooks of the BasicConvertOptions that report
+// the warnings and edits of converting the specified file.
+func logConvertEvents(options *vmwareify.BasicConvertOptions, inputFilePath string) {
+	options.OnWarning = func(warning string) {
+		logAt(normalLogLevel, "Warning for '"+inputFilePath+"': "+warning)
+	}
+
+	if currentLogLevel >= debugLogLevel {
+		options.OnEdit = func(edit vmwareify.ConvertEdit) {
+			logDebug("Edit of '" + inputFilePath + "': " + edit.Action.String() + " " +
+				describeEditedObject(edit) + " - " + edit.Reason)
+		}
+	}
+}
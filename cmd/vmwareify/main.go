@@ -2,47 +2,91 @@ package main
 
 import (
 	"flag"
-	"log"
+	"fmt"
 	"os"
-	"path"
 	"strings"
 
 	"github.com/stephen-fox/vmwareify"
-)
-
-const (
-	inputFilePathArg  = "f"
-	outputFilePathArg = "o"
-	helpArg           = "h"
+	"github.com/stephen-fox/vmwareify/internal/version"
 )
 
 func main() {
-	inputFilePath := flag.String(inputFilePathArg, "", "The .ovf file to convert")
-	outputFilePath := flag.String(outputFilePathArg, "", "The output file path for the converted file")
-	help := flag.Bool(helpArg, false, "Display this help page")
+	args := os.Args[1:]
+
+	c, isCommand := commandFor(args)
+	if isCommand {
+		args = args[1:]
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		logError("Unknown command '" + args[0] + "' - run 'help' for a list of commands")
+		os.Exit(exitFailure)
+	}
+
+	err := runCommand(c, args)
+	if err != nil {
+		logError(err.Error())
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// commandFor returns the command named by the first argument. The convert
+// command is returned if the first argument is not a command name.
+func commandFor(args []string) (command, bool) {
+	if len(args) > 0 {
+		for _, c := range commands() {
+			if c.name == args[0] {
+				return c, true
+			}
+		}
+	}
+
+	return convertCommand(), false
+}
+
+func runCommand(c command, args []string) error {
+	// flag.ExitOnError is not used because the flag package exits
+	// with a status of 2, which is reserved for validation failures.
+	flagSet := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	help := flagSet.Bool(helpArg, false, "Display this help page")
+	showVersion := flagSet.Bool(versionArg, false, "Display the application's version and exit")
+	level := logLevelFlags(flagSet)
+	run := c.setup(flagSet)
+	flagSet.Usage = func() {
+		printCommandUsage(os.Stderr, c)
+	}
 
-	flag.Parse()
+	err := flagSet.Parse(args)
+	if err == flag.ErrHelp {
+		os.Exit(exitSuccess)
+	}
+	if err != nil {
+		os.Exit(exitFailure)
+	}
 
 	if *help {
-		flag.PrintDefaults()
-		os.Exit(0)
+		printCommandUsage(os.Stdout, c)
+		os.Exit(exitSuccess)
 	}
 
-	if len(*inputFilePath) == 0 {
-		log.Fatal("Please specify a .ovf file to convert")
+	if *showVersion {
+		fmt.Println(appName + " " + version.Get(vmwareify.Version).String())
+		os.Exit(exitSuccess)
 	}
 
-	if len(*outputFilePath) == 0 {
-		inputFilename := path.Base(*inputFilePath)
-		*outputFilePath = path.Dir(*inputFilePath) + "/" + getFilenameWithoutExtension(inputFilename) + "-vmware" + getFileExtension(inputFilename)
+	// The config command does not use the configuration file so that
+	// it can be used to fix an invalid file.
+	if c.name != configCommandName {
+		err := applyConfigFile(c, flagSet)
+		if err != nil {
+			return err
+		}
 	}
 
-	err := vmwareify.BasicConvert(*inputFilePath, *outputFilePath)
+	currentLogLevel, err = level()
 	if err != nil {
-		log.Fatal("Failed to convert .ovf file - " + err.Error())
+		return err
 	}
 
-	log.Println("Saved converted file to '" + *outputFilePath + "'")
+	return run(flagSet.Args())
 }
 
 func getFilenameWithoutExtension(filename string) string {
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+const (
+	algorithmArg = "algorithm"
+	workersArg   = "workers"
+	cacheArg     = "cache"
+
+	remoteFilesArg = "remote-files"
+)
+
+func manifestCommand() command {
+	return command{
+		name:    "manifest",
+		args:    "[options]",
+		summary: "Generate a .mf manifest for a .ovf and the files it references",
+		examples: []string{
+			"vmwareify manifest -f /some-vmware.ovf",
+			"vmwareify manifest -f /some-vmware.ovf -algorithm SHA1 -workers 2",
+			"vmwareify manifest -f /some-vmware.ovf -cache ~/.vmwareify-digests.json",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf file to generate a manifest for")
+			outputFilePath := flagSet.String(outputFilePathArg, "", "The output file path for the manifest "+
+				"(defaults to the .ovf file path with a .mf extension)")
+			digestConfig := digestFlags(flagSet)
+			remoteFiles := flagSet.String(remoteFilesArg, ova.RejectRemoteFiles.String(), "How to handle files "+
+				"referenced by URL ('"+ova.RejectRemoteFiles.String()+"' or '"+ova.KeepRemoteFiles.String()+
+				"'). Remote files are never included in the manifest")
+
+			return func(args []string) error {
+				if len(*inputFilePath) == 0 {
+					return errors.New("Please specify a .ovf file")
+				}
+
+				if len(*outputFilePath) == 0 {
+					*outputFilePath = strings.TrimSuffix(*inputFilePath, filepath.Ext(*inputFilePath)) + ".mf"
+				}
+
+				config, cache, err := digestConfig()
+				if err != nil {
+					return err
+				}
+
+				policy, err := ova.ParseRemoteFilePolicy(*remoteFiles)
+				if err != nil {
+					return err
+				}
+
+				manifest, err := ova.GenerateManifest(*inputFilePath, ova.ManifestConfig{
+					Digest:      config,
+					RemoteFiles: policy,
+				})
+				if err != nil {
+					return err
+				}
+
+				if cache != nil {
+					err = cache.Save()
+					if err != nil {
+						return err
+					}
+				}
+
+				err = os.WriteFile(*outputFilePath, manifest.Bytes(), 0644)
+				if err != nil {
+					return err
+				}
+
+				logInfo("Saved manifest to '" + *outputFilePath + "'")
+
+				return nil
+			}
+		},
+	}
+}
+
+// digestFlags registers the flags that configure digest calculation, and
+// returns a function that creates the resulting ova.DigestConfig. The
+// *ova.FileDigestCache is nil if a cache was not specified, and must be
+// saved once the digests have been calculated.
+func digestFlags(flagSet *flag.FlagSet) func() (ova.DigestConfig, *ova.FileDigestCache, error) {
+	algorithm := flagSet.String(algorithmArg, ova.Sha256Algorithm, "The digest algorithm ('"+
+		ova.Sha1Algorithm+"', '"+ova.Sha256Algorithm+"', or '"+ova.Sha512Algorithm+"')")
+	workers := flagSet.Int(workersArg, 0, "The maximum number of files to hash concurrently "+
+		"(defaults to the number of CPUs)")
+	cachePath := flagSet.String(cacheArg, "", "A file used to cache digests between runs. "+
+		"Files whose size and modification time are unchanged are not hashed again")
+
+	return func() (ova.DigestConfig, *ova.FileDigestCache, error) {
+		config := ova.DigestConfig{
+			Algorithm: strings.ToUpper(*algorithm),
+			Workers:   *workers,
+		}
+
+		if len(*cachePath) == 0 {
+			return config, nil, nil
+		}
+
+		cache, err := ova.LoadFileDigestCache(*cachePath)
+		if err != nil {
+			return config, nil, err
+		}
+
+		config.Cache = cache
+
+		return config, cache, nil
+	}
+}
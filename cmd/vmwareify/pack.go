@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+const (
+	noManifestArg     = "no-manifest"
+	rejectSymlinksArg = "reject-symlinks"
+	rejectSparseArg   = "reject-sparse"
+)
+
+func packCommand() command {
+	return command{
+		name:    "pack",
+		args:    "[options]",
+		summary: "Pack a .ovf and the files it references into a .ova",
+		examples: []string{
+			"vmwareify pack -f /some-vmware.ovf",
+			"vmwareify pack -f /some-vmware.ovf -o /appliance.ova -reject-symlinks -reject-sparse",
+			"vmwareify pack -f /some-vmware.ovf -o - > /appliance.ova",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf file to pack")
+			outputFilePath := flagSet.String(outputFilePathArg, "", "The output file path for the .ova ('"+
+				stdioPath+"' for stdout, defaults to the .ovf file path with a .ova extension)")
+			noManifest := flagSet.Bool(noManifestArg, false, "Do not include a manifest in the .ova")
+			rejectSymlinks := flagSet.Bool(rejectSymlinksArg, false, "Fail if a file is a symbolic link "+
+				"rather than packing the link's target")
+			rejectSparse := flagSet.Bool(rejectSparseArg, false, "Fail if a file is sparse, as its holes "+
+				"would be stored as zeros")
+			reproducible := flagSet.Bool(reproducibleArg, false, "Produce an identical .ova every time the "+
+				"same files are packed by fixing the file timestamps")
+			digestConfig := digestFlags(flagSet)
+			remoteFiles := flagSet.String(remoteFilesArg, ova.RejectRemoteFiles.String(), "How to handle files "+
+				"referenced by URL ('"+ova.RejectRemoteFiles.String()+"', '"+ova.KeepRemoteFiles.String()+
+				"', or '"+ova.DownloadRemoteFiles.String()+"' them into the .ova)")
+
+			return func(args []string) error {
+				if len(*inputFilePath) == 0 {
+					return errors.New("Please specify a .ovf file")
+				}
+
+				if len(*outputFilePath) == 0 {
+					*outputFilePath = strings.TrimSuffix(*inputFilePath, filepath.Ext(*inputFilePath)) + ovaExtension
+				}
+
+				config, cache, err := digestConfig()
+				if err != nil {
+					return err
+				}
+
+				policy, err := ova.ParseRemoteFilePolicy(*remoteFiles)
+				if err != nil {
+					return err
+				}
+
+				packConfig := ova.PackConfig{
+					Digest:            config,
+					SkipManifest:      *noManifest,
+					RejectSymlinks:    *rejectSymlinks,
+					RejectSparseFiles: *rejectSparse,
+					RemoteFiles:       policy,
+					Reproducible:      *reproducible,
+				}
+
+				if *outputFilePath == stdioPath {
+					err = ova.Pack(*inputFilePath, os.Stdout, packConfig)
+				} else {
+					err = packFile(*inputFilePath, *outputFilePath, packConfig)
+				}
+				if err != nil {
+					return err
+				}
+
+				if cache != nil {
+					err = cache.Save()
+					if err != nil {
+						return err
+					}
+				}
+
+				if *outputFilePath != stdioPath {
+					logInfo("Saved .ova to '" + *outputFilePath + "'")
+				}
+
+				return nil
+			}
+		},
+	}
+}
+
+// packFile packs a .ovf into the specified .ova file, removing the .ova
+// if packing fails.
+func packFile(inputFilePath string, outputFilePath string, config ova.PackConfig) error {
+	out, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = ova.Pack(inputFilePath, out, config)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputFilePath)
+		return err
+	}
+
+	return nil
+}
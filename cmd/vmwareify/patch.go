@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/patch"
+)
+
+const (
+	patchFilePathArg = "p"
+	savePatchArg     = "save-patch"
+)
+
+func patchCommand() command {
+	return command{
+		name:    "patch",
+		args:    "[options]",
+		summary: "Apply a patch saved by 'convert -" + savePatchArg + "' to a .ovf file",
+		examples: []string{
+			"vmwareify convert -f /reviewed.ovf -" + savePatchArg + " /changes.json",
+			"vmwareify patch -f /some.ovf -p /changes.json",
+			"vmwareify patch -f /some.ovf -p /changes.json -o /some-patched.ovf",
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf file to patch")
+			patchFilePath := flagSet.String(patchFilePathArg, "", "The patch file to apply")
+			outputFilePath := flagSet.String(outputFilePathArg, "", "The output file path for the patched file "+
+				"(defaults to the same path as 'convert' would use)")
+
+			return func(args []string) error {
+				if len(*inputFilePath) == 0 {
+					return errors.New("Please specify a .ovf file to patch")
+				}
+
+				if len(*patchFilePath) == 0 {
+					return errors.New("Please specify a patch file")
+				}
+
+				if len(*outputFilePath) == 0 {
+					*outputFilePath = defaultOutputFilePath(*inputFilePath)
+				}
+
+				err := applyPatchFile(*inputFilePath, *patchFilePath, *outputFilePath)
+				if err != nil {
+					return err
+				}
+
+				logInfo("Saved patched file to '" + *outputFilePath + "'")
+
+				return nil
+			}
+		},
+	}
+}
+
+// applyPatchFile applies a patch file to a .ovf, and saves the result to
+// the output file path.
+func applyPatchFile(inputFilePath string, patchFilePath string, outputFilePath string) error {
+	patchFile, err := os.Open(patchFilePath)
+	if err != nil {
+		return err
+	}
+	defer patchFile.Close()
+
+	p, err := patch.Parse(patchFile)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(inputFilePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	patched, err := patch.Apply(in, p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputFilePath, patched.Bytes(), 0644)
+}
+
+// savePatchFile saves the changes made by converting a .ovf as a patch
+// file.
+func savePatchFile(inputFilePath string, outputFilePath string, patchFilePath string) error {
+	original, err := os.Open(inputFilePath)
+	if err != nil {
+		return err
+	}
+	defer original.Close()
+
+	converted, err := os.Open(outputFilePath)
+	if err != nil {
+		return err
+	}
+	defer converted.Close()
+
+	p, err := patch.Diff(original, converted)
+	if err != nil {
+		return err
+	}
+
+	raw, err := p.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(patchFilePath, raw, 0644)
+}
+
+// canSavePatch returns a non-nil error if a patch cannot be saved for the
+// conversion of the specified files.
+func canSavePatch(inputFilePaths []string, outputFilePath string) error {
+	if len(inputFilePaths) != 1 {
+		return errors.New("A patch can only be saved when converting a single file")
+	}
+
+	if inputFilePaths[0] == stdioPath || outputFilePath == stdioPath ||
+		strings.EqualFold(path.Ext(inputFilePaths[0]), ovaExtension) {
+		return errors.New("A patch can only be saved when converting a .ovf file to a .ovf file")
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/internal/version"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	jsonArg = "json"
+
+	// reportVersion is the version of the JSON report schema. It is
+	// incremented when a field is removed or its meaning changes.
+	// Adding a field does not change the version.
+	reportVersion = 1
+
+	explainReportKind = "explain"
+)
+
+// report is the JSON document produced by an analysis command when the
+// -json flag is specified. The schema is described in docs/json.md.
+type report struct {
+	// Version is the version of the report schema.
+	Version int `json:"version"`
+
+	// Kind is the name of the command that produced the report,
+	// which determines the remaining fields.
+	Kind string `json:"kind"`
+
+	// ToolVersion is the version of the application that produced
+	// the report (e.g., 'v1.2.3 (commit 0123456789ab)').
+	ToolVersion string `json:"toolVersion"`
+
+	// Input is the path of the analyzed file.
+	Input string `json:"input"`
+
+	// Warnings are problems that did not prevent the analysis.
+	Warnings []string `json:"warnings"`
+
+	// Edits are the edits described by the explain command. It is
+	// a pointer so that an empty list is still included in the
+	// report.
+	Edits *[]reportEdit `json:"edits,omitempty"`
+
+	// Findings are the problems found by the validate command.
+	Findings *[]reportFinding `json:"findings,omitempty"`
+}
+
+// reportEdit is the JSON form of a vmwareify.ConvertEdit.
+type reportEdit struct {
+	Object          string `json:"object"`
+	Action          string `json:"action"`
+	ElementName     string `json:"elementName,omitempty"`
+	InstanceID      string `json:"instanceId,omitempty"`
+	ResourceType    string `json:"resourceType,omitempty"`
+	ResourceSubType string `json:"resourceSubType,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// reportFinding is the JSON form of an ovf.Finding.
+type reportFinding struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+func newReport(kind string, input string) *report {
+	return &report{
+		Version:     reportVersion,
+		Kind:        kind,
+		ToolVersion: version.Get(vmwareify.Version).String(),
+		Input:       input,
+		Warnings:    []string{},
+	}
+}
+
+func (o *report) addEdits(edits []vmwareify.ConvertEdit) {
+	reportEdits := []reportEdit{}
+
+	for _, edit := range edits {
+		reportEdits = append(reportEdits, reportEdit{
+			Object:          edit.Object.String(),
+			Action:          edit.Action.String(),
+			ElementName:     edit.ElementName,
+			InstanceID:      edit.InstanceID,
+			ResourceType:    edit.ResourceType,
+			ResourceSubType: edit.ResourceSubType,
+			Reason:          edit.Reason,
+		})
+	}
+
+	o.Edits = &reportEdits
+}
+
+func (o *report) addFindings(findings []ovf.Finding) {
+	reportFindings := []reportFinding{}
+
+	for _, finding := range findings {
+		reportFindings = append(reportFindings, reportFinding{
+			Line:    finding.Line,
+			Column:  finding.Column,
+			Path:    finding.Path,
+			Rule:    finding.Rule,
+			Message: finding.Err.Error(),
+		})
+	}
+
+	o.Findings = &reportFindings
+}
+
+func (o *report) write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(o)
+}
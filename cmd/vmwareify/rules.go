@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	listRulesArg      = "list-rules"
+	enableRulesArg    = "enable-rules"
+	disableRulesArg   = "disable-rules"
+	maxMemoryArg      = "max-memory"
+	forbidNetworksArg = "forbid-networks"
+	policyArg         = "policy"
+
+	// maxMemoryRuleName, forbiddenNetworksRuleName, and
+	// unreferencedFilesRuleName are the names of the policy rules,
+	// which are disabled unless they are enabled or configured.
+	maxMemoryRuleName         = "max-memory"
+	forbiddenNetworksRuleName = "forbidden-networks"
+	unreferencedFilesRuleName = "unreferenced-files"
+
+	defaultMaxMemory         = "16GiB"
+	defaultForbiddenNetworks = "NAT"
+
+	// policyEnabledKey, policyLimitKey, and policyNamesKey are the
+	// settings of a rule in a policy file.
+	policyEnabledKey = "enabled"
+	policyLimitKey   = "limit"
+	policyNamesKey   = "names"
+)
+
+// ruleSettings are the validation rules chosen by a policy file and the
+// command line.
+type ruleSettings struct {
+	maxMemory         string
+	forbiddenNetworks string
+	enable            []string
+	disable           []string
+}
+
+// ruleFlags adds the flags that choose the validation rules to a
+// flag.FlagSet. The returned func builds the chosen ovf.RuleSet after
+// the flags are parsed. The flags take precedence over a policy file.
+func ruleFlags(flagSet *flag.FlagSet) func() (*ovf.RuleSet, error) {
+	policyFilePath := flagSet.String(policyArg, "", "A policy file that chooses the rules and their settings")
+	enable := flagSet.String(enableRulesArg, "", "A comma separated list of rules to enable (see -"+listRulesArg+")")
+	disable := flagSet.String(disableRulesArg, "", "A comma separated list of rules to disable (see -"+listRulesArg+")")
+	maxMemory := flagSet.String(maxMemoryArg, "", "Enable the '"+maxMemoryRuleName+"' rule, which finds virtual "+
+		"machines with more than the specified memory (default '"+defaultMaxMemory+"')")
+	forbidNetworks := flagSet.String(forbidNetworksArg, "", "Enable the '"+forbiddenNetworksRuleName+"' rule, "+
+		"which finds networks with the specified comma separated names (default '"+defaultForbiddenNetworks+"')")
+
+	return func() (*ovf.RuleSet, error) {
+		var settings ruleSettings
+
+		if len(*policyFilePath) > 0 {
+			err := settings.loadPolicyFile(*policyFilePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(*maxMemory) > 0 {
+			settings.maxMemory = *maxMemory
+			settings.enable = append(settings.enable, maxMemoryRuleName)
+		}
+
+		if len(*forbidNetworks) > 0 {
+			settings.forbiddenNetworks = *forbidNetworks
+			settings.enable = append(settings.enable, forbiddenNetworksRuleName)
+		}
+
+		settings.enable = append(settings.enable, splitList(*enable)...)
+		settings.disable = append(settings.disable, splitList(*disable)...)
+
+		return settings.ruleSet()
+	}
+}
+
+// loadPolicyFile loads the rule settings in a policy file. A policy file
+// has the same format as the configuration file, in which each top-level
+// key is the name of a rule that contains its settings:
+//
+//	max-memory:
+//	  limit: 32GiB
+//	forbidden-networks:
+//	  names: NAT, Bridged
+//	item-parents:
+//	  enabled: false
+//
+// A rule is enabled if it is listed, unless it sets 'enabled' to false.
+func (o *ruleSettings) loadPolicyFile(policyFilePath string) error {
+	f, err := os.Open(policyFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	policy, err := parseConfigFile(f)
+	if err != nil {
+		return errors.New("Failed to parse policy file '" + policyFilePath + "' - " + err.Error())
+	}
+
+	for name, settings := range policy {
+		enabled := true
+
+		for key, value := range settings {
+			switch {
+			case key == policyEnabledKey:
+				enabled, err = strconv.ParseBool(value)
+				if err != nil {
+					return errors.New("Invalid value for '" + name + "." + key + "' in policy file '" +
+						policyFilePath + "' - " + err.Error())
+				}
+			case key == policyLimitKey && name == maxMemoryRuleName:
+				o.maxMemory = value
+			case key == policyNamesKey && name == forbiddenNetworksRuleName:
+				o.forbiddenNetworks = value
+			default:
+				return errors.New("Policy file '" + policyFilePath + "' contains unknown setting '" +
+					name + "." + key + "'")
+			}
+		}
+
+		if enabled {
+			o.enable = append(o.enable, name)
+		} else {
+			o.disable = append(o.disable, name)
+		}
+	}
+
+	return nil
+}
+
+// ruleSet builds the ovf.RuleSet chosen by the settings.
+func (o ruleSettings) ruleSet() (*ovf.RuleSet, error) {
+	memory := defaultMaxMemory
+	if len(o.maxMemory) > 0 {
+		memory = o.maxMemory
+	}
+
+	maxBytes, err := ovf.ParseCapacity(memory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maximum memory - %w", err)
+	}
+
+	networks := defaultForbiddenNetworks
+	if len(o.forbiddenNetworks) > 0 {
+		networks = o.forbiddenNetworks
+	}
+
+	rules := ovf.NewRuleSet()
+	rules.Add(maxMemoryRuleName, "Virtual machines have at most "+memory+" of memory",
+		ovf.MaxMemoryRule(maxBytes))
+	rules.Add(forbiddenNetworksRuleName, "Virtual machines are not connected to the networks '"+
+		strings.Join(splitList(networks), "', '")+"'", ovf.ForbiddenNetworksRule(splitList(networks)...))
+	rules.Add(unreferencedFilesRuleName, "Every References File is referenced by a Disk, Item, or Strings bundle",
+		ovf.UnreferencedFilesRule())
+
+	rules.Disable(maxMemoryRuleName)
+	rules.Disable(forbiddenNetworksRuleName)
+	rules.Disable(unreferencedFilesRuleName)
+
+	for _, name := range o.enable {
+		err := rules.Enable(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range o.disable {
+		err := rules.Disable(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// splitList splits a comma separated list, ignoring empty entries.
+func splitList(s string) []string {
+	var entries []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// printRules writes the name, state, and description of each rule to w.
+func printRules(w io.Writer, rules *ovf.RuleSet) error {
+	infos := rules.Rules()
+	if len(infos) == 0 {
+		return errors.New("no rules are available")
+	}
+
+	for _, info := range infos {
+		state := "enabled"
+		if !info.Enabled {
+			state = "disabled"
+		}
+
+		_, err := fmt.Fprintf(w, "%-20s %-9s %s\n", info.Name, state, info.Description)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/service"
+)
+
+const (
+	addressArg           = "addr"
+	maxSizeArg           = "max-size"
+	maxDescriptorSizeArg = "max-descriptor-size"
+	timeoutArg           = "timeout"
+	metricsArg           = "metrics"
+	defaultAddr          = "127.0.0.1:8080"
+)
+
+func serveCommand() command {
+	return command{
+		name:    "serve",
+		args:    "[options]",
+		summary: "Serve conversions over HTTP (POST a .ovf or .ova to /convert)",
+		examples: []string{
+			"vmwareify serve",
+			"vmwareify serve -addr 0.0.0.0:8080 -max-size 33554432 -timeout 5m",
+			"curl --data-binary @/some.ovf 'http://127.0.0.1:8080/convert?guest-os=windows'",
+			"vmwareify serve -" + metricsArg,
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			address := flagSet.String(addressArg, defaultAddr, "The address to listen on")
+			maxSize := flagSet.Int64(maxSizeArg, service.DefaultMaxRequestBytes, "The maximum request size in bytes")
+			maxDescriptorSize := flagSet.Int64(maxDescriptorSizeArg, vmwareify.DefaultMaxDescriptorBytes,
+				"The maximum size in bytes of a .ovf descriptor, including one inside of an .ova")
+			maxOvaBuffer := flagSet.Int64(maxOvaBufferArg, ova.DefaultMaxBufferBytes, "The maximum size in "+
+				"bytes of the descriptor and manifest of an .ova, which are held in memory while the other "+
+				"files are streamed")
+			timeout := flagSet.Duration(timeoutArg, service.DefaultTimeout, "The maximum amount of time "+
+				"allowed for reading a request and writing its response")
+			metrics := flagSet.Bool(metricsArg, false, "Serve Prometheus metrics at '"+service.MetricsPath+"'")
+
+			return func(args []string) error {
+				config := service.Config{
+					MaxRequestBytes:    *maxSize,
+					Timeout:            *timeout,
+					MaxDescriptorBytes: *maxDescriptorSize,
+					MaxOvaBufferBytes:  *maxOvaBuffer,
+				}
+
+				if *metrics {
+					config.Metrics = service.NewMetrics()
+					logInfo("Serving metrics on 'http://" + *address + service.MetricsPath + "'")
+				}
+
+				server := service.NewServer(*address, config)
+
+				logInfo("Serving conversions on 'http://" + *address + service.ConvertPath + "'")
+
+				return server.ListenAndServe()
+			}
+		},
+	}
+}
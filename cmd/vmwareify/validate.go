@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	formatArg = "format"
+
+	textFormat   = "text"
+	jsonFormat   = "json"
+	githubFormat = "github"
+
+	validateReportKind = "validate"
+)
+
+// errValidationFailed is returned when a validated file has problems.
+var errValidationFailed = errors.New("validation failed")
+
+func validateCommand() command {
+	return command{
+		name:    "validate",
+		args:    "[options] [additional .ovf files]",
+		summary: "Check .ovf files for problems that would prevent them from being converted",
+		examples: []string{
+			"vmwareify validate -f /some.ovf",
+			"vmwareify validate /first.ovf /second.ovf",
+			"vmwareify validate -" + formatArg + " " + githubFormat + " appliances/*.ovf",
+			"vmwareify validate -" + maxMemoryArg + " 32GiB -" + disableRulesArg + " " + ovf.ItemParentsRuleName + " /some.ovf",
+			"vmwareify validate -" + listRulesArg,
+		},
+		setup: func(flagSet *flag.FlagSet) func(args []string) error {
+			inputFilePath := flagSet.String(inputFilePathArg, "", "The .ovf file to validate ('"+stdioPath+"' for stdin)")
+			format := flagSet.String(formatArg, textFormat, "The output format ('"+textFormat+"', '"+jsonFormat+
+				"', or '"+githubFormat+"' for GitHub Actions annotations)")
+			jsonOutput := flagSet.Bool(jsonArg, false, "Print a JSON report (the same as -"+formatArg+" "+jsonFormat+")")
+			listRules := flagSet.Bool(listRulesArg, false, "List the validation rules, and whether they are enabled")
+			chosenRules := ruleFlags(flagSet)
+
+			return func(args []string) error {
+				rules, err := chosenRules()
+				if err != nil {
+					return err
+				}
+
+				if *listRules {
+					return printRules(os.Stdout, rules)
+				}
+
+				inputFilePaths := args
+				if len(*inputFilePath) > 0 {
+					inputFilePaths = append([]string{*inputFilePath}, args...)
+				}
+
+				if len(inputFilePaths) == 0 {
+					return errors.New("Please specify a .ovf file to validate")
+				}
+
+				if *jsonOutput {
+					*format = jsonFormat
+				}
+
+				switch *format {
+				case textFormat, jsonFormat, githubFormat:
+				default:
+					return errors.New("Unsupported format '" + *format + "' - must be '" + textFormat +
+						"', '" + jsonFormat + "', or '" + githubFormat + "'")
+				}
+
+				failed := 0
+				for _, inputFilePath := range inputFilePaths {
+					findings, err := validateFile(inputFilePath, rules)
+					if err != nil {
+						return fmt.Errorf("Failed to validate '%s' - %w", inputFilePath, err)
+					}
+
+					if len(findings) > 0 {
+						failed = failed + 1
+					}
+
+					err = printFindings(os.Stdout, *format, inputFilePath, findings)
+					if err != nil {
+						return err
+					}
+				}
+
+				if failed > 0 {
+					return fmt.Errorf("%w - %d of %d files have problems",
+						errValidationFailed, failed, len(inputFilePaths))
+				}
+
+				if *format == textFormat {
+					logInfo("No problems found")
+				}
+
+				return nil
+			}
+		},
+	}
+}
+
+// validateFile validates a single .ovf using the specified rules. The
+// path '-' refers to stdin.
+func validateFile(inputFilePath string, rules *ovf.RuleSet) ([]ovf.Finding, error) {
+	if inputFilePath == stdioPath {
+		return ovf.ValidateWithRules(os.Stdin, ovf.Limits{}, rules)
+	}
+
+	f, err := os.Open(inputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ovf.ValidateWithRules(f, ovf.Limits{}, rules)
+}
+
+// printFindings writes the findings of a single file to w in the
+// specified format.
+func printFindings(w io.Writer, format string, inputFilePath string, findings []ovf.Finding) error {
+	switch format {
+	case jsonFormat:
+		r := newReport(validateReportKind, inputFilePath)
+		r.addFindings(findings)
+		return r.write(w)
+	case githubFormat:
+		for _, finding := range findings {
+			properties := "file=" + escapeAnnotationProperty(inputFilePath)
+			if finding.Line > 0 {
+				properties = properties + ",line=" + strconv.Itoa(finding.Line) +
+					",col=" + strconv.Itoa(finding.Column)
+			}
+
+			_, err := fmt.Fprintln(w, "::error "+properties+"::"+escapeAnnotationData(describeFinding(finding)))
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		for _, finding := range findings {
+			location := inputFilePath
+			if finding.Line > 0 {
+				location = location + ":" + strconv.Itoa(finding.Line) + ":" + strconv.Itoa(finding.Column)
+			}
+
+			_, err := fmt.Fprintln(w, location+": "+describeFinding(finding))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeFinding returns a one line description of an ovf.Finding.
+func describeFinding(finding ovf.Finding) string {
+	description := finding.Err.Error()
+	if len(finding.Path) > 0 {
+		description = "'" + finding.Path + "' - " + description
+	}
+
+	if len(finding.Rule) > 0 {
+		description = description + " (rule '" + finding.Rule + "')"
+	}
+
+	return description
+}
+
+// escapeAnnotationData escapes the message of a GitHub Actions workflow
+// command.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a property value of a GitHub Actions
+// workflow command.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
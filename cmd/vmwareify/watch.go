@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	watchArg             = "watch"
+	watchIntervalArg     = "watch-interval"
+	defaultWatchInterval = 2 * time.Second
+)
+
+// fileState is the size and modification time of a file, which are used
+// to determine if the file is still being written.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher converts the .ovf and .ova files that appear in a directory.
+type watcher struct {
+	// dir is the directory that is watched.
+	dir string
+
+	// outputDir is the directory that converted files are saved to.
+	// Converted files are saved next to their inputs if it is empty.
+	outputDir string
+
+	// interval is the amount of time between checks of the directory.
+	// A file is converted once its state has not changed for an
+	// interval, as it may still be being written.
+	interval time.Duration
+
+	// convert converts a single file.
+	convert func(inputFilePath string, outputFilePath string) error
+
+	// pending maps the paths of files that may still be being
+	// written to their most recent state.
+	pending map[string]fileState
+
+	// done maps the paths of files that were converted (or failed to
+	// be converted) to their state when they were converted. A file
+	// is converted again if its state changes.
+	done map[string]fileState
+}
+
+// run checks the directory for new files every interval until the
+// context is canceled. Conversion failures are logged rather than
+// returned.
+func (o *watcher) run(ctx context.Context) error {
+	if o.interval <= 0 {
+		return errors.New("the watch interval must be greater than zero")
+	}
+
+	for _, dir := range []string{o.dir, o.outputDir} {
+		if len(dir) == 0 {
+			continue
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return errors.New("'" + dir + "' is not a directory")
+		}
+	}
+
+	o.pending = make(map[string]fileState)
+	o.done = make(map[string]fileState)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	logInfo("Watching '" + o.dir + "' for .ovf and .ova files")
+
+	for {
+		err := o.poll()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll converts the files in the directory whose state has not changed
+// since the previous poll.
+func (o *watcher) poll() error {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isWatchedFile(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed since the directory was read.
+			continue
+		}
+
+		inputFilePath := filepath.Join(o.dir, entry.Name())
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+
+		if done, ok := o.done[inputFilePath]; ok && done == state {
+			continue
+		}
+
+		if pending, ok := o.pending[inputFilePath]; !ok || pending != state {
+			o.pending[inputFilePath] = state
+			continue
+		}
+
+		delete(o.pending, inputFilePath)
+		o.done[inputFilePath] = state
+
+		outputFilePath := defaultOutputFilePath(inputFilePath)
+		if len(o.outputDir) > 0 {
+			outputFilePath = filepath.Join(o.outputDir, filepath.Base(outputFilePath))
+		}
+
+		// The file was converted before the application started.
+		if isNewer(outputFilePath, info.ModTime()) {
+			continue
+		}
+
+		err = o.convert(inputFilePath, outputFilePath)
+		if err != nil {
+			logError("Failed to convert '" + inputFilePath + "' - " + err.Error())
+			continue
+		}
+
+		logInfo("Saved converted file to '" + outputFilePath + "'")
+	}
+
+	return nil
+}
+
+// isWatchedFile returns true if a file in the watched directory should
+// be converted. Hidden files (which are often incomplete downloads or
+// exports) and converted files are ignored.
+func isWatchedFile(name string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+	if extension != ovfExtension && extension != ovaExtension {
+		return false
+	}
+
+	return !strings.HasPrefix(name, ".") &&
+		!strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), convertedSuffix)
+}
+
+// isNewer returns true if the file exists, and was modified at or after
+// the specified time.
+func isNewer(filePath string, t time.Time) bool {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+
+	return !info.ModTime().Before(t)
+}
@@ -0,0 +1,183 @@
+package vmwareify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	VirtualBoxDialect = "virtualbox"
+	ProxmoxDialect    = "proxmox"
+	AhvDialect        = "ahv"
+	GenericDialect    = "generic"
+)
+
+// dialectQuirks are the known problems with the descriptors written by
+// a tool.
+type dialectQuirks struct {
+	// undeclaredPrefixes is true if well-known namespace prefixes
+	// (e.g., 'rasd') may be used without being declared.
+	undeclaredPrefixes bool
+
+	// misorderedSections is true if the children of the Envelope may
+	// not be in the order required by the OVF specification.
+	misorderedSections bool
+}
+
+var dialects = map[string]dialectQuirks{
+	VirtualBoxDialect: {},
+	GenericDialect:    {},
+	ProxmoxDialect: {
+		undeclaredPrefixes: true,
+		misorderedSections: true,
+	},
+	AhvDialect: {
+		undeclaredPrefixes: true,
+		misorderedSections: true,
+	},
+}
+
+// resolveDialect returns the dialect chosen by
+// BasicConvertOptions.SourceDialect, or the dialect detected from the
+// .ovf if it is empty.
+func resolveDialect(raw []byte, options BasicConvertOptions) (string, error) {
+	if len(options.SourceDialect) == 0 {
+		return detectDialect(raw)
+	}
+
+	dialect := strings.ToLower(options.SourceDialect)
+	if _, ok := dialects[dialect]; !ok {
+		return "", errors.New("unsupported source dialect '" + options.SourceDialect + "' - must be '" +
+			VirtualBoxDialect + "', '" + ProxmoxDialect + "', '" + AhvDialect + "', or '" + GenericDialect + "'")
+	}
+
+	return dialect, nil
+}
+
+// detectDialect returns the dialect of the tool that wrote an .ovf. It
+// is identified by the namespaces declared in the .ovf, the System's
+// VirtualSystemType, and the Product and Vendor of its ProductSections.
+// GenericDialect is returned if the tool is not recognized.
+func detectDialect(raw []byte) (string, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	var namespaces []string
+	var systemTypes []string
+	var products []string
+
+	var current *[]string
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			for _, attr := range v.Attr {
+				if attr.Name.Space == "xmlns" || (len(attr.Name.Space) == 0 && attr.Name.Local == "xmlns") {
+					namespaces = append(namespaces, strings.ToLower(attr.Value))
+				}
+			}
+
+			switch v.Name.Local {
+			case "VirtualSystemType":
+				current = &systemTypes
+			case "Product", "Vendor":
+				current = &products
+			default:
+				current = nil
+			}
+		case xml.CharData:
+			if current != nil {
+				*current = append(*current, strings.ToLower(strings.TrimSpace(string(v))))
+			}
+		case xml.EndElement:
+			current = nil
+		}
+	}
+
+	contains := func(values []string, substr string) bool {
+		for _, value := range values {
+			if strings.Contains(value, substr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	switch {
+	case contains(namespaces, "nutanix") || contains(products, "nutanix") || contains(systemTypes, "ahv"):
+		return AhvDialect, nil
+	case contains(namespaces, "proxmox") || contains(products, "proxmox") || contains(systemTypes, "pve"):
+		return ProxmoxDialect, nil
+	case contains(namespaces, ovf.VboxNamespace) || contains(systemTypes, VirtualBoxDialect):
+		return VirtualBoxDialect, nil
+	}
+
+	return GenericDialect, nil
+}
+
+// fixDialectQuirks returns the .ovf with the known problems of its
+// dialect fixed, so that it can be converted. Undeclared well-known
+// namespace prefixes are declared on the Envelope, and the children of
+// the Envelope are put in the order required by the OVF specification.
+// Undeclared prefixes that are not well-known are reported as warnings.
+func fixDialectQuirks(raw []byte, dialect string, options BasicConvertOptions, recorder *editRecorder) ([]byte, error) {
+	quirks := dialects[dialect]
+
+	if quirks.undeclaredPrefixes {
+		prefixes, err := ovf.UndeclaredPrefixes(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		editScheme := ovf.NewEditScheme()
+		declared := false
+
+		for _, prefix := range prefixes {
+			namespace, ok := ovf.ConventionalNamespace(prefix)
+			if !ok {
+				options.warn("the '" + prefix + "' namespace prefix is used without being declared, " +
+					"and its namespace is unknown")
+				continue
+			}
+
+			editScheme.Propose(recorder.explain(ovf.DeclareNamespaceFunc(prefix, namespace),
+				"the '"+prefix+"' namespace prefix is used without being declared, which "+dialect+
+					" exports are known to do"), ovf.EnvelopeName)
+			declared = true
+		}
+
+		if declared {
+			buff, err := editRawOvf(bytes.NewReader(raw), editScheme)
+			if err != nil {
+				return nil, err
+			}
+
+			raw = buff.Bytes()
+		}
+	}
+
+	if quirks.misorderedSections {
+		sorted, _, err := ovf.SortEnvelopeChildren(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		raw = sorted
+	}
+
+	return raw, nil
+}
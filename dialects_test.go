@@ -0,0 +1,151 @@
+package vmwareify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ovfFixturesDir contains descriptors modeled on the exports of other
+// tools (see its README.md).
+const ovfFixturesDir = "ovf/.testdata/fixtures"
+
+func readOvfFixture(t *testing.T, name string) []byte {
+	raw, err := ioutil.ReadFile(filepath.Join(ovfFixturesDir, name))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return raw
+}
+
+func TestDetectDialect(t *testing.T) {
+	expected := map[string]string{
+		"proxmox.ovf":       ProxmoxDialect,
+		"nutanix-ahv.ovf":   AhvDialect,
+		"virtualbox-7.ovf":  VirtualBoxDialect,
+		"virt-manager.ovf":  GenericDialect,
+		"vmware-fusion.ovf": GenericDialect,
+	}
+
+	for name, dialect := range expected {
+		result, err := detectDialect(readOvfFixture(t, name))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if result != dialect {
+			t.Fatalf("%s: expected dialect '%s' - got '%s'", name, dialect, result)
+		}
+	}
+
+	result, err := detectDialect([]byte(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if result != VirtualBoxDialect {
+		t.Fatalf("expected the test data to be detected as '%s' - got '%s'", VirtualBoxDialect, result)
+	}
+}
+
+func TestBasicConvertDialectQuirks(t *testing.T) {
+	for _, name := range []string{"proxmox.ovf", "nutanix-ahv.ovf"} {
+		var warnings []string
+		b, err := basicConvertWithOptions(bytes.NewReader(readOvfFixture(t, name)), BasicConvertOptions{
+			OnWarning: func(warning string) {
+				warnings = append(warnings, warning)
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+
+		prefixes, err := ovf.UndeclaredPrefixes(b.Bytes())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(prefixes) > 0 {
+			t.Fatalf("%s: expected every prefix to be declared - got undeclared prefixes %q in:\n%s",
+				name, prefixes, b.String())
+		}
+
+		_, changed, err := ovf.SortEnvelopeChildren(b.Bytes())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if changed {
+			t.Fatalf("%s: expected the Envelope's children to be in order - got:\n%s", name, b.String())
+		}
+
+		if !strings.Contains(b.String(), `xmlns:rasd="`+ovf.RasdNamespace+`"`) {
+			t.Fatalf("%s: expected the 'rasd' namespace to be declared - got:\n%s", name, b.String())
+		}
+
+		for _, warning := range warnings {
+			if strings.Contains(warning, "namespace") {
+				t.Fatalf("%s: unexpected warning '%s'", name, warning)
+			}
+		}
+	}
+}
+
+func TestBasicConvertSourceDialect(t *testing.T) {
+	raw := readOvfFixture(t, "proxmox.ovf")
+
+	// The generic dialect has no quirks, so the undeclared prefixes
+	// are left as they are.
+	b, err := basicConvertWithOptions(bytes.NewReader(raw), BasicConvertOptions{SourceDialect: GenericDialect})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "xmlns:rasd") {
+		t.Fatalf("expected the 'rasd' namespace to be left undeclared - got:\n%s", b.String())
+	}
+
+	// The dialect is case-insensitive.
+	b, err = basicConvertWithOptions(bytes.NewReader(raw), BasicConvertOptions{SourceDialect: "Proxmox"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), "xmlns:rasd") {
+		t.Fatalf("expected the 'rasd' namespace to be declared - got:\n%s", b.String())
+	}
+
+	_, err = basicConvertWithOptions(bytes.NewReader(raw), BasicConvertOptions{SourceDialect: "hyper-v"})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported source dialect")
+	}
+}
+
+func TestFixDialectQuirksUnknownPrefix(t *testing.T) {
+	raw := []byte(`<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1"><foo:Bar/></Envelope>`)
+
+	var warnings []string
+	options := BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	}
+
+	result, err := fixDialectQuirks(raw, ProxmoxDialect, options, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(result, raw) {
+		t.Fatalf("expected the .ovf to be unchanged - got:\n%s", result)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'foo'") {
+		t.Fatalf("expected a warning about the 'foo' prefix - got %q", warnings)
+	}
+}
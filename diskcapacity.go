@@ -0,0 +1,148 @@
+package vmwareify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/internal/vmdk"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// parseDiskCapacities parses a BasicConvertOptions.DiskCapacity. It
+// returns the capacity for every disk (or an empty string), and the
+// capacities of specific disks keyed by their diskId.
+func parseDiskCapacities(s string) (string, map[string]string, error) {
+	if !strings.Contains(s, "=") {
+		return strings.TrimSpace(s), nil, nil
+	}
+
+	capacities := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		diskId := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || len(diskId) == 0 {
+			return "", nil, errors.New("invalid disk capacity '" + entry + "' - must be in the format " +
+				"'<disk-id>=<capacity>'")
+		}
+
+		if _, ok := capacities[diskId]; ok {
+			return "", nil, errors.New("the capacity of disk '" + diskId + "' is specified more than once")
+		}
+
+		capacities[diskId] = strings.TrimSpace(parts[1])
+	}
+
+	return "", capacities, nil
+}
+
+// diskCapacityEdits returns the explained edits that set the declared
+// capacity of the disks chosen by BasicConvertOptions.DiskCapacity. A
+// non-nil error is returned if a capacity is smaller than the disk's
+// current capacity or data.
+func diskCapacityEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, error) {
+	if len(options.DiskCapacity) == 0 {
+		return nil, nil
+	}
+
+	all, capacities, err := parseDiskCapacities(options.DiskCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := parsed.Envelope.DiskSection.Disks
+
+	known := make(map[string]bool)
+	for _, disk := range disks {
+		known[disk.DiskId] = true
+	}
+
+	for diskId := range capacities {
+		if !known[diskId] {
+			return nil, errors.New("cannot set the capacity of disk '" + diskId + "' because it does not exist")
+		}
+	}
+
+	var edits []explainedFunc
+	for _, disk := range disks {
+		capacity := all
+		if capacities != nil {
+			capacity = capacities[disk.DiskId]
+		}
+
+		if len(capacity) == 0 {
+			continue
+		}
+
+		f, err := ovf.SetDiskCapacityFunc(disk.DiskId, capacity)
+		if err != nil {
+			return nil, err
+		}
+
+		requested, _ := ovf.ParseCapacity(capacity)
+
+		minimum, err := minimumDiskCapacity(parsed, disk, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if requested < minimum {
+			return nil, errors.New("the capacity of disk '" + disk.DiskId + "' cannot be set to '" + capacity +
+				"' (" + strconv.FormatInt(requested, 10) + " bytes) because it is smaller than the disk (" +
+				strconv.FormatInt(minimum, 10) + " bytes) - disks can only be grown")
+		}
+
+		edits = append(edits, explainedFunc{
+			f: f,
+			reason: "the disk's declared capacity is set to '" + capacity + "' (" +
+				strconv.FormatInt(requested, 10) + " bytes)",
+		})
+	}
+
+	return edits, nil
+}
+
+// minimumDiskCapacity returns the smallest capacity in bytes that a Disk
+// can be given, which is the largest of its declared capacity, its
+// populatedSize, and the capacity of its VMDK (if the VMDK is in
+// BasicConvertOptions.ReferencesDir).
+func minimumDiskCapacity(parsed ovf.Ovf, disk ovf.Disk, options BasicConvertOptions) (int64, error) {
+	minimum, err := disk.CapacityBytes()
+	if err != nil {
+		return 0, err
+	}
+
+	populatedSize, err := strconv.ParseInt(disk.PopulatedSize, 10, 64)
+	if err == nil && populatedSize > minimum {
+		minimum = populatedSize
+	}
+
+	if len(options.ReferencesDir) == 0 {
+		return minimum, nil
+	}
+
+	for _, file := range parsed.Envelope.References.Files {
+		if file.Id != disk.FileRef || ovf.IsRemoteHref(file.Href) {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(options.ReferencesDir, filepath.FromSlash(file.Href)))
+		if err != nil {
+			// The file is optional, and the declared capacity is
+			// checked regardless.
+			break
+		}
+
+		capacity, err := vmdk.Capacity(f)
+		f.Close()
+		if err == nil && capacity > minimum {
+			minimum = capacity
+		}
+
+		break
+	}
+
+	return minimum, nil
+}
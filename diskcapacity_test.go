@@ -0,0 +1,71 @@
+package vmwareify
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertDiskCapacity(t *testing.T) {
+	for _, capacity := range []string{"100GiB", "vmdisk1=100GiB"} {
+		b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+			DiskCapacity: capacity,
+		})
+		if err != nil {
+			t.Fatal(capacity + " - " + err.Error())
+		}
+
+		if !strings.Contains(b.String(), `ovf:capacity="100"`) ||
+			!strings.Contains(b.String(), `ovf:capacityAllocationUnits="byte * 2^30"`) {
+			t.Fatalf("%s - disk capacity was not set - got:\n%s", capacity, b.String())
+		}
+	}
+}
+
+func TestBasicConvertDiskCapacityInvalid(t *testing.T) {
+	// The disk's declared capacity is 104857600000 bytes.
+	for _, capacity := range []string{"1GiB", "missing=100GiB", "vmdisk1", "vmdisk1=1GiB",
+		"vmdisk1=100GiB,vmdisk1=200GiB", "=100GiB", "lots"} {
+		_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+			DiskCapacity: capacity,
+		})
+		if err == nil {
+			t.Fatal("expected an error for '" + capacity + "'")
+		}
+	}
+}
+
+func TestBasicConvertDiskCapacityVmdk(t *testing.T) {
+	dir := t.TempDir()
+
+	// A sparse extent with a capacity of 200GiB, which is larger than
+	// the declared capacity.
+	disk := make([]byte, 512)
+	binary.LittleEndian.PutUint32(disk[0:], 0x564d444b)
+	binary.LittleEndian.PutUint64(disk[12:], 200<<30/512)
+	binary.LittleEndian.PutUint64(disk[20:], 128)
+	binary.LittleEndian.PutUint32(disk[44:], 512)
+
+	err := os.WriteFile(filepath.Join(dir, "centos-0.0.1-disk001.vmdk"), disk, 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		DiskCapacity:  "100GiB",
+		ReferencesDir: dir,
+	})
+	if err == nil {
+		t.Fatal("expected an error when the capacity is smaller than the VMDK")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		DiskCapacity:  "200GiB",
+		ReferencesDir: dir,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,256 @@
+package vmwareify
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// renamedDiskFile is a References File containing a disk image that is
+// renamed by a conversion (see BasicConvertOptions.DiskFileSuffix).
+type renamedDiskFile struct {
+	id      string
+	href    string
+	newHref string
+}
+
+// diskFileRenames returns the References Files that contain disk images
+// (i.e., the Files referred to by a Disk's fileRef), along with their
+// new hrefs. Remote files are not renamed.
+func diskFileRenames(parsed ovf.Ovf, suffix string) ([]renamedDiskFile, error) {
+	if strings.ContainsAny(suffix, `/\`) {
+		return nil, errors.New("disk file suffix '" + suffix + "' cannot contain a path separator")
+	}
+
+	diskFiles := make(map[string]bool)
+	for _, disk := range parsed.Envelope.DiskSection.Disks {
+		diskFiles[disk.FileRef] = true
+	}
+
+	hrefs := make(map[string]bool)
+	for _, file := range parsed.Envelope.References.Files {
+		hrefs[file.Href] = true
+	}
+
+	var renames []renamedDiskFile
+	for _, file := range parsed.Envelope.References.Files {
+		if !diskFiles[file.Id] || ovf.IsRemoteHref(file.Href) {
+			continue
+		}
+
+		extension := path.Ext(file.Href)
+		newHref := strings.TrimSuffix(file.Href, extension) + suffix + extension
+
+		if hrefs[newHref] {
+			return nil, errors.New("disk file '" + file.Href + "' cannot be renamed to '" + newHref +
+				"' because another file has that name")
+		}
+		hrefs[newHref] = true
+
+		renames = append(renames, renamedDiskFile{
+			id:      file.Id,
+			href:    file.Href,
+			newHref: newHref,
+		})
+	}
+
+	return renames, nil
+}
+
+// renameDiskFileEdits returns the explained edits that set the href of
+// each renamed disk file.
+func renameDiskFileEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, error) {
+	if len(options.DiskFileSuffix) == 0 {
+		return nil, nil
+	}
+
+	renames, err := diskFileRenames(parsed, options.DiskFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []explainedFunc
+	for _, rename := range renames {
+		edits = append(edits, explainedFunc{
+			f:      ovf.SetFileHrefFunc(rename.id, rename.newHref),
+			reason: "the disk file is renamed to '" + rename.newHref + "'",
+		})
+	}
+
+	return edits, nil
+}
+
+const (
+	// CopyCompanionFiles copies the files referenced by the .ovf next
+	// to the converted .ovf.
+	CopyCompanionFiles = "copy"
+
+	// SymlinkCompanionFiles creates symbolic links next to the
+	// converted .ovf that refer to the files referenced by the .ovf.
+	SymlinkCompanionFiles = "symlink"
+)
+
+// placeReferencedFiles places the files referenced by the original .ovf
+// next to the converted .ovf, so that the converted .ovf is
+// self-contained. If BasicConvertOptions.CompanionFiles is empty, only
+// the renamed disk files are placed, and they are hard-linked if possible
+// (and copied otherwise). Remote files are not placed. The files are
+// located relative to BasicConvertOptions.ReferencesDir, or the original
+// .ovf's directory if it is empty.
+func placeReferencedFiles(ovfFilePath string, newFilePath string, options BasicConvertOptions) error {
+	if len(options.CompanionFiles) == 0 && len(options.DiskFileSuffix) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(ovfFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	parsed, err := toOvf(f)
+	if err != nil {
+		return err
+	}
+
+	sourceDir := options.ReferencesDir
+	if len(sourceDir) == 0 {
+		sourceDir = filepath.Dir(ovfFilePath)
+	}
+
+	if options.NormalizeHrefs {
+		_, err = normalizeHrefs(&parsed, sourceDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	var renames []renamedDiskFile
+	if len(options.DiskFileSuffix) > 0 {
+		renames, err = diskFileRenames(parsed, options.DiskFileSuffix)
+		if err != nil {
+			return err
+		}
+	}
+
+	newHrefs := make(map[string]string)
+	for _, rename := range renames {
+		newHrefs[rename.id] = rename.newHref
+	}
+
+	for _, file := range parsed.Envelope.References.Files {
+		newHref, renamed := newHrefs[file.Id]
+		if !renamed {
+			if len(options.CompanionFiles) == 0 || ovf.IsRemoteHref(file.Href) {
+				continue
+			}
+
+			newHref = file.Href
+		}
+
+		if path.IsAbs(file.Href) || strings.HasPrefix(path.Clean(file.Href), "../") {
+			return errors.New("referenced file '" + file.Href + "' is not in the same directory as the .ovf")
+		}
+
+		source := filepath.Join(sourceDir, filepath.FromSlash(file.Href))
+		destination := filepath.Join(filepath.Dir(newFilePath), filepath.FromSlash(newHref))
+
+		err := placeFile(source, destination, options.CompanionFiles)
+		if err != nil {
+			return errors.New("failed to place referenced file '" + file.Href + "' at '" + destination +
+				"' - " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// placeFile places the source file at the destination using the specified
+// BasicConvertOptions.CompanionFiles mode. If the mode is empty, the file
+// is hard-linked, or copied if it cannot be linked (e.g., the files are
+// on different file systems). An existing destination file is replaced.
+func placeFile(source string, destination string, mode string) error {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	destinationInfo, err := os.Stat(destination)
+	if err == nil && os.SameFile(sourceInfo, destinationInfo) {
+		return nil
+	}
+
+	_, err = os.Lstat(destination)
+	if err == nil {
+		err = os.Remove(destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(destination), 0755)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case CopyCompanionFiles:
+		return copyFile(source, destination, sourceInfo.Mode())
+	case SymlinkCompanionFiles:
+		absSource, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(absSource, destination)
+	}
+
+	err = os.Link(source, destination)
+	if err == nil {
+		return nil
+	}
+
+	return copyFile(source, destination, sourceInfo.Mode())
+}
+
+// checkCompanionFiles returns a non-nil error if the
+// BasicConvertOptions.CompanionFiles mode is not supported.
+func checkCompanionFiles(mode string) error {
+	switch mode {
+	case "", CopyCompanionFiles, SymlinkCompanionFiles:
+		return nil
+	}
+
+	return errors.New("unsupported companion files option '" + mode + "' - must be '" +
+		CopyCompanionFiles + "' or '" + SymlinkCompanionFiles + "'")
+}
+
+func copyFile(source string, destination string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destination)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package vmwareify
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertDiskFileSuffix(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		DiskFileSuffix: "-vmware",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), `<File ovf:id="file1" ovf:href="centos-0.0.1-disk001-vmware.vmdk"/>`) {
+		t.Fatalf("disk file was not renamed - got:\n%s", b.String())
+	}
+
+	if !strings.Contains(b.String(), `ovf:fileRef="file1"`) {
+		t.Fatalf("disk file reference changed - got:\n%s", b.String())
+	}
+}
+
+func TestBasicConvertDiskFileSuffixInvalid(t *testing.T) {
+	_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		DiskFileSuffix: "/vmware",
+	})
+	if err == nil {
+		t.Fatal("expected an error for a suffix containing a path separator")
+	}
+
+	existing := strings.Replace(basicOvfFileContents,
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>
+    <File ovf:id="file2" ovf:href="centos-0.0.1-disk001-vmware.vmdk"/>`, 1)
+
+	_, err = basicConvertWithOptions(strings.NewReader(existing), BasicConvertOptions{
+		DiskFileSuffix: "-vmware",
+	})
+	if err == nil {
+		t.Fatal("expected an error when a renamed disk file has the same name as another file")
+	}
+}
+
+func TestBasicConvertWithOptionsPlacesDiskFiles(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	ovfFilePath := filepath.Join(inputDir, "centos.ovf")
+	err := os.WriteFile(ovfFilePath, []byte(basicOvfFileContents), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	disk := []byte("disk")
+	err = os.WriteFile(filepath.Join(inputDir, "centos-0.0.1-disk001.vmdk"), disk, 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	newFilePath := filepath.Join(outputDir, "centos-vmware.ovf")
+	options := BasicConvertOptions{DiskFileSuffix: "-vmware"}
+
+	// Converting twice replaces the previously placed disk file.
+	for i := 0; i < 2; i++ {
+		err = BasicConvertWithOptions(ovfFilePath, newFilePath, options)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	placed, err := os.ReadFile(filepath.Join(outputDir, "centos-0.0.1-disk001-vmware.vmdk"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(placed, disk) {
+		t.Fatalf("placed disk file contents are '%s' - expected '%s'", placed, disk)
+	}
+
+	err = os.Remove(filepath.Join(inputDir, "centos-0.0.1-disk001.vmdk"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = BasicConvertWithOptions(ovfFilePath, newFilePath, options)
+	if err == nil {
+		t.Fatal("expected an error when a disk file does not exist")
+	}
+}
+
+func TestConvertOvaDiskFileSuffix(t *testing.T) {
+	err := ConvertOva(strings.NewReader(""), io.Discard, BasicConvertOptions{DiskFileSuffix: "-vmware"})
+	if err == nil {
+		t.Fatal("expected an error when renaming the disk files of an .ova")
+	}
+}
+
+func TestBasicConvertWithOptionsCompanionFiles(t *testing.T) {
+	for _, mode := range []string{CopyCompanionFiles, SymlinkCompanionFiles} {
+		inputDir := t.TempDir()
+		outputDir := t.TempDir()
+
+		existing := strings.Replace(basicOvfFileContents,
+			`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>`,
+			`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>
+    <File ovf:id="file2" ovf:href="guest-additions.iso"/>
+    <File ovf:id="file3" ovf:href="https://example.com/remote.iso"/>`, 1)
+
+		ovfFilePath := filepath.Join(inputDir, "centos.ovf")
+		err := os.WriteFile(ovfFilePath, []byte(existing), 0600)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		for _, name := range []string{"centos-0.0.1-disk001.vmdk", "guest-additions.iso"} {
+			err = os.WriteFile(filepath.Join(inputDir, name), []byte(name), 0600)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		newFilePath := filepath.Join(outputDir, "centos-vmware.ovf")
+		err = BasicConvertWithOptions(ovfFilePath, newFilePath, BasicConvertOptions{CompanionFiles: mode})
+		if err != nil {
+			t.Fatal(mode + " - " + err.Error())
+		}
+
+		for _, name := range []string{"centos-0.0.1-disk001.vmdk", "guest-additions.iso"} {
+			placedPath := filepath.Join(outputDir, name)
+
+			info, err := os.Lstat(placedPath)
+			if err != nil {
+				t.Fatal(mode + " - " + err.Error())
+			}
+
+			isSymlink := info.Mode()&os.ModeSymlink != 0
+			if isSymlink != (mode == SymlinkCompanionFiles) {
+				t.Fatalf("%s - '%s' has mode %s", mode, name, info.Mode())
+			}
+
+			placed, err := os.ReadFile(placedPath)
+			if err != nil {
+				t.Fatal(mode + " - " + err.Error())
+			}
+
+			if string(placed) != name {
+				t.Fatalf("%s - placed file contents are '%s' - expected '%s'", mode, placed, name)
+			}
+		}
+
+		_, err = os.Lstat(filepath.Join(outputDir, "remote.iso"))
+		if !os.IsNotExist(err) {
+			t.Fatalf("%s - remote file should not be placed - got: %v", mode, err)
+		}
+	}
+}
+
+func TestBasicConvertWithOptionsCompanionFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	ovfFilePath := filepath.Join(dir, "centos.ovf")
+	err := os.WriteFile(ovfFilePath, []byte(basicOvfFileContents), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = BasicConvertWithOptions(ovfFilePath, filepath.Join(dir, "centos-vmware.ovf"),
+		BasicConvertOptions{CompanionFiles: "teleport"})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported companion files option")
+	}
+}
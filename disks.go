@@ -0,0 +1,64 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// warnSharedDisks reports a warning for each disk that is attached to
+// more than one device (i.e., a multi-attach disk).
+func warnSharedDisks(items []ovf.Item, options BasicConvertOptions) {
+	attachments := ovf.DiskAttachments(items)
+
+	for _, diskId := range ovf.SharedDisks(items) {
+		var devices []string
+		for _, item := range attachments[diskId] {
+			devices = append(devices, "'"+item.ElementName+"' (InstanceID "+item.InstanceID+")")
+		}
+
+		options.warn("disk '" + diskId + "' is attached to " + strconv.Itoa(len(devices)) + " devices (" +
+			strings.Join(devices, ", ") + ") - VMWare only allows a disk to be shared if it is thick " +
+			"provisioned (eager zeroed) and its sharing mode is set to 'multi-writer', which must be " +
+			"configured after the virtual machine is imported")
+	}
+}
+
+// checkSharedDisks returns a non-nil error if an edited .ovf no longer
+// attaches each shared disk to the same devices as the original Items.
+func checkSharedDisks(edited *bytes.Buffer, original []ovf.Item) error {
+	shared := ovf.SharedDisks(original)
+	if len(shared) == 0 {
+		return nil
+	}
+
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	before := ovf.DiskAttachments(original)
+	after := ovf.DiskAttachments(parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items)
+
+	for _, diskId := range shared {
+		if instanceIDs(before[diskId]) != instanceIDs(after[diskId]) {
+			return errors.New("the conversion would change the devices that shared disk '" + diskId +
+				"' is attached to (InstanceIDs " + instanceIDs(before[diskId]) + " became " +
+				instanceIDs(after[diskId]) + ")")
+		}
+	}
+
+	return nil
+}
+
+func instanceIDs(items []ovf.Item) string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.InstanceID)
+	}
+
+	return "[" + strings.Join(ids, ", ") + "]"
+}
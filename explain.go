@@ -0,0 +1,128 @@
+package vmwareify
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ConvertEdit describes a single edit made by a conversion.
+type ConvertEdit struct {
+	// Object is the name of the edited OVF object (e.g., 'Item').
+	Object ovf.ObjectName
+
+	// Action is the action taken on the object.
+	Action ovf.EditAction
+
+	// ElementName, InstanceID, ResourceType, and ResourceSubType
+	// describe the object before it was edited. They are only set
+	// if the object is an Item or a System.
+	ElementName     string
+	InstanceID      string
+	ResourceType    string
+	ResourceSubType string
+
+	// Reason describes why the edit was made.
+	Reason string
+}
+
+// Explain reads a .ovf or .ova from in, and returns the edits that
+// Convert would make to its .ovf descriptor given the specified
+// BasicConvertOptions, in the order they would be made. Nothing is
+// written.
+func Explain(in io.Reader, options BasicConvertOptions) ([]ConvertEdit, error) {
+	buffered := bufio.NewReaderSize(in, ova.DetectionSize)
+	recorder := &editRecorder{}
+
+	header, _ := buffered.Peek(ova.DetectionSize)
+	if ova.IsOva(header) {
+		err := ova.Rewrite(buffered, ioutil.Discard, func(descriptor io.Reader) (*bytes.Buffer, error) {
+			return convert(descriptor, options, recorder)
+		})
+
+		return recorder.edits, err
+	}
+
+	_, err := convert(buffered, options, recorder)
+
+	return recorder.edits, err
+}
+
+// editRecorder records the edits made by a conversion. A nil
+// *editRecorder records nothing.
+type editRecorder struct {
+	edits []ConvertEdit
+
+	// onEdit, if non-nil, is called with each recorded edit.
+	onEdit func(edit ConvertEdit)
+}
+
+// explain returns an ovf.EditObjectFunc that records the edits made by
+// the specified func along with the reason they were made.
+func (o *editRecorder) explain(f ovf.EditObjectFunc, reason string) ovf.EditObjectFunc {
+	if o == nil {
+		return f
+	}
+
+	return func(i interface{}) ovf.EditObjectResult {
+		var original []byte
+		raw, isRaw := i.(*ovf.RawObject)
+		if isRaw {
+			original = append([]byte(nil), raw.Data().Bytes()...)
+		}
+
+		result := f(i)
+		if result.Action == ovf.NoOp || (result.Action == ovf.Replace && isUnchanged(i, original, result.Object)) {
+			return result
+		}
+
+		edit := ConvertEdit{
+			Action: result.Action,
+			Reason: reason,
+		}
+
+		switch v := i.(type) {
+		case ovf.Item:
+			edit.Object = ovf.VirtualHardwareItemName
+			edit.ElementName = v.ElementName
+			edit.InstanceID = v.InstanceID
+			edit.ResourceType = v.ResourceType
+			edit.ResourceSubType = v.ResourceSubType
+		case ovf.System:
+			edit.Object = ovf.VirtualHardwareSystemName
+			edit.ElementName = v.ElementName
+			edit.InstanceID = v.InstanceId
+		case *ovf.RawObject:
+			edit.Object = ovf.ObjectName(v.Start.Name.Local)
+		}
+
+		o.edits = append(o.edits, edit)
+		if o.onEdit != nil {
+			o.onEdit(edit)
+		}
+
+		return result
+	}
+}
+
+// isUnchanged returns true if a replacement object is the same as the
+// original object. The original data is only used if the object is a
+// *ovf.RawObject, which may be modified in place.
+func isUnchanged(object interface{}, originalData []byte, replacement ovf.EditedObject) bool {
+	switch v := replacement.(type) {
+	case *ovf.Item:
+		original, ok := object.(ovf.Item)
+		return ok && v != nil && *v == original
+	case *ovf.System:
+		original, ok := object.(ovf.System)
+		return ok && v != nil && *v == original
+	case *ovf.RawObject:
+		return v != nil && bytes.Equal(v.Data().Bytes(), originalData)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,85 @@
+package vmwareify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestExplain(t *testing.T) {
+	edits, err := Explain(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		GuestOSProfile: "linux",
+		VirtualTPM:     true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	deletedIdeControllers := 0
+	disabledCdrom := false
+	addedTPM := false
+	for _, edit := range edits {
+		if len(edit.Reason) == 0 {
+			t.Fatalf("Edit is missing a reason - %+v", edit)
+		}
+
+		if edit.Action == ovf.Delete && edit.ResourceType == ovf.IdeControllerResourceType {
+			deletedIdeControllers = deletedIdeControllers + 1
+		}
+
+		if edit.Object == ovf.VirtualHardwareSectionName && strings.Contains(edit.Reason, "TPM") {
+			addedTPM = true
+		}
+
+		if edit.Action == ovf.Replace && edit.ResourceType == ovf.CdDriveResourceType {
+			disabledCdrom = true
+		}
+	}
+
+	if deletedIdeControllers != 2 || !disabledCdrom || !addedTPM {
+		t.Fatalf("Did not get expected edits - %+v", edits)
+	}
+
+	converted, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	edits, err = Explain(converted, BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, edit := range edits {
+		if edit.Object != ovf.VirtualHardwareSystemName {
+			t.Fatalf("Got unexpected edit for an already converted .ovf - %+v", edit)
+		}
+	}
+}
+
+func TestBasicConvertOnEdit(t *testing.T) {
+	options := BasicConvertOptions{
+		GuestOSProfile: "linux",
+	}
+
+	expected, err := Explain(strings.NewReader(basicOvfFileContents), options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var edits []ConvertEdit
+	options.OnEdit = func(edit ConvertEdit) {
+		edits = append(edits, edit)
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(basicOvfFileContents), options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(edits) == 0 || !reflect.DeepEqual(edits, expected) {
+		t.Fatalf("OnEdit was not called with the explained edits - got %+v, expected %+v", edits, expected)
+	}
+}
@@ -0,0 +1,150 @@
+package vmwareify
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	RemoveFloppyDrives = "remove"
+	KeepFloppyDrives   = "keep"
+
+	// vmwFloppySubType is the ResourceSubType of a VMWare floppy
+	// drive that is connected to a client device when needed.
+	vmwFloppySubType = "vmware.floppy.remotedevice"
+)
+
+var (
+	floppyDriveMatch = ovf.ItemMatch{
+		NamePrefix:   "floppy",
+		Description:  "Floppy Drive",
+		ResourceType: ovf.FloppyDriveResourceType,
+	}
+
+	// floppyControllerMatch matches floppy controllers, which some
+	// tools declare as Items. VMWare does not model the controller.
+	floppyControllerMatch = ovf.ItemMatch{
+		NamePrefix:  "floppyController",
+		Description: "Floppy Controller",
+	}
+)
+
+func isFloppyController(item ovf.Item) bool {
+	return floppyControllerMatch.Matches(item)
+}
+
+func isFloppyDrive(item ovf.Item) bool {
+	return floppyDriveMatch.Matches(item) && !isFloppyController(item)
+}
+
+// RemoveFloppyDrivesFunc returns an ovf.EditObjectFunc that will remove
+// floppy drives and floppy controllers.
+func RemoveFloppyDrivesFunc() ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(ovf.Item)
+		if !ok || (!isFloppyDrive(o) && !isFloppyController(o)) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		return ovf.EditObjectResult{Action: ovf.Delete}
+	}
+}
+
+// ConvertFloppyDrivesFunc returns an ovf.EditObjectFunc that will convert
+// floppy drives to VMWare floppy drives, and remove floppy controllers.
+func ConvertFloppyDrivesFunc() ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(ovf.Item)
+		if !ok {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		if isFloppyController(o) {
+			return ovf.EditObjectResult{Action: ovf.Delete}
+		}
+
+		if !isFloppyDrive(o) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		o.ResourceType = ovf.FloppyDriveResourceType
+		o.ResourceSubType = vmwFloppySubType
+		o.AutomaticAllocation = false
+		o.Parent = ""
+		o.AddressOnParent = ""
+
+		return ovf.EditObjectResult{
+			Action: ovf.Replace,
+			Object: &o,
+		}
+	}
+}
+
+// floppyEdits returns the explained ovf.EditObjectFunc for the floppy
+// drives chosen by the BasicConvertOptions, and the IDs of the Files that
+// should be deleted because only removed floppy drives use them.
+func floppyEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, []string, error) {
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+
+	var drives []string
+	for _, item := range items {
+		if isFloppyDrive(item) {
+			drives = append(drives, "'"+item.ElementName+"'")
+		}
+	}
+
+	switch strings.ToLower(options.FloppyDrives) {
+	case "":
+		if len(drives) > 0 {
+			options.warn("the virtual machine has floppy drives (" + strings.Join(drives, ", ") +
+				"), which VMWare may warn about when importing it - remove them, or convert them " +
+				"to VMWare floppy drives")
+		}
+
+		return nil, nil, nil
+	case KeepFloppyDrives:
+		return []explainedFunc{{
+			f: ConvertFloppyDrivesFunc(),
+			reason: "floppy drives are converted to VMWare floppy drives ('" + vmwFloppySubType +
+				"'), and floppy controllers are removed",
+		}}, nil, nil
+	case RemoveFloppyDrives:
+	default:
+		return nil, nil, errors.New("unsupported floppy drive option '" + options.FloppyDrives +
+			"' - must be '" + RemoveFloppyDrives + "' or '" + KeepFloppyDrives + "'")
+	}
+
+	// Files that are used by anything else are kept.
+	removedFiles := make(map[string]bool)
+	keptFiles := make(map[string]bool)
+	for _, item := range items {
+		fileId, ok := ovf.HostResourceFileId(item.HostResource)
+		if !ok {
+			continue
+		}
+
+		if isFloppyDrive(item) {
+			removedFiles[fileId] = true
+		} else {
+			keptFiles[fileId] = true
+		}
+	}
+
+	for _, disk := range parsed.Envelope.DiskSection.Disks {
+		keptFiles[disk.FileRef] = true
+	}
+
+	var files []string
+	for _, file := range parsed.Envelope.References.Files {
+		if removedFiles[file.Id] && !keptFiles[file.Id] {
+			files = append(files, file.Id)
+		}
+	}
+
+	return []explainedFunc{{
+		f:      RemoveFloppyDrivesFunc(),
+		reason: "floppy drives and floppy controllers are removed",
+	}}, files, nil
+}
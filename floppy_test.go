@@ -0,0 +1,86 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func withFloppyDrive(t *testing.T) string {
+	floppy := `      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Caption>floppy0</rasd:Caption>
+        <rasd:Description>Floppy Drive</rasd:Description>
+        <rasd:ElementName>floppy0</rasd:ElementName>
+        <rasd:HostResource>ovf:/file/file2</rasd:HostResource>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:ResourceType>14</rasd:ResourceType>
+      </Item>
+`
+	input := strings.Replace(withHardwareItem(t, basicOvfFileContents, floppy), "  </References>", `    <File ovf:id="file2" ovf:href="boot.img"/>
+  </References>`, 1)
+	if !strings.Contains(input, "boot.img") {
+		t.Fatal("Failed to add floppy image to test data")
+	}
+
+	return input
+}
+
+func TestBasicConvertFloppyDrives(t *testing.T) {
+	input := withFloppyDrive(t)
+
+	var warnings []string
+	_, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'floppy0'") {
+		t.Fatalf("Expected a warning about the floppy drive, got %v", warnings)
+	}
+
+	removed, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{FloppyDrives: RemoveFloppyDrives})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(removed.String(), "floppy0") || strings.Contains(removed.String(), "boot.img") {
+		t.Fatal("Floppy drive or its image was not removed:\n'" + removed.String() + "'")
+	}
+
+	kept, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{FloppyDrives: KeepFloppyDrives})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(kept.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var found bool
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.InstanceID == "9" {
+			found = true
+
+			if item.ResourceSubType != vmwFloppySubType || item.AutomaticAllocation || len(item.AddressOnParent) > 0 {
+				t.Fatalf("Floppy drive was not converted - %+v", item)
+			}
+		}
+	}
+
+	if !found || !strings.Contains(kept.String(), "boot.img") {
+		t.Fatal("Floppy drive or its image was not kept:\n'" + kept.String() + "'")
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(input), BasicConvertOptions{FloppyDrives: "junk"})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported floppy drive option")
+	}
+}
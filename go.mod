@@ -1 +1,3 @@
 module github.com/stephen-fox/vmwareify
+
+go 1.20
@@ -0,0 +1,61 @@
+package vmwareify
+
+import (
+	"github.com/stephen-fox/vmwareify/internal/guestos"
+)
+
+// GuestOS describes a guest operating system known to VirtualBox, and
+// its VMWare equivalent.
+type GuestOS struct {
+	// VboxOSType is the VirtualBox OS type identifier
+	// (e.g., 'RedHat_64').
+	VboxOSType string
+
+	// VMwareID is the VMWare guest OS identifier
+	// (e.g., 'rhel7_64Guest').
+	VMwareID string
+
+	// Family is the operating system's family (e.g., 'windows'
+	// or 'linux').
+	Family string
+
+	// Legacy is true if the operating system predates the modern
+	// VMWare virtual hardware (e.g., it lacks e1000e drivers).
+	Legacy bool
+
+	// Is64Bit is true if the operating system is 64-bit.
+	Is64Bit bool
+}
+
+// LookupVMwareGuestOS returns the GuestOS for the specified VirtualBox
+// OS type (e.g., 'Windows10_64'). The lookup is case-insensitive.
+func LookupVMwareGuestOS(vboxOSType string) (GuestOS, bool) {
+	info, ok := guestos.Lookup(vboxOSType)
+	if !ok {
+		return GuestOS{}, false
+	}
+
+	return guestOSFromInfo(info), true
+}
+
+// VMwareGuestOSes returns the GuestOS of every known VirtualBox OS type,
+// including 64-bit variants.
+func VMwareGuestOSes() []GuestOS {
+	var guestOSes []GuestOS
+
+	for _, info := range guestos.All() {
+		guestOSes = append(guestOSes, guestOSFromInfo(info))
+	}
+
+	return guestOSes
+}
+
+func guestOSFromInfo(info guestos.Info) GuestOS {
+	return GuestOS{
+		VboxOSType: info.VboxOSType,
+		VMwareID:   info.VMwareID,
+		Family:     info.Family.String(),
+		Legacy:     info.Legacy,
+		Is64Bit:    info.Is64Bit,
+	}
+}
@@ -0,0 +1,35 @@
+package vmwareify
+
+import (
+	"testing"
+)
+
+func TestLookupVMwareGuestOS(t *testing.T) {
+	guestOS, ok := LookupVMwareGuestOS("Windows10_64")
+	if !ok {
+		t.Fatal("Failed to find Windows10_64")
+	}
+
+	if guestOS.VMwareID != "windows9_64Guest" || guestOS.Family != "windows" || !guestOS.Is64Bit {
+		t.Fatalf("Got unexpected guest OS - %+v", guestOS)
+	}
+
+	_, ok = LookupVMwareGuestOS("junk")
+	if ok {
+		t.Fatal("Found guest OS for an unknown OS type")
+	}
+}
+
+func TestVMwareGuestOSes(t *testing.T) {
+	guestOSes := VMwareGuestOSes()
+	if len(guestOSes) == 0 {
+		t.Fatal("Expected at least one guest OS")
+	}
+
+	for _, guestOS := range guestOSes {
+		found, ok := LookupVMwareGuestOS(guestOS.VboxOSType)
+		if !ok || found != guestOS {
+			t.Fatalf("Lookup does not match table entry - %+v", guestOS)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package vmwareify
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// windowsDrivePath matches an absolute Windows file path that has been
+// converted to forward slashes (e.g., 'C:/VMs/disk1.vmdk').
+var windowsDrivePath = regexp.MustCompile(`^[A-Za-z]:/`)
+
+// normalizedHref is a References File whose href is normalized by a
+// conversion (see BasicConvertOptions.NormalizeHrefs).
+type normalizedHref struct {
+	id      string
+	href    string
+	newHref string
+}
+
+// normalizeHrefs converts the href of each local References File to a
+// relative, forward slash separated path, and updates the parsed .ovf to
+// use the new hrefs. If dir is non-empty, each file must exist relative to
+// it, and absolute paths inside it are made relative to it. Otherwise,
+// absolute paths are replaced by their last element.
+func normalizeHrefs(parsed *ovf.Ovf, dir string) ([]normalizedHref, error) {
+	var changes []normalizedHref
+
+	files := parsed.Envelope.References.Files
+	for i, file := range files {
+		if ovf.IsRemoteHref(file.Href) {
+			continue
+		}
+
+		newHref, err := normalizeHref(file.Href, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if newHref == file.Href {
+			continue
+		}
+
+		changes = append(changes, normalizedHref{
+			id:      file.Id,
+			href:    file.Href,
+			newHref: newHref,
+		})
+
+		files[i].Href = newHref
+	}
+
+	return changes, nil
+}
+
+// normalizeHref returns the relative, forward slash separated form of a
+// local href (see normalizeHrefs).
+func normalizeHref(href string, dir string) (string, error) {
+	slashed := strings.Replace(href, `\`, "/", -1)
+
+	var candidates []string
+	if strings.HasPrefix(slashed, "/") || windowsDrivePath.MatchString(slashed) {
+		if len(dir) > 0 {
+			relative, err := filepath.Rel(dir, filepath.FromSlash(slashed))
+			if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+				candidates = append(candidates, filepath.ToSlash(relative))
+			}
+		}
+
+		// The file was most likely exported on another system, and
+		// now accompanies the .ovf.
+		candidates = append(candidates, path.Base(slashed))
+	} else {
+		candidates = append(candidates, path.Clean(slashed))
+	}
+
+	if len(dir) == 0 {
+		return candidates[len(candidates)-1], nil
+	}
+
+	for _, candidate := range candidates {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(candidate)))
+		if err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", errors.New("referenced file '" + href + "' cannot be located relative to '" + dir + "'")
+}
+
+// normalizeHrefEdits returns the explained edits that set the href of
+// each normalized File, and updates the parsed .ovf to use the new hrefs.
+func normalizeHrefEdits(parsed *ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, error) {
+	if !options.NormalizeHrefs {
+		return nil, nil
+	}
+
+	changes, err := normalizeHrefs(parsed, options.ReferencesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []explainedFunc
+	for _, change := range changes {
+		edits = append(edits, explainedFunc{
+			f: ovf.SetFileHrefFunc(change.id, change.newHref),
+			reason: "the href '" + change.href + "' is normalized to a relative, forward slash separated " +
+				"path ('" + change.newHref + "')",
+		})
+	}
+
+	return edits, nil
+}
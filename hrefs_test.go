@@ -0,0 +1,83 @@
+package vmwareify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHref(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(dir, "disks"), 0755)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, name := range []string{"disk1.vmdk", "disks/disk2.vmdk"} {
+		err = os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), nil, 0600)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	tests := []struct {
+		href     string
+		dir      string
+		expected string
+	}{
+		{href: "disk1.vmdk", dir: dir, expected: "disk1.vmdk"},
+		{href: `disks\disk2.vmdk`, dir: dir, expected: "disks/disk2.vmdk"},
+		{href: "./disks//disk2.vmdk", dir: dir, expected: "disks/disk2.vmdk"},
+		{href: filepath.ToSlash(filepath.Join(dir, "disks", "disk2.vmdk")), dir: dir, expected: "disks/disk2.vmdk"},
+		{href: `C:\Users\someone\VirtualBox VMs\centos\disk1.vmdk`, dir: dir, expected: "disk1.vmdk"},
+		{href: "/home/someone/exports/disk1.vmdk", dir: dir, expected: "disk1.vmdk"},
+		{href: `C:\Users\someone\disk3.vmdk`, expected: "disk3.vmdk"},
+		{href: `disks\disk3.vmdk`, expected: "disks/disk3.vmdk"},
+	}
+
+	for _, test := range tests {
+		href, err := normalizeHref(test.href, test.dir)
+		if err != nil {
+			t.Fatal("'" + test.href + "' - " + err.Error())
+		}
+
+		if href != test.expected {
+			t.Fatalf("'%s' was normalized to '%s' - expected '%s'", test.href, href, test.expected)
+		}
+	}
+
+	for _, href := range []string{"disk3.vmdk", `C:\Users\someone\disk3.vmdk`} {
+		_, err = normalizeHref(href, dir)
+		if err == nil {
+			t.Fatal("expected an error for '" + href + "', which does not exist")
+		}
+	}
+}
+
+func TestBasicConvertNormalizeHrefs(t *testing.T) {
+	existing := strings.Replace(basicOvfFileContents,
+		`ovf:href="centos-0.0.1-disk001.vmdk"`,
+		`ovf:href="C:\Users\someone\centos-0.0.1-disk001.vmdk"`, 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(existing), BasicConvertOptions{
+		NormalizeHrefs: true,
+		DiskFileSuffix: "-vmware",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), `<File ovf:id="file1" ovf:href="centos-0.0.1-disk001-vmware.vmdk"/>`) {
+		t.Fatalf("href was not normalized and renamed - got:\n%s", b.String())
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(existing), BasicConvertOptions{
+		NormalizeHrefs: true,
+		ReferencesDir:  t.TempDir(),
+	})
+	if err == nil {
+		t.Fatal("expected an error when the referenced file cannot be located")
+	}
+}
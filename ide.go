@@ -0,0 +1,132 @@
+package vmwareify
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// esxiIdeControllerSubType is the IDE controller model that ESXi
+// supports.
+const esxiIdeControllerSubType = "PIIX4"
+
+// ideControllerMatch matches IDE controllers. Controllers are identified
+// by their resource type if their names have been localized.
+var ideControllerMatch = ovf.ItemMatch{
+	NamePrefix:   "ideController",
+	Description:  "IDE Controller",
+	ResourceType: ovf.IdeControllerResourceType,
+}
+
+// ideDevices returns the InstanceIDs of the IDE controllers, and the
+// devices attached to them.
+func ideDevices(items []ovf.Item) ([]string, []ovf.Item) {
+	isController := make(map[string]bool)
+	var controllers []string
+	for _, item := range items {
+		if ideControllerMatch.Matches(item) {
+			isController[item.InstanceID] = true
+			controllers = append(controllers, item.InstanceID)
+		}
+	}
+
+	var devices []ovf.Item
+	for _, item := range items {
+		if len(item.Parent) > 0 && isController[item.Parent] {
+			devices = append(devices, item)
+		}
+	}
+
+	return controllers, devices
+}
+
+// warnIdeDevices reports a warning if devices are attached to the IDE
+// controllers that will be removed.
+func warnIdeDevices(items []ovf.Item, options BasicConvertOptions) {
+	_, devices := ideDevices(items)
+	if len(devices) == 0 {
+		return
+	}
+
+	var names []string
+	for _, device := range devices {
+		names = append(names, "'"+device.ElementName+"'")
+	}
+
+	options.warn("the IDE controllers are removed, but devices are attached to them (" +
+		strings.Join(names, ", ") + ") - migrate the devices to SATA so that they are not lost")
+}
+
+// migrateIdeDevices attaches the devices of the IDE controllers to a SATA
+// controller, adding one if the .ovf does not have one. The IDE
+// controllers are left for the conversion to remove.
+func migrateIdeDevices(raw []byte, recorder *editRecorder) ([]byte, error) {
+	parsed, err := toOvf(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+
+	controllers, devices := ideDevices(items)
+	if len(devices) == 0 {
+		return raw, nil
+	}
+
+	var sataController string
+	for _, item := range items {
+		if item.ResourceType == ovf.SataControllerResourceType &&
+			!strings.Contains(strings.ToLower(item.ResourceSubType), "nvme") {
+			sataController = item.InstanceID
+			break
+		}
+	}
+
+	if len(sataController) == 0 {
+		sataController = nextInstanceID(items)
+
+		buff, err := editRawOvf(bytes.NewReader(raw), ovf.NewEditScheme().
+			Propose(recorder.explain(ovf.AddHardwareItemFunc(ovf.Item{
+				Address:         "0",
+				Caption:         "sataController0",
+				Description:     "SATA Controller",
+				ElementName:     "sataController0",
+				InstanceID:      sataController,
+				ResourceSubType: "AHCI",
+				ResourceType:    ovf.SataControllerResourceType,
+			}), "a SATA controller is added for the devices attached to the IDE controllers"),
+				ovf.VirtualHardwareSectionName))
+		if err != nil {
+			return nil, err
+		}
+
+		raw = buff.Bytes()
+	}
+
+	buff, err := editRawOvf(bytes.NewReader(raw), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.MoveStorageDevicesFunc(controllers, sataController),
+			"devices attached to the IDE controllers are moved to the SATA controller (InstanceID "+
+				sataController+")"),
+			ovf.VirtualHardwareItemName))
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// nextInstanceID returns an InstanceID that is greater than the InstanceID
+// of every Item.
+func nextInstanceID(items []ovf.Item) string {
+	maxInstanceID := 0
+	for _, item := range items {
+		instanceID, err := strconv.Atoi(item.InstanceID)
+		if err == nil && instanceID > maxInstanceID {
+			maxInstanceID = instanceID
+		}
+	}
+
+	return strconv.Itoa(maxInstanceID + 1)
+}
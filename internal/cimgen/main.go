@@ -0,0 +1,259 @@
+// Command cimgen generates the Go bindings of the DMTF CIM classes used by
+// OVF descriptors (e.g., the Item's CIM_ResourceAllocationSettingData) from
+// their XML schemas. It is run using 'go generate' in the ovf package.
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+// binding describes a Go struct generated from a CIM class.
+type binding struct {
+	// schemaPath is the path to the class's XML schema.
+	schemaPath string
+
+	// typeName is the name of the Go struct (e.g., 'Item').
+	typeName string
+
+	// prefix is the namespace prefix of the class's elements when
+	// they are marshalled (e.g., 'rasd').
+	prefix string
+
+	// elementsVar is the name of the generated map of the class's
+	// element names.
+	elementsVar string
+
+	// fieldNames maps element names to the names of their fields,
+	// for fields whose names predate the generated bindings.
+	fieldNames map[string]string
+
+	// types maps the schema types of elements to Go types. Other
+	// types are strings.
+	types map[string]string
+}
+
+// element is an element of a CIM class.
+type element struct {
+	name     string
+	goType   string
+	required bool
+	multiple bool
+}
+
+type schema struct {
+	Elements     []schemaElement     `xml:"element"`
+	ComplexTypes []schemaComplexType `xml:"complexType"`
+}
+
+type schemaElement struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Ref       string `xml:"ref,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+}
+
+type schemaComplexType struct {
+	Name     string          `xml:"name,attr"`
+	Sequence []schemaElement `xml:"sequence>element"`
+}
+
+func main() {
+	rasdPath := flag.String("rasd", "", "The path to the CIM_ResourceAllocationSettingData schema")
+	vssdPath := flag.String("vssd", "", "The path to the CIM_VirtualSystemSettingData schema")
+	outputPath := flag.String("o", "", "The path to write the generated Go file to")
+
+	flag.Parse()
+
+	if len(*rasdPath) == 0 || len(*vssdPath) == 0 || len(*outputPath) == 0 {
+		log.Fatalln("please specify -rasd, -vssd, and -o")
+	}
+
+	bindings := []binding{
+		{
+			schemaPath:  *vssdPath,
+			typeName:    "System",
+			prefix:      "vssd",
+			elementsVar: "systemElements",
+			fieldNames:  map[string]string{"InstanceID": "InstanceId"},
+		},
+		{
+			schemaPath:  *rasdPath,
+			typeName:    "Item",
+			prefix:      "rasd",
+			elementsVar: "itemElements",
+			types:       map[string]string{"cim:cimBoolean": "bool"},
+		},
+	}
+
+	b := bytes.NewBuffer(nil)
+	b.WriteString("// Code generated by cimgen from the DMTF CIM schemas. DO NOT EDIT.\n\n")
+	b.WriteString("package ovf\n\nimport (\n\t\"encoding/xml\"\n)\n")
+
+	for _, binding := range bindings {
+		elements, err := readElements(binding)
+		if err != nil {
+			log.Fatalln("failed to read schema '" + binding.schemaPath + "' - " + err.Error())
+		}
+
+		writeBinding(b, binding, elements)
+	}
+
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalln("failed to format generated code - " + err.Error())
+	}
+
+	err = os.WriteFile(*outputPath, formatted, 0644)
+	if err != nil {
+		log.Fatalln("failed to write generated code - " + err.Error())
+	}
+}
+
+// readElements returns the elements of the CIM class described by a
+// schema, in the order of the class's sequence.
+func readElements(binding binding) ([]element, error) {
+	raw, err := os.ReadFile(binding.schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var s schema
+	err = xml.Unmarshal(raw, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string)
+	for _, e := range s.Elements {
+		types[e.Name] = e.Type
+	}
+
+	var sequence []schemaElement
+	for _, complexType := range s.ComplexTypes {
+		if strings.HasPrefix(complexType.Name, "CIM_") && strings.HasSuffix(complexType.Name, "_Type") {
+			sequence = complexType.Sequence
+			break
+		}
+	}
+
+	if len(sequence) == 0 {
+		return nil, errors.New("the schema does not contain a CIM class type")
+	}
+
+	var elements []element
+	for _, ref := range sequence {
+		name := ref.Ref[strings.IndexByte(ref.Ref, ':')+1:]
+		schemaType, ok := types[name]
+		if !ok {
+			return nil, errors.New("the element '" + ref.Ref + "' is not declared")
+		}
+
+		goType, ok := binding.types[schemaType]
+		if !ok {
+			goType = "string"
+		}
+
+		elements = append(elements, element{
+			name:     name,
+			goType:   goType,
+			required: ref.MinOccurs != "0",
+			multiple: ref.MaxOccurs == "unbounded",
+		})
+	}
+
+	return elements, nil
+}
+
+// writeComment writes a comment, wrapping its words at commentWidth.
+func writeComment(b *bytes.Buffer, text string) {
+	const commentWidth = 72
+
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > commentWidth {
+			b.WriteString(line + "\n")
+			line = "//"
+		}
+
+		line = line + " " + word
+	}
+
+	b.WriteString(line + "\n")
+}
+
+func writeBinding(b *bytes.Buffer, binding binding, elements []element) {
+	fieldName := func(e element) string {
+		name, ok := binding.fieldNames[e.name]
+		if ok {
+			return name
+		}
+
+		return e.name
+	}
+
+	var multiple []string
+	for _, e := range elements {
+		if e.multiple {
+			multiple = append(multiple, "'"+e.name+"'")
+		}
+	}
+
+	doc := binding.typeName + " contains the " + binding.prefix + " elements of the '" +
+		binding.typeName + "' element."
+	if len(multiple) > 0 {
+		doc = doc + " Only the first value of an element that may occur more than once (" +
+			strings.Join(multiple, " and ") + ") is kept."
+	}
+
+	b.WriteString("\n")
+	writeComment(b, doc)
+
+	b.WriteString("type " + binding.typeName + " struct {\n")
+	b.WriteString("\tXMLName xml.Name `xml:\"" + binding.typeName + "\"`\n")
+	for _, e := range elements {
+		b.WriteString("\t" + fieldName(e) + " " + e.goType + " `xml:\"" + e.name + "\"`\n")
+	}
+	b.WriteString("}\n")
+
+	b.WriteString("\n// TODO: Hack for https://github.com/golang/go/issues/9519.\n")
+	b.WriteString("func (o *" + binding.typeName + ") Marshallable() interface{} {\n")
+	b.WriteString("\treturn marshable" + binding.typeName + "{\n")
+	for _, e := range elements {
+		b.WriteString("\t\t" + fieldName(e) + ": o." + fieldName(e) + ",\n")
+	}
+	b.WriteString("\t}\n}\n")
+
+	b.WriteString("\n// TODO: Hack for https://github.com/golang/go/issues/9519.\n")
+	b.WriteString("type marshable" + binding.typeName + " struct {\n")
+	b.WriteString("\tXMLName xml.Name `xml:\"" + binding.typeName + "\"`\n")
+	for _, e := range elements {
+		tag := binding.prefix + ":" + e.name
+		if !e.required {
+			tag = tag + ",omitempty"
+		}
+
+		b.WriteString("\t" + fieldName(e) + " " + e.goType + " `xml:\"" + tag + "\"`\n")
+	}
+	b.WriteString("}\n")
+
+	b.WriteString("\n")
+	writeComment(b, binding.elementsVar+" are the local names of the elements modeled by "+
+		binding.typeName+". An element's value is true if it may occur more than once.")
+	b.WriteString("var " + binding.elementsVar + " = map[string]bool{\n")
+	for _, e := range elements {
+		if e.multiple {
+			b.WriteString("\t\"" + e.name + "\": true,\n")
+		} else {
+			b.WriteString("\t\"" + e.name + "\": false,\n")
+		}
+	}
+	b.WriteString("}\n")
+}
@@ -0,0 +1,3 @@
+// Package guestos maps VirtualBox guest operating system types to their
+// VMWare equivalents.
+package guestos
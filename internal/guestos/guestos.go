@@ -0,0 +1,145 @@
+package guestos
+
+import (
+	"strings"
+)
+
+const (
+	Windows Family = "windows"
+	Linux   Family = "linux"
+	Bsd     Family = "bsd"
+	Solaris Family = "solaris"
+	Dos     Family = "dos"
+	Other   Family = "other"
+)
+
+// Family represents a family of guest operating systems.
+type Family string
+
+func (o Family) String() string {
+	return string(o)
+}
+
+// Info describes a guest operating system known to VirtualBox, and its
+// VMWare equivalent.
+type Info struct {
+	// VboxOSType is the VirtualBox OS type identifier
+	// (e.g., 'RedHat_64').
+	VboxOSType string
+
+	// VMwareID is the VMWare guest OS identifier
+	// (e.g., 'rhel7_64Guest').
+	VMwareID string
+
+	// Family is the operating system's family.
+	Family Family
+
+	// Legacy is true if the operating system predates the modern
+	// VMWare virtual hardware (e.g., it lacks e1000e drivers).
+	Legacy bool
+
+	// Is64Bit is true if the operating system is 64-bit.
+	Is64Bit bool
+}
+
+// table maps VirtualBox OS types (without the '_64' suffix) to their
+// VMWare equivalent. The second VMWare ID is used for 64-bit variants.
+var table = []struct {
+	vboxOSType string
+	vmware32   string
+	vmware64   string
+	family     Family
+	legacy     bool
+}{
+	{"WindowsNT4", "winNTGuest", "winNTGuest", Windows, true},
+	{"Windows2000", "win2000ProGuest", "win2000ProGuest", Windows, true},
+	{"WindowsXP", "winXPProGuest", "winXPPro64Guest", Windows, true},
+	{"Windows2003", "winNetStandardGuest", "winNetStandard64Guest", Windows, true},
+	{"WindowsVista", "winVistaGuest", "winVista64Guest", Windows, false},
+	{"Windows2008", "winLonghornGuest", "winLonghorn64Guest", Windows, false},
+	{"Windows7", "windows7Guest", "windows7_64Guest", Windows, false},
+	{"Windows8", "windows8Guest", "windows8_64Guest", Windows, false},
+	{"Windows81", "windows8Guest", "windows8_64Guest", Windows, false},
+	{"Windows2012", "windows8Server64Guest", "windows8Server64Guest", Windows, false},
+	{"Windows10", "windows9Guest", "windows9_64Guest", Windows, false},
+	{"Windows2016", "windows9Server64Guest", "windows9Server64Guest", Windows, false},
+	{"Windows2019", "windows2019srv_64Guest", "windows2019srv_64Guest", Windows, false},
+	{"Windows2022", "windows2019srvNext_64Guest", "windows2019srvNext_64Guest", Windows, false},
+	{"Windows11", "windows11_64Guest", "windows11_64Guest", Windows, false},
+	{"Linux22", "otherLinuxGuest", "otherLinux64Guest", Linux, true},
+	{"Linux24", "other24xLinuxGuest", "other24xLinux64Guest", Linux, true},
+	{"Linux26", "other26xLinuxGuest", "other26xLinux64Guest", Linux, false},
+	{"ArchLinux", "other3xLinuxGuest", "other3xLinux64Guest", Linux, false},
+	{"Debian", "debian10Guest", "debian10_64Guest", Linux, false},
+	{"Fedora", "fedoraGuest", "fedora64Guest", Linux, false},
+	{"Gentoo", "other3xLinuxGuest", "other3xLinux64Guest", Linux, false},
+	{"Mandriva", "mandrivaGuest", "mandriva64Guest", Linux, true},
+	{"OpenSUSE", "opensuseGuest", "opensuse64Guest", Linux, false},
+	{"Oracle", "oracleLinuxGuest", "oracleLinux64Guest", Linux, false},
+	{"RedHat", "rhel7Guest", "rhel7_64Guest", Linux, false},
+	{"Turbolinux", "turboLinuxGuest", "turboLinux64Guest", Linux, true},
+	{"Ubuntu", "ubuntuGuest", "ubuntu64Guest", Linux, false},
+	{"Linux", "otherLinuxGuest", "otherLinux64Guest", Linux, false},
+	{"FreeBSD", "freebsdGuest", "freebsd64Guest", Bsd, false},
+	{"OpenBSD", "otherGuest", "otherGuest64", Bsd, false},
+	{"NetBSD", "otherGuest", "otherGuest64", Bsd, false},
+	{"Solaris", "solaris10Guest", "solaris10_64Guest", Solaris, false},
+	{"Solaris11", "solaris11_64Guest", "solaris11_64Guest", Solaris, false},
+	{"OpenSolaris", "solaris11_64Guest", "solaris11_64Guest", Solaris, false},
+	{"DOS", "dosGuest", "dosGuest", Dos, true},
+	{"Other", "otherGuest", "otherGuest64", Other, false},
+}
+
+// Lookup returns the Info for the specified VirtualBox OS type. The
+// lookup is case-insensitive.
+func Lookup(vboxOSType string) (Info, bool) {
+	base := vboxOSType
+	is64Bit := false
+	if strings.HasSuffix(strings.ToLower(base), "_64") {
+		base = base[:len(base)-len("_64")]
+		is64Bit = true
+	}
+
+	for _, entry := range table {
+		if !strings.EqualFold(entry.vboxOSType, base) {
+			continue
+		}
+
+		info := Info{
+			VboxOSType: vboxOSType,
+			VMwareID:   entry.vmware32,
+			Family:     entry.family,
+			Legacy:     entry.legacy,
+			Is64Bit:    is64Bit,
+		}
+
+		if is64Bit {
+			info.VMwareID = entry.vmware64
+		}
+
+		return info, true
+	}
+
+	return Info{}, false
+}
+
+// All returns the Info of every known VirtualBox OS type, including
+// 64-bit variants. Variants that VMWare does not support (such as a
+// 32-bit Windows 11) are omitted.
+func All() []Info {
+	var infos []Info
+
+	for _, entry := range table {
+		if !strings.Contains(entry.vmware32, "64") {
+			info, _ := Lookup(entry.vboxOSType)
+			infos = append(infos, info)
+		}
+
+		if strings.Contains(entry.vmware64, "64") {
+			info, _ := Lookup(entry.vboxOSType + "_64")
+			infos = append(infos, info)
+		}
+	}
+
+	return infos
+}
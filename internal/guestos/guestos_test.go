@@ -0,0 +1,38 @@
+package guestos
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	info, ok := Lookup("RedHat_64")
+	if !ok {
+		t.Fatal("Failed to find RedHat_64")
+	}
+
+	if info.VMwareID != "rhel7_64Guest" || info.Family != Linux || !info.Is64Bit {
+		t.Fatalf("Got unexpected info - %+v", info)
+	}
+
+	info, ok = Lookup("windowsxp")
+	if !ok {
+		t.Fatal("Failed to find windowsxp")
+	}
+
+	if info.VMwareID != "winXPProGuest" || info.Family != Windows || !info.Legacy || info.Is64Bit {
+		t.Fatalf("Got unexpected info - %+v", info)
+	}
+
+	_, ok = Lookup("Junk_64")
+	if ok {
+		t.Fatal("Found info for an unknown OS type")
+	}
+}
+
+func TestAll(t *testing.T) {
+	for _, info := range All() {
+		if len(info.VMwareID) == 0 || len(info.Family) == 0 {
+			t.Fatalf("Got incomplete info - %+v", info)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package version describes the version of vmwareify that is running,
+// using the build information that the Go toolchain embeds in binaries.
+package version
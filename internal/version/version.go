@@ -0,0 +1,117 @@
+package version
+
+import (
+	"runtime/debug"
+)
+
+const (
+	// Devel is the version of a build whose version is unknown
+	// (e.g., one built from a working copy of the repository).
+	Devel = "devel"
+
+	modulePath = "github.com/stephen-fox/vmwareify"
+
+	// commitLength is the number of characters of a commit that
+	// are included in Info.String.
+	commitLength = 12
+)
+
+// Info describes the version of vmwareify that is running.
+type Info struct {
+	// Version is the version of the module (e.g., 'v1.2.3'), or
+	// Devel if it is unknown.
+	Version string
+
+	// Commit is the VCS revision that the binary was built from.
+	// It is empty if it is unknown, which is the case when
+	// vmwareify is used as a library.
+	Commit string
+
+	// Modified is true if the binary was built from a working
+	// copy with uncommitted changes.
+	Modified bool
+}
+
+// String returns the version, followed by the abbreviated commit if it
+// is known (e.g., 'v1.2.3 (commit 0123456789ab)').
+func (o Info) String() string {
+	if len(o.Commit) == 0 {
+		return o.Version
+	}
+
+	commit := o.Commit
+	if len(commit) > commitLength {
+		commit = commit[:commitLength]
+	}
+
+	if o.Modified {
+		return o.Version + " (commit " + commit + ", modified)"
+	}
+
+	return o.Version + " (commit " + commit + ")"
+}
+
+// Get returns the Info of the running binary. The override is used as
+// the version if it is not empty or Devel, which allows the version to
+// be set when building (see vmwareify.Version).
+func Get(override string) Info {
+	info := Info{Version: Devel}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if ok {
+		info = fromBuildInfo(buildInfo)
+	}
+
+	if len(override) > 0 && override != Devel {
+		info.Version = override
+	}
+
+	return info
+}
+
+// fromBuildInfo returns the Info of vmwareify in a binary's build
+// information. vmwareify is either the binary's main module, or one of
+// its dependencies.
+func fromBuildInfo(buildInfo *debug.BuildInfo) Info {
+	info := Info{Version: Devel}
+
+	module := &buildInfo.Main
+	isMain := module.Path == modulePath
+	if !isMain {
+		module = nil
+		for _, dep := range buildInfo.Deps {
+			if dep.Path == modulePath {
+				module = dep
+				break
+			}
+		}
+	}
+
+	if module == nil {
+		return info
+	}
+
+	if module.Replace != nil {
+		module = module.Replace
+	}
+
+	// Binaries built from a working copy report '(devel)'.
+	if len(module.Version) > 0 && module.Version != "(devel)" {
+		info.Version = module.Version
+	}
+
+	if !isMain {
+		return info
+	}
+
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,85 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		buildInfo debug.BuildInfo
+		expected  string
+	}{
+		{
+			name: "main-module",
+			buildInfo: debug.BuildInfo{
+				Main: debug.Module{Path: modulePath, Version: "v1.2.3"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef0123456789abcdef01234567"},
+					{Key: "vcs.modified", Value: "true"},
+				},
+			},
+			expected: "v1.2.3 (commit 0123456789ab, modified)",
+		},
+		{
+			name: "working-copy",
+			buildInfo: debug.BuildInfo{
+				Main: debug.Module{Path: modulePath, Version: "(devel)"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.modified", Value: "false"},
+				},
+			},
+			expected: Devel + " (commit 0123456789ab)",
+		},
+		{
+			name: "dependency",
+			buildInfo: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app", Version: "v0.1.0"},
+				Deps: []*debug.Module{
+					{Path: "example.com/other", Version: "v2.0.0"},
+					{Path: modulePath, Version: "v1.4.0"},
+				},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+				},
+			},
+			expected: "v1.4.0",
+		},
+		{
+			name: "replaced-dependency",
+			buildInfo: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+				Deps: []*debug.Module{
+					{Path: modulePath, Version: "v1.4.0", Replace: &debug.Module{Path: "../vmwareify"}},
+				},
+			},
+			expected: Devel,
+		},
+		{
+			name: "unknown",
+			buildInfo: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+			},
+			expected: Devel,
+		},
+	}
+
+	for _, test := range tests {
+		info := fromBuildInfo(&test.buildInfo)
+		if info.String() != test.expected {
+			t.Fatalf("%s - got '%s', expected '%s'", test.name, info.String(), test.expected)
+		}
+	}
+}
+
+func TestGetOverride(t *testing.T) {
+	if Get("1.2.3").Version != "1.2.3" {
+		t.Fatal("The override was not used as the version")
+	}
+
+	if Get(Devel).Version == "" || Get("").Version == "" {
+		t.Fatal("The version should never be empty")
+	}
+}
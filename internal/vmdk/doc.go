@@ -0,0 +1,3 @@
+// Package vmdk reads the metadata of VMDK sparse extents, which is used to
+// determine how much of a virtual disk contains data.
+package vmdk
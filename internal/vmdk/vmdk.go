@@ -0,0 +1,205 @@
+package vmdk
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// SectorSize is the size of a VMDK sector in bytes.
+	SectorSize = 512
+
+	// sparseMagic is the magic number of a sparse extent ('KDMV').
+	sparseMagic = 0x564d444b
+
+	// markersFlag is set in the header of an extent that contains
+	// markers (i.e., a stream-optimized extent).
+	markersFlag = 1 << 17
+
+	// eosMarker is the type of the marker at the end of a
+	// stream-optimized extent.
+	eosMarker = 0
+
+	// zeroGrainEntry is a grain table entry that refers to a grain
+	// of zeros, which is not allocated.
+	zeroGrainEntry = 1
+
+	headerSize = SectorSize
+)
+
+// ErrNotSparse is returned when a file is not a VMDK sparse extent (e.g.,
+// a flat extent or a different disk format).
+var ErrNotSparse = errors.New("file is not a VMDK sparse extent")
+
+// header is a sparse extent header.
+type header struct {
+	flags        uint32
+	capacity     uint64
+	grainSize    uint64
+	numGTEsPerGT uint32
+	gdOffset     uint64
+	overhead     uint64
+}
+
+func readHeader(r io.ReaderAt, offset int64) (header, error) {
+	b := make([]byte, headerSize)
+
+	_, err := r.ReadAt(b, offset)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return header{}, ErrNotSparse
+		}
+
+		return header{}, err
+	}
+
+	if binary.LittleEndian.Uint32(b[0:4]) != sparseMagic {
+		return header{}, ErrNotSparse
+	}
+
+	h := header{
+		flags:        binary.LittleEndian.Uint32(b[8:12]),
+		capacity:     binary.LittleEndian.Uint64(b[12:20]),
+		grainSize:    binary.LittleEndian.Uint64(b[20:28]),
+		numGTEsPerGT: binary.LittleEndian.Uint32(b[44:48]),
+		gdOffset:     binary.LittleEndian.Uint64(b[56:64]),
+		overhead:     binary.LittleEndian.Uint64(b[64:72]),
+	}
+
+	if h.grainSize == 0 || h.numGTEsPerGT == 0 {
+		return header{}, errors.New("VMDK sparse extent header is invalid")
+	}
+
+	return h, nil
+}
+
+// Capacity returns the capacity of a VMDK sparse extent in bytes.
+// ErrNotSparse is returned if the file is not a sparse extent.
+func Capacity(r io.ReaderAt) (int64, error) {
+	h, err := readHeader(r, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if h.capacity > math.MaxInt64/SectorSize {
+		return 0, errors.New("VMDK sparse extent capacity is too large")
+	}
+
+	return int64(h.capacity * SectorSize), nil
+}
+
+// PopulatedSize returns the number of bytes of a VMDK sparse extent's
+// capacity that contain data (i.e., the size of its allocated grains).
+// size is the size of the file in bytes. ErrNotSparse is returned if the
+// file is not a sparse extent.
+func PopulatedSize(r io.ReaderAt, size int64) (int64, error) {
+	h, err := readHeader(r, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if h.flags&markersFlag != 0 {
+		return streamPopulatedSize(r, size, h)
+	}
+
+	return grainTablePopulatedSize(r, size, h)
+}
+
+// grainTablePopulatedSize counts the allocated grains of a sparse extent
+// using its grain directory and grain tables.
+func grainTablePopulatedSize(r io.ReaderAt, size int64, h header) (int64, error) {
+	gtCoverage := h.grainSize * uint64(h.numGTEsPerGT)
+	numGDEs := (h.capacity + gtCoverage - 1) / gtCoverage
+
+	// The header is not trusted to size the allocations.
+	if numGDEs*4 > uint64(size) || uint64(h.numGTEsPerGT)*4 > uint64(size) {
+		return 0, errors.New("VMDK grain directory is larger than the file")
+	}
+
+	gd := make([]byte, numGDEs*4)
+	_, err := r.ReadAt(gd, int64(h.gdOffset*SectorSize))
+	if err != nil {
+		return 0, errors.New("failed to read VMDK grain directory - " + err.Error())
+	}
+
+	var populated uint64
+	gt := make([]byte, h.numGTEsPerGT*4)
+
+	for i := uint64(0); i < numGDEs; i++ {
+		gtOffset := binary.LittleEndian.Uint32(gd[i*4:])
+		if gtOffset == 0 {
+			continue
+		}
+
+		_, err := r.ReadAt(gt, int64(gtOffset)*SectorSize)
+		if err != nil {
+			return 0, errors.New("failed to read VMDK grain table - " + err.Error())
+		}
+
+		for j := uint64(0); j < uint64(h.numGTEsPerGT); j++ {
+			entry := binary.LittleEndian.Uint32(gt[j*4:])
+			if entry == 0 || entry == zeroGrainEntry {
+				continue
+			}
+
+			populated += grainSectors(h, (i*uint64(h.numGTEsPerGT)+j)*h.grainSize)
+		}
+	}
+
+	return int64(populated * SectorSize), nil
+}
+
+// streamPopulatedSize counts the grains of a stream-optimized extent by
+// reading its markers.
+func streamPopulatedSize(r io.ReaderAt, size int64, h header) (int64, error) {
+	var populated uint64
+	marker := make([]byte, 16)
+	offset := int64(h.overhead * SectorSize)
+
+	for offset < size {
+		_, err := r.ReadAt(marker, offset)
+		if err != nil {
+			return 0, errors.New("failed to read VMDK marker - " + err.Error())
+		}
+
+		value := binary.LittleEndian.Uint64(marker[0:8])
+		dataSize := binary.LittleEndian.Uint32(marker[8:12])
+
+		if dataSize > 0 {
+			// A grain marker's value is its LBA.
+			populated += grainSectors(h, value)
+			offset += roundUpToSector(12 + int64(dataSize))
+			continue
+		}
+
+		if binary.LittleEndian.Uint32(marker[12:16]) == eosMarker {
+			break
+		}
+
+		// A metadata marker's value is the number of sectors of
+		// metadata that follow it.
+		offset += int64(SectorSize + value*SectorSize)
+	}
+
+	return int64(populated * SectorSize), nil
+}
+
+// grainSectors returns the number of sectors of the grain at the
+// specified LBA that are inside the extent's capacity.
+func grainSectors(h header, lba uint64) uint64 {
+	if lba >= h.capacity {
+		return 0
+	}
+
+	if h.capacity-lba < h.grainSize {
+		return h.capacity - lba
+	}
+
+	return h.grainSize
+}
+
+func roundUpToSector(n int64) int64 {
+	return (n + SectorSize - 1) / SectorSize * SectorSize
+}
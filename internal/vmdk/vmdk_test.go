@@ -0,0 +1,119 @@
+package vmdk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func testHeader(flags uint32, capacity uint64, grainSize uint64, numGTEsPerGT uint32, gdOffset uint64, overhead uint64) []byte {
+	b := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(b[0:], sparseMagic)
+	binary.LittleEndian.PutUint32(b[4:], 1)
+	binary.LittleEndian.PutUint32(b[8:], flags)
+	binary.LittleEndian.PutUint64(b[12:], capacity)
+	binary.LittleEndian.PutUint64(b[20:], grainSize)
+	binary.LittleEndian.PutUint32(b[44:], numGTEsPerGT)
+	binary.LittleEndian.PutUint64(b[56:], gdOffset)
+	binary.LittleEndian.PutUint64(b[64:], overhead)
+	return b
+}
+
+func TestPopulatedSizeGrainTables(t *testing.T) {
+	// 2 grain tables of 4 entries, each grain is 8 sectors, and the
+	// last grain is only partially inside the capacity.
+	const capacity = 60
+
+	raw := make([]byte, 8*SectorSize)
+	copy(raw, testHeader(0, capacity, 8, 4, 1, 8))
+
+	// Grain directory at sector 1, and grain tables at sectors 2
+	// and 3.
+	binary.LittleEndian.PutUint32(raw[1*SectorSize:], 2)
+	binary.LittleEndian.PutUint32(raw[1*SectorSize+4:], 3)
+
+	gt0 := raw[2*SectorSize:]
+	binary.LittleEndian.PutUint32(gt0[0:], 100)
+	binary.LittleEndian.PutUint32(gt0[4:], zeroGrainEntry)
+	binary.LittleEndian.PutUint32(gt0[12:], 200)
+
+	gt1 := raw[3*SectorSize:]
+	binary.LittleEndian.PutUint32(gt1[12:], 300)
+
+	populated, err := PopulatedSize(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := int64((8 + 8 + 4) * SectorSize)
+	if populated != expected {
+		t.Fatalf("got populated size %d - expected %d", populated, expected)
+	}
+}
+
+func TestPopulatedSizeStreamOptimized(t *testing.T) {
+	buff := bytes.NewBuffer(testHeader(markersFlag|1<<16, 128, 16, 512, 0xffffffffffffffff, 1))
+
+	grain := func(lba uint64, dataSize uint32) {
+		b := make([]byte, roundUpToSector(12+int64(dataSize)))
+		binary.LittleEndian.PutUint64(b[0:], lba)
+		binary.LittleEndian.PutUint32(b[8:], dataSize)
+		buff.Write(b)
+	}
+
+	metadata := func(sectors uint64, markerType uint32) {
+		b := make([]byte, SectorSize+sectors*SectorSize)
+		binary.LittleEndian.PutUint64(b[0:], sectors)
+		binary.LittleEndian.PutUint32(b[12:], markerType)
+		buff.Write(b)
+	}
+
+	grain(0, 100)
+	grain(32, 700)
+	metadata(1, 1)
+	grain(112, 10)
+	metadata(0, eosMarker)
+
+	// Data after the end-of-stream marker is ignored.
+	grain(48, 10)
+
+	raw := buff.Bytes()
+
+	populated, err := PopulatedSize(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := int64(3 * 16 * SectorSize)
+	if populated != expected {
+		t.Fatalf("got populated size %d - expected %d", populated, expected)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	raw := testHeader(0, 2048, 128, 512, 1, 8)
+
+	capacity, err := Capacity(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if capacity != 2048*SectorSize {
+		t.Fatalf("got capacity %d", capacity)
+	}
+
+	_, err = Capacity(bytes.NewReader([]byte("# Disk DescriptorFile")))
+	if !errors.Is(err, ErrNotSparse) {
+		t.Fatalf("expected ErrNotSparse - got %v", err)
+	}
+}
+
+func TestPopulatedSizeNotSparse(t *testing.T) {
+	for _, raw := range [][]byte{nil, []byte("# Disk DescriptorFile"), make([]byte, 4096)} {
+		_, err := PopulatedSize(bytes.NewReader(raw), int64(len(raw)))
+		if !errors.Is(err, ErrNotSparse) {
+			t.Fatalf("expected ErrNotSparse - got %v", err)
+		}
+	}
+}
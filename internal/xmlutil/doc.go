@@ -1,2 +0,0 @@
-// Package xmlutil provides helpful XML function extensions.
-package xmlutil
@@ -0,0 +1,52 @@
+package vmwareify
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// setIpAssignment adds a 'vmw:IpAssignmentSection' declaring the IP
+// protocols and IP assignment schemes chosen by
+// BasicConvertOptions.IpProtocols and IpAssignmentSchemes to an edited
+// .ovf, declaring the 'vmw' namespace if needed.
+func setIpAssignment(edited *bytes.Buffer, options BasicConvertOptions, recorder *editRecorder) (*bytes.Buffer, error) {
+	protocols := splitOptionList(options.IpProtocols)
+	schemes := splitOptionList(options.IpAssignmentSchemes)
+
+	f, err := ovf.SetIpAssignmentSectionFunc(protocols, schemes)
+	if err != nil {
+		return nil, err
+	}
+
+	buff, err := editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.DeclareNamespaceFunc(ovf.VmwPrefix, ovf.VmwNamespace),
+			"the '"+ovf.VmwPrefix+"' namespace is declared for the IP assignment section"), ovf.EnvelopeName))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(protocols) == 0 {
+		protocols = []string{ovf.Ipv4Protocol}
+	}
+
+	return editRawOvf(bytes.NewReader(buff.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(f, "the virtual machine declares support for the IP assignment schemes '"+
+			strings.Join(schemes, ",")+"' using '"+strings.Join(protocols, ",")+"' (vmw:IpAssignmentSection)"),
+			ovf.VirtualSystemName))
+}
+
+// splitOptionList splits a comma separated option, ignoring empty
+// entries.
+func splitOptionList(s string) []string {
+	var entries []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
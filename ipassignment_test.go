@@ -0,0 +1,39 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertIpAssignment(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		IpAssignmentSchemes: "dhcp, ovfenv",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	section := parsed.Envelope.VirtualSystem.IpAssignmentSection
+	if section.Protocols != ovf.Ipv4Protocol || section.Schemes != "dhcp,ovfenv" {
+		t.Fatalf("Got unexpected section - %+v", section)
+	}
+
+	if !strings.Contains(b.String(), `xmlns:vmw="`+ovf.VmwNamespace+`"`) {
+		t.Fatal("The 'vmw' namespace was not declared")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		IpAssignmentSchemes: "dhcp",
+		IpProtocols:         "IPX",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported protocol")
+	}
+}
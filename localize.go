@@ -0,0 +1,21 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// localize flattens the localization of the .ovf to the specified locale
+// (see BasicConvertOptions.Locale).
+func localize(raw []byte, locale string) ([]byte, error) {
+	localized := bytes.NewBuffer(nil)
+
+	err := ovf.Localize(bytes.NewReader(raw), localized, locale)
+	if err != nil {
+		return nil, errors.New("failed to localize the .ovf - " + err.Error())
+	}
+
+	return localized.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertLocale(t *testing.T) {
+	input := strings.NewReplacer(
+		"<Info>A virtual machine</Info>",
+		`<Info ovf:msgid="vm.info">A virtual machine</Info>`,
+		"</VirtualSystem>\n</Envelope>", `</VirtualSystem>
+  <Strings xml:lang="de-DE">
+    <Msg ovf:msgid="vm.info">Eine virtuelle Maschine</Msg>
+  </Strings>
+</Envelope>`).Replace(basicOvfFileContents)
+	if !strings.Contains(input, "<Strings") || !strings.Contains(input, `ovf:msgid="vm.info">A`) {
+		t.Fatal("Failed to localize the test input")
+	}
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), `<Info ovf:msgid="vm.info">A virtual machine</Info>`) ||
+		!strings.Contains(b.String(), `<Strings xml:lang="de-DE">`) {
+		t.Fatal("Expected the localization to be kept:\n" + b.String())
+	}
+
+	b, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{Locale: "de-DE"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), "<Info>Eine virtuelle Maschine</Info>") || strings.Contains(b.String(), "Strings") {
+		t.Fatal("Expected the localization to be flattened:\n" + b.String())
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{Locale: "fr-FR"})
+	if err == nil {
+		t.Fatal("Expected an error for a missing locale")
+	}
+}
@@ -0,0 +1,219 @@
+package vmwareify
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	// maxVramMegabytes is the most video memory that a VMWare SVGA
+	// adapter supports.
+	maxVramMegabytes = 256
+)
+
+// displayExtraConfigs maps the VirtualBox display settings to VMWare
+// .vmx settings. Settings that are not declared are left for VMWare
+// to choose.
+func displayExtraConfigs(display ovf.VboxDisplay) ([]vmwConfig, error) {
+	var configs []vmwConfig
+
+	if len(display.VRAMSize) > 0 {
+		megabytes, err := strconv.Atoi(display.VRAMSize)
+		if err != nil || megabytes < 1 {
+			return nil, errors.New("invalid VirtualBox video memory size '" + display.VRAMSize + "'")
+		}
+
+		if megabytes > maxVramMegabytes {
+			megabytes = maxVramMegabytes
+		}
+
+		// VMWare ignores the video memory size unless automatic
+		// detection is disabled.
+		configs = append(configs,
+			vmwConfig{extra: true, key: "svga.autodetect", value: "FALSE"},
+			vmwConfig{extra: true, key: "svga.vramSize", value: strconv.Itoa(megabytes * 1024 * 1024)})
+	}
+
+	if len(display.MonitorCount) > 0 {
+		count, err := strconv.Atoi(display.MonitorCount)
+		if err != nil || count < 1 {
+			return nil, errors.New("invalid VirtualBox monitor count '" + display.MonitorCount + "'")
+		}
+
+		configs = append(configs, vmwConfig{extra: true, key: "svga.numDisplays", value: strconv.Itoa(count)})
+	}
+
+	if strings.EqualFold(display.Accelerate3D, "true") {
+		configs = append(configs, vmwConfig{extra: true, key: "mks.enable3d", value: "TRUE"})
+	}
+
+	return configs, nil
+}
+
+// bootOrderExtraConfig maps the VirtualBox boot order to the VMWare
+// 'bios.bootOrder' .vmx setting. No setting is returned if the boot order
+// is not declared.
+func bootOrderExtraConfig(boot ovf.VboxBoot) ([]vmwConfig, error) {
+	type position struct {
+		index  int
+		device string
+	}
+
+	var positions []position
+	for _, order := range boot.Orders {
+		index, err := strconv.Atoi(order.Position)
+		if err != nil {
+			return nil, errors.New("invalid VirtualBox boot order position '" + order.Position + "'")
+		}
+
+		positions = append(positions, position{index: index, device: order.Device})
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].index < positions[j].index
+	})
+
+	var devices []string
+	for _, p := range positions {
+		switch strings.ToLower(p.device) {
+		case "none":
+			continue
+		case "harddisk":
+			devices = append(devices, "hdd")
+		case "dvd":
+			devices = append(devices, "cdrom")
+		case "floppy":
+			devices = append(devices, "floppy")
+		case "network":
+			devices = append(devices, "ethernet")
+		default:
+			return nil, errors.New("VirtualBox boot device '" + p.device + "' has no VMWare equivalent")
+		}
+	}
+
+	if len(devices) == 0 {
+		return nil, nil
+	}
+
+	return []vmwConfig{
+		{extra: true, key: "bios.bootOrder", value: strings.Join(devices, ",")},
+	}, nil
+}
+
+const (
+	// defaultHardwareVersion is the VMWare virtual hardware version of
+	// a converted virtual machine.
+	defaultHardwareVersion = 10
+
+	// secureBootHardwareVersion is the earliest VMWare virtual hardware
+	// version that supports EFI Secure Boot.
+	secureBootHardwareVersion = 13
+
+	// virtualTPMHardwareVersion is the earliest VMWare virtual hardware
+	// version that supports a virtual TPM.
+	virtualTPMHardwareVersion = 14
+
+	virtualSystemTypePrefix = "vmx-"
+)
+
+// ParseHardwareVersion parses a VMWare virtual hardware version, which may
+// be specified as a VirtualSystemType (e.g., 'vmx-13') or as a number
+// (e.g., '13').
+func ParseHardwareVersion(version string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(version), virtualSystemTypePrefix))
+	if err != nil || number < 1 {
+		return 0, errors.New("invalid hardware version '" + version + "' - must be a number or 'vmx-<number>'")
+	}
+
+	return number, nil
+}
+
+// resolveHardwareVersion chooses the virtual hardware version needed for
+// the hardware choices. An explicitly specified version must support all
+// of the chosen hardware.
+func resolveHardwareVersion(choices *hardwareChoices, version string) error {
+	required := defaultHardwareVersion
+	var requiredBy string
+
+	if choices.secureBoot && secureBootHardwareVersion > required {
+		required = secureBootHardwareVersion
+		requiredBy = "EFI Secure Boot"
+	}
+
+	if choices.virtualTPM && virtualTPMHardwareVersion > required {
+		required = virtualTPMHardwareVersion
+		requiredBy = "a virtual TPM"
+	}
+
+	if len(version) == 0 {
+		choices.hardwareVersion = required
+		return nil
+	}
+
+	chosen, err := ParseHardwareVersion(version)
+	if err != nil {
+		return err
+	}
+
+	if chosen < required && len(requiredBy) > 0 {
+		return errors.New("hardware version " + virtualSystemTypePrefix + strconv.Itoa(chosen) + " does not support " +
+			requiredBy + " - " + virtualSystemTypePrefix + strconv.Itoa(required) + " or later is required")
+	}
+
+	choices.hardwareVersion = chosen
+
+	return nil
+}
+
+// resolveSecureBoot enables EFI Secure Boot if it is enabled on the
+// VirtualBox machine. Secure Boot requires EFI firmware.
+func resolveSecureBoot(choices *hardwareChoices, secureBoot ovf.VboxSecureBoot) error {
+	if !strings.EqualFold(secureBoot.Enabled, "true") {
+		return nil
+	}
+
+	if choices.firmware == BiosFirmware {
+		return errors.New("the VirtualBox machine has EFI Secure Boot enabled, which requires '" +
+			EfiFirmware + "' firmware")
+	}
+
+	choices.firmware = EfiFirmware
+	choices.secureBoot = true
+
+	return nil
+}
+
+// resolveVirtualTPM updates the hardware choices to include a virtual TPM
+// if one was requested. A warning is reported if the VirtualBox machine
+// has a TPM that will not be converted.
+func resolveVirtualTPM(choices *hardwareChoices, tpm ovf.VboxTPM, options BasicConvertOptions) error {
+	hasTPM := len(tpm.Type) > 0 && !strings.EqualFold(tpm.Type, "None")
+
+	if !options.VirtualTPM {
+		if hasTPM {
+			options.warn("the VirtualBox machine has a TPM (type '" + tpm.Type +
+				"') that will not be converted - add a virtual TPM if the guest OS requires one (e.g., Windows 11)")
+		}
+
+		return nil
+	}
+
+	if choices.firmware == BiosFirmware {
+		return errors.New("a virtual TPM requires '" + EfiFirmware + "' firmware")
+	}
+
+	choices.firmware = EfiFirmware
+	choices.virtualTPM = true
+
+	if strings.EqualFold(tpm.Type, "v1_2") {
+		options.warn("the VirtualBox machine has a TPM 1.2, which will be replaced by a TPM 2.0 virtual TPM")
+	}
+
+	options.warn("the virtual TPM requires a key provider to be configured in vCenter before the virtual machine can be deployed")
+
+	return nil
+}
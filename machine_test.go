@@ -0,0 +1,284 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestDisplayExtraConfigs(t *testing.T) {
+	configs, err := displayExtraConfigs(ovf.VboxDisplay{
+		Controller:   "VMSVGA",
+		VRAMSize:     "512",
+		MonitorCount: "2",
+		Accelerate3D: "true",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []vmwConfig{
+		{extra: true, key: "svga.autodetect", value: "FALSE"},
+		{extra: true, key: "svga.vramSize", value: "268435456"},
+		{extra: true, key: "svga.numDisplays", value: "2"},
+		{extra: true, key: "mks.enable3d", value: "TRUE"},
+	}
+
+	if len(configs) != len(expected) {
+		t.Fatal("Got unexpected number of configs")
+	}
+
+	for i := range expected {
+		if configs[i] != expected[i] {
+			t.Fatal("Got unexpected config '" + configs[i].key + "' = '" + configs[i].value + "'")
+		}
+	}
+
+	configs, err = displayExtraConfigs(ovf.VboxDisplay{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(configs) != 0 {
+		t.Fatal("Expected no configs when display settings are not declared")
+	}
+
+	_, err = displayExtraConfigs(ovf.VboxDisplay{VRAMSize: "junk"})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid video memory size")
+	}
+}
+
+func TestBasicConvertMapDisplay(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "        <Memory RAMSize=\"512\"/>\n",
+		"        <Memory RAMSize=\"512\"/>\n        <Display VRAMSize=\"16\" monitorCount=\"1\" accelerate3D=\"false\"/>\n", 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{MapDisplay: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "      <vmw:ExtraConfig ovf:required=\"false\" vmw:key=\"svga.autodetect\" vmw:value=\"FALSE\"/>\n" +
+		"      <vmw:ExtraConfig ovf:required=\"false\" vmw:key=\"svga.vramSize\" vmw:value=\"16777216\"/>\n" +
+		"      <vmw:ExtraConfig ovf:required=\"false\" vmw:key=\"svga.numDisplays\" vmw:value=\"1\"/>\n" +
+		"    </VirtualHardwareSection>"
+
+	if !strings.Contains(b.String(), expected) || strings.Contains(b.String(), "mks.enable3d") {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
+
+func TestBootOrderExtraConfig(t *testing.T) {
+	configs, err := bootOrderExtraConfig(ovf.VboxBoot{
+		Orders: []ovf.VboxBootOrder{
+			{Position: "3", Device: "Network"},
+			{Position: "1", Device: "DVD"},
+			{Position: "4", Device: "None"},
+			{Position: "2", Device: "HardDisk"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(configs) != 1 || configs[0] != (vmwConfig{extra: true, key: "bios.bootOrder", value: "cdrom,hdd,ethernet"}) {
+		t.Fatal("Got unexpected boot order")
+	}
+
+	configs, err = bootOrderExtraConfig(ovf.VboxBoot{Orders: []ovf.VboxBootOrder{{Position: "1", Device: "None"}}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(configs) != 0 {
+		t.Fatal("Expected no boot order when no devices are bootable")
+	}
+
+	_, err = bootOrderExtraConfig(ovf.VboxBoot{Orders: []ovf.VboxBootOrder{{Position: "1", Device: "USB"}}})
+	if err == nil {
+		t.Fatal("Expected an error for a boot device without a VMWare equivalent")
+	}
+}
+
+func TestBasicConvertPreserveBootOrder(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{PreserveBootOrder: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "      <vmw:ExtraConfig ovf:required=\"false\" vmw:key=\"bios.bootOrder\" vmw:value=\"hdd,cdrom\"/>\n" +
+		"    </VirtualHardwareSection>"
+
+	if !strings.Contains(b.String(), expected) {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
+
+func TestResolveVirtualTPM(t *testing.T) {
+	var warnings []string
+	options := BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	}
+
+	var choices hardwareChoices
+	err := resolveVirtualTPM(&choices, ovf.VboxTPM{Type: "v2_0"}, options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if choices.virtualTPM || len(warnings) != 1 {
+		t.Fatal("Expected a warning about the unconverted TPM")
+	}
+
+	warnings = nil
+	err = resolveVirtualTPM(&choices, ovf.VboxTPM{Type: "None"}, options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(warnings) != 0 {
+		t.Fatal("Expected no warnings when the VirtualBox machine has no TPM")
+	}
+
+	options.VirtualTPM = true
+	err = resolveVirtualTPM(&choices, ovf.VboxTPM{Type: "v2_0"}, options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !choices.virtualTPM || choices.firmware != EfiFirmware {
+		t.Fatal("Expected a virtual TPM with EFI firmware")
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "key provider") {
+		t.Fatal("Expected a warning about the key provider")
+	}
+
+	choices = hardwareChoices{firmware: BiosFirmware}
+	err = resolveVirtualTPM(&choices, ovf.VboxTPM{}, options)
+	if err == nil {
+		t.Fatal("Expected an error when a virtual TPM is used with BIOS firmware")
+	}
+}
+
+func TestBasicConvertVirtualTPM(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "        <Memory RAMSize=\"512\"/>\n",
+		"        <Memory RAMSize=\"512\"/>\n        <TrustedPlatformModule type=\"v2_0\"/>\n", 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{VirtualTPM: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	expected := "        <rasd:ResourceSubType>vmware.vtpm</rasd:ResourceSubType>\n" +
+		"        <rasd:ResourceType>1</rasd:ResourceType>\n" +
+		"      </Item>\n" +
+		"      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n" +
+		"    </VirtualHardwareSection>"
+
+	if !strings.Contains(result, expected) || !strings.Contains(result, "<vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>") {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	b, err = basicConvertWithOptions(strings.NewReader(result), BasicConvertOptions{VirtualTPM: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Count(b.String(), "vmware.vtpm") != 1 {
+		t.Fatal("Virtual TPM was added twice:\n'" + b.String() + "'")
+	}
+}
+
+func TestParseHardwareVersion(t *testing.T) {
+	for _, version := range []string{"vmx-13", "VMX-13", "13"} {
+		number, err := ParseHardwareVersion(version)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if number != 13 {
+			t.Fatal("Got unexpected hardware version for '" + version + "'")
+		}
+	}
+
+	for _, version := range []string{"", "vmx-", "vmx-0", "virtualbox-2.2"} {
+		_, err := ParseHardwareVersion(version)
+		if err == nil {
+			t.Fatal("Expected an error for hardware version '" + version + "'")
+		}
+	}
+}
+
+func TestResolveHardwareVersion(t *testing.T) {
+	choices := hardwareChoices{secureBoot: true}
+	err := resolveHardwareVersion(&choices, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if choices.virtualSystemType() != "vmx-13" {
+		t.Fatal("Got unexpected hardware version " + choices.virtualSystemType())
+	}
+
+	choices = hardwareChoices{secureBoot: true, virtualTPM: true}
+	err = resolveHardwareVersion(&choices, "vmx-17")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if choices.virtualSystemType() != "vmx-17" {
+		t.Fatal("Got unexpected hardware version " + choices.virtualSystemType())
+	}
+
+	choices = hardwareChoices{virtualTPM: true}
+	err = resolveHardwareVersion(&choices, "13")
+	if err == nil {
+		t.Fatal("Expected an error for a hardware version that does not support a virtual TPM")
+	}
+
+	choices = hardwareChoices{}
+	err = resolveHardwareVersion(&choices, "vmx-8")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if choices.virtualSystemType() != "vmx-8" {
+		t.Fatal("Got unexpected hardware version " + choices.virtualSystemType())
+	}
+}
+
+func TestBasicConvertSecureBoot(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<Hardware>",
+		"<Hardware>\n        <Firmware type=\"EFI\"/>\n        <SecureBoot enabled=\"true\"/>", 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	expected := "      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n" +
+		"      <vmw:Config ovf:required=\"false\" vmw:key=\"uefi.secureBoot.enabled\" vmw:value=\"true\"/>\n" +
+		"    </VirtualHardwareSection>"
+
+	if !strings.Contains(result, expected) || !strings.Contains(result, "<vssd:VirtualSystemType>vmx-13</vssd:VirtualSystemType>") {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{Firmware: BiosFirmware})
+	if err == nil {
+		t.Fatal("Expected an error when Secure Boot is used with BIOS firmware")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{HardwareVersion: "vmx-10"})
+	if err == nil {
+		t.Fatal("Expected an error when the hardware version does not support Secure Boot")
+	}
+}
@@ -0,0 +1,99 @@
+package vmwareify
+
+import (
+	"io"
+	"time"
+)
+
+// Metrics receives a ConversionStats for each conversion (see
+// BasicConvertOptions.Metrics). Implementations must be safe for
+// concurrent use.
+type Metrics interface {
+	// ConversionFinished is called once a conversion has finished,
+	// whether or not it succeeded.
+	ConversionFinished(stats ConversionStats)
+}
+
+// ConversionStats describes a single conversion.
+type ConversionStats struct {
+	// Ova is true if the input was an .ova.
+	Ova bool
+
+	// BytesRead is the number of bytes read from the input.
+	BytesRead int64
+
+	// BytesWritten is the number of bytes of output produced.
+	BytesWritten int64
+
+	// Duration is the amount of time the conversion took.
+	Duration time.Duration
+
+	// Err is the error that caused the conversion to fail, or nil
+	// if it succeeded.
+	Err error
+}
+
+// conversionMeter measures a conversion, reporting it to the Metrics
+// once it has finished.
+type conversionMeter struct {
+	metrics Metrics
+	start   time.Time
+	in      *countingReader
+}
+
+// startMeter starts measuring a conversion, returning the io.Reader that
+// the input must be read from. The returned *conversionMeter is nil if
+// the BasicConvertOptions do not specify Metrics.
+func startMeter(options BasicConvertOptions, in io.Reader) (*conversionMeter, io.Reader) {
+	if options.Metrics == nil {
+		return nil, in
+	}
+
+	counting := &countingReader{r: in}
+
+	return &conversionMeter{
+		metrics: options.Metrics,
+		start:   time.Now(),
+		in:      counting,
+	}, counting
+}
+
+func (o *conversionMeter) finish(isOva bool, bytesWritten int64, err error) {
+	if o == nil {
+		return
+	}
+
+	o.metrics.ConversionFinished(ConversionStats{
+		Ova:          isOva,
+		BytesRead:    o.in.n,
+		BytesWritten: bytesWritten,
+		Duration:     time.Since(o.start),
+		Err:          err,
+	})
+}
+
+// countingReader counts the bytes read from an io.Reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (o *countingReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	o.n = o.n + int64(n)
+
+	return n, err
+}
+
+// countingWriter counts the bytes written to an io.Writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (o *countingWriter) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.n = o.n + int64(n)
+
+	return n, err
+}
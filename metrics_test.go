@@ -0,0 +1,46 @@
+package vmwareify
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testMetrics struct {
+	mu    sync.Mutex
+	stats []ConversionStats
+}
+
+func (o *testMetrics) ConversionFinished(stats ConversionStats) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.stats = append(o.stats, stats)
+}
+
+func TestBasicConvertReaderMetrics(t *testing.T) {
+	metrics := &testMetrics{}
+
+	buff, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{Metrics: metrics})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = BasicConvertReader(strings.NewReader("junk"), BasicConvertOptions{Metrics: metrics})
+	if err == nil {
+		t.Fatal("Expected an error when converting junk")
+	}
+
+	if len(metrics.stats) != 2 {
+		t.Fatalf("Expected 2 stats, got %d", len(metrics.stats))
+	}
+
+	stats := metrics.stats[0]
+	if stats.Ova || stats.Err != nil || stats.BytesRead != int64(len(basicOvfFileContents)) || stats.BytesWritten != int64(buff.Len()) {
+		t.Fatalf("Got unexpected stats - %+v", stats)
+	}
+
+	if metrics.stats[1].Err == nil {
+		t.Fatal("Expected the failed conversion's stats to have an error")
+	}
+}
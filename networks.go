@@ -0,0 +1,200 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ParseNetworkMapping parses a network mapping in the format
+// '<old-name>=<new-name>' (e.g., 'NAT=VM Network'), as used by
+// BasicConvertOptions.NetworkMappings.
+func ParseNetworkMapping(s string) (string, string, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 || len(strings.TrimSpace(parts[1])) == 0 {
+		return "", "", errors.New("invalid network mapping '" + s + "' - must be in the format " +
+			"'<old-name>=<new-name>'")
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+// removeNetworkEdits returns the explained edits that remove the
+// networks chosen by BasicConvertOptions.RemoveNetworks, and the network
+// adapters connected to them. They must be proposed for ovf.NetworkName,
+// ovf.VirtualHardwareItemName, and ovf.EthernetPortItemName. A non-nil
+// error is returned if a network does not exist, or is also mapped.
+func removeNetworkEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, error) {
+	if len(options.RemoveNetworks) == 0 {
+		return nil, nil
+	}
+
+	known := knownNetworks(parsed)
+
+	var edits []explainedFunc
+	for _, network := range options.RemoveNetworks {
+		if !known[network] {
+			return nil, errors.New("cannot remove network '" + network + "' because it does not exist")
+		}
+
+		if _, ok := options.NetworkMappings[network]; ok {
+			return nil, errors.New("network '" + network + "' cannot be both removed and mapped")
+		}
+
+		edits = append(edits, explainedFunc{
+			f:      ovf.DeleteEthernetAdaptersOnNetworkFunc(network),
+			reason: "the network '" + network + "' is removed, along with the network adapters connected to it",
+		})
+	}
+
+	return edits, nil
+}
+
+// knownNetworks returns the names of the Networks in the NetworkSection,
+// and of the networks that Items are connected to.
+func knownNetworks(parsed ovf.Ovf) map[string]bool {
+	known := make(map[string]bool)
+	for _, network := range parsed.Envelope.NetworkSection.Networks {
+		known[network.Name] = true
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if len(item.Connection) > 0 {
+			known[item.Connection] = true
+		}
+	}
+
+	return known
+}
+
+// networkMappingEdits returns the explained edits that connect the
+// virtual machine to the networks chosen by
+// BasicConvertOptions.NetworkMappings. The first edits must be proposed
+// for ovf.NetworkName, and the second for ovf.VirtualHardwareItemName and
+// ovf.EthernetPortItemName.
+// A Network that is mapped to an existing Network (or to the same
+// Network as another mapping) is deleted, rather than renamed, so that
+// network names remain unique. A non-nil error is returned if a network
+// does not exist, or if a mapping would be chained with another.
+func networkMappingEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, []explainedFunc, error) {
+	if len(options.NetworkMappings) == 0 {
+		return nil, nil, nil
+	}
+
+	known := knownNetworks(parsed)
+
+	oldNames := mappedNetworkNames(options.NetworkMappings)
+
+	for _, oldName := range oldNames {
+		newName := options.NetworkMappings[oldName]
+
+		if !known[oldName] {
+			return nil, nil, errors.New("cannot map network '" + oldName + "' because it does not exist")
+		}
+
+		if _, ok := options.NetworkMappings[newName]; ok && newName != oldName {
+			return nil, nil, errors.New("network '" + oldName + "' cannot be mapped to network '" + newName +
+				"' because it is also mapped")
+		}
+	}
+
+	// Networks that are not mapped keep their names.
+	existing := make(map[string]bool)
+	for _, network := range parsed.Envelope.NetworkSection.Networks {
+		if _, ok := options.NetworkMappings[network.Name]; !ok {
+			existing[network.Name] = true
+		}
+	}
+
+	var networkEdits []explainedFunc
+	var itemEdits []explainedFunc
+
+	for _, oldName := range oldNames {
+		newName := options.NetworkMappings[oldName]
+		if newName == oldName {
+			continue
+		}
+
+		f := ovf.SetNetworkConnectionFunc(oldName, newName)
+
+		if existing[newName] {
+			networkEdits = append(networkEdits, explainedFunc{
+				f:      ovf.DeleteNetworkFunc(oldName),
+				reason: "the network '" + oldName + "' is mapped to the existing network '" + newName + "'",
+			})
+		} else {
+			networkEdits = append(networkEdits, explainedFunc{
+				f:      f,
+				reason: "the network '" + oldName + "' is mapped to the network '" + newName + "'",
+			})
+
+			existing[newName] = true
+		}
+
+		itemEdits = append(itemEdits, explainedFunc{
+			f:      f,
+			reason: "devices connected to the network '" + oldName + "' are connected to the network '" + newName + "'",
+		})
+	}
+
+	return networkEdits, itemEdits, nil
+}
+
+// mappedNetworkNames returns the names of the networks that are mapped
+// by a BasicConvertOptions.NetworkMappings in sorted order.
+func mappedNetworkNames(mappings map[string]string) []string {
+	oldNames := make([]string, 0, len(mappings))
+	for oldName := range mappings {
+		oldNames = append(oldNames, oldName)
+	}
+
+	sort.Strings(oldNames)
+
+	return oldNames
+}
+
+// addNetworkAdapters adds an Ethernet adapter connected to each of the
+// networks chosen by BasicConvertOptions.AddNetworkAdapters to an edited
+// .ovf.
+func addNetworkAdapters(edited *bytes.Buffer, networks []string, hardware hardwareChoices, recorder *editRecorder) (*bytes.Buffer, error) {
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parsed.Envelope.NetworkSection.XMLName.Local) == 0 {
+		return nil, errors.New("network adapters cannot be added because the .ovf does not have a NetworkSection")
+	}
+
+	subType := hardware.profile.NetworkAdapterSubType
+	if len(subType) == 0 {
+		subType = e1000NicSubType
+
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if item.ResourceType == ovf.EthernetAdapterResourceType && len(item.ResourceSubType) > 0 {
+				subType = item.ResourceSubType
+				break
+			}
+		}
+	}
+
+	editScheme := ovf.NewEditScheme()
+
+	for _, network := range networks {
+		if len(strings.TrimSpace(network)) == 0 {
+			return nil, errors.New("the network of an added network adapter cannot be empty")
+		}
+
+		f := ovf.AddEthernetAdapterFunc(network, subType)
+
+		editScheme.Propose(recorder.explain(f, "the network '"+network+"' is added for an added network adapter"),
+			ovf.NetworkSectionName).
+			Propose(recorder.explain(f, "a '"+subType+"' network adapter connected to the network '"+
+				network+"' is added"), ovf.VirtualHardwareSectionName)
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), editScheme)
+}
@@ -0,0 +1,170 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestParseNetworkMapping(t *testing.T) {
+	oldName, newName, err := ParseNetworkMapping(" NAT = VM Network=2 ")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if oldName != "NAT" || newName != "VM Network=2" {
+		t.Fatalf("Got unexpected mapping '%s' to '%s'", oldName, newName)
+	}
+
+	for _, invalid := range []string{"NAT", "=VM Network", "NAT=", ""} {
+		_, _, err = ParseNetworkMapping(invalid)
+		if err == nil {
+			t.Fatal("Expected an error for '" + invalid + "'")
+		}
+	}
+}
+
+func TestBasicConvertNetworkMappings(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		NetworkMappings: map[string]string{"NAT": "VM Network"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	networks := parsed.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "VM Network" {
+		t.Fatalf("Got unexpected networks - %+v", networks)
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if len(item.Connection) > 0 && item.Connection != "VM Network" {
+			t.Fatalf("Item '%s' is connected to '%s'", item.InstanceID, item.Connection)
+		}
+	}
+}
+
+func TestBasicConvertNetworkMappingsMerge(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, `<Network ovf:name="NAT">`,
+		`<Network ovf:name="VM Network">
+      <Description>A port group</Description>
+    </Network>
+    <Network ovf:name="NAT">`, 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		NetworkMappings: map[string]string{"NAT": "VM Network"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	networks := parsed.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "VM Network" || networks[0].Description != "A port group" {
+		t.Fatalf("Got unexpected networks - %+v", networks)
+	}
+
+	if !strings.Contains(b.String(), "<rasd:Connection>VM Network</rasd:Connection>") {
+		t.Fatalf("Item was not connected to the existing network:\n%s", b.String())
+	}
+}
+
+func TestBasicConvertNetworkMappingsErrors(t *testing.T) {
+	invalid := []map[string]string{
+		{"missing": "VM Network"},
+		{"NAT": "VM Network", "VM Network": "Other"},
+	}
+
+	for _, mappings := range invalid {
+		_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+			NetworkMappings: mappings,
+		})
+		if err == nil {
+			t.Fatalf("Expected an error for %v", mappings)
+		}
+	}
+}
+
+func TestBasicConvertAddNetworkAdapters(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		NetworkAdapterSubType: "VmxNet3",
+		NetworkMappings:       map[string]string{"NAT": "VM Network"},
+		AddNetworkAdapters:    []string{"Management", "VM Network"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var networks []string
+	for _, network := range parsed.Envelope.NetworkSection.Networks {
+		networks = append(networks, network.Name)
+	}
+
+	if strings.Join(networks, ",") != "VM Network,Management" {
+		t.Fatalf("Got unexpected networks %q", networks)
+	}
+
+	var adapters []string
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == ovf.EthernetAdapterResourceType {
+			adapters = append(adapters, item.ResourceSubType+"="+item.Connection)
+		}
+	}
+
+	if strings.Join(adapters, ",") != "VmxNet3=VM Network,VmxNet3=Management,VmxNet3=VM Network" {
+		t.Fatalf("Got unexpected adapters %q", adapters)
+	}
+}
+
+func TestBasicConvertRemoveNetworks(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		RemoveNetworks:     []string{"NAT"},
+		AddNetworkAdapters: []string{"VM Network"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	networks := parsed.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "VM Network" {
+		t.Fatalf("Got unexpected networks - %+v", networks)
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.Connection == "NAT" {
+			t.Fatalf("Item '%s' was not removed", item.InstanceID)
+		}
+	}
+
+	invalid := []BasicConvertOptions{
+		{RemoveNetworks: []string{"missing"}},
+		{RemoveNetworks: []string{"NAT"}, NetworkMappings: map[string]string{"NAT": "VM Network"}},
+	}
+
+	for _, options := range invalid {
+		_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), options)
+		if err == nil {
+			t.Fatalf("Expected an error for %+v", options)
+		}
+	}
+}
@@ -0,0 +1,572 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/internal/guestos"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	BiosFirmware = "bios"
+	EfiFirmware  = "efi"
+
+	// DefaultMaxDescriptorBytes is the default maximum size of a .ovf
+	// descriptor. Descriptors are typically a few kilobytes, so the
+	// limit only prevents untrusted inputs from exhausting memory.
+	DefaultMaxDescriptorBytes = ovf.DefaultMaxDescriptorBytes
+)
+
+// BasicConvertOptions customizes the conversion performed by
+// BasicConvertWithOptions.
+type BasicConvertOptions struct {
+	// GuestOSProfile is the name of a GuestOSProfile to apply during
+	// the conversion (see LookupGuestOSProfile). No profile is
+	// applied if it is empty.
+	GuestOSProfile string
+
+	// AutoDetectGuestOS, when true, chooses hardware based on the
+	// guest OS and firmware declared in the .ovf (the
+	// OperatingSystemSection's vbox:OSType, and the vbox:Machine).
+	// An explicitly specified GuestOSProfile takes precedence.
+	AutoDetectGuestOS bool
+
+	// NetworkAdapterSubType overrides the ResourceSubType of Ethernet
+	// adapters (e.g., 'E1000', 'E1000e', or 'VmxNet3').
+	NetworkAdapterSubType string
+
+	// NetworkMappings, if non-empty, connects the virtual machine to
+	// other networks. It maps the name of each network to replace
+	// (e.g., 'NAT' or 'HostOnly') to the name of the network to use
+	// instead (e.g., the vSphere port group 'VM Network'). The
+	// NetworkSection Networks and the Connection of each Item are
+	// renamed together. A network mapped to an existing network is
+	// merged into it. The conversion fails if a network does not
+	// exist.
+	NetworkMappings map[string]string
+
+	// RemoveNetworks, if non-empty, removes the named networks (e.g.,
+	// 'HostOnly' networks that do not exist on the target), along with
+	// the network adapters connected to them. The conversion fails if
+	// a network does not exist, or is also mapped by NetworkMappings.
+	RemoveNetworks []string
+
+	// AddNetworkAdapters, if non-empty, adds an Ethernet adapter
+	// connected to each of the named networks (e.g., a management
+	// network), which are added to the NetworkSection if they do not
+	// exist. The adapters are of the model chosen by
+	// NetworkAdapterSubType or the guest OS profile, or of the same
+	// model as the first existing adapter, or are E1000 adapters.
+	AddNetworkAdapters []string
+
+	// ScsiControllerSubType overrides the ResourceSubType of SCSI
+	// controllers (e.g., 'lsilogic', 'lsilogicsas', or 'VirtualSCSI').
+	ScsiControllerSubType string
+
+	// Firmware overrides the virtual machine's firmware. It must be
+	// empty, BiosFirmware, or EfiFirmware.
+	Firmware string
+
+	// CpuHotAdd, when true, allows CPUs to be added to the virtual
+	// machine while it is running.
+	CpuHotAdd bool
+
+	// MemoryHotAdd, when true, allows memory to be added to the
+	// virtual machine while it is running.
+	MemoryHotAdd bool
+
+	// MapDisplay, when true, maps the VirtualBox display settings
+	// (video memory, monitor count, and 3D acceleration) declared in
+	// the vbox:Machine to their VMWare equivalents. Otherwise, VMWare
+	// chooses the display settings.
+	MapDisplay bool
+
+	// PreserveBootOrder, when true, maps the VirtualBox boot order
+	// declared in the vbox:Machine to the VMWare boot order, so that
+	// the virtual machine boots from the intended device.
+	PreserveBootOrder bool
+
+	// VirtualTPM, when true, adds a VMWare virtual TPM to the virtual
+	// machine, which is required by Windows 11. A virtual TPM requires
+	// EFI firmware, which is selected automatically, and a key
+	// provider must be configured in vCenter before the virtual
+	// machine can be deployed.
+	VirtualTPM bool
+
+	// MigrateIdeDevices, when true, attaches the devices of IDE
+	// controllers (e.g., disks and CD/DVD drives) to a SATA controller
+	// before the IDE controllers are removed. A SATA controller is
+	// added if the virtual machine does not have one. Otherwise, the
+	// devices are left attached to controllers that no longer exist.
+	MigrateIdeDevices bool
+
+	// KeepIdeControllers, when true, keeps the IDE controllers rather
+	// than removing them, which some legacy guest operating systems
+	// require. Their ResourceSubType is set to a model that ESXi
+	// supports. It cannot be combined with MigrateIdeDevices.
+	KeepIdeControllers bool
+
+	// FloppyDrives chooses what happens to floppy drives. It must be
+	// empty, RemoveFloppyDrives, or KeepFloppyDrives. Removing them
+	// also removes floppy images that nothing else uses from the
+	// References. Keeping them converts them to VMWare floppy drives.
+	// If it is empty, floppy drives are left as they are, and a
+	// warning is reported.
+	FloppyDrives string
+
+	// AudioDevices chooses what happens to audio devices (e.g., the
+	// 'ensoniq1371' sound card exported by VirtualBox), which ESXi
+	// rejects. It must be empty, RemoveAudioDevices, HdAudioDevices,
+	// or Es1371AudioDevices. The latter two convert them to the
+	// corresponding VMWare sound card, which VMWare Workstation and
+	// Fusion support. If it is empty, audio devices are left as they
+	// are, and a warning is reported.
+	AudioDevices string
+
+	// UsbTablet, when true, adds a USB tablet to the virtual machine,
+	// which tracks the mouse by its absolute position so that the
+	// pointer follows the mouse in the ESXi console. A VMWare USB
+	// controller is added if the virtual machine does not have one,
+	// and the USB controllers of other tools (e.g., VirtualBox) are
+	// converted to a single VMWare USB controller.
+	UsbTablet bool
+
+	// StripSnapshotMetadata, when true, removes the VirtualBox
+	// snapshot metadata from the vbox:Machine, which is harmless if
+	// the disks were flattened when the virtual machine was
+	// exported. Otherwise, the conversion fails with ErrSnapshots.
+	// Differencing disks always cause the conversion to fail.
+	StripSnapshotMetadata bool
+
+	// NormalizeHrefs, when true, converts the href of each local file
+	// in the References to a relative, forward slash separated path,
+	// as required by the OVF specification. Some tools write absolute
+	// paths, or separate paths with backslashes. Absolute paths are
+	// made relative to ReferencesDir if they are inside it, and are
+	// otherwise replaced by their last element. If ReferencesDir is
+	// set, the conversion fails if a referenced file cannot be
+	// located.
+	NormalizeHrefs bool
+
+	// ReferencesDir is the directory that contains the files
+	// referenced by the .ovf. BasicConvertWithOptions uses the .ovf's
+	// directory if it is empty.
+	ReferencesDir string
+
+	// RecomputeSizes, when true, sets the size of each local file in
+	// the References, and the populatedSize of each Disk, to the
+	// values of the referenced files in ReferencesDir. Stale sizes
+	// cause vCenter to report incorrect progress, or to reject the
+	// .ovf. Files that cannot be read are reported as warnings, and
+	// the populatedSize is only recomputed for VMDK sparse extents.
+	RecomputeSizes bool
+
+	// SortItems, when true, orders the Items of the converted .ovf
+	// so that every controller precedes the devices attached to it,
+	// and Items are otherwise ordered by InstanceID (see
+	// ovf.SortItems). Some importers reject a device that is
+	// declared before its controller.
+	SortItems bool
+
+	// DiskCapacity, if non-empty, sets the declared capacity of the
+	// disks, so that the imported virtual machine has room for its
+	// file systems to grow. The disk images are not modified. It is
+	// either a capacity for every disk (e.g., '100GiB'), or a comma
+	// separated list of capacities for specific disks (e.g.,
+	// 'vmdisk1=100GiB,vmdisk2=1TiB'). Capacities are parsed using
+	// ovf.ParseCapacity. A disk cannot be shrunk below its current
+	// capacity, its populatedSize, or the capacity of its VMDK (if it
+	// is in ReferencesDir).
+	DiskCapacity string
+
+	// CpuAllocation, if non-empty, sets the CPU resources guaranteed
+	// to, and available to, the virtual machine. It is a comma
+	// separated list of settings: 'reservation' and 'limit' in MHz,
+	// and the relative 'shares' (e.g., 'reservation=2000,shares=4000').
+	CpuAllocation string
+
+	// MemoryAllocation, if non-empty, sets the memory resources
+	// guaranteed to, and available to, the virtual machine, in the
+	// same format as CpuAllocation. The 'reservation' and 'limit' are
+	// parsed using ovf.ParseCapacity (e.g., 'reservation=4GiB').
+	MemoryAllocation string
+
+	// IpAssignmentSchemes, if non-empty, declares the schemes that
+	// the virtual machine supports for being assigned IP addresses
+	// in a 'vmw:IpAssignmentSection', so that vCenter offers the
+	// matching IP allocation policies when the vApp is deployed. It
+	// is a comma separated list of ovf.DhcpIpAssignmentScheme and
+	// ovf.OvfEnvIpAssignmentScheme (which allows fixed IP addresses
+	// to be assigned using the OVF environment).
+	IpAssignmentSchemes string
+
+	// IpProtocols is a comma separated list of the IP protocols
+	// declared with IpAssignmentSchemes (ovf.Ipv4Protocol and
+	// ovf.Ipv6Protocol). ovf.Ipv4Protocol is used if it is empty.
+	// It is only used if IpAssignmentSchemes is specified.
+	IpProtocols string
+
+	// DiskFileSuffix, if non-empty, is appended to the name of each
+	// disk file (before its extension) in the References, so that the
+	// converted .ovf does not share its disks with the original
+	// (e.g., '-vmware' renames 'disk1.vmdk' to 'disk1-vmware.vmdk').
+	// BasicConvertWithOptions hard-links or copies the disk files next
+	// to the converted .ovf (see CompanionFiles). Other functions only rewrite the
+	// references, and leave the files to the caller. It cannot be
+	// used when converting an .ova.
+	DiskFileSuffix string
+
+	// CompanionFiles chooses how BasicConvertWithOptions places the
+	// files referenced by the .ovf (e.g., disk images) next to the
+	// converted .ovf, so that it can be imported immediately. It
+	// must be empty, CopyCompanionFiles, or SymlinkCompanionFiles.
+	// If it is empty, only the files renamed by DiskFileSuffix are
+	// placed. Manifests and certificates are not placed, as the
+	// converted .ovf's digest differs from the original. It is only
+	// used by BasicConvertWithOptions.
+	CompanionFiles string
+
+	// Scrub, when true, removes information that identifies the
+	// system the .ovf was exported from, for publishing appliances
+	// derived from internal templates. The vbox:Machine (which
+	// contains the machine's UUID, MAC addresses, timestamps, and
+	// extra data) is removed, as are the VirtualBox UUIDs of disks
+	// and the MAC addresses of Ethernet adapters. The values of
+	// ProductSection properties that contain hostnames are cleared
+	// (see ovf.ClearHostnamePropertiesFunc). The digest of the
+	// original .ovf is not recorded by EmbedProvenance.
+	Scrub bool
+
+	// AnnotateSharedFolders, when true, describes the shared folders
+	// of the VirtualBox machine in the annotation of the converted
+	// .ovf, which VMWare shows as the virtual machine's notes. The
+	// folders are not converted, and must be configured as VMWare
+	// Shared Folders after the virtual machine is imported. The
+	// existing annotation is kept. Host paths are omitted if Scrub
+	// is true.
+	AnnotateSharedFolders bool
+
+	// EmbedProvenance, when true, records the provenance of the
+	// conversion in a ProductSection of the converted .ovf with the
+	// class ProvenanceClass. The section records the application's
+	// Version and commit, the SHA256 digest of the original .ovf, the
+	// guest OS profile that was applied, and the options that were
+	// specified.
+	// No timestamps are recorded, so the result is reproducible.
+	EmbedProvenance bool
+
+	// NormalizeOvaModes, when true, sets the mode of every file in a
+	// converted .ova to 0644. Otherwise, the original modes are kept.
+	NormalizeOvaModes bool
+
+	// StripOvaOwnership, when true, removes the user and group IDs
+	// and names of every file in a converted .ova, so that the .ova
+	// does not depend on the system that created it.
+	StripOvaOwnership bool
+
+	// Reproducible, when true, converts an .ova such that the same
+	// input always produces the same output, so that the result can
+	// be attested by its digest. It implies NormalizeOvaModes and
+	// StripOvaOwnership, and sets the modification time of every
+	// file to ova.ReproducibleModTime.
+	Reproducible bool
+
+	// Validate, when true, checks the converted .ovf for references
+	// that do not resolve (see ovf.CheckIntegrity), such as an Item
+	// whose Parent is not a controller, or a Disk whose fileRef is
+	// not a References File. The conversion fails with an error
+	// wrapping ErrValidation if there are any.
+	Validate bool
+
+	// ValidationRules, if non-nil, are the rules checked when Validate
+	// is true, rather than those of ovf.NewRuleSet (e.g., to enforce
+	// an organization's policies).
+	ValidationRules *ovf.RuleSet
+
+	// OnWarning, if non-nil, is called with a description of each
+	// problem that does not prevent the conversion, but may prevent
+	// the virtual machine from working as expected (e.g., a VirtualBox
+	// TPM that was not converted).
+	OnWarning func(warning string)
+
+	// OnEdit, if non-nil, is called with each edit made to the .ovf
+	// descriptor, in the order the edits are made. It is intended
+	// for debug logging (see Explain for collecting the edits).
+	OnEdit func(edit ConvertEdit)
+
+	// BeforeStage and AfterStage, if non-nil, are called before and
+	// after each ConversionStage of the conversion (e.g., to make a
+	// custom edit after EditStage, or to write a custom manifest
+	// after WriteStage). They may modify the ConversionState, which
+	// affects the stages that follow.
+	BeforeStage StageHook
+	AfterStage  StageHook
+
+	// SourceDialect is the tool that wrote the .ovf, whose known
+	// problems are fixed before it is converted. It must be empty,
+	// VirtualBoxDialect, ProxmoxDialect, AhvDialect, or
+	// GenericDialect. If it is empty, the tool is detected from the
+	// .ovf. Proxmox VE and Nutanix AHV exports use namespace
+	// prefixes (e.g., 'rasd') without declaring them, and may not
+	// order the sections of the Envelope as required by the OVF
+	// specification.
+	SourceDialect string
+
+	// HardwareVersion is the VMWare virtual hardware version of the
+	// converted virtual machine (e.g., 'vmx-13' or '13'). It must
+	// support the chosen hardware. If it is empty, vmx-10 is used
+	// unless newer hardware is required (e.g., vmx-13 for EFI Secure
+	// Boot, which is enabled if it is enabled on the VirtualBox
+	// machine).
+	HardwareVersion string
+
+	// PruneReferences, when true, deletes the References Files that
+	// are no longer referenced once the .ovf is converted (e.g., the
+	// File of a Strings bundle removed by Locale). Otherwise, a
+	// warning is logged for such Files. Files that were not
+	// referenced by the original .ovf are always kept.
+	PruneReferences bool
+
+	// Locale, when non-empty, flattens the localization of the .ovf
+	// to the specified locale (e.g., 'de-DE'): the text of each
+	// localizable element (e.g., an Info or a Description) is
+	// replaced by its message in the locale's Strings bundle, and the
+	// bundles are removed. Without it, the bundles and the ovf:msgid
+	// references of localized elements are kept.
+	Locale string
+
+	// KeepByteOrderMark, when true, starts the converted .ovf with a
+	// UTF-8 byte order mark if the original .ovf started with one
+	// (as some Windows tools write). Otherwise, the mark is removed,
+	// as the OVF specification does not require it.
+	KeepByteOrderMark bool
+
+	// MaxDescriptorBytes is the maximum size of the .ovf descriptor.
+	// DefaultMaxDescriptorBytes is used if it is less than one. A
+	// larger descriptor causes the conversion to fail with an error
+	// wrapping xmlutil.ErrDocumentTooLarge.
+	MaxDescriptorBytes int64
+
+	// MaxOvaBufferBytes is the maximum size of a file that is held in
+	// memory when converting an .ova, which are the descriptor and
+	// the manifest (see ova.RewriteConfig.MaxBufferBytes). Other files,
+	// such as disk images, are streamed using a fixed size buffer.
+	// ova.DefaultMaxBufferBytes is used if it is less than one. A
+	// larger file causes the conversion to fail with an error
+	// wrapping ova.ErrBufferLimit.
+	MaxOvaBufferBytes int64
+
+	// OvaHashProvider, if non-nil, creates the hash used to update
+	// the digest of the descriptor in the manifest of a converted
+	// .ova (e.g., to use a FIPS-validated implementation).
+	// ova.StandardHashProvider is used if it is nil.
+	OvaHashProvider ova.HashProvider
+
+	// Metrics, if non-nil, receives a ConversionStats for each
+	// conversion performed using these options.
+	Metrics Metrics
+}
+
+func (o BasicConvertOptions) maxDescriptorBytes() int64 {
+	if o.MaxDescriptorBytes < 1 {
+		return DefaultMaxDescriptorBytes
+	}
+
+	return o.MaxDescriptorBytes
+}
+
+// rewriteConfig returns the ova.RewriteConfig used to convert an .ova.
+func (o BasicConvertOptions) rewriteConfig() ova.RewriteConfig {
+	return ova.RewriteConfig{
+		NormalizeModes: o.NormalizeOvaModes,
+		StripOwnership: o.StripOvaOwnership,
+		Reproducible:   o.Reproducible,
+		MaxBufferBytes: o.MaxOvaBufferBytes,
+		HashProvider:   o.OvaHashProvider,
+	}
+}
+
+func (o BasicConvertOptions) warn(warning string) {
+	if o.OnWarning != nil {
+		o.OnWarning(warning)
+	}
+}
+
+// hardwareChoices are the hardware selections resolved from the
+// BasicConvertOptions and the .ovf itself.
+type hardwareChoices struct {
+	profile         GuestOSProfile
+	firmware        string
+	cpuHotAdd       bool
+	memoryHotAdd    bool
+	secureBoot      bool
+	virtualTPM      bool
+	hardwareVersion int
+	extraConfigs    []vmwConfig
+}
+
+// virtualSystemType returns the VirtualSystemType of the chosen VMWare
+// virtual hardware version.
+func (o hardwareChoices) virtualSystemType() string {
+	if o.hardwareVersion < 1 {
+		return virtualSystemTypePrefix + strconv.Itoa(defaultHardwareVersion)
+	}
+
+	return virtualSystemTypePrefix + strconv.Itoa(o.hardwareVersion)
+}
+
+// vmwConfig is a VMWare-specific 'vmw:Config' setting, or a
+// 'vmw:ExtraConfig' setting if extra is true.
+type vmwConfig struct {
+	extra bool
+	key   string
+	value string
+}
+
+// vmwConfigs returns the 'vmw:Config' settings for the hardware
+// choices in the order they should be applied.
+func (o hardwareChoices) vmwConfigs() []vmwConfig {
+	var configs []vmwConfig
+
+	if len(o.firmware) > 0 {
+		configs = append(configs, vmwConfig{key: "firmware", value: o.firmware})
+	}
+
+	if o.cpuHotAdd {
+		configs = append(configs, vmwConfig{key: "cpuHotAddEnabled", value: "true"})
+	}
+
+	if o.memoryHotAdd {
+		configs = append(configs, vmwConfig{key: "memoryHotAddEnabled", value: "true"})
+	}
+
+	if o.secureBoot {
+		configs = append(configs, vmwConfig{key: "uefi.secureBoot.enabled", value: "true"})
+	}
+
+	return append(configs, o.extraConfigs...)
+}
+
+// resolveHardware determines the hardware to use for the converted .ovf.
+// Explicit options take precedence over the guest OS profile, which
+// takes precedence over auto-detected values.
+func resolveHardware(raw []byte, options BasicConvertOptions) (hardwareChoices, error) {
+	var choices hardwareChoices
+
+	if options.AutoDetectGuestOS {
+		detected, err := detectHardware(raw)
+		if err != nil {
+			return choices, err
+		}
+		choices = detected
+	}
+
+	if len(options.GuestOSProfile) > 0 {
+		profile, ok := LookupGuestOSProfile(options.GuestOSProfile)
+		if !ok {
+			return choices, errors.New("unknown guest OS profile '" + options.GuestOSProfile + "'")
+		}
+		choices.profile = profile
+	}
+
+	if len(options.NetworkAdapterSubType) > 0 {
+		choices.profile.NetworkAdapterSubType = options.NetworkAdapterSubType
+	}
+
+	if len(options.ScsiControllerSubType) > 0 {
+		choices.profile.ScsiControllerSubType = options.ScsiControllerSubType
+	}
+
+	switch strings.ToLower(options.Firmware) {
+	case "":
+	case BiosFirmware:
+		choices.firmware = BiosFirmware
+	case EfiFirmware:
+		choices.firmware = EfiFirmware
+	default:
+		return choices, errors.New("unsupported firmware '" + options.Firmware +
+			"' - must be '" + BiosFirmware + "' or '" + EfiFirmware + "'")
+	}
+
+	choices.cpuHotAdd = options.CpuHotAdd
+	choices.memoryHotAdd = options.MemoryHotAdd
+
+	parsed, err := toOvf(bytes.NewReader(raw))
+	if err != nil {
+		return choices, err
+	}
+
+	hardware := parsed.Envelope.VirtualSystem.Machine.Hardware
+
+	if options.MapDisplay {
+		displayConfigs, err := displayExtraConfigs(hardware.Display)
+		if err != nil {
+			return choices, err
+		}
+
+		choices.extraConfigs = append(choices.extraConfigs, displayConfigs...)
+	}
+
+	if options.UsbTablet {
+		choices.extraConfigs = append(choices.extraConfigs, usbTabletExtraConfigs()...)
+	}
+
+	if options.PreserveBootOrder {
+		bootConfigs, err := bootOrderExtraConfig(hardware.Boot)
+		if err != nil {
+			return choices, err
+		}
+
+		choices.extraConfigs = append(choices.extraConfigs, bootConfigs...)
+	}
+
+	err = resolveSecureBoot(&choices, hardware.SecureBoot)
+	if err != nil {
+		return choices, err
+	}
+
+	err = resolveVirtualTPM(&choices, hardware.TPM, options)
+	if err != nil {
+		return choices, err
+	}
+
+	err = resolveHardwareVersion(&choices, options.HardwareVersion)
+	if err != nil {
+		return choices, err
+	}
+
+	return choices, nil
+}
+
+// detectHardware chooses hardware based on the guest OS and firmware
+// declared in the .ovf.
+func detectHardware(raw []byte) (hardwareChoices, error) {
+	var choices hardwareChoices
+
+	parsed, err := toOvf(strings.NewReader(string(raw)))
+	if err != nil {
+		return choices, err
+	}
+
+	virtualSystem := parsed.Envelope.VirtualSystem
+
+	osType := virtualSystem.OperatingSystemSection.VboxOSType
+	if len(osType) == 0 {
+		osType = virtualSystem.Machine.OSType
+	}
+
+	info, ok := guestos.Lookup(osType)
+	if ok {
+		choices.profile, _ = profileForGuestOS(info)
+	}
+
+	if strings.HasPrefix(strings.ToUpper(virtualSystem.Machine.Hardware.Firmware.Type), "EFI") {
+		choices.firmware = EfiFirmware
+	}
+
+	return choices, nil
+}
@@ -0,0 +1,87 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertAutoDetectGuestOS(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<Hardware>", "<Hardware>\n        <Firmware type=\"EFI\"/>", 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		AutoDetectGuestOS: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	if !strings.Contains(result, "<rasd:ResourceSubType>E1000e</rasd:ResourceSubType>") {
+		t.Fatal("Network adapter was not converted for the detected guest OS:\n'" + result + "'")
+	}
+
+	if !strings.Contains(result, `xmlns:vmw="http://www.vmware.com/schema/ovf"`) {
+		t.Fatal("The vmw namespace was not declared:\n'" + result + "'")
+	}
+
+	if !strings.Contains(result, `<vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>`) {
+		t.Fatal("EFI firmware was not detected:\n'" + result + "'")
+	}
+}
+
+func TestBasicConvertOverrides(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		AutoDetectGuestOS:     true,
+		NetworkAdapterSubType: "VmxNet3",
+		Firmware:              "BIOS",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	if !strings.Contains(result, "<rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>") {
+		t.Fatal("Network adapter override was not applied:\n'" + result + "'")
+	}
+
+	if !strings.Contains(result, `vmw:key="firmware" vmw:value="bios"`) {
+		t.Fatal("Firmware override was not applied:\n'" + result + "'")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		Firmware: "junk",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for unsupported firmware")
+	}
+}
+
+func TestBasicConvertHotAdd(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		Firmware:     EfiFirmware,
+		CpuHotAdd:    true,
+		MemoryHotAdd: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected, err := basicConvert(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expectedStr := strings.Replace(expected.String(), `xmlns:vbox="http://www.virtualbox.org/ovf/machine">`,
+		`xmlns:vbox="http://www.virtualbox.org/ovf/machine" xmlns:vmw="http://www.vmware.com/schema/ovf">`, 1)
+	expectedStr = strings.Replace(expectedStr, "    </VirtualHardwareSection>",
+		"      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n"+
+			"      <vmw:Config ovf:required=\"false\" vmw:key=\"cpuHotAddEnabled\" vmw:value=\"true\"/>\n"+
+			"      <vmw:Config ovf:required=\"false\" vmw:key=\"memoryHotAddEnabled\" vmw:value=\"true\"/>\n"+
+			"    </VirtualHardwareSection>", 1)
+
+	if b.String() != expectedStr {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
@@ -0,0 +1,330 @@
+package ova
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DigestConfig configures how DigestFiles calculates file digests.
+type DigestConfig struct {
+	// Algorithm is the digest algorithm (e.g., Sha256Algorithm).
+	// Sha256Algorithm is used if it is empty.
+	Algorithm string
+
+	// Workers is the maximum number of files that are hashed
+	// concurrently. The number of CPUs is used if it is less
+	// than one.
+	Workers int
+
+	// Cache, if non-nil, is used to skip hashing files that have
+	// not changed since their digest was last calculated.
+	Cache DigestCache
+
+	// HashProvider, if non-nil, creates the hashes used to calculate
+	// the digests. StandardHashProvider is used if it is nil.
+	HashProvider HashProvider
+}
+
+func (o DigestConfig) algorithm() string {
+	if len(o.Algorithm) == 0 {
+		return Sha256Algorithm
+	}
+
+	return o.Algorithm
+}
+
+func (o DigestConfig) hashProvider() HashProvider {
+	if o.HashProvider == nil {
+		return StandardHashProvider
+	}
+
+	return o.HashProvider
+}
+
+func (o DigestConfig) workers() int {
+	if o.Workers < 1 {
+		return runtime.NumCPU()
+	}
+
+	return o.Workers
+}
+
+// DigestFiles calculates the digest of each of the specified files,
+// returning a ManifestEntry for each file in the same order. Files are
+// hashed concurrently, which significantly reduces the time needed to
+// generate a manifest for an appliance with several large disk images.
+//
+// Each ManifestEntry's Filename is the base name of the file, as
+// manifests only refer to files in the same directory.
+func DigestFiles(filePaths []string, config DigestConfig) ([]ManifestEntry, error) {
+	algorithm := config.algorithm()
+	provider := config.hashProvider()
+
+	_, err := provider.NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, len(filePaths))
+	errs := make([]error, len(filePaths))
+	indexes := make(chan int)
+	wait := &sync.WaitGroup{}
+
+	workers := config.workers()
+	if workers > len(filePaths) {
+		workers = len(filePaths)
+	}
+
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			for index := range indexes {
+				var digest string
+				digest, errs[index] = digestFile(filePaths[index], algorithm, provider, config.Cache)
+				entries[index] = ManifestEntry{
+					Algorithm: algorithm,
+					Filename:  filepath.Base(filePaths[index]),
+					Digest:    digest,
+				}
+			}
+		}()
+	}
+
+	for i := range filePaths {
+		indexes <- i
+	}
+	close(indexes)
+
+	wait.Wait()
+
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ManifestConfig configures how GenerateManifest creates a manifest.
+type ManifestConfig struct {
+	// Digest configures how the digests are calculated.
+	Digest DigestConfig
+
+	// RemoteFiles specifies how files referenced by URL are
+	// handled. Remote files are not part of the package, so they
+	// are never included in the manifest. DownloadRemoteFiles is
+	// not supported.
+	RemoteFiles RemoteFilePolicy
+}
+
+// GenerateManifest returns a Manifest containing the digests of an .ovf
+// descriptor and the files it references, which must be stored in the
+// same directory as the descriptor.
+func GenerateManifest(ovfFilePath string, config ManifestConfig) (Manifest, error) {
+	if config.RemoteFiles == DownloadRemoteFiles {
+		return Manifest{}, errors.New("remote files cannot be downloaded when generating a manifest")
+	}
+
+	references, err := readReferences(ovfFilePath, config.RemoteFiles)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	filePaths := []string{ovfFilePath}
+	for _, reference := range references {
+		filePaths = append(filePaths, reference.filePath)
+	}
+
+	entries, err := DigestFiles(filePaths, config.Digest)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return Manifest{Entries: entries}, nil
+}
+
+// digestFile returns the hex encoded digest of a file, consulting the
+// DigestCache first if it is non-nil.
+func digestFile(filePath string, algorithm string, provider HashProvider, cache DigestCache) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	var key DigestCacheKey
+	if cache != nil {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return "", err
+		}
+
+		key = DigestCacheKey{
+			Path:      absPath,
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			Algorithm: algorithm,
+		}
+
+		digest, ok := cache.Get(key)
+		if ok {
+			return digest, nil
+		}
+	}
+
+	h, err := provider.NewHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	if cache != nil {
+		cache.Put(key, digest)
+	}
+
+	return digest, nil
+}
+
+// DigestCacheKey identifies a version of a file. A file whose size or
+// modification time has changed is considered to be a different file.
+type DigestCacheKey struct {
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Algorithm string
+}
+
+// DigestCache stores previously calculated file digests. Implementations
+// must be safe for concurrent use.
+type DigestCache interface {
+	// Get returns the digest stored for the key, and true if the
+	// digest was found.
+	Get(key DigestCacheKey) (string, bool)
+
+	// Put stores the digest for the key.
+	Put(key DigestCacheKey, digest string)
+}
+
+type digestCacheEntry struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Algorithm string    `json:"algorithm"`
+	Digest    string    `json:"digest"`
+}
+
+// FileDigestCache is a DigestCache that can be saved to, and loaded from,
+// a file so that digests are reused when a command is re-run.
+type FileDigestCache struct {
+	filePath string
+	mutex    sync.Mutex
+	entries  map[string][]digestCacheEntry
+}
+
+// Get returns the cached digest of the file described by the key, if the
+// file's size and modification time are unchanged.
+func (o *FileDigestCache) Get(key DigestCacheKey) (string, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, entry := range o.entries[key.Path] {
+		if entry.Size == key.Size && entry.ModTime.Equal(key.ModTime) && entry.Algorithm == key.Algorithm {
+			return entry.Digest, true
+		}
+	}
+
+	return "", false
+}
+
+// Put caches the digest of the file described by the key, replacing any
+// digest of the same algorithm that was cached for the file's path.
+func (o *FileDigestCache) Put(key DigestCacheKey, digest string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// Only one digest is kept per path and algorithm, as digests
+	// of older versions of the file will never be used again.
+	var kept []digestCacheEntry
+	for _, entry := range o.entries[key.Path] {
+		if entry.Algorithm != key.Algorithm {
+			kept = append(kept, entry)
+		}
+	}
+
+	o.entries[key.Path] = append(kept, digestCacheEntry{
+		Size:      key.Size,
+		ModTime:   key.ModTime,
+		Algorithm: key.Algorithm,
+		Digest:    digest,
+	})
+}
+
+// Save writes the cache to the file it was loaded from.
+func (o *FileDigestCache) Save() error {
+	if len(o.filePath) == 0 {
+		return errors.New("the digest cache was not loaded from a file")
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	raw, err := json.MarshalIndent(o.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(o.filePath, raw, 0600)
+}
+
+// NewMemoryDigestCache returns a DigestCache that is only stored in
+// memory.
+func NewMemoryDigestCache() *FileDigestCache {
+	return &FileDigestCache{
+		entries: make(map[string][]digestCacheEntry),
+	}
+}
+
+// LoadFileDigestCache loads a FileDigestCache from the specified file.
+// An empty cache is returned if the file does not exist.
+func LoadFileDigestCache(filePath string) (*FileDigestCache, error) {
+	cache := NewMemoryDigestCache()
+	cache.filePath = filePath
+
+	raw, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &cache.entries)
+	if err != nil {
+		return nil, errors.New("failed to parse digest cache '" + filePath + "' - " + err.Error())
+	}
+
+	if cache.entries == nil {
+		cache.entries = make(map[string][]digestCacheEntry)
+	}
+
+	return cache, nil
+}
@@ -0,0 +1,203 @@
+package ova
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, dir string, files []testFile) []string {
+	var filePaths []string
+
+	for _, file := range files {
+		filePath := filepath.Join(dir, file.name)
+
+		err := os.WriteFile(filePath, []byte(file.contents), 0644)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		filePaths = append(filePaths, filePath)
+	}
+
+	return filePaths
+}
+
+func TestDigestFiles(t *testing.T) {
+	var files []testFile
+	for i := 0; i < 10; i++ {
+		files = append(files, testFile{name: "disk" + strconv.Itoa(i) + ".vmdk", contents: "disk " + strconv.Itoa(i)})
+	}
+
+	filePaths := writeTestFiles(t, t.TempDir(), files)
+
+	entries, err := DigestFiles(filePaths, DigestConfig{Algorithm: Sha1Algorithm, Workers: 3})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(entries) != len(files) {
+		t.Fatal("Got unexpected number of entries - " + strconv.Itoa(len(entries)))
+	}
+
+	for i, file := range files {
+		expected := ManifestEntry{Algorithm: Sha1Algorithm, Filename: file.name, Digest: sha1Hex(file.contents)}
+		if entries[i] != expected {
+			t.Fatal("Got unexpected entry '" + entries[i].String() + "'")
+		}
+	}
+
+	_, err = DigestFiles(append(filePaths, filepath.Join(t.TempDir(), "missing.vmdk")), DigestConfig{})
+	if err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+
+	_, err = DigestFiles(filePaths, DigestConfig{Algorithm: "MD5"})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported algorithm")
+	}
+}
+
+// sha384Provider is a HashProvider that supports the SHA384 algorithm, in
+// addition to the standard ones, and counts the hashes it creates.
+type sha384Provider struct {
+	created atomic.Int32
+}
+
+func (o *sha384Provider) NewHash(algorithm string) (hash.Hash, error) {
+	o.created.Add(1)
+
+	if algorithm == "SHA384" {
+		return sha512.New384(), nil
+	}
+
+	return NewHash(algorithm)
+}
+
+func TestDigestFilesHashProvider(t *testing.T) {
+	filePaths := writeTestFiles(t, t.TempDir(), []testFile{
+		{name: "disk1.vmdk", contents: "disk 1"},
+		{name: "disk2.vmdk", contents: "disk 2"},
+	})
+
+	provider := &sha384Provider{}
+
+	entries, err := DigestFiles(filePaths, DigestConfig{Algorithm: "SHA384", HashProvider: provider})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i, contents := range []string{"disk 1", "disk 2"} {
+		digest := sha512.Sum384([]byte(contents))
+		if entries[i].Algorithm != "SHA384" || entries[i].Digest != hex.EncodeToString(digest[:]) {
+			t.Fatal("Got unexpected entry '" + entries[i].String() + "'")
+		}
+	}
+
+	// One hash is created to check the algorithm is supported.
+	if created := provider.created.Load(); created != 3 {
+		t.Fatalf("Expected the provider to create 3 hashes, it created %d", created)
+	}
+
+	_, err = DigestFiles(filePaths, DigestConfig{Algorithm: "SHA384"})
+	if err == nil {
+		t.Fatal("Expected an error for an algorithm the standard provider does not support")
+	}
+}
+
+func TestDigestFilesCache(t *testing.T) {
+	dir := t.TempDir()
+	filePaths := writeTestFiles(t, dir, []testFile{{name: "disk.vmdk", contents: "disk"}})
+	cachePath := filepath.Join(dir, "cache.json")
+
+	cache, err := LoadFileDigestCache(cachePath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = DigestFiles(filePaths, DigestConfig{Algorithm: Sha1Algorithm, Cache: cache})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = cache.Save()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	cache, err = LoadFileDigestCache(cachePath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Replace the cached digest to prove that the file is not
+	// hashed again while it is unchanged.
+	info, err := os.Stat(filePaths[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	key := DigestCacheKey{Path: filePaths[0], Size: info.Size(), ModTime: info.ModTime(), Algorithm: Sha1Algorithm}
+	if _, ok := cache.Get(key); !ok {
+		t.Fatal("Digest was not loaded from the cache file")
+	}
+	cache.Put(key, "cached")
+
+	entries, err := DigestFiles(filePaths, DigestConfig{Algorithm: Sha1Algorithm, Cache: cache})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if entries[0].Digest != "cached" {
+		t.Fatal("Cached digest was not used - got '" + entries[0].Digest + "'")
+	}
+
+	later := info.ModTime().Add(time.Minute)
+	err = os.Chtimes(filePaths[0], later, later)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entries, err = DigestFiles(filePaths, DigestConfig{Algorithm: Sha1Algorithm, Cache: cache})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if entries[0].Digest != sha1Hex("disk") {
+		t.Fatal("Modified file was not hashed again - got '" + entries[0].Digest + "'")
+	}
+}
+
+func TestGenerateManifest(t *testing.T) {
+	descriptor := `<?xml version="1.0"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:id="file1" ovf:href="test-disk1.vmdk"/>
+  </References>
+</Envelope>
+`
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{
+		{name: "test.ovf", contents: descriptor},
+		{name: "test-disk1.vmdk", contents: "disk"},
+	})
+
+	manifest, err := GenerateManifest(filepath.Join(dir, "test.ovf"), ManifestConfig{Digest: DigestConfig{Algorithm: Sha1Algorithm}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "SHA1(test.ovf)= " + sha1Hex(descriptor) + "\n" +
+		"SHA1(test-disk1.vmdk)= " + sha1Hex("disk") + "\n"
+
+	if string(manifest.Bytes()) != expected {
+		t.Fatal("Got unexpected manifest:\n'" + string(manifest.Bytes()) + "'")
+	}
+}
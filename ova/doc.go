@@ -0,0 +1,8 @@
+// Package ova provides functionality for working with .ova files, which are
+// tar archives containing an .ovf descriptor, an optional manifest of file
+// digests, and the files referenced by the descriptor (e.g., disk images).
+//
+// Archives are processed as a stream of tar entries. Disk images, which can
+// be many gigabytes in size, are copied from the input to the output without
+// being buffered in memory or written to temporary files.
+package ova
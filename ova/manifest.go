@@ -0,0 +1,190 @@
+package ova
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	Sha1Algorithm   = "SHA1"
+	Sha256Algorithm = "SHA256"
+	Sha512Algorithm = "SHA512"
+)
+
+// ManifestEntry is a single line of a manifest, which records the digest
+// of a file in the archive.
+type ManifestEntry struct {
+	// Algorithm is the name of the digest algorithm (e.g., 'SHA256').
+	Algorithm string
+
+	// Filename is the name of the file that the digest was
+	// calculated from.
+	Filename string
+
+	// Digest is the hex encoded digest.
+	Digest string
+}
+
+func (o ManifestEntry) String() string {
+	return o.Algorithm + "(" + o.Filename + ")= " + o.Digest
+}
+
+// Manifest represents a .mf file.
+type Manifest struct {
+	Entries []ManifestEntry
+
+	eol string
+}
+
+// Bytes returns the manifest in its file format.
+func (o Manifest) Bytes() []byte {
+	eol := o.eol
+	if len(eol) == 0 {
+		eol = "\n"
+	}
+
+	buff := bytes.NewBuffer(nil)
+	for _, entry := range o.Entries {
+		buff.WriteString(entry.String())
+		buff.WriteString(eol)
+	}
+
+	return buff.Bytes()
+}
+
+// SetDigest recalculates the digest of the specified file using the
+// algorithm already recorded for the file. It returns false if the file
+// does not appear in the manifest.
+func (o *Manifest) SetDigest(filename string, data []byte) (bool, error) {
+	return o.SetDigestUsing(filename, data, StandardHashProvider)
+}
+
+// SetDigestUsing is the same as SetDigest, but the digest is calculated
+// using a hash.Hash created by the HashProvider.
+func (o *Manifest) SetDigestUsing(filename string, data []byte, provider HashProvider) (bool, error) {
+	for i, entry := range o.Entries {
+		if entry.Filename != filename {
+			continue
+		}
+
+		h, err := provider.NewHash(entry.Algorithm)
+		if err != nil {
+			return false, err
+		}
+
+		h.Write(data)
+
+		o.Entries[i].Digest = hex.EncodeToString(h.Sum(nil))
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// ParseManifest parses a .mf file.
+func ParseManifest(r io.Reader) (Manifest, error) {
+	manifest := Manifest{}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return manifest, err
+	}
+
+	if bytes.Contains(raw, []byte("\r\n")) {
+		manifest.eol = "\r\n"
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	line := 0
+
+	for scanner.Scan() {
+		line = line + 1
+
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+
+		entry, err := parseManifestEntry(text)
+		if err != nil {
+			return manifest, errors.New("failed to parse manifest line " + strconv.Itoa(line) + " - " + err.Error())
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	return manifest, scanner.Err()
+}
+
+// parseManifestEntry parses a manifest line in the form of
+// 'ALGORITHM(filename)= digest'.
+func parseManifestEntry(line string) (ManifestEntry, error) {
+	open := strings.Index(line, "(")
+	end := strings.LastIndex(line, ")")
+	if open < 1 || end < open {
+		return ManifestEntry{}, errors.New("expected 'ALGORITHM(filename)= digest'")
+	}
+
+	rest := strings.TrimSpace(line[end+1:])
+	if !strings.HasPrefix(rest, "=") {
+		return ManifestEntry{}, errors.New("missing '=' after filename")
+	}
+
+	digest := strings.TrimSpace(rest[1:])
+	_, err := hex.DecodeString(digest)
+	if err != nil || len(digest) == 0 {
+		return ManifestEntry{}, errors.New("digest is not hex encoded")
+	}
+
+	return ManifestEntry{
+		Algorithm: strings.TrimSpace(line[:open]),
+		Filename:  line[open+1 : end],
+		Digest:    strings.ToLower(digest),
+	}, nil
+}
+
+// HashProvider creates the hash.Hash used to calculate digests using a
+// manifest algorithm (e.g., Sha256Algorithm). It allows callers to supply
+// their own implementations, such as FIPS-validated or hardware
+// accelerated ones. Implementations must be safe for concurrent use.
+type HashProvider interface {
+	// NewHash returns a new hash.Hash for the algorithm, or a non-nil
+	// error if the algorithm is not supported.
+	NewHash(algorithm string) (hash.Hash, error)
+}
+
+// HashProviderFunc is a HashProvider implemented by a function.
+type HashProviderFunc func(algorithm string) (hash.Hash, error)
+
+func (o HashProviderFunc) NewHash(algorithm string) (hash.Hash, error) {
+	return o(algorithm)
+}
+
+// StandardHashProvider is the HashProvider used when one is not
+// specified. It creates hashes using the standard library's crypto
+// packages (see NewHash).
+var StandardHashProvider HashProvider = HashProviderFunc(NewHash)
+
+// NewHash returns a new hash.Hash for the specified manifest algorithm.
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToUpper(algorithm) {
+	case Sha1Algorithm:
+		return sha1.New(), nil
+	case Sha256Algorithm:
+		return sha256.New(), nil
+	case Sha512Algorithm:
+		return sha512.New(), nil
+	}
+
+	return nil, errors.New("unsupported manifest digest algorithm '" + algorithm + "'")
+}
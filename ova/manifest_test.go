@@ -0,0 +1,131 @@
+package ova
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testManifest = "SHA256(test.ovf)= 1f0a2f0b6a2e4c6f2c0f6b8e1f0a2f0b6a2e4c6f2c0f6b8e1f0a2f0b6a2e4c6f\n" +
+		"SHA256(test-disk1.vmdk)= 9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08\n"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := ParseManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(manifest.Entries) != 2 {
+		t.Fatal("Expected 2 entries, got:\n" + string(manifest.Bytes()))
+	}
+
+	disk := manifest.Entries[1]
+	if disk.Algorithm != Sha256Algorithm || disk.Filename != "test-disk1.vmdk" ||
+		disk.Digest != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Fatal("Got unexpected entry '" + disk.String() + "'")
+	}
+
+	expected := strings.Replace(testManifest, "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+		"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", 1)
+	if string(manifest.Bytes()) != expected {
+		t.Fatal("Got unexpected manifest:\n'" + string(manifest.Bytes()) + "'")
+	}
+}
+
+func TestParseManifestCrLf(t *testing.T) {
+	raw := strings.Replace(strings.ToLower(testManifest), "\n", "\r\n", -1)
+	raw = strings.Replace(raw, "sha256", "SHA256", -1)
+
+	manifest, err := ParseManifest(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(manifest.Bytes()) != raw {
+		t.Fatal("Line endings were not preserved:\n'" + string(manifest.Bytes()) + "'")
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	invalid := []string{
+		"SHA256 test.ovf 1f0a\n",
+		"SHA256(test.ovf) 1f0a\n",
+		"SHA256(test.ovf)= not-hex\n",
+	}
+
+	for _, raw := range invalid {
+		_, err := ParseManifest(strings.NewReader(raw))
+		if err == nil {
+			t.Fatal("Expected an error for '" + raw + "'")
+		}
+	}
+}
+
+func TestManifestSetDigest(t *testing.T) {
+	manifest, err := ParseManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	found, err := manifest.SetDigest("test.ovf", []byte("test"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !found {
+		t.Fatal("Descriptor was not found in the manifest")
+	}
+
+	if manifest.Entries[0].Digest != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Fatal("Got unexpected digest '" + manifest.Entries[0].Digest + "'")
+	}
+
+	found, err = manifest.SetDigest("missing.ovf", []byte("test"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if found {
+		t.Fatal("Missing file was reported as found")
+	}
+
+	manifest.Entries[0].Algorithm = "MD5"
+
+	_, err = manifest.SetDigest("test.ovf", []byte("test"))
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestParseManifestFilenames(t *testing.T) {
+	filenames := []string{
+		"Vendor Appliance (x86_64) disk 1.vmdk",
+		"アプライアンス-disk1.vmdk",
+		strings.Repeat("long-name-", 20) + ".vmdk",
+	}
+
+	var raw string
+	for _, filename := range filenames {
+		raw = raw + "SHA1(" + filename + ")= " + sha1Hex(filename) + "\n"
+	}
+
+	manifest, err := ParseManifest(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(manifest.Entries) != len(filenames) {
+		t.Fatal("Got unexpected manifest:\n'" + string(manifest.Bytes()) + "'")
+	}
+
+	for i, filename := range filenames {
+		if manifest.Entries[i].Filename != filename {
+			t.Fatal("Got unexpected filename '" + manifest.Entries[i].Filename + "'")
+		}
+	}
+
+	if string(manifest.Bytes()) != raw {
+		t.Fatal("Got unexpected manifest:\n'" + string(manifest.Bytes()) + "'")
+	}
+}
@@ -0,0 +1,310 @@
+package ova
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ustarNameSize is the maximum length of a file name in a USTAR header.
+const ustarNameSize = 100
+
+// PackConfig configures how Pack creates an .ova.
+type PackConfig struct {
+	// Digest configures how the manifest's digests are calculated.
+	Digest DigestConfig
+
+	// SkipManifest, when true, omits the manifest from the .ova.
+	SkipManifest bool
+
+	// RejectSymlinks, when true, causes Pack to fail if the .ovf or
+	// any of its referenced files is a symbolic link. Otherwise,
+	// symbolic links are followed and the target's contents are
+	// stored under the link's name.
+	RejectSymlinks bool
+
+	// RejectSparseFiles, when true, causes Pack to fail if any of the
+	// files are sparse.
+	//
+	// The OVF specification requires an .ova to be a standard tar
+	// archive, and the tar writer cannot create sparse entries. As
+	// a result, the holes in a sparse file are stored as zeros and
+	// can drastically increase the size of the .ova. Sparse disk
+	// images should be converted to a streamOptimized .vmdk
+	// before packing.
+	RejectSparseFiles bool
+
+	// RemoteFiles specifies how files referenced by URL are handled.
+	// RejectRemoteFiles is used if it is empty.
+	RemoteFiles RemoteFilePolicy
+
+	// HTTPClient is used to download remote files when using
+	// DownloadRemoteFiles. http.DefaultClient is used if it is nil.
+	HTTPClient *http.Client
+
+	// Reproducible, when true, sets the modification time of every
+	// file to ReproducibleModTime rather than the time the file was
+	// last modified, so that the same files always produce the same
+	// .ova. The files are always stored in the order that they are
+	// listed in the References, and their modes and owners are
+	// never stored.
+	Reproducible bool
+}
+
+// Pack creates an .ova from an .ovf descriptor and the files that it
+// references, which must be stored in the same directory as the
+// descriptor. The .ova is written to w with the descriptor first,
+// followed by a manifest, and then the referenced files, as required
+// by the OVF specification.
+func Pack(ovfFilePath string, w io.Writer, config PackConfig) error {
+	references, err := readReferences(ovfFilePath, config.RemoteFiles)
+	if err != nil {
+		return err
+	}
+
+	descriptorPath := ovfFilePath
+	descriptorName := filepath.Base(ovfFilePath)
+
+	if hasRemoteReferences(references) {
+		tempDir, err := os.MkdirTemp("", "vmwareify-pack-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+
+		descriptorPath, err = downloadReferences(ovfFilePath, references, tempDir, config.HTTPClient)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries := []packEntry{{name: descriptorName, filePath: descriptorPath}}
+	for _, reference := range references {
+		entries = append(entries, packEntry{
+			name:     filepath.Base(reference.filePath),
+			filePath: reference.filePath,
+		})
+	}
+
+	for i := range entries {
+		entries[i].info, err = packableFileInfo(entries[i].filePath, config)
+		if err != nil {
+			return err
+		}
+
+		entries[i].modTime = entries[i].info.ModTime().Truncate(time.Second)
+		if config.Reproducible {
+			entries[i].modTime = ReproducibleModTime
+		}
+	}
+
+	var manifest []byte
+	if !config.SkipManifest {
+		var filePaths []string
+		for _, entry := range entries {
+			filePaths = append(filePaths, entry.filePath)
+		}
+
+		digests, err := DigestFiles(filePaths, config.Digest)
+		if err != nil {
+			return err
+		}
+
+		for i := range digests {
+			digests[i].Filename = entries[i].name
+		}
+
+		manifest = Manifest{Entries: digests}.Bytes()
+	}
+
+	tarWriter := tar.NewWriter(w)
+
+	for i, entry := range entries {
+		err = packFile(tarWriter, entry)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 && manifest != nil {
+			manifestName := strings.TrimSuffix(descriptorName, filepath.Ext(descriptorName)) + manifestExtension
+
+			err = writeEntry(tarWriter, packHeader(manifestName, entry), manifest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+// packEntry is a file that will be stored in an .ova.
+type packEntry struct {
+	name     string
+	filePath string
+	info     os.FileInfo
+	modTime  time.Time
+}
+
+func hasRemoteReferences(references []reference) bool {
+	for _, reference := range references {
+		if reference.remote {
+			return true
+		}
+	}
+
+	return false
+}
+
+// downloadReferences downloads the remote files referenced by an .ovf to
+// the specified directory, and saves a copy of the .ovf to the directory
+// that refers to the downloaded files. It returns the path of the copy.
+func downloadReferences(ovfFilePath string, references []reference, dir string, client *http.Client) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	names := make(map[string]bool)
+	for _, reference := range references {
+		if !reference.remote {
+			names[reference.href] = true
+		}
+	}
+
+	editScheme := ovf.NewEditScheme()
+
+	for i, reference := range references {
+		if !reference.remote {
+			continue
+		}
+
+		name := ovf.RemoteHrefFilename(reference.href)
+		if names[name] {
+			return "", errors.New("remote file '" + reference.href + "' has the same name as another referenced file")
+		}
+		names[name] = true
+
+		filePath, err := download(client, reference.href, dir)
+		if err != nil {
+			return "", err
+		}
+
+		references[i].filePath = filePath
+
+		editScheme.Propose(ovf.SetFileHrefFunc(reference.id, filepath.Base(filePath)), ovf.ReferencesFileName)
+	}
+
+	f, err := os.Open(ovfFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	edited, err := ovf.EditRawOvf(f, editScheme)
+	if err != nil {
+		return "", err
+	}
+
+	descriptorPath := filepath.Join(dir, filepath.Base(ovfFilePath))
+
+	err = os.WriteFile(descriptorPath, edited.Bytes(), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return descriptorPath, nil
+}
+
+// packableFileInfo returns the os.FileInfo of a file to be packed, or
+// a non-nil error if the file cannot be packed.
+func packableFileInfo(filePath string, config PackConfig) (os.FileInfo, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if config.RejectSymlinks {
+			return nil, errors.New("'" + filePath + "' is a symbolic link")
+		}
+
+		info, err = os.Stat(filePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, errors.New("'" + filePath + "' is not a regular file")
+	}
+
+	if config.RejectSparseFiles && isSparse(info) {
+		return nil, errors.New("'" + filePath + "' is a sparse file")
+	}
+
+	return info, nil
+}
+
+// packFile writes the contents of a file to the archive.
+func packFile(tarWriter *tar.Writer, entry packEntry) error {
+	f, err := os.Open(entry.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = tarWriter.WriteHeader(packHeader(entry.name, entry))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tarWriter, f, entry.info.Size())
+	if err != nil {
+		return errors.New("failed to pack '" + entry.filePath + "' (was it modified?) - " + err.Error())
+	}
+
+	return nil
+}
+
+// packHeader returns the tar header of a file in an .ova, using the size
+// and modification time of the specified packEntry. Ownership
+// information is omitted because it is meaningless to the recipient.
+func packHeader(name string, entry packEntry) *tar.Header {
+	return &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     entry.info.Size(),
+		ModTime:  entry.modTime,
+		Typeflag: tar.TypeReg,
+		Format:   headerFormat(name),
+	}
+}
+
+// headerFormat returns the tar format used to store a file name.
+//
+// The OVF specification requires an .ova to be a USTAR archive, which
+// can only store ASCII names of up to 100 characters (longer names can
+// only be split at a '/', which the files in an .ova do not have). Any
+// other name (e.g., one containing unicode characters) is stored in a
+// PAX extended header. PAX is an extension of USTAR that is supported by
+// the common .ova consumers.
+func headerFormat(name string) tar.Format {
+	if len(name) > ustarNameSize {
+		return tar.FormatPAX
+	}
+
+	for _, r := range name {
+		if r < ' ' || r > '~' {
+			return tar.FormatPAX
+		}
+	}
+
+	return tar.FormatUSTAR
+}
@@ -0,0 +1,235 @@
+package ova
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testPackDescriptor = `<?xml version="1.0"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:id="file1" ovf:href="test-disk1.vmdk"/>
+  </References>
+</Envelope>
+`
+)
+
+func TestPack(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{
+		{name: "test.ovf", contents: testPackDescriptor},
+		{name: "test-disk1.vmdk", contents: "disk"},
+		{name: "unreferenced.txt", contents: "junk"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{Digest: DigestConfig{Algorithm: Sha1Algorithm}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []testFile{
+		{name: "test.ovf", contents: testPackDescriptor},
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex(testPackDescriptor) + "\n" +
+			"SHA1(test-disk1.vmdk)= " + sha1Hex("disk") + "\n"},
+		{name: "test-disk1.vmdk", contents: "disk"},
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != len(expected) {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatal("Got unexpected file '" + files[i].name + "':\n'" + files[i].contents + "'")
+		}
+	}
+
+	output.Reset()
+
+	err = Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{SkipManifest: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files = readTestOva(t, output.Bytes())
+	if len(files) != 2 || files[1].name != "test-disk1.vmdk" {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+}
+
+func TestPackSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{{name: "test.ovf", contents: testPackDescriptor}})
+
+	targetDir := t.TempDir()
+	writeTestFiles(t, targetDir, []testFile{{name: "actual.vmdk", contents: "disk"}})
+
+	err := os.Symlink(filepath.Join(targetDir, "actual.vmdk"), filepath.Join(dir, "test-disk1.vmdk"))
+	if err != nil {
+		t.Skip("symbolic links are not supported - " + err.Error())
+	}
+
+	output := bytes.NewBuffer(nil)
+
+	err = Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{SkipManifest: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != 2 || files[1] != (testFile{name: "test-disk1.vmdk", contents: "disk"}) {
+		t.Fatalf("Symbolic link was not followed: %v", files)
+	}
+
+	err = Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{RejectSymlinks: true})
+	if err == nil {
+		t.Fatal("Expected an error when rejecting symbolic links")
+	}
+}
+
+func TestPackSparseFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{{name: "test.ovf", contents: testPackDescriptor}})
+
+	diskPath := filepath.Join(dir, "test-disk1.vmdk")
+	f, err := os.Create(diskPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = f.Truncate(64 << 20)
+	f.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	info, err := os.Stat(diskPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !isSparse(info) {
+		t.Skip("the file system does not support sparse files")
+	}
+
+	err = Pack(filepath.Join(dir, "test.ovf"), bytes.NewBuffer(nil), PackConfig{RejectSparseFiles: true})
+	if err == nil {
+		t.Fatal("Expected an error when rejecting sparse files")
+	}
+}
+
+func TestPackFilenames(t *testing.T) {
+	descriptorName := "Vendor Appliance 日本語.ovf"
+	diskName := "Vendor Appliance (x86_64) " + strings.Repeat("long-name-", 10) + "disk1.vmdk"
+	descriptor := strings.Replace(testPackDescriptor, "test-disk1.vmdk", diskName, 1)
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{
+		{name: descriptorName, contents: descriptor},
+		{name: diskName, contents: "disk"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Pack(filepath.Join(dir, descriptorName), output, PackConfig{Digest: DigestConfig{Algorithm: Sha1Algorithm}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []testFile{
+		{name: descriptorName, contents: descriptor},
+		{name: "Vendor Appliance 日本語.mf", contents: "SHA1(" + descriptorName + ")= " + sha1Hex(descriptor) + "\n" +
+			"SHA1(" + diskName + ")= " + sha1Hex("disk") + "\n"},
+		{name: diskName, contents: "disk"},
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != len(expected) {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatal("Got unexpected file '" + files[i].name + "':\n'" + files[i].contents + "'")
+		}
+	}
+
+	tarReader := tar.NewReader(bytes.NewReader(output.Bytes()))
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if header.Format != tar.FormatPAX {
+			t.Fatal("Expected a PAX header for '" + header.Name + "', got " + header.Format.String())
+		}
+	}
+}
+
+func TestHeaderFormat(t *testing.T) {
+	if headerFormat("test disk1.vmdk") != tar.FormatUSTAR {
+		t.Fatal("Expected a USTAR header for a short ASCII name")
+	}
+
+	if headerFormat(strings.Repeat("a", ustarNameSize+1)) != tar.FormatPAX {
+		t.Fatal("Expected a PAX header for a long name")
+	}
+
+	if headerFormat("disk-é.vmdk") != tar.FormatPAX {
+		t.Fatal("Expected a PAX header for a unicode name")
+	}
+}
+
+func TestPackReproducible(t *testing.T) {
+	dir := t.TempDir()
+	filePaths := writeTestFiles(t, dir, []testFile{
+		{name: "test.ovf", contents: testPackDescriptor},
+		{name: "test-disk1.vmdk", contents: "disk"},
+	})
+
+	var outputs [][]byte
+	for _, modTime := range []time.Time{time.Unix(1700000000, 0), time.Unix(1800000000, 0)} {
+		for _, filePath := range filePaths {
+			err := os.Chtimes(filePath, modTime, modTime)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		output := bytes.NewBuffer(nil)
+
+		err := Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{Reproducible: true})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		outputs = append(outputs, output.Bytes())
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Fatal("Reproducible outputs differ")
+	}
+
+	header, err := tar.NewReader(bytes.NewReader(outputs[0])).Next()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !header.ModTime.Equal(ReproducibleModTime) {
+		t.Fatal("Got unexpected modification time " + header.ModTime.String())
+	}
+}
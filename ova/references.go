@@ -0,0 +1,151 @@
+package ova
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	// RejectRemoteFiles causes an error to be returned if the .ovf
+	// references a file by URL. This is the default.
+	RejectRemoteFiles RemoteFilePolicy = "reject"
+
+	// KeepRemoteFiles leaves references to remote files intact. The
+	// files are not downloaded, and are not included in the manifest.
+	KeepRemoteFiles RemoteFilePolicy = "keep"
+
+	// DownloadRemoteFiles downloads remote files so that they can be
+	// packaged with the .ovf, and updates the references to refer to
+	// the downloaded files.
+	DownloadRemoteFiles RemoteFilePolicy = "download"
+)
+
+// RemoteFilePolicy specifies how files that an .ovf references by URL
+// (e.g., 'ovf:href="https://example.com/disk1.vmdk"') are handled.
+type RemoteFilePolicy string
+
+func (o RemoteFilePolicy) String() string {
+	return string(o)
+}
+
+// ParseRemoteFilePolicy parses a RemoteFilePolicy from its string form.
+// An empty string is parsed as RejectRemoteFiles.
+func ParseRemoteFilePolicy(s string) (RemoteFilePolicy, error) {
+	switch RemoteFilePolicy(s) {
+	case "", RejectRemoteFiles:
+		return RejectRemoteFiles, nil
+	case KeepRemoteFiles:
+		return KeepRemoteFiles, nil
+	case DownloadRemoteFiles:
+		return DownloadRemoteFiles, nil
+	}
+
+	return "", errors.New("unknown remote file policy '" + s + "' - must be '" + RejectRemoteFiles.String() +
+		"', '" + KeepRemoteFiles.String() + "', or '" + DownloadRemoteFiles.String() + "'")
+}
+
+// reference is a file referenced by an .ovf.
+type reference struct {
+	// id is the ovf:id of the File.
+	id string
+
+	// href is the original ovf:href of the File.
+	href string
+
+	// filePath is the path of the file on disk. It is empty for
+	// remote files until they are downloaded.
+	filePath string
+
+	// remote is true if the href is a URL.
+	remote bool
+}
+
+// readReferences returns the files referenced by an .ovf. Local files
+// must be stored in the same directory as the .ovf. Remote files are
+// omitted when using KeepRemoteFiles.
+func readReferences(ovfFilePath string, policy RemoteFilePolicy) ([]reference, error) {
+	f, err := os.Open(ovfFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsed, err := ovf.ToOvf(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []reference
+	for _, file := range parsed.Envelope.References.Files {
+		if ovf.IsRemoteHref(file.Href) {
+			switch policy {
+			case KeepRemoteFiles:
+				continue
+			case DownloadRemoteFiles:
+				references = append(references, reference{
+					id:     file.Id,
+					href:   file.Href,
+					remote: true,
+				})
+				continue
+			default:
+				return nil, errors.New("referenced file '" + file.Href + "' is a remote file - " +
+					"remote files must be kept or downloaded")
+			}
+		}
+
+		if file.Href != filepath.Base(file.Href) {
+			return nil, errors.New("referenced file '" + file.Href +
+				"' is not in the same directory as the .ovf")
+		}
+
+		references = append(references, reference{
+			id:       file.Id,
+			href:     file.Href,
+			filePath: filepath.Join(filepath.Dir(ovfFilePath), file.Href),
+		})
+	}
+
+	return references, nil
+}
+
+// download saves a remote file to the specified directory, and returns
+// the path of the downloaded file.
+func download(client *http.Client, href string, dir string) (string, error) {
+	name := ovf.RemoteHrefFilename(href)
+	if len(name) == 0 {
+		return "", errors.New("failed to determine the filename of remote file '" + href + "'")
+	}
+
+	resp, err := client.Get(href)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("failed to download '" + href + "' - got status code " +
+			strconv.Itoa(resp.StatusCode))
+	}
+
+	filePath := filepath.Join(dir, name)
+
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, resp.Body)
+	if err != nil {
+		return "", errors.New("failed to download '" + href + "' - " + err.Error())
+	}
+
+	return filePath, f.Close()
+}
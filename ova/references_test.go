@@ -0,0 +1,146 @@
+package ova
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testRemoteDescriptor = `<?xml version="1.0"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:id="file1" ovf:href="test-disk1.vmdk"/>
+    <File ovf:id="file2" ovf:href="REMOTE/files/test-disk2.vmdk"/>
+  </References>
+</Envelope>
+`
+)
+
+func TestParseRemoteFilePolicy(t *testing.T) {
+	policy, err := ParseRemoteFilePolicy("")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if policy != RejectRemoteFiles {
+		t.Fatal("Got unexpected default policy '" + policy.String() + "'")
+	}
+
+	_, err = ParseRemoteFilePolicy("junk")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown policy")
+	}
+}
+
+func remoteTestDir(t *testing.T, serverURL string) string {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, []testFile{
+		{name: "test.ovf", contents: strings.Replace(testRemoteDescriptor, "REMOTE", serverURL, 1)},
+		{name: "test-disk1.vmdk", contents: "disk1"},
+	})
+
+	return dir
+}
+
+func TestGenerateManifestRemoteFiles(t *testing.T) {
+	dir := remoteTestDir(t, "https://example.com")
+	ovfFilePath := filepath.Join(dir, "test.ovf")
+
+	_, err := GenerateManifest(ovfFilePath, ManifestConfig{})
+	if err == nil {
+		t.Fatal("Expected an error when rejecting remote files")
+	}
+
+	_, err = GenerateManifest(ovfFilePath, ManifestConfig{RemoteFiles: DownloadRemoteFiles})
+	if err == nil {
+		t.Fatal("Expected an error when downloading remote files")
+	}
+
+	manifest, err := GenerateManifest(ovfFilePath, ManifestConfig{RemoteFiles: KeepRemoteFiles})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(manifest.Entries) != 2 || manifest.Entries[1].Filename != "test-disk1.vmdk" {
+		t.Fatal("Got unexpected manifest:\n'" + string(manifest.Bytes()) + "'")
+	}
+}
+
+func TestPackDownloadRemoteFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/test-disk2.vmdk" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte("disk2"))
+	}))
+	defer server.Close()
+
+	dir := remoteTestDir(t, server.URL)
+	output := bytes.NewBuffer(nil)
+
+	err := Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{
+		Digest:      DigestConfig{Algorithm: Sha1Algorithm},
+		RemoteFiles: DownloadRemoteFiles,
+		HTTPClient:  server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	descriptor := strings.Replace(testRemoteDescriptor, "REMOTE/files/", "", 1)
+
+	expected := []testFile{
+		{name: "test.ovf", contents: descriptor},
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex(descriptor) + "\n" +
+			"SHA1(test-disk1.vmdk)= " + sha1Hex("disk1") + "\n" +
+			"SHA1(test-disk2.vmdk)= " + sha1Hex("disk2") + "\n"},
+		{name: "test-disk1.vmdk", contents: "disk1"},
+		{name: "test-disk2.vmdk", contents: "disk2"},
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != len(expected) {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatal("Got unexpected file '" + files[i].name + "':\n'" + files[i].contents + "'")
+		}
+	}
+
+	missing := remoteTestDir(t, server.URL+"/missing")
+
+	err = Pack(filepath.Join(missing, "test.ovf"), bytes.NewBuffer(nil), PackConfig{
+		RemoteFiles: DownloadRemoteFiles,
+		HTTPClient:  server.Client(),
+	})
+	if err == nil {
+		t.Fatal("Expected an error when a remote file cannot be downloaded")
+	}
+}
+
+func TestPackKeepRemoteFiles(t *testing.T) {
+	dir := remoteTestDir(t, "https://example.com")
+	output := bytes.NewBuffer(nil)
+
+	err := Pack(filepath.Join(dir, "test.ovf"), output, PackConfig{
+		SkipManifest: true,
+		RemoteFiles:  KeepRemoteFiles,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != 2 || !strings.Contains(files[0].contents, "https://example.com/files/test-disk2.vmdk") ||
+		files[1].name != "test-disk1.vmdk" {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+}
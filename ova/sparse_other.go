@@ -0,0 +1,13 @@
+//go:build !unix
+
+package ova
+
+import (
+	"os"
+)
+
+// isSparse always returns false because sparse files cannot be
+// detected on this platform.
+func isSparse(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package ova
+
+import (
+	"os"
+	"syscall"
+)
+
+// isSparse returns true if fewer bytes are allocated for a file than
+// its size, meaning that the file contains holes. Files compressed by
+// the file system are also reported as sparse.
+func isSparse(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	return int64(stat.Blocks)*512 < info.Size()
+}
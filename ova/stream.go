@@ -0,0 +1,380 @@
+package ova
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// DetectionSize is the number of bytes from the start of a file
+	// that IsOva requires.
+	DetectionSize = tarMagicEnd
+
+	// tarMagicStart and tarMagicEnd are the offsets of the 'ustar'
+	// magic string in a tar header.
+	tarMagicStart = 257
+	tarMagicEnd   = 262
+
+	// descriptorSniffSize is the number of bytes from the start of a
+	// file that are checked by IsDescriptor when the file does not
+	// have the descriptor extension.
+	descriptorSniffSize = 64 * 1024
+
+	// DefaultMaxBufferBytes is the default maximum size of a file that
+	// is held in memory while rewriting an .ova.
+	DefaultMaxBufferBytes = 64 << 20
+
+	descriptorExtension  = ".ovf"
+	manifestExtension    = ".mf"
+	certificateExtension = ".cert"
+)
+
+// IsOva returns true if the data begins with a tar header, which is how an
+// .ova is packaged. The data must be at least DetectionSize bytes long.
+func IsOva(data []byte) bool {
+	return len(data) >= tarMagicEnd && bytes.Equal(data[tarMagicStart:tarMagicEnd], []byte("ustar"))
+}
+
+// ReproducibleModTime is the modification time of every file in an .ova
+// that is created or rewritten in reproducible mode (the Unix epoch).
+var ReproducibleModTime = time.Unix(0, 0).UTC()
+
+// ErrMultipleDescriptors is returned when an .ova contains more than one
+// file that could be its .ovf descriptor.
+var ErrMultipleDescriptors = errors.New("the .ova contains more than one .ovf descriptor")
+
+// ErrBufferLimit is returned when a file that is held in memory while
+// rewriting an .ova is larger than RewriteConfig.MaxBufferBytes.
+var ErrBufferLimit = errors.New("the file is too large to hold in memory")
+
+// IsDescriptor returns true if the data begins with a XML document whose
+// root element is an OVF Envelope. Only the start of the document is
+// needed, meaning the data may be truncated after the root element's
+// start tag.
+func IsDescriptor(data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return false
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			return v.Name.Local == "Envelope"
+		case xml.CharData:
+			if len(bytes.TrimSpace(v)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+// DescriptorFunc receives the contents of an .ovf descriptor and returns
+// the replacement descriptor.
+type DescriptorFunc func(descriptor io.Reader) (*bytes.Buffer, error)
+
+// Rewrite reads an .ova from r, and writes a copy of it to w in which the
+// .ovf descriptor has been replaced by the result of the DescriptorFunc.
+//
+// The descriptor is the file with the .ovf extension, or the file whose
+// contents are an OVF Envelope (see IsDescriptor), meaning descriptors
+// with other names (e.g., 'appliance.xml') are found. ErrMultipleDescriptors
+// is returned if more than one file could be the descriptor.
+//
+// The archive is processed in a single pass. Only the descriptor and the
+// manifest are held in memory - all other files are copied directly from
+// r to w through a fixed size buffer, meaning memory use does not grow
+// with the size of the disk images. The descriptor and the manifest may
+// each be at most DefaultMaxBufferBytes (see RewriteConfig). The
+// descriptor's digest is updated in the manifest using the manifest's
+// existing digest algorithm. Certificate (.cert) files are removed
+// because the signature they contain is no longer valid.
+//
+// The OVF specification requires the descriptor to be the first file in
+// the archive, followed by the manifest. A manifest that appears before
+// the descriptor is held until the descriptor has been written.
+func Rewrite(r io.Reader, w io.Writer, convert DescriptorFunc) error {
+	return RewriteWithConfig(r, w, convert, RewriteConfig{})
+}
+
+// RewriteConfig configures how RewriteWithConfig rewrites an .ova.
+type RewriteConfig struct {
+	// NormalizeModes, when true, sets the mode of every file in the
+	// rewritten .ova to 0644 (0755 for directories), which are the
+	// modes used by Pack. Otherwise, the original modes are kept.
+	NormalizeModes bool
+
+	// StripOwnership, when true, removes the user and group IDs and
+	// names from every file in the rewritten .ova. Ownership is
+	// meaningless to the recipient of an .ova, and differs between
+	// the systems that create them.
+	StripOwnership bool
+
+	// Reproducible, when true, rewrites the .ova such that the same
+	// input always produces the same output, regardless of when or
+	// where it was created. It implies NormalizeModes and
+	// StripOwnership. The modification time of every file is set to
+	// ReproducibleModTime, and access and change times are removed.
+	// The files keep their order in the original .ova.
+	Reproducible bool
+
+	// MaxBufferBytes is the maximum size of a file that is held in
+	// memory, which are the descriptor (before and after it is
+	// converted) and the manifest. An error wrapping ErrBufferLimit
+	// is returned if either is larger. DefaultMaxBufferBytes is used
+	// if it is zero, and the size is not limited if it is negative.
+	// Other files are streamed, and are not limited.
+	MaxBufferBytes int64
+
+	// HashProvider, if non-nil, creates the hash used to update the
+	// descriptor's digest in the manifest. StandardHashProvider is
+	// used if it is nil.
+	HashProvider HashProvider
+}
+
+func (o RewriteConfig) maxBufferBytes() int64 {
+	if o.MaxBufferBytes == 0 {
+		return DefaultMaxBufferBytes
+	}
+
+	return o.MaxBufferBytes
+}
+
+func (o RewriteConfig) hashProvider() HashProvider {
+	if o.HashProvider == nil {
+		return StandardHashProvider
+	}
+
+	return o.HashProvider
+}
+
+// normalize modifies a tar header as specified by the RewriteConfig.
+func (o RewriteConfig) normalize(header *tar.Header) {
+	if o.Reproducible {
+		o.NormalizeModes = true
+		o.StripOwnership = true
+
+		header.ModTime = ReproducibleModTime
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+
+		for _, key := range []string{"mtime", "atime", "ctime"} {
+			delete(header.PAXRecords, key)
+		}
+	}
+
+	if o.NormalizeModes {
+		if header.Typeflag == tar.TypeDir {
+			header.Mode = 0755
+		} else {
+			header.Mode = 0644
+		}
+	}
+
+	if o.StripOwnership {
+		header.Uid = 0
+		header.Gid = 0
+		header.Uname = ""
+		header.Gname = ""
+
+		// The IDs and names are also recorded in the PAX records
+		// of archives that store them in PAX extended headers.
+		for _, key := range []string{"uid", "gid", "uname", "gname"} {
+			delete(header.PAXRecords, key)
+		}
+	}
+}
+
+// RewriteWithConfig is the same as Rewrite, but the tar headers of the
+// rewritten .ova are modified as specified by the RewriteConfig.
+func RewriteWithConfig(r io.Reader, w io.Writer, convert DescriptorFunc, config RewriteConfig) error {
+	tarReader := tar.NewReader(r)
+	tarWriter := tar.NewWriter(w)
+
+	var descriptor []byte
+	var descriptorName string
+	var manifestHeader *tar.Header
+	var manifest Manifest
+
+	// The same buffer is used to read every file, so that memory use
+	// is constant regardless of the number or size of files.
+	entry := bufio.NewReaderSize(nil, descriptorSniffSize)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		config.normalize(header)
+
+		entry.Reset(tarReader)
+
+		switch {
+		case isDescriptor(header, entry):
+			if descriptor != nil {
+				return fmt.Errorf("%w ('%s' and '%s')", ErrMultipleDescriptors, descriptorName, header.Name)
+			}
+
+			converted, err := convert(newBufferLimitReader(entry, config.maxBufferBytes()))
+			if err != nil {
+				return fmt.Errorf("failed to convert descriptor '%s' - %w", header.Name, err)
+			}
+
+			if config.maxBufferBytes() > 0 && int64(converted.Len()) > config.maxBufferBytes() {
+				return fmt.Errorf("failed to convert descriptor '%s' - the converted descriptor is %d bytes - %w",
+					header.Name, converted.Len(), ErrBufferLimit)
+			}
+
+			descriptor = converted.Bytes()
+			descriptorName = header.Name
+
+			err = writeEntry(tarWriter, header, descriptor)
+			if err != nil {
+				return err
+			}
+
+			if manifestHeader != nil {
+				err = writeManifest(tarWriter, manifestHeader, manifest, descriptorName, descriptor, config.hashProvider())
+				if err != nil {
+					return err
+				}
+			}
+		case manifestHeader == nil && hasExtension(header, manifestExtension):
+			manifest, err = ParseManifest(newBufferLimitReader(tarReader, config.maxBufferBytes()))
+			if err != nil {
+				return fmt.Errorf("failed to read manifest '%s' - %w", header.Name, err)
+			}
+
+			manifestHeader = header
+
+			if descriptor != nil {
+				err = writeManifest(tarWriter, manifestHeader, manifest, descriptorName, descriptor, config.hashProvider())
+				if err != nil {
+					return err
+				}
+			}
+		case hasExtension(header, certificateExtension):
+			continue
+		default:
+			err = tarWriter.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(tarWriter, entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if descriptor == nil {
+		return errors.New("the .ova does not contain an .ovf descriptor")
+	}
+
+	return tarWriter.Close()
+}
+
+// bufferLimitReader reads from an io.Reader, returning an error wrapping
+// ErrBufferLimit once more than max bytes have been read.
+type bufferLimitReader struct {
+	r         io.Reader
+	max       int64
+	remaining int64
+}
+
+// newBufferLimitReader returns an io.Reader that reads at most max bytes
+// from r. The size is not limited if max is less than one.
+func newBufferLimitReader(r io.Reader, max int64) io.Reader {
+	if max < 1 {
+		return r
+	}
+
+	return &bufferLimitReader{
+		r:         r,
+		max:       max,
+		remaining: max,
+	}
+}
+
+func (o *bufferLimitReader) Read(p []byte) (int, error) {
+	// One more byte than the limit is read to tell whether the
+	// data ends at the limit.
+	if int64(len(p)) > o.remaining+1 {
+		p = p[:o.remaining+1]
+	}
+
+	n, err := o.r.Read(p)
+	o.remaining -= int64(n)
+	if o.remaining < 0 {
+		return n, fmt.Errorf("%w (%d bytes)", ErrBufferLimit, o.max)
+	}
+
+	return n, err
+}
+
+// writeManifest updates the descriptor's digest in the manifest, and
+// writes the manifest to the archive.
+func writeManifest(tarWriter *tar.Writer, header *tar.Header, manifest Manifest, descriptorName string,
+	descriptor []byte, provider HashProvider) error {
+	_, err := manifest.SetDigestUsing(descriptorName, descriptor, provider)
+	if err != nil {
+		return err
+	}
+
+	return writeEntry(tarWriter, header, manifest.Bytes())
+}
+
+// writeEntry writes a regular file to the archive using a copy of the
+// original header, adjusted for the file's new size.
+func writeEntry(tarWriter *tar.Writer, original *tar.Header, data []byte) error {
+	header := *original
+	header.Size = int64(len(data))
+
+	err := tarWriter.WriteHeader(&header)
+	if err != nil {
+		return err
+	}
+
+	_, err = tarWriter.Write(data)
+	return err
+}
+
+// isDescriptor returns true if a tar entry is an .ovf descriptor. The
+// entry's contents are only checked if it does not have the descriptor
+// extension.
+func isDescriptor(header *tar.Header, entry *bufio.Reader) bool {
+	if header.Typeflag != tar.TypeReg || hasExtension(header, manifestExtension) ||
+		hasExtension(header, certificateExtension) {
+		return false
+	}
+
+	if hasExtension(header, descriptorExtension) {
+		return true
+	}
+
+	// Peek returns fewer bytes if the file is smaller.
+	start, _ := entry.Peek(descriptorSniffSize)
+
+	return IsDescriptor(start)
+}
+
+func hasExtension(header *tar.Header, extension string) bool {
+	return header.Typeflag == tar.TypeReg && strings.HasSuffix(strings.ToLower(header.Name), extension)
+}
@@ -0,0 +1,601 @@
+package ova
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testFile struct {
+	name     string
+	contents string
+}
+
+func testOva(t *testing.T, files []testFile) []byte {
+	buff := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(buff)
+
+	for _, file := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     file.name,
+			Mode:     0644,
+			Size:     int64(len(file.contents)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		tarWriter.Write([]byte(file.contents))
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return buff.Bytes()
+}
+
+func readTestOva(t *testing.T, raw []byte) []testFile {
+	var files []testFile
+
+	tarReader := tar.NewReader(bytes.NewReader(raw))
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		contents, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		files = append(files, testFile{name: header.Name, contents: string(contents)})
+	}
+}
+
+func sha1Hex(s string) string {
+	digest := sha1.Sum([]byte(s))
+	return hex.EncodeToString(digest[:])
+}
+
+func upperDescriptor(descriptor io.Reader) (*bytes.Buffer, error) {
+	raw, err := io.ReadAll(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBufferString(strings.ToUpper(string(raw))), nil
+}
+
+func TestIsOva(t *testing.T) {
+	if !IsOva(testOva(t, []testFile{{name: "test.ovf", contents: "<Envelope/>"}})) {
+		t.Fatal("Tar archive was not detected as an .ova")
+	}
+
+	if IsOva([]byte("<Envelope/>")) {
+		t.Fatal("Descriptor was detected as an .ova")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	input := testOva(t, []testFile{
+		{name: "test.ovf", contents: "<envelope/>"},
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex("<envelope/>") + "\n" +
+			"SHA1(test-disk1.vmdk)= " + sha1Hex("disk") + "\n"},
+		{name: "test.cert", contents: "signature"},
+		{name: "test-disk1.vmdk", contents: "disk"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Rewrite(bytes.NewReader(input), output, upperDescriptor)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []testFile{
+		{name: "test.ovf", contents: "<ENVELOPE/>"},
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex("<ENVELOPE/>") + "\n" +
+			"SHA1(test-disk1.vmdk)= " + sha1Hex("disk") + "\n"},
+		{name: "test-disk1.vmdk", contents: "disk"},
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(files))
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatal("Got unexpected file '" + files[i].name + "':\n'" + files[i].contents + "'")
+		}
+	}
+}
+
+func TestRewriteManifestBeforeDescriptor(t *testing.T) {
+	input := testOva(t, []testFile{
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex("<envelope/>") + "\n"},
+		{name: "test.ovf", contents: "<envelope/>"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Rewrite(bytes.NewReader(input), output, upperDescriptor)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != 2 || files[0].name != "test.ovf" ||
+		files[1].contents != "SHA1(test.ovf)= "+sha1Hex("<ENVELOPE/>")+"\n" {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+}
+
+func TestRewriteErrors(t *testing.T) {
+	err := Rewrite(bytes.NewReader(testOva(t, []testFile{{name: "disk.vmdk", contents: "disk"}})),
+		io.Discard, upperDescriptor)
+	if err == nil {
+		t.Fatal("Expected an error for an .ova without a descriptor")
+	}
+
+	expectedErr := errors.New("bad descriptor")
+
+	err = Rewrite(bytes.NewReader(testOva(t, []testFile{{name: "test.ovf", contents: "<envelope/>"}})),
+		io.Discard, func(io.Reader) (*bytes.Buffer, error) {
+			return nil, expectedErr
+		})
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("Got unexpected error: %v", err)
+	}
+}
+
+func TestIsDescriptor(t *testing.T) {
+	descriptors := []string{
+		"<Envelope/>",
+		"\xef\xbb\xbf<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<!-- comment -->\n<Envelope xmlns=\"http://schemas.dmtf.org/ovf/envelope/1\">",
+		"<ovf:Envelope xmlns:ovf=\"http://schemas.dmtf.org/ovf/envelope/1\"></ovf:Envelope>",
+	}
+
+	for _, descriptor := range descriptors {
+		if !IsDescriptor([]byte(descriptor)) {
+			t.Fatal("Descriptor was not detected - '" + descriptor + "'")
+		}
+	}
+
+	others := []string{
+		"",
+		"disk",
+		"<?xml version=\"1.0\"?>\n<Settings/>",
+		"text<Envelope/>",
+	}
+
+	for _, other := range others {
+		if IsDescriptor([]byte(other)) {
+			t.Fatal("Data was detected as a descriptor - '" + other + "'")
+		}
+	}
+}
+
+func TestRewriteDescriptorWithOtherExtension(t *testing.T) {
+	input := testOva(t, []testFile{
+		{name: "settings.xml", contents: "<Settings/>"},
+		{name: "appliance.xml", contents: "<Envelope/>"},
+		{name: "appliance.mf", contents: "SHA1(appliance.xml)= " + sha1Hex("<Envelope/>") + "\n"},
+		{name: "disk1.vmdk", contents: "disk"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Rewrite(bytes.NewReader(input), output, upperDescriptor)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []testFile{
+		{name: "settings.xml", contents: "<Settings/>"},
+		{name: "appliance.xml", contents: "<ENVELOPE/>"},
+		{name: "appliance.mf", contents: "SHA1(appliance.xml)= " + sha1Hex("<ENVELOPE/>") + "\n"},
+		{name: "disk1.vmdk", contents: "disk"},
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(files))
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatal("Got unexpected file '" + files[i].name + "':\n'" + files[i].contents + "'")
+		}
+	}
+}
+
+func TestRewriteMultipleDescriptors(t *testing.T) {
+	input := testOva(t, []testFile{
+		{name: "test.ovf", contents: "<Envelope/>"},
+		{name: "backup.xml", contents: "<?xml version=\"1.0\"?>\n<Envelope/>"},
+	})
+
+	err := Rewrite(bytes.NewReader(input), io.Discard, upperDescriptor)
+	if !errors.Is(err, ErrMultipleDescriptors) {
+		t.Fatalf("Got unexpected error: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "'test.ovf' and 'backup.xml'") {
+		t.Fatal("Error does not name the descriptors - " + err.Error())
+	}
+}
+
+func TestRewriteFilenames(t *testing.T) {
+	descriptorName := "Vendor Appliance " + strings.Repeat("長い名前", 20) + ".ovf"
+	diskName := "Vendor Appliance disk (1).vmdk"
+
+	input := testOva(t, []testFile{
+		{name: descriptorName, contents: "<envelope/>"},
+		{name: "Vendor Appliance.mf", contents: "SHA256(" + descriptorName + ")= " + sha1Hex("<envelope/>") + "\n"},
+		{name: diskName, contents: "disk"},
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := Rewrite(bytes.NewReader(input), output, upperDescriptor)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != 3 || files[0].name != descriptorName || files[2].name != diskName {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+
+	digest := sha256.Sum256([]byte("<ENVELOPE/>"))
+	if files[1].contents != "SHA256("+descriptorName+")= "+hex.EncodeToString(digest[:])+"\n" {
+		t.Fatal("Got unexpected manifest:\n'" + files[1].contents + "'")
+	}
+}
+
+func TestRewriteWithConfigNormalizesHeaders(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(buff)
+
+	for _, file := range []testFile{{name: "test.ovf", contents: "<Envelope/>"}, {name: "test-disk1.vmdk", contents: "disk"}} {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     file.name,
+			Mode:     0600,
+			Size:     int64(len(file.contents)),
+			Uid:      1000,
+			Gid:      1000,
+			Uname:    "ユーザー",
+			Gname:    "staff",
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		tarWriter.Write([]byte(file.contents))
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	readHeaders := func(raw []byte) []*tar.Header {
+		var headers []*tar.Header
+
+		tarReader := tar.NewReader(bytes.NewReader(raw))
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				return headers
+			}
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			headers = append(headers, header)
+		}
+	}
+
+	output := bytes.NewBuffer(nil)
+
+	err = Rewrite(bytes.NewReader(buff.Bytes()), output, upperDescriptor)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, header := range readHeaders(output.Bytes()) {
+		if header.Mode != 0600 || header.Uid != 1000 || header.Uname != "ユーザー" {
+			t.Fatalf("Header of '%s' was modified by default - %+v", header.Name, header)
+		}
+	}
+
+	output.Reset()
+
+	err = RewriteWithConfig(bytes.NewReader(buff.Bytes()), output, upperDescriptor, RewriteConfig{
+		NormalizeModes: true,
+		StripOwnership: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	headers := readHeaders(output.Bytes())
+	if len(headers) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(headers))
+	}
+
+	for _, header := range headers {
+		if header.Mode != 0644 || header.Uid != 0 || header.Gid != 0 ||
+			len(header.Uname) > 0 || len(header.Gname) > 0 {
+			t.Fatalf("Header of '%s' was not normalized - %+v", header.Name, header)
+		}
+	}
+}
+
+func TestRewriteWithConfigReproducible(t *testing.T) {
+	createOva := func(modTime time.Time, uid int) []byte {
+		buff := bytes.NewBuffer(nil)
+		tarWriter := tar.NewWriter(buff)
+
+		for _, file := range []testFile{{name: "test.ovf", contents: "<Envelope/>"}, {name: "test-disk1.vmdk", contents: "disk"}} {
+			err := tarWriter.WriteHeader(&tar.Header{
+				Name:       file.name,
+				Mode:       0600,
+				Size:       int64(len(file.contents)),
+				Uid:        uid,
+				ModTime:    modTime,
+				AccessTime: modTime,
+				Typeflag:   tar.TypeReg,
+				Format:     tar.FormatPAX,
+			})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			tarWriter.Write([]byte(file.contents))
+		}
+
+		err := tarWriter.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return buff.Bytes()
+	}
+
+	var outputs [][]byte
+	for i, input := range [][]byte{
+		createOva(time.Unix(1700000000, 123456789), 1000),
+		createOva(time.Unix(1800000000, 0), 501),
+	} {
+		output := bytes.NewBuffer(nil)
+
+		err := RewriteWithConfig(bytes.NewReader(input), output, upperDescriptor, RewriteConfig{Reproducible: true})
+		if err != nil {
+			t.Fatalf("input %d - %s", i, err)
+		}
+
+		outputs = append(outputs, output.Bytes())
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Fatal("Reproducible outputs differ")
+	}
+
+	tarReader := tar.NewReader(bytes.NewReader(outputs[0]))
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !header.ModTime.Equal(ReproducibleModTime) || !header.AccessTime.IsZero() || header.Mode != 0644 {
+			t.Fatalf("Header of '%s' is not reproducible - %+v", header.Name, header)
+		}
+	}
+}
+
+func TestRewriteWithConfigMaxBufferBytes(t *testing.T) {
+	descriptor := "<envelope>" + strings.Repeat(" ", 1024) + "</envelope>"
+
+	tests := []struct {
+		name    string
+		files   []testFile
+		convert DescriptorFunc
+	}{
+		{
+			name:    "descriptor",
+			files:   []testFile{{name: "test.ovf", contents: descriptor}},
+			convert: upperDescriptor,
+		},
+		{
+			name:  "converted descriptor",
+			files: []testFile{{name: "test.ovf", contents: "<envelope/>"}},
+			convert: func(io.Reader) (*bytes.Buffer, error) {
+				return bytes.NewBufferString(descriptor), nil
+			},
+		},
+		{
+			name: "manifest",
+			files: []testFile{
+				{name: "test.ovf", contents: "<envelope/>"},
+				{name: "test.mf", contents: strings.Repeat("SHA1(test-disk1.vmdk)= "+sha1Hex("disk")+"\n", 32)},
+			},
+			convert: upperDescriptor,
+		},
+	}
+
+	for _, test := range tests {
+		err := RewriteWithConfig(bytes.NewReader(testOva(t, test.files)), io.Discard, test.convert,
+			RewriteConfig{MaxBufferBytes: 512})
+		if !errors.Is(err, ErrBufferLimit) {
+			t.Fatalf("%s - got unexpected error: %v", test.name, err)
+		}
+
+		err = RewriteWithConfig(bytes.NewReader(testOva(t, test.files)), io.Discard, test.convert,
+			RewriteConfig{MaxBufferBytes: -1})
+		if err != nil {
+			t.Fatalf("%s - unlimited buffer failed - %s", test.name, err)
+		}
+	}
+
+	err := RewriteWithConfig(bytes.NewReader(testOva(t, []testFile{{name: "test.ovf", contents: descriptor}})),
+		io.Discard, upperDescriptor, RewriteConfig{MaxBufferBytes: int64(len(descriptor))})
+	if err != nil {
+		t.Fatalf("Descriptor at the limit failed - %s", err)
+	}
+}
+
+// zeroReader is an io.Reader of endless zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// largeTestsEnv is the environment variable that enables tests which
+// process several gigabytes of data.
+const largeTestsEnv = "VMWAREIFY_LARGE_TESTS"
+
+func TestRewriteLargeOvaUsesConstantMemory(t *testing.T) {
+	// The disk image is several times larger than the allowed
+	// allocations. When explicitly enabled, it is grown past 2 GiB,
+	// so that sizes and byte counts which do not fit in a signed
+	// 32-bit integer are also streamed. The tar format itself is not
+	// a concern: its octal size field holds up to 8 GiB, and larger
+	// files use PAX headers.
+	var diskSize int64 = 64 << 20
+	if os.Getenv(largeTestsEnv) == "1" {
+		diskSize = 3 << 30
+	} else if testing.Short() {
+		t.Skip("skipping .ova with a large disk image in short mode")
+	}
+
+	// The .ova is generated while it is read, so that the test
+	// itself does not hold the disk image in memory.
+	r, w := io.Pipe()
+	go func() {
+		tarWriter := tar.NewWriter(w)
+
+		files := []struct {
+			name string
+			size int64
+			r    io.Reader
+		}{
+			{name: "test.ovf", size: int64(len("<envelope/>")), r: strings.NewReader("<envelope/>")},
+			{name: "test-disk1.vmdk", size: diskSize, r: io.LimitReader(zeroReader{}, diskSize)},
+		}
+
+		for _, file := range files {
+			err := tarWriter.WriteHeader(&tar.Header{
+				Name:     file.name,
+				Mode:     0644,
+				Size:     file.size,
+				Typeflag: tar.TypeReg,
+			})
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+
+			_, err = io.Copy(tarWriter, file.r)
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+
+		w.CloseWithError(tarWriter.Close())
+	}()
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	output := &countingDiscard{}
+
+	err := RewriteWithConfig(r, output, upperDescriptor, RewriteConfig{MaxBufferBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	if output.n < diskSize {
+		t.Fatalf("Expected at least %d bytes, got %d", diskSize, output.n)
+	}
+
+	// The allocations include those of the goroutine writing the
+	// .ova, which are also constant.
+	const maxAllocated = 16 << 20
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if allocated > maxAllocated {
+		t.Fatalf("Rewriting a %d byte .ova allocated %d bytes, expected at most %d",
+			output.n, allocated, maxAllocated)
+	}
+}
+
+// countingDiscard is an io.Writer that discards data, counting the
+// number of bytes written to it.
+type countingDiscard struct {
+	n int64
+}
+
+func (o *countingDiscard) Write(p []byte) (int, error) {
+	o.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestRewriteWithConfigHashProvider(t *testing.T) {
+	input := testOva(t, []testFile{
+		{name: "test.ovf", contents: "<envelope/>"},
+		{name: "test.mf", contents: "SHA1(test.ovf)= " + sha1Hex("<envelope/>") + "\n"},
+	})
+
+	// The provider replaces every digest with that of SHA256, so
+	// that the test can tell whether it was used.
+	provider := HashProviderFunc(func(string) (hash.Hash, error) {
+		return sha256.New(), nil
+	})
+
+	output := bytes.NewBuffer(nil)
+
+	err := RewriteWithConfig(bytes.NewReader(input), output, upperDescriptor, RewriteConfig{HashProvider: provider})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	digest := sha256.Sum256([]byte("<ENVELOPE/>"))
+	expected := "SHA1(test.ovf)= " + hex.EncodeToString(digest[:]) + "\n"
+
+	files := readTestOva(t, output.Bytes())
+	if len(files) != 2 || files[1].contents != expected {
+		t.Fatalf("Got unexpected files: %v", files)
+	}
+}
@@ -0,0 +1,65 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+const (
+	// AnnotationSectionName is the name of the section containing a
+	// free-form description of a VirtualSystem, which VMWare shows
+	// as the virtual machine's notes.
+	AnnotationSectionName ObjectName = "AnnotationSection"
+)
+
+// AnnotationSection is a free-form description of a VirtualSystem.
+type AnnotationSection struct {
+	XMLName    xml.Name `xml:"AnnotationSection"`
+	Info       string   `xml:"Info"`
+	Annotation string   `xml:"Annotation"`
+}
+
+// SetAnnotationFunc returns an EditObjectFunc that replaces the
+// AnnotationSection of a VirtualSystem with one containing the specified
+// Info and Annotation, adding the section if it does not exist. The
+// section is written on a single line per element, meaning line breaks
+// in the annotation are escaped. It should be proposed for the
+// VirtualSystemName.
+func SetAnnotationFunc(info string, annotation string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// A missing section is not an error.
+		_ = o.DeleteChild(AnnotationSectionName.String())
+
+		err := o.InsertChild(annotationSection(info, annotation, o.RelativeBodyPrefix()))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+func annotationSection(info string, annotation string, indent string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString("<AnnotationSection>\n")
+
+	b.WriteString(indent + "<Info>")
+	xml.EscapeText(b, []byte(info))
+	b.WriteString("</Info>\n")
+
+	b.WriteString(indent + "<Annotation>")
+	xml.EscapeText(b, []byte(annotation))
+	b.WriteString("</Annotation>\n")
+
+	b.WriteString("</AnnotationSection>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,82 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetAnnotationFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(SetAnnotationFunc("Notes", "first"), VirtualSystemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	editScheme = NewEditScheme().Propose(SetAnnotationFunc("Notes", "first\n\n<second> & third"), VirtualSystemName)
+
+	b, err = EditRawOvf(strings.NewReader(b.String()), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "    </vbox:Machine>\n  </VirtualSystem>",
+		"    </vbox:Machine>\n"+
+			"    <AnnotationSection>\n"+
+			"      <Info>Notes</Info>\n"+
+			"      <Annotation>first&#xA;&#xA;&lt;second&gt; &amp; third</Annotation>\n"+
+			"    </AnnotationSection>\n"+
+			"  </VirtualSystem>", 1)
+	if expected == basicOvfFileContents {
+		t.Fatal("Failed to find end of VirtualSystem in test data")
+	}
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	parsed, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	section := parsed.Envelope.VirtualSystem.AnnotationSection
+	if section.Info != "Notes" || section.Annotation != "first\n\n<second> & third" {
+		t.Fatalf("Got unexpected AnnotationSection: %+v", section)
+	}
+}
+
+func TestToOvfVboxSharedFolders(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "      </Hardware>",
+		"        <SharedFolders>\n"+
+			"          <SharedFolder name=\"projects\" hostPath=\"/Users/someone/projects\" writable=\"true\" autoMount=\"true\"/>\n"+
+			"          <SharedFolder name=\"media\" hostPath=\"/Volumes/media\" writable=\"false\" autoMount=\"false\"/>\n"+
+			"        </SharedFolders>\n"+
+			"      </Hardware>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add shared folders to the test input")
+	}
+
+	parsed, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []VboxSharedFolder{
+		{Name: "projects", HostPath: "/Users/someone/projects", Writable: "true", AutoMount: "true"},
+		{Name: "media", HostPath: "/Volumes/media", Writable: "false", AutoMount: "false"},
+	}
+
+	folders := parsed.Envelope.VirtualSystem.Machine.Hardware.SharedFolders
+	if len(folders) != len(expected) {
+		t.Fatalf("Expected %d shared folders, got %d", len(expected), len(folders))
+	}
+
+	for i := range expected {
+		folders[i].XMLName = expected[i].XMLName
+		if folders[i] != expected[i] {
+			t.Fatalf("Got unexpected shared folder: %+v", folders[i])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ovf
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ApplyToItem runs EditObjectFunc against a single Item in the same way
+// as EditRawOvf, without a document or an EditScheme. Each func receives
+// the Item returned by the last func that returned Replace, and a func
+// that returns Delete stops the remaining funcs from running.
+//
+// The resulting Item is returned along with the overall EditAction:
+// Delete if a func deleted the Item (in which case the Item is the one
+// that was deleted), Replace if any func replaced it, and NoOp otherwise.
+// A non-nil error is returned if a func returns Replace without an Item.
+func ApplyToItem(item Item, funcs ...EditObjectFunc) (Item, EditAction, error) {
+	current := item
+	action := NoOp
+
+	for i, f := range funcs {
+		result := f(current)
+		switch result.Action {
+		case NoOp:
+			continue
+		case Delete:
+			return current, Delete, nil
+		case Replace:
+			replacement, ok := result.Object.(*Item)
+			if !ok || replacement == nil {
+				return item, NoOp, errors.New("EditObjectFunc " + strconv.Itoa(i) +
+					" returned '" + Replace.String() + "' without an Item")
+			}
+
+			current = *replacement
+			action = Replace
+		default:
+			return item, NoOp, errors.New("EditObjectFunc " + strconv.Itoa(i) +
+				" returned unknown action '" + result.Action.String() + "'")
+		}
+	}
+
+	return current, action, nil
+}
@@ -0,0 +1,48 @@
+package ovf
+
+import (
+	"testing"
+)
+
+func TestApplyToItem(t *testing.T) {
+	nic := Item{InstanceID: "8", ResourceType: EthernetAdapterResourceType, ResourceSubType: "E1000"}
+
+	result, action, err := ApplyToItem(nic,
+		SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"),
+		DeleteHardwareItemByInstanceIDFunc("99"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if action != Replace || result.ResourceSubType != "VmxNet3" {
+		t.Fatalf("Got unexpected result - %s %+v", action, result)
+	}
+
+	result, action, err = ApplyToItem(nic,
+		SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"),
+		DeleteHardwareItemByInstanceIDFunc("8"),
+		SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "E1000e"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if action != Delete || result.ResourceSubType != "VmxNet3" {
+		t.Fatalf("Got unexpected result - %s %+v", action, result)
+	}
+
+	result, action, err = ApplyToItem(nic, DeleteHardwareItemByInstanceIDFunc("99"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if action != NoOp || result != nic {
+		t.Fatalf("Got unexpected result - %s %+v", action, result)
+	}
+
+	_, _, err = ApplyToItem(nic, func(interface{}) EditObjectResult {
+		return EditObjectResult{Action: Replace}
+	})
+	if err == nil {
+		t.Fatal("Expected an error when a func replaces an Item without an Item")
+	}
+}
@@ -0,0 +1,92 @@
+package ovf
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// AttrMap contains the attributes of an element in the order that they
+// appeared, including its namespace declarations. The Space of each
+// attribute's name is its namespace prefix (e.g., 'xmlns' or 'ovf')
+// rather than its namespace URL, meaning that the attributes can be
+// written exactly as they were declared.
+type AttrMap []xml.Attr
+
+// newAttrMap returns an AttrMap of attributes whose namespaces have been
+// resolved by an xml.Decoder. The namespace URL of each attribute is
+// replaced by the first prefix that the attributes declare for it.
+func newAttrMap(attrs []xml.Attr) AttrMap {
+	prefixes := map[string]string{
+		xmlNamespace: "xml",
+	}
+
+	for _, attr := range attrs {
+		if attr.Name.Space != "xmlns" {
+			continue
+		}
+
+		if _, ok := prefixes[attr.Value]; !ok {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+
+	m := make(AttrMap, 0, len(attrs))
+	for _, attr := range attrs {
+		if prefix, ok := prefixes[attr.Name.Space]; ok {
+			attr.Name.Space = prefix
+		}
+
+		m = append(m, attr)
+	}
+
+	return m
+}
+
+// Get returns the value of the attribute with the specified name, as it
+// was written (e.g., 'ovf:version' or 'xmlns:vbox').
+func (o AttrMap) Get(name string) (string, bool) {
+	for _, attr := range o {
+		if prefixedName(attr.Name) == name {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Set sets the value of the attribute with the specified name (e.g.,
+// 'xmlns:vmw'). The attribute is added after the existing attributes if
+// it does not exist.
+func (o *AttrMap) Set(name string, value string) {
+	for i, attr := range *o {
+		if prefixedName(attr.Name) == name {
+			(*o)[i].Value = value
+			return
+		}
+	}
+
+	var attrName xml.Name
+	if i := strings.Index(name, ":"); i > -1 {
+		attrName = xml.Name{Space: name[:i], Local: name[i+1:]}
+	} else {
+		attrName = xml.Name{Local: name}
+	}
+
+	*o = append(*o, xml.Attr{Name: attrName, Value: value})
+}
+
+// Namespaces returns the namespace declarations, keyed by prefix. The
+// default namespace has an empty prefix.
+func (o AttrMap) Namespaces() map[string]string {
+	namespaces := make(map[string]string)
+	for _, attr := range o {
+		switch {
+		case attr.Name.Space == "xmlns":
+			namespaces[attr.Name.Local] = attr.Value
+		case len(attr.Name.Space) == 0 && attr.Name.Local == "xmlns":
+			namespaces[""] = attr.Value
+		}
+	}
+
+	return namespaces
+}
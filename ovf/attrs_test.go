@@ -0,0 +1,55 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeAttrsRoundTrip(t *testing.T) {
+	start := `<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" ` +
+		`xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:cim="http://schemas.dmtf.org/wbem/wscim/1/common" ` +
+		`xmlns:example="http://example.com/ovf" example:build="42" unknown="value">`
+
+	original := basicOvfFileContents[strings.Index(basicOvfFileContents, "<Envelope"):]
+	original = original[:strings.Index(original, "\n")]
+	input := strings.Replace(basicOvfFileContents, original, start, 1)
+
+	r, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	attrs := r.Envelope.Attrs
+
+	out := bytes.NewBuffer(nil)
+	encodeToken(out, xml.StartElement{Name: xml.Name{Local: "Envelope"}, Attr: attrs})
+	if out.String() != start {
+		t.Fatalf("Envelope attributes were not preserved:\n%s\nexpected:\n%s", out.String(), start)
+	}
+
+	value, ok := attrs.Get("example:build")
+	if !ok || value != "42" {
+		t.Fatalf("Got unexpected value for unknown attribute - '%s'", value)
+	}
+
+	expected := map[string]string{
+		"":        OvfNamespace,
+		"ovf":     OvfNamespace,
+		"cim":     "http://schemas.dmtf.org/wbem/wscim/1/common",
+		"example": "http://example.com/ovf",
+	}
+	if !reflect.DeepEqual(attrs.Namespaces(), expected) {
+		t.Fatalf("Got unexpected namespaces - %v", attrs.Namespaces())
+	}
+
+	attrs.Set("example:build", "43")
+	attrs.Set("xmlns:vmw", VmwNamespace)
+
+	value, _ = attrs.Get("example:build")
+	if value != "43" || attrs[len(attrs)-1].Name != (xml.Name{Space: "xmlns", Local: "vmw"}) {
+		t.Fatalf("Set did not modify the attributes as expected - %v", attrs)
+	}
+}
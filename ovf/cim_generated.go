@@ -0,0 +1,229 @@
+// Code generated by cimgen from the DMTF CIM schemas. DO NOT EDIT.
+
+package ovf
+
+import (
+	"encoding/xml"
+)
+
+// System contains the vssd elements of the 'System' element. Only the
+// first value of an element that may occur more than once ('Notes') is
+// kept.
+type System struct {
+	XMLName                              xml.Name `xml:"System"`
+	AutomaticRecoveryAction              string   `xml:"AutomaticRecoveryAction"`
+	AutomaticShutdownAction              string   `xml:"AutomaticShutdownAction"`
+	AutomaticStartupAction               string   `xml:"AutomaticStartupAction"`
+	AutomaticStartupActionDelay          string   `xml:"AutomaticStartupActionDelay"`
+	AutomaticStartupActionSequenceNumber string   `xml:"AutomaticStartupActionSequenceNumber"`
+	Caption                              string   `xml:"Caption"`
+	ConfigurationDataRoot                string   `xml:"ConfigurationDataRoot"`
+	ConfigurationFile                    string   `xml:"ConfigurationFile"`
+	ConfigurationID                      string   `xml:"ConfigurationID"`
+	CreationTime                         string   `xml:"CreationTime"`
+	Description                          string   `xml:"Description"`
+	ElementName                          string   `xml:"ElementName"`
+	InstanceId                           string   `xml:"InstanceID"`
+	LogDataRoot                          string   `xml:"LogDataRoot"`
+	Notes                                string   `xml:"Notes"`
+	RecoveryFile                         string   `xml:"RecoveryFile"`
+	SnapshotDataRoot                     string   `xml:"SnapshotDataRoot"`
+	SuspendDataRoot                      string   `xml:"SuspendDataRoot"`
+	SwapFileDataRoot                     string   `xml:"SwapFileDataRoot"`
+	VirtualSystemIdentifier              string   `xml:"VirtualSystemIdentifier"`
+	VirtualSystemType                    string   `xml:"VirtualSystemType"`
+}
+
+// TODO: Hack for https://github.com/golang/go/issues/9519.
+func (o *System) Marshallable() interface{} {
+	return marshableSystem{
+		AutomaticRecoveryAction:              o.AutomaticRecoveryAction,
+		AutomaticShutdownAction:              o.AutomaticShutdownAction,
+		AutomaticStartupAction:               o.AutomaticStartupAction,
+		AutomaticStartupActionDelay:          o.AutomaticStartupActionDelay,
+		AutomaticStartupActionSequenceNumber: o.AutomaticStartupActionSequenceNumber,
+		Caption:                              o.Caption,
+		ConfigurationDataRoot:                o.ConfigurationDataRoot,
+		ConfigurationFile:                    o.ConfigurationFile,
+		ConfigurationID:                      o.ConfigurationID,
+		CreationTime:                         o.CreationTime,
+		Description:                          o.Description,
+		ElementName:                          o.ElementName,
+		InstanceId:                           o.InstanceId,
+		LogDataRoot:                          o.LogDataRoot,
+		Notes:                                o.Notes,
+		RecoveryFile:                         o.RecoveryFile,
+		SnapshotDataRoot:                     o.SnapshotDataRoot,
+		SuspendDataRoot:                      o.SuspendDataRoot,
+		SwapFileDataRoot:                     o.SwapFileDataRoot,
+		VirtualSystemIdentifier:              o.VirtualSystemIdentifier,
+		VirtualSystemType:                    o.VirtualSystemType,
+	}
+}
+
+// TODO: Hack for https://github.com/golang/go/issues/9519.
+type marshableSystem struct {
+	XMLName                              xml.Name `xml:"System"`
+	AutomaticRecoveryAction              string   `xml:"vssd:AutomaticRecoveryAction,omitempty"`
+	AutomaticShutdownAction              string   `xml:"vssd:AutomaticShutdownAction,omitempty"`
+	AutomaticStartupAction               string   `xml:"vssd:AutomaticStartupAction,omitempty"`
+	AutomaticStartupActionDelay          string   `xml:"vssd:AutomaticStartupActionDelay,omitempty"`
+	AutomaticStartupActionSequenceNumber string   `xml:"vssd:AutomaticStartupActionSequenceNumber,omitempty"`
+	Caption                              string   `xml:"vssd:Caption,omitempty"`
+	ConfigurationDataRoot                string   `xml:"vssd:ConfigurationDataRoot,omitempty"`
+	ConfigurationFile                    string   `xml:"vssd:ConfigurationFile,omitempty"`
+	ConfigurationID                      string   `xml:"vssd:ConfigurationID,omitempty"`
+	CreationTime                         string   `xml:"vssd:CreationTime,omitempty"`
+	Description                          string   `xml:"vssd:Description,omitempty"`
+	ElementName                          string   `xml:"vssd:ElementName"`
+	InstanceId                           string   `xml:"vssd:InstanceID"`
+	LogDataRoot                          string   `xml:"vssd:LogDataRoot,omitempty"`
+	Notes                                string   `xml:"vssd:Notes,omitempty"`
+	RecoveryFile                         string   `xml:"vssd:RecoveryFile,omitempty"`
+	SnapshotDataRoot                     string   `xml:"vssd:SnapshotDataRoot,omitempty"`
+	SuspendDataRoot                      string   `xml:"vssd:SuspendDataRoot,omitempty"`
+	SwapFileDataRoot                     string   `xml:"vssd:SwapFileDataRoot,omitempty"`
+	VirtualSystemIdentifier              string   `xml:"vssd:VirtualSystemIdentifier,omitempty"`
+	VirtualSystemType                    string   `xml:"vssd:VirtualSystemType,omitempty"`
+}
+
+// systemElements are the local names of the elements modeled by System.
+// An element's value is true if it may occur more than once.
+var systemElements = map[string]bool{
+	"AutomaticRecoveryAction":              false,
+	"AutomaticShutdownAction":              false,
+	"AutomaticStartupAction":               false,
+	"AutomaticStartupActionDelay":          false,
+	"AutomaticStartupActionSequenceNumber": false,
+	"Caption":                              false,
+	"ConfigurationDataRoot":                false,
+	"ConfigurationFile":                    false,
+	"ConfigurationID":                      false,
+	"CreationTime":                         false,
+	"Description":                          false,
+	"ElementName":                          false,
+	"InstanceID":                           false,
+	"LogDataRoot":                          false,
+	"Notes":                                true,
+	"RecoveryFile":                         false,
+	"SnapshotDataRoot":                     false,
+	"SuspendDataRoot":                      false,
+	"SwapFileDataRoot":                     false,
+	"VirtualSystemIdentifier":              false,
+	"VirtualSystemType":                    false,
+}
+
+// Item contains the rasd elements of the 'Item' element. Only the first
+// value of an element that may occur more than once ('Connection' and
+// 'HostResource') is kept.
+type Item struct {
+	XMLName               xml.Name `xml:"Item"`
+	Address               string   `xml:"Address"`
+	AddressOnParent       string   `xml:"AddressOnParent"`
+	AllocationUnits       string   `xml:"AllocationUnits"`
+	AutomaticAllocation   bool     `xml:"AutomaticAllocation"`
+	AutomaticDeallocation bool     `xml:"AutomaticDeallocation"`
+	Caption               string   `xml:"Caption"`
+	Connection            string   `xml:"Connection"`
+	ConsumerVisibility    string   `xml:"ConsumerVisibility"`
+	Description           string   `xml:"Description"`
+	ElementName           string   `xml:"ElementName"`
+	HostResource          string   `xml:"HostResource"`
+	InstanceID            string   `xml:"InstanceID"`
+	Limit                 string   `xml:"Limit"`
+	MappingBehavior       string   `xml:"MappingBehavior"`
+	OtherResourceType     string   `xml:"OtherResourceType"`
+	Parent                string   `xml:"Parent"`
+	PoolID                string   `xml:"PoolID"`
+	Reservation           string   `xml:"Reservation"`
+	ResourceSubType       string   `xml:"ResourceSubType"`
+	ResourceType          string   `xml:"ResourceType"`
+	VirtualQuantity       string   `xml:"VirtualQuantity"`
+	VirtualQuantityUnits  string   `xml:"VirtualQuantityUnits"`
+	Weight                string   `xml:"Weight"`
+}
+
+// TODO: Hack for https://github.com/golang/go/issues/9519.
+func (o *Item) Marshallable() interface{} {
+	return marshableItem{
+		Address:               o.Address,
+		AddressOnParent:       o.AddressOnParent,
+		AllocationUnits:       o.AllocationUnits,
+		AutomaticAllocation:   o.AutomaticAllocation,
+		AutomaticDeallocation: o.AutomaticDeallocation,
+		Caption:               o.Caption,
+		Connection:            o.Connection,
+		ConsumerVisibility:    o.ConsumerVisibility,
+		Description:           o.Description,
+		ElementName:           o.ElementName,
+		HostResource:          o.HostResource,
+		InstanceID:            o.InstanceID,
+		Limit:                 o.Limit,
+		MappingBehavior:       o.MappingBehavior,
+		OtherResourceType:     o.OtherResourceType,
+		Parent:                o.Parent,
+		PoolID:                o.PoolID,
+		Reservation:           o.Reservation,
+		ResourceSubType:       o.ResourceSubType,
+		ResourceType:          o.ResourceType,
+		VirtualQuantity:       o.VirtualQuantity,
+		VirtualQuantityUnits:  o.VirtualQuantityUnits,
+		Weight:                o.Weight,
+	}
+}
+
+// TODO: Hack for https://github.com/golang/go/issues/9519.
+type marshableItem struct {
+	XMLName               xml.Name `xml:"Item"`
+	Address               string   `xml:"rasd:Address,omitempty"`
+	AddressOnParent       string   `xml:"rasd:AddressOnParent,omitempty"`
+	AllocationUnits       string   `xml:"rasd:AllocationUnits,omitempty"`
+	AutomaticAllocation   bool     `xml:"rasd:AutomaticAllocation,omitempty"`
+	AutomaticDeallocation bool     `xml:"rasd:AutomaticDeallocation,omitempty"`
+	Caption               string   `xml:"rasd:Caption,omitempty"`
+	Connection            string   `xml:"rasd:Connection,omitempty"`
+	ConsumerVisibility    string   `xml:"rasd:ConsumerVisibility,omitempty"`
+	Description           string   `xml:"rasd:Description,omitempty"`
+	ElementName           string   `xml:"rasd:ElementName"`
+	HostResource          string   `xml:"rasd:HostResource,omitempty"`
+	InstanceID            string   `xml:"rasd:InstanceID"`
+	Limit                 string   `xml:"rasd:Limit,omitempty"`
+	MappingBehavior       string   `xml:"rasd:MappingBehavior,omitempty"`
+	OtherResourceType     string   `xml:"rasd:OtherResourceType,omitempty"`
+	Parent                string   `xml:"rasd:Parent,omitempty"`
+	PoolID                string   `xml:"rasd:PoolID,omitempty"`
+	Reservation           string   `xml:"rasd:Reservation,omitempty"`
+	ResourceSubType       string   `xml:"rasd:ResourceSubType,omitempty"`
+	ResourceType          string   `xml:"rasd:ResourceType"`
+	VirtualQuantity       string   `xml:"rasd:VirtualQuantity,omitempty"`
+	VirtualQuantityUnits  string   `xml:"rasd:VirtualQuantityUnits,omitempty"`
+	Weight                string   `xml:"rasd:Weight,omitempty"`
+}
+
+// itemElements are the local names of the elements modeled by Item. An
+// element's value is true if it may occur more than once.
+var itemElements = map[string]bool{
+	"Address":               false,
+	"AddressOnParent":       false,
+	"AllocationUnits":       false,
+	"AutomaticAllocation":   false,
+	"AutomaticDeallocation": false,
+	"Caption":               false,
+	"Connection":            true,
+	"ConsumerVisibility":    false,
+	"Description":           false,
+	"ElementName":           false,
+	"HostResource":          true,
+	"InstanceID":            false,
+	"Limit":                 false,
+	"MappingBehavior":       false,
+	"OtherResourceType":     false,
+	"Parent":                false,
+	"PoolID":                false,
+	"Reservation":           false,
+	"ResourceSubType":       false,
+	"ResourceType":          false,
+	"VirtualQuantity":       false,
+	"VirtualQuantityUnits":  false,
+	"Weight":                false,
+}
@@ -0,0 +1,258 @@
+package ovf
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// capacityUnits are the units that capacities are written in by
+// SetDiskCapacityFunc, from largest to smallest. The OVF specification
+// expresses units using DMTF programmatic units (DSP0004).
+var capacityUnits = []struct {
+	units      string
+	multiplier int64
+}{
+	{units: "byte * 2^40", multiplier: 1 << 40},
+	{units: "byte * 2^30", multiplier: 1 << 30},
+	{units: "byte * 2^20", multiplier: 1 << 20},
+	{units: "byte * 2^10", multiplier: 1 << 10},
+}
+
+// legacyCapacityUnits are capacity allocation units written by older
+// tools, which predate programmatic units.
+var legacyCapacityUnits = map[string]int64{
+	"kilobytes": 1 << 10,
+	"megabytes": 1 << 20,
+	"gigabytes": 1 << 30,
+}
+
+// humanCapacityUnits are the suffixes accepted by ParseCapacity.
+var humanCapacityUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{suffix: "kib", multiplier: 1 << 10},
+	{suffix: "mib", multiplier: 1 << 20},
+	{suffix: "gib", multiplier: 1 << 30},
+	{suffix: "tib", multiplier: 1 << 40},
+	{suffix: "kb", multiplier: 1e3},
+	{suffix: "mb", multiplier: 1e6},
+	{suffix: "gb", multiplier: 1e9},
+	{suffix: "tb", multiplier: 1e12},
+	{suffix: "k", multiplier: 1 << 10},
+	{suffix: "m", multiplier: 1 << 20},
+	{suffix: "g", multiplier: 1 << 30},
+	{suffix: "t", multiplier: 1 << 40},
+	{suffix: "b", multiplier: 1},
+}
+
+// ParseCapacityAllocationUnits returns the number of bytes represented by
+// a Disk's CapacityAllocationUnits (e.g., 'byte * 2^30' is 1073741824).
+// An empty string is parsed as bytes.
+func ParseCapacityAllocationUnits(units string) (int64, error) {
+	normalized := strings.ToLower(strings.Replace(units, " ", "", -1))
+	if len(normalized) == 0 || normalized == "byte" {
+		return 1, nil
+	}
+
+	multiplier, ok := legacyCapacityUnits[normalized]
+	if ok {
+		return multiplier, nil
+	}
+
+	if !strings.HasPrefix(normalized, "byte*") {
+		return 0, errors.New("unsupported capacity allocation units '" + units + "'")
+	}
+
+	expression := strings.TrimPrefix(normalized, "byte*")
+
+	var base, exponent int64 = 0, 1
+	var err error
+
+	if i := strings.Index(expression, "^"); i > -1 {
+		base, err = strconv.ParseInt(expression[:i], 10, 64)
+		if err == nil {
+			exponent, err = strconv.ParseInt(expression[i+1:], 10, 64)
+		}
+	} else {
+		base, err = strconv.ParseInt(expression, 10, 64)
+	}
+	if err != nil || base < 1 || exponent < 0 {
+		return 0, errors.New("unsupported capacity allocation units '" + units + "'")
+	}
+
+	// The exponent is not bounded by the loop below, as a base of one
+	// never overflows (e.g., 'byte * 1^9223372036854775807').
+	if base == 1 || exponent == 0 {
+		return 1, nil
+	}
+
+	// Any other base overflows an int64 within 63 multiplications.
+	if exponent > 63 {
+		return 0, errors.New("capacity allocation units '" + units + "' are too large")
+	}
+
+	multiplier = 1
+	for i := int64(0); i < exponent; i++ {
+		multiplier, ok = multiplyBytes(multiplier, base)
+		if !ok {
+			return 0, errors.New("capacity allocation units '" + units + "' are too large")
+		}
+	}
+
+	return multiplier, nil
+}
+
+// CapacityBytes returns the Disk's capacity in bytes.
+func (o Disk) CapacityBytes() (int64, error) {
+	capacity, err := strconv.ParseInt(strings.TrimSpace(o.Capacity), 10, 64)
+	if err != nil || capacity < 0 {
+		return 0, errors.New("disk '" + o.DiskId + "' has an unsupported capacity '" + o.Capacity + "'")
+	}
+
+	multiplier, err := ParseCapacityAllocationUnits(o.CapacityAllocationUnits)
+	if err != nil {
+		return 0, errors.New("disk '" + o.DiskId + "' has " + err.Error())
+	}
+
+	bytes, ok := multiplyBytes(capacity, multiplier)
+	if !ok {
+		return 0, errors.New("disk '" + o.DiskId + "' has a capacity that is too large")
+	}
+
+	return bytes, nil
+}
+
+// ParseCapacity parses a capacity in bytes, or in human units (e.g.,
+// '100GiB', '512MiB', or '1TB'). Binary units are also accepted without
+// the 'iB' suffix (e.g., '100G').
+func ParseCapacity(s string) (int64, error) {
+	normalized := strings.ToLower(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+	for _, unit := range humanCapacityUnits {
+		if strings.HasSuffix(normalized, unit.suffix) {
+			normalized = strings.TrimSpace(strings.TrimSuffix(normalized, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseInt(normalized, 10, 64)
+	if err != nil || value < 1 {
+		return 0, errors.New("invalid capacity '" + s + "' - must be a positive number of bytes, " +
+			"or include units (e.g., '100GiB')")
+	}
+
+	bytes, ok := multiplyBytes(value, multiplier)
+	if !ok {
+		return 0, errors.New("capacity '" + s + "' is too large")
+	}
+
+	return bytes, nil
+}
+
+// FormatCapacity returns the capacity and capacity allocation units that
+// represent a number of bytes, using the largest programmatic units that
+// represent it exactly (e.g., 107374182400 is '100' and 'byte * 2^30').
+// The units are empty if the capacity is not a multiple of a kibibyte.
+func FormatCapacity(bytes int64) (string, string) {
+	for _, unit := range capacityUnits {
+		if bytes >= unit.multiplier && bytes%unit.multiplier == 0 {
+			return strconv.FormatInt(bytes/unit.multiplier, 10), unit.units
+		}
+	}
+
+	return strconv.FormatInt(bytes, 10), ""
+}
+
+// SetDiskCapacityFunc returns an EditObjectFunc that sets the capacity of
+// the Disk with the specified diskId. The capacity is parsed using
+// ParseCapacity, and is written using the units chosen by FormatCapacity.
+// A non-nil error is returned if the capacity is invalid.
+func SetDiskCapacityFunc(diskId string, capacity string) (EditObjectFunc, error) {
+	bytes, err := ParseCapacity(capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	return setDiskCapacityBytesFunc(diskId, bytes), nil
+}
+
+func setDiskCapacityBytesFunc(diskId string, bytes int64) EditObjectFunc {
+	value, units := FormatCapacity(bytes)
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("diskId")
+		if current != diskId {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.SetAttr("ovf:capacity", value)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		_, hasUnits := o.Attr("capacityAllocationUnits")
+		switch {
+		case len(units) > 0:
+			err = o.SetAttr("ovf:capacityAllocationUnits", units)
+		case hasUnits:
+			err = o.SetAttr("ovf:capacityAllocationUnits", "byte")
+		}
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// multiplyBytes returns the product of two non-negative numbers, and false
+// if the product overflows an int64.
+func multiplyBytes(a int64, b int64) (int64, bool) {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi != 0 || lo > math.MaxInt64 {
+		return 0, false
+	}
+
+	return int64(lo), true
+}
+
+// SetDiskPopulatedSizeFunc returns an EditObjectFunc that sets the
+// populatedSize of the Disk with the specified diskId, which is the number
+// of bytes of the disk that contain data.
+func SetDiskPopulatedSizeFunc(diskId string, populatedSize int64) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("diskId")
+		if current != diskId {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.SetAttr("ovf:populatedSize", strconv.FormatInt(populatedSize, 10))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
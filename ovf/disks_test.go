@@ -0,0 +1,177 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetDiskPopulatedSizeFunc(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(SetDiskPopulatedSizeFunc("vmdisk1", 2048), DiskName).
+		Propose(SetDiskPopulatedSizeFunc("missing", 1), DiskName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, `vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>`,
+		`vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade" ovf:populatedSize="2048"/>`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestParseCapacityAllocationUnits(t *testing.T) {
+	tests := map[string]int64{
+		"":                             1,
+		"byte":                         1,
+		"byte * 2^30":                  1 << 30,
+		"byte*2^20":                    1 << 20,
+		"byte * 10^9":                  1e9,
+		"byte * 1024":                  1024,
+		"MegaBytes":                    1 << 20,
+		"Byte * 2^0":                   1,
+		" byte * 2^40":                 1 << 40,
+		"byte * 1^9223372036854775807": 1,
+		"byte * 7^0":                   1,
+	}
+
+	for units, expected := range tests {
+		multiplier, err := ParseCapacityAllocationUnits(units)
+		if err != nil {
+			t.Fatal("'" + units + "' - " + err.Error())
+		}
+
+		if multiplier != expected {
+			t.Fatalf("'%s' was parsed as %d - expected %d", units, multiplier, expected)
+		}
+	}
+
+	for _, units := range []string{"bit", "byte * ", "byte * 2^x", "byte * 0", "byte * 2^64", "byte * 2^-1",
+		"byte * 2^9223372036854775807"} {
+		_, err := ParseCapacityAllocationUnits(units)
+		if err == nil {
+			t.Fatal("expected an error for '" + units + "'")
+		}
+	}
+}
+
+func TestDiskCapacityBytes(t *testing.T) {
+	disk := Disk{DiskId: "vmdisk1", Capacity: "100", CapacityAllocationUnits: "byte * 2^30"}
+
+	bytes, err := disk.CapacityBytes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if bytes != 100<<30 {
+		t.Fatalf("got capacity %d", bytes)
+	}
+
+	for _, disk := range []Disk{{Capacity: "${capacity}"}, {Capacity: "1", CapacityAllocationUnits: "bit"},
+		{Capacity: "9223372036854775807", CapacityAllocationUnits: "byte * 2^10"}} {
+		_, err := disk.CapacityBytes()
+		if err == nil {
+			t.Fatalf("expected an error for %+v", disk)
+		}
+	}
+}
+
+func TestParseCapacity(t *testing.T) {
+	tests := map[string]int64{
+		"1024":    1024,
+		"100GiB":  100 << 30,
+		"100 gib": 100 << 30,
+		"512MiB":  512 << 20,
+		"2T":      2 << 40,
+		"1TB":     1e12,
+		"20G":     20 << 30,
+		"4096B":   4096,
+	}
+
+	for s, expected := range tests {
+		bytes, err := ParseCapacity(s)
+		if err != nil {
+			t.Fatal("'" + s + "' - " + err.Error())
+		}
+
+		if bytes != expected {
+			t.Fatalf("'%s' was parsed as %d - expected %d", s, bytes, expected)
+		}
+	}
+
+	for _, s := range []string{"", "GiB", "-1GiB", "0", "1.5GiB", "100PiB", "9999999999TiB"} {
+		_, err := ParseCapacity(s)
+		if err == nil {
+			t.Fatal("expected an error for '" + s + "'")
+		}
+	}
+}
+
+func TestFormatCapacity(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		capacity string
+		units    string
+	}{
+		{bytes: 100 << 30, capacity: "100", units: "byte * 2^30"},
+		{bytes: 1536 << 20, capacity: "1536", units: "byte * 2^20"},
+		{bytes: 2 << 40, capacity: "2", units: "byte * 2^40"},
+		{bytes: 1e12, capacity: "976562500", units: "byte * 2^10"},
+		{bytes: 1000, capacity: "1000", units: ""},
+	}
+
+	for _, test := range tests {
+		capacity, units := FormatCapacity(test.bytes)
+		if capacity != test.capacity || units != test.units {
+			t.Fatalf("%d was formatted as '%s' '%s' - expected '%s' '%s'",
+				test.bytes, capacity, units, test.capacity, test.units)
+		}
+	}
+}
+
+func TestSetDiskCapacityFunc(t *testing.T) {
+	f, err := SetDiskCapacityFunc("vmdisk1", "100GiB")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), NewEditScheme().Propose(f, DiskName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, `ovf:capacity="68719476736"`, `ovf:capacity="100"`, 1)
+	expected = strings.Replace(expected, `vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>`,
+		`vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade" ovf:capacityAllocationUnits="byte * 2^30"/>`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	withUnits := strings.Replace(basicOvfFileContents, `ovf:capacity="68719476736"`,
+		`ovf:capacity="64" ovf:capacityAllocationUnits="byte * 2^30"`, 1)
+
+	f, err = SetDiskCapacityFunc("vmdisk1", "1000")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err = EditRawOvf(strings.NewReader(withUnits), NewEditScheme().Propose(f, DiskName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), `ovf:capacity="1000" ovf:capacityAllocationUnits="byte"`) {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+
+	_, err = SetDiskCapacityFunc("vmdisk1", "lots")
+	if err == nil {
+		t.Fatal("expected an error for an invalid capacity")
+	}
+}
@@ -0,0 +1,276 @@
+package ovf
+
+import (
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// OnSystemFunc edits the System. It returns the resulting System and the
+// EditAction to take.
+type OnSystemFunc func(system System) (System, EditAction)
+
+// OnHardwareItemFunc edits a hardware Item. It returns the resulting Item
+// and the EditAction to take.
+type OnHardwareItemFunc func(item Item) (Item, EditAction)
+
+// OnDiskFunc edits a Disk in the DiskSection. It returns the resulting
+// Disk and the EditAction to take.
+type OnDiskFunc func(disk Disk) (Disk, EditAction)
+
+// OnNetworkFunc edits a Network in the NetworkSection. It returns the
+// resulting Network and the EditAction to take.
+type OnNetworkFunc func(network Network) (Network, EditAction)
+
+// OnFileFunc edits a File in the References. It returns the resulting File
+// and the EditAction to take.
+type OnFileFunc func(file File) (File, EditAction)
+
+// EditOptions is a typed alternative to an EditScheme for the most common
+// edits. The funcs receive and return the modeled objects directly, rather
+// than an interface{} and an EditObjectResult.
+//
+// Use EditOptions when only the modeled objects need to be edited. Use an
+// EditScheme to edit other OVF objects (which are provided as a
+// *RawObject), or to reuse the EditObjectFunc provided by this package.
+// The two can be combined by merging the result of EditScheme with
+// another EditScheme (see MergeEditSchemes).
+type EditOptions struct {
+	// OnSystem funcs are called with the System, in order.
+	OnSystem []OnSystemFunc
+
+	// OnHardwareItems funcs are called with each Item, in order.
+	OnHardwareItems []OnHardwareItemFunc
+
+	// OnDisks funcs are called with each Disk, in order. Only the
+	// attributes of a Disk are replaced, and other attributes
+	// (e.g., 'vbox:uuid') are preserved. An attribute cannot be
+	// removed by setting it to an empty string.
+	OnDisks []OnDiskFunc
+
+	// OnNetworks funcs are called with each Network, in order. The
+	// same limitations as OnDisks apply.
+	OnNetworks []OnNetworkFunc
+
+	// OnFiles funcs are called with each File, in order. The same
+	// limitations as OnDisks apply.
+	OnFiles []OnFileFunc
+}
+
+// EditScheme returns an EditScheme that makes the edits described by the
+// EditOptions. Funcs are chained in the same manner as any other
+// EditObjectFunc.
+func (o EditOptions) EditScheme() EditScheme {
+	scheme := NewEditScheme()
+
+	for _, f := range o.OnSystem {
+		scheme.Propose(systemEditFunc(f), VirtualHardwareSystemName)
+	}
+
+	for _, f := range o.OnHardwareItems {
+		scheme.Propose(itemEditFunc(f), VirtualHardwareItemName)
+	}
+
+	for _, f := range o.OnDisks {
+		scheme.Propose(diskEditFunc(f), DiskName)
+	}
+
+	for _, f := range o.OnNetworks {
+		scheme.Propose(networkEditFunc(f), NetworkName)
+	}
+
+	for _, f := range o.OnFiles {
+		scheme.Propose(fileEditFunc(f), ReferencesFileName)
+	}
+
+	return scheme
+}
+
+func systemEditFunc(f OnSystemFunc) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(System)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited, action := f(o)
+		if action != Replace {
+			return EditObjectResult{Action: action}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &edited,
+		}
+	}
+}
+
+func itemEditFunc(f OnHardwareItemFunc) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited, action := f(o)
+		if action != Replace {
+			return EditObjectResult{Action: action}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &edited,
+		}
+	}
+}
+
+func diskEditFunc(f OnDiskFunc) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		_, ok = o.Attr("diskId")
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		var original Disk
+		err := xmlutil.Unmarshal(o.Data().Bytes(), &original)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited, action := f(original)
+		if action != Replace {
+			return EditObjectResult{Action: action}
+		}
+
+		return replaceOvfAttrs(o, []attrChange{
+			{localName: "diskId", original: original.DiskId, edited: edited.DiskId},
+			{localName: "fileRef", original: original.FileRef, edited: edited.FileRef},
+			{localName: "capacity", original: original.Capacity, edited: edited.Capacity},
+			{localName: "capacityAllocationUnits", original: original.CapacityAllocationUnits, edited: edited.CapacityAllocationUnits},
+			{localName: "format", original: original.Format, edited: edited.Format},
+			{localName: "populatedSize", original: original.PopulatedSize, edited: edited.PopulatedSize},
+		})
+	}
+}
+
+func networkEditFunc(f OnNetworkFunc) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// Other objects may share the element name (e.g., the
+		// VirtualBox network settings).
+		_, ok = o.Attr("name")
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		var original Network
+		err := xmlutil.Unmarshal(o.Data().Bytes(), &original)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited, action := f(original)
+		if action != Replace {
+			return EditObjectResult{Action: action}
+		}
+
+		if edited.Description != original.Description && len(edited.Description) > 0 {
+			err = o.SetChildText("Description", edited.Description)
+			if err != nil {
+				err = o.AddChild("Description", edited.Description)
+			}
+			if err != nil {
+				return EditObjectResult{Action: NoOp}
+			}
+		}
+
+		return replaceOvfAttrs(o, []attrChange{
+			{localName: "name", original: original.Name, edited: edited.Name},
+		})
+	}
+}
+
+func fileEditFunc(f OnFileFunc) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		_, ok = o.Attr("href")
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		var original File
+		err := xmlutil.Unmarshal(o.Data().Bytes(), &original)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited, action := f(original)
+		if action != Replace {
+			return EditObjectResult{Action: action}
+		}
+
+		return replaceOvfAttrs(o, []attrChange{
+			{localName: "id", original: original.Id, edited: edited.Id},
+			{localName: "href", original: original.Href, edited: edited.Href},
+			{localName: "size", original: original.Size, edited: edited.Size},
+		})
+	}
+}
+
+// attrChange is a possible change to an attribute of a *RawObject.
+type attrChange struct {
+	localName string
+	original  string
+	edited    string
+}
+
+// replaceOvfAttrs applies the attribute changes to a *RawObject, returning
+// a Replace result. Attributes that were not changed, or were changed to
+// an empty string, are left as-is.
+func replaceOvfAttrs(o *RawObject, changes []attrChange) EditObjectResult {
+	for _, change := range changes {
+		if change.edited == change.original || len(change.edited) == 0 {
+			continue
+		}
+
+		err := o.SetAttr(qualifiedAttrName(o, change.localName), change.edited)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+	}
+
+	return EditObjectResult{
+		Action: Replace,
+		Object: o,
+	}
+}
+
+// qualifiedAttrName returns the name of an attribute as it appears in the
+// object's start element, or the name with the 'ovf' prefix if the
+// attribute is not present.
+func qualifiedAttrName(o *RawObject, localName string) string {
+	for _, attr := range o.Start.Attr {
+		if attr.Name.Local != localName {
+			continue
+		}
+
+		if len(attr.Name.Space) == 0 {
+			return localName
+		}
+
+		return attr.Name.Space + ":" + localName
+	}
+
+	return "ovf:" + localName
+}
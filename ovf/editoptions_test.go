@@ -0,0 +1,134 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditOptionsEditScheme(t *testing.T) {
+	options := EditOptions{
+		OnSystem: []OnSystemFunc{
+			func(system System) (System, EditAction) {
+				system.VirtualSystemType = "vmx-13"
+				return system, Replace
+			},
+		},
+		OnHardwareItems: []OnHardwareItemFunc{
+			func(item Item) (Item, EditAction) {
+				if item.ResourceType == IdeControllerResourceType {
+					return item, Delete
+				}
+				return item, NoOp
+			},
+			func(item Item) (Item, EditAction) {
+				if item.ResourceType != EthernetAdapterResourceType {
+					return item, NoOp
+				}
+				item.ResourceSubType = "VmxNet3"
+				return item, Replace
+			},
+			func(item Item) (Item, EditAction) {
+				if item.ResourceSubType != "VmxNet3" {
+					return item, NoOp
+				}
+				item.Caption = "Network adapter 1"
+				return item, Replace
+			},
+		},
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options.EditScheme())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	if !strings.Contains(result, "<vssd:VirtualSystemType>vmx-13</vssd:VirtualSystemType>") {
+		t.Fatal("System was not edited:\n'" + result + "'")
+	}
+
+	if strings.Contains(result, "ideController") {
+		t.Fatal("IDE controllers were not deleted:\n'" + result + "'")
+	}
+
+	if !strings.Contains(result, "<rasd:Caption>Network adapter 1</rasd:Caption>") ||
+		!strings.Contains(result, "<rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>") {
+		t.Fatal("Item edits were not chained:\n'" + result + "'")
+	}
+}
+
+func TestEditOptionsMergeWithEditScheme(t *testing.T) {
+	options := EditOptions{
+		OnHardwareItems: []OnHardwareItemFunc{
+			func(item Item) (Item, EditAction) {
+				return item, NoOp
+			},
+		},
+	}
+
+	scheme, err := MergeEditSchemes(
+		NewEditScheme().Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName),
+		options.EditScheme())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fns, _ := scheme.ShouldEditObject(VirtualHardwareItemName)
+	if len(fns) != 2 {
+		t.Fatal("Expected EditOptions funcs to be merged into the EditScheme")
+	}
+}
+
+func TestEditOptionsDisksNetworksAndFiles(t *testing.T) {
+	var networks []string
+
+	options := EditOptions{
+		OnDisks: []OnDiskFunc{
+			func(disk Disk) (Disk, EditAction) {
+				disk.Capacity = "137438953472"
+				return disk, Replace
+			},
+		},
+		OnNetworks: []OnNetworkFunc{
+			func(network Network) (Network, EditAction) {
+				networks = append(networks, network.Name)
+				network.Name = "VM Network"
+				network.Description = "The VM Network network"
+				return network, Replace
+			},
+		},
+		OnFiles: []OnFileFunc{
+			func(file File) (File, EditAction) {
+				file.Href = "disk1.vmdk"
+				return file, Replace
+			},
+			func(file File) (File, EditAction) {
+				file.Size = "1024"
+				return file, Replace
+			},
+		},
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options.EditScheme())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents,
+		`<File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="disk1.vmdk" ovf:size="1024"/>`, 1)
+	expected = strings.Replace(expected, `ovf:capacity="68719476736"`, `ovf:capacity="137438953472"`, 1)
+	expected = strings.Replace(expected, `    <Network ovf:name="NAT">
+      <Description>Logical network used by this appliance.</Description>`, `    <Network ovf:name="VM Network">
+      <Description>The VM Network network</Description>`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	if len(networks) != 1 || networks[0] != "NAT" {
+		t.Fatal("OnNetworks funcs should only be called with the NetworkSection's networks")
+	}
+}
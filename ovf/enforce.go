@@ -0,0 +1,118 @@
+package ovf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// FixableRule is a Rule that can fix the problems that it finds.
+type FixableRule interface {
+	Rule
+
+	// Fix returns an EditScheme that fixes the problems that Check
+	// finds in the OVF. It is only called if Check found problems,
+	// and may return nil if they cannot be fixed.
+	Fix(o *Ovf) EditScheme
+}
+
+// EnforceResult describes the problems found and fixed by Enforce.
+type EnforceResult struct {
+	// Fixed are the problems that were fixed.
+	Fixed []Finding
+
+	// Remaining are the problems that were not fixed, either
+	// because their Rule is not a FixableRule, or because they
+	// prevent the OVF from being parsed.
+	Remaining []Finding
+}
+
+// Enforce reads an OVF, checks it in the same manner as
+// ValidateWithRules, and fixes the problems found by each enabled
+// FixableRule in the RuleSet. The fixes of each Rule are applied in the
+// order the Rules were added, and the OVF is checked again once they are
+// applied. The fixed OVF is returned along with the EnforceResult, and
+// is the same as the original if nothing was fixed. No fixes are
+// applied if the OVF cannot be parsed, and the OVF is nil if it exceeds
+// the Limits. A non-nil error is returned if the OVF cannot be read, or
+// if a fix cannot be applied.
+func Enforce(r io.Reader, limits Limits, rules *RuleSet) (*bytes.Buffer, EnforceResult, error) {
+	raw, err := readLimited(r, limits)
+	if err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return nil, EnforceResult{Remaining: []Finding{{Err: err}}}, nil
+		}
+
+		return nil, EnforceResult{}, err
+	}
+
+	findings, err := ValidateWithRules(bytes.NewReader(raw), limits, rules)
+	if err != nil {
+		return nil, EnforceResult{}, err
+	}
+
+	fixable := make(map[string]bool)
+	for _, finding := range findings {
+		if len(finding.Rule) == 0 {
+			return bytes.NewBuffer(raw), EnforceResult{Remaining: findings}, nil
+		}
+
+		fixable[finding.Rule] = true
+	}
+
+	fixed := raw
+	config := EditConfig{Limits: limits, KeepByteOrderMark: true}
+
+	for _, rule := range rules.rules {
+		fixer, ok := rule.rule.(FixableRule)
+		if !ok || !rule.info.Enabled || !fixable[rule.info.Name] {
+			continue
+		}
+
+		parsed, err := parseOvf(fixed, false)
+		if err != nil {
+			return nil, EnforceResult{}, err
+		}
+
+		scheme := fixer.Fix(&Ovf{Envelope: parsed})
+		if scheme == nil {
+			continue
+		}
+
+		buff, err := EditRawOvfWithConfig(bytes.NewReader(fixed), scheme, config)
+		if err != nil {
+			return nil, EnforceResult{}, errors.New("failed to fix the problems found by rule '" +
+				rule.info.Name + "' - " + err.Error())
+		}
+
+		fixed = buff.Bytes()
+	}
+
+	remaining, err := ValidateWithRules(bytes.NewReader(fixed), limits, rules)
+	if err != nil {
+		return nil, EnforceResult{}, err
+	}
+
+	result := EnforceResult{Remaining: remaining}
+
+	for _, finding := range findings {
+		if !containsFinding(remaining, finding) {
+			result.Fixed = append(result.Fixed, finding)
+		}
+	}
+
+	return bytes.NewBuffer(fixed), result, nil
+}
+
+// containsFinding returns true if the Findings contain a Finding with
+// the same Rule and description.
+func containsFinding(findings []Finding, finding Finding) bool {
+	for _, f := range findings {
+		if f.Rule == finding.Rule && f.Err.Error() == finding.Err.Error() {
+			return true
+		}
+	}
+
+	return false
+}
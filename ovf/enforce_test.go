@@ -0,0 +1,91 @@
+package ovf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEnforce(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var memory Item
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == MemoryResourceType {
+			memory = item
+		}
+	}
+
+	allocated, err := memoryBytes(memory)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// The file reference cannot be fixed.
+	input := strings.Replace(basicOvfFileContents, `ovf:fileRef="file1"`, `ovf:fileRef="file404"`, 1)
+
+	rules := NewRuleSet()
+	err = rules.Add("max-memory", "Virtual machines have half of their memory", MaxMemoryRule(allocated/2))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fixed, result, err := Enforce(strings.NewReader(input), Limits{}, rules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(result.Fixed) != 1 || result.Fixed[0].Rule != "max-memory" {
+		t.Fatalf("Expected the memory to be fixed - got %+v", result.Fixed)
+	}
+
+	if len(result.Remaining) != 1 || result.Remaining[0].Rule != DiskFilesRuleName {
+		t.Fatalf("Expected the file reference to remain - got %+v", result.Remaining)
+	}
+
+	parsed, err := ToOvf(bytes.NewReader(fixed.Bytes()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.InstanceID != memory.InstanceID {
+			continue
+		}
+
+		reduced, err := memoryBytes(item)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if reduced != allocated/2 {
+			t.Fatalf("Expected memory to be reduced to %d bytes - got %d", allocated/2, reduced)
+		}
+	}
+
+	// Nothing is fixed once the rules are followed.
+	again, result, err := Enforce(bytes.NewReader(fixed.Bytes()), Limits{}, rules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(result.Fixed) != 0 || !bytes.Equal(again.Bytes(), fixed.Bytes()) {
+		t.Fatalf("Expected nothing to be fixed - got %+v", result.Fixed)
+	}
+
+	// Nothing is fixed if the OVF cannot be parsed.
+	junk := strings.Replace(input, "<rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>",
+		"<rasd:AutomaticAllocation>junk</rasd:AutomaticAllocation>", 1)
+
+	unchanged, result, err := Enforce(strings.NewReader(junk), Limits{}, rules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(result.Fixed) != 0 || len(result.Remaining) != 1 || unchanged.String() != junk {
+		t.Fatalf("Expected nothing to be fixed in an OVF that cannot be parsed - got %+v", result)
+	}
+}
@@ -0,0 +1,90 @@
+package ovf
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update the golden files in "+goldenDir+" rather than comparing against them")
+
+const (
+	testDataDir = ".testdata"
+	goldenDir   = testDataDir + "/golden"
+	fixturesDir = testDataDir + "/fixtures"
+)
+
+// checkGolden compares a result to the golden file with the specified
+// name, or replaces the golden file with the result if -update is set.
+func checkGolden(t *testing.T, name string, result []byte) {
+	goldenPath := filepath.Join(goldenDir, name)
+
+	if *update {
+		err := ioutil.WriteFile(goldenPath, result, 0644)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(result, expected) {
+		t.Fatal("Result does not match golden file '" + goldenPath + "' (use -update to replace it):\n'" +
+			string(result) + "'")
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(fixturesDir, "*.ovf"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(paths) == 0 {
+		t.Fatal("Found no fixtures in " + fixturesDir)
+	}
+
+	for _, p := range paths {
+		name := filepath.Base(p)
+
+		t.Run(name, func(t *testing.T) {
+			raw, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			_, err = ToOvf(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			unedited, err := EditRawOvf(bytes.NewReader(raw), NewEditScheme())
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if !bytes.Equal(unedited.Bytes(), raw) {
+				t.Fatal("An empty edit scheme modified the fixture:\n'" + unedited.String() + "'")
+			}
+
+			scheme := NewEditScheme().
+				Propose(SetVirtualSystemTypeFunc("vmx-14"), VirtualHardwareSystemName).
+				Propose(DeleteHardwareItemsMatchFunc(ItemMatch{ResourceType: IdeControllerResourceType}, -1), VirtualHardwareItemName)
+
+			edited, err := EditRawOvf(bytes.NewReader(raw), scheme)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			checkGolden(t, "fixture-"+strings.TrimSuffix(name, ".ovf")+".ovf", edited.Bytes())
+		})
+	}
+}
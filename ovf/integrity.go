@@ -0,0 +1,118 @@
+package ovf
+
+import (
+	"errors"
+)
+
+const (
+	itemPath = "Envelope/VirtualSystem/VirtualHardwareSection/Item"
+	diskPath = "Envelope/DiskSection/Disk"
+)
+
+// isController returns true if the Item is a controller that other
+// devices can be attached to (e.g., a storage or USB controller).
+func isController(item Item) bool {
+	if IsStorageController(item) {
+		return true
+	}
+
+	switch item.ResourceType {
+	case FcHbaResourceType, IscsiHbaResourceType, IbHcaResourceType, UsbControllerResourceType,
+		Ieee1394ControllerResourceType:
+		return true
+	}
+
+	return false
+}
+
+// CheckIntegrity returns the references between the objects of an OVF
+// that do not resolve, which importers reject. It checks the rules of
+// NewRuleSet, which check that:
+//
+//   - The Parent of every Item is the InstanceID of a controller Item
+//   - The fileRef of every Disk is the ID of a References File
+//   - Every HostResource that references a Disk (e.g., 'ovf:/disk/vmdisk1')
+//     references an existing Disk
+//
+// The Findings do not have a location.
+func CheckIntegrity(o Ovf) []Finding {
+	return NewRuleSet().Check(&o)
+}
+
+func checkItemParents(o *Ovf) []Finding {
+	items := o.Envelope.VirtualSystem.VirtualHardwareSection.Items
+
+	itemsById := make(map[string]Item)
+	for _, item := range items {
+		itemsById[item.InstanceID] = item
+	}
+
+	var findings []Finding
+
+	for _, item := range items {
+		if len(item.Parent) == 0 {
+			continue
+		}
+
+		parent, ok := itemsById[item.Parent]
+		switch {
+		case !ok:
+			findings = append(findings, Finding{
+				Path: itemPath,
+				Err: errors.New("item '" + item.InstanceID + "' has parent '" + item.Parent +
+					"', which is not the InstanceID of an item"),
+			})
+		case !isController(parent):
+			findings = append(findings, Finding{
+				Path: itemPath,
+				Err: errors.New("item '" + item.InstanceID + "' has parent '" + item.Parent +
+					"', which is not a controller (resource type " + parent.ResourceType + ")"),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkDiskFiles(o *Ovf) []Finding {
+	files := make(map[string]bool)
+	for _, file := range o.Envelope.References.Files {
+		files[file.Id] = true
+	}
+
+	var findings []Finding
+
+	for _, disk := range o.Envelope.DiskSection.Disks {
+		if len(disk.FileRef) > 0 && !files[disk.FileRef] {
+			findings = append(findings, Finding{
+				Path: diskPath,
+				Err: errors.New("disk '" + disk.DiskId + "' references file '" + disk.FileRef +
+					"', which does not exist"),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkHostResourceDisks(o *Ovf) []Finding {
+	disks := make(map[string]bool)
+	for _, disk := range o.Envelope.DiskSection.Disks {
+		disks[disk.DiskId] = true
+	}
+
+	var findings []Finding
+
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		diskId, ok := HostResourceDiskId(item.HostResource)
+		if ok && !disks[diskId] {
+			findings = append(findings, Finding{
+				Path: itemPath,
+				Err: errors.New("item '" + item.InstanceID + "' references disk '" + diskId +
+					"', which does not exist"),
+			})
+		}
+	}
+
+	return findings
+}
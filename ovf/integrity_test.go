@@ -0,0 +1,73 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	findings := CheckIntegrity(o)
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	var memoryId string
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == MemoryResourceType {
+			memoryId = item.InstanceID
+		}
+	}
+
+	o.Envelope.VirtualSystem.VirtualHardwareSection.Items = append(o.Envelope.VirtualSystem.VirtualHardwareSection.Items,
+		Item{InstanceID: "100", ResourceType: DiskDriveResourceType, Parent: "404"},
+		Item{InstanceID: "101", ResourceType: DiskDriveResourceType, Parent: memoryId},
+		Item{InstanceID: "102", ResourceType: DiskDriveResourceType, HostResource: "ovf:/disk/missing"})
+
+	o.Envelope.DiskSection.Disks[0].FileRef = "missing"
+
+	messages := make(map[string]bool)
+	for _, finding := range CheckIntegrity(o) {
+		messages[finding.Err.Error()] = true
+	}
+
+	expected := []string{
+		"', which is not the InstanceID of an item",
+		"', which is not a controller (resource type " + MemoryResourceType + ")",
+		"references disk 'missing', which does not exist",
+		"references file 'missing', which does not exist",
+	}
+
+	for _, e := range expected {
+		found := false
+		for message := range messages {
+			if strings.Contains(message, e) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatalf("Expected a finding containing '%s' - got %v", e, messages)
+		}
+	}
+}
+
+func TestValidateIntegrity(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, `ovf:fileRef="file1"`, `ovf:fileRef="file404"`, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find the disk's fileRef in test data")
+	}
+
+	findings, err := Validate(strings.NewReader(input), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 1 || findings[0].Path != diskPath || !strings.Contains(findings[0].Err.Error(), "'file404'") {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+}
@@ -0,0 +1,144 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+const (
+	// Ipv4Protocol and Ipv6Protocol are the IP protocols that a
+	// virtual machine can declare support for in its
+	// IpAssignmentSection.
+	Ipv4Protocol = "IPv4"
+	Ipv6Protocol = "IPv6"
+
+	// DhcpIpAssignmentScheme is the IP assignment scheme of a virtual
+	// machine that obtains its IP addresses using DHCP.
+	DhcpIpAssignmentScheme = "dhcp"
+
+	// OvfEnvIpAssignmentScheme is the IP assignment scheme of a
+	// virtual machine that reads its IP addresses from the OVF
+	// environment, which allows vCenter to assign fixed IP addresses
+	// (e.g., from an IP pool).
+	OvfEnvIpAssignmentScheme = "ovfenv"
+
+	ipAssignmentSectionInfo = "Supported IP assignment schemes"
+)
+
+// IpAssignmentSection is VMWare's 'vmw:IpAssignmentSection', which
+// declares the IP protocols a virtual machine supports, and the schemes
+// it supports for being assigned IP addresses. vCenter uses the section
+// to choose the IP allocation policies that a vApp can be deployed with.
+type IpAssignmentSection struct {
+	XMLName   xml.Name `xml:"IpAssignmentSection"`
+	Protocols string   `xml:"protocols,attr"`
+	Schemes   string   `xml:"schemes,attr"`
+	Info      string   `xml:"Info"`
+}
+
+// ProtocolList returns the IP protocols declared by the section (e.g.,
+// Ipv4Protocol).
+func (o IpAssignmentSection) ProtocolList() []string {
+	return splitIpAssignmentList(o.Protocols)
+}
+
+// SchemeList returns the IP assignment schemes declared by the section
+// (e.g., DhcpIpAssignmentScheme).
+func (o IpAssignmentSection) SchemeList() []string {
+	return splitIpAssignmentList(o.Schemes)
+}
+
+func splitIpAssignmentList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// SetIpAssignmentSectionFunc returns an EditObjectFunc that adds a
+// 'vmw:IpAssignmentSection' declaring the specified IP protocols (e.g.,
+// Ipv4Protocol) and IP assignment schemes (e.g., DhcpIpAssignmentScheme)
+// to the VirtualSystem. An existing IpAssignmentSection is replaced. The
+// protocols default to Ipv4Protocol if none are specified. A non-nil
+// error is returned if a protocol or scheme is not supported, or if no
+// schemes are specified.
+//
+// The EditObjectFunc must be proposed for VirtualSystemName in its own
+// EditScheme, as the VirtualSystem contains every other object. The 'vmw'
+// namespace must be declared on the Envelope for the resulting OVF to be
+// valid (see DeclareNamespaceFunc).
+func SetIpAssignmentSectionFunc(protocols []string, schemes []string) (EditObjectFunc, error) {
+	if len(protocols) == 0 {
+		protocols = []string{Ipv4Protocol}
+	}
+
+	var normalizedProtocols []string
+	for _, protocol := range protocols {
+		switch {
+		case strings.EqualFold(protocol, Ipv4Protocol):
+			normalizedProtocols = append(normalizedProtocols, Ipv4Protocol)
+		case strings.EqualFold(protocol, Ipv6Protocol):
+			normalizedProtocols = append(normalizedProtocols, Ipv6Protocol)
+		default:
+			return nil, errors.New("unsupported IP protocol '" + protocol + "' - must be '" +
+				Ipv4Protocol + "' or '" + Ipv6Protocol + "'")
+		}
+	}
+
+	if len(schemes) == 0 {
+		return nil, errors.New("at least one IP assignment scheme must be specified")
+	}
+
+	var normalizedSchemes []string
+	for _, scheme := range schemes {
+		switch strings.ToLower(scheme) {
+		case DhcpIpAssignmentScheme, OvfEnvIpAssignmentScheme:
+			normalizedSchemes = append(normalizedSchemes, strings.ToLower(scheme))
+		default:
+			return nil, errors.New("unsupported IP assignment scheme '" + scheme + "' - must be '" +
+				DhcpIpAssignmentScheme + "' or '" + OvfEnvIpAssignmentScheme + "'")
+		}
+	}
+
+	section := ipAssignmentSection(strings.Join(normalizedProtocols, ","), strings.Join(normalizedSchemes, ","))
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// A missing section is not an error.
+		_ = o.DeleteChild("IpAssignmentSection")
+
+		err := o.InsertChild(section)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}, nil
+}
+
+func ipAssignmentSection(protocols string, schemes string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString(`<vmw:IpAssignmentSection ovf:required="false" vmw:protocols="`)
+	xml.EscapeText(b, []byte(protocols))
+	b.WriteString(`" vmw:schemes="`)
+	xml.EscapeText(b, []byte(schemes))
+	b.WriteString(`">` + "\n")
+	b.WriteString("  <Info>" + ipAssignmentSectionInfo + "</Info>\n")
+	b.WriteString("</vmw:IpAssignmentSection>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,68 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetIpAssignmentSectionFunc(t *testing.T) {
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), NewEditScheme().
+		Propose(DeclareNamespaceFunc(VmwPrefix, VmwNamespace), EnvelopeName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := b.String()
+
+	for _, schemes := range [][]string{{"DHCP"}, {OvfEnvIpAssignmentScheme, DhcpIpAssignmentScheme}} {
+		f, err := SetIpAssignmentSectionFunc([]string{"ipv4", Ipv6Protocol}, schemes)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		b, err = EditRawOvf(strings.NewReader(input), NewEditScheme().Propose(f, VirtualSystemName))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		input = b.String()
+	}
+
+	if strings.Count(input, "<vmw:IpAssignmentSection") != 1 {
+		t.Fatal("Expected the section to be replaced:\n" + input)
+	}
+
+	o, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	section := o.Envelope.VirtualSystem.IpAssignmentSection
+	if strings.Join(section.ProtocolList(), ",") != "IPv4,IPv6" || strings.Join(section.SchemeList(), ",") != "ovfenv,dhcp" {
+		t.Fatalf("Got unexpected section - %+v", section)
+	}
+
+	findings, err := Validate(strings.NewReader(input), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	_, err = SetIpAssignmentSectionFunc(nil, []string{"static"})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported scheme")
+	}
+
+	_, err = SetIpAssignmentSectionFunc([]string{"IPX"}, []string{DhcpIpAssignmentScheme})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported protocol")
+	}
+
+	_, err = SetIpAssignmentSectionFunc(nil, nil)
+	if err == nil {
+		t.Fatal("Expected an error when no schemes are specified")
+	}
+}
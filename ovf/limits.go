@@ -0,0 +1,187 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// DefaultMaxDescriptorBytes is the default maximum size of an
+	// OVF descriptor.
+	DefaultMaxDescriptorBytes = 16 << 20
+
+	// DefaultMaxItems is the default maximum number of hardware
+	// Items in an OVF descriptor.
+	DefaultMaxItems = 4096
+
+	// DefaultMaxDepth is the default maximum element nesting depth
+	// of an OVF descriptor.
+	DefaultMaxDepth = 64
+
+	// MaxDescriptorBytesLimit is the LimitError.Limit of an OVF
+	// descriptor that exceeds Limits.MaxDescriptorBytes.
+	MaxDescriptorBytesLimit = "MaxDescriptorBytes"
+
+	// MaxItemsLimit is the LimitError.Limit of an OVF descriptor
+	// that exceeds Limits.MaxItems.
+	MaxItemsLimit = "MaxItems"
+
+	// MaxDepthLimit is the LimitError.Limit of an OVF descriptor
+	// that exceeds Limits.MaxDepth.
+	MaxDepthLimit = "MaxDepth"
+)
+
+var (
+	// ErrTooManyItems is wrapped by the *LimitError of an OVF
+	// descriptor that exceeds Limits.MaxItems.
+	ErrTooManyItems = errors.New("ovf has too many hardware items")
+
+	// ErrTooDeep is wrapped by the *LimitError of an OVF descriptor
+	// that exceeds Limits.MaxDepth.
+	ErrTooDeep = errors.New("ovf elements are nested too deeply")
+)
+
+// Limits bounds the resources used when processing an untrusted OVF
+// descriptor. A limit uses its default if it is zero, and is disabled if
+// it is negative.
+type Limits struct {
+	// MaxDescriptorBytes is the maximum size of the descriptor.
+	MaxDescriptorBytes int64
+
+	// MaxItems is the maximum number of hardware Items.
+	MaxItems int
+
+	// MaxDepth is the maximum element nesting depth. The root
+	// element has a depth of one.
+	MaxDepth int
+}
+
+func (o Limits) maxDescriptorBytes() int64 {
+	if o.MaxDescriptorBytes == 0 {
+		return DefaultMaxDescriptorBytes
+	}
+
+	return o.MaxDescriptorBytes
+}
+
+func (o Limits) maxItems() int {
+	if o.MaxItems == 0 {
+		return DefaultMaxItems
+	}
+
+	return o.MaxItems
+}
+
+func (o Limits) maxDepth() int {
+	if o.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+
+	return o.MaxDepth
+}
+
+// LimitError is returned when an OVF descriptor exceeds one of its
+// Limits. It wraps xmlutil.ErrDocumentTooLarge for MaxDescriptorBytesLimit,
+// ErrTooManyItems for MaxItemsLimit, and ErrTooDeep for MaxDepthLimit.
+type LimitError struct {
+	// Limit is the name of the exceeded limit (e.g., MaxItemsLimit).
+	Limit string
+
+	// Max is the value of the exceeded limit.
+	Max int64
+}
+
+func (o *LimitError) Error() string {
+	return "ovf exceeds " + o.Limit + " limit of " + strconv.FormatInt(o.Max, 10)
+}
+
+func (o *LimitError) Unwrap() error {
+	switch o.Limit {
+	case MaxDescriptorBytesLimit:
+		return xmlutil.ErrDocumentTooLarge
+	case MaxItemsLimit:
+		return ErrTooManyItems
+	case MaxDepthLimit:
+		return ErrTooDeep
+	}
+
+	return nil
+}
+
+// readLimited reads an OVF descriptor, returning a *LimitError if it is
+// larger than the MaxDescriptorBytes limit.
+func readLimited(r io.Reader, limits Limits) ([]byte, error) {
+	raw, err := xmlutil.ReadAllLimit(r, limits.maxDescriptorBytes())
+	if errors.Is(err, xmlutil.ErrDocumentTooLarge) {
+		return nil, &LimitError{
+			Limit: MaxDescriptorBytesLimit,
+			Max:   limits.maxDescriptorBytes(),
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// CheckLimits returns a *LimitError if the OVF descriptor exceeds any of
+// the Limits. Only the well-formed portion of a malformed descriptor is
+// checked.
+func CheckLimits(raw []byte, limits Limits) error {
+	if limits.maxDescriptorBytes() > 0 && int64(len(raw)) > limits.maxDescriptorBytes() {
+		return &LimitError{
+			Limit: MaxDescriptorBytesLimit,
+			Max:   limits.maxDescriptorBytes(),
+		}
+	}
+
+	maxItems := limits.maxItems()
+	maxDepth := limits.maxDepth()
+	if maxItems < 0 && maxDepth < 0 {
+		return nil
+	}
+
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+	items := 0
+	depth := 0
+
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			// Malformed XML is reported by the parser, which
+			// provides the location of the problem.
+			break
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			depth = depth + 1
+			if maxDepth > 0 && depth > maxDepth {
+				return &LimitError{
+					Limit: MaxDepthLimit,
+					Max:   int64(maxDepth),
+				}
+			}
+
+			if v.Name.Local == VirtualHardwareItemName.String() {
+				items = items + 1
+				if maxItems > 0 && items > maxItems {
+					return &LimitError{
+						Limit: MaxItemsLimit,
+						Max:   int64(maxItems),
+					}
+				}
+			}
+		case xml.EndElement:
+			depth = depth - 1
+		}
+	}
+
+	return nil
+}
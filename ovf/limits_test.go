@@ -0,0 +1,77 @@
+package ovf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+func TestCheckLimits(t *testing.T) {
+	raw := []byte(basicOvfFileContents)
+
+	err := CheckLimits(raw, Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		limits   Limits
+		expected string
+		wrapped  error
+	}{
+		{limits: Limits{MaxDescriptorBytes: int64(len(raw) - 1)}, expected: MaxDescriptorBytesLimit, wrapped: xmlutil.ErrDocumentTooLarge},
+		{limits: Limits{MaxItems: 2}, expected: MaxItemsLimit, wrapped: ErrTooManyItems},
+		{limits: Limits{MaxDepth: 3}, expected: MaxDepthLimit, wrapped: ErrTooDeep},
+	}
+
+	for _, test := range tests {
+		err := CheckLimits(raw, test.limits)
+
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != test.expected {
+			t.Fatalf("Expected a %s *LimitError, got - %v", test.expected, err)
+		}
+
+		if !errors.Is(err, test.wrapped) {
+			t.Fatalf("Expected the %s *LimitError to wrap '%v'", test.expected, test.wrapped)
+		}
+	}
+
+	err = CheckLimits(raw, Limits{MaxDescriptorBytes: -1, MaxItems: -1, MaxDepth: -1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestEditRawOvfLimits(t *testing.T) {
+	_, err := EditRawOvfWithConfig(strings.NewReader(basicOvfFileContents), NewEditScheme(),
+		EditConfig{Limits: Limits{MaxDescriptorBytes: 64}})
+
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != MaxDescriptorBytesLimit || limitErr.Max != 64 {
+		t.Fatalf("Expected a %s *LimitError, got - %v", MaxDescriptorBytesLimit, err)
+	}
+
+	deep := strings.Repeat("<a>", DefaultMaxDepth+1) + strings.Repeat("</a>", DefaultMaxDepth+1)
+
+	_, err = EditRawOvf(strings.NewReader(deep), NewEditScheme())
+	if !errors.As(err, &limitErr) || limitErr.Limit != MaxDepthLimit {
+		t.Fatalf("Expected a %s *LimitError, got - %v", MaxDepthLimit, err)
+	}
+}
+
+func TestToOvfLimits(t *testing.T) {
+	_, err := ToOvfWithConfig(strings.NewReader(basicOvfFileContents), ToOvfConfig{Limits: Limits{MaxItems: 1}})
+
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != MaxItemsLimit {
+		t.Fatalf("Expected a %s *LimitError, got - %v", MaxItemsLimit, err)
+	}
+
+	_, err = ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"encoding/xml"
 	"errors"
 	"io"
-	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
 
-	"github.com/stephen-fox/vmwareify/internal/xmlutil"
+	"github.com/stephen-fox/vmwareify/xmlutil"
 )
 
 const (
@@ -31,7 +33,11 @@ func (o EditAction) String() string {
 
 // EditScheme specifies how an OVF configuration should be modified.
 // There is no guarantee that the specified edits will be executed as the
-// specified OVF object(s) may not be present in the file.
+// specified OVF object(s) may not be present in the file. Objects that are
+// described by the OVF specification (e.g., Items) are only edited in the
+// section the specification places them in, meaning that look-alike
+// elements inside vendor-specific sections (e.g., vbox:Machine) are never
+// edited.
 type EditScheme interface {
 	// ShouldEditObject returns true and a non-empty slice of
 	// EditObjectFunc if the specified OVF object has been
@@ -39,24 +45,164 @@ type EditScheme interface {
 	ShouldEditObject(objectName ObjectName) ([]EditObjectFunc, bool)
 
 	// Propose will execute the provided EditObjectFunc if it
-	// encounters the specified ObjectName.
+	// encounters the specified ObjectName. Funcs are executed in
+	// the order they were proposed. Each func receives the object
+	// returned by the last func that returned Replace, and a func
+	// that returns Delete stops the remaining funcs from executing
+	// (see EditConfig.StopAtFirstReplace). Funcs proposed for a
+	// qualified ObjectName (see QualifiedObjectName) are executed
+	// after the funcs proposed for its local name.
 	Propose(EditObjectFunc, ObjectName) EditScheme
 }
 
+// ListableEditScheme is an EditScheme that can list the OVF objects that
+// it targets, which is required to merge or clone it (see
+// MergeEditSchemes). It is separate from EditScheme so that existing
+// implementations of EditScheme are not broken. The EditScheme returned
+// by NewEditScheme implements it.
+type ListableEditScheme interface {
+	EditScheme
+
+	// ObjectNames returns the names of the OVF objects that have
+	// been targeted for editing, in sorted order.
+	ObjectNames() []ObjectName
+}
+
+// ErrUnlistableEditScheme is returned when merging or cloning an
+// EditScheme that does not implement ListableEditScheme.
+var ErrUnlistableEditScheme = errors.New("edit scheme does not implement ListableEditScheme")
+
+// PathEditScheme is an EditScheme that can constrain its EditObjectFunc to
+// objects with a specific parent (e.g., only Items that are inside of a
+// VirtualHardwareSection). It is separate from EditScheme so that existing
+// implementations of EditScheme are not broken. The EditScheme returned by
+// NewEditScheme implements it.
+type PathEditScheme interface {
+	ListableEditScheme
+
+	// ProposeUnder is the equivalent of Propose, except that the
+	// EditObjectFunc is only executed if the Path of the object's
+	// parent ends with the specified local names (e.g.,
+	// 'VirtualSystem', 'VirtualHardwareSection'). Specifying no
+	// names is the equivalent of Propose.
+	ProposeUnder(f EditObjectFunc, objectName ObjectName, parent ...string) PathEditScheme
+
+	// ShouldEditObjectAt is the equivalent of ShouldEditObject for
+	// an object at the specified Path, which includes the object
+	// itself. Only the EditObjectFunc whose parent constraints are
+	// satisfied by the Path are returned.
+	ShouldEditObjectAt(objectName ObjectName, path Path) ([]EditObjectFunc, bool)
+}
+
 type defaultEditScheme struct {
 	objectNamesToFuncs map[ObjectName][]EditObjectFunc
+
+	// objectNamesToParents contains the parent constraint of each
+	// func in objectNamesToFuncs, at the same index. A nil Path
+	// means that the func is not constrained.
+	objectNamesToParents map[ObjectName][]Path
 }
 
+// ShouldEditObject returns all of the funcs proposed for the ObjectName,
+// including those that were proposed with ProposeUnder.
 func (o *defaultEditScheme) ShouldEditObject(objectName ObjectName) ([]EditObjectFunc, bool) {
 	fns, ok := o.objectNamesToFuncs[objectName]
 	return fns, ok
 }
 
+func (o *defaultEditScheme) ShouldEditObjectAt(objectName ObjectName, path Path) ([]EditObjectFunc, bool) {
+	fns, ok := o.objectNamesToFuncs[objectName]
+	if !ok {
+		return nil, false
+	}
+
+	parents := o.objectNamesToParents[objectName]
+
+	var matching []EditObjectFunc
+	for i, f := range fns {
+		if len(parents[i]) > 0 && !path.Parent().HasSuffix(parents[i]...) {
+			continue
+		}
+
+		matching = append(matching, f)
+	}
+
+	return matching, len(matching) > 0
+}
+
 func (o *defaultEditScheme) Propose(f EditObjectFunc, objectName ObjectName, ) EditScheme {
+	return o.ProposeUnder(f, objectName)
+}
+
+func (o *defaultEditScheme) ProposeUnder(f EditObjectFunc, objectName ObjectName, parent ...string) PathEditScheme {
+	var constraint Path
+	if len(parent) > 0 {
+		constraint = append(Path(nil), parent...)
+	}
+
 	o.objectNamesToFuncs[objectName] = append(o.objectNamesToFuncs[objectName], f)
+	o.objectNamesToParents[objectName] = append(o.objectNamesToParents[objectName], constraint)
 	return o
 }
 
+func (o *defaultEditScheme) ObjectNames() []ObjectName {
+	var names []ObjectName
+	for name := range o.objectNamesToFuncs {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// MergeEditSchemes returns a new EditScheme that proposes all of the
+// EditObjectFunc of the specified EditScheme, in order, meaning that the
+// funcs of a later EditScheme are executed after the funcs of an earlier
+// one for the same ObjectName. This allows a scheme to be built from
+// reusable building blocks (e.g., a base scheme and per-customer
+// overrides). The specified EditScheme are not modified.
+// ErrUnlistableEditScheme is returned if any of them does not implement
+// ListableEditScheme.
+func MergeEditSchemes(schemes ...EditScheme) (PathEditScheme, error) {
+	merged := NewPathEditScheme()
+
+	for _, scheme := range schemes {
+		listable, ok := scheme.(ListableEditScheme)
+		if !ok {
+			return nil, ErrUnlistableEditScheme
+		}
+
+		// The parent constraints can only be copied from a
+		// defaultEditScheme.
+		other, isDefault := scheme.(*defaultEditScheme)
+
+		for _, name := range listable.ObjectNames() {
+			fns, _ := scheme.ShouldEditObject(name)
+			for i, f := range fns {
+				if isDefault {
+					merged.ProposeUnder(f, name, other.objectNamesToParents[name][i]...)
+				} else {
+					merged.Propose(f, name)
+				}
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// CloneEditScheme returns a copy of an EditScheme that can be modified
+// without affecting the original. The EditObjectFunc are not copied, so
+// funcs that keep state (e.g., a limit on the number of deletions) are
+// shared by the copies. ErrUnlistableEditScheme is returned if the
+// EditScheme does not implement ListableEditScheme.
+func CloneEditScheme(scheme EditScheme) (PathEditScheme, error) {
+	return MergeEditSchemes(scheme)
+}
+
 // EditObjectFunc receives an OVF object and returns the resulting object
 // as an EditObjectResult.
 type EditObjectFunc func(originalObject interface{}) EditObjectResult
@@ -64,6 +210,9 @@ type EditObjectFunc func(originalObject interface{}) EditObjectResult
 // EditObjectResult represents the result of editing an OVF object.
 type EditObjectResult struct {
 	Action EditAction
+
+	// Object is the replacement object. It is only required when
+	// the Action is Replace, and is ignored otherwise.
 	Object EditedObject
 }
 
@@ -80,93 +229,529 @@ var (
 	lfEol   = []byte{'\n'}
 )
 
+// EditConfig configures how EditRawOvfWithConfig edits an OVF.
+type EditConfig struct {
+	// ContinueOnError, when true, causes editing to continue after
+	// an object fails to be edited. The failing object is left
+	// unmodified, and all of the errors are joined into a single
+	// error (see errors.Join) that is returned once the entire
+	// OVF has been processed.
+	ContinueOnError bool
+
+	// StopAtFirstReplace, when true, causes the first EditObjectFunc
+	// that returns Replace to determine the outcome, skipping the
+	// remaining funcs proposed for the same ObjectName. Otherwise,
+	// the replaced object is provided to the remaining funcs so that
+	// several funcs can modify the same object.
+	StopAtFirstReplace bool
+
+	// RawObjects, when true, causes every object to be provided to
+	// the EditObjectFunc as a *RawObject, including those modeled
+	// by this package (e.g., an Item). This allows modeled objects
+	// to be edited without being re-marshalled.
+	RawObjects bool
+
+	// AllowRequiredSectionDeletes, when true, allows sections that
+	// are required to be deleted. A section is required unless its
+	// 'ovf:required' attribute is "false". Deleting a required
+	// section produces a descriptor that does not conform to the
+	// OVF specification, so ErrRequiredSection is returned (wrapped
+	// in an *EditError) unless this is true.
+	AllowRequiredSectionDeletes bool
+
+	// OnWarning, if non-nil, is called with a description of each
+	// edit that may produce an invalid descriptor (e.g., deleting a
+	// required section when AllowRequiredSectionDeletes is true),
+	// and of each replaced Item or System that loses elements which
+	// are not modeled (e.g., vendor-specific elements) or the
+	// ovf:msgid attributes of its elements.
+	OnWarning func(warning string)
+
+	// HardwareSection, when non-nil, limits the edits of a
+	// VirtualHardwareSection and the objects inside of it (e.g.,
+	// Items) to the sections that match. Objects outside of a
+	// VirtualHardwareSection are edited as usual.
+	HardwareSection *HardwareSectionMatch
+
+	// Limits bounds the size and complexity of the OVF. A
+	// *LimitError is returned if the OVF exceeds a limit.
+	Limits Limits
+
+	// KeepByteOrderMark, when true, starts the edited OVF with a
+	// UTF-8 byte order mark if the original OVF started with one
+	// (as some Windows tools write). Otherwise, the mark is removed.
+	KeepByteOrderMark bool
+}
+
+// ErrRequiredSection is returned when an EditObjectFunc deletes a section
+// that is required (see EditConfig.AllowRequiredSectionDeletes).
+var ErrRequiredSection = errors.New("section is required (its 'ovf:required' attribute is not \"false\")")
+
+// EditError describes a failure to edit a single OVF object.
+type EditError struct {
+	// Object is the name of the object that failed to be edited.
+	Object ObjectName
+
+	// Line is the 1-based line number of the object's start
+	// element in the original OVF.
+	Line int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (o *EditError) Error() string {
+	return "failed to edit '" + o.Object.String() + "' object on line " +
+		strconv.Itoa(o.Line) + " - " + o.Err.Error()
+}
+
+func (o *EditError) Unwrap() error {
+	return o.Err
+}
+
 // EditRawOvf edits an existing OVF configuration in the form of an io.Reader
 // given a set of EditScheme.
 func EditRawOvf(r io.Reader, scheme EditScheme) (*bytes.Buffer, error) {
-	raw, err := ioutil.ReadAll(r)
+	return EditRawOvfWithConfig(r, scheme, EditConfig{})
+}
+
+// EditRawOvfWithConfig edits an existing OVF configuration in the form of
+// an io.Reader given a set of EditScheme and an EditConfig.
+func EditRawOvfWithConfig(r io.Reader, scheme EditScheme, config EditConfig) (*bytes.Buffer, error) {
+	return editRawOvf(r, scheme, config, nil)
+}
+
+func editRawOvf(r io.Reader, scheme EditScheme, config EditConfig, planned *[]PlannedEdit) (*bytes.Buffer, error) {
+	raw, err := readLimited(r, config.Limits)
 	if err != nil {
 		return nil, err
 	}
 
+	// The mark would prevent the first line from being recognized.
+	raw, hasByteOrderMark := xmlutil.TrimByteOrderMark(raw)
+
 	err = xmlutil.ValidateFormatting(raw)
 	if err != nil {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	err = CheckLimits(raw, config.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	// The editor only sees elements that start a line.
+	raw, err = xmlutil.Normalize(raw)
+	if err != nil {
+		return nil, err
+	}
 
+	// The end of line characters are taken from the first line,
+	// since the last line may not have any.
 	endOfLineChars := lfEol
-	lenRaw := len(raw)
-	if lenRaw > 1 && raw[lenRaw-2] == '\r' {
+	firstEol := bytes.IndexByte(raw, '\n')
+	if firstEol > 0 && raw[firstEol-1] == '\r' {
 		endOfLineChars = crLfEol
 	}
 
-	newData := bytes.NewBuffer(nil)
+	locations, err := elementLocations(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	editor := &rawEditor{
+		scanner:   bufio.NewScanner(bytes.NewReader(raw)),
+		tracker:   &xmlutil.LineTracker{},
+		eol:       endOfLineChars,
+		newData:   bytes.NewBuffer(nil),
+		scheme:    scheme,
+		config:    config,
+		planned:   planned,
+		locations: locations,
+	}
+
+	editor.scanner.Split(editor.countLines)
 
-	for scanner.Scan() {
-		err := processNextToken(scanner, endOfLineChars, newData, scheme)
+	if hasByteOrderMark && config.KeepByteOrderMark {
+		editor.newData.WriteString(xmlutil.ByteOrderMark)
+	}
+
+	for editor.scanner.Scan() {
+		err := editor.processNextToken()
 		if err != nil {
-			return newData, err
+			return editor.newData, err
 		}
 	}
 
-	err = scanner.Err()
+	err = editor.scanner.Err()
 	if err != nil {
-		return newData, err
+		return editor.newData, err
+	}
+
+	// Every line is written with end of line characters, so they
+	// are removed from the last line if it did not have any.
+	if !bytes.HasSuffix(raw, lfEol) && bytes.HasSuffix(editor.newData.Bytes(), editor.eol) {
+		editor.newData.Truncate(editor.newData.Len() - len(editor.eol))
 	}
 
-	return newData, nil
+	if len(editor.errs) > 0 {
+		return editor.newData, errors.Join(editor.errs...)
+	}
+
+	return editor.newData, nil
 }
 
-func processNextToken(scanner *bufio.Scanner, eol []byte, newData *bytes.Buffer, scheme EditScheme) error {
-	rawLine := scanner.Bytes()
+// rawEditor maintains the state of a single EditRawOvfWithConfig call.
+type rawEditor struct {
+	scanner *bufio.Scanner
+	tracker *xmlutil.LineTracker
+	eol     []byte
+	newData *bytes.Buffer
+	scheme  EditScheme
+	config  EditConfig
+	line    int
+	errs    []error
+	planned *[]PlannedEdit
+
+	// locations maps line numbers to the location of the element
+	// that starts on the line.
+	locations map[int]elementLocation
+
+	// sectionIndex is the number of VirtualHardwareSection seen in
+	// the current VirtualSystem.
+	sectionIndex int
+
+	// inSection is true if the current line is inside of a
+	// VirtualHardwareSection, in which case sectionMatches is true
+	// if the section matches EditConfig.HardwareSection.
+	inSection      bool
+	sectionMatches bool
+}
+
+// countLines is a bufio.SplitFunc that keeps track of the current
+// line number.
+func (o *rawEditor) countLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if err == nil && token != nil {
+		o.line = o.line + 1
+	}
+
+	return advance, token, err
+}
+
+func (o *rawEditor) processNextToken() error {
+	rawLine := o.scanner.Bytes()
+	lineNumber := o.line
+
+	// Lines that begin inside of a comment or CDATA section are
+	// never treated as elements.
+	literal := o.tracker.Next(rawLine)
 
 	element, isStartElement := xmlutil.IsStartElement(rawLine)
-	if isStartElement {
+	if isStartElement && !literal {
 		var result []byte
 		action := NoOp
 
-		fns, shouldEdit := scheme.ShouldEditObject(ObjectName(element.Name.Local))
+		objectName := ObjectName(element.Name.Local)
+
+		isSectionStart := objectName == VirtualHardwareSectionName
+		if isSectionStart {
+			o.sectionMatches = o.config.HardwareSection.matches(element, o.sectionIndex)
+			o.sectionIndex = o.sectionIndex + 1
+		} else if objectName == "VirtualSystem" {
+			o.sectionIndex = 0
+		}
+
+		location := o.locations[lineNumber]
+
+		fns, shouldEdit := o.shouldEditObject(objectName, location.path)
+
+		// Funcs proposed for the qualified name are executed after
+		// those proposed for the local name.
+		qualifiedFns, shouldEditQualified := o.shouldEditObject(
+			QualifiedObjectName(location.namespace, objectName.String()), location.path)
+		if shouldEditQualified {
+			fns = append(append([]EditObjectFunc(nil), fns...), qualifiedFns...)
+			shouldEdit = true
+		}
+
+		if shouldEdit && !isStandardLocation(objectName, location.path) {
+			shouldEdit = false
+		}
+		if shouldEdit && (o.inSection || isSectionStart) && !o.sectionMatches {
+			shouldEdit = false
+		}
+
+		// The contents of an edited object are consumed with it.
+		if isSectionStart && !shouldEdit && !isSelfContained(rawLine) {
+			o.inSection = true
+		}
+
 		if shouldEdit {
-			findConfig, err := xmlutil.NewFindObjectConfig(element, scanner, eol)
+			findConfig, err := xmlutil.NewFindObjectConfig(element, o.scanner, o.eol)
 			if err != nil {
 				return err
 			}
 
-			result, action, err = edit(findConfig, fns)
+			var outcome editOutcome
+			outcome, err = edit(findConfig, fns, o.config)
+			result = outcome.data
+			action = outcome.action
+			if err == nil && action == Delete {
+				err = o.checkDelete(element, objectName, lineNumber)
+			}
+			if err == nil && action == Replace && len(outcome.dropped) > 0 && o.config.OnWarning != nil {
+				o.config.OnWarning("replaced '" + objectName.String() + "' on line " + strconv.Itoa(lineNumber) +
+					" without its '" + strings.Join(outcome.dropped, "', '") + "' elements, which are not modeled")
+			}
+			if err == nil && action == Replace && len(outcome.unlocalized) > 0 && o.config.OnWarning != nil {
+				o.config.OnWarning("replaced '" + objectName.String() + "' on line " + strconv.Itoa(lineNumber) +
+					" without the ovf:msgid of its '" + strings.Join(outcome.unlocalized, "', '") +
+					"' elements, meaning that their text is no longer localized")
+			}
+			if err == nil && o.planned != nil && action != NoOp {
+				*o.planned = append(*o.planned, PlannedEdit{
+					Object:    objectName,
+					Line:      lineNumber,
+					FuncIndex: outcome.funcIndex,
+					Action:    action,
+					Original:  outcome.object,
+				})
+			}
 			if err != nil {
-				return err
+				err = &EditError{
+					Object: objectName,
+					Line:   lineNumber,
+					Err:    err,
+				}
+
+				if !o.config.ContinueOnError {
+					return err
+				}
+
+				o.errs = append(o.errs, err)
+				action = NoOp
 			}
 		}
 
 		switch action {
 		case NoOp:
 			if len(result) > 0 {
-				newData.Write(result)
+				o.newData.Write(result)
 			} else {
-				newData.Write(rawLine)
+				o.newData.Write(rawLine)
 			}
 		case Delete:
 			return nil
 		case Replace:
-			newData.Write(result)
+			o.newData.Write(result)
 		default:
 			return errors.New("unknown EditAction - '" + action.String() + "")
 		}
 
-		newData.Write(eol)
+		o.newData.Write(o.eol)
 
 		return nil
 	}
 
-	newData.Write(rawLine)
+	if o.inSection {
+		end, isEndElement := xmlutil.IsEndElement(rawLine)
+		if isEndElement && !literal && end.Name.Local == VirtualHardwareSectionName.String() {
+			o.inSection = false
+		}
+	}
+
+	o.newData.Write(rawLine)
 
-	newData.Write(eol)
+	o.newData.Write(o.eol)
 
 	return nil
 }
 
-func edit(findConfig xmlutil.FindObjectConfig, funcs []EditObjectFunc) ([]byte, EditAction, error) {
+// standardParents are the parents of the OVF objects that are described
+// by the OVF specification. Elements with the same name elsewhere (e.g.,
+// the 'Network' element inside of a vbox:Machine) are vendor-specific
+// look-alikes, which are never edited.
+var standardParents = map[ObjectName]Path{
+	VirtualHardwareSystemName: {"VirtualSystem", VirtualHardwareSectionName.String()},
+	VirtualHardwareItemName:   {"VirtualSystem", VirtualHardwareSectionName.String()},
+	ReferencesFileName:        {EnvelopeName.String(), "References"},
+	DiskName:                  {EnvelopeName.String(), "DiskSection"},
+	NetworkName:               {EnvelopeName.String(), "NetworkSection"},
+}
+
+// isStandardLocation returns true if the object at the specified Path is
+// where the OVF specification places objects with its name. Objects that
+// the specification does not describe are always in a standard location.
+func isStandardLocation(objectName ObjectName, path Path) bool {
+	parent, ok := standardParents[objectName]
+	if !ok {
+		return true
+	}
+
+	return path.Parent().HasSuffix(parent...)
+}
+
+// shouldEditObject returns the funcs that the EditScheme proposed for an
+// object at the specified Path.
+func (o *rawEditor) shouldEditObject(objectName ObjectName, path Path) ([]EditObjectFunc, bool) {
+	if pathScheme, ok := o.scheme.(PathEditScheme); ok {
+		return pathScheme.ShouldEditObjectAt(objectName, path)
+	}
+
+	return o.scheme.ShouldEditObject(objectName)
+}
+
+// elementLocation describes where an element is in an OVF.
+type elementLocation struct {
+	path Path
+
+	// namespace is the URL of the element's XML namespace, which
+	// is empty if the element's prefix is not declared.
+	namespace string
+}
+
+// elementLocations returns the location of each element in an OVF, keyed
+// by the number of the line that the element starts on. Only the first
+// element that starts on a line is included.
+func elementLocations(raw []byte) (map[int]elementLocation, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+	locations := make(map[int]elementLocation)
+
+	var path Path
+
+	// scopes contains the namespaces declared by each open
+	// element, keyed by prefix. The default namespace has an
+	// empty prefix.
+	var scopes []map[string]string
+
+	for {
+		line, _ := d.InputPos()
+
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			return locations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			for _, attr := range v.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+				case len(attr.Name.Space) == 0 && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+
+			path = append(path, v.Name.Local)
+			if _, ok := locations[line]; !ok {
+				locations[line] = elementLocation{
+					path:      append(Path(nil), path...),
+					namespace: resolveNamespace(scopes, v.Name.Space),
+				}
+			}
+		case xml.EndElement:
+			path = path.Parent()
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+}
+
+// resolveNamespace returns the namespace URL of a prefix, which is
+// declared by the innermost scope that declares it.
+func resolveNamespace(scopes []map[string]string, prefix string) string {
+	if prefix == "xml" {
+		return xmlNamespace
+	}
+
+	for i := len(scopes) - 1; i >= 0; i-- {
+		namespace, ok := scopes[i][prefix]
+		if ok {
+			return namespace
+		}
+	}
+
+	return ""
+}
+
+// checkDelete returns ErrRequiredSection if the object that starts with
+// the specified element is a required section, and required sections
+// may not be deleted.
+func (o *rawEditor) checkDelete(start *xml.StartElement, objectName ObjectName, lineNumber int) error {
+	required, isSection := sectionRequired(start)
+	if !isSection || !required {
+		return nil
+	}
+
+	if !o.config.AllowRequiredSectionDeletes {
+		return ErrRequiredSection
+	}
+
+	if o.config.OnWarning != nil {
+		o.config.OnWarning("deleted required section '" + objectName.String() +
+			"' on line " + strconv.Itoa(lineNumber) + ", the OVF may be rejected by its consumers")
+	}
+
+	return nil
+}
+
+// sectionRequired returns true if the element is the start of a section,
+// and whether the section is required. Sections are identified by their
+// name (e.g., 'DiskSection'), or by the 'ovf:required' attribute of an
+// extension element (e.g., 'vbox:Machine'). The OVF specification states
+// that sections are required unless 'ovf:required' is "false".
+func sectionRequired(start *xml.StartElement) (bool, bool) {
+	requiredValue := ""
+	hasRequired := false
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "required" {
+			requiredValue = attr.Value
+			hasRequired = true
+		}
+	}
+
+	isExtension := len(start.Name.Space) > 0 && start.Name.Space != "ovf"
+	if !strings.HasSuffix(start.Name.Local, "Section") && !(isExtension && hasRequired) {
+		return false, false
+	}
+
+	return strings.TrimSpace(requiredValue) != "false", true
+}
+
+// isSelfContained returns true if a line contains an element's start and
+// end (e.g., '<Info>Example</Info>' or '<Info/>').
+func isSelfContained(line []byte) bool {
+	line = bytes.TrimSpace(line)
+
+	return bytes.HasSuffix(line, []byte("/>")) || bytes.Contains(line, []byte("</"))
+}
+
+// editOutcome is the result of running a set of EditObjectFunc against
+// a single OVF object.
+type editOutcome struct {
+	data      []byte
+	action    EditAction
+	funcIndex int
+	object    interface{}
+
+	// dropped are the elements of the original object that are
+	// not in its replacement (see droppedElements).
+	dropped []string
+
+	// unlocalized are the elements of the original object that
+	// lost their ovf:msgid in its replacement.
+	unlocalized []string
+}
+
+func edit(findConfig xmlutil.FindObjectConfig, funcs []EditObjectFunc, config EditConfig) (editOutcome, error) {
 	var rawObject xmlutil.RawObject
 	var err error
 
@@ -174,7 +759,12 @@ func edit(findConfig xmlutil.FindObjectConfig, funcs []EditObjectFunc) ([]byte,
 		i interface{}
 	}{}
 
-	switch findConfig.Start().Name.Local {
+	name := findConfig.Start().Name.Local
+	if config.RawObjects {
+		name = ""
+	}
+
+	switch name {
 	case VirtualHardwareSystemName.String():
 		t := System{}
 		rawObject, err = xmlutil.FindAndDeserializeObject(findConfig, &t)
@@ -184,37 +774,195 @@ func edit(findConfig xmlutil.FindObjectConfig, funcs []EditObjectFunc) ([]byte,
 		rawObject, err = xmlutil.FindAndDeserializeObject(findConfig, &t)
 		temp.i = t
 	default:
-		return []byte{}, NoOp, errors.New("deserializing object '" +
-			findConfig.Start().Name.Local + "' is not supported")
+		rawObject, err = xmlutil.FindObject(findConfig)
+		editable, _ := rawObject.(xmlutil.EditableRawObject)
+		temp.i = &RawObject{EditableRawObject: editable, Start: findConfig.Start().Copy()}
 	}
 	if err != nil {
-		return []byte{}, NoOp, err
+		if rawObject != nil {
+			return editOutcome{data: rawObject.Data().Bytes(), action: NoOp, funcIndex: -1}, err
+		}
+
+		return editOutcome{action: NoOp, funcIndex: -1}, err
 	}
 
-	for _, f := range funcs {
-		result := f(temp.i)
+	// RawObject may be modified in place by the EditObjectFunc.
+	original := append([]byte(nil), rawObject.Data().Bytes()...)
+
+	current := temp.i
+	var replacement EditedObject
+	replacedBy := -1
+
+	for i, f := range funcs {
+		result := f(current)
 		switch result.Action {
 		case NoOp:
 			continue
 		case Delete:
-			return []byte{}, Delete, nil
+			return editOutcome{data: original, action: Delete, funcIndex: i, object: temp.i}, nil
 		case Replace:
-			raw, err := xml.MarshalIndent(result.Object.Marshallable(),
-				rawObject.StartAndEndLinePrefix(), rawObject.RelativeBodyPrefix())
-			if err != nil {
-				return []byte{}, NoOp, err
+			if isNilObject(result.Object) {
+				return editOutcome{data: original, action: NoOp, funcIndex: i, object: temp.i},
+					errors.New("EditObjectFunc " + strconv.Itoa(i) + " returned '" + Replace.String() + "' without an object")
 			}
 
-			return raw, Replace, nil
+			if config.StopAtFirstReplace {
+				return replaceOutcome(rawObject, result.Object, findConfig.Eol(), original, i, temp.i)
+			}
+
+			replacement = result.Object
+			replacedBy = i
+			current = chainable(result.Object)
 		}
 	}
 
-	return rawObject.Data().Bytes(), NoOp, nil
+	if replacement != nil {
+		return replaceOutcome(rawObject, replacement, findConfig.Eol(), original, replacedBy, temp.i)
+	}
+
+	return editOutcome{data: original, action: NoOp, funcIndex: -1, object: temp.i}, nil
+}
+
+// replaceOutcome returns the editOutcome of replacing an object. The
+// replacement is written using the specified end of line characters.
+func replaceOutcome(rawObject xmlutil.RawObject, replacement EditedObject, eol []byte, original []byte, funcIndex int, object interface{}) (editOutcome, error) {
+	if raw, ok := replacement.(*RawObject); ok {
+		return editOutcome{data: raw.Data().Bytes(), action: Replace, funcIndex: funcIndex, object: object}, nil
+	}
+
+	raw, err := xml.MarshalIndent(replacement.Marshallable(),
+		rawObject.StartAndEndLinePrefix(), rawObject.RelativeBodyPrefix())
+	if err != nil {
+		return editOutcome{data: original, action: NoOp, funcIndex: funcIndex, object: object}, err
+	}
+
+	// MarshalIndent always separates lines with '\n'.
+	if len(eol) > 0 && !bytes.Equal(eol, lfEol) {
+		raw = bytes.Replace(raw, lfEol, eol, -1)
+	}
+
+	var dropped []string
+	var unlocalized []string
+	switch object.(type) {
+	case Item:
+		dropped, unlocalized = droppedElements(original, itemElements)
+	case System:
+		dropped, unlocalized = droppedElements(original, systemElements)
+	}
+
+	return editOutcome{
+		data:        raw,
+		action:      Replace,
+		funcIndex:   funcIndex,
+		object:      object,
+		dropped:     dropped,
+		unlocalized: unlocalized,
+	}, nil
+}
+
+// droppedElements returns the names of the child elements of a raw Item or
+// System that are lost when it is marshalled: elements that are not
+// modeled (e.g., 'vmw:Config'), and the additional values of modeled
+// elements that may occur more than once (e.g., a second Connection).
+// The modeled map is the element names of the object's generated binding.
+// The names of the kept elements that lose their ovf:msgid attribute are
+// also returned, as marshalling does not keep attributes.
+func droppedElements(original []byte, modeled map[string]bool) ([]string, []string) {
+	d := xmlutil.NewDecoder(bytes.NewReader(original))
+
+	var dropped []string
+	var unlocalized []string
+	seen := make(map[string]bool)
+	reported := make(map[string]bool)
+	depth := 0
+
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			return dropped, unlocalized
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			depth = depth + 1
+			if depth != 2 {
+				continue
+			}
+
+			name := v.Name.Local
+			if len(v.Name.Space) > 0 {
+				name = v.Name.Space + ":" + name
+			}
+
+			multiple, isModeled := modeled[v.Name.Local]
+			isDropped := !isModeled || (multiple && seen[v.Name.Local])
+			seen[v.Name.Local] = true
+
+			if isDropped && !reported[name] {
+				dropped = append(dropped, name)
+				reported[name] = true
+			}
+
+			if !isDropped && hasMsgId(v) {
+				unlocalized = append(unlocalized, name)
+			}
+		case xml.EndElement:
+			depth = depth - 1
+		}
+	}
+}
+
+// hasMsgId returns true if an element has an ovf:msgid attribute,
+// meaning that its text is localized.
+func hasMsgId(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "msgid" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNilObject returns true if an EditedObject is nil, or is a nil pointer
+// of a type that is known to this package.
+func isNilObject(object EditedObject) bool {
+	switch v := object.(type) {
+	case nil:
+		return true
+	case *Item:
+		return v == nil
+	case *System:
+		return v == nil
+	case *RawObject:
+		return v == nil
+	default:
+		return false
+	}
+}
+
+// chainable returns a replacement object in the form that is provided to
+// an EditObjectFunc (e.g., an Item rather than a *Item).
+func chainable(replacement EditedObject) interface{} {
+	switch v := replacement.(type) {
+	case *Item:
+		return *v
+	case *System:
+		return *v
+	default:
+		return replacement
+	}
 }
 
 // NewEditScheme returns a new instance of EditScheme.
 func NewEditScheme() EditScheme {
+	return NewPathEditScheme()
+}
+
+// NewPathEditScheme returns a new instance of PathEditScheme.
+func NewPathEditScheme() PathEditScheme {
 	return &defaultEditScheme{
-		objectNamesToFuncs: make(map[ObjectName][]EditObjectFunc),
+		objectNamesToFuncs:   make(map[ObjectName][]EditObjectFunc),
+		objectNamesToParents: make(map[ObjectName][]Path),
 	}
 }
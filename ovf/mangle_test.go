@@ -2,156 +2,146 @@ package ovf
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"testing"
 	"unicode"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
 )
 
-func TestEditRawOvfDeleteHardwareItems(t *testing.T) {
-	f := DeleteHardwareItemsMatchingFunc("ideController", -1)
+func TestEditRawOvfGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme func(t *testing.T) EditScheme
+	}{
+		{
+			name: "delete-hardware-items",
+			scheme: func(t *testing.T) EditScheme {
+				return NewEditScheme().Propose(DeleteHardwareItemsMatchingFunc("ideController", -1), VirtualHardwareItemName)
+			},
+		},
+		{
+			name: "delete-one-hardware-item",
+			scheme: func(t *testing.T) EditScheme {
+				return NewEditScheme().Propose(DeleteHardwareItemsMatchingFunc("ideController", 1), VirtualHardwareItemName)
+			},
+		},
+		{
+			name: "replace-hardware-item",
+			scheme: func(t *testing.T) EditScheme {
+				replacement := Item{
+					Address:         "0",
+					Caption:         "SATA Controller",
+					Description:     "SATAController",
+					ElementName:     "SATAController1",
+					InstanceID:      "3",
+					ResourceSubType: "vmware.sata.ahci",
+					ResourceType:    SataControllerResourceType,
+				}
+
+				return NewEditScheme().Propose(ReplaceHardwareItemFunc("sataController0", replacement), VirtualHardwareItemName)
+			},
+		},
+		{
+			name: "update-virtual-system-type",
+			scheme: func(t *testing.T) EditScheme {
+				return NewEditScheme().Propose(SetVirtualSystemTypeFunc("junk"), VirtualHardwareSystemName)
+			},
+		},
+		{
+			name: "multiple-changes",
+			scheme: func(t *testing.T) EditScheme {
+				ovfData, err := ToOvf(strings.NewReader(basicOvfFileContents))
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+
+				options := NewEditScheme().
+					Propose(SetVirtualSystemTypeFunc("junk"), VirtualHardwareSystemName).
+					Propose(DeleteHardwareItemsMatchingFunc("ideController", -1), VirtualHardwareItemName)
+
+				for _, item := range ovfData.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+					if item.ElementName == "sataController0" {
+						editedController := item
+						editedController.Caption = "SATA Controller"
+						editedController.Description = "SATAController"
+						editedController.ElementName = "SATAController0"
+						editedController.ResourceSubType = "vmware.sata.ahci"
+
+						options.Propose(ReplaceHardwareItemFunc("sataController0", editedController), VirtualHardwareItemName)
+						break
+					}
+				}
+
+				return options
+			},
+		},
+		{
+			name: "modify-hardware-items-of-resource-type",
+			scheme: func(t *testing.T) EditScheme {
+				modifyFunc := func(sataController Item) Item {
+					sataController.Caption = "SATA Controller"
+					sataController.Description = "SATAController"
+
+					updatedElementNameBuffer := bytes.NewBuffer(nil)
+					updatedElementNameBuffer.WriteString("SATAController")
+					for i := range sataController.ElementName {
+						char := rune(sataController.ElementName[i])
+						if unicode.IsDigit(char) {
+							updatedElementNameBuffer.WriteString(string(char))
+						}
+					}
+					sataController.ElementName = updatedElementNameBuffer.String()
+
+					sataController.ResourceSubType = "vmware.sata.ahci"
+
+					return sataController
+				}
+
+				return NewEditScheme().Propose(ModifyHardwareItemsOfResourceTypeFunc(OtherStorageDeviceResourceType, modifyFunc),
+					VirtualHardwareItemName)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), test.scheme(t))
+		if err != nil {
+			t.Fatal("Test '" + test.name + "' failed - " + err.Error())
+		}
+
+		checkGolden(t, test.name+".ovf", b.Bytes())
+	}
+}
+
+func TestEditRawOvfRawObject(t *testing.T) {
+	f := func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			t.Fatalf("Got unexpected object type %T", i)
+		}
+
+		err := o.SetChildText("Description", "CentOS 7")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
 
-	editScheme := NewEditScheme().Propose(f, VirtualHardwareItemName)
+	editScheme := NewEditScheme().Propose(f, "OperatingSystemSection")
 
 	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
-      </System>
-      <Item>
-        <rasd:Caption>1 virtual CPU</rasd:Caption>
-        <rasd:Description>Number of virtual CPUs</rasd:Description>
-        <rasd:ElementName>1 virtual CPU</rasd:ElementName>
-        <rasd:InstanceID>1</rasd:InstanceID>
-        <rasd:ResourceType>3</rasd:ResourceType>
-        <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>sataController0</rasd:Caption>
-        <rasd:Description>SATA Controller</rasd:Description>
-        <rasd:ElementName>sataController0</rasd:ElementName>
-        <rasd:InstanceID>5</rasd:InstanceID>
-        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
-        <rasd:ResourceType>20</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+	expected := strings.Replace(basicOvfFileContents,
+		"<Description>RedHat_64</Description>", "<Description>CentOS 7</Description>", 1)
 
 	result := b.String()
 	if result != expected {
@@ -159,47 +149,104 @@ func TestEditRawOvfDeleteHardwareItems(t *testing.T) {
 	}
 }
 
-func TestEditRawOvfDeleteOneHardwareItem(t *testing.T) {
-	f := DeleteHardwareItemsMatchingFunc("ideController", 1)
+func TestEditRawOvfIgnoresCommentedObjects(t *testing.T) {
+	commented := strings.Replace(basicOvfFileContents, "      <Item>\n        <rasd:Address>0</rasd:Address>\n        <rasd:Caption>ideController0",
+		"      <!--\n      <Item>\n      -->\n      <Item>\n        <rasd:Address>0</rasd:Address>\n        <rasd:Caption>ideController0", 1)
+	if commented == basicOvfFileContents {
+		t.Fatal("Failed to insert comment into test data")
+	}
 
-	options := NewEditScheme().Propose(f, VirtualHardwareItemName)
+	editScheme := NewEditScheme().Propose(DeleteHardwareItemsMatchingFunc("ideController0", -1), VirtualHardwareItemName)
 
-	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options)
+	b, err := EditRawOvf(strings.NewReader(commented), editScheme)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
-      </System>
-      <Item>
+	if !strings.Contains(b.String(), "      <!--\n      <Item>\n      -->\n") {
+		t.Fatal("Commented object was modified:\n'" + b.String() + "'")
+	}
+
+	if strings.Contains(b.String(), "ideController0") {
+		t.Fatal("Failed to delete object following comment:\n'" + b.String() + "'")
+	}
+}
+
+type unmarshallableObject struct{}
+
+func (o *unmarshallableObject) Marshallable() interface{} {
+	return make(chan int)
+}
+
+func TestEditRawOvfWithConfigContinueOnError(t *testing.T) {
+	f := func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok || !strings.HasPrefix(o.ElementName, "ideController") {
+			return EditObjectResult{
+				Action: NoOp,
+				Object: &o,
+			}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &unmarshallableObject{},
+		}
+	}
+
+	editScheme := NewEditScheme().
+		Propose(f, VirtualHardwareItemName).
+		Propose(SetVirtualSystemTypeFunc("vmx-10"), VirtualHardwareSystemName)
+
+	_, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	b, err := EditRawOvfWithConfig(strings.NewReader(basicOvfFileContents), editScheme,
+		EditConfig{ContinueOnError: true})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected a joined error, got %T", err)
+	}
+
+	errs := joined.Unwrap()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d - %s", len(errs), err.Error())
+	}
+
+	for i, line := range []int{48, 57} {
+		editErr, ok := errs[i].(*EditError)
+		if !ok {
+			t.Fatalf("Got unexpected error type %T", errs[i])
+		}
+
+		if editErr.Line != line || editErr.Object != VirtualHardwareItemName {
+			t.Fatal("Got unexpected error - " + editErr.Error())
+		}
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "virtualbox-2.2", "vmx-10", 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestEditRawOvfDeleteHardwareItemByInstanceID(t *testing.T) {
+	editScheme := NewEditScheme().Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, `      <Item>
         <rasd:Caption>1 virtual CPU</rasd:Caption>
         <rasd:Description>Number of virtual CPUs</rasd:Description>
         <rasd:ElementName>1 virtual CPU</rasd:ElementName>
@@ -207,112 +254,10 @@ func TestEditRawOvfDeleteOneHardwareItem(t *testing.T) {
         <rasd:ResourceType>3</rasd:ResourceType>
         <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
       </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>1</rasd:Address>
-        <rasd:Caption>ideController1</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController1</rasd:ElementName>
-        <rasd:InstanceID>4</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>sataController0</rasd:Caption>
-        <rasd:Description>SATA Controller</rasd:Description>
-        <rasd:ElementName>sataController0</rasd:ElementName>
-        <rasd:InstanceID>5</rasd:InstanceID>
-        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
-        <rasd:ResourceType>20</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+`, "", 1)
+	if expected == basicOvfFileContents {
+		t.Fatal("Failed to find CPU Item in test data")
+	}
 
 	result := b.String()
 	if result != expected {
@@ -320,585 +265,414 @@ func TestEditRawOvfDeleteOneHardwareItem(t *testing.T) {
 	}
 }
 
-func TestEditRawOvfReplaceHardwareItem(t *testing.T) {
+func TestEditRawOvfReplaceHardwareItemByInstanceID(t *testing.T) {
 	replacement := Item{
-		Address:         "0",
-		Caption:         "SATA Controller",
-		Description:     "SATAController",
-		ElementName:     "SATAController1",
-		InstanceID:      "3",
-		ResourceSubType: "vmware.sata.ahci",
-		ResourceType:    "20",
+		AllocationUnits: "MegaBytes",
+		Caption:         "1024 MB of memory",
+		Description:     "Memory Size",
+		ElementName:     "1024 MB of memory",
+		InstanceID:      "2",
+		ResourceType:    "4",
+		VirtualQuantity: "1024",
 	}
 
-	f := ReplaceHardwareItemFunc("sataController0", replacement)
+	editScheme := NewEditScheme().Propose(ReplaceHardwareItemByInstanceIDFunc("2", replacement), VirtualHardwareItemName)
 
-	options := NewEditScheme().Propose(f, VirtualHardwareItemName)
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
 
-	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options)
+	parsed, err := ToOvf(strings.NewReader(b.String()))
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
-      </System>
-      <Item>
-        <rasd:Caption>1 virtual CPU</rasd:Caption>
-        <rasd:Description>Number of virtual CPUs</rasd:Description>
-        <rasd:ElementName>1 virtual CPU</rasd:ElementName>
-        <rasd:InstanceID>1</rasd:InstanceID>
-        <rasd:ResourceType>3</rasd:ResourceType>
-        <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>ideController0</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController0</rasd:ElementName>
-        <rasd:InstanceID>3</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>1</rasd:Address>
-        <rasd:Caption>ideController1</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController1</rasd:ElementName>
-        <rasd:InstanceID>4</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>SATA Controller</rasd:Caption>
-        <rasd:Description>SATAController</rasd:Description>
-        <rasd:ElementName>SATAController1</rasd:ElementName>
-        <rasd:InstanceID>3</rasd:InstanceID>
-        <rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>
-        <rasd:ResourceType>20</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+	replaced := 0
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.InstanceID == "2" {
+			replaced = replaced + 1
+			if item.VirtualQuantity != "1024" || item.ElementName != "1024 MB of memory" {
+				t.Fatal("Item was not replaced - got '" + item.ElementName + "'")
+			}
+		}
+	}
+
+	if replaced != 1 {
+		t.Fatal("Expected exactly one Item with InstanceID 2")
+	}
+
+	if !strings.Contains(b.String(), "<rasd:ElementName>1 virtual CPU</rasd:ElementName>") {
+		t.Fatal("Other Items were modified:\n'" + b.String() + "'")
+	}
+}
+
+func TestMergeEditSchemes(t *testing.T) {
+	base := NewEditScheme().
+		Propose(SetVirtualSystemTypeFunc("vmx-10"), VirtualHardwareSystemName)
+
+	overrides := NewEditScheme().
+		Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName)
+
+	clone, err := CloneEditScheme(base)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	merged, err := MergeEditSchemes(clone, overrides)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	names := merged.ObjectNames()
+	if len(names) != 2 || names[0] != VirtualHardwareItemName || names[1] != VirtualHardwareSystemName {
+		t.Fatal("Got unexpected object names after merging")
+	}
+
+	_, ok := base.ShouldEditObject(VirtualHardwareItemName)
+	if ok {
+		t.Fatal("Merging modified the original EditScheme")
+	}
+
+	clone.Propose(DeleteHardwareItemByInstanceIDFunc("2"), VirtualHardwareItemName)
+
+	_, ok = base.ShouldEditObject(VirtualHardwareItemName)
+	if ok {
+		t.Fatal("Modifying a clone modified the original EditScheme")
+	}
+
+	fns, _ := overrides.ShouldEditObject(VirtualHardwareItemName)
+	if len(fns) != 1 {
+		t.Fatal("Merging modified the other EditScheme")
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), merged)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
 
 	result := b.String()
-	if result != expected {
+	if !strings.Contains(result, "<vssd:VirtualSystemType>vmx-10</vssd:VirtualSystemType>") ||
+		strings.Contains(result, "<rasd:ElementName>1 virtual CPU</rasd:ElementName>") {
 		t.Fatal("Did not get expected result:\n'" + result + "'")
 	}
+
+	merged, err = MergeEditSchemes(merged, merged)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fns, _ = merged.ShouldEditObject(VirtualHardwareSystemName)
+	if len(fns) != 2 {
+		t.Fatal("Merging an EditScheme with itself should propose its funcs twice")
+	}
+}
+
+// singleFuncEditScheme is an EditScheme that does not implement
+// ListableEditScheme.
+type singleFuncEditScheme struct {
+	objectName ObjectName
+	f          EditObjectFunc
 }
 
-func TestEditRawOvfUpdateVirtualSystemType(t *testing.T) {
-	f := SetVirtualSystemTypeFunc("junk")
+func (o *singleFuncEditScheme) ShouldEditObject(objectName ObjectName) ([]EditObjectFunc, bool) {
+	if objectName != o.objectName || o.f == nil {
+		return nil, false
+	}
+
+	return []EditObjectFunc{o.f}, true
+}
 
-	options := NewEditScheme().Propose(f, VirtualHardwareSystemName)
+func (o *singleFuncEditScheme) Propose(f EditObjectFunc, objectName ObjectName) EditScheme {
+	o.f = f
+	o.objectName = objectName
+	return o
+}
+
+func TestMergeEditSchemesUnlistable(t *testing.T) {
+	unlistable := (&singleFuncEditScheme{}).
+		Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName)
+
+	_, err := MergeEditSchemes(NewEditScheme(), unlistable)
+	if !errors.Is(err, ErrUnlistableEditScheme) {
+		t.Fatalf("Expected ErrUnlistableEditScheme - got: %v", err)
+	}
+
+	_, err = CloneEditScheme(unlistable)
+	if !errors.Is(err, ErrUnlistableEditScheme) {
+		t.Fatalf("Expected ErrUnlistableEditScheme - got: %v", err)
+	}
 
-	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options)
+	// An unlistable EditScheme can still be used to edit.
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), unlistable)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>junk</vssd:VirtualSystemType>
-      </System>
-      <Item>
-        <rasd:Caption>1 virtual CPU</rasd:Caption>
-        <rasd:Description>Number of virtual CPUs</rasd:Description>
-        <rasd:ElementName>1 virtual CPU</rasd:ElementName>
-        <rasd:InstanceID>1</rasd:InstanceID>
-        <rasd:ResourceType>3</rasd:ResourceType>
-        <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>ideController0</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController0</rasd:ElementName>
-        <rasd:InstanceID>3</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>1</rasd:Address>
-        <rasd:Caption>ideController1</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController1</rasd:ElementName>
-        <rasd:InstanceID>4</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>sataController0</rasd:Caption>
-        <rasd:Description>SATA Controller</rasd:Description>
-        <rasd:ElementName>sataController0</rasd:ElementName>
-        <rasd:InstanceID>5</rasd:InstanceID>
-        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
-        <rasd:ResourceType>20</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+	if strings.Contains(b.String(), "<rasd:ElementName>1 virtual CPU</rasd:ElementName>") {
+		t.Fatal("Item was not deleted by an unlistable EditScheme")
+	}
+}
+
+func TestEditRawOvfChainsReplacements(t *testing.T) {
+	newScheme := func() EditScheme {
+		return NewEditScheme().
+			Propose(SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"), VirtualHardwareItemName).
+			Propose(ModifyHardwareItemsOfResourceTypeFunc(EthernetAdapterResourceType, func(i Item) Item {
+				i.Caption = "Network adapter 1"
+				return i
+			}), VirtualHardwareItemName).
+			Propose(DeleteHardwareItemsMatchingFunc("ideController", -1), VirtualHardwareItemName)
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), newScheme())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
 
 	result := b.String()
-	if result != expected {
-		t.Fatal("Did not get expected result:\n'" + result + "'")
+	if !strings.Contains(result, ">VmxNet3<") || !strings.Contains(result, "Network adapter 1") {
+		t.Fatal("Replacements were not chained:\n'" + result + "'")
+	}
+
+	if strings.Contains(result, "ideController") {
+		t.Fatal("Delete was not applied when chaining replacements:\n'" + result + "'")
+	}
+
+	b, err = EditRawOvfWithConfig(strings.NewReader(basicOvfFileContents), newScheme(),
+		EditConfig{StopAtFirstReplace: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result = b.String()
+	if !strings.Contains(result, ">VmxNet3<") || strings.Contains(result, "Network adapter 1") {
+		t.Fatal("Only the first replacement should be made:\n'" + result + "'")
 	}
 }
 
-func TestEditRawOvfMultipleChanges(t *testing.T) {
-	ovfData, err := ToOvf(strings.NewReader(basicOvfFileContents))
+func TestEditRawOvfDeleteStopsChain(t *testing.T) {
+	called := false
+
+	editScheme := NewEditScheme().
+		Propose(SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"), VirtualHardwareItemName).
+		Propose(DeleteHardwareItemsMatchFunc(ItemMatch{ResourceType: EthernetAdapterResourceType}, -1), VirtualHardwareItemName).
+		Propose(func(i interface{}) EditObjectResult {
+			o := i.(Item)
+			if o.ResourceType == EthernetAdapterResourceType {
+				called = true
+			}
+
+			return EditObjectResult{
+				Action: NoOp,
+				Object: &o,
+			}
+		}, VirtualHardwareItemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	options := NewEditScheme().
-		Propose(SetVirtualSystemTypeFunc("junk"), VirtualHardwareSystemName).
-		Propose(DeleteHardwareItemsMatchingFunc("ideController", -1), VirtualHardwareItemName)
+	if strings.Contains(b.String(), "VmxNet3") {
+		t.Fatal("Replaced Item was not deleted:\n'" + b.String() + "'")
+	}
 
-	for _, item := range ovfData.Envelope.VirtualSystem.VirtualHardwareSection.Items {
-		if item.ElementName == "sataController0" {
-			editedController := item
-			editedController.Caption = "SATA Controller"
-			editedController.Description = "SATAController"
-			editedController.ElementName = "SATAController0"
-			editedController.ResourceSubType = "vmware.sata.ahci"
+	if called {
+		t.Fatal("Funcs proposed after a Delete should not be executed")
+	}
+}
 
-			f := ReplaceHardwareItemFunc("sataController0", editedController)
-			options.Propose(f, VirtualHardwareItemName)
-			break
+func TestEditObjectResultObjectOnlyRequiredForReplace(t *testing.T) {
+	deleteCpu := func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok || o.InstanceID != "1" {
+			return EditObjectResult{Action: NoOp}
 		}
+
+		return EditObjectResult{Action: Delete}
 	}
 
-	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options)
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(deleteCpu, VirtualHardwareItemName))
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>junk</vssd:VirtualSystemType>
-      </System>
-      <Item>
-        <rasd:Caption>1 virtual CPU</rasd:Caption>
-        <rasd:Description>Number of virtual CPUs</rasd:Description>
-        <rasd:ElementName>1 virtual CPU</rasd:ElementName>
-        <rasd:InstanceID>1</rasd:InstanceID>
-        <rasd:ResourceType>3</rasd:ResourceType>
-        <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>SATA Controller</rasd:Caption>
-        <rasd:Description>SATAController</rasd:Description>
-        <rasd:ElementName>SATAController0</rasd:ElementName>
-        <rasd:InstanceID>5</rasd:InstanceID>
-        <rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>
-        <rasd:ResourceType>20</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+	if strings.Contains(b.String(), "1 virtual CPU") {
+		t.Fatal("Item was not deleted:\n'" + b.String() + "'")
+	}
 
-	result := b.String()
-	if result != expected {
-		t.Fatal("Did not get expected result:\n'" + result + "'")
+	var missing *Item
+	replaceWithNothing := func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Replace, Object: missing}
+	}
+
+	_, err = EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(replaceWithNothing, VirtualHardwareItemName))
+	if err == nil {
+		t.Fatal("Expected an error when Replace is returned without an object")
 	}
 }
 
-func TestEditRawOvfModifyHardwareItemsOfResourceTypeFunc(t *testing.T) {
-	modifyFunc := func(sataController Item) Item {
-		sataController.Caption = "SATA Controller"
-		sataController.Description = "SATAController"
-
-		updatedElementNameBuffer := bytes.NewBuffer(nil)
-		updatedElementNameBuffer.WriteString("SATAController")
-		for i := range sataController.ElementName {
-			char := rune(sataController.ElementName[i])
-			if unicode.IsDigit(char) {
-				updatedElementNameBuffer.WriteString(string(char))
-			}
+func TestEditRawOvfPathEditScheme(t *testing.T) {
+	deleteInfo := func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Delete}
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), NewEditScheme().Propose(deleteInfo, "Info"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "Info>") {
+		t.Fatal("Expected an unconstrained func to delete every Info:\n'" + b.String() + "'")
+	}
+
+	scheme := NewPathEditScheme().ProposeUnder(deleteInfo, "Info", "VirtualSystem", "VirtualHardwareSection")
+
+	clone, err := CloneEditScheme(scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	merged, err := MergeEditSchemes(NewEditScheme(), scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Merging and cloning must keep the constraint.
+	for _, s := range []EditScheme{scheme, clone, merged} {
+		b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), s)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		result := b.String()
+		if strings.Contains(result, "<Info>Virtual hardware requirements for a virtual machine</Info>") {
+			t.Fatal("Info inside of the VirtualHardwareSection was not deleted")
+		}
+
+		if strings.Count(result, "Info>") != strings.Count(basicOvfFileContents, "Info>")-2 {
+			t.Fatal("Info outside of the VirtualHardwareSection was modified:\n'" + result + "'")
+		}
+	}
+
+	fns, ok := scheme.ShouldEditObjectAt("Info", Path{"Envelope", "VirtualSystem", "Info"})
+	if ok || len(fns) > 0 {
+		t.Fatal("ShouldEditObjectAt returned funcs for an unexpected parent")
+	}
+
+	fns, ok = scheme.ShouldEditObject("Info")
+	if !ok || len(fns) != 1 {
+		t.Fatal("ShouldEditObject should return funcs regardless of their constraints")
+	}
+}
+
+func TestEditRawOvfIgnoresLookAlikeObjects(t *testing.T) {
+	lookAlikes := `        <Item>
+          <rasd:InstanceID>1</rasd:InstanceID>
+          <rasd:ResourceType>3</rasd:ResourceType>
+        </Item>
+        <Disk ovf:diskId="vmdisk1"/>
+        <File ovf:id="file1"/>
+      </Hardware>`
+
+	input := strings.Replace(basicOvfFileContents, "      </Hardware>", lookAlikes, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add look-alike objects to vbox:Machine in test data")
+	}
+
+	machineStart := strings.Index(input, "<vbox:Machine")
+	originalMachine := input[machineStart:]
+
+	deleteAll := func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Delete}
+	}
+
+	schemes := map[string]EditScheme{
+		"scheme": NewEditScheme().
+			Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName).
+			Propose(deleteAll, DiskName).
+			Propose(deleteAll, ReferencesFileName).
+			Propose(deleteAll, NetworkName),
+		"edit-options": EditOptions{
+			OnHardwareItems: []OnHardwareItemFunc{
+				func(item Item) (Item, EditAction) { return item, Delete },
+			},
+			OnNetworks: []OnNetworkFunc{
+				func(network Network) (Network, EditAction) { return network, Delete },
+			},
+		}.EditScheme(),
+	}
+
+	for name, scheme := range schemes {
+		b, err := EditRawOvf(strings.NewReader(input), scheme)
+		if err != nil {
+			t.Fatalf("%s - %s", name, err.Error())
+		}
+
+		result := b.String()
+		i := strings.Index(result, "<vbox:Machine")
+		if i < 0 || result[i:] != originalMachine {
+			t.Fatalf("%s - objects inside of vbox:Machine were modified:\n'%s'", name, result)
 		}
-		sataController.ElementName = updatedElementNameBuffer.String()
 
-		sataController.ResourceSubType = "vmware.sata.ahci"
+		parsed, err := ToOvf(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatalf("%s - %s", name, err.Error())
+		}
 
-		return sataController
+		if len(parsed.Envelope.NetworkSection.Networks) != 0 {
+			t.Fatalf("%s - Network in the NetworkSection was not deleted", name)
+		}
 	}
+}
 
-	options := NewEditScheme().Propose(ModifyHardwareItemsOfResourceTypeFunc(OtherStorageDeviceResourceType, modifyFunc),
-		VirtualHardwareItemName)
+func TestEditRawOvfQualifiedObjectName(t *testing.T) {
+	deleteAll := func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Delete}
+	}
 
-	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), options)
+	machine := QualifiedObjectName(VboxNamespace, "Machine")
+	if machine.Namespace() != VboxNamespace || machine.Local() != "Machine" {
+		t.Fatalf("Got unexpected namespace '%s' and local name '%s'", machine.Namespace(), machine.Local())
+	}
+
+	if VboxMachineName.Namespace() != "" || VboxMachineName.Local() != "Machine" {
+		t.Fatal("An unqualified ObjectName should not have a namespace")
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(deleteAll, QualifiedObjectName("http://example.com", "Machine")))
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	expected := `<?xml version="1.0"?>
-<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
-  <References>
-    <File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
-  </References>
-  <DiskSection>
-    <Info>List of the virtual disks used in the package</Info>
-    <Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>
-  </DiskSection>
-  <NetworkSection>
-    <Info>Logical networks used in the package</Info>
-    <Network ovf:name="NAT">
-      <Description>Logical network used by this appliance.</Description>
-    </Network>
-  </NetworkSection>
-  <VirtualSystem ovf:id="centos7">
-    <Info>A virtual machine</Info>
-    <OperatingSystemSection ovf:id="80">
-      <Info>The kind of installed guest operating system</Info>
-      <Description>RedHat_64</Description>
-      <vbox:OSType ovf:required="false">RedHat_64</vbox:OSType>
-    </OperatingSystemSection>
-    <VirtualHardwareSection>
-      <Info>Virtual hardware requirements for a virtual machine</Info>
-      <System>
-        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
-        <vssd:InstanceID>0</vssd:InstanceID>
-        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
-        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
-      </System>
+	if b.String() != basicOvfFileContents {
+		t.Fatal("An object in another namespace was modified")
+	}
+
+	// The prefix of the element does not matter.
+	input := strings.Replace(basicOvfFileContents, "<vbox:Machine ", `<vm:Machine xmlns:vm="`+VboxNamespace+`" `, 1)
+	input = strings.Replace(input, "</vbox:Machine>", "</vm:Machine>", 1)
+
+	for _, in := range []string{basicOvfFileContents, input} {
+		b, err = EditRawOvf(strings.NewReader(in), NewEditScheme().Propose(deleteAll, machine))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if strings.Contains(b.String(), ":Machine") {
+			t.Fatal("The qualified object was not deleted:\n'" + b.String() + "'")
+		}
+	}
+}
+
+func TestEditRawOvfWithConfigHardwareSection(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "    </VirtualHardwareSection>\n", `    </VirtualHardwareSection>
+    <VirtualHardwareSection ovf:id="vmware">
+      <Info>Virtual hardware requirements for VMWare</Info>
       <Item>
         <rasd:Caption>1 virtual CPU</rasd:Caption>
         <rasd:Description>Number of virtual CPUs</rasd:Description>
@@ -907,124 +681,226 @@ func TestEditRawOvfModifyHardwareItemsOfResourceTypeFunc(t *testing.T) {
         <rasd:ResourceType>3</rasd:ResourceType>
         <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
       </Item>
-      <Item>
-        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
-        <rasd:Caption>512 MB of memory</rasd:Caption>
-        <rasd:Description>Memory Size</rasd:Description>
-        <rasd:ElementName>512 MB of memory</rasd:ElementName>
-        <rasd:InstanceID>2</rasd:InstanceID>
-        <rasd:ResourceType>4</rasd:ResourceType>
-        <rasd:VirtualQuantity>512</rasd:VirtualQuantity>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>ideController0</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController0</rasd:ElementName>
-        <rasd:InstanceID>3</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>1</rasd:Address>
-        <rasd:Caption>ideController1</rasd:Caption>
-        <rasd:Description>IDE Controller</rasd:Description>
-        <rasd:ElementName>ideController1</rasd:ElementName>
-        <rasd:InstanceID>4</rasd:InstanceID>
-        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
-        <rasd:ResourceType>5</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:Address>0</rasd:Address>
-        <rasd:Caption>SATA Controller</rasd:Caption>
-        <rasd:Description>SATAController</rasd:Description>
-        <rasd:ElementName>SATAController0</rasd:ElementName>
-        <rasd:InstanceID>5</rasd:InstanceID>
-        <rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>
+    </VirtualHardwareSection>
+`, 1)
+
+	matches := []HardwareSectionMatch{
+		{Id: "vmware"},
+		{Index: 1},
+	}
+
+	for _, match := range matches {
+		match := match
+
+		b, err := EditRawOvfWithConfig(strings.NewReader(input),
+			NewEditScheme().Propose(DeleteHardwareItemByInstanceIDFunc("1"), VirtualHardwareItemName),
+			EditConfig{HardwareSection: &match})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		parsed, err := ToOvf(b)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		sections := parsed.Envelope.VirtualSystem.VirtualHardwareSections
+		if len(sections) != 2 {
+			t.Fatalf("Expected 2 sections, got %d", len(sections))
+		}
+
+		if len(sections[1].Items) != 0 {
+			t.Fatalf("Item was not deleted from the matching section - %+v", match)
+		}
+
+		if sections[0].Items[0].InstanceID != "1" {
+			t.Fatalf("Item was deleted from a section that does not match - %+v", match)
+		}
+	}
+}
+
+func TestEditRawOvfWithConfigRequiredSections(t *testing.T) {
+	deleteFunc := func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Delete}
+	}
+
+	_, err := EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(deleteFunc, "NetworkSection"))
+	if !errors.Is(err, ErrRequiredSection) {
+		t.Fatalf("Expected ErrRequiredSection, got - %v", err)
+	}
+
+	var warnings []string
+	b, err := EditRawOvfWithConfig(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(deleteFunc, "NetworkSection"),
+		EditConfig{
+			AllowRequiredSectionDeletes: true,
+			OnWarning: func(warning string) {
+				warnings = append(warnings, warning)
+			},
+		})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "NetworkSection") || len(warnings) != 1 {
+		t.Fatal("Expected the section to be deleted with a warning")
+	}
+
+	// The vbox:Machine is marked as optional.
+	warnings = nil
+	b, err = EditRawOvfWithConfig(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(deleteFunc, "Machine"),
+		EditConfig{
+			OnWarning: func(warning string) {
+				warnings = append(warnings, warning)
+			},
+		})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "vbox:Machine") || len(warnings) != 0 {
+		t.Fatal("Expected the optional section to be deleted without a warning")
+	}
+}
+
+func TestAddHardwareItemFunc(t *testing.T) {
+	item := Item{
+		Caption:         "sataController1",
+		ElementName:     "sataController1",
+		InstanceID:      "9",
+		ResourceSubType: "AHCI",
+		ResourceType:    SataControllerResourceType,
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(AddHardwareItemFunc(item), VirtualHardwareSectionName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `      <Item>
+        <rasd:Caption>sataController1</rasd:Caption>
+        <rasd:ElementName>sataController1</rasd:ElementName>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
         <rasd:ResourceType>20</rasd:ResourceType>
       </Item>
-      <Item>
-        <rasd:AddressOnParent>3</rasd:AddressOnParent>
-        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
-        <rasd:Caption>sound</rasd:Caption>
-        <rasd:Description>Sound Card</rasd:Description>
-        <rasd:ElementName>sound</rasd:ElementName>
-        <rasd:InstanceID>6</rasd:InstanceID>
-        <rasd:ResourceSubType>ensoniq1371</rasd:ResourceSubType>
-        <rasd:ResourceType>35</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AddressOnParent>0</rasd:AddressOnParent>
-        <rasd:Caption>disk1</rasd:Caption>
-        <rasd:Description>Disk Image</rasd:Description>
-        <rasd:ElementName>disk1</rasd:ElementName>
-        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
-        <rasd:InstanceID>7</rasd:InstanceID>
-        <rasd:Parent>5</rasd:Parent>
-        <rasd:ResourceType>17</rasd:ResourceType>
-      </Item>
-      <Item>
-        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
-        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
-        <rasd:Connection>NAT</rasd:Connection>
-        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
-        <rasd:InstanceID>8</rasd:InstanceID>
-        <rasd:ResourceType>10</rasd:ResourceType>
-      </Item>
-    </VirtualHardwareSection>
-    <vbox:Machine ovf:required="false" version="1.16-macosx" uuid="{6edb492e-28eb-40d0-8b2c-f76402335ef0}" name="centos7" OSType="RedHat_64" snapshotFolder="Snapshots" lastStateChange="2018-11-07T14:51:53Z">
-      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
-      <ExtraData>
-        <ExtraDataItem name="GUI/LastNormalWindowPosition" value="400,182,720,421"/>
-      </ExtraData>
-      <Hardware>
-        <CPU>
-          <PAE enabled="true"/>
-          <LongMode enabled="true"/>
-          <X2APIC enabled="true"/>
-          <HardwareVirtExLargePages enabled="true"/>
-        </CPU>
-        <Memory RAMSize="512"/>
-        <Boot>
-          <Order position="1" device="HardDisk"/>
-          <Order position="2" device="DVD"/>
-          <Order position="3" device="None"/>
-          <Order position="4" device="None"/>
-        </Boot>
-        <RemoteDisplay enabled="true">
-          <VRDEProperties>
-            <Property name="TCP/Address" value="127.0.0.1"/>
-            <Property name="TCP/Ports" value="5955"/>
-          </VRDEProperties>
-        </RemoteDisplay>
-        <BIOS>
-          <IOAPIC enabled="true"/>
-        </BIOS>
-        <Network>
-          <Adapter slot="0" enabled="true" MACAddress="0800276C83FA" type="virtio">
-            <NAT/>
-          </Adapter>
-        </Network>
-        <AudioAdapter driver="CoreAudio" enabled="true" enabledIn="false" enabledOut="false"/>
-        <GuestProperties>
-          <GuestProperty name="/VirtualBox/HostInfo/GUI/LanguageID" value="en_US" timestamp="1541602313081497000" flags=""/>
-        </GuestProperties>
-      </Hardware>
-      <StorageControllers>
-        <StorageController name="IDE Controller" type="PIIX4" PortCount="2" useHostIOCache="true" Bootable="true"/>
-        <StorageController name="SATA Controller" type="AHCI" PortCount="1" useHostIOCache="false" Bootable="true" IDE0MasterEmulationPort="0" IDE0SlaveEmulationPort="1" IDE1MasterEmulationPort="2" IDE1SlaveEmulationPort="3">
-          <AttachedDevice type="HardDisk" hotpluggable="false" port="0" device="0">
-            <Image uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}"/>
-          </AttachedDevice>
-        </StorageController>
-      </StorageControllers>
-    </vbox:Machine>
-  </VirtualSystem>
-</Envelope>
-`
+    </VirtualHardwareSection>`
 
-	result := b.String()
-	if result != expected {
-		t.Fatal("Did not get expected result:\n'" + result + "'")
+	if !strings.Contains(b.String(), expected) {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+
+	o, err := ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	items := o.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	added := items[len(items)-1]
+	added.XMLName = item.XMLName
+	if added != item {
+		t.Fatalf("Got unexpected item - %+v", items[len(items)-1])
+	}
+}
+
+func TestEditRawOvfByteOrderMark(t *testing.T) {
+	input := xmlutil.ByteOrderMark + basicOvfFileContents
+	editScheme := NewEditScheme().Propose(SetVirtualSystemTypeFunc("vmx-10"), VirtualHardwareSystemName)
+
+	b, err := EditRawOvf(strings.NewReader(input), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.HasPrefix(b.String(), xmlutil.ByteOrderMark) {
+		t.Fatal("Expected the byte order mark to be removed")
+	}
+
+	if !strings.Contains(b.String(), "vmx-10") {
+		t.Fatal("Expected the System to be edited:\n" + b.String())
+	}
+
+	b, err = EditRawOvfWithConfig(strings.NewReader(input), editScheme, EditConfig{KeepByteOrderMark: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasPrefix(b.String(), xmlutil.ByteOrderMark+"<?xml") {
+		t.Fatal("Expected the byte order mark to be kept")
+	}
+}
+
+func TestEditRawOvfRootOnDeclarationLine(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "?>\n<Envelope", "?><Envelope", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to move the Envelope to the declaration's line")
+	}
+
+	b, err := EditRawOvf(strings.NewReader(input), NewEditScheme().
+		Propose(DeclareNamespaceFunc(VmwPrefix, VmwNamespace), EnvelopeName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected, err := EditRawOvf(strings.NewReader(basicOvfFileContents), NewEditScheme().
+		Propose(DeclareNamespaceFunc(VmwPrefix, VmwNamespace), EnvelopeName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != expected.String() {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
+
+func TestEditRawOvfReplaceKeepsCimElements(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:ResourceType>10</rasd:ResourceType>",
+		"<rasd:PoolID>pool1</rasd:PoolID>\n        <rasd:ResourceType>10</rasd:ResourceType>\n"+
+			"        <rasd:VirtualQuantityUnits>count</rasd:VirtualQuantityUnits>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add elements to the test input")
+	}
+
+	var warnings []string
+	b, err := EditRawOvfWithConfig(strings.NewReader(input), NewEditScheme().
+		Propose(SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"), VirtualHardwareItemName),
+		EditConfig{OnWarning: func(warning string) { warnings = append(warnings, warning) }})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, expected := range []string{"<rasd:PoolID>pool1</rasd:PoolID>",
+		"<rasd:VirtualQuantityUnits>count</rasd:VirtualQuantityUnits>", "VmxNet3"} {
+		if !strings.Contains(b.String(), expected) {
+			t.Fatal("Expected the output to contain '" + expected + "':\n" + b.String())
+		}
+	}
+
+	if len(warnings) > 0 {
+		t.Fatalf("Got unexpected warnings - %v", warnings)
+	}
+}
+
+func TestEditRawOvfReplaceWarnsOfDroppedElements(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:Connection>NAT</rasd:Connection>",
+		"<rasd:Connection>NAT</rasd:Connection>\n        <rasd:Connection>Bridged</rasd:Connection>\n"+
+			`        <vmw:Config ovf:required="false" vmw:key="wakeOnLanEnabled" vmw:value="false"/>`, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add elements to the test input")
+	}
+
+	var warnings []string
+	_, err := EditRawOvfWithConfig(strings.NewReader(input), NewEditScheme().
+		Propose(SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"), VirtualHardwareItemName),
+		EditConfig{OnWarning: func(warning string) { warnings = append(warnings, warning) }})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'rasd:Connection', 'vmw:Config'") {
+		t.Fatalf("Got unexpected warnings - %v", warnings)
 	}
 }
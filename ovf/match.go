@@ -0,0 +1,127 @@
+package ovf
+
+import (
+	"encoding/xml"
+	"strings"
+	"unicode"
+)
+
+// ItemMatch describes the OVF Items that an edit applies to. VirtualBox
+// localizes the element names and captions of Items in some locales, so
+// an ItemMatch can fall back to comparing the Description and the
+// ResourceType, which are not localized.
+//
+// An Item matches if any of the non-empty criteria match.
+type ItemMatch struct {
+	// NamePrefix is compared to the beginning of the Item's element
+	// name and caption, ignoring case and whitespace (e.g.,
+	// 'ideController' matches 'IDEController0').
+	NamePrefix string
+
+	// Description is compared to the Item's description, ignoring
+	// case and whitespace (e.g., 'IDE Controller').
+	Description string
+
+	// ResourceType is compared to the Item's resource type (e.g.,
+	// IdeControllerResourceType).
+	ResourceType string
+}
+
+// Matches returns true if the Item matches.
+func (o ItemMatch) Matches(item Item) bool {
+	if len(o.NamePrefix) > 0 {
+		prefix := normalizeName(o.NamePrefix)
+
+		if strings.HasPrefix(normalizeName(item.ElementName), prefix) ||
+			strings.HasPrefix(normalizeName(item.Caption), prefix) {
+			return true
+		}
+	}
+
+	if len(o.Description) > 0 && normalizeName(item.Description) == normalizeName(o.Description) {
+		return true
+	}
+
+	if len(o.ResourceType) > 0 && item.ResourceType == o.ResourceType {
+		return true
+	}
+
+	return false
+}
+
+// normalizeName returns the lower case form of a name without whitespace.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// DeleteHardwareItemsMatchFunc returns an EditObjectFunc that deletes OVF
+// Items that match the ItemMatch. If the specified limit is less than 0,
+// then the resulting function will have no limit.
+func DeleteHardwareItemsMatchFunc(match ItemMatch, limit int) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok || limit == 0 || !match.Matches(o) {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		limit = limit - 1
+
+		return EditObjectResult{Action: Delete}
+	}
+}
+
+// ModifyHardwareItemsMatchFunc returns an EditObjectFunc that modifies
+// OVF Items that match the ItemMatch.
+func ModifyHardwareItemsMatchFunc(match ItemMatch, modifyFunc func(i Item) Item) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok || !match.Matches(o) {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		newItem := modifyFunc(o)
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &newItem,
+		}
+	}
+}
+
+// HardwareSectionMatch describes the VirtualHardwareSection that an edit
+// applies to (see EditConfig.HardwareSection).
+type HardwareSectionMatch struct {
+	// Id is compared to the section's 'ovf:id' attribute.
+	Id string
+
+	// Index is the 0-based position of the section within its
+	// VirtualSystem. It is only used if Id is empty.
+	Index int
+}
+
+// matches returns true if the VirtualHardwareSection that starts with the
+// specified element, and is at the specified index, matches. A nil
+// *HardwareSectionMatch matches every section.
+func (o *HardwareSectionMatch) matches(start *xml.StartElement, index int) bool {
+	if o == nil {
+		return true
+	}
+
+	if len(o.Id) == 0 {
+		return o.Index == index
+	}
+
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "id" {
+			return attr.Value == o.Id
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestItemMatchMatches(t *testing.T) {
+	match := ItemMatch{
+		NamePrefix:   "ideController",
+		Description:  "IDE Controller",
+		ResourceType: IdeControllerResourceType,
+	}
+
+	tests := []struct {
+		item     Item
+		expected bool
+	}{
+		{item: Item{ElementName: "ideController0"}, expected: true},
+		{item: Item{ElementName: "IDEController1"}, expected: true},
+		{item: Item{Caption: "IDE controller 0"}, expected: true},
+		{item: Item{ElementName: "Contrôleur IDE", Description: "ide controller"}, expected: true},
+		{item: Item{ElementName: "Contrôleur IDE", ResourceType: IdeControllerResourceType}, expected: true},
+		{item: Item{ElementName: "sataController0", Description: "SATA Controller", ResourceType: SataControllerResourceType}, expected: false},
+	}
+
+	for _, test := range tests {
+		if match.Matches(test.item) != test.expected {
+			t.Fatal("Got unexpected match result for Item '" + test.item.ElementName + "'")
+		}
+	}
+
+	if (ItemMatch{}).Matches(Item{ElementName: "ideController0"}) {
+		t.Fatal("An empty ItemMatch should not match anything")
+	}
+}
+
+func TestEditRawOvfDeleteHardwareItemsMatchFunc(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:ElementName>ideController0</rasd:ElementName>",
+		"<rasd:ElementName>Contrôleur IDE 0</rasd:ElementName>", 1)
+
+	f := DeleteHardwareItemsMatchFunc(ItemMatch{
+		NamePrefix:   "ideController",
+		ResourceType: IdeControllerResourceType,
+	}, -1)
+
+	b, err := EditRawOvf(strings.NewReader(input), NewEditScheme().Propose(f, VirtualHardwareItemName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == IdeControllerResourceType {
+			t.Fatal("IDE controller '" + item.ElementName + "' was not deleted")
+		}
+	}
+
+	if !strings.Contains(b.String(), "<rasd:ElementName>sataController0</rasd:ElementName>") {
+		t.Fatal("Other Items were deleted:\n'" + b.String() + "'")
+	}
+}
+
+func TestEditRawOvfModifyHardwareItemsMatchFunc(t *testing.T) {
+	f := ModifyHardwareItemsMatchFunc(ItemMatch{NamePrefix: "SATACONTROLLER"}, func(i Item) Item {
+		i.ResourceSubType = "vmware.sata.ahci"
+		return i
+	})
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), NewEditScheme().Propose(f, VirtualHardwareItemName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), "<rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>") {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
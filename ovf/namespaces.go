@@ -0,0 +1,194 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// RasdNamespace is the XML namespace of the resource allocation
+	// settings of an Item (e.g., 'rasd:ResourceType').
+	RasdNamespace = "http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData"
+
+	// VssdNamespace is the XML namespace of the virtual system
+	// settings of a System (e.g., 'vssd:VirtualSystemType').
+	VssdNamespace = "http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData"
+
+	// XsiNamespace is the XML Schema instance namespace.
+	XsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// conventionalPrefixes are the namespace prefixes that OVF tools use for
+// well-known namespaces.
+var conventionalPrefixes = map[string]string{
+	"ovf":     OvfNamespace,
+	"rasd":    RasdNamespace,
+	"vssd":    VssdNamespace,
+	"xsi":     XsiNamespace,
+	VmwPrefix: VmwNamespace,
+	"vbox":    VboxNamespace,
+}
+
+// ConventionalNamespace returns the namespace that a prefix is
+// conventionally bound to by OVF tools (e.g., 'rasd' is bound to
+// RasdNamespace), and false if the prefix is not well-known.
+func ConventionalNamespace(prefix string) (string, bool) {
+	namespace, ok := conventionalPrefixes[prefix]
+	return namespace, ok
+}
+
+// UndeclaredPrefixes returns the namespace prefixes that are used by the
+// elements and attributes of an OVF, but are not declared by an xmlns
+// attribute. The prefixes are sorted. Go's XML decoder accepts such
+// prefixes, but stricter parsers (e.g., vCenter's) reject the OVF.
+func UndeclaredPrefixes(raw []byte) ([]string, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	var scopes []map[string]string
+	undeclared := make(map[string]bool)
+
+	check := func(prefix string) {
+		if len(prefix) > 0 && prefix != "xmlns" && len(resolveNamespace(scopes, prefix)) == 0 {
+			undeclared[prefix] = true
+		}
+	}
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			for _, attr := range v.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+
+			check(v.Name.Space)
+			for _, attr := range v.Attr {
+				check(attr.Name.Space)
+			}
+		case xml.EndElement:
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+
+	var prefixes []string
+	for prefix := range undeclared {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	return prefixes, nil
+}
+
+// envelopeChildRank returns the position of a child of the Envelope in
+// the order required by the OVF specification: the References, then the
+// sections, then the content (a VirtualSystem or a
+// VirtualSystemCollection), and then the Strings.
+func envelopeChildRank(local string) int {
+	switch local {
+	case "References":
+		return 0
+	case "VirtualSystem", "VirtualSystemCollection":
+		return 2
+	case "Strings":
+		return 3
+	}
+
+	return 1
+}
+
+// SortEnvelopeChildren reorders the children of an OVF's Envelope into
+// the order required by the OVF specification, which some tools do not
+// follow (e.g., a NetworkSection after the VirtualSystem). Children that
+// are already in order keep their relative order. Each child is moved
+// along with the whitespace and comments that precede it, and is
+// otherwise written exactly as it appeared. The OVF is returned as it
+// is, along with false, if its children are already in order.
+func SortEnvelopeChildren(raw []byte) ([]byte, bool, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	type child struct {
+		rank  int
+		start int64
+		end   int64
+	}
+
+	var children []child
+	var local string
+	var segmentStart int64
+	depth := 0
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 1:
+				// The first child's segment starts after the
+				// Envelope's start tag.
+				segmentStart = d.InputOffset()
+			case 2:
+				local = v.Name.Local
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 {
+				end := d.InputOffset()
+				children = append(children, child{
+					rank:  envelopeChildRank(local),
+					start: segmentStart,
+					end:   end,
+				})
+				segmentStart = end
+			}
+		}
+	}
+
+	sorted := sort.SliceIsSorted(children, func(i int, j int) bool {
+		return children[i].rank < children[j].rank
+	})
+	if sorted {
+		return raw, false, nil
+	}
+
+	ordered := append([]child(nil), children...)
+	sort.SliceStable(ordered, func(i int, j int) bool {
+		return ordered[i].rank < ordered[j].rank
+	})
+
+	first := children[0].start
+	last := children[len(children)-1].end
+
+	out := bytes.NewBuffer(nil)
+	out.Write(raw[:first])
+	for _, c := range ordered {
+		out.Write(raw[c.start:c.end])
+	}
+	out.Write(raw[last:])
+
+	return out.Bytes(), true, nil
+}
@@ -0,0 +1,92 @@
+package ovf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUndeclaredPrefixes(t *testing.T) {
+	expected := map[string][]string{
+		"proxmox.ovf":      {"rasd", "vssd"},
+		"nutanix-ahv.ovf":  {"rasd"},
+		"virt-manager.ovf": nil,
+		"virtualbox-7.ovf": nil,
+	}
+
+	for name, prefixes := range expected {
+		raw, err := ioutil.ReadFile(filepath.Join(fixturesDir, name))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		result, err := UndeclaredPrefixes(raw)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !reflect.DeepEqual(result, prefixes) {
+			t.Fatalf("%s: expected undeclared prefixes %q - got %q", name, prefixes, result)
+		}
+	}
+
+	// A prefix declared by an ancestor is in scope, but one declared
+	// by a sibling is not.
+	raw := []byte(`<Envelope xmlns:a="urn:a"><b:X xmlns:b="urn:b"/><a:Y b:z="1"/></Envelope>`)
+
+	result, err := UndeclaredPrefixes(raw)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual(result, []string{"b"}) {
+		t.Fatalf("expected only 'b' to be undeclared - got %q", result)
+	}
+}
+
+func TestSortEnvelopeChildren(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join(fixturesDir, "nutanix-ahv.ovf"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	sorted, changed, err := SortEnvelopeChildren(raw)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !changed {
+		t.Fatal("expected the children to be reordered")
+	}
+
+	if len(sorted) != len(raw) {
+		t.Fatalf("expected the OVF to keep its length of %d - got %d", len(raw), len(sorted))
+	}
+
+	var order []int
+	for _, name := range []string{"<References>", "<DiskSection>", "<NetworkSection>", "<VirtualSystem "} {
+		order = append(order, bytes.Index(sorted, []byte(name)))
+	}
+
+	for i := 1; i < len(order); i++ {
+		if order[i-1] < 0 || order[i-1] > order[i] {
+			t.Fatalf("children are not in order:\n%s", sorted)
+		}
+	}
+
+	if !strings.HasSuffix(string(sorted), "  </VirtualSystem>\n</Envelope>\n") {
+		t.Fatalf("expected the end of the OVF to be unchanged - got:\n%s", sorted)
+	}
+
+	again, changed, err := SortEnvelopeChildren(sorted)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if changed || !bytes.Equal(again, sorted) {
+		t.Fatal("expected sorted children to be left as they are")
+	}
+}
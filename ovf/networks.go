@@ -0,0 +1,231 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// SetNetworkConnectionFunc returns an EditObjectFunc that renames the
+// NetworkSection Network with the specified name, and connects the Items
+// that are connected to it (i.e., whose Connection is the name) to the
+// renamed Network (e.g., to connect the adapters of a 'NAT' network to a
+// vSphere port group named 'VM Network'). It must be proposed for
+// NetworkName, VirtualHardwareItemName, and EthernetPortItemName, so that
+// the Network and its connections remain consistent.
+func SetNetworkConnectionFunc(oldName string, newName string) EditObjectFunc {
+	renameNetwork := networkEditFunc(func(network Network) (Network, EditAction) {
+		if network.Name != oldName {
+			return network, NoOp
+		}
+
+		network.Name = newName
+
+		return network, Replace
+	})
+
+	return func(i interface{}) EditObjectResult {
+		if raw, ok := i.(*RawObject); ok {
+			if _, isNetwork := raw.Attr("name"); isNetwork {
+				return renameNetwork(i)
+			}
+
+			return setRawConnection(raw, oldName, newName)
+		}
+
+		item, ok := i.(Item)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		if item.Connection != oldName {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		item.Connection = newName
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &item,
+		}
+	}
+}
+
+// setRawConnection connects an EthernetPortItem that is connected to the
+// Network named oldName to the Network named newName.
+func setRawConnection(raw *RawObject, oldName string, newName string) EditObjectResult {
+	if rawConnection(raw) != oldName {
+		return EditObjectResult{Action: NoOp}
+	}
+
+	err := raw.SetChildText("Connection", newName)
+	if err != nil {
+		return EditObjectResult{Action: NoOp}
+	}
+
+	return EditObjectResult{
+		Action: Replace,
+		Object: raw,
+	}
+}
+
+// rawConnection returns the Connection of an EthernetPortItem, or an
+// empty string if it does not have one.
+func rawConnection(raw *RawObject) string {
+	var port struct {
+		Connection string `xml:"Connection"`
+	}
+
+	err := xmlutil.Unmarshal(raw.Data().Bytes(), &port)
+	if err != nil {
+		return ""
+	}
+
+	return port.Connection
+}
+
+// DeleteNetworkFunc returns an EditObjectFunc that deletes the
+// NetworkSection Network with the specified name. Items connected to the
+// Network are not modified.
+func DeleteNetworkFunc(name string) EditObjectFunc {
+	return networkEditFunc(func(network Network) (Network, EditAction) {
+		if network.Name != name {
+			return network, NoOp
+		}
+
+		return network, Delete
+	})
+}
+
+// DeleteEthernetAdaptersOnNetworkFunc returns an EditObjectFunc that
+// deletes the Items connected to the Network with the specified name
+// (e.g., the adapters of a 'HostOnly' network that does not exist on the
+// target), along with the Network itself, which is no longer referenced.
+// It must be proposed for NetworkName, VirtualHardwareItemName, and
+// EthernetPortItemName.
+func DeleteEthernetAdaptersOnNetworkFunc(network string) EditObjectFunc {
+	deleteNetwork := DeleteNetworkFunc(network)
+
+	return func(i interface{}) EditObjectResult {
+		if raw, ok := i.(*RawObject); ok {
+			if _, isNetwork := raw.Attr("name"); isNetwork {
+				return deleteNetwork(i)
+			}
+
+			if rawConnection(raw) != network {
+				return EditObjectResult{Action: NoOp}
+			}
+
+			return EditObjectResult{Action: Delete}
+		}
+
+		item, ok := i.(Item)
+		if !ok || item.Connection != network {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{Action: Delete}
+	}
+}
+
+// AddEthernetAdapterFunc returns an EditObjectFunc that adds an Ethernet
+// adapter Item of the specified ResourceSubType (e.g., 'VmxNet3')
+// connected to the Network with the specified name (e.g., to add a
+// management interface to an appliance). The Network is added to the
+// NetworkSection if it does not exist. It must be proposed for both
+// NetworkSectionName and VirtualHardwareSectionName. The adapter's
+// InstanceID is one greater than the largest numeric InstanceID in the
+// VirtualHardwareSection, so that the func can be proposed more than once.
+func AddEthernetAdapterFunc(network string, subType string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		edited := false
+		var err error
+		switch o.Start.Name.Local {
+		case string(NetworkSectionName):
+			edited, err = addNetwork(o, network)
+		case string(VirtualHardwareSectionName):
+			edited, err = true, addEthernetAdapter(o, network, subType)
+		}
+
+		if err != nil || !edited {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// addNetwork adds a Network to a NetworkSection, unless it already has a
+// Network with the name. It returns true if the Network was added.
+func addNetwork(o *RawObject, name string) (bool, error) {
+	var section NetworkSection
+	err := xmlutil.Unmarshal(o.Data().Bytes(), &section)
+	if err != nil {
+		return false, err
+	}
+
+	for _, network := range section.Networks {
+		if network.Name == name {
+			return false, nil
+		}
+	}
+
+	b := bytes.NewBuffer(nil)
+	b.WriteString(`<Network ovf:name="`)
+	xml.EscapeText(b, []byte(name))
+	b.WriteString("\">\n")
+	b.WriteString("  <Description>Logical network used by this appliance.</Description>\n")
+	b.WriteString("</Network>")
+
+	err = o.InsertChild(b.Bytes())
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addEthernetAdapter adds an Ethernet adapter Item to a
+// VirtualHardwareSection.
+func addEthernetAdapter(o *RawObject, network string, subType string) error {
+	var section VirtualHardwareSection
+	err := xmlutil.Unmarshal(o.Data().Bytes(), &section)
+	if err != nil {
+		return err
+	}
+
+	maxInstanceID := 0
+	for _, item := range section.Items {
+		instanceID, err := strconv.Atoi(item.InstanceID)
+		if err == nil && instanceID > maxInstanceID {
+			maxInstanceID = instanceID
+		}
+	}
+
+	item := Item{
+		AutomaticAllocation: true,
+		Caption:             "Ethernet adapter on '" + network + "'",
+		Connection:          network,
+		ElementName:         "Ethernet adapter on '" + network + "'",
+		InstanceID:          strconv.Itoa(maxInstanceID + 1),
+		ResourceSubType:     subType,
+		ResourceType:        EthernetAdapterResourceType,
+	}
+
+	raw, err := xml.MarshalIndent(item.Marshallable(), "", o.RelativeBodyPrefix())
+	if err != nil {
+		return err
+	}
+
+	return o.InsertChild(raw)
+}
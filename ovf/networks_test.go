@@ -0,0 +1,195 @@
+package ovf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetNetworkConnectionFunc(t *testing.T) {
+	f := SetNetworkConnectionFunc("NAT", "VM Network")
+
+	editScheme := NewEditScheme().
+		Propose(f, NetworkName).
+		Propose(f, VirtualHardwareItemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	o, err := ToOvf(b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	networks := o.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "VM Network" {
+		t.Fatalf("Got unexpected networks - %+v", networks)
+	}
+
+	connected := 0
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		switch item.Connection {
+		case "":
+		case "VM Network":
+			connected = connected + 1
+		default:
+			t.Fatalf("Item '%s' is connected to unexpected network '%s'", item.InstanceID, item.Connection)
+		}
+	}
+
+	if connected == 0 {
+		t.Fatal("No items were connected to the renamed network")
+	}
+}
+
+func TestSetNetworkConnectionFuncEthernetPortItem(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join(fixturesDir, "virtualbox-7.ovf"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	f := SetNetworkConnectionFunc("NAT", "VM Network")
+
+	editScheme := NewEditScheme().
+		Propose(f, NetworkName).
+		Propose(f, EthernetPortItemName)
+
+	b, err := EditRawOvf(strings.NewReader(string(raw)), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	if !strings.Contains(result, "<epasd:Connection>VM Network</epasd:Connection>") ||
+		!strings.Contains(result, `<Network ovf:name="VM Network">`) || strings.Contains(result, "NAT<") {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestDeleteNetworkFunc(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(DeleteNetworkFunc("missing"), NetworkName).
+		Propose(DeleteNetworkFunc("NAT"), NetworkName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	o, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(o.Envelope.NetworkSection.Networks) != 0 {
+		t.Fatalf("Got unexpected networks - %+v", o.Envelope.NetworkSection.Networks)
+	}
+
+	if !strings.Contains(result, "<rasd:Connection>NAT</rasd:Connection>") {
+		t.Fatal("The connection of an item was modified")
+	}
+}
+
+func TestAddEthernetAdapterFunc(t *testing.T) {
+	editScheme := NewEditScheme()
+
+	for _, network := range []string{"NAT", "Management"} {
+		f := AddEthernetAdapterFunc(network, "VmxNet3")
+		editScheme.Propose(f, NetworkSectionName).
+			Propose(f, VirtualHardwareSectionName)
+	}
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	o, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var names []string
+	for _, network := range o.Envelope.NetworkSection.Networks {
+		names = append(names, network.Name)
+	}
+
+	if strings.Join(names, ",") != "NAT,Management" {
+		t.Fatalf("Got unexpected networks %q:\n%s", names, result)
+	}
+
+	var added []string
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == EthernetAdapterResourceType && item.ResourceSubType == "VmxNet3" {
+			added = append(added, item.InstanceID+"="+item.Connection)
+		}
+	}
+
+	if strings.Join(added, ",") != "9=NAT,10=Management" {
+		t.Fatalf("Got unexpected adapters %q:\n%s", added, result)
+	}
+
+	findings, err := Validate(strings.NewReader(result), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+}
+
+func TestDeleteEthernetAdaptersOnNetworkFunc(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:Connection>NAT</rasd:Connection>",
+		"<rasd:Connection>HostOnly</rasd:Connection>", 1)
+	input = strings.Replace(input, `<Network ovf:name="NAT">`, `<Network ovf:name="HostOnly">`, 1)
+
+	f := AddEthernetAdapterFunc("NAT", "E1000")
+	input = editRawOvfString(t, input, NewEditScheme().
+		Propose(f, NetworkSectionName).
+		Propose(f, VirtualHardwareSectionName))
+
+	f = DeleteEthernetAdaptersOnNetworkFunc("HostOnly")
+	result := editRawOvfString(t, input, NewEditScheme().
+		Propose(f, NetworkName).
+		Propose(f, VirtualHardwareItemName).
+		Propose(f, EthernetPortItemName))
+
+	o, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	networks := o.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "NAT" {
+		t.Fatalf("Got unexpected networks - %+v", networks)
+	}
+
+	var connections []string
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if len(item.Connection) > 0 {
+			connections = append(connections, item.Connection)
+		}
+	}
+
+	if strings.Join(connections, ",") != "NAT" {
+		t.Fatalf("Got unexpected connections %q:\n%s", connections, result)
+	}
+}
+
+func editRawOvfString(t *testing.T, input string, editScheme EditScheme) string {
+	b, err := EditRawOvf(strings.NewReader(input), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return b.String()
+}
@@ -1,28 +1,138 @@
 package ovf
 
+//go:generate go run ../internal/cimgen -rasd ../internal/cimgen/schemas/CIM_ResourceAllocationSettingData.xsd -vssd ../internal/cimgen/schemas/CIM_VirtualSystemSettingData.xsd -o cim_generated.go
+
 import (
 	"encoding/xml"
 	"io"
-	"io/ioutil"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
 )
 
+// Hardware Item resource types, as defined by the DMTF
+// CIM_ResourceAllocationSettingData ResourceType property.
 const (
-	CdDriveResourceType            = "15"
-	OtherStorageDeviceResourceType = "20"
+	OtherResourceType               = "1"
+	ComputerSystemResourceType      = "2"
+	ProcessorResourceType           = "3"
+	MemoryResourceType              = "4"
+	IdeControllerResourceType       = "5"
+	ParallelScsiHbaResourceType     = "6"
+	FcHbaResourceType               = "7"
+	IscsiHbaResourceType            = "8"
+	IbHcaResourceType               = "9"
+	EthernetAdapterResourceType     = "10"
+	OtherNetworkAdapterResourceType = "11"
+	IoSlotResourceType              = "12"
+	IoDeviceResourceType            = "13"
+	FloppyDriveResourceType         = "14"
+	CdDriveResourceType             = "15"
+	DvdDriveResourceType            = "16"
+	DiskDriveResourceType           = "17"
+	TapeDriveResourceType           = "18"
+	StorageExtentResourceType       = "19"
+	SerialPortResourceType          = "21"
+	ParallelPortResourceType        = "22"
+	UsbControllerResourceType       = "23"
+	GraphicsControllerResourceType  = "24"
+	Ieee1394ControllerResourceType  = "25"
+	EthernetSwitchPortResourceType  = "30"
+	LogicalDiskResourceType         = "31"
+	StorageVolumeResourceType       = "32"
+	EthernetConnectionResourceType  = "33"
+	SoundCardResourceType           = "35"
+
+	// SataControllerResourceType is the resource type of a SATA
+	// controller. CIM does not define a SATA controller type, so
+	// VirtualBox and VMWare both use the "Other storage device"
+	// type ("20"), and the ResourceSubType identifies the device
+	// (e.g., 'AHCI' for VirtualBox, or 'vmware.sata.ahci' for
+	// VMWare). VMWare NVMe controllers use this type as well, with
+	// the 'vmware.nvme.controller' ResourceSubType.
+	SataControllerResourceType = "20"
+
+	// OtherStorageDeviceResourceType is the CIM "Other storage
+	// device" resource type.
+	//
+	// Deprecated: The name does not reveal that the type is used by
+	// SATA controllers. Use SataControllerResourceType instead.
+	OtherStorageDeviceResourceType = SataControllerResourceType
 )
 
 const (
 	VirtualHardwareSystemName ObjectName = "System"
 	VirtualHardwareItemName   ObjectName = "Item"
+	VboxStorageControllerName ObjectName = "StorageController"
+	VboxMachineName           ObjectName = "Machine"
+	ReferencesFileName        ObjectName = "File"
+	DiskName                  ObjectName = "Disk"
+	NetworkName               ObjectName = "Network"
+
+	// EthernetPortItemName is the name of the OVF 2.0 element that
+	// describes an Ethernet adapter, which is not modeled by this
+	// package (it is provided as a *RawObject).
+	EthernetPortItemName ObjectName = "EthernetPortItem"
+
+	EnvelopeName               ObjectName = "Envelope"
+	VirtualSystemName          ObjectName = "VirtualSystem"
+	VirtualHardwareSectionName ObjectName = "VirtualHardwareSection"
+	NetworkSectionName         ObjectName = "NetworkSection"
 )
 
-// ObjectName represents an OVF object name.
+const (
+	// OvfNamespace is the XML namespace of the OVF envelope.
+	OvfNamespace = "http://schemas.dmtf.org/ovf/envelope/1"
+
+	// VboxNamespace is the XML namespace of VirtualBox's OVF
+	// extensions (e.g., 'vbox:Machine').
+	VboxNamespace = "http://www.virtualbox.org/ovf/machine"
+
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// ObjectName represents an OVF object name. An ObjectName is normally the
+// local name of an element (e.g., 'System'), which matches elements with
+// that name in any XML namespace. A qualified ObjectName, created using
+// QualifiedObjectName, only matches elements in a specific namespace.
 type ObjectName string
 
+// QualifiedObjectName returns an ObjectName that only matches elements
+// with the specified local name in the specified XML namespace (e.g.,
+// the 'Machine' element in VboxNamespace). Namespaces are resolved
+// using the document's xmlns declarations, meaning that the prefix of
+// an element does not matter. The name is written in Clark notation
+// (e.g., '{http://www.virtualbox.org/ovf/machine}Machine').
+func QualifiedObjectName(namespace string, local string) ObjectName {
+	return ObjectName("{" + namespace + "}" + local)
+}
+
 func (o ObjectName) String() string {
 	return string(o)
 }
 
+// Namespace returns the XML namespace of a qualified ObjectName. It
+// returns an empty string if the ObjectName is not qualified.
+func (o ObjectName) Namespace() string {
+	namespace, _ := o.split()
+	return namespace
+}
+
+// Local returns the local name of an ObjectName (e.g., 'Machine').
+func (o ObjectName) Local() string {
+	_, local := o.split()
+	return local
+}
+
+func (o ObjectName) split() (string, string) {
+	end := strings.Index(string(o), "}")
+	if !strings.HasPrefix(string(o), "{") || end < 0 {
+		return "", string(o)
+	}
+
+	return string(o)[1:end], string(o)[end+1:]
+}
+
 // Ovf is the parent that represents a single OVF configuration.
 //
 // TODO: Be advised: Not all fields are currently implemented.
@@ -44,115 +154,338 @@ type Envelope struct {
 	Vssd          string   `xml:"vssd,attr"`
 	Xsi           string   `xml:"xsi,attr"`
 	Vbox          string   `xml:"vbox,attr"`
-	VirtualSystem VirtualSystem
+	Vmw           string   `xml:"vmw,attr"`
+
+	// Attrs contains every attribute of the Envelope, including the
+	// namespace declarations and attributes that do not have a
+	// field, as they appeared in the OVF.
+	Attrs AttrMap `xml:"-"`
+
+	References     References
+	DiskSection    DiskSection
+	NetworkSection NetworkSection
+	VirtualSystem  VirtualSystem
+
+	// Strings are the bundles of localized messages, in the order
+	// they appear.
+	Strings []Strings
+}
+
+func (o *Envelope) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type envelope Envelope
+
+	var v envelope
+
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+
+	*o = Envelope(v)
+	o.Attrs = newAttrMap(start.Attr)
+
+	return nil
+}
+
+// References lists the files that accompany the .ovf (e.g., disk images).
+type References struct {
+	XMLName xml.Name `xml:"References"`
+	Files   []File   `xml:"File"`
+}
+
+type File struct {
+	XMLName xml.Name `xml:"File"`
+	Id      string   `xml:"id,attr"`
+	Href    string   `xml:"href,attr"`
+	Size    string   `xml:"size,attr,omitempty"`
+}
+
+// DiskSection describes the virtual disks used by the virtual machines.
+type DiskSection struct {
+	XMLName xml.Name `xml:"DiskSection"`
+	Disks   []Disk   `xml:"Disk"`
+}
+
+// Disk is a virtual disk. FileRef is the Id of the File containing the
+// disk image, and Capacity is measured in CapacityAllocationUnits (bytes
+// if it is empty).
+type Disk struct {
+	XMLName                 xml.Name `xml:"Disk"`
+	DiskId                  string   `xml:"diskId,attr"`
+	FileRef                 string   `xml:"fileRef,attr"`
+	Capacity                string   `xml:"capacity,attr"`
+	CapacityAllocationUnits string   `xml:"capacityAllocationUnits,attr"`
+	Format                  string   `xml:"format,attr"`
+	PopulatedSize           string   `xml:"populatedSize,attr"`
+
+	// ParentRef is the DiskId of the Disk that this disk is a
+	// differencing disk of (e.g., a snapshot's disk), if any.
+	ParentRef string `xml:"parentRef,attr,omitempty"`
+}
+
+// NetworkSection describes the logical networks used by the virtual
+// machines.
+type NetworkSection struct {
+	XMLName  xml.Name  `xml:"NetworkSection"`
+	Networks []Network `xml:"Network"`
+}
+
+// Network is a logical network that Ethernet adapters connect to.
+type Network struct {
+	XMLName     xml.Name `xml:"Network"`
+	Name        string   `xml:"name,attr"`
+	Description string   `xml:"Description"`
 }
 
 type VirtualSystem struct {
 	XMLName                xml.Name `xml:"VirtualSystem"`
 	Id                     string   `xml:"id,attr"`
-	VirtualHardwareSection VirtualHardwareSection
+	OperatingSystemSection OperatingSystemSection
+
+	// VirtualHardwareSection is the first VirtualHardwareSection.
+	VirtualHardwareSection VirtualHardwareSection `xml:"-"`
+
+	// VirtualHardwareSections contains every VirtualHardwareSection,
+	// in the order they appear. The OVF specification allows a
+	// VirtualSystem to provide several sections (e.g., one for each
+	// virtualization platform), which are told apart by their
+	// 'ovf:id' and their System's VirtualSystemType.
+	VirtualHardwareSections []VirtualHardwareSection `xml:"VirtualHardwareSection"`
+
+	// IpAssignmentSection is VMWare's 'vmw:IpAssignmentSection', if
+	// the VirtualSystem has one.
+	IpAssignmentSection IpAssignmentSection
+
+	// AnnotationSection is the VirtualSystem's description, if it
+	// has one.
+	AnnotationSection AnnotationSection
+
+	Machine VboxMachine
+}
+
+func (o *VirtualSystem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type virtualSystem VirtualSystem
+
+	var v virtualSystem
+
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+
+	*o = VirtualSystem(v)
+
+	if len(o.VirtualHardwareSections) > 0 {
+		o.VirtualHardwareSection = o.VirtualHardwareSections[0]
+	}
+
+	return nil
+}
+
+// HardwareSection returns the VirtualHardwareSection with the specified
+// 'ovf:id'.
+func (o VirtualSystem) HardwareSection(id string) (VirtualHardwareSection, bool) {
+	for _, section := range o.VirtualHardwareSections {
+		if section.Id == id {
+			return section, true
+		}
+	}
+
+	return VirtualHardwareSection{}, false
+}
+
+type OperatingSystemSection struct {
+	XMLName     xml.Name `xml:"OperatingSystemSection"`
+	Id          string   `xml:"id,attr"`
+	Version     string   `xml:"version,attr,omitempty"`
+	OSType      string   `xml:"osType,attr,omitempty"`
+	Info        string   `xml:"Info"`
+	Description string   `xml:"Description"`
+	VboxOSType  string   `xml:"http://www.virtualbox.org/ovf/machine OSType"`
+}
+
+// VboxMachine represents the VirtualBox-specific vbox:Machine section.
+//
+// TODO: Be advised: Only the fields required for conversion
+//  are implemented.
+type VboxMachine struct {
+	XMLName         xml.Name `xml:"http://www.virtualbox.org/ovf/machine Machine"`
+	Name            string   `xml:"name,attr"`
+	OSType          string   `xml:"OSType,attr"`
+	CurrentSnapshot string   `xml:"currentSnapshot,attr"`
+	Hardware        VboxHardware
+	MediaRegistry   VboxMediaRegistry
+	Snapshots       []VboxSnapshot `xml:"Snapshot"`
+}
+
+// VboxSnapshot represents a VirtualBox snapshot. Only the snapshot's
+// identity is modeled.
+type VboxSnapshot struct {
+	XMLName xml.Name `xml:"Snapshot"`
+	Uuid    string   `xml:"uuid,attr"`
+	Name    string   `xml:"name,attr"`
+}
+
+// VboxMediaRegistry lists the disk images known to the VirtualBox machine.
+type VboxMediaRegistry struct {
+	XMLName   xml.Name       `xml:"MediaRegistry"`
+	HardDisks []VboxHardDisk `xml:"HardDisks>HardDisk"`
+}
+
+// VboxHardDisk is a VirtualBox disk image. Children are differencing
+// disks (e.g., created by snapshots) that are based on the image.
+type VboxHardDisk struct {
+	XMLName  xml.Name       `xml:"HardDisk"`
+	Uuid     string         `xml:"uuid,attr"`
+	Location string         `xml:"location,attr"`
+	Children []VboxHardDisk `xml:"HardDisk"`
+}
+
+type VboxHardware struct {
+	XMLName  xml.Name `xml:"Hardware"`
+	Firmware   VboxFirmware
+	SecureBoot VboxSecureBoot
+	Display    VboxDisplay
+	Boot       VboxBoot
+	TPM        VboxTPM
+
+	SharedFolders []VboxSharedFolder `xml:"SharedFolders>SharedFolder"`
+}
+
+// VboxSharedFolder is a directory of the VirtualBox host that is shared
+// with the guest. Writable and AutoMount are 'true' or 'false'.
+type VboxSharedFolder struct {
+	XMLName   xml.Name `xml:"SharedFolder"`
+	Name      string   `xml:"name,attr"`
+	HostPath  string   `xml:"hostPath,attr"`
+	Writable  string   `xml:"writable,attr"`
+	AutoMount string   `xml:"autoMount,attr"`
+}
+
+type VboxFirmware struct {
+	XMLName xml.Name `xml:"Firmware"`
+	Type    string   `xml:"type,attr"`
+}
+
+// VboxSecureBoot represents the VirtualBox EFI Secure Boot setting.
+type VboxSecureBoot struct {
+	XMLName xml.Name `xml:"SecureBoot"`
+	Enabled string   `xml:"enabled,attr"`
+}
+
+// VboxDisplay represents the VirtualBox graphics adapter settings.
+// VRAMSize is in megabytes.
+type VboxDisplay struct {
+	XMLName      xml.Name `xml:"Display"`
+	Controller   string   `xml:"controller,attr"`
+	VRAMSize     string   `xml:"VRAMSize,attr"`
+	MonitorCount string   `xml:"monitorCount,attr"`
+	Accelerate3D string   `xml:"accelerate3D,attr"`
+}
+
+// VboxTPM represents the VirtualBox trusted platform module. Type is one
+// of 'None', 'v1_2', 'v2_0', 'Host', or 'Swtpm'.
+type VboxTPM struct {
+	XMLName xml.Name `xml:"TrustedPlatformModule"`
+	Type    string   `xml:"type,attr"`
+}
+
+// VboxBoot represents the VirtualBox boot order.
+type VboxBoot struct {
+	XMLName xml.Name        `xml:"Boot"`
+	Orders  []VboxBootOrder `xml:"Order"`
+}
+
+// VboxBootOrder is a single device in the VirtualBox boot order. Device
+// is one of 'HardDisk', 'DVD', 'Floppy', 'Network', 'USB', or 'None'.
+type VboxBootOrder struct {
+	XMLName  xml.Name `xml:"Order"`
+	Position string   `xml:"position,attr"`
+	Device   string   `xml:"device,attr"`
 }
 
 type VirtualHardwareSection struct {
 	XMLName xml.Name `xml:"VirtualHardwareSection"`
+	Id      string   `xml:"id,attr,omitempty"`
 	Info    string   `xml:"Info"`
 	System  System
 	Items   []Item `xml:"Item"`
 }
 
-type System struct {
-	XMLName                 xml.Name `xml:"System"`
-	ElementName             string   `xml:"ElementName"`
-	InstanceId              string   `xml:"InstanceID"`
-	VirtualSystemIdentifier string   `xml:"VirtualSystemIdentifier"`
-	VirtualSystemType       string   `xml:"VirtualSystemType"`
+// RawObject represents an OVF object that is not modeled by this package.
+// It is provided to an EditObjectFunc when the targeted ObjectName has no
+// corresponding Go type (e.g., 'StorageController').
+//
+// The object can be modified in place using the embedded
+// xmlutil.EditableRawObject's write-back methods, and then returned as the
+// Object of an EditObjectResult. Its data is written verbatim, preserving
+// the original formatting.
+type RawObject struct {
+	xmlutil.EditableRawObject
+
+	// Start is the object's start element, which provides access
+	// to the object's attributes.
+	Start xml.StartElement
 }
 
-// TODO: Hack for https://github.com/golang/go/issues/9519.
-func (o *System) Marshallable() interface{} {
-	return marshableSystem{
-		ElementName:             o.ElementName,
-		InstanceId:              o.InstanceId,
-		VirtualSystemIdentifier: o.VirtualSystemIdentifier,
-		VirtualSystemType:       o.VirtualSystemType,
+// Attr returns the value of the start element's attribute with the
+// specified local name.
+func (o *RawObject) Attr(localName string) (string, bool) {
+	for _, attr := range o.Start.Attr {
+		if attr.Name.Local == localName {
+			return attr.Value, true
+		}
 	}
+
+	return "", false
 }
 
-// TODO: Hack for https://github.com/golang/go/issues/9519.
-type marshableSystem struct {
-	XMLName                 xml.Name `xml:"System"`
-	ElementName             string   `xml:"vssd:ElementName"`
-	InstanceId              string   `xml:"vssd:InstanceID"`
-	VirtualSystemIdentifier string   `xml:"vssd:VirtualSystemIdentifier"`
-	VirtualSystemType       string   `xml:"vssd:VirtualSystemType"`
-}
-
-type Item struct {
-	XMLName             xml.Name `xml:"Item"`
-	Address             string   `xml:"Address"`
-	AddressOnParent     string   `xml:"AddressOnParent"`
-	AllocationUnits     string   `xml:"AllocationUnits"`
-	AutomaticAllocation bool     `xml:"AutomaticAllocation"`
-	Caption             string   `xml:"Caption"`
-	Description         string   `xml:"Description"`
-	ElementName         string   `xml:"ElementName"`
-	InstanceID          string   `xml:"InstanceID"`
-	Parent              string   `xml:"Parent"`
-	ResourceSubType     string   `xml:"ResourceSubType"`
-	ResourceType        string   `xml:"ResourceType"`
-	VirtualQuantity     string   `xml:"VirtualQuantity"`
-}
-
-// TODO: Hack for https://github.com/golang/go/issues/9519.
-func (o *Item) Marshallable() interface{} {
-	return marshableItem{
-		Address:             o.Address,
-		AddressOnParent:     o.AddressOnParent,
-		AllocationUnits:     o.AllocationUnits,
-		AutomaticAllocation: o.AutomaticAllocation,
-		Caption:             o.Caption,
-		Description:         o.Description,
-		ElementName:         o.ElementName,
-		InstanceID:          o.InstanceID,
-		Parent:              o.Parent,
-		ResourceSubType:     o.ResourceSubType,
-		ResourceType:        o.ResourceType,
-		VirtualQuantity:     o.VirtualQuantity,
-	}
+// Marshallable returns the raw XML data of the object.
+func (o *RawObject) Marshallable() interface{} {
+	return o.Data().Bytes()
 }
 
-// TODO: Hack for https://github.com/golang/go/issues/9519.
-type marshableItem struct {
-	XMLName             xml.Name `xml:"Item"`
-	Address             string   `xml:"rasd:Address,omitempty"`
-	AddressOnParent     string   `xml:"rasd:AddressOnParent,omitempty"`
-	AllocationUnits     string   `xml:"rasd:AllocationUnits,omitempty"`
-	AutomaticAllocation bool     `xml:"rasd:AutomaticAllocation,omitempty"`
-	Caption             string   `xml:"rasd:Caption"`
-	Description         string   `xml:"rasd:Description"`
-	ElementName         string   `xml:"rasd:ElementName"`
-	InstanceID          string   `xml:"rasd:InstanceID"`
-	Parent              string   `xml:"rasd:Parent,omitempty"`
-	ResourceSubType     string   `xml:"rasd:ResourceSubType,omitempty"`
-	ResourceType        string   `xml:"rasd:ResourceType"`
-	VirtualQuantity     string   `xml:"rasd:VirtualQuantity,omitempty"`
+// ToOvfConfig configures how ToOvfWithConfig parses an OVF.
+type ToOvfConfig struct {
+	// Limits bounds the size and complexity of the OVF. A
+	// *LimitError is returned if the OVF exceeds a limit.
+	Limits Limits
+
+	// AllowPartial, when true, causes elements that fail to be
+	// parsed to be skipped rather than failing the entire parse.
+	// The Ovf is returned along with every *ParseError (joined
+	// using errors.Join), which is useful for tools that inspect
+	// damaged OVFs. Malformed XML still stops the parse, in which
+	// case the elements parsed before the malformed data are
+	// returned.
+	AllowPartial bool
 }
 
 // ToOvf produces an Ovf for the data provided by the io.Reader.
 func ToOvf(r io.Reader) (Ovf, error) {
-	raw, err := ioutil.ReadAll(r)
+	return ToOvfWithConfig(r, ToOvfConfig{})
+}
+
+// ToOvfWithConfig produces an Ovf for the data provided by the io.Reader
+// given a ToOvfConfig. A failure to parse an element is reported as a
+// *ParseError containing the element's path and byte offset.
+func ToOvfWithConfig(r io.Reader, config ToOvfConfig) (Ovf, error) {
+	raw, err := readLimited(r, config.Limits)
 	if err != nil {
 		return Ovf{}, err
 	}
 
-	var env Envelope
-
-	err = xml.Unmarshal(raw, &env)
+	err = CheckLimits(raw, config.Limits)
 	if err != nil {
 		return Ovf{}, err
 	}
 
+	env, err := parseOvf(raw, config.AllowPartial)
+	if err != nil && !config.AllowPartial {
+		return Ovf{}, err
+	}
+
 	return Ovf{
 		Envelope: env,
-	}, nil
+	}, err
 }
@@ -171,4 +171,29 @@ func TestToOvf(t *testing.T) {
 	if r.Envelope.VirtualSystem.Id != "centos7" {
 		t.Fatal("Did not get expected virtual system ID -", r.Envelope.VirtualSystem.Id)
 	}
+
+	files := r.Envelope.References.Files
+	if len(files) != 1 || files[0].Id != "file1" || files[0].Href != "centos7-disk001.vmdk" {
+		t.Fatal("Did not get expected file references -", files)
+	}
+
+	disks := r.Envelope.DiskSection.Disks
+	if len(disks) != 1 || disks[0].DiskId != "vmdisk1" || disks[0].FileRef != "file1" || disks[0].Capacity != "68719476736" {
+		t.Fatal("Did not get expected disks -", disks)
+	}
+
+	networks := r.Envelope.NetworkSection.Networks
+	if len(networks) != 1 || networks[0].Name != "NAT" {
+		t.Fatal("Did not get expected networks -", networks)
+	}
+}
+
+func TestSataControllerResourceType(t *testing.T) {
+	if SataControllerResourceType != "20" || OtherStorageDeviceResourceType != SataControllerResourceType {
+		t.Fatal("Got unexpected SATA controller resource type " + SataControllerResourceType)
+	}
+
+	if !IsStorageController(Item{ResourceType: SataControllerResourceType, ResourceSubType: "AHCI"}) {
+		t.Fatal("Expected a SATA controller to be a storage controller")
+	}
 }
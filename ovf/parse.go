@@ -0,0 +1,303 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// ParseError describes a failure to parse an element of an OVF.
+type ParseError struct {
+	// Path is the path of the element that could not be parsed,
+	// made up of the local names of the element and its ancestors
+	// (e.g., 'Envelope/VirtualSystem/VirtualHardwareSection/Item').
+	Path string
+
+	// Offset is the byte offset of the element's start tag, or of
+	// the malformed data if the OVF is not well-formed.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (o *ParseError) Error() string {
+	return "failed to parse '" + o.Path + "' at byte offset " +
+		strconv.FormatInt(o.Offset, 10) + " - " + o.Err.Error()
+}
+
+func (o *ParseError) Unwrap() error {
+	return o.Err
+}
+
+// parser parses an OVF one element at a time, so that a failure can be
+// attributed to a specific element. Each element that is modeled by this
+// package is decoded from a copy of its tokens, meaning that a failure
+// to decode one element does not prevent the remaining elements from
+// being parsed.
+type parser struct {
+	d        *xml.Decoder
+	partial  bool
+	path     []string
+	env      *Envelope
+	failures []error
+}
+
+func parseOvf(raw []byte, partial bool) (Envelope, error) {
+	var env Envelope
+
+	p := &parser{
+		d:       xmlutil.NewDecoder(bytes.NewReader(raw)),
+		partial: partial,
+		env:     &env,
+	}
+
+	err := p.parse()
+
+	sections := env.VirtualSystem.VirtualHardwareSections
+	if len(sections) > 0 {
+		env.VirtualSystem.VirtualHardwareSection = sections[0]
+	}
+
+	if err != nil {
+		return env, err
+	}
+
+	if len(p.failures) > 0 {
+		return env, errors.Join(p.failures...)
+	}
+
+	return env, nil
+}
+
+func (o *parser) parse() error {
+	for {
+		offset := o.d.InputOffset()
+
+		t, err := o.d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &ParseError{
+				Path:   o.childPath(""),
+				Offset: o.d.InputOffset(),
+				Err:    err,
+			}
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			err := o.startElement(v, offset)
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			o.path = o.path[:len(o.path)-1]
+		}
+	}
+
+	if len(o.env.XMLName.Local) == 0 {
+		return &ParseError{
+			Path:   EnvelopeName.String(),
+			Offset: o.d.InputOffset(),
+			Err:    errors.New("document does not contain an Envelope"),
+		}
+	}
+
+	return nil
+}
+
+// hardwareSection returns the VirtualHardwareSection that is currently
+// being parsed.
+func (o *parser) hardwareSection() *VirtualHardwareSection {
+	sections := o.env.VirtualSystem.VirtualHardwareSections
+
+	return &sections[len(sections)-1]
+}
+
+func (o *parser) startElement(start xml.StartElement, offset int64) error {
+	parent := strings.Join(o.path, "/")
+
+	switch {
+	case parent == "" && start.Name.Local == EnvelopeName.String():
+		return o.decodeAttrs(start, offset, o.env)
+	case parent == "Envelope" && start.Name.Local == "References":
+		return o.decodeAttrs(start, offset, &o.env.References)
+	case parent == "Envelope/References" && start.Name.Local == ReferencesFileName.String():
+		var file File
+		return o.decodeElement(start, offset, &file, func() {
+			o.env.References.Files = append(o.env.References.Files, file)
+		})
+	case parent == "Envelope" && start.Name.Local == "DiskSection":
+		return o.decodeAttrs(start, offset, &o.env.DiskSection)
+	case parent == "Envelope/DiskSection" && start.Name.Local == DiskName.String():
+		var disk Disk
+		return o.decodeElement(start, offset, &disk, func() {
+			o.env.DiskSection.Disks = append(o.env.DiskSection.Disks, disk)
+		})
+	case parent == "Envelope" && start.Name.Local == "NetworkSection":
+		return o.decodeAttrs(start, offset, &o.env.NetworkSection)
+	case parent == "Envelope/NetworkSection" && start.Name.Local == NetworkName.String():
+		var network Network
+		return o.decodeElement(start, offset, &network, func() {
+			o.env.NetworkSection.Networks = append(o.env.NetworkSection.Networks, network)
+		})
+	case parent == "Envelope" && start.Name.Local == StringsSectionName.String():
+		var bundle Strings
+		return o.decodeElement(start, offset, &bundle, func() {
+			o.env.Strings = append(o.env.Strings, bundle)
+		})
+	case parent == "Envelope" && start.Name.Local == "VirtualSystem":
+		return o.decodeAttrs(start, offset, &o.env.VirtualSystem)
+	case parent == "Envelope/VirtualSystem" && start.Name.Local == "OperatingSystemSection":
+		var section OperatingSystemSection
+		return o.decodeElement(start, offset, &section, func() {
+			o.env.VirtualSystem.OperatingSystemSection = section
+		})
+	case parent == "Envelope/VirtualSystem" && start.Name.Local == "Machine" &&
+		start.Name.Space == VboxNamespace:
+		var machine VboxMachine
+		return o.decodeElement(start, offset, &machine, func() {
+			o.env.VirtualSystem.Machine = machine
+		})
+	case parent == "Envelope/VirtualSystem" && start.Name.Local == "IpAssignmentSection" &&
+		start.Name.Space == VmwNamespace:
+		var section IpAssignmentSection
+		return o.decodeElement(start, offset, &section, func() {
+			o.env.VirtualSystem.IpAssignmentSection = section
+		})
+	case parent == "Envelope/VirtualSystem" && start.Name.Local == AnnotationSectionName.String():
+		var section AnnotationSection
+		return o.decodeElement(start, offset, &section, func() {
+			o.env.VirtualSystem.AnnotationSection = section
+		})
+	case parent == "Envelope/VirtualSystem" && start.Name.Local == VirtualHardwareSectionName.String():
+		o.env.VirtualSystem.VirtualHardwareSections = append(o.env.VirtualSystem.VirtualHardwareSections, VirtualHardwareSection{})
+		return o.decodeAttrs(start, offset, o.hardwareSection())
+	case parent == "Envelope/VirtualSystem/VirtualHardwareSection" && start.Name.Local == "Info":
+		var info string
+		return o.decodeElement(start, offset, &info, func() {
+			o.hardwareSection().Info = info
+		})
+	case parent == "Envelope/VirtualSystem/VirtualHardwareSection" && start.Name.Local == VirtualHardwareSystemName.String():
+		var system System
+		return o.decodeElement(start, offset, &system, func() {
+			o.hardwareSection().System = system
+		})
+	case parent == "Envelope/VirtualSystem/VirtualHardwareSection" && start.Name.Local == VirtualHardwareItemName.String():
+		var item Item
+		return o.decodeElement(start, offset, &item, func() {
+			section := o.hardwareSection()
+			section.Items = append(section.Items, item)
+		})
+	}
+
+	o.path = append(o.path, start.Name.Local)
+
+	return nil
+}
+
+// decodeAttrs decodes the attributes of a container element, and then
+// continues parsing its children.
+func (o *parser) decodeAttrs(start xml.StartElement, offset int64, v interface{}) error {
+	o.path = append(o.path, start.Name.Local)
+
+	tokens := []xml.Token{start, start.End()}
+
+	err := xml.NewTokenDecoder(&tokenReader{tokens: tokens}).Decode(v)
+	if err != nil {
+		return o.fail(o.childPath(""), offset, err)
+	}
+
+	return nil
+}
+
+// decodeElement decodes an entire element, calling onSuccess if it was
+// decoded successfully.
+func (o *parser) decodeElement(start xml.StartElement, offset int64, v interface{}, onSuccess func()) error {
+	tokens := []xml.Token{start.Copy()}
+	depth := 1
+
+	for depth > 0 {
+		t, err := o.d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return &ParseError{
+				Path:   o.childPath(start.Name.Local),
+				Offset: o.d.InputOffset(),
+				Err:    err,
+			}
+		}
+
+		switch t.(type) {
+		case xml.StartElement:
+			depth = depth + 1
+		case xml.EndElement:
+			depth = depth - 1
+		}
+
+		tokens = append(tokens, xml.CopyToken(t))
+	}
+
+	err := xml.NewTokenDecoder(&tokenReader{tokens: tokens}).Decode(v)
+	if err != nil {
+		return o.fail(o.childPath(start.Name.Local), offset, err)
+	}
+
+	onSuccess()
+
+	return nil
+}
+
+// fail records or returns the failure to decode an element, depending on
+// whether partial parsing is enabled.
+func (o *parser) fail(path string, offset int64, err error) error {
+	parseErr := &ParseError{
+		Path:   path,
+		Offset: offset,
+		Err:    err,
+	}
+
+	if !o.partial {
+		return parseErr
+	}
+
+	o.failures = append(o.failures, parseErr)
+
+	return nil
+}
+
+// childPath returns the path of a child of the current element, or the
+// path of the current element if localName is empty.
+func (o *parser) childPath(localName string) string {
+	if len(localName) == 0 {
+		return strings.Join(o.path, "/")
+	}
+
+	return strings.Join(append(o.path[:len(o.path):len(o.path)], localName), "/")
+}
+
+// tokenReader is a xml.TokenReader that provides a fixed set of tokens.
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+func (o *tokenReader) Token() (xml.Token, error) {
+	if len(o.tokens) == 0 {
+		return nil, io.EOF
+	}
+
+	t := o.tokens[0]
+	o.tokens = o.tokens[1:]
+
+	return t, nil
+}
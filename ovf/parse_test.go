@@ -0,0 +1,139 @@
+package ovf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+func TestToOvfMatchesUnmarshal(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<Hardware>", `<Hardware>
+        <Firmware type="EFI"/>
+        <TrustedPlatformModule type="v2_0"/>`, 1)
+
+	var expected Envelope
+	err := xmlutil.Unmarshal([]byte(input), &expected)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if expected.VirtualSystem.Machine.Hardware.Firmware.Type != "EFI" {
+		t.Fatal("Failed to add vbox:Machine to test data")
+	}
+
+	r, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual(r.Envelope, expected) {
+		t.Fatalf("Got unexpected result:\n%+v\nexpected:\n%+v", r.Envelope, expected)
+	}
+}
+
+func TestToOvfParseError(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>",
+		"<rasd:AutomaticAllocation>junk</rasd:AutomaticAllocation>", 1)
+
+	_, err := ToOvf(strings.NewReader(input))
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *ParseError, got - %v", err)
+	}
+
+	if parseErr.Path != "Envelope/VirtualSystem/VirtualHardwareSection/Item" {
+		t.Fatal("Got unexpected path '" + parseErr.Path + "'")
+	}
+
+	itemOffset := strings.LastIndex(input[:strings.Index(input, "junk")], "<Item>")
+	if parseErr.Offset != int64(itemOffset) {
+		t.Fatal("Got unexpected offset - " + err.Error())
+	}
+
+	r, err := ToOvfWithConfig(strings.NewReader(input), ToOvfConfig{AllowPartial: true})
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *ParseError, got - %v", err)
+	}
+
+	original, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	items := r.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	if len(items) != len(original.Envelope.VirtualSystem.VirtualHardwareSection.Items)-1 {
+		t.Fatal("Expected every Item except the invalid Item to be parsed")
+	}
+
+	if r.Envelope.VirtualSystem.Id != original.Envelope.VirtualSystem.Id || len(r.Envelope.References.Files) != 1 {
+		t.Fatal("Expected the remaining elements to be parsed")
+	}
+}
+
+func TestToOvfMalformed(t *testing.T) {
+	index := strings.Index(basicOvfFileContents, "</System>")
+	input := basicOvfFileContents[:index] + "<" + basicOvfFileContents[index+len("</System>"):]
+
+	r, err := ToOvfWithConfig(strings.NewReader(input), ToOvfConfig{AllowPartial: true})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *ParseError, got - %v", err)
+	}
+
+	if parseErr.Path != "Envelope/VirtualSystem/VirtualHardwareSection/System" {
+		t.Fatal("Got unexpected path '" + parseErr.Path + "'")
+	}
+
+	if len(r.Envelope.References.Files) != 1 {
+		t.Fatal("Expected the elements before the malformed data to be parsed")
+	}
+
+	_, err = ToOvf(strings.NewReader("<Junk/>"))
+	if !errors.As(err, &parseErr) || parseErr.Path != "Envelope" {
+		t.Fatalf("Expected a *ParseError for a missing Envelope, got - %v", err)
+	}
+}
+
+func TestToOvfMultipleHardwareSections(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "    </VirtualHardwareSection>\n", `    </VirtualHardwareSection>
+    <VirtualHardwareSection ovf:id="vmware">
+      <Info>Virtual hardware requirements for VMWare</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>centos7</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>
+      </System>
+    </VirtualHardwareSection>
+`, 1)
+
+	var expected Envelope
+	err := xmlutil.Unmarshal([]byte(input), &expected)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	r, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual(r.Envelope, expected) {
+		t.Fatalf("Got unexpected result:\n%+v\nexpected:\n%+v", r.Envelope, expected)
+	}
+
+	virtualSystem := r.Envelope.VirtualSystem
+	if virtualSystem.VirtualHardwareSection.System.VirtualSystemType != "virtualbox-2.2" {
+		t.Fatal("Expected the first section to be the VirtualHardwareSection")
+	}
+
+	section, ok := virtualSystem.HardwareSection("vmware")
+	if !ok || section.System.VirtualSystemType != "vmx-14" || len(section.Items) != 0 {
+		t.Fatalf("Got unexpected section - %+v", section)
+	}
+}
@@ -0,0 +1,46 @@
+package ovf
+
+import (
+	"io"
+)
+
+// PlannedEdit describes an edit that would be made to an OVF object.
+type PlannedEdit struct {
+	// Object is the name of the affected object.
+	Object ObjectName
+
+	// Line is the 1-based line number of the object's start
+	// element in the OVF.
+	Line int
+
+	// FuncIndex is the index of the EditObjectFunc that determined
+	// the Action. The index is relative to the order in which the
+	// funcs were proposed for the ObjectName. When several funcs
+	// return Replace, it is the index of the last one.
+	FuncIndex int
+
+	// Action is the action that would be taken.
+	Action EditAction
+
+	// Original is the object as it was provided to the
+	// EditObjectFunc (e.g., an Item or a *RawObject).
+	Original interface{}
+}
+
+// Plan runs the provided EditScheme against an existing OVF configuration
+// without producing any output. It returns the objects that would be
+// deleted or replaced, in the order in which they appear.
+//
+// Be advised: the EditObjectFunc are executed as they would be by
+// EditRawOvf. Funcs that maintain state (such as the limit of
+// DeleteHardwareItemsMatchingFunc) will have that state consumed.
+func Plan(r io.Reader, scheme EditScheme) ([]PlannedEdit, error) {
+	var planned []PlannedEdit
+
+	_, err := editRawOvf(r, scheme, EditConfig{}, &planned)
+	if err != nil {
+		return planned, err
+	}
+
+	return planned, nil
+}
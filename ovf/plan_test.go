@@ -0,0 +1,41 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(SetVirtualSystemTypeFunc("vmx-10"), VirtualHardwareSystemName).
+		Propose(ReplaceHardwareItemFunc("junk", Item{}), VirtualHardwareItemName).
+		Propose(DeleteHardwareItemsMatchingFunc("ideController", -1), VirtualHardwareItemName)
+
+	planned, err := Plan(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []PlannedEdit{
+		{Object: VirtualHardwareSystemName, Line: 25, FuncIndex: 0, Action: Replace},
+		{Object: VirtualHardwareItemName, Line: 48, FuncIndex: 1, Action: Delete},
+		{Object: VirtualHardwareItemName, Line: 57, FuncIndex: 1, Action: Delete},
+	}
+
+	if len(planned) != len(expected) {
+		t.Fatalf("Expected %d planned edits, got %d - %+v", len(expected), len(planned), planned)
+	}
+
+	for i := range expected {
+		p := planned[i]
+		e := expected[i]
+		if p.Object != e.Object || p.Line != e.Line || p.FuncIndex != e.FuncIndex || p.Action != e.Action {
+			t.Fatalf("Got unexpected planned edit at index %d - %+v", i, p)
+		}
+	}
+
+	item, ok := planned[1].Original.(Item)
+	if !ok || item.ElementName != "ideController0" {
+		t.Fatalf("Got unexpected original object - %+v", planned[1].Original)
+	}
+}
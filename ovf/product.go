@@ -0,0 +1,68 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// ProductProperty is a Property of a ProductSection.
+type ProductProperty struct {
+	// Key is the property's key (e.g., 'version').
+	Key string
+
+	// Value is the property's value.
+	Value string
+}
+
+// SetProductSectionFunc returns an EditObjectFunc that adds a
+// ProductSection with the specified class (e.g., 'com.example.tool'),
+// info, and properties to the VirtualSystem. The properties are strings
+// that cannot be configured by the user. An existing ProductSection with
+// the same class is replaced.
+//
+// The EditObjectFunc must be proposed for VirtualSystemName in its own
+// EditScheme, as the VirtualSystem contains every other object.
+func SetProductSectionFunc(class string, info string, properties []ProductProperty) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// A missing section is not an error.
+		_ = o.DeleteChildWithAttr("ProductSection", "class", class)
+
+		err := o.InsertChild(productSection(class, info, properties, o.RelativeBodyPrefix()))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+func productSection(class string, info string, properties []ProductProperty, indent string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString(`<ProductSection ovf:class="`)
+	xml.EscapeText(b, []byte(class))
+	b.WriteString(`" ovf:required="false">` + "\n")
+
+	b.WriteString(indent + "<Info>")
+	xml.EscapeText(b, []byte(info))
+	b.WriteString("</Info>\n")
+
+	for _, property := range properties {
+		b.WriteString(indent + `<Property ovf:key="`)
+		xml.EscapeText(b, []byte(property.Key))
+		b.WriteString(`" ovf:type="string" ovf:userConfigurable="false" ovf:value="`)
+		xml.EscapeText(b, []byte(property.Value))
+		b.WriteString(`"/>` + "\n")
+	}
+
+	b.WriteString("</ProductSection>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,50 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetProductSectionFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(SetProductSectionFunc("com.example.tool", "Provenance",
+		[]ProductProperty{{Key: "version", Value: "1.0"}}), VirtualSystemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	editScheme = NewEditScheme().Propose(SetProductSectionFunc("com.example.tool", "Provenance",
+		[]ProductProperty{{Key: "version", Value: "2.0"}, {Key: "input", Value: "<a & b>"}}), VirtualSystemName)
+
+	b, err = EditRawOvf(strings.NewReader(b.String()), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "    </vbox:Machine>\n  </VirtualSystem>",
+		"    </vbox:Machine>\n"+
+			"    <ProductSection ovf:class=\"com.example.tool\" ovf:required=\"false\">\n"+
+			"      <Info>Provenance</Info>\n"+
+			"      <Property ovf:key=\"version\" ovf:type=\"string\" ovf:userConfigurable=\"false\" ovf:value=\"2.0\"/>\n"+
+			"      <Property ovf:key=\"input\" ovf:type=\"string\" ovf:userConfigurable=\"false\" ovf:value=\"&lt;a &amp; b&gt;\"/>\n"+
+			"    </ProductSection>\n"+
+			"  </VirtualSystem>", 1)
+	if expected == basicOvfFileContents {
+		t.Fatal("Failed to find end of VirtualSystem in test data")
+	}
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	parsed, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items) == 0 {
+		t.Fatal("Failed to parse the edited OVF")
+	}
+}
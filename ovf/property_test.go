@@ -0,0 +1,246 @@
+package ovf
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const propertyIterations = 250
+
+// generatedOvf is a randomized OVF document, along with the locations of
+// the objects that the property tests edit.
+type generatedOvf struct {
+	raw   []byte
+	eol   string
+	items []generatedObject
+
+	// system is the location of the System, which always contains
+	// the same fields, in the order that they are marshalled in.
+	system generatedObject
+}
+
+// generatedObject is the location of an object in a generatedOvf. The
+// start and end offsets include the object's indentation and its final
+// end of line characters.
+type generatedObject struct {
+	instanceID string
+	start      int
+	end        int
+}
+
+// ovfGenerator writes a randomized OVF document.
+type ovfGenerator struct {
+	rand   *rand.Rand
+	buff   *bytes.Buffer
+	eol    string
+	indent string
+}
+
+// generateOvf returns a randomized OVF document that is valid input for
+// EditRawOvf. The same seed always produces the same document.
+func generateOvf(seed int64) generatedOvf {
+	g := &ovfGenerator{
+		rand: rand.New(rand.NewSource(seed)),
+		buff: bytes.NewBuffer(nil),
+	}
+
+	g.eol = []string{"\n", "\r\n"}[g.rand.Intn(2)]
+	g.indent = []string{"  ", "    ", "\t"}[g.rand.Intn(3)]
+
+	result := generatedOvf{
+		eol: g.eol,
+	}
+
+	if g.chance(2) {
+		g.line(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	}
+	if g.chance(3) {
+		g.line(0, "<!--Generated for seed "+strconv.FormatInt(seed, 10)+"-->")
+	}
+
+	g.line(0, `<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">`)
+
+	g.line(1, "<References>")
+	for i := g.rand.Intn(3); i > 0; i-- {
+		g.line(2, `<File ovf:href="`+g.text()+`.vmdk" ovf:id="file`+strconv.Itoa(i)+`"/>`)
+	}
+	g.line(1, "</References>")
+
+	g.line(1, `<VirtualSystem ovf:id="`+g.text()+`">`)
+	g.line(2, "<Info>"+g.text()+"</Info>")
+	g.line(2, "<VirtualHardwareSection>")
+	g.line(3, "<Info>"+g.text()+"</Info>")
+
+	result.system.start = g.buff.Len()
+	g.line(3, "<System>")
+	g.line(4, "<vssd:ElementName>Virtual Hardware Family</vssd:ElementName>")
+	g.line(4, "<vssd:InstanceID>0</vssd:InstanceID>")
+	g.line(4, "<vssd:VirtualSystemIdentifier>"+g.text()+"</vssd:VirtualSystemIdentifier>")
+	g.line(4, "<vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>")
+	g.line(3, "</System>")
+	result.system.end = g.buff.Len()
+
+	fields := []string{"Address", "AddressOnParent", "AutomaticAllocation", "Caption", "Description",
+		"ElementName", "HostResource", "Parent", "ResourceSubType", "ResourceType"}
+
+	numItems := g.rand.Intn(8)
+	for i := 1; i <= numItems; i++ {
+		if g.chance(4) {
+			// Comments may not contain "--".
+			g.line(3, "<!--"+strings.Replace(g.text(), "-", "+", -1)+"-->")
+		}
+
+		item := generatedObject{
+			instanceID: strconv.Itoa(i),
+			start:      g.buff.Len(),
+		}
+
+		g.line(3, "<Item>")
+		for _, field := range fields {
+			if !g.chance(2) {
+				continue
+			}
+
+			value := g.text()
+			if field == "AutomaticAllocation" {
+				value = strconv.FormatBool(g.chance(2))
+			}
+
+			g.line(4, "<rasd:"+field+">"+value+"</rasd:"+field+">")
+		}
+		g.line(4, "<rasd:InstanceID>"+item.instanceID+"</rasd:InstanceID>")
+		g.line(3, "</Item>")
+
+		item.end = g.buff.Len()
+		result.items = append(result.items, item)
+	}
+
+	g.line(2, "</VirtualHardwareSection>")
+	g.line(1, "</VirtualSystem>")
+
+	if g.chance(2) {
+		g.line(0, "</Envelope>")
+	} else {
+		// The final line does not always end with an end of line.
+		g.buff.WriteString("</Envelope>")
+	}
+
+	result.raw = g.buff.Bytes()
+
+	return result
+}
+
+// chance returns true with a probability of 1 in n.
+func (o *ovfGenerator) chance(n int) bool {
+	return o.rand.Intn(n) == 0
+}
+
+// line writes a line at the specified depth. Some lines end with
+// trailing whitespace.
+func (o *ovfGenerator) line(depth int, content string) {
+	o.buff.WriteString(strings.Repeat(o.indent, depth))
+	o.buff.WriteString(content)
+
+	if o.chance(10) {
+		o.buff.WriteString([]string{" ", "\t", "  "}[o.rand.Intn(3)])
+	}
+
+	o.buff.WriteString(o.eol)
+}
+
+// text returns a random string of character data, which may include
+// escaped and non-ASCII characters.
+func (o *ovfGenerator) text() string {
+	parts := []string{"centos", "Disk", "ide", "0", "&amp;", "&lt;", "&quot;", "é", "網", "-", "_", " "}
+
+	var text string
+	for i := o.rand.Intn(4) + 1; i > 0; i-- {
+		text = text + parts[o.rand.Intn(len(parts))]
+	}
+
+	return strings.TrimSpace(text)
+}
+
+func TestEditRawOvfPropertyEmptySchemeIsIdentity(t *testing.T) {
+	for seed := int64(0); seed < propertyIterations; seed++ {
+		generated := generateOvf(seed)
+
+		b, err := EditRawOvf(bytes.NewReader(generated.raw), NewEditScheme())
+		if err != nil {
+			t.Fatal("seed " + strconv.FormatInt(seed, 10) + " - " + err.Error())
+		}
+
+		if !bytes.Equal(b.Bytes(), generated.raw) {
+			t.Fatalf("seed %d - empty edit scheme modified the OVF:\n%q\nexpected:\n%q",
+				seed, b.String(), generated.raw)
+		}
+	}
+}
+
+func TestEditRawOvfPropertyDeleteOnlyTouchesTarget(t *testing.T) {
+	for seed := int64(0); seed < propertyIterations; seed++ {
+		generated := generateOvf(seed)
+		if len(generated.items) == 0 {
+			continue
+		}
+
+		target := generated.items[rand.New(rand.NewSource(seed)).Intn(len(generated.items))]
+
+		f := func(i interface{}) EditObjectResult {
+			o, ok := i.(Item)
+			if !ok || o.InstanceID != target.instanceID {
+				return EditObjectResult{Action: NoOp}
+			}
+
+			return EditObjectResult{Action: Delete}
+		}
+
+		b, err := EditRawOvf(bytes.NewReader(generated.raw), NewEditScheme().Propose(f, VirtualHardwareItemName))
+		if err != nil {
+			t.Fatal("seed " + strconv.FormatInt(seed, 10) + " - " + err.Error())
+		}
+
+		expected := append(append([]byte(nil), generated.raw[:target.start]...), generated.raw[target.end:]...)
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Fatalf("seed %d - deleting Item %s modified other content:\n%q\nexpected:\n%q",
+				seed, target.instanceID, b.String(), expected)
+		}
+	}
+}
+
+func TestEditRawOvfPropertyReplaceOnlyTouchesTarget(t *testing.T) {
+	for seed := int64(0); seed < propertyIterations; seed++ {
+		generated := generateOvf(seed)
+
+		editScheme := NewEditScheme().Propose(SetVirtualSystemTypeFunc("vmx-14"), VirtualHardwareSystemName)
+
+		b, err := EditRawOvf(bytes.NewReader(generated.raw), editScheme)
+		if err != nil {
+			t.Fatal("seed " + strconv.FormatInt(seed, 10) + " - " + err.Error())
+		}
+
+		result := b.Bytes()
+		before := generated.raw[:generated.system.start]
+		after := generated.raw[generated.system.end:]
+
+		if !bytes.HasPrefix(result, before) || !bytes.HasSuffix(result, after) ||
+			len(result) < len(before)+len(after) {
+			t.Fatalf("seed %d - replacing the System modified other content:\n%q\nexpected to start with:\n%q\nand end with:\n%q",
+				seed, result, before, after)
+		}
+
+		system := string(result[len(before) : len(result)-len(after)])
+		if !strings.HasSuffix(system, generated.eol) ||
+			strings.Count(system, "\n") != strings.Count(system, generated.eol) {
+			t.Fatalf("seed %d - replaced System does not use the OVF's end of line characters:\n%q",
+				seed, system)
+		}
+
+		if !strings.Contains(system, "<vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>") {
+			t.Fatalf("seed %d - System was not replaced:\n%q", seed, system)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ovf
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// fileHostResourcePrefix prefixes the File ID in the HostResource
+	// of an Item that uses a File directly (e.g., a floppy image). The
+	// 'ovf:' scheme is optional.
+	fileHostResourcePrefix = "/file/"
+)
+
+// IsRemoteHref returns true if a File's href is a URL (e.g.,
+// 'http://example.com/disk1.vmdk') rather than the name of a file that
+// accompanies the .ovf.
+func IsRemoteHref(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+
+	// A single letter scheme is a Windows drive letter.
+	return len(u.Scheme) > 1
+}
+
+// RemoteHrefFilename returns the name that a remote file should be
+// stored as when it is downloaded (i.e., the last element of the URL's
+// path). An empty string is returned if a name cannot be determined.
+func RemoteHrefFilename(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return ""
+	}
+
+	return name
+}
+
+// SetFileHrefFunc returns an EditObjectFunc that sets the href of the
+// References File with the specified ID.
+func SetFileHrefFunc(id string, href string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("id")
+		if current != id {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.SetAttr("ovf:href", href)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// SetFileSizeFunc returns an EditObjectFunc that sets the size of the
+// References File with the specified ID.
+func SetFileSizeFunc(id string, size int64) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("id")
+		if current != id {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.SetAttr("ovf:size", strconv.FormatInt(size, 10))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// HostResourceFileId returns the File ID referenced by an Item's
+// HostResource (e.g., 'ovf:/file/file2' or '/file/file2').
+func HostResourceFileId(hostResource string) (string, bool) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(hostResource), "ovf:")
+	if !strings.HasPrefix(trimmed, fileHostResourcePrefix) {
+		return "", false
+	}
+
+	fileId := strings.TrimPrefix(trimmed, fileHostResourcePrefix)
+	if len(fileId) == 0 {
+		return "", false
+	}
+
+	return fileId, true
+}
+
+// DeleteFileFunc returns an EditObjectFunc that deletes the References
+// File with the specified ID.
+func DeleteFileFunc(id string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("id")
+		if current != id {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{Action: Delete}
+	}
+}
+
+// UnreferencedFiles returns the IDs of the References Files that nothing
+// references, in the order they appear. A File is referenced by the
+// fileRef of a Disk or a Strings bundle, and by the HostResource of an
+// Item (e.g., an ISO image used by a CD-ROM drive). References made by
+// elements that are not parsed (e.g., the Icon of a ProductSection, or
+// vendor-specific elements) are not seen, meaning that a File used only
+// by such an element is included.
+func UnreferencedFiles(o Ovf) []string {
+	referenced := make(map[string]bool)
+
+	for _, disk := range o.Envelope.DiskSection.Disks {
+		referenced[disk.FileRef] = true
+	}
+
+	for _, bundle := range o.Envelope.Strings {
+		referenced[bundle.FileRef] = true
+	}
+
+	for _, section := range o.Envelope.VirtualSystem.VirtualHardwareSections {
+		for _, item := range section.Items {
+			fileId, ok := HostResourceFileId(item.HostResource)
+			if ok {
+				referenced[fileId] = true
+			}
+		}
+	}
+
+	var unreferenced []string
+	for _, file := range o.Envelope.References.Files {
+		if !referenced[file.Id] {
+			unreferenced = append(unreferenced, file.Id)
+		}
+	}
+
+	return unreferenced
+}
+
+// GarbageCollectReferencesFunc returns an EditObjectFunc that deletes the
+// References Files with the specified IDs, which are typically the Files
+// that became unreferenced when the objects using them were deleted
+// (e.g., the ISO image of a removed CD-ROM drive). The IDs are usually
+// chosen using UnreferencedFiles. It must be proposed for
+// ReferencesFileName.
+func GarbageCollectReferencesFunc(ids ...string) EditObjectFunc {
+	unreferenced := make(map[string]bool)
+	for _, id := range ids {
+		unreferenced[id] = true
+	}
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		id, _ := o.Attr("id")
+		if !unreferenced[id] {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{Action: Delete}
+	}
+}
@@ -0,0 +1,140 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsRemoteHref(t *testing.T) {
+	remote := []string{"http://example.com/disk1.vmdk", "https://example.com/a/disk1.vmdk?x=1", "ftp://example.com/disk1.vmdk"}
+	for _, href := range remote {
+		if !IsRemoteHref(href) {
+			t.Fatal("Expected '" + href + "' to be remote")
+		}
+	}
+
+	local := []string{"disk1.vmdk", "C:\\disk1.vmdk", "dir/disk1.vmdk", ""}
+	for _, href := range local {
+		if IsRemoteHref(href) {
+			t.Fatal("Expected '" + href + "' to be local")
+		}
+	}
+}
+
+func TestRemoteHrefFilename(t *testing.T) {
+	name := RemoteHrefFilename("https://example.com/a/disk1.vmdk?x=1")
+	if name != "disk1.vmdk" {
+		t.Fatal("Got unexpected filename '" + name + "'")
+	}
+
+	name = RemoteHrefFilename("https://example.com/")
+	if name != "" {
+		t.Fatal("Got unexpected filename '" + name + "'")
+	}
+}
+
+func TestSetFileHrefFunc(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(SetFileHrefFunc("file1", "local-disk001.vmdk"), ReferencesFileName).
+		Propose(SetFileHrefFunc("missing", "junk.vmdk"), ReferencesFileName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, `ovf:href="centos7-disk001.vmdk"`, `ovf:href="local-disk001.vmdk"`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestSetFileSizeFunc(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(SetFileSizeFunc("file1", 1024), ReferencesFileName).
+		Propose(SetFileSizeFunc("missing", 1), ReferencesFileName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, `ovf:href="centos7-disk001.vmdk"/>`,
+		`ovf:href="centos7-disk001.vmdk" ovf:size="1024"/>`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestHostResourceFileId(t *testing.T) {
+	for _, hostResource := range []string{"ovf:/file/file2", "/file/file2"} {
+		id, ok := HostResourceFileId(hostResource)
+		if !ok || id != "file2" {
+			t.Fatal("Got unexpected file ID '" + id + "' for '" + hostResource + "'")
+		}
+	}
+
+	for _, hostResource := range []string{"ovf:/disk/vmdisk1", "/file/", ""} {
+		_, ok := HostResourceFileId(hostResource)
+		if ok {
+			t.Fatal("Expected no file ID for '" + hostResource + "'")
+		}
+	}
+}
+
+func TestDeleteFileFunc(t *testing.T) {
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents),
+		NewEditScheme().Propose(DeleteFileFunc("file1"), ReferencesFileName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), `ovf:id="file1"`) {
+		t.Fatal("File was not deleted:\n'" + b.String() + "'")
+	}
+}
+
+func TestUnreferencedFiles(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, `<File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
+    <File ovf:id="file2" ovf:href="install.iso"/>
+    <File ovf:id="file3" ovf:href="fr-FR.xml"/>
+    <File ovf:id="file4" ovf:href="unused.iso"/>`, 1)
+	input = strings.Replace(input, "</VirtualSystem>\n</Envelope>",
+		"</VirtualSystem>\n  <Strings xml:lang=\"fr-FR\" ovf:fileRef=\"file3\"/>\n</Envelope>", 1)
+	input = strings.Replace(input, "    </VirtualHardwareSection>", `      <Item>
+        <rasd:ElementName>cdrom1</rasd:ElementName>
+        <rasd:HostResource>ovf:/file/file2</rasd:HostResource>
+        <rasd:InstanceID>10</rasd:InstanceID>
+        <rasd:ResourceType>15</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>`, 1)
+
+	o, err := ToOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(o.Envelope.References.Files) != 4 {
+		t.Fatal("Failed to add files to the test input")
+	}
+
+	unreferenced := UnreferencedFiles(o)
+	if len(unreferenced) != 1 || unreferenced[0] != "file4" {
+		t.Fatalf("Got unexpected unreferenced files - %v", unreferenced)
+	}
+
+	b, err := EditRawOvf(strings.NewReader(input),
+		NewEditScheme().Propose(GarbageCollectReferencesFunc(unreferenced...), ReferencesFileName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), `ovf:id="file4"`) || !strings.Contains(b.String(), `ovf:id="file3"`) {
+		t.Fatal("Got unexpected references:\n'" + b.String() + "'")
+	}
+}
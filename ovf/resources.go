@@ -0,0 +1,77 @@
+package ovf
+
+import (
+	"strconv"
+)
+
+const (
+	// CpuAllocationUnits are the AllocationUnits of a processor Item
+	// whose Reservation and Limit are in MHz.
+	CpuAllocationUnits = "hertz * 10^6"
+)
+
+// ResourceAllocation describes the resources guaranteed to, and the
+// resources available to, a virtual machine. Values less than one are
+// not set, and the existing values are kept.
+type ResourceAllocation struct {
+	// Reservation is the amount of the resource that is guaranteed
+	// to be available to the virtual machine.
+	Reservation int64
+
+	// Limit is the maximum amount of the resource that the virtual
+	// machine can use.
+	Limit int64
+
+	// Shares is the relative priority of the virtual machine when
+	// the resource is contended. It is stored in the Item's Weight.
+	Shares int64
+}
+
+// SetCpuReservationFunc returns an EditObjectFunc that sets the
+// Reservation, Limit, and Weight of the processor Items. The Reservation
+// and Limit are in MHz, and the Item's AllocationUnits are set to
+// CpuAllocationUnits if they are not specified.
+func SetCpuReservationFunc(allocation ResourceAllocation) EditObjectFunc {
+	return ModifyHardwareItemsOfResourceTypeFunc(ProcessorResourceType, func(i Item) Item {
+		if len(i.AllocationUnits) == 0 && (allocation.Reservation > 0 || allocation.Limit > 0) {
+			i.AllocationUnits = CpuAllocationUnits
+		}
+
+		return setResourceAllocation(i, allocation, 1)
+	})
+}
+
+// SetMemoryReservationFunc returns an EditObjectFunc that sets the
+// Reservation, Limit, and Weight of the memory Items. The Reservation and
+// Limit are in bytes, and are converted to the Item's AllocationUnits
+// (e.g., 'byte * 2^20'), rounding down. Items whose AllocationUnits are
+// not supported are not modified.
+func SetMemoryReservationFunc(allocation ResourceAllocation) EditObjectFunc {
+	return ModifyHardwareItemsOfResourceTypeFunc(MemoryResourceType, func(i Item) Item {
+		multiplier, err := ParseCapacityAllocationUnits(i.AllocationUnits)
+		if err != nil {
+			return i
+		}
+
+		return setResourceAllocation(i, allocation, multiplier)
+	})
+}
+
+// setResourceAllocation sets the Reservation, Limit, and Weight of an
+// Item. The Reservation and Limit are divided by the multiplier of the
+// Item's AllocationUnits.
+func setResourceAllocation(item Item, allocation ResourceAllocation, multiplier int64) Item {
+	if allocation.Reservation > 0 {
+		item.Reservation = strconv.FormatInt(allocation.Reservation/multiplier, 10)
+	}
+
+	if allocation.Limit > 0 {
+		item.Limit = strconv.FormatInt(allocation.Limit/multiplier, 10)
+	}
+
+	if allocation.Shares > 0 {
+		item.Weight = strconv.FormatInt(allocation.Shares, 10)
+	}
+
+	return item
+}
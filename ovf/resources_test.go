@@ -0,0 +1,47 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetCpuAndMemoryReservationFunc(t *testing.T) {
+	editScheme := NewEditScheme().
+		Propose(SetCpuReservationFunc(ResourceAllocation{Reservation: 2000, Shares: 4000}), VirtualHardwareItemName).
+		Propose(SetMemoryReservationFunc(ResourceAllocation{Reservation: 256 << 20, Limit: 1 << 30}), VirtualHardwareItemName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	o, err := ToOvf(b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	checked := 0
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		switch item.ResourceType {
+		case ProcessorResourceType:
+			if item.AllocationUnits != CpuAllocationUnits || item.Reservation != "2000" ||
+				item.Limit != "" || item.Weight != "4000" {
+				t.Fatalf("Got unexpected CPU item - %+v", item)
+			}
+			checked = checked + 1
+		case MemoryResourceType:
+			if item.Reservation != "256" || item.Limit != "1024" || item.Weight != "" {
+				t.Fatalf("Got unexpected memory item - %+v", item)
+			}
+			checked = checked + 1
+		default:
+			if len(item.Reservation) > 0 || len(item.Limit) > 0 || len(item.Weight) > 0 {
+				t.Fatalf("Got unexpected item - %+v", item)
+			}
+		}
+	}
+
+	if checked != 2 {
+		t.Fatalf("Expected to check 2 items - checked %d", checked)
+	}
+}
@@ -0,0 +1,323 @@
+package ovf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ItemParentsRuleName is the name of the rule that checks that
+	// the Parent of every Item is a controller.
+	ItemParentsRuleName = "item-parents"
+
+	// DiskFilesRuleName is the name of the rule that checks that the
+	// fileRef of every Disk is a References File.
+	DiskFilesRuleName = "disk-files"
+
+	// HostResourceDisksRuleName is the name of the rule that checks
+	// that every HostResource that references a Disk references an
+	// existing Disk.
+	HostResourceDisksRuleName = "host-resource-disks"
+)
+
+// Rule checks a parsed OVF for a problem, such as a violation of an
+// organization's policy. Rules are combined using a RuleSet.
+type Rule interface {
+	// Check returns the problems found in the OVF. The OVF must not
+	// be modified.
+	Check(o *Ovf) []Finding
+}
+
+// RuleFunc is a func that implements Rule.
+type RuleFunc func(o *Ovf) []Finding
+
+// Check calls the func.
+func (o RuleFunc) Check(parsed *Ovf) []Finding {
+	return o(parsed)
+}
+
+// RuleInfo describes a Rule in a RuleSet.
+type RuleInfo struct {
+	// Name uniquely identifies the Rule in its RuleSet (e.g.,
+	// 'item-parents').
+	Name string
+
+	// Description is a one line description of what the Rule
+	// checks.
+	Description string
+
+	// Enabled is true if the Rule is checked by RuleSet.Check.
+	Enabled bool
+}
+
+type namedRule struct {
+	info RuleInfo
+	rule Rule
+}
+
+// RuleSet is a collection of named Rules, each of which can be enabled
+// or disabled. The zero value is an empty RuleSet.
+type RuleSet struct {
+	rules []namedRule
+}
+
+// NewRuleSet returns a RuleSet containing the rules that are checked by
+// Validate, which check that the references between the objects of an
+// OVF resolve (see CheckIntegrity).
+func NewRuleSet() *RuleSet {
+	rules := &RuleSet{}
+
+	rules.Add(ItemParentsRuleName, "The Parent of every Item is the InstanceID of a controller",
+		RuleFunc(checkItemParents))
+	rules.Add(DiskFilesRuleName, "The fileRef of every Disk is the ID of a References File",
+		RuleFunc(checkDiskFiles))
+	rules.Add(HostResourceDisksRuleName, "Every HostResource that references a Disk references an existing Disk",
+		RuleFunc(checkHostResourceDisks))
+
+	return rules
+}
+
+// Add adds an enabled Rule to the RuleSet. Rules are checked in the
+// order they are added. A non-nil error is returned if the name is empty,
+// or if the RuleSet already has a Rule with the name.
+func (o *RuleSet) Add(name string, description string, rule Rule) error {
+	if len(name) == 0 {
+		return errors.New("a rule's name cannot be empty")
+	}
+
+	if o.find(name) != nil {
+		return errors.New("a rule named '" + name + "' already exists")
+	}
+
+	o.rules = append(o.rules, namedRule{
+		info: RuleInfo{
+			Name:        name,
+			Description: description,
+			Enabled:     true,
+		},
+		rule: rule,
+	})
+
+	return nil
+}
+
+// Enable enables the Rule with the specified name. A non-nil error is
+// returned if the RuleSet does not have a Rule with the name.
+func (o *RuleSet) Enable(name string) error {
+	return o.setEnabled(name, true)
+}
+
+// Disable disables the Rule with the specified name, meaning that it is
+// not checked. A non-nil error is returned if the RuleSet does not have a
+// Rule with the name.
+func (o *RuleSet) Disable(name string) error {
+	return o.setEnabled(name, false)
+}
+
+func (o *RuleSet) setEnabled(name string, enabled bool) error {
+	rule := o.find(name)
+	if rule == nil {
+		return errors.New("unknown rule '" + name + "'")
+	}
+
+	rule.info.Enabled = enabled
+
+	return nil
+}
+
+func (o *RuleSet) find(name string) *namedRule {
+	for i := range o.rules {
+		if o.rules[i].info.Name == name {
+			return &o.rules[i]
+		}
+	}
+
+	return nil
+}
+
+// Rules describes the Rules in the RuleSet, in the order they are
+// checked.
+func (o *RuleSet) Rules() []RuleInfo {
+	var infos []RuleInfo
+	for _, rule := range o.rules {
+		infos = append(infos, rule.info)
+	}
+
+	return infos
+}
+
+// Check checks the OVF using each enabled Rule, and returns their
+// Findings. The Rule of each Finding is set to the name of the Rule that
+// found it.
+func (o *RuleSet) Check(parsed *Ovf) []Finding {
+	var findings []Finding
+
+	for _, rule := range o.rules {
+		if !rule.info.Enabled {
+			continue
+		}
+
+		for _, finding := range rule.rule.Check(parsed) {
+			finding.Rule = rule.info.Name
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// MaxMemoryRule returns a FixableRule that finds memory Items that
+// allocate more than the specified number of bytes (e.g., to enforce a
+// policy that virtual machines have at most 16 GiB of memory). Its fix
+// reduces their memory to the maximum.
+func MaxMemoryRule(maxBytes int64) FixableRule {
+	return maxMemoryRule{maxBytes: maxBytes}
+}
+
+type maxMemoryRule struct {
+	maxBytes int64
+}
+
+func (o maxMemoryRule) Check(parsed *Ovf) []Finding {
+	var findings []Finding
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType != MemoryResourceType {
+			continue
+		}
+
+		memory, err := memoryBytes(item)
+		if err != nil {
+			findings = append(findings, Finding{Path: itemPath, Err: err})
+			continue
+		}
+
+		if memory > o.maxBytes {
+			findings = append(findings, Finding{
+				Path: itemPath,
+				Err: errors.New("item '" + item.InstanceID + "' allocates " +
+					strconv.FormatInt(memory, 10) + " bytes of memory, which is more than " +
+					strconv.FormatInt(o.maxBytes, 10) + " bytes"),
+			})
+		}
+	}
+
+	return findings
+}
+
+func (o maxMemoryRule) Fix(parsed *Ovf) EditScheme {
+	return NewEditScheme().Propose(func(i interface{}) EditObjectResult {
+		item, ok := i.(Item)
+		if !ok || item.ResourceType != MemoryResourceType {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		memory, err := memoryBytes(item)
+		if err != nil || memory <= o.maxBytes {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// The units are known to be valid.
+		multiplier, _ := ParseCapacityAllocationUnits(item.AllocationUnits)
+		item.VirtualQuantity = strconv.FormatInt(o.maxBytes/multiplier, 10)
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &item,
+		}
+	}, VirtualHardwareItemName)
+}
+
+// memoryBytes returns the number of bytes of memory allocated by a
+// memory Item.
+func memoryBytes(item Item) (int64, error) {
+	quantity, err := strconv.ParseInt(strings.TrimSpace(item.VirtualQuantity), 10, 64)
+	if err != nil || quantity < 0 {
+		return 0, errors.New("item '" + item.InstanceID + "' has an unsupported memory quantity '" +
+			item.VirtualQuantity + "'")
+	}
+
+	multiplier, err := ParseCapacityAllocationUnits(item.AllocationUnits)
+	if err != nil {
+		return 0, errors.New("item '" + item.InstanceID + "' has " + err.Error())
+	}
+
+	memory, ok := multiplyBytes(quantity, multiplier)
+	if !ok {
+		return 0, errors.New("item '" + item.InstanceID + "' allocates too much memory")
+	}
+
+	return memory, nil
+}
+
+// ForbiddenNetworksRule returns a Rule that finds Networks, and the
+// Items connected to them, whose names match one of the specified names,
+// ignoring case (e.g., to enforce a policy that virtual machines do not
+// use 'NAT' networks).
+func ForbiddenNetworksRule(names ...string) Rule {
+	forbidden := func(name string) bool {
+		for _, n := range names {
+			if strings.EqualFold(strings.TrimSpace(name), n) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return RuleFunc(func(o *Ovf) []Finding {
+		var findings []Finding
+
+		for _, network := range o.Envelope.NetworkSection.Networks {
+			if forbidden(network.Name) {
+				findings = append(findings, Finding{
+					Path: "Envelope/NetworkSection/Network",
+					Err:  errors.New("network '" + network.Name + "' is not allowed"),
+				})
+			}
+		}
+
+		for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if len(item.Connection) > 0 && forbidden(item.Connection) {
+				findings = append(findings, Finding{
+					Path: itemPath,
+					Err: errors.New("item '" + item.InstanceID + "' is connected to network '" +
+						item.Connection + "', which is not allowed"),
+				})
+			}
+		}
+
+		return findings
+	})
+}
+
+// UnreferencedFilesRule returns a FixableRule that finds References
+// Files that nothing references (see UnreferencedFiles), such as the ISO
+// image of a CD-ROM drive that was removed. Its fix deletes the Files.
+// It is not included in NewRuleSet, as OVFs may legitimately contain
+// Files that are referenced by elements which are not parsed (e.g., the
+// Icon of a ProductSection).
+func UnreferencedFilesRule() FixableRule {
+	return unreferencedFilesRule{}
+}
+
+type unreferencedFilesRule struct{}
+
+func (o unreferencedFilesRule) Check(parsed *Ovf) []Finding {
+	var findings []Finding
+
+	for _, id := range UnreferencedFiles(*parsed) {
+		findings = append(findings, Finding{
+			Path: "Envelope/References/File",
+			Err:  errors.New("file '" + id + "' is not referenced by anything"),
+		})
+	}
+
+	return findings
+}
+
+func (o unreferencedFilesRule) Fix(parsed *Ovf) EditScheme {
+	return NewEditScheme().Propose(GarbageCollectReferencesFunc(UnreferencedFiles(*parsed)...),
+		ReferencesFileName)
+}
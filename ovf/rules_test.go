@@ -0,0 +1,174 @@
+package ovf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRuleSet(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rules := NewRuleSet()
+
+	err = rules.Add("custom", "Always finds a problem", RuleFunc(func(o *Ovf) []Finding {
+		return []Finding{{Err: errors.New("custom problem")}}
+	}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rules.Add(ItemParentsRuleName, "A duplicate", RuleFunc(func(o *Ovf) []Finding { return nil }))
+	if err == nil {
+		t.Fatal("Expected an error when adding a rule with a duplicate name")
+	}
+
+	var names []string
+	for _, info := range rules.Rules() {
+		if !info.Enabled || len(info.Description) == 0 {
+			t.Fatalf("Expected rule to be enabled and described - %+v", info)
+		}
+
+		names = append(names, info.Name)
+	}
+
+	if strings.Join(names, ",") != "item-parents,disk-files,host-resource-disks,custom" {
+		t.Fatalf("Got unexpected rules %q", names)
+	}
+
+	findings := rules.Check(&o)
+	if len(findings) != 1 || findings[0].Rule != "custom" {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	err = rules.Disable("custom")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	findings = rules.Check(&o)
+	if len(findings) != 0 {
+		t.Fatalf("Got findings from a disabled rule - %+v", findings)
+	}
+
+	err = rules.Enable("missing")
+	if err == nil {
+		t.Fatal("Expected an error when enabling an unknown rule")
+	}
+}
+
+func TestValidateWithRules(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, `ovf:fileRef="file1"`, `ovf:fileRef="file404"`, 1)
+
+	rules := NewRuleSet()
+	err := rules.Disable(DiskFilesRuleName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	findings, err := ValidateWithRules(strings.NewReader(input), Limits{}, rules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("Got findings from a disabled rule - %+v", findings)
+	}
+
+	findings, err = ValidateWithRules(strings.NewReader(input), Limits{}, &RuleSet{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("Got findings from an empty RuleSet - %+v", findings)
+	}
+}
+
+func TestMaxMemoryRule(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var memory Item
+	for _, item := range o.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == MemoryResourceType {
+			memory = item
+		}
+	}
+
+	allocated, err := memoryBytes(memory)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	findings := MaxMemoryRule(allocated).Check(&o)
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	findings = MaxMemoryRule(allocated - 1).Check(&o)
+	if len(findings) != 1 || !strings.Contains(findings[0].Err.Error(), "item '"+memory.InstanceID+"'") {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+}
+
+func TestForbiddenNetworksRule(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(o.Envelope.NetworkSection.Networks) == 0 {
+		t.Fatal("Failed to find a network in test data")
+	}
+
+	name := o.Envelope.NetworkSection.Networks[0].Name
+
+	findings := ForbiddenNetworksRule("other").Check(&o)
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	findings = ForbiddenNetworksRule(strings.ToUpper(name)).Check(&o)
+	if len(findings) == 0 || !strings.Contains(findings[0].Err.Error(), "network '"+name+"'") {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+}
+
+func TestUnreferencedFilesRule(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, `<File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="centos7-disk001.vmdk"/>
+    <File ovf:id="file2" ovf:href="install.iso"/>`, 1)
+
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	findings := UnreferencedFilesRule().Check(&o)
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	rules := &RuleSet{}
+	rules.Add("unreferenced-files", "Every file is referenced", UnreferencedFilesRule())
+
+	b, result, err := Enforce(strings.NewReader(input), Limits{}, rules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(result.Fixed) != 1 || !strings.Contains(result.Fixed[0].Err.Error(), "file 'file2'") ||
+		len(result.Remaining) != 0 {
+		t.Fatalf("Got unexpected result - %+v", result)
+	}
+
+	if b.String() != basicOvfFileContents {
+		t.Fatal("Expected the file to be removed:\n" + b.String())
+	}
+}
@@ -0,0 +1,165 @@
+package ovf
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// ProductPropertyName is the name of a Property of a
+	// ProductSection.
+	ProductPropertyName ObjectName = "Property"
+)
+
+// fqdnPattern matches fully qualified domain names with at least three
+// labels (e.g., 'build01.corp.example.com'), which excludes most file
+// names and version strings.
+var fqdnPattern = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9-]*[a-z0-9])?\.){2,}[a-z][a-z0-9-]*[a-z0-9]\.?$`)
+
+// DeleteVboxMachineFunc returns an EditObjectFunc that deletes the
+// vbox:Machine, which VMWare does not use. It contains information that
+// identifies the machine the OVF was exported from, such as its UUID, the
+// MAC addresses of its network adapters, the UUIDs of its disk images,
+// timestamps, guest properties, and extra data. It must be proposed for
+// QualifiedObjectName(VboxNamespace, VboxMachineName.String()), and in a
+// separate EditScheme from EditObjectFunc that need the vbox:Machine.
+func DeleteVboxMachineFunc() EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		_, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{Action: Delete}
+	}
+}
+
+// RemoveDiskUuidFunc returns an EditObjectFunc that removes the
+// vendor-specific UUID attribute (e.g., 'vbox:uuid') of a Disk in the
+// DiskSection. It must be proposed for DiskName.
+func RemoveDiskUuidFunc() EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		removed := false
+		for _, attr := range o.Start.Attr {
+			if attr.Name.Local != "uuid" || len(attr.Name.Space) == 0 {
+				continue
+			}
+
+			err := o.RemoveAttr(attr.Name.Space + ":" + attr.Name.Local)
+			if err != nil {
+				return EditObjectResult{Action: NoOp}
+			}
+
+			removed = true
+		}
+
+		if !removed {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// RemoveEthernetAdapterAddressFunc returns an EditObjectFunc that removes
+// the Address (i.e., the MAC address) of Ethernet adapters, so that the
+// hypervisor generates a new address when the OVF is deployed. It must be
+// proposed for VirtualHardwareItemName and EthernetPortItemName.
+func RemoveEthernetAdapterAddressFunc() EditObjectFunc {
+	removeItemAddress := ModifyHardwareItemsOfResourceTypeFunc(EthernetAdapterResourceType, func(i Item) Item {
+		i.Address = ""
+		return i
+	})
+
+	return func(i interface{}) EditObjectResult {
+		item, ok := i.(Item)
+		if ok {
+			if len(item.Address) == 0 {
+				return EditObjectResult{Action: NoOp}
+			}
+
+			return removeItemAddress(i)
+		}
+
+		raw, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		var port struct {
+			Address      string `xml:"Address"`
+			ResourceType string `xml:"ResourceType"`
+		}
+
+		err := xmlutil.Unmarshal(raw.Data().Bytes(), &port)
+		if err != nil || port.ResourceType != EthernetAdapterResourceType || len(port.Address) == 0 {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err = raw.DeleteChild("Address")
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: raw,
+		}
+	}
+}
+
+// ClearHostnamePropertiesFunc returns an EditObjectFunc that clears the
+// value of ProductSection Properties that contain a hostname: properties
+// whose key names a host (e.g., 'guestinfo.hostname' or 'domain'), and
+// properties whose value is a fully qualified domain name with at least
+// three labels (e.g., 'build01.corp.example.com'). The properties are
+// kept, so that they can still be configured when the OVF is deployed.
+// It must be proposed for ProductPropertyName.
+func ClearHostnamePropertiesFunc() EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		key, _ := o.Attr("key")
+		value, hasValue := o.Attr("value")
+		if !hasValue || len(value) == 0 || !isHostnameProperty(key, value) {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.SetAttr(qualifiedAttrName(o, "value"), "")
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// isHostnameProperty returns true if a ProductSection Property contains
+// a hostname.
+func isHostnameProperty(key string, value string) bool {
+	key = strings.ToLower(key)
+
+	for _, name := range []string{"hostname", "fqdn", "domain"} {
+		if strings.Contains(key, name) {
+			return true
+		}
+	}
+
+	return fqdnPattern.MatchString(value)
+}
@@ -0,0 +1,63 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClearHostnamePropertiesFunc(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "  </VirtualSystem>", `    <ProductSection ovf:required="false">
+      <Info>Appliance properties</Info>
+      <Property ovf:key="guestinfo.hostname" ovf:type="string" ovf:value="appliance"/>
+      <Property ovf:key="ntp-server" ovf:type="string" ovf:value="ntp01.corp.example.com"/>
+      <Property ovf:key="config" ovf:type="string" ovf:value="settings.yaml"/>
+      <Property ovf:key="version" ovf:type="string" ovf:value="1.2.3"/>
+    </ProductSection>
+  </VirtualSystem>`, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add a ProductSection to the test input")
+	}
+
+	output := editRawOvfString(t, input, NewEditScheme().
+		Propose(ClearHostnamePropertiesFunc(), ProductPropertyName))
+
+	for _, cleared := range []string{"appliance\"", "ntp01.corp.example.com"} {
+		if strings.Contains(output, cleared) {
+			t.Fatal("Expected '" + cleared + "' to be cleared:\n" + output)
+		}
+	}
+
+	for _, kept := range []string{`ovf:key="guestinfo.hostname" ovf:type="string" ovf:value=""`,
+		`ovf:value="settings.yaml"`, `ovf:value="1.2.3"`} {
+		if !strings.Contains(output, kept) {
+			t.Fatal("Expected the output to contain '" + kept + "':\n" + output)
+		}
+	}
+}
+
+func TestRemoveDiskUuidFunc(t *testing.T) {
+	output := editRawOvfString(t, basicOvfFileContents, NewEditScheme().
+		Propose(RemoveDiskUuidFunc(), DiskName))
+
+	if strings.Contains(output, "vbox:uuid") {
+		t.Fatal("Expected the disk UUID to be removed:\n" + output)
+	}
+
+	if !strings.Contains(output, `ovf:diskId="vmdisk1"`) {
+		t.Fatal("Expected the disk to be kept:\n" + output)
+	}
+}
+
+func TestDeleteVboxMachineFunc(t *testing.T) {
+	output := editRawOvfString(t, basicOvfFileContents, NewEditScheme().
+		Propose(DeleteVboxMachineFunc(), QualifiedObjectName(VboxNamespace, VboxMachineName.String())))
+
+	if strings.Contains(output, "Machine") || strings.Contains(output, "MACAddress") {
+		t.Fatal("Expected the vbox:Machine to be deleted:\n" + output)
+	}
+
+	_, err := ToOvf(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package ovf
+
+// DifferencingDisks returns the differencing disks of an OVF, which store
+// the changes made since a snapshot was taken rather than a complete disk.
+// These are Disks that have a parentRef, and disk images in the
+// vbox:Machine's media registry that are based on another image. Disks
+// are identified by their DiskId, and images by their location.
+func DifferencingDisks(o Ovf) []string {
+	var disks []string
+
+	for _, disk := range o.Envelope.DiskSection.Disks {
+		if len(disk.ParentRef) > 0 {
+			disks = append(disks, disk.DiskId)
+		}
+	}
+
+	var addChildren func(hardDisks []VboxHardDisk)
+	addChildren = func(hardDisks []VboxHardDisk) {
+		for _, hardDisk := range hardDisks {
+			for _, child := range hardDisk.Children {
+				disks = append(disks, child.Location)
+			}
+
+			addChildren(hardDisk.Children)
+		}
+	}
+
+	addChildren(o.Envelope.VirtualSystem.Machine.MediaRegistry.HardDisks)
+
+	return disks
+}
+
+// HasVboxSnapshots returns true if the vbox:Machine contains snapshot
+// metadata.
+func HasVboxSnapshots(machine VboxMachine) bool {
+	return len(machine.Snapshots) > 0 || len(machine.CurrentSnapshot) > 0
+}
+
+// StripVboxSnapshotsFunc returns an EditObjectFunc that removes the
+// snapshot metadata from a vbox:Machine: its Snapshot elements (including
+// nested snapshots), and its 'currentSnapshot' attribute. It should be
+// proposed for the VboxMachineName.
+func StripVboxSnapshotsFunc() EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.RemoveAttr("currentSnapshot")
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		for {
+			err := o.DeleteChild("Snapshot")
+			if err != nil {
+				break
+			}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
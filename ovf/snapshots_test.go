@@ -0,0 +1,90 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testVboxSnapshots = `      <Snapshot uuid="{11111111-0000-0000-0000-000000000000}" name="Before update" timeStamp="2018-11-07T14:00:00Z">
+        <Hardware/>
+        <Snapshots>
+          <Snapshot uuid="{22222222-0000-0000-0000-000000000000}" name="After update" timeStamp="2018-11-07T14:30:00Z">
+            <Hardware/>
+          </Snapshot>
+        </Snapshots>
+      </Snapshot>
+`
+	testVboxMediaRegistry = `      <MediaRegistry>
+        <HardDisks>
+          <HardDisk uuid="{a80fb9c1-b029-4bf3-855e-79830aeeaade}" location="centos7-disk001.vmdk" format="VMDK" type="Normal">
+            <HardDisk uuid="{33333333-0000-0000-0000-000000000000}" location="Snapshots/{33333333-0000-0000-0000-000000000000}.vmdk" format="VMDK"/>
+          </HardDisk>
+        </HardDisks>
+      </MediaRegistry>
+`
+)
+
+func withVboxSnapshots(t *testing.T, contents string, snapshots string) string {
+	withSnapshots := strings.Replace(contents, `lastStateChange="2018-11-07T14:51:53Z">`,
+		`lastStateChange="2018-11-07T14:51:53Z" currentSnapshot="{22222222-0000-0000-0000-000000000000}">`, 1)
+	withSnapshots = strings.Replace(withSnapshots, "    </vbox:Machine>", snapshots+"    </vbox:Machine>", 1)
+	if withSnapshots == contents {
+		t.Fatal("Failed to find vbox:Machine in test data")
+	}
+
+	return withSnapshots
+}
+
+func TestStripVboxSnapshotsFunc(t *testing.T) {
+	withSnapshots := withVboxSnapshots(t, basicOvfFileContents, testVboxSnapshots)
+
+	o, err := ToOvf(strings.NewReader(withSnapshots))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	machine := o.Envelope.VirtualSystem.Machine
+	if !HasVboxSnapshots(machine) || len(machine.Snapshots) != 1 || machine.Snapshots[0].Name != "Before update" {
+		t.Fatalf("Got unexpected snapshots - %+v", machine.Snapshots)
+	}
+
+	b, err := EditRawOvf(strings.NewReader(withSnapshots),
+		NewEditScheme().Propose(StripVboxSnapshotsFunc(), VboxMachineName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != basicOvfFileContents {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
+
+func TestDifferencingDisks(t *testing.T) {
+	o, err := ToOvf(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if HasVboxSnapshots(o.Envelope.VirtualSystem.Machine) {
+		t.Fatal("Found snapshots in an OVF without snapshots")
+	}
+
+	disks := DifferencingDisks(o)
+	if len(disks) != 0 {
+		t.Fatalf("Expected no differencing disks, got %v", disks)
+	}
+
+	withDisks := withVboxSnapshots(t, basicOvfFileContents, testVboxMediaRegistry)
+	withDisks = strings.Replace(withDisks, `ovf:diskId="vmdisk1"`, `ovf:diskId="vmdisk1" ovf:parentRef="vmdisk0"`, 1)
+
+	o, err = ToOvf(strings.NewReader(withDisks))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	disks = DifferencingDisks(o)
+	if len(disks) != 2 || disks[0] != "vmdisk1" || !strings.HasPrefix(disks[1], "Snapshots/") {
+		t.Fatalf("Got unexpected differencing disks - %v", disks)
+	}
+}
@@ -0,0 +1,193 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// sortableItem is an Item found by SortItems, along with the bytes that
+// make it up.
+type sortableItem struct {
+	instanceId string
+	parent     string
+	start      int64
+	end        int64
+}
+
+// SortItems reorders the Items of each VirtualHardwareSection so that
+// every controller precedes the Items attached to it, and Items are
+// otherwise ordered by InstanceID. Some importers reject an Item whose
+// Parent has not been declared yet. InstanceIDs are compared as numbers
+// if they are numbers. The sort is stable, and Items that are part of a
+// Parent cycle, or whose Parent does not exist, are ordered by
+// InstanceID alone.
+//
+// Items are moved along with the whitespace and comments that precede
+// them, and are otherwise written exactly as they appeared. Other
+// elements of the VirtualHardwareSection (e.g., the System) are not
+// moved. The OVF is returned as it is, along with false, if its Items
+// are already in order.
+func SortItems(raw []byte) ([]byte, bool, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	var path Path
+	var sections [][]sortableItem
+	var current *sortableItem
+	var field *string
+
+	// siblingEnd is the offset at which the previous child of the
+	// current VirtualHardwareSection ended.
+	var siblingEnd int64
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			path = append(path, v.Name.Local)
+
+			switch {
+			case path.HasSuffix("VirtualSystem", "VirtualHardwareSection"):
+				sections = append(sections, nil)
+				siblingEnd = d.InputOffset()
+			case path.HasSuffix("VirtualSystem", "VirtualHardwareSection", "Item") && len(sections) > 0:
+				current = &sortableItem{start: siblingEnd}
+			case current != nil && path.Parent().HasSuffix("VirtualSystem", "VirtualHardwareSection", "Item"):
+				switch v.Name.Local {
+				case "InstanceID":
+					field = &current.instanceId
+				case "Parent":
+					field = &current.parent
+				}
+			}
+		case xml.CharData:
+			if field != nil {
+				*field += string(v)
+			}
+		case xml.EndElement:
+			field = nil
+
+			switch {
+			case path.HasSuffix("VirtualSystem", "VirtualHardwareSection", "Item") && current != nil:
+				current.end = d.InputOffset()
+				current.instanceId = strings.TrimSpace(current.instanceId)
+				current.parent = strings.TrimSpace(current.parent)
+
+				last := len(sections) - 1
+				sections[last] = append(sections[last], *current)
+				current = nil
+				siblingEnd = d.InputOffset()
+			case path.Parent().HasSuffix("VirtualSystem", "VirtualHardwareSection"):
+				siblingEnd = d.InputOffset()
+			}
+
+			path = path.Parent()
+		}
+	}
+
+	out := bytes.NewBuffer(nil)
+	var written int64
+	changed := false
+
+	for _, items := range sections {
+		ordered := orderItems(items)
+
+		for i := range items {
+			if ordered[i].start != items[i].start {
+				changed = true
+			}
+
+			// Each Item is written in the slot of the Item it
+			// replaces, so that other elements keep their place.
+			out.Write(raw[written:items[i].start])
+			out.Write(raw[ordered[i].start:ordered[i].end])
+			written = items[i].end
+		}
+	}
+
+	if !changed {
+		return raw, false, nil
+	}
+
+	out.Write(raw[written:])
+
+	return out.Bytes(), true, nil
+}
+
+// orderItems returns the Items of a VirtualHardwareSection in the order
+// described by SortItems.
+func orderItems(items []sortableItem) []sortableItem {
+	byId := append([]sortableItem(nil), items...)
+	sort.SliceStable(byId, func(i int, j int) bool {
+		return lessInstanceId(byId[i].instanceId, byId[j].instanceId)
+	})
+
+	ids := make(map[string]bool)
+	for _, item := range items {
+		ids[item.instanceId] = true
+	}
+
+	placed := make(map[string]bool)
+	used := make([]bool, len(byId))
+	ordered := make([]sortableItem, 0, len(byId))
+
+	for len(ordered) < len(byId) {
+		next := -1
+		for i, item := range byId {
+			if used[i] {
+				continue
+			}
+
+			if item.parent == item.instanceId || !ids[item.parent] || placed[item.parent] {
+				next = i
+				break
+			}
+		}
+
+		// The remaining Items are part of a cycle.
+		if next < 0 {
+			for i := range byId {
+				if !used[i] {
+					next = i
+					break
+				}
+			}
+		}
+
+		used[next] = true
+		placed[byId[next].instanceId] = true
+		ordered = append(ordered, byId[next])
+	}
+
+	return ordered
+}
+
+// lessInstanceId returns true if InstanceID a sorts before InstanceID b.
+// Numbers sort before other InstanceIDs.
+func lessInstanceId(a string, b string) bool {
+	aNum, aErr := strconv.ParseInt(a, 10, 64)
+	bNum, bErr := strconv.ParseInt(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return aNum < bNum
+	case aErr == nil:
+		return true
+	case bErr == nil:
+		return false
+	}
+
+	return a < b
+}
@@ -0,0 +1,115 @@
+package ovf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const unsortedItemsOvf = `<?xml version="1.0"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData">
+  <VirtualSystem>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <Item>
+        <rasd:ElementName>disk1</rasd:ElementName>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:Parent>10</rasd:Parent>
+      </Item>
+      <!-- The controller of disk1. -->
+      <Item>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <rasd:InstanceID>10</rasd:InstanceID>
+      </Item>
+      <vmw:Config xmlns:vmw="http://www.vmware.com/schema/ovf" vmw:key="firmware" vmw:value="efi"/>
+      <Item>
+        <rasd:ElementName>cpu</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+      </Item>
+      <Item>
+        <rasd:ElementName>cdrom</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:Parent>10</rasd:Parent>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestSortItems(t *testing.T) {
+	sorted, changed, err := SortItems([]byte(unsortedItemsOvf))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !changed {
+		t.Fatal("expected the Items to be reordered")
+	}
+
+	if len(sorted) != len(unsortedItemsOvf) {
+		t.Fatalf("expected the OVF to keep its length of %d - got %d:\n%s", len(unsortedItemsOvf), len(sorted), sorted)
+	}
+
+	var order []int
+	for _, name := range []string{">cpu<", "The controller of disk1", ">sataController0<", "<vmw:Config", ">disk1<", ">cdrom<"} {
+		order = append(order, bytes.Index(sorted, []byte(name)))
+	}
+
+	for i := 1; i < len(order); i++ {
+		if order[i-1] < 0 || order[i-1] > order[i] {
+			t.Fatalf("Items are not in order:\n%s", sorted)
+		}
+	}
+
+	parsed, err := ToOvf(bytes.NewReader(sorted))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var ids []string
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		ids = append(ids, item.InstanceID)
+	}
+
+	if strings.Join(ids, ",") != "1,10,2,3" {
+		t.Fatalf("expected Items in the order 1,10,2,3 - got %q", ids)
+	}
+
+	again, changed, err := SortItems(sorted)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if changed || !bytes.Equal(again, sorted) {
+		t.Fatal("expected sorted Items to be left as they are")
+	}
+}
+
+func TestSortItemsCycle(t *testing.T) {
+	raw := strings.Replace(strings.Replace(unsortedItemsOvf,
+		"<rasd:InstanceID>10</rasd:InstanceID>",
+		"<rasd:InstanceID>10</rasd:InstanceID>\n        <rasd:Parent>2</rasd:Parent>", 1),
+		"<rasd:InstanceID>1</rasd:InstanceID>",
+		"<rasd:InstanceID>1</rasd:InstanceID>\n        <rasd:Parent>404</rasd:Parent>", 1)
+
+	sorted, _, err := SortItems([]byte(raw))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ToOvf(bytes.NewReader(sorted))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var ids []string
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		ids = append(ids, item.InstanceID)
+	}
+
+	// Items 2 and 10 are parents of each other, so they are ordered
+	// by InstanceID, followed by the children of item 10.
+	if strings.Join(ids, ",") != "1,2,10,3" {
+		t.Fatalf("expected Items in the order 1,2,10,3 - got %q", ids)
+	}
+}
@@ -0,0 +1,150 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// StartupSectionName is the name of the section of a
+	// VirtualSystemCollection that describes the order in which its
+	// virtual machines are started and stopped.
+	StartupSectionName ObjectName = "StartupSection"
+
+	// PowerOnStartAction, PowerOffStopAction, GuestShutdownStopAction,
+	// and NoAction are the actions that can be taken when a virtual
+	// machine in a StartupSection is started or stopped.
+	PowerOnStartAction      = "powerOn"
+	PowerOffStopAction      = "powerOff"
+	GuestShutdownStopAction = "guestShutdown"
+	NoAction                = "none"
+)
+
+// StartupItem is an Item of a StartupSection, which describes when one
+// of the virtual machines (or nested collections) of a
+// VirtualSystemCollection is started and stopped.
+//
+// Be advised: VirtualSystemCollections are not otherwise supported by
+// this package (e.g., ToOvf only parses a single VirtualSystem).
+type StartupItem struct {
+	// Id is the ovf:id of the VirtualSystem or
+	// VirtualSystemCollection.
+	Id string `xml:"id,attr"`
+
+	// Order is the start order. Items with a lower order are started
+	// first, and items with the same order are started concurrently.
+	// Items are stopped in the reverse order.
+	Order int `xml:"order,attr"`
+
+	// StartDelay is the number of seconds to wait before starting
+	// the items with the next order.
+	StartDelay int `xml:"startDelay,attr,omitempty"`
+
+	// WaitingForGuest, when true, starts the items with the next
+	// order once the guest reports that it is ready (e.g., once
+	// VMWare Tools are running), or once StartDelay has elapsed.
+	WaitingForGuest bool `xml:"waitingForGuest,attr,omitempty"`
+
+	// StartAction is the action taken when the item is started
+	// (e.g., PowerOnStartAction). It is omitted if it is empty.
+	StartAction string `xml:"startAction,attr,omitempty"`
+
+	// StopDelay is the number of seconds to wait before stopping
+	// the items with the previous order.
+	StopDelay int `xml:"stopDelay,attr,omitempty"`
+
+	// StopAction is the action taken when the item is stopped (e.g.,
+	// GuestShutdownStopAction). It is omitted if it is empty.
+	StopAction string `xml:"stopAction,attr,omitempty"`
+}
+
+// SetStartupItemFunc returns an EditObjectFunc that sets the Item of a
+// StartupSection with the same Id as the specified StartupItem, adding
+// it if the section does not have one. It must be proposed for
+// StartupSectionName. A non-nil error is returned if the StartupItem's
+// Id is empty, or if its Order or delays are negative.
+func SetStartupItemFunc(item StartupItem) (EditObjectFunc, error) {
+	if len(item.Id) == 0 {
+		return nil, errors.New("the id of a startup item cannot be empty")
+	}
+
+	if item.Order < 0 || item.StartDelay < 0 || item.StopDelay < 0 {
+		return nil, errors.New("the order and delays of startup item '" + item.Id + "' cannot be negative")
+	}
+
+	raw := startupItem(item)
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// A missing item is not an error.
+		_ = o.DeleteChildWithAttr("Item", "id", item.Id)
+
+		err := o.InsertChild(raw)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}, nil
+}
+
+// StartupItems returns the Items of a StartupSection, in the order they
+// appear.
+func StartupItems(o *RawObject) ([]StartupItem, error) {
+	var section struct {
+		Items []StartupItem `xml:"Item"`
+	}
+
+	err := xmlutil.Unmarshal(o.Data().Bytes(), &section)
+	if err != nil {
+		return nil, err
+	}
+
+	return section.Items, nil
+}
+
+func startupItem(item StartupItem) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString(`<Item ovf:id="`)
+	xml.EscapeText(b, []byte(item.Id))
+	b.WriteString(`" ovf:order="` + strconv.Itoa(item.Order) + `"`)
+
+	if item.StartDelay > 0 {
+		b.WriteString(` ovf:startDelay="` + strconv.Itoa(item.StartDelay) + `"`)
+	}
+
+	if item.WaitingForGuest {
+		b.WriteString(` ovf:waitingForGuest="true"`)
+	}
+
+	if len(item.StartAction) > 0 {
+		b.WriteString(` ovf:startAction="`)
+		xml.EscapeText(b, []byte(item.StartAction))
+		b.WriteString(`"`)
+	}
+
+	if item.StopDelay > 0 {
+		b.WriteString(` ovf:stopDelay="` + strconv.Itoa(item.StopDelay) + `"`)
+	}
+
+	if len(item.StopAction) > 0 {
+		b.WriteString(` ovf:stopAction="`)
+		xml.EscapeText(b, []byte(item.StopAction))
+		b.WriteString(`"`)
+	}
+
+	b.WriteString("/>")
+
+	return b.Bytes()
+}
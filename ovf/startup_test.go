@@ -0,0 +1,93 @@
+package ovf
+
+import (
+	"testing"
+)
+
+const collectionOvfFileContents = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References/>
+  <VirtualSystemCollection ovf:id="appliance">
+    <Info>A multi-VM appliance</Info>
+    <StartupSection>
+      <Info>Virtual system startup order</Info>
+      <Item ovf:id="database" ovf:order="0" ovf:startDelay="30"/>
+      <Item ovf:id="web" ovf:order="0"/>
+    </StartupSection>
+    <VirtualSystem ovf:id="database">
+      <Info>A database</Info>
+    </VirtualSystem>
+    <VirtualSystem ovf:id="web">
+      <Info>A web server</Info>
+    </VirtualSystem>
+  </VirtualSystemCollection>
+</Envelope>
+`
+
+func TestSetStartupItemFunc(t *testing.T) {
+	setWeb, err := SetStartupItemFunc(StartupItem{
+		Id:              "web",
+		Order:           1,
+		StartDelay:      10,
+		WaitingForGuest: true,
+		StartAction:     PowerOnStartAction,
+		StopAction:      GuestShutdownStopAction,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	setCache, err := SetStartupItemFunc(StartupItem{Id: "cache", Order: 2})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := editRawOvfString(t, collectionOvfFileContents, NewEditScheme().
+		Propose(setWeb, StartupSectionName).
+		Propose(setCache, StartupSectionName))
+
+	var section *RawObject
+	findSection := func(i interface{}) EditObjectResult {
+		section, _ = i.(*RawObject)
+		return EditObjectResult{Action: NoOp}
+	}
+
+	editRawOvfString(t, output, NewEditScheme().Propose(findSection, StartupSectionName))
+	if section == nil {
+		t.Fatal("Failed to find the StartupSection:\n" + output)
+	}
+
+	items, err := StartupItems(section)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []StartupItem{
+		{Id: "database", StartDelay: 30},
+		{Id: "web", Order: 1, StartDelay: 10, WaitingForGuest: true,
+			StartAction: PowerOnStartAction, StopAction: GuestShutdownStopAction},
+		{Id: "cache", Order: 2},
+	}
+
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items - got %+v\n%s", len(expected), items, output)
+	}
+
+	for i := range expected {
+		if items[i] != expected[i] {
+			t.Fatalf("Expected item %d to be %+v - got %+v", i, expected[i], items[i])
+		}
+	}
+}
+
+func TestSetStartupItemFuncInvalidItem(t *testing.T) {
+	_, err := SetStartupItemFunc(StartupItem{Order: 1})
+	if err == nil {
+		t.Fatal("Expected an error for an empty id")
+	}
+
+	_, err = SetStartupItemFunc(StartupItem{Id: "web", StartDelay: -1})
+	if err == nil {
+		t.Fatal("Expected an error for a negative delay")
+	}
+}
@@ -0,0 +1,303 @@
+package ovf
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ideControllerPortCount is the number of devices that can be
+	// attached to an IDE controller (master and slave).
+	ideControllerPortCount = 2
+
+	// scsiControllerPortCount is the number of devices that can be
+	// attached to a SCSI controller. Unit number 7 is reserved for
+	// the controller itself.
+	scsiControllerPortCount = 16
+	scsiControllerUnit      = 7
+
+	// sataControllerPortCount is the number of devices that can be
+	// attached to a VMWare SATA controller.
+	sataControllerPortCount = 30
+
+	// nvmeControllerPortCount is the number of devices that can be
+	// attached to a VMWare NVMe controller.
+	nvmeControllerPortCount = 15
+
+	// diskHostResourcePrefix prefixes the DiskId in the HostResource
+	// of an Item that a Disk is attached to. The 'ovf:' scheme is
+	// optional (VirtualBox omits it).
+	diskHostResourcePrefix = "/disk/"
+)
+
+// IsStorageController returns true if the Item is a storage controller
+// that other devices can be attached to.
+func IsStorageController(item Item) bool {
+	switch item.ResourceType {
+	case IdeControllerResourceType, ParallelScsiHbaResourceType, SataControllerResourceType:
+		return true
+	}
+
+	return false
+}
+
+// StorageControllerPortCount returns the maximum number of devices that
+// can be attached to a storage controller Item, and a slice of reserved
+// addresses that cannot be assigned to devices.
+func StorageControllerPortCount(controller Item) (int, []int) {
+	switch controller.ResourceType {
+	case IdeControllerResourceType:
+		return ideControllerPortCount, nil
+	case ParallelScsiHbaResourceType:
+		return scsiControllerPortCount, []int{scsiControllerUnit}
+	}
+
+	if strings.Contains(strings.ToLower(controller.ResourceSubType), "nvme") {
+		return nvmeControllerPortCount, nil
+	}
+
+	return sataControllerPortCount, nil
+}
+
+// ReassignAddressesOnParentFunc returns an EditObjectFunc that ensures
+// every device attached to a storage controller has a unique, valid
+// AddressOnParent. Existing addresses are kept when possible. The provided
+// Ovf must represent the OVF that will be edited, and is used to determine
+// which devices are attached to each controller.
+//
+// A non-nil error is returned if a controller has more devices attached
+// to it than it has ports.
+func ReassignAddressesOnParentFunc(o Ovf) (EditObjectFunc, error) {
+	newAddresses, err := assignAddressesOnParent(o.Envelope.VirtualSystem.VirtualHardwareSection.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		address, ok := newAddresses[o.InstanceID]
+		if !ok || address == o.AddressOnParent {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		o.AddressOnParent = address
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &o,
+		}
+	}, nil
+}
+
+// assignAddressesOnParent returns a map of device InstanceID to the
+// AddressOnParent the device should be assigned.
+func assignAddressesOnParent(items []Item) (map[string]string, error) {
+	controllers := make(map[string]Item)
+	var controllerIds []string
+	for _, item := range items {
+		if IsStorageController(item) {
+			controllers[item.InstanceID] = item
+			controllerIds = append(controllerIds, item.InstanceID)
+		}
+	}
+
+	children := make(map[string][]Item)
+	for _, item := range items {
+		if _, ok := controllers[item.Parent]; ok && len(item.Parent) > 0 {
+			children[item.Parent] = append(children[item.Parent], item)
+		}
+	}
+
+	newAddresses := make(map[string]string)
+
+	for _, controllerId := range controllerIds {
+		controller := controllers[controllerId]
+		devices := children[controllerId]
+		portCount, reserved := StorageControllerPortCount(controller)
+
+		if len(devices) > portCount-len(reserved) {
+			return nil, errors.New("storage controller '" + controller.ElementName + "' has " +
+				strconv.Itoa(len(devices)) + " devices attached, but only has " +
+				strconv.Itoa(portCount-len(reserved)) + " ports")
+		}
+
+		used := make(map[int]bool)
+		for _, r := range reserved {
+			used[r] = true
+		}
+
+		// Keep valid, unique addresses in the order the devices appear.
+		var needAddress []Item
+		for _, device := range devices {
+			address, err := strconv.Atoi(device.AddressOnParent)
+			if err != nil || address < 0 || address >= portCount || used[address] {
+				needAddress = append(needAddress, device)
+				continue
+			}
+
+			used[address] = true
+			newAddresses[device.InstanceID] = device.AddressOnParent
+		}
+
+		var free []int
+		for port := 0; port < portCount; port++ {
+			if !used[port] {
+				free = append(free, port)
+			}
+		}
+
+		for i, device := range needAddress {
+			newAddresses[device.InstanceID] = strconv.Itoa(free[i])
+		}
+	}
+
+	return newAddresses, nil
+}
+
+// StoragePortsInUse returns the number of ports a storage controller
+// must have for all of the devices attached to it. This is the greater
+// of the number of attached devices and the highest AddressOnParent
+// plus one.
+func StoragePortsInUse(items []Item, controller Item) int {
+	inUse := 0
+	count := 0
+
+	for _, item := range items {
+		if item.Parent != controller.InstanceID || len(item.Parent) == 0 {
+			continue
+		}
+
+		count = count + 1
+
+		address, err := strconv.Atoi(item.AddressOnParent)
+		if err == nil && address+1 > inUse {
+			inUse = address + 1
+		}
+	}
+
+	if count > inUse {
+		return count
+	}
+
+	return inUse
+}
+
+// ExpandVboxStorageControllerPortCountFunc returns an EditObjectFunc that
+// raises the PortCount attribute of VirtualBox StorageController objects
+// of the specified type (e.g., 'AHCI') to at least the specified value.
+// The PortCount is never lowered.
+//
+// This does not affect VMWare, which ignores the vbox:Machine section and
+// has no equivalent setting: its SATA controllers always provide 30 ports,
+// and ReassignAddressesOnParentFunc fails if more devices are attached.
+// The vbox:Machine section is preserved for VirtualBox, which rejects OVFs
+// that attach devices to ports beyond the controller's PortCount.
+func ExpandVboxStorageControllerPortCountFunc(controllerType string, portCount int) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		t, _ := o.Attr("type")
+		if t != controllerType {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		current, _ := o.Attr("PortCount")
+		currentCount, err := strconv.Atoi(current)
+		if err == nil && currentCount >= portCount {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err = o.SetAttr("PortCount", strconv.Itoa(portCount))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// MoveStorageDevicesFunc returns an EditObjectFunc that attaches the
+// devices whose Parent is one of the specified controller InstanceIDs to
+// the controller with the InstanceID newParent. The devices'
+// AddressOnParent is cleared, so ReassignAddressesOnParentFunc must be
+// used afterwards to assign them a free address on the new controller.
+func MoveStorageDevicesFunc(parents []string, newParent string) EditObjectFunc {
+	isParent := make(map[string]bool)
+	for _, parent := range parents {
+		isParent[parent] = true
+	}
+
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok || len(o.Parent) == 0 || !isParent[o.Parent] {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		o.Parent = newParent
+		o.AddressOnParent = ""
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: &o,
+		}
+	}
+}
+
+// HostResourceDiskId returns the DiskId referenced by an Item's
+// HostResource (e.g., 'ovf:/disk/vmdisk1' or '/disk/vmdisk1').
+func HostResourceDiskId(hostResource string) (string, bool) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(hostResource), "ovf:")
+	if !strings.HasPrefix(trimmed, diskHostResourcePrefix) {
+		return "", false
+	}
+
+	diskId := strings.TrimPrefix(trimmed, diskHostResourcePrefix)
+	if len(diskId) == 0 {
+		return "", false
+	}
+
+	return diskId, true
+}
+
+// DiskAttachments returns the Items that each Disk is attached to, keyed
+// by DiskId, in the order the Items appear.
+func DiskAttachments(items []Item) map[string][]Item {
+	attachments := make(map[string][]Item)
+
+	for _, item := range items {
+		diskId, ok := HostResourceDiskId(item.HostResource)
+		if ok {
+			attachments[diskId] = append(attachments[diskId], item)
+		}
+	}
+
+	return attachments
+}
+
+// SharedDisks returns the sorted DiskIds of the Disks that are attached
+// to more than one Item (i.e., multi-attach disks).
+func SharedDisks(items []Item) []string {
+	var shared []string
+
+	for diskId, attached := range DiskAttachments(items) {
+		if len(attached) > 1 {
+			shared = append(shared, diskId)
+		}
+	}
+
+	sort.Strings(shared)
+
+	return shared
+}
@@ -0,0 +1,196 @@
+package ovf
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReassignAddressesOnParentFunc(t *testing.T) {
+	disk := `      <Item>
+        <rasd:AddressOnParent>%s</rasd:AddressOnParent>
+        <rasd:Caption>disk2</rasd:Caption>
+        <rasd:Description>Disk Image</rasd:Description>
+        <rasd:ElementName>disk2</rasd:ElementName>
+        <rasd:HostResource>/disk/vmdisk2</rasd:HostResource>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:Parent>5</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+`
+	insertAfter := "        <rasd:InstanceID>7</rasd:InstanceID>\n        <rasd:Parent>5</rasd:Parent>\n        <rasd:ResourceType>17</rasd:ResourceType>\n      </Item>\n"
+	if !strings.Contains(basicOvfFileContents, insertAfter) {
+		t.Fatal("Failed to find disk in test data")
+	}
+
+	colliding := strings.Replace(basicOvfFileContents, insertAfter, insertAfter+strings.Replace(disk, "%s", "0", 1), 1)
+
+	o, err := ToOvf(strings.NewReader(colliding))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	f, err := ReassignAddressesOnParentFunc(o)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := EditRawOvf(strings.NewReader(colliding), NewEditScheme().Propose(f, VirtualHardwareItemName))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, insertAfter, insertAfter+strings.Replace(disk, "%s", "1", 1), 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestReassignAddressesOnParentFuncTooManyDevices(t *testing.T) {
+	items := []Item{
+		{InstanceID: "1", ElementName: "ideController0", ResourceType: IdeControllerResourceType},
+		{InstanceID: "2", Parent: "1", AddressOnParent: "0", ResourceType: DiskDriveResourceType},
+		{InstanceID: "3", Parent: "1", AddressOnParent: "1", ResourceType: DiskDriveResourceType},
+		{InstanceID: "4", Parent: "1", AddressOnParent: "1", ResourceType: CdDriveResourceType},
+	}
+
+	o := Ovf{}
+	o.Envelope.VirtualSystem.VirtualHardwareSection.Items = items
+
+	_, err := ReassignAddressesOnParentFunc(o)
+	if err == nil {
+		t.Fatal("Expected an error when a controller has too many devices")
+	}
+}
+
+func TestReassignAddressesOnParentFuncTooManySataDevices(t *testing.T) {
+	items := []Item{
+		{InstanceID: "1", ElementName: "sataController0", ResourceType: SataControllerResourceType, ResourceSubType: "AHCI"},
+	}
+	for i := 0; i <= sataControllerPortCount; i++ {
+		items = append(items, Item{
+			InstanceID:      strconv.Itoa(i + 2),
+			Parent:          "1",
+			AddressOnParent: strconv.Itoa(i),
+			ResourceType:    DiskDriveResourceType,
+		})
+	}
+
+	o := Ovf{}
+	o.Envelope.VirtualSystem.VirtualHardwareSection.Items = items
+
+	_, err := ReassignAddressesOnParentFunc(o)
+	if err == nil {
+		t.Fatal("Expected an error when a SATA controller has more than " +
+			strconv.Itoa(sataControllerPortCount) + " devices")
+	}
+}
+
+func TestReassignAddressesOnParentFuncScsiReservedUnit(t *testing.T) {
+	items := []Item{
+		{InstanceID: "1", ElementName: "scsiController0", ResourceType: ParallelScsiHbaResourceType},
+	}
+	for i := 0; i < 8; i++ {
+		items = append(items, Item{
+			InstanceID:      "d" + string(rune('0'+i)),
+			Parent:          "1",
+			AddressOnParent: "0",
+			ResourceType:    DiskDriveResourceType,
+		})
+	}
+
+	addresses, err := assignAddressesOnParent(items)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		if address == "7" {
+			t.Fatal("Reserved SCSI unit 7 was assigned to a device")
+		}
+
+		if seen[address] {
+			t.Fatal("Address '" + address + "' was assigned more than once")
+		}
+		seen[address] = true
+	}
+}
+
+func TestExpandVboxStorageControllerPortCountFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(ExpandVboxStorageControllerPortCountFunc("AHCI", 3), VboxStorageControllerName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents,
+		`<StorageController name="SATA Controller" type="AHCI" PortCount="1"`,
+		`<StorageController name="SATA Controller" type="AHCI" PortCount="3"`, 1)
+	if expected == basicOvfFileContents {
+		t.Fatal("Failed to find SATA controller in test data")
+	}
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestStoragePortsInUse(t *testing.T) {
+	controller := Item{InstanceID: "1", ResourceType: OtherStorageDeviceResourceType}
+	items := []Item{
+		controller,
+		{InstanceID: "2", Parent: "1", AddressOnParent: "4"},
+		{InstanceID: "3", Parent: "1", AddressOnParent: "0"},
+		{InstanceID: "4", Parent: "9", AddressOnParent: "12"},
+	}
+
+	inUse := StoragePortsInUse(items, controller)
+	if inUse != 5 {
+		t.Fatalf("Expected 5 ports in use, got %d", inUse)
+	}
+}
+
+func TestSharedDisks(t *testing.T) {
+	items := []Item{
+		{InstanceID: "1", HostResource: "ovf:/disk/vmdisk1"},
+		{InstanceID: "2", HostResource: "/disk/vmdisk2"},
+		{InstanceID: "3", HostResource: "/disk/vmdisk1"},
+		{InstanceID: "4", HostResource: "ovf:/file/file1"},
+		{InstanceID: "5"},
+	}
+
+	attachments := DiskAttachments(items)
+	if len(attachments) != 2 || len(attachments["vmdisk1"]) != 2 || attachments["vmdisk1"][1].InstanceID != "3" {
+		t.Fatalf("Got unexpected attachments - %+v", attachments)
+	}
+
+	shared := SharedDisks(items)
+	if len(shared) != 1 || shared[0] != "vmdisk1" {
+		t.Fatalf("Got unexpected shared disks - %v", shared)
+	}
+}
+
+func TestMoveStorageDevicesFunc(t *testing.T) {
+	f := MoveStorageDevicesFunc([]string{"3", "4"}, "5")
+
+	result := f(Item{InstanceID: "9", Parent: "4", AddressOnParent: "1"})
+	if result.Action != Replace {
+		t.Fatal("Expected device attached to an IDE controller to be moved")
+	}
+
+	moved := result.Object.(*Item)
+	if moved.Parent != "5" || len(moved.AddressOnParent) > 0 {
+		t.Fatalf("Got unexpected moved device - %+v", moved)
+	}
+
+	for _, item := range []Item{{InstanceID: "7", Parent: "5"}, {InstanceID: "8"}} {
+		if f(item).Action != NoOp {
+			t.Fatalf("Expected device not to be moved - %+v", item)
+		}
+	}
+}
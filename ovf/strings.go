@@ -0,0 +1,204 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	// StringsSectionName is the name of a bundle of localized
+	// messages, which is a child of the Envelope.
+	StringsSectionName ObjectName = "Strings"
+)
+
+// Strings is a bundle of the messages of a locale (e.g., 'de-DE'). The
+// messages are referenced by the ovf:msgid attribute of elements whose
+// text can be localized (e.g., an Info or a Description). The text of
+// such an element is its message in the Envelope's default locale (the
+// Envelope's Lang). The messages of a bundle may instead be in an
+// external file, in which case FileRef is the Id of its File.
+type Strings struct {
+	XMLName  xml.Name `xml:"Strings"`
+	Lang     string   `xml:"lang,attr"`
+	FileRef  string   `xml:"fileRef,attr"`
+	Messages []Msg    `xml:"Msg"`
+}
+
+// Msg is a localized message of a Strings bundle.
+type Msg struct {
+	XMLName xml.Name `xml:"Msg"`
+	MsgId   string   `xml:"msgid,attr"`
+	Text    string   `xml:",chardata"`
+}
+
+// Locales returns the locales of the Envelope's Strings bundles, in the
+// order they appear.
+func (o Envelope) Locales() []string {
+	var locales []string
+	for _, bundle := range o.Strings {
+		locales = append(locales, bundle.Lang)
+	}
+
+	return locales
+}
+
+// LocalizedString returns the message with the specified ovf:msgid in
+// the Strings bundle of a locale (e.g., 'de-DE'). Locales are compared
+// without regard to case. False is returned if the Envelope does not
+// contain the message, including if the bundle is in an external file.
+func (o Envelope) LocalizedString(locale string, msgId string) (string, bool) {
+	for _, bundle := range o.Strings {
+		if !strings.EqualFold(bundle.Lang, locale) {
+			continue
+		}
+
+		for _, msg := range bundle.Messages {
+			if msg.MsgId == msgId {
+				return msg.Text, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Localize reads an OVF from r and writes it to w with its localization
+// flattened to the specified locale (e.g., 'de-DE'). The text of each
+// element with an ovf:msgid is replaced by the locale's message, its
+// ovf:msgid attribute is removed, and the Strings bundles are deleted.
+// Elements whose message is not in the locale's bundle keep their text.
+// Specifying the Envelope's default locale, or an empty locale, keeps
+// the text of every element.
+//
+// The Files of external bundles are not removed from the References. A
+// non-nil error is returned if the locale's bundle is in an external
+// file, or if the OVF does not contain the locale.
+func Localize(r io.Reader, w io.Writer, locale string) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := ToOvf(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	env := parsed.Envelope
+
+	if len(locale) > 0 && !strings.EqualFold(locale, env.Lang) {
+		found := false
+		for _, bundle := range env.Strings {
+			if !strings.EqualFold(bundle.Lang, locale) {
+				continue
+			}
+
+			if len(bundle.FileRef) > 0 {
+				return errors.New("the strings of locale '" + locale + "' are in the external file '" +
+					bundle.FileRef + "', which is not supported")
+			}
+
+			found = true
+		}
+
+		if !found {
+			return errors.New("the ovf does not contain strings for locale '" + locale +
+				"' (it contains: '" + strings.Join(append([]string{env.Lang}, env.Locales()...), "', '") + "')")
+		}
+	} else {
+		locale = ""
+	}
+
+	// localizing is the depth of the element whose text is being
+	// replaced, or zero.
+	localizing := 0
+	wroteText := false
+	var text string
+
+	flattened := bytes.NewBuffer(nil)
+	err = Transform(bytes.NewReader(raw), flattened, func(tok xml.Token, ctx Path) ([]xml.Token, error) {
+		// The bundles are deleted once they are no longer needed.
+		if len(ctx) > 1 && ctx[1] == StringsSectionName.String() {
+			return []xml.Token{tok}, nil
+		}
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			msgId, ok := removeMsgId(&v)
+			if !ok {
+				return []xml.Token{v}, nil
+			}
+
+			text, ok = env.LocalizedString(locale, msgId)
+			if ok {
+				localizing = len(ctx)
+				wroteText = false
+			}
+
+			return []xml.Token{v}, nil
+		case xml.CharData:
+			if localizing == 0 || len(ctx) != localizing {
+				return []xml.Token{v}, nil
+			}
+
+			if wroteText {
+				return nil, nil
+			}
+
+			wroteText = true
+
+			return []xml.Token{xml.CharData(text)}, nil
+		case xml.EndElement:
+			if localizing == 0 || len(ctx) != localizing {
+				return []xml.Token{v}, nil
+			}
+
+			localizing = 0
+			if wroteText {
+				return []xml.Token{v}, nil
+			}
+
+			return []xml.Token{xml.CharData(text), v}, nil
+		}
+
+		return []xml.Token{tok}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	deleteStrings := func(i interface{}) EditObjectResult {
+		_, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{Action: Delete}
+	}
+
+	edited, err := EditRawOvf(flattened, NewPathEditScheme().
+		ProposeUnder(deleteStrings, StringsSectionName, EnvelopeName.String()))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(edited.Bytes())
+
+	return err
+}
+
+// removeMsgId removes the ovf:msgid attribute of an element, returning
+// its value and true if the element had one.
+func removeMsgId(start *xml.StartElement) (string, bool) {
+	for i, attr := range start.Attr {
+		if attr.Name.Local == "msgid" {
+			start.Attr = append(start.Attr[:i], start.Attr[i+1:]...)
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,129 @@
+package ovf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// localizedOvf returns the basic OVF with localized text and a 'de-DE'
+// Strings bundle.
+func localizedOvf(t *testing.T) string {
+	input := strings.NewReplacer(
+		"<Info>A virtual machine</Info>",
+		`<Info ovf:msgid="vm.info">A virtual machine</Info>`,
+		"<rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>",
+		`<rasd:Caption ovf:msgid="nic.caption">Ethernet adapter on 'NAT'</rasd:Caption>`,
+		"</VirtualSystem>\n</Envelope>", `</VirtualSystem>
+  <Strings xml:lang="de-DE">
+    <Msg ovf:msgid="vm.info">Eine virtuelle Maschine</Msg>
+    <Msg ovf:msgid="nic.caption">Ethernet-Adapter an 'NAT'</Msg>
+  </Strings>
+</Envelope>`).Replace(basicOvfFileContents)
+	if strings.Count(input, "msgid") != 4 {
+		t.Fatal("Failed to localize the test input")
+	}
+
+	return input
+}
+
+func TestToOvfStrings(t *testing.T) {
+	parsed, err := ToOvf(strings.NewReader(localizedOvf(t)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	locales := parsed.Envelope.Locales()
+	if len(locales) != 1 || locales[0] != "de-DE" {
+		t.Fatalf("Got unexpected locales - %v", locales)
+	}
+
+	msg, ok := parsed.Envelope.LocalizedString("de-de", "vm.info")
+	if !ok || msg != "Eine virtuelle Maschine" {
+		t.Fatal("Got unexpected message '" + msg + "'")
+	}
+
+	_, ok = parsed.Envelope.LocalizedString("fr-FR", "vm.info")
+	if ok {
+		t.Fatal("Expected no message for a missing locale")
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	err := Localize(strings.NewReader(localizedOvf(t)), b, "de-DE")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := b.String()
+
+	for _, expected := range []string{"<Info>Eine virtuelle Maschine</Info>",
+		"<rasd:Caption>Ethernet-Adapter an 'NAT'</rasd:Caption>",
+		"<rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>",
+		"  </VirtualSystem>\n</Envelope>"} {
+		if !strings.Contains(output, expected) {
+			t.Fatal("Expected the output to contain '" + expected + "':\n" + output)
+		}
+	}
+
+	for _, removed := range []string{"msgid", "Strings"} {
+		if strings.Contains(output, removed) {
+			t.Fatal("Expected '" + removed + "' to be removed:\n" + output)
+		}
+	}
+}
+
+func TestLocalizeDefaultLocale(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	err := Localize(strings.NewReader(localizedOvf(t)), b, "en-US")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != basicOvfFileContents {
+		t.Fatal("Expected the localization to be removed:\n" + b.String())
+	}
+}
+
+func TestLocalizeMissingLocale(t *testing.T) {
+	err := Localize(strings.NewReader(localizedOvf(t)), bytes.NewBuffer(nil), "fr-FR")
+	if err == nil {
+		t.Fatal("Expected an error for a missing locale")
+	}
+
+	external := strings.Replace(localizedOvf(t), `<Strings xml:lang="de-DE">`,
+		`<Strings xml:lang="fr-FR" ovf:fileRef="fr-strings"/>
+  <Strings xml:lang="de-DE">`, 1)
+
+	err = Localize(strings.NewReader(external), bytes.NewBuffer(nil), "fr-FR")
+	if err == nil || !strings.Contains(err.Error(), "external file 'fr-strings'") {
+		t.Fatalf("Got unexpected error - %v", err)
+	}
+}
+
+func TestEditRawOvfKeepsLocalization(t *testing.T) {
+	input := localizedOvf(t)
+
+	var warnings []string
+	b, err := EditRawOvfWithConfig(strings.NewReader(input), NewEditScheme().
+		Propose(SetHardwareItemsResourceSubTypeFunc(EthernetAdapterResourceType, "VmxNet3"), VirtualHardwareItemName).
+		Propose(SetNetworkConnectionFunc("NAT", "Bridged"), NetworkName),
+		EditConfig{OnWarning: func(warning string) { warnings = append(warnings, warning) }})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := b.String()
+
+	for _, expected := range []string{`<Info ovf:msgid="vm.info">A virtual machine</Info>`,
+		input[strings.Index(input, "  <Strings"):]} {
+		if !strings.Contains(output, expected) {
+			t.Fatal("Expected the output to contain '" + expected + "':\n" + output)
+		}
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "ovf:msgid of its 'rasd:Caption'") {
+		t.Fatalf("Got unexpected warnings - %v", warnings)
+	}
+}
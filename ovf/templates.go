@@ -1,6 +1,7 @@
 package ovf
 
 import (
+	"encoding/xml"
 	"strings"
 )
 
@@ -10,10 +11,7 @@ func SetVirtualSystemTypeFunc(newVirtualSystemType string) EditObjectFunc {
 	return func(i interface{}) EditObjectResult {
 		o, ok := i.(System)
 		if !ok {
-			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
-			}
+			return EditObjectResult{Action: NoOp}
 		}
 
 		o.VirtualSystemType = newVirtualSystemType
@@ -32,19 +30,13 @@ func DeleteHardwareItemsMatchingFunc(elementNamePrefix string, limit int) EditOb
 	deleteFunc := deleteHardwareItemsMatchingFunc(elementNamePrefix)
 
 	return func(i interface{}) EditObjectResult {
-		o, ok := i.(Item)
+		_, ok := i.(Item)
 		if !ok {
-			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
-			}
+			return EditObjectResult{Action: NoOp}
 		}
 
 		if limit == 0 {
-			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
-			}
+			return EditObjectResult{Action: NoOp}
 		}
 
 		result := deleteFunc(i)
@@ -60,23 +52,14 @@ func deleteHardwareItemsMatchingFunc(elementNamePrefix string) EditObjectFunc {
 	return func(i interface{}) EditObjectResult {
 		o, ok := i.(Item)
 		if !ok {
-			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
-			}
+			return EditObjectResult{Action: NoOp}
 		}
 
 		if strings.HasPrefix(o.ElementName, elementNamePrefix) {
-			return EditObjectResult{
-				Action: Delete,
-				Object: &o,
-			}
+			return EditObjectResult{Action: Delete}
 		}
 
-		return EditObjectResult{
-			Action: NoOp,
-			Object: &o,
-		}
+		return EditObjectResult{Action: NoOp}
 	}
 }
 
@@ -86,23 +69,55 @@ func ReplaceHardwareItemFunc(elementName string, replacement Item) EditObjectFun
 	return func(i interface{}) EditObjectResult {
 		o, ok := i.(Item)
 		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		if o.ElementName == elementName {
 			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
+				Action: Replace,
+				Object: &replacement,
 			}
 		}
 
-		if o.ElementName == elementName {
+		return EditObjectResult{Action: NoOp}
+	}
+}
+
+// ReplaceHardwareItemByInstanceIDFunc returns an EditObjectFunc that
+// replaces the OVF Item with the specified InstanceID. Unlike element
+// names, InstanceIDs are unique and do not vary by locale.
+func ReplaceHardwareItemByInstanceIDFunc(instanceID string, replacement Item) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		if o.InstanceID == instanceID {
 			return EditObjectResult{
 				Action: Replace,
 				Object: &replacement,
 			}
 		}
 
-		return EditObjectResult{
-			Action: NoOp,
-			Object: &o,
+		return EditObjectResult{Action: NoOp}
+	}
+}
+
+// DeleteHardwareItemByInstanceIDFunc returns an EditObjectFunc that
+// deletes the OVF Item with the specified InstanceID.
+func DeleteHardwareItemByInstanceIDFunc(instanceID string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(Item)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		if o.InstanceID == instanceID {
+			return EditObjectResult{Action: Delete}
 		}
+
+		return EditObjectResult{Action: NoOp}
 	}
 }
 
@@ -112,10 +127,7 @@ func ModifyHardwareItemsOfResourceTypeFunc(resourceType string, modifyFunc func(
 	return func(i interface{}) EditObjectResult {
 		o, ok := i.(Item)
 		if !ok {
-			return EditObjectResult{
-				Action: NoOp,
-				Object: &o,
-			}
+			return EditObjectResult{Action: NoOp}
 		}
 
 		if o.ResourceType == resourceType {
@@ -127,9 +139,43 @@ func ModifyHardwareItemsOfResourceTypeFunc(resourceType string, modifyFunc func(
 			}
 		}
 
+		return EditObjectResult{Action: NoOp}
+	}
+}
+
+// SetHardwareItemsResourceSubTypeFunc returns an EditObjectFunc that sets
+// the ResourceSubType of OVF Item of a certain resource type (e.g., to set
+// the model of Ethernet adapters).
+func SetHardwareItemsResourceSubTypeFunc(resourceType string, resourceSubType string) EditObjectFunc {
+	return ModifyHardwareItemsOfResourceTypeFunc(resourceType, func(i Item) Item {
+		i.ResourceSubType = resourceSubType
+		return i
+	})
+}
+
+// AddHardwareItemFunc returns an EditObjectFunc that adds an Item to the
+// VirtualHardwareSection. The Item's InstanceID must not be used by
+// another Item.
+func AddHardwareItemFunc(item Item) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		raw, err := xml.MarshalIndent(item.Marshallable(), "", o.RelativeBodyPrefix())
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err = o.InsertChild(raw)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
 		return EditObjectResult{
-			Action: NoOp,
-			Object: &o,
+			Action: Replace,
+			Object: o,
 		}
 	}
 }
@@ -0,0 +1,297 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// Path is the path of an element, made up of the local names of the
+// element and its ancestors (e.g., 'Envelope', 'VirtualSystem').
+type Path []string
+
+// String returns the path's names separated by slashes (e.g.,
+// 'Envelope/VirtualSystem/VirtualHardwareSection/Item').
+func (o Path) String() string {
+	return strings.Join(o, "/")
+}
+
+// Parent returns the path of the element's parent. The parent of the
+// root element is an empty Path.
+func (o Path) Parent() Path {
+	if len(o) == 0 {
+		return nil
+	}
+
+	return o[:len(o)-1]
+}
+
+// HasSuffix returns true if the path ends with the specified local names
+// (e.g., 'VirtualHardwareSection', 'Item').
+func (o Path) HasSuffix(names ...string) bool {
+	if len(names) > len(o) {
+		return false
+	}
+
+	offset := len(o) - len(names)
+	for i, name := range names {
+		if o[offset+i] != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TransformFunc is called by Transform with each token of an OVF, and the
+// Path of the element that the token belongs to. The Path of a
+// StartElement or EndElement includes the element itself. The returned
+// tokens are written in place of the token, meaning that returning nil
+// removes it, and returning several tokens inserts the extra tokens.
+//
+// Tokens are provided in the same form as xml.Decoder.RawToken, meaning
+// that the Space of a name is its namespace prefix (e.g., 'ovf') rather
+// than the namespace URL. The token is a copy, and may be modified. The
+// Path must not be retained.
+type TransformFunc func(tok xml.Token, ctx Path) ([]xml.Token, error)
+
+// Transform reads an OVF from r, calls the TransformFunc with each of its
+// tokens, and writes the resulting tokens to w. It is a low-level
+// alternative to an EditScheme for edits that an EditScheme cannot make.
+//
+// The OVF is checked in the same manner as EditRawOvf before any tokens
+// are provided (e.g., DOCTYPE declarations are rejected). Tokens that
+// are returned unchanged are written exactly as they appeared, including
+// their formatting. Other tokens are re-encoded, which does not preserve
+// details such as attribute quoting. Nothing is written to w if an error
+// occurs, and the TransformFunc's output is not checked, meaning that it
+// is responsible for producing well-formed XML.
+func Transform(r io.Reader, w io.Writer, f TransformFunc) error {
+	return TransformWithConfig(r, w, f, EditConfig{})
+}
+
+// TransformWithConfig is the equivalent of Transform that bounds the OVF
+// using the EditConfig's Limits.
+func TransformWithConfig(r io.Reader, w io.Writer, f TransformFunc, config EditConfig) error {
+	raw, err := readLimited(r, config.Limits)
+	if err != nil {
+		return err
+	}
+
+	err = xmlutil.ValidateFormatting(raw)
+	if err != nil {
+		return err
+	}
+
+	err = CheckLimits(raw, config.Limits)
+	if err != nil {
+		return err
+	}
+
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+	out := bytes.NewBuffer(nil)
+
+	var path Path
+
+	// selfClosed tracks whether each open element was written as a
+	// self-closing element, which must not be closed again.
+	var selfClosed []bool
+
+	for {
+		start := d.InputOffset()
+
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rawTok := raw[start:d.InputOffset()]
+
+		var original xml.EndElement
+		isEnd := false
+
+		switch v := tok.(type) {
+		case xml.StartElement:
+			path = append(path, v.Name.Local)
+		case xml.EndElement:
+			original = v
+			isEnd = true
+		}
+
+		results, err := f(xml.CopyToken(tok), path)
+		if err != nil {
+			return err
+		}
+
+		for i, result := range results {
+			if result == nil {
+				return errors.New("transform func returned a nil token for '" + path.String() + "'")
+			}
+
+			unchanged := len(results) == 1 && tokensEqual(result, tok)
+
+			switch v := result.(type) {
+			case xml.StartElement:
+				// Only the element that was read can be
+				// written as it appeared (which may be
+				// self-closing).
+				if i == 0 && unchanged {
+					out.Write(rawTok)
+					selfClosed = append(selfClosed, bytes.HasSuffix(rawTok, []byte("/>")))
+					continue
+				}
+
+				selfClosed = append(selfClosed, false)
+				encodeToken(out, v)
+				continue
+			case xml.EndElement:
+				if len(selfClosed) == 0 {
+					return errors.New("transform func returned an unexpected end element for '" +
+						path.String() + "'")
+				}
+
+				wasSelfClosed := selfClosed[len(selfClosed)-1]
+				selfClosed = selfClosed[:len(selfClosed)-1]
+
+				if isEnd && wasSelfClosed && tokensEqual(v, original) {
+					continue
+				}
+
+				// The end of a self-closing element that was
+				// read has no XML of its own.
+				if unchanged && len(rawTok) > 0 {
+					out.Write(rawTok)
+				} else {
+					encodeToken(out, v)
+				}
+				continue
+			}
+
+			if unchanged {
+				out.Write(rawTok)
+				continue
+			}
+
+			encodeToken(out, result)
+		}
+
+		if isEnd {
+			path = path.Parent()
+		}
+	}
+
+	_, err = w.Write(out.Bytes())
+
+	return err
+}
+
+// tokensEqual returns true if two tokens are the same.
+func tokensEqual(a xml.Token, b xml.Token) bool {
+	switch v := a.(type) {
+	case xml.StartElement:
+		o, ok := b.(xml.StartElement)
+		if !ok || v.Name != o.Name || len(v.Attr) != len(o.Attr) {
+			return false
+		}
+
+		for i := range v.Attr {
+			if v.Attr[i] != o.Attr[i] {
+				return false
+			}
+		}
+
+		return true
+	case xml.EndElement:
+		o, ok := b.(xml.EndElement)
+		return ok && v == o
+	case xml.CharData:
+		o, ok := b.(xml.CharData)
+		return ok && bytes.Equal(v, o)
+	case xml.Comment:
+		o, ok := b.(xml.Comment)
+		return ok && bytes.Equal(v, o)
+	case xml.ProcInst:
+		o, ok := b.(xml.ProcInst)
+		return ok && v.Target == o.Target && bytes.Equal(v.Inst, o.Inst)
+	case xml.Directive:
+		o, ok := b.(xml.Directive)
+		return ok && bytes.Equal(v, o)
+	}
+
+	return false
+}
+
+// encodeToken writes the XML of a token. Names are written with their
+// Space as a namespace prefix.
+func encodeToken(w *bytes.Buffer, tok xml.Token) {
+	switch v := tok.(type) {
+	case xml.StartElement:
+		w.WriteString("<" + prefixedName(v.Name))
+		for _, attr := range v.Attr {
+			w.WriteString(" " + prefixedName(attr.Name) + `="`)
+			escapeTokenText(w, attr.Value, true)
+			w.WriteString(`"`)
+		}
+		w.WriteString(">")
+	case xml.EndElement:
+		w.WriteString("</" + prefixedName(v.Name) + ">")
+	case xml.CharData:
+		escapeTokenText(w, string(v), false)
+	case xml.Comment:
+		w.WriteString("<!--")
+		w.Write(v)
+		w.WriteString("-->")
+	case xml.ProcInst:
+		w.WriteString("<?" + v.Target)
+		if len(v.Inst) > 0 {
+			w.WriteString(" ")
+			w.Write(v.Inst)
+		}
+		w.WriteString("?>")
+	case xml.Directive:
+		w.WriteString("<!")
+		w.Write(v)
+		w.WriteString(">")
+	}
+}
+
+func prefixedName(name xml.Name) string {
+	if len(name.Space) == 0 {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// escapeTokenText escapes character data. Unlike xml.EscapeText, line
+// breaks are only escaped in attribute values, where they would
+// otherwise be normalized to spaces.
+func escapeTokenText(w *bytes.Buffer, s string, isAttr bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			w.WriteString("&amp;")
+		case r == '<':
+			w.WriteString("&lt;")
+		case r == '>':
+			w.WriteString("&gt;")
+		case r == '"' && isAttr:
+			w.WriteString("&quot;")
+		case r == '\n' && isAttr:
+			w.WriteString("&#xA;")
+		case r == '\r':
+			w.WriteString("&#xD;")
+		case r == '\t' && isAttr:
+			w.WriteString("&#x9;")
+		default:
+			w.WriteRune(r)
+		}
+	}
+}
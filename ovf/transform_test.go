@@ -0,0 +1,140 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func identityTransform(tok xml.Token, ctx Path) ([]xml.Token, error) {
+	return []xml.Token{tok}, nil
+}
+
+func TestTransformIdentity(t *testing.T) {
+	for _, fixture := range []string{basicOvfFileContents, strings.Replace(basicOvfFileContents, "\n", "\r\n", -1)} {
+		out := bytes.NewBuffer(nil)
+
+		err := Transform(strings.NewReader(fixture), out, identityTransform)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if out.String() != fixture {
+			t.Fatalf("identity transform modified the OVF:\n%q", out.String())
+		}
+	}
+}
+
+func TestTransformPath(t *testing.T) {
+	var paths []string
+
+	err := Transform(strings.NewReader(basicOvfFileContents), bytes.NewBuffer(nil), func(tok xml.Token, ctx Path) ([]xml.Token, error) {
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "Item" {
+			paths = append(paths, ctx.String())
+
+			if !ctx.Parent().HasSuffix("VirtualSystem", "VirtualHardwareSection") {
+				t.Fatalf("unexpected parent path '%s'", ctx.Parent())
+			}
+		}
+
+		return []xml.Token{tok}, nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(paths) == 0 || paths[0] != "Envelope/VirtualSystem/VirtualHardwareSection/Item" {
+		t.Fatalf("got unexpected paths %q", paths)
+	}
+}
+
+func TestTransformEdits(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+
+	err := Transform(strings.NewReader(basicOvfFileContents), out, func(tok xml.Token, ctx Path) ([]xml.Token, error) {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			// A self-closing element that is modified.
+			if v.Name.Local == "File" {
+				for i := range v.Attr {
+					if v.Attr[i].Name.Local == "href" {
+						v.Attr[i].Value = `disk "1".vmdk`
+					}
+				}
+
+				return []xml.Token{v}, nil
+			}
+		case xml.CharData:
+			if ctx.HasSuffix("System", "VirtualSystemType") {
+				return []xml.Token{xml.CharData("vmx-14 & later")}, nil
+			}
+		case xml.Comment:
+			return nil, nil
+		case xml.EndElement:
+			// Insert an element after the NetworkSection.
+			if v.Name.Local == "NetworkSection" {
+				return []xml.Token{
+					v,
+					xml.CharData("\n  "),
+					xml.StartElement{Name: xml.Name{Space: "vmw", Local: "Extra"}},
+					xml.EndElement{Name: xml.Name{Space: "vmw", Local: "Extra"}},
+				}, nil
+			}
+		}
+
+		return []xml.Token{tok}, nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := out.String()
+
+	expected := []string{
+		`<File ovf:id="file1" ovf:href="disk &quot;1&quot;.vmdk"></File>`,
+		`<vssd:VirtualSystemType>vmx-14 &amp; later</vssd:VirtualSystemType>`,
+		"</NetworkSection>\n  <vmw:Extra></vmw:Extra>\n",
+		`<Disk ovf:capacity="68719476736" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized" vbox:uuid="a80fb9c1-b029-4bf3-855e-79830aeeaade"/>`,
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(result, s) {
+			t.Fatalf("expected result to contain '%s' - got:\n%s", s, result)
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	expectedErr := errors.New("stop")
+
+	out := bytes.NewBuffer(nil)
+	err := Transform(strings.NewReader(basicOvfFileContents), out, func(tok xml.Token, ctx Path) ([]xml.Token, error) {
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "Item" {
+			return nil, expectedErr
+		}
+
+		return []xml.Token{tok}, nil
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("expected the transform func's error - got %v", err)
+	}
+
+	if out.Len() > 0 {
+		t.Fatal("output was written despite an error")
+	}
+
+	doctype := `<?xml version="1.0"?><!DOCTYPE Envelope [<!ENTITY x "y">]><Envelope/>`
+	err = Transform(strings.NewReader(doctype), out, identityTransform)
+	if err == nil {
+		t.Fatal("expected an error for a DOCTYPE with an internal subset")
+	}
+
+	err = Transform(strings.NewReader(basicOvfFileContents), out, func(tok xml.Token, ctx Path) ([]xml.Token, error) {
+		return []xml.Token{nil}, nil
+	})
+	if err == nil {
+		t.Fatal("expected an error for a nil token")
+	}
+}
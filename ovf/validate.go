@@ -0,0 +1,117 @@
+package ovf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// Finding describes a problem found in an OVF by Validate.
+type Finding struct {
+	// Line is the 1-based line number of the problem, or 0 if
+	// the problem does not have a location (e.g., the OVF exceeds
+	// a limit).
+	Line int
+
+	// Column is the 1-based column number of the problem, or 0 if
+	// the problem does not have a location.
+	Column int
+
+	// Path is the path of the element that caused the problem
+	// (see ParseError), if known.
+	Path string
+
+	// Rule is the name of the Rule that found the problem, or an
+	// empty string if the problem prevents the OVF from being
+	// parsed.
+	Rule string
+
+	// Err describes the problem.
+	Err error
+}
+
+// Validate reads an OVF and returns the problems that would prevent it
+// from being edited or parsed, given the specified Limits. Formatting
+// errors and exceeded limits stop the validation, so at most one Finding
+// is returned for them. If the OVF can be parsed, the references
+// between its objects are checked using CheckIntegrity. A non-nil error
+// is only returned if the OVF cannot be read.
+func Validate(r io.Reader, limits Limits) ([]Finding, error) {
+	return ValidateWithRules(r, limits, NewRuleSet())
+}
+
+// ValidateWithRules is the equivalent of Validate that checks the OVF
+// using the specified RuleSet rather than NewRuleSet, if it can be
+// parsed.
+func ValidateWithRules(r io.Reader, limits Limits, rules *RuleSet) ([]Finding, error) {
+	raw, err := readLimited(r, limits)
+	if err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return []Finding{{Err: err}}, nil
+		}
+
+		return nil, err
+	}
+
+	err = xmlutil.ValidateFormatting(raw)
+	if err != nil {
+		finding := Finding{Err: err}
+
+		var formattingErr *xmlutil.FormattingError
+		if errors.As(err, &formattingErr) {
+			finding.Line = formattingErr.Line
+			finding.Column = formattingErr.Column
+			finding.Err = formattingErr.Err
+		}
+
+		return []Finding{finding}, nil
+	}
+
+	err = CheckLimits(raw, limits)
+	if err != nil {
+		return []Finding{{Err: err}}, nil
+	}
+
+	parsed, err := parseOvf(raw, true)
+	if err == nil {
+		return rules.Check(&Ovf{Envelope: parsed}), nil
+	}
+
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var findings []Finding
+	for _, err := range errs {
+		finding := Finding{Err: err}
+
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			finding.Line, finding.Column = lineAndColumn(raw, parseErr.Offset)
+			finding.Path = parseErr.Path
+			finding.Err = parseErr.Err
+		}
+
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// lineAndColumn returns the 1-based line and column numbers of a byte
+// offset.
+func lineAndColumn(raw []byte, offset int64) (int, int) {
+	if offset > int64(len(raw)) {
+		offset = int64(len(raw))
+	}
+
+	before := raw[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
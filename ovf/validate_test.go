@@ -0,0 +1,52 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	findings, err := Validate(strings.NewReader(basicOvfFileContents), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	input := strings.Replace(basicOvfFileContents, "<rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>",
+		"<rasd:AutomaticAllocation>junk</rasd:AutomaticAllocation>", 1)
+
+	findings, err = Validate(strings.NewReader(input), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	junkIndex := strings.Index(input, "junk")
+	itemIndex := strings.LastIndex(input[:junkIndex], "<Item>")
+	expectedLine := strings.Count(input[:itemIndex], "\n") + 1
+
+	if len(findings) != 1 || findings[0].Line != expectedLine || findings[0].Column != 7 ||
+		findings[0].Path != "Envelope/VirtualSystem/VirtualHardwareSection/Item" {
+		t.Fatalf("Got unexpected findings - %+v", findings)
+	}
+
+	findings, err = Validate(strings.NewReader("<Envelope>\n  <Junk>\n</Envelope>\n"), Limits{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 1 || findings[0].Line != 3 {
+		t.Fatalf("Got unexpected findings for malformed XML - %+v", findings)
+	}
+
+	findings, err = Validate(strings.NewReader(basicOvfFileContents), Limits{MaxItems: 1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(findings) != 1 || findings[0].Line != 0 {
+		t.Fatalf("Got unexpected findings for an exceeded limit - %+v", findings)
+	}
+}
@@ -0,0 +1,146 @@
+package ovf
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+const (
+	// VmwNamespace is the XML namespace of VMWare's OVF extensions.
+	VmwNamespace = "http://www.vmware.com/schema/ovf"
+
+	// VmwPrefix is the conventional prefix of VmwNamespace.
+	VmwPrefix = "vmw"
+
+	// VmwVirtualTPMSubType is the ResourceSubType of a VMWare virtual
+	// TPM Item.
+	VmwVirtualTPMSubType = "vmware.vtpm"
+)
+
+// DeclareNamespaceFunc returns an EditObjectFunc that declares an XML
+// namespace on the OVF Envelope (e.g., 'xmlns:vmw'), if it has not
+// already been declared.
+//
+// Be advised: Editing the Envelope consumes the entire document as a
+// single object. This func must be proposed in an EditScheme that does
+// not edit any other objects.
+func DeclareNamespaceFunc(prefix string, uri string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		for _, attr := range o.Start.Attr {
+			if attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return EditObjectResult{Action: NoOp}
+			}
+		}
+
+		err := o.SetAttr("xmlns:"+prefix, uri)
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+// SetVmwConfigFunc returns an EditObjectFunc that adds a 'vmw:Config'
+// element with the specified key and value to the VirtualHardwareSection
+// (e.g., key 'firmware' and value 'efi'). An existing 'vmw:Config' with
+// the same key is replaced.
+//
+// The 'vmw' namespace must be declared on the Envelope for the resulting
+// OVF to be valid (see DeclareNamespaceFunc).
+func SetVmwConfigFunc(key string, value string) EditObjectFunc {
+	return setVmwKeyValueFunc("Config", key, value)
+}
+
+// SetVmwExtraConfigFunc returns an EditObjectFunc that adds a
+// 'vmw:ExtraConfig' element with the specified key and value to the
+// VirtualHardwareSection. ExtraConfig keys are .vmx settings (e.g., key
+// 'svga.vramSize' and value '16777216'). An existing 'vmw:ExtraConfig'
+// with the same key is replaced.
+//
+// The 'vmw' namespace must be declared on the Envelope for the resulting
+// OVF to be valid (see DeclareNamespaceFunc).
+func SetVmwExtraConfigFunc(key string, value string) EditObjectFunc {
+	return setVmwKeyValueFunc("ExtraConfig", key, value)
+}
+
+func setVmwKeyValueFunc(element string, key string, value string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		// A missing element is not an error.
+		_ = o.DeleteChildWithAttr(element, "key", key)
+
+		err := o.InsertChild(vmwKeyValueElement(element, key, value))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+func vmwKeyValueElement(element string, key string, value string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString(`<vmw:` + element + ` ovf:required="false" vmw:key="`)
+	xml.EscapeText(b, []byte(key))
+	b.WriteString(`" vmw:value="`)
+	xml.EscapeText(b, []byte(value))
+	b.WriteString(`"/>`)
+
+	return b.Bytes()
+}
+
+// AddVirtualTPMFunc returns an EditObjectFunc that adds a VMWare virtual
+// TPM Item with the specified InstanceID to the VirtualHardwareSection.
+// The InstanceID must not be used by another Item.
+//
+// A virtual machine with a virtual TPM requires EFI firmware, and can only
+// be deployed to a vCenter that has a key provider configured.
+func AddVirtualTPMFunc(instanceID string) EditObjectFunc {
+	return func(i interface{}) EditObjectResult {
+		o, ok := i.(*RawObject)
+		if !ok {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		err := o.InsertChild(virtualTPMItem(instanceID))
+		if err != nil {
+			return EditObjectResult{Action: NoOp}
+		}
+
+		return EditObjectResult{
+			Action: Replace,
+			Object: o,
+		}
+	}
+}
+
+func virtualTPMItem(instanceID string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString("<Item ovf:required=\"false\">\n")
+	b.WriteString("  <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>\n")
+	b.WriteString("  <rasd:ElementName>Virtual TPM</rasd:ElementName>\n")
+	b.WriteString("  <rasd:InstanceID>")
+	xml.EscapeText(b, []byte(instanceID))
+	b.WriteString("</rasd:InstanceID>\n")
+	b.WriteString("  <rasd:ResourceSubType>" + VmwVirtualTPMSubType + "</rasd:ResourceSubType>\n")
+	b.WriteString("  <rasd:ResourceType>" + OtherResourceType + "</rasd:ResourceType>\n")
+	b.WriteString("</Item>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,115 @@
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeclareNamespaceFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(DeclareNamespaceFunc(VmwPrefix, VmwNamespace), EnvelopeName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents,
+		`xmlns:vbox="http://www.virtualbox.org/ovf/machine">`,
+		`xmlns:vbox="http://www.virtualbox.org/ovf/machine" xmlns:vmw="http://www.vmware.com/schema/ovf">`, 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	editScheme = NewEditScheme().Propose(DeclareNamespaceFunc(VmwPrefix, VmwNamespace), EnvelopeName)
+
+	b, err = EditRawOvf(strings.NewReader(expected), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != expected {
+		t.Fatal("Namespace was declared twice:\n'" + b.String() + "'")
+	}
+}
+
+func TestSetVmwConfigFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(SetVmwConfigFunc("firmware", "bios"), VirtualHardwareSectionName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	editScheme = NewEditScheme().Propose(SetVmwConfigFunc("firmware", "efi"), VirtualHardwareSectionName)
+
+	b, err = EditRawOvf(strings.NewReader(b.String()), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "      </Item>\n    </VirtualHardwareSection>",
+		"      </Item>\n      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n    </VirtualHardwareSection>", 1)
+	if expected == basicOvfFileContents {
+		t.Fatal("Failed to find end of VirtualHardwareSection in test data")
+	}
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestSetVmwExtraConfigFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(SetVmwExtraConfigFunc("svga.vramSize", "16777216"), VirtualHardwareSectionName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "      </Item>\n    </VirtualHardwareSection>",
+		"      </Item>\n      <vmw:ExtraConfig ovf:required=\"false\" vmw:key=\"svga.vramSize\" vmw:value=\"16777216\"/>\n    </VirtualHardwareSection>", 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestAddVirtualTPMFunc(t *testing.T) {
+	editScheme := NewEditScheme().Propose(AddVirtualTPMFunc("42"), VirtualHardwareSectionName)
+
+	b, err := EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := strings.Replace(basicOvfFileContents, "      </Item>\n    </VirtualHardwareSection>",
+		"      </Item>\n"+
+			"      <Item ovf:required=\"false\">\n"+
+			"        <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>\n"+
+			"        <rasd:ElementName>Virtual TPM</rasd:ElementName>\n"+
+			"        <rasd:InstanceID>42</rasd:InstanceID>\n"+
+			"        <rasd:ResourceSubType>vmware.vtpm</rasd:ResourceSubType>\n"+
+			"        <rasd:ResourceType>1</rasd:ResourceType>\n"+
+			"      </Item>\n"+
+			"    </VirtualHardwareSection>", 1)
+
+	result := b.String()
+	if result != expected {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+
+	parsed, err := ToOvf(strings.NewReader(result))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	last := items[len(items)-1]
+	if last.ResourceSubType != VmwVirtualTPMSubType || last.InstanceID != "42" {
+		t.Fatal("Virtual TPM Item was not parsed - got " + last.ResourceSubType)
+	}
+}
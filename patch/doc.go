@@ -0,0 +1,11 @@
+// Package patch records the changes made to an .ovf as a patch, and
+// re-applies the patch to other .ovf files.
+//
+// A patch is a JSON document containing a list of operations, each of
+// which targets an element by its path (e.g.,
+// 'Envelope/VirtualSystem/VirtualHardwareSection/Item[InstanceID=3]').
+// This allows a team to review and approve a set of changes once, and
+// then apply it to many appliances. Patches are applied using the same
+// line-oriented editor as the rest of this project, so the formatting of
+// the target .ovf is preserved.
+package patch
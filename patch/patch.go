@@ -0,0 +1,457 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// SetAttrOp sets the value of an element's attribute, adding the
+	// attribute if it does not exist.
+	SetAttrOp OperationType = "set-attr"
+
+	// SetTextOp sets the text of an element that has no children.
+	SetTextOp OperationType = "set-text"
+
+	// InsertOp adds an element as the last child of the element at
+	// the path.
+	InsertOp OperationType = "insert"
+
+	// DeleteOp deletes the element at the path.
+	DeleteOp OperationType = "delete"
+
+	// CurrentVersion is the version of the patch format produced by
+	// this package.
+	CurrentVersion = 1
+)
+
+// OperationType is the type of change made by an Operation.
+type OperationType string
+
+func (o OperationType) String() string {
+	return string(o)
+}
+
+// Patch is a set of changes that can be applied to an .ovf.
+type Patch struct {
+	Version    int         `json:"version"`
+	Operations []Operation `json:"operations"`
+}
+
+// Bytes returns the patch as indented JSON.
+func (o Patch) Bytes() ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+
+	e := json.NewEncoder(buff)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+
+	err := e.Encode(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// Operation is a single change to an .ovf.
+type Operation struct {
+	// Op is the type of change.
+	Op OperationType `json:"op"`
+
+	// Path identifies the element that is changed. For InsertOp, it
+	// is the path of the new element's parent.
+	Path string `json:"path"`
+
+	// Name is the qualified name of the attribute that is set by
+	// SetAttrOp (e.g., 'ovf:href').
+	Name string `json:"name,omitempty"`
+
+	// Value is the new attribute value for SetAttrOp, or the new
+	// text for SetTextOp.
+	Value string `json:"value,omitempty"`
+
+	// XML is the element that is added by InsertOp.
+	XML string `json:"xml,omitempty"`
+
+	// Force, when true, allows DeleteOp to delete a section that
+	// is required by the OVF specification (i.e., one that lacks
+	// 'ovf:required="false"'). See ovf.ErrRequiredSection.
+	Force bool `json:"force,omitempty"`
+}
+
+func (o Operation) validate() error {
+	if len(o.Path) == 0 {
+		return errors.New("'" + o.Op.String() + "' operation is missing a path")
+	}
+
+	switch o.Op {
+	case SetAttrOp:
+		if len(o.Name) == 0 {
+			return errors.New("'" + o.Op.String() + "' operation on '" + o.Path + "' is missing an attribute name")
+		}
+	case SetTextOp, DeleteOp:
+	case InsertOp:
+		if len(o.XML) == 0 {
+			return errors.New("'" + o.Op.String() + "' operation on '" + o.Path + "' is missing XML")
+		}
+
+		err := xmlutil.ValidateFormatting([]byte(o.XML))
+		if err != nil {
+			return errors.New("'" + o.Op.String() + "' operation on '" + o.Path + "' contains invalid XML - " + err.Error())
+		}
+	default:
+		return errors.New("unsupported patch operation '" + o.Op.String() + "'")
+	}
+
+	return nil
+}
+
+// Parse parses a JSON patch.
+func Parse(r io.Reader) (Patch, error) {
+	var p Patch
+
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	err := d.Decode(&p)
+	if err != nil {
+		return Patch{}, errors.New("failed to parse patch - " + err.Error())
+	}
+
+	if p.Version != CurrentVersion {
+		return Patch{}, errors.New("unsupported patch version " + strconv.Itoa(p.Version))
+	}
+
+	for _, operation := range p.Operations {
+		err := operation.validate()
+		if err != nil {
+			return Patch{}, err
+		}
+	}
+
+	return p, nil
+}
+
+// Diff returns a Patch containing the changes needed to turn the original
+// .ovf into the edited .ovf.
+//
+// Attributes and text are changed in place. Added elements are inserted
+// as the last child of their parent, so their position relative to
+// existing siblings may differ when the patch is applied. The removal of
+// an attribute cannot be represented, and results in an error.
+func Diff(original io.Reader, edited io.Reader) (Patch, error) {
+	originalRaw, err := ioutil.ReadAll(original)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	editedRaw, err := ioutil.ReadAll(edited)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	originalRoot, err := parseTree(originalRaw)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	editedRoot, err := parseTree(editedRaw)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	if originalRoot.path != editedRoot.path {
+		return Patch{}, errors.New("root elements differ ('" + originalRoot.path + "' and '" + editedRoot.path + "')")
+	}
+
+	differ := &differ{}
+
+	err = differ.diff(originalRoot, editedRoot)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	// Deletions are made last, and in reverse document order, so
+	// that they do not change the paths used by other operations.
+	operations := differ.changes
+	for i := len(differ.deletions) - 1; i >= 0; i-- {
+		operations = append(operations, differ.deletions[i])
+	}
+
+	return Patch{
+		Version:    CurrentVersion,
+		Operations: operations,
+	}, nil
+}
+
+type differ struct {
+	changes   []Operation
+	deletions []Operation
+}
+
+func (o *differ) diff(original *node, edited *node) error {
+	for _, attr := range original.attrs {
+		_, ok := edited.attr(qualifiedName(attr.Name))
+		if !ok {
+			return errors.New("attribute '" + qualifiedName(attr.Name) + "' was removed from '" +
+				original.path + "', which cannot be represented by a patch")
+		}
+	}
+
+	for _, attr := range edited.attrs {
+		name := qualifiedName(attr.Name)
+
+		value, ok := original.attr(name)
+		if !ok || value != attr.Value {
+			o.changes = append(o.changes, Operation{
+				Op:    SetAttrOp,
+				Path:  original.path,
+				Name:  name,
+				Value: attr.Value,
+			})
+		}
+	}
+
+	if len(original.children) == 0 && len(edited.children) == 0 {
+		if original.text != edited.text {
+			o.changes = append(o.changes, Operation{
+				Op:    SetTextOp,
+				Path:  original.path,
+				Value: edited.text,
+			})
+		}
+
+		return nil
+	}
+
+	originalChildren := make(map[string]*node)
+	for _, child := range original.children {
+		originalChildren[child.path] = child
+	}
+
+	editedChildren := make(map[string]bool)
+	for _, child := range edited.children {
+		editedChildren[child.path] = true
+	}
+
+	for _, child := range original.children {
+		if !editedChildren[child.path] {
+			o.deletions = append(o.deletions, Operation{
+				Op:   DeleteOp,
+				Path: child.path,
+			})
+		}
+	}
+
+	for _, child := range edited.children {
+		originalChild, ok := originalChildren[child.path]
+		if !ok {
+			o.changes = append(o.changes, Operation{
+				Op:   InsertOp,
+				Path: original.path,
+				XML:  string(child.raw),
+			})
+			continue
+		}
+
+		err := o.diff(originalChild, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply applies a Patch to an .ovf, returning the patched .ovf. An error
+// is returned if an operation's path does not exist in the .ovf.
+func Apply(r io.Reader, p Patch) (*bytes.Buffer, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	buff := bytes.NewBuffer(raw)
+
+	for i, operation := range p.Operations {
+		err := operation.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		buff, err = apply(buff.Bytes(), operation)
+		if err != nil {
+			return nil, errors.New("failed to apply patch operation " + strconv.Itoa(i+1) +
+				" ('" + operation.Op.String() + "' on '" + operation.Path + "') - " + err.Error())
+		}
+	}
+
+	return buff, nil
+}
+
+// apply applies a single operation to an .ovf.
+func apply(raw []byte, operation Operation) (*bytes.Buffer, error) {
+	root, err := parseTree(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *node
+	root.walk(func(n *node) {
+		if n.path == operation.Path {
+			target = n
+		}
+	})
+	if target == nil {
+		return nil, errors.New("path does not exist")
+	}
+
+	var f func(o *ovf.RawObject) (ovf.EditAction, error)
+
+	switch operation.Op {
+	case SetAttrOp:
+		f = func(o *ovf.RawObject) (ovf.EditAction, error) {
+			return ovf.Replace, o.SetAttr(operation.Name, operation.Value)
+		}
+	case SetTextOp:
+		if len(target.children) > 0 {
+			return nil, errors.New("element has children")
+		}
+
+		parent := target.parent
+		if parent == nil {
+			return nil, errors.New("the text of the root element cannot be set")
+		}
+
+		for _, sibling := range parent.children {
+			if sibling != target && sibling.name.Local == target.name.Local {
+				return nil, errors.New("element has siblings with the same name")
+			}
+		}
+
+		local := target.name.Local
+		target = parent
+		f = func(o *ovf.RawObject) (ovf.EditAction, error) {
+			return ovf.Replace, o.SetChildText(local, operation.Value)
+		}
+	case InsertOp:
+		f = func(o *ovf.RawObject) (ovf.EditAction, error) {
+			return ovf.Replace, o.InsertChild([]byte(operation.XML))
+		}
+	case DeleteOp:
+		f = func(o *ovf.RawObject) (ovf.EditAction, error) {
+			return ovf.Delete, nil
+		}
+	}
+
+	return editNode(raw, root, target, f, ovf.EditConfig{
+		RawObjects:                  true,
+		AllowRequiredSectionDeletes: operation.Force,
+	})
+}
+
+// editNode edits the target node using the line-oriented editor. The
+// editor identifies objects by name, so the target is identified by its
+// position among the elements with the same name that the editor visits.
+func editNode(raw []byte, root *node, target *node, f func(o *ovf.RawObject) (ovf.EditAction, error), config ovf.EditConfig) (*bytes.Buffer, error) {
+	name := target.name.Local
+
+	index := -1
+	count := 0
+	root.walk(func(n *node) {
+		if n.name.Local != name || hasAncestorNamed(n, name) {
+			return
+		}
+
+		if n == target {
+			index = count
+		}
+		count = count + 1
+	})
+	if index < 0 {
+		return nil, errors.New("element is nested inside of another element with the same name")
+	}
+
+	visited := 0
+	applied := false
+	var editErr error
+
+	scheme := ovf.NewEditScheme().Propose(func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(*ovf.RawObject)
+		if !ok {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		visited = visited + 1
+		if visited-1 != index {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		if !sameAttrs(o, target) {
+			editErr = errors.New("element could not be located by the editor (is it on the same line as another element?)")
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		action, err := f(o)
+		if err != nil {
+			editErr = err
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		applied = true
+
+		return ovf.EditObjectResult{
+			Action: action,
+			Object: o,
+		}
+	}, ovf.ObjectName(name))
+
+	buff, err := ovf.EditRawOvfWithConfig(bytes.NewReader(raw), scheme, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if editErr != nil {
+		return nil, editErr
+	}
+
+	if !applied {
+		return nil, errors.New("element could not be located by the editor (is it on the same line as another element?)")
+	}
+
+	return buff, nil
+}
+
+func hasAncestorNamed(n *node, name string) bool {
+	for parent := n.parent; parent != nil; parent = parent.parent {
+		if parent.name.Local == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameAttrs returns true if the object's start element has the same
+// attributes as the node.
+func sameAttrs(o *ovf.RawObject, n *node) bool {
+	if len(o.Start.Attr) != len(n.attrs) {
+		return false
+	}
+
+	for i, attr := range o.Start.Attr {
+		if qualifiedName(attr.Name) != qualifiedName(n.attrs[i].Name) || attr.Value != n.attrs[i].Value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,225 @@
+package patch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	testOvf = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <References>
+    <File ovf:id="file1" ovf:href="disk1.vmdk"/>
+  </References>
+  <VirtualSystem ovf:id="vm">
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements for a virtual machine</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:Caption>ideController0</rasd:Caption>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceType>5</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+	otherOvf = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+    <References>
+        <File ovf:id="file1" ovf:href="other-disk1.vmdk"/>
+    </References>
+    <VirtualSystem ovf:id="vm">
+        <VirtualHardwareSection>
+            <Info>Virtual hardware requirements for a virtual machine</Info>
+            <System>
+                <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+                <vssd:InstanceID>0</vssd:InstanceID>
+                <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
+            </System>
+            <Item>
+                <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
+                <rasd:InstanceID>4</rasd:InstanceID>
+                <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+                <rasd:ResourceType>10</rasd:ResourceType>
+            </Item>
+            <Item>
+                <rasd:Caption>ideController0</rasd:Caption>
+                <rasd:InstanceID>3</rasd:InstanceID>
+                <rasd:ResourceType>5</rasd:ResourceType>
+            </Item>
+        </VirtualHardwareSection>
+    </VirtualSystem>
+</Envelope>
+`
+)
+
+func editedTestOvf() string {
+	edited := strings.Replace(testOvf, "virtualbox-2.2", "vmx-10", 1)
+	edited = strings.Replace(edited, "<rasd:ResourceSubType>E1000</rasd:ResourceSubType>",
+		"<rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>", 1)
+	edited = strings.Replace(edited, `<VirtualSystem ovf:id="vm">`, `<VirtualSystem ovf:id="vm" ovf:transport="iso">`, 1)
+	edited = strings.Replace(edited, "      <Item>\n        <rasd:Caption>ideController0</rasd:Caption>\n"+
+		"        <rasd:InstanceID>3</rasd:InstanceID>\n        <rasd:ResourceType>5</rasd:ResourceType>\n      </Item>\n", "", 1)
+	edited = strings.Replace(edited, "    </VirtualHardwareSection>",
+		"      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n    </VirtualHardwareSection>", 1)
+
+	return edited
+}
+
+func TestDiff(t *testing.T) {
+	p, err := Diff(strings.NewReader(testOvf), strings.NewReader(editedTestOvf()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []Operation{
+		{Op: SetAttrOp, Path: "Envelope/VirtualSystem[@id=vm]", Name: "ovf:transport", Value: "iso"},
+		{Op: SetTextOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection/System[InstanceID=0]/VirtualSystemType", Value: "vmx-10"},
+		{Op: SetTextOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection/Item[InstanceID=4]/ResourceSubType", Value: "VmxNet3"},
+		{Op: InsertOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection",
+			XML: `<vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>`},
+		{Op: DeleteOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection/Item[InstanceID=3]"},
+	}
+
+	if p.Version != CurrentVersion || len(p.Operations) != len(expected) {
+		raw, _ := p.Bytes()
+		t.Fatal("Got unexpected patch:\n" + string(raw))
+	}
+
+	for i := range expected {
+		if p.Operations[i] != expected[i] {
+			t.Fatal("Got unexpected operation '" + p.Operations[i].Op.String() + "' on '" + p.Operations[i].Path + "'")
+		}
+	}
+}
+
+func TestDiffRemovedAttribute(t *testing.T) {
+	edited := strings.Replace(testOvf, ` ovf:href="disk1.vmdk"`, "", 1)
+
+	_, err := Diff(strings.NewReader(testOvf), strings.NewReader(edited))
+	if err == nil {
+		t.Fatal("Expected an error when an attribute is removed")
+	}
+}
+
+func TestApply(t *testing.T) {
+	p, err := Diff(strings.NewReader(testOvf), strings.NewReader(editedTestOvf()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := Apply(strings.NewReader(testOvf), p)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != editedTestOvf() {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+
+	b, err = Apply(strings.NewReader(otherOvf), p)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+
+	expected := "                <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>\n" +
+		"                <rasd:ResourceType>10</rasd:ResourceType>\n" +
+		"            </Item>\n" +
+		"            <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n" +
+		"        </VirtualHardwareSection>"
+
+	if !strings.Contains(result, expected) || strings.Contains(result, "ideController0") ||
+		!strings.Contains(result, `<VirtualSystem ovf:id="vm" ovf:transport="iso">`) ||
+		!strings.Contains(result, "<vssd:VirtualSystemType>vmx-10</vssd:VirtualSystemType>") ||
+		!strings.Contains(result, `ovf:href="other-disk1.vmdk"`) {
+		t.Fatal("Did not get expected result:\n'" + result + "'")
+	}
+}
+
+func TestApplyMissingPath(t *testing.T) {
+	p := Patch{
+		Version: CurrentVersion,
+		Operations: []Operation{
+			{Op: DeleteOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection/Item[InstanceID=99]"},
+		},
+	}
+
+	_, err := Apply(strings.NewReader(testOvf), p)
+	if err == nil {
+		t.Fatal("Expected an error for a path that does not exist")
+	}
+}
+
+func TestApplyDeleteRequiredSection(t *testing.T) {
+	p := Patch{
+		Version: CurrentVersion,
+		Operations: []Operation{
+			{Op: DeleteOp, Path: "Envelope/VirtualSystem[@id=vm]/VirtualHardwareSection"},
+		},
+	}
+
+	_, err := Apply(strings.NewReader(testOvf), p)
+	if err == nil {
+		t.Fatal("Expected an error when deleting a required section")
+	}
+
+	p.Operations[0].Force = true
+
+	b, err := Apply(strings.NewReader(testOvf), p)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "VirtualHardwareSection") {
+		t.Fatal("Section was not deleted:\n'" + b.String() + "'")
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Diff(strings.NewReader(testOvf), strings.NewReader(editedTestOvf()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	raw, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := Parse(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(parsed.Operations) != len(p.Operations) {
+		t.Fatal("Got unexpected number of operations")
+	}
+
+	for _, invalid := range []string{
+		`{"version": 2, "operations": []}`,
+		`{"version": 1, "operations": [{"op": "move", "path": "Envelope"}]}`,
+		`{"version": 1, "operations": [{"op": "set-attr", "path": "Envelope"}]}`,
+		`{"version": 1, "operations": [{"op": "insert", "path": "Envelope"}]}`,
+		`{"version": 1, "operations": [], "junk": true}`,
+	} {
+		_, err := Parse(strings.NewReader(invalid))
+		if err == nil {
+			t.Fatal("Expected an error for patch '" + invalid + "'")
+		}
+	}
+}
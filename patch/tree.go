@@ -0,0 +1,203 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+// keyAttrs are the attributes that identify an element among its
+// siblings, in order of preference.
+var keyAttrs = []string{"id", "diskId", "name", "key"}
+
+// node is an element of an XML document.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*node
+	parent   *node
+
+	// path identifies the node within the document.
+	path string
+
+	// raw is the node's XML, with the indentation of its start
+	// element removed from each line.
+	raw []byte
+}
+
+// qualifiedName returns the name of an element or attribute as it appears
+// in the document (e.g., 'ovf:href').
+func qualifiedName(name xml.Name) string {
+	if len(name.Space) == 0 {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// attr returns the value of the attribute with the specified qualified
+// name.
+func (o *node) attr(name string) (string, bool) {
+	for _, attr := range o.attrs {
+		if qualifiedName(attr.Name) == name {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// key returns the value that identifies the node among its siblings
+// formatted as a path predicate (e.g., '[InstanceID=3]'), or an empty
+// string if it has none.
+func (o *node) key() string {
+	for _, child := range o.children {
+		if child.name.Local == "InstanceID" && len(child.children) == 0 {
+			return "[InstanceID=" + child.text + "]"
+		}
+	}
+
+	for _, local := range keyAttrs {
+		for _, attr := range o.attrs {
+			if attr.Name.Local == local && attr.Name.Space != "xmlns" {
+				return "[@" + local + "=" + attr.Value + "]"
+			}
+		}
+	}
+
+	return ""
+}
+
+// walk calls f for the node and each of its descendants in document
+// order.
+func (o *node) walk(f func(n *node)) {
+	f(o)
+
+	for _, child := range o.children {
+		child.walk(f)
+	}
+}
+
+// parseTree parses an XML document into a tree of nodes.
+func parseTree(raw []byte) (*node, error) {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	var root *node
+	var current *node
+	var starts []int64
+
+	for {
+		offset := d.InputOffset()
+
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			n := &node{
+				name:   v.Name,
+				attrs:  v.Copy().Attr,
+				parent: current,
+			}
+
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("document has more than one root element")
+				}
+				root = n
+			} else {
+				current.children = append(current.children, n)
+			}
+
+			current = n
+			starts = append(starts, offset)
+		case xml.EndElement:
+			if current == nil {
+				return nil, errors.New("unexpected end element '" + qualifiedName(v.Name) + "'")
+			}
+
+			start := starts[len(starts)-1]
+			starts = starts[:len(starts)-1]
+
+			current.raw = dedent(raw, start, d.InputOffset())
+			if len(current.children) > 0 {
+				current.text = ""
+			}
+
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.text = current.text + strings.TrimSpace(string(v))
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("document does not contain any elements")
+	}
+
+	assignPaths(root, root.name.Local)
+
+	return root, nil
+}
+
+// assignPaths sets the path of a node and its descendants. An element is
+// identified by its key if it is unique among its siblings. Otherwise, an
+// element that has siblings with the same name is identified by its
+// 1-based position among those siblings.
+func assignPaths(n *node, path string) {
+	n.path = path
+
+	names := make(map[string]int)
+	keys := make(map[string]int)
+	for _, child := range n.children {
+		names[child.name.Local] = names[child.name.Local] + 1
+		keys[child.name.Local+child.key()] = keys[child.name.Local+child.key()] + 1
+	}
+
+	positions := make(map[string]int)
+	for _, child := range n.children {
+		positions[child.name.Local] = positions[child.name.Local] + 1
+
+		segment := child.name.Local + child.key()
+		if len(child.key()) == 0 || keys[segment] > 1 {
+			segment = child.name.Local
+			if names[child.name.Local] > 1 {
+				segment = segment + "[" + strconv.Itoa(positions[child.name.Local]) + "]"
+			}
+		}
+
+		assignPaths(child, path+"/"+segment)
+	}
+}
+
+// dedent returns the raw XML between the specified offsets, removing the
+// indentation of the first line from each of the following lines.
+func dedent(raw []byte, start int64, end int64) []byte {
+	lineStart := bytes.LastIndexByte(raw[:start], '\n') + 1
+	indent := raw[lineStart:start]
+	if len(bytes.TrimLeft(indent, " \t")) > 0 {
+		indent = nil
+	}
+
+	lines := bytes.Split(raw[start:end], []byte{'\n'})
+	for i := range lines {
+		lines[i] = bytes.TrimRight(lines[i], "\r")
+		if i > 0 {
+			lines[i] = bytes.TrimPrefix(lines[i], indent)
+		}
+	}
+
+	return bytes.Join(lines, []byte{'\n'})
+}
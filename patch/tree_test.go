@@ -0,0 +1,65 @@
+package patch
+
+import (
+	"testing"
+)
+
+func TestParseTreePaths(t *testing.T) {
+	root, err := parseTree([]byte(`<Envelope xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:id="file1"/>
+    <File ovf:id="file2"/>
+  </References>
+  <Section>
+    <Info>First</Info>
+  </Section>
+  <Section>
+    <Info>Second</Info>
+  </Section>
+</Envelope>`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var paths []string
+	root.walk(func(n *node) {
+		paths = append(paths, n.path)
+	})
+
+	expected := []string{
+		"Envelope",
+		"Envelope/References",
+		"Envelope/References/File[@id=file1]",
+		"Envelope/References/File[@id=file2]",
+		"Envelope/Section[1]",
+		"Envelope/Section[1]/Info",
+		"Envelope/Section[2]",
+		"Envelope/Section[2]/Info",
+	}
+
+	if len(paths) != len(expected) {
+		t.Fatal("Got unexpected number of paths")
+	}
+
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Fatal("Got unexpected path '" + paths[i] + "'")
+		}
+	}
+
+	if root.children[1].children[0].text != "First" {
+		t.Fatal("Got unexpected text '" + root.children[1].children[0].text + "'")
+	}
+}
+
+func TestDedent(t *testing.T) {
+	raw := []byte("<A>\n    <B>\n      <C/>\n    </B>\n</A>")
+
+	start := int64(8)
+	end := int64(len(raw) - len("\n</A>"))
+
+	result := string(dedent(raw, start, end))
+	if result != "<B>\n  <C/>\n</B>" {
+		t.Fatal("Got unexpected result '" + result + "'")
+	}
+}
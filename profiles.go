@@ -0,0 +1,157 @@
+package vmwareify
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/internal/guestos"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	e1000NicSubType  = "E1000"
+	e1000eNicSubType = "E1000e"
+
+	lsiLogicScsiSubType    = "lsilogic"
+	lsiLogicSasScsiSubType = "lsilogicsas"
+)
+
+// GuestOSProfile is a curated set of hardware choices that work well for
+// a family of guest operating systems.
+type GuestOSProfile struct {
+	// Name is the name used to select the profile.
+	Name string
+
+	// Description describes the profile.
+	Description string
+
+	// NetworkAdapterSubType is the ResourceSubType that Ethernet
+	// adapters are converted to.
+	NetworkAdapterSubType string
+
+	// ScsiControllerSubType is the ResourceSubType that SCSI
+	// controllers are converted to.
+	ScsiControllerSubType string
+}
+
+// EditObjectFuncs returns the ovf.EditObjectFunc that apply the profile
+// to OVF Items.
+func (o GuestOSProfile) EditObjectFuncs() []ovf.EditObjectFunc {
+	var funcs []ovf.EditObjectFunc
+
+	for _, f := range o.explainedEditObjectFuncs() {
+		funcs = append(funcs, f.f)
+	}
+
+	return funcs
+}
+
+// explainedFunc is an ovf.EditObjectFunc and the reason it is used.
+type explainedFunc struct {
+	f      ovf.EditObjectFunc
+	reason string
+}
+
+func (o GuestOSProfile) explainedEditObjectFuncs() []explainedFunc {
+	var funcs []explainedFunc
+
+	if len(o.NetworkAdapterSubType) > 0 {
+		funcs = append(funcs, explainedFunc{
+			f: ovf.SetHardwareItemsResourceSubTypeFunc(
+				ovf.EthernetAdapterResourceType, o.NetworkAdapterSubType),
+			reason: "Ethernet adapters (resource type " + ovf.EthernetAdapterResourceType +
+				") are converted to the chosen model '" + o.NetworkAdapterSubType + "'",
+		})
+	}
+
+	if len(o.ScsiControllerSubType) > 0 {
+		funcs = append(funcs, explainedFunc{
+			f: ovf.SetHardwareItemsResourceSubTypeFunc(
+				ovf.ParallelScsiHbaResourceType, o.ScsiControllerSubType),
+			reason: "SCSI controllers (resource type " + ovf.ParallelScsiHbaResourceType +
+				") are converted to the chosen model '" + o.ScsiControllerSubType + "'",
+		})
+	}
+
+	return funcs
+}
+
+var guestOSProfiles = map[string]GuestOSProfile{
+	"windows": {
+		Name:                  "windows",
+		Description:           "Modern Windows guests (e1000e network adapters, LSI Logic SAS)",
+		NetworkAdapterSubType: e1000eNicSubType,
+		ScsiControllerSubType: lsiLogicSasScsiSubType,
+	},
+	"windows-legacy": {
+		Name:                  "windows-legacy",
+		Description:           "Windows XP / Server 2003 and older (e1000 network adapters, LSI Logic)",
+		NetworkAdapterSubType: e1000NicSubType,
+		ScsiControllerSubType: lsiLogicScsiSubType,
+	},
+	"linux": {
+		Name:                  "linux",
+		Description:           "Modern Linux guests (e1000e network adapters, LSI Logic)",
+		NetworkAdapterSubType: e1000eNicSubType,
+		ScsiControllerSubType: lsiLogicScsiSubType,
+	},
+	"linux-legacy": {
+		Name:                  "linux-legacy",
+		Description:           "Older Linux guests (e1000 network adapters, LSI Logic)",
+		NetworkAdapterSubType: e1000NicSubType,
+		ScsiControllerSubType: lsiLogicScsiSubType,
+	},
+	"bsd": {
+		Name:                  "bsd",
+		Description:           "BSD guests (e1000 network adapters supported by the em driver, LSI Logic)",
+		NetworkAdapterSubType: e1000NicSubType,
+		ScsiControllerSubType: lsiLogicScsiSubType,
+	},
+}
+
+// GuestOSProfiles returns all of the available GuestOSProfile, sorted
+// by name.
+func GuestOSProfiles() []GuestOSProfile {
+	var profiles []GuestOSProfile
+	for _, profile := range guestOSProfiles {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles
+}
+
+// LookupGuestOSProfile returns the GuestOSProfile for the specified name.
+// The name may be the name of a profile (e.g., 'windows') or a VirtualBox
+// OS type (e.g., 'Windows10_64'), in which case the profile for the OS
+// type's family is returned.
+func LookupGuestOSProfile(name string) (GuestOSProfile, bool) {
+	profile, ok := guestOSProfiles[strings.ToLower(name)]
+	if ok {
+		return profile, true
+	}
+
+	info, ok := guestos.Lookup(name)
+	if !ok {
+		return GuestOSProfile{}, false
+	}
+
+	return profileForGuestOS(info)
+}
+
+func profileForGuestOS(info guestos.Info) (GuestOSProfile, bool) {
+	profileName := info.Family.String()
+	if info.Legacy {
+		profileName = profileName + "-legacy"
+	}
+
+	profile, ok := guestOSProfiles[profileName]
+	if !ok {
+		profile, ok = guestOSProfiles[info.Family.String()]
+	}
+
+	return profile, ok
+}
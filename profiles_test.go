@@ -0,0 +1,99 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupGuestOSProfile(t *testing.T) {
+	tests := map[string]string{
+		"windows":      "windows",
+		"BSD":          "bsd",
+		"Windows10_64": "windows",
+		"WindowsXP":    "windows-legacy",
+		"Linux24":      "linux-legacy",
+		"RedHat_64":    "linux",
+		"FreeBSD_64":   "bsd",
+	}
+
+	for name, expected := range tests {
+		profile, ok := LookupGuestOSProfile(name)
+		if !ok {
+			t.Fatal("Failed to find profile for '" + name + "'")
+		}
+
+		if profile.Name != expected {
+			t.Fatal("Expected profile '" + expected + "' for '" + name + "', got '" + profile.Name + "'")
+		}
+	}
+
+	_, ok := LookupGuestOSProfile("junk")
+	if ok {
+		t.Fatal("Found a profile for an unknown name")
+	}
+}
+
+func TestBasicConvertWithGuestOSProfile(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		GuestOSProfile: "windows",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expectedNic := `      <Item>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Caption>Ethernet adapter on &#39;NAT&#39;</rasd:Caption>
+        <rasd:Connection>NAT</rasd:Connection>
+        <rasd:ElementName>Ethernet adapter on &#39;NAT&#39;</rasd:ElementName>
+        <rasd:InstanceID>8</rasd:InstanceID>
+        <rasd:ResourceSubType>E1000e</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>`
+
+	if !strings.Contains(b.String(), expectedNic) {
+		t.Fatal("Did not find expected network adapter in result:\n'" + b.String() + "'")
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		GuestOSProfile: "junk",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for an unknown profile")
+	}
+}
+
+func TestConvertReader(t *testing.T) {
+	profile, ok := LookupGuestOSProfile("windows")
+	if !ok {
+		t.Fatal("Failed to find the windows profile")
+	}
+
+	expected, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		GuestOSProfile: "windows",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := ConvertReader(strings.NewReader(basicOvfFileContents), profile)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != expected.String() {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+
+	b, err = ConvertReader(strings.NewReader(basicOvfFileContents), GuestOSProfile{
+		Name:                  "custom",
+		NetworkAdapterSubType: "VmxNet3",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), "<rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>") {
+		t.Fatal("Expected the custom profile to be applied:\n'" + b.String() + "'")
+	}
+}
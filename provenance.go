@@ -0,0 +1,151 @@
+package vmwareify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/internal/version"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	// ProvenanceClass is the class of the ProductSection that records
+	// the provenance of a converted .ovf (see
+	// BasicConvertOptions.EmbedProvenance).
+	ProvenanceClass = "com.github.stephen-fox.vmwareify"
+
+	noneProvenanceValue = "none"
+)
+
+// Version is the version of the application, which is recorded in the
+// provenance of converted .ovf files. It can be set when building the
+// application (e.g., '-ldflags "-X github.com/stephen-fox/vmwareify.Version=1.2.3"').
+// Otherwise, the module version embedded by the Go toolchain is used if
+// it is known.
+var Version = version.Devel
+
+// provenanceProperties returns the ProductSection properties that record
+// how a .ovf was converted: the application's version (and commit, if it
+// is known), the digest of the original .ovf, the guest OS profile that
+// was applied, and the options that were specified.
+func provenanceProperties(original []byte, hardware hardwareChoices, options BasicConvertOptions) []ovf.ProductProperty {
+	digest := sha256.Sum256(original)
+
+	profile := hardware.profile.Name
+	if len(profile) == 0 {
+		profile = noneProvenanceValue
+	}
+
+	optionNames := provenanceOptions(options)
+	if len(optionNames) == 0 {
+		optionNames = []string{noneProvenanceValue}
+	}
+
+	info := version.Get(Version)
+
+	properties := []ovf.ProductProperty{
+		{Key: "version", Value: info.Version},
+	}
+
+	if len(info.Commit) > 0 {
+		properties = append(properties, ovf.ProductProperty{Key: "commit", Value: info.Commit})
+	}
+
+	// The digest identifies the original .ovf.
+	if !options.Scrub {
+		properties = append(properties, ovf.ProductProperty{Key: "input-sha256", Value: hex.EncodeToString(digest[:])})
+	}
+
+	return append(properties,
+		ovf.ProductProperty{Key: "profile", Value: profile},
+		ovf.ProductProperty{Key: "options", Value: strings.Join(optionNames, ",")},
+	)
+}
+
+// provenanceOptions returns the conversion options that differ from their
+// defaults, using the names of the equivalent command line options
+// (e.g., 'auto' or 'floppy=remove').
+func provenanceOptions(options BasicConvertOptions) []string {
+	var names []string
+
+	strs := []struct {
+		name  string
+		value string
+	}{
+		{name: "guest-os", value: options.GuestOSProfile},
+		{name: "nic", value: options.NetworkAdapterSubType},
+		{name: "scsi", value: options.ScsiControllerSubType},
+		{name: "firmware", value: options.Firmware},
+		{name: "hardware-version", value: options.HardwareVersion},
+		{name: "floppy", value: options.FloppyDrives},
+		{name: "audio", value: options.AudioDevices},
+		{name: "rename-disks", value: options.DiskFileSuffix},
+		{name: "disk-capacity", value: options.DiskCapacity},
+		{name: "source-dialect", value: options.SourceDialect},
+		{name: "locale", value: options.Locale},
+		{name: "cpu-allocation", value: options.CpuAllocation},
+		{name: "memory-allocation", value: options.MemoryAllocation},
+		{name: "ip-schemes", value: options.IpAssignmentSchemes},
+		{name: "ip-protocols", value: options.IpProtocols},
+	}
+
+	for _, s := range strs {
+		if len(s.value) > 0 {
+			names = append(names, s.name+"="+s.value)
+		}
+	}
+
+	for _, oldName := range mappedNetworkNames(options.NetworkMappings) {
+		names = append(names, "network="+oldName+"="+options.NetworkMappings[oldName])
+	}
+
+	for _, network := range options.RemoveNetworks {
+		names = append(names, "remove-network="+network)
+	}
+
+	for _, network := range options.AddNetworkAdapters {
+		names = append(names, "add-nic="+network)
+	}
+
+	bools := []struct {
+		name  string
+		value bool
+	}{
+		{name: "auto", value: options.AutoDetectGuestOS},
+		{name: "cpu-hot-add", value: options.CpuHotAdd},
+		{name: "memory-hot-add", value: options.MemoryHotAdd},
+		{name: "map-display", value: options.MapDisplay},
+		{name: "boot-order", value: options.PreserveBootOrder},
+		{name: "vtpm", value: options.VirtualTPM},
+		{name: "ide-to-sata", value: options.MigrateIdeDevices},
+		{name: "keep-ide", value: options.KeepIdeControllers},
+		{name: "usb-tablet", value: options.UsbTablet},
+		{name: "strip-snapshots", value: options.StripSnapshotMetadata},
+		{name: "normalize-hrefs", value: options.NormalizeHrefs},
+		{name: "recompute-sizes", value: options.RecomputeSizes},
+		{name: "scrub", value: options.Scrub},
+		{name: "annotate-shared-folders", value: options.AnnotateSharedFolders},
+		{name: "keep-bom", value: options.KeepByteOrderMark},
+		{name: "prune-references", value: options.PruneReferences},
+		{name: "sort-items", value: options.SortItems},
+	}
+
+	for _, b := range bools {
+		if b.value {
+			names = append(names, b.name)
+		}
+	}
+
+	return names
+}
+
+// addProvenance records the provenance of an edited .ovf in a
+// ProductSection, replacing the provenance of any earlier conversion.
+func addProvenance(edited *bytes.Buffer, properties []ovf.ProductProperty, recorder *editRecorder) (*bytes.Buffer, error) {
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.SetProductSectionFunc(ProvenanceClass,
+			"Provenance of the descriptor, recorded by vmwareify", properties),
+			"the provenance of the conversion is recorded"), ovf.VirtualSystemName))
+}
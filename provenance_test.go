@@ -0,0 +1,69 @@
+package vmwareify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/internal/version"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertEmbedProvenance(t *testing.T) {
+	options := BasicConvertOptions{
+		EmbedProvenance: true,
+		GuestOSProfile:  "linux",
+		FloppyDrives:    RemoveFloppyDrives,
+		CpuHotAdd:       true,
+	}
+
+	converted, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	digest := sha256.Sum256([]byte(basicOvfFileContents))
+
+	expectedProperties := []string{
+		`<Property ovf:key="version" ovf:type="string" ovf:userConfigurable="false" ovf:value="` + version.Get(Version).Version + `"/>`,
+		`<Property ovf:key="input-sha256" ovf:type="string" ovf:userConfigurable="false" ovf:value="` +
+			hex.EncodeToString(digest[:]) + `"/>`,
+		`<Property ovf:key="profile" ovf:type="string" ovf:userConfigurable="false" ovf:value="linux"/>`,
+		`<Property ovf:key="options" ovf:type="string" ovf:userConfigurable="false" ovf:value="guest-os=linux,floppy=remove,cpu-hot-add"/>`,
+	}
+
+	result := converted.String()
+	if !strings.Contains(result, `<ProductSection ovf:class="`+ProvenanceClass+`" ovf:required="false">`) {
+		t.Fatal("Provenance was not recorded:\n'" + result + "'")
+	}
+
+	for _, property := range expectedProperties {
+		if !strings.Contains(result, property) {
+			t.Fatal("Missing provenance property '" + property + "':\n'" + result + "'")
+		}
+	}
+
+	reconverted, err := BasicConvertReader(strings.NewReader(result), BasicConvertOptions{EmbedProvenance: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Count(reconverted.String(), "<ProductSection") != 1 {
+		t.Fatal("Provenance of the earlier conversion was not replaced:\n'" + reconverted.String() + "'")
+	}
+
+	_, err = ovf.ToOvf(strings.NewReader(reconverted.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	withoutProvenance, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(withoutProvenance.String(), "ProductSection") {
+		t.Fatal("Provenance was recorded by default")
+	}
+}
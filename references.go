@@ -0,0 +1,46 @@
+package vmwareify
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// pruneReferences checks that the edits made to the .ovf did not leave
+// References Files that nothing references (e.g., the Files of removed
+// Strings bundles), and deletes them if BasicConvertOptions.PruneReferences
+// is true. Otherwise, a warning is logged. Files that were unreferenced before the .ovf was
+// edited are kept, as they may be used by elements that are not parsed.
+func pruneReferences(edited *bytes.Buffer, original ovf.Ovf, options BasicConvertOptions, recorder *editRecorder) (*bytes.Buffer, error) {
+	converted, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return edited, err
+	}
+
+	wasUnreferenced := make(map[string]bool)
+	for _, id := range ovf.UnreferencedFiles(original) {
+		wasUnreferenced[id] = true
+	}
+
+	var dangling []string
+	for _, id := range ovf.UnreferencedFiles(converted) {
+		if !wasUnreferenced[id] {
+			dangling = append(dangling, id)
+		}
+	}
+
+	if len(dangling) == 0 {
+		return edited, nil
+	}
+
+	if !options.PruneReferences {
+		options.warn("the converted .ovf references files that nothing uses anymore ('" +
+			strings.Join(dangling, "', '") + "') - prune the references to remove them")
+		return edited, nil
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.GarbageCollectReferencesFunc(dangling...),
+			"the file is no longer referenced by anything in the converted .ovf"), ovf.ReferencesFileName))
+}
@@ -0,0 +1,56 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertPruneReferences(t *testing.T) {
+	input := strings.NewReplacer(
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>
+    <File ovf:id="file2" ovf:href="fr-FR.xml"/>
+    <File ovf:id="file3" ovf:href="icon.png"/>`,
+		"</VirtualSystem>\n</Envelope>", `</VirtualSystem>
+  <Strings xml:lang="de-DE">
+    <Msg ovf:msgid="vm.info">Eine virtuelle Maschine</Msg>
+  </Strings>
+  <Strings xml:lang="fr-FR" ovf:fileRef="file2"/>
+</Envelope>`).Replace(basicOvfFileContents)
+	if strings.Count(input, "<Strings") != 2 {
+		t.Fatal("Failed to localize the test input")
+	}
+
+	var warnings []string
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		Locale: "de-DE",
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "('file2')") {
+		t.Fatalf("Got unexpected warnings - %v", warnings)
+	}
+
+	if !strings.Contains(b.String(), `ovf:id="file2"`) {
+		t.Fatal("Expected the file to be kept:\n" + b.String())
+	}
+
+	b, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		Locale:          "de-DE",
+		PruneReferences: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// The icon was never referenced, so it may be used by something
+	// that is not parsed.
+	if strings.Contains(b.String(), `ovf:id="file2"`) || !strings.Contains(b.String(), `ovf:id="file3"`) {
+		t.Fatal("Got unexpected references:\n" + b.String())
+	}
+}
@@ -0,0 +1,102 @@
+package vmwareify
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	reservationSetting = "reservation"
+	limitSetting       = "limit"
+	sharesSetting      = "shares"
+)
+
+// parseResourceAllocation parses a BasicConvertOptions.CpuAllocation or
+// MemoryAllocation, which is a comma separated list of settings (e.g.,
+// 'reservation=2000,shares=4000'). The reservation and limit are parsed
+// using parseAmount.
+func parseResourceAllocation(s string, parseAmount func(string) (int64, error)) (ovf.ResourceAllocation, error) {
+	var allocation ovf.ResourceAllocation
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return allocation, errors.New("invalid resource allocation setting '" + entry + "' - must be in " +
+				"the format '<" + reservationSetting + "|" + limitSetting + "|" + sharesSetting + ">=<value>'")
+		}
+
+		setting := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		var err error
+		switch setting {
+		case reservationSetting:
+			allocation.Reservation, err = parseAmount(value)
+		case limitSetting:
+			allocation.Limit, err = parseAmount(value)
+		case sharesSetting:
+			allocation.Shares, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return allocation, errors.New("unsupported resource allocation setting '" + setting + "' - must be '" +
+				reservationSetting + "', '" + limitSetting + "', or '" + sharesSetting + "'")
+		}
+		if err != nil {
+			return allocation, errors.New("invalid value for resource allocation setting '" + setting + "' - " +
+				err.Error())
+		}
+	}
+
+	if allocation.Reservation > 0 && allocation.Limit > 0 && allocation.Reservation > allocation.Limit {
+		return allocation, errors.New("the reservation cannot be greater than the limit")
+	}
+
+	return allocation, nil
+}
+
+// parseMhz parses a positive number of MHz.
+func parseMhz(s string) (int64, error) {
+	mhz, err := strconv.ParseInt(strings.TrimSuffix(strings.ToLower(s), "mhz"), 10, 64)
+	if err != nil || mhz < 1 {
+		return 0, errors.New("'" + s + "' is not a positive number of MHz")
+	}
+
+	return mhz, nil
+}
+
+// resourceAllocationEdits returns the explained edits that set the CPU
+// and memory allocations chosen by BasicConvertOptions.CpuAllocation and
+// MemoryAllocation.
+func resourceAllocationEdits(options BasicConvertOptions) ([]explainedFunc, error) {
+	var edits []explainedFunc
+
+	if len(options.CpuAllocation) > 0 {
+		allocation, err := parseResourceAllocation(options.CpuAllocation, parseMhz)
+		if err != nil {
+			return nil, errors.New("invalid CPU allocation - " + err.Error())
+		}
+
+		edits = append(edits, explainedFunc{
+			f: ovf.SetCpuReservationFunc(allocation),
+			reason: "the CPU allocation is set to '" + options.CpuAllocation + "' (resource type " +
+				ovf.ProcessorResourceType + ")",
+		})
+	}
+
+	if len(options.MemoryAllocation) > 0 {
+		allocation, err := parseResourceAllocation(options.MemoryAllocation, ovf.ParseCapacity)
+		if err != nil {
+			return nil, errors.New("invalid memory allocation - " + err.Error())
+		}
+
+		edits = append(edits, explainedFunc{
+			f: ovf.SetMemoryReservationFunc(allocation),
+			reason: "the memory allocation is set to '" + options.MemoryAllocation + "' (resource type " +
+				ovf.MemoryResourceType + ")",
+		})
+	}
+
+	return edits, nil
+}
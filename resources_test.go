@@ -0,0 +1,51 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertResourceAllocations(t *testing.T) {
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		CpuAllocation:    "reservation=2000MHz,shares=4000",
+		MemoryAllocation: "reservation=256MiB,limit=512MiB",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		switch item.ResourceType {
+		case ovf.ProcessorResourceType:
+			if item.Reservation != "2000" || item.Weight != "4000" {
+				t.Fatalf("Got unexpected CPU item - %+v", item)
+			}
+		case ovf.MemoryResourceType:
+			if item.Reservation != "256" || item.Limit != "512" {
+				t.Fatalf("Got unexpected memory item - %+v", item)
+			}
+		}
+	}
+
+	invalid := []BasicConvertOptions{
+		{CpuAllocation: "reservation"},
+		{CpuAllocation: "reservation=0"},
+		{CpuAllocation: "priority=high"},
+		{MemoryAllocation: "reservation=1GiB,limit=512MiB"},
+		{MemoryAllocation: "shares=lots"},
+	}
+
+	for _, options := range invalid {
+		_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), options)
+		if err == nil {
+			t.Fatalf("Expected an error for %+v", options)
+		}
+	}
+}
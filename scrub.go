@@ -0,0 +1,27 @@
+package vmwareify
+
+import (
+	"bytes"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// scrub removes information that identifies the system an edited .ovf was
+// exported from (see BasicConvertOptions.Scrub).
+func scrub(edited *bytes.Buffer, recorder *editRecorder) (*bytes.Buffer, error) {
+	addressFunc := recorder.explain(ovf.RemoveEthernetAdapterAddressFunc(),
+		"the MAC addresses of Ethernet adapters are removed, so that new addresses are generated")
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.DeleteVboxMachineFunc(),
+			"the vbox:Machine is removed, as it contains the machine's UUID, MAC addresses, timestamps, "+
+				"and extra data"),
+			ovf.QualifiedObjectName(ovf.VboxNamespace, ovf.VboxMachineName.String())).
+		Propose(recorder.explain(ovf.RemoveDiskUuidFunc(),
+			"the VirtualBox UUIDs of disks are removed"), ovf.DiskName).
+		Propose(addressFunc, ovf.VirtualHardwareItemName).
+		Propose(addressFunc, ovf.EthernetPortItemName).
+		Propose(recorder.explain(ovf.ClearHostnamePropertiesFunc(),
+			"the values of ProductSection properties that contain hostnames are cleared"),
+			ovf.ProductPropertyName))
+}
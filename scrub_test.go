@@ -0,0 +1,40 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertScrub(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>",
+		"<rasd:Address>08:00:27:18:a8:f8</rasd:Address>\n        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add a MAC address to the test input")
+	}
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		Scrub:           true,
+		EmbedProvenance: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := b.String()
+
+	for _, identifying := range []string{"aaf6485a", "b3595d90", "08002718A8F8", "08:00:27:18:a8:f8",
+		"lastStateChange", "vbox:Machine", "input-sha256"} {
+		if strings.Contains(output, identifying) {
+			t.Fatal("The output contains '" + identifying + "':\n" + output)
+		}
+	}
+
+	if !strings.Contains(output, "scrub") {
+		t.Fatal("The provenance does not record the scrub option:\n" + output)
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(output), BasicConvertOptions{Validate: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
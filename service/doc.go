@@ -0,0 +1,10 @@
+// Package service exposes vmwareify conversions over HTTP, allowing
+// conversion to be centralized behind a single internal service rather
+// than distributing the application to every machine that needs it.
+//
+// Clients POST a .ovf or .ova to the '/convert' endpoint and receive the
+// converted file in the response body. An .ova is streamed back to the
+// client as it is converted. Conversion options are specified using query
+// parameters that mirror the command line options (e.g.,
+// '/convert?guest-os=windows&firmware=efi').
+package service
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/stephen-fox/vmwareify"
+)
+
+const (
+	// MetricsPath is the path of the metrics endpoint, which is
+	// only served if Config.Metrics is non-nil.
+	MetricsPath = "/metrics"
+
+	metricsContentType = "text/plain; version=0.0.4"
+
+	ovfType = "ovf"
+	ovaType = "ova"
+)
+
+// Metrics counts the conversions performed by the service, and exports
+// the counters in the Prometheus text format. It implements
+// vmwareify.Metrics, so it can also be used to measure conversions that
+// are not performed by the service.
+type Metrics struct {
+	mu              sync.Mutex
+	conversions     map[string]int64
+	failures        map[string]int64
+	bytesRead       int64
+	bytesWritten    int64
+	durationSeconds float64
+}
+
+// NewMetrics returns a new instance of *Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		conversions: make(map[string]int64),
+		failures:    make(map[string]int64),
+	}
+}
+
+func (o *Metrics) ConversionFinished(stats vmwareify.ConversionStats) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	conversionType := ovfType
+	if stats.Ova {
+		conversionType = ovaType
+	}
+
+	o.conversions[conversionType] = o.conversions[conversionType] + 1
+	o.bytesRead = o.bytesRead + stats.BytesRead
+	o.bytesWritten = o.bytesWritten + stats.BytesWritten
+	o.durationSeconds = o.durationSeconds + stats.Duration.Seconds()
+
+	if stats.Err != nil {
+		class := failureClass(stats.Err)
+		o.failures[class] = o.failures[class] + 1
+	}
+}
+
+// ServeHTTP writes the counters in the Prometheus text format.
+func (o *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+
+	o.WriteTo(w)
+}
+
+// WriteTo writes the counters to w in the Prometheus text format.
+func (o *Metrics) WriteTo(w io.Writer) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var total int64
+	var count int64
+	for _, n := range o.conversions {
+		count = count + n
+	}
+
+	lines := []string{
+		"# HELP vmwareify_conversions_total The number of finished conversions by input type.",
+		"# TYPE vmwareify_conversions_total counter",
+	}
+	for _, conversionType := range []string{ovfType, ovaType} {
+		lines = append(lines, fmt.Sprintf("vmwareify_conversions_total{type=%q} %d",
+			conversionType, o.conversions[conversionType]))
+	}
+
+	lines = append(lines,
+		"# HELP vmwareify_conversion_failures_total The number of failed conversions by class of failure.",
+		"# TYPE vmwareify_conversion_failures_total counter")
+	for _, class := range sortedKeys(o.failures) {
+		lines = append(lines, fmt.Sprintf("vmwareify_conversion_failures_total{class=%q} %d",
+			class, o.failures[class]))
+	}
+
+	lines = append(lines,
+		"# HELP vmwareify_read_bytes_total The number of bytes read from conversion inputs.",
+		"# TYPE vmwareify_read_bytes_total counter",
+		fmt.Sprintf("vmwareify_read_bytes_total %d", o.bytesRead),
+		"# HELP vmwareify_written_bytes_total The number of bytes of conversion output produced.",
+		"# TYPE vmwareify_written_bytes_total counter",
+		fmt.Sprintf("vmwareify_written_bytes_total %d", o.bytesWritten),
+		"# HELP vmwareify_conversion_duration_seconds The amount of time taken by conversions.",
+		"# TYPE vmwareify_conversion_duration_seconds summary",
+		fmt.Sprintf("vmwareify_conversion_duration_seconds_sum %g", o.durationSeconds),
+		fmt.Sprintf("vmwareify_conversion_duration_seconds_count %d", count))
+
+	for _, line := range lines {
+		n, err := io.WriteString(w, line+"\n")
+		total = total + int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// Publish publishes the counters as an expvar.Var with the specified
+// name, which makes them available at '/debug/vars' when the
+// expvar package's handler is served. Like expvar.Publish, it panics
+// if the name is already in use.
+func (o *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		conversions := make(map[string]int64)
+		for k, v := range o.conversions {
+			conversions[k] = v
+		}
+
+		failures := make(map[string]int64)
+		for k, v := range o.failures {
+			failures[k] = v
+		}
+
+		return map[string]interface{}{
+			"conversions":     conversions,
+			"failures":        failures,
+			"bytesRead":       o.bytesRead,
+			"bytesWritten":    o.bytesWritten,
+			"durationSeconds": o.durationSeconds,
+		}
+	}))
+}
+
+// failureClass returns the class of a conversion failure, which is
+// derived from the HTTP status code the failure is reported with.
+func failureClass(err error) string {
+	switch statusCodeFor(err) {
+	case http.StatusRequestEntityTooLarge:
+		return "too_large"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	case http.StatusUnprocessableEntity:
+		return "unsupported"
+	case http.StatusBadRequest:
+		return "invalid"
+	default:
+		return "failed"
+	}
+}
+
+func sortedKeys(m map[string]int64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
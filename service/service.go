@@ -0,0 +1,339 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// ConvertPath is the path of the conversion endpoint.
+	ConvertPath = "/convert"
+
+	// DefaultMaxRequestBytes is the default maximum size of a
+	// request body.
+	DefaultMaxRequestBytes = 16 << 20
+
+	// DefaultTimeout is the default amount of time allowed for
+	// reading a request and writing its response.
+	DefaultTimeout = 2 * time.Minute
+
+	GuestOSParam         = "guest-os"
+	AutoParam            = "auto"
+	NicParam             = "nic"
+	NetworkParam         = "network"
+	AddNicParam          = "add-nic"
+	RemoveNetworkParam   = "remove-network"
+	ScsiParam            = "scsi"
+	FirmwareParam        = "firmware"
+	CpuHotAddParam       = "cpu-hot-add"
+	MemoryHotAddParam    = "memory-hot-add"
+	MapDisplayParam      = "map-display"
+	BootOrderParam       = "boot-order"
+	VirtualTPMParam      = "vtpm"
+	HardwareVersionParam = "hardware-version"
+	StripSnapshotsParam  = "strip-snapshots"
+	IdeToSataParam       = "ide-to-sata"
+	KeepIdeParam         = "keep-ide"
+	FloppyParam          = "floppy"
+	AudioParam           = "audio"
+	UsbTabletParam       = "usb-tablet"
+	NormalizeModesParam  = "normalize-modes"
+	StripOwnersParam     = "strip-owners"
+	ReproducibleParam    = "reproducible"
+	ProvenanceParam      = "provenance"
+	ScrubParam           = "scrub"
+	AnnotateFoldersParam = "annotate-shared-folders"
+	NormalizeHrefsParam  = "normalize-hrefs"
+	DiskCapacityParam    = "disk-capacity"
+	CpuAllocParam        = "cpu-allocation"
+	MemoryAllocParam     = "memory-allocation"
+	IpSchemesParam       = "ip-schemes"
+	IpProtocolsParam     = "ip-protocols"
+	SourceDialectParam   = "source-dialect"
+	SortItemsParam       = "sort-items"
+	ValidateParam        = "validate"
+	KeepBomParam         = "keep-bom"
+	LocaleParam          = "locale"
+	PruneReferencesParam = "prune-references"
+
+	// WarningHeader is the response header containing a conversion
+	// warning. The header is repeated for each warning.
+	WarningHeader = "X-Vmwareify-Warning"
+
+	ovfContentType = "application/ovf+xml"
+	ovaContentType = "application/x-tar"
+)
+
+// Config configures the conversion service.
+type Config struct {
+	// MaxRequestBytes is the maximum size of a request body.
+	// DefaultMaxRequestBytes is used if it is less than one.
+	MaxRequestBytes int64
+
+	// Timeout is the amount of time allowed for reading a request
+	// and writing its response. DefaultTimeout is used if it is
+	// less than one.
+	Timeout time.Duration
+
+	// MaxDescriptorBytes is the maximum size of a .ovf descriptor,
+	// including one inside of an .ova. The default is used if it is
+	// less than one (see vmwareify.BasicConvertOptions).
+	MaxDescriptorBytes int64
+
+	// MaxOvaBufferBytes is the maximum size of a file held in memory
+	// when converting an .ova (see vmwareify.BasicConvertOptions).
+	MaxOvaBufferBytes int64
+
+	// Metrics, if non-nil, counts the conversions performed by the
+	// service, and is served at MetricsPath.
+	Metrics *Metrics
+}
+
+func (o Config) maxRequestBytes() int64 {
+	if o.MaxRequestBytes < 1 {
+		return DefaultMaxRequestBytes
+	}
+
+	return o.MaxRequestBytes
+}
+
+func (o Config) timeout() time.Duration {
+	if o.Timeout < 1 {
+		return DefaultTimeout
+	}
+
+	return o.Timeout
+}
+
+// NewServer returns a *http.Server that serves the conversion service on
+// the specified address.
+func NewServer(address string, config Config) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           NewHandler(config),
+		ReadHeaderTimeout: config.timeout(),
+		ReadTimeout:       config.timeout(),
+		WriteTimeout:      config.timeout(),
+	}
+}
+
+// NewHandler returns a http.Handler that serves the conversion service.
+func NewHandler(config Config) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle(ConvertPath, &convertHandler{
+		config: config,
+	})
+
+	if config.Metrics != nil {
+		mux.Handle(MetricsPath, config.Metrics)
+	}
+
+	return mux
+}
+
+type convertHandler struct {
+	config Config
+}
+
+func (o *convertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := optionsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options.MaxDescriptorBytes = o.config.MaxDescriptorBytes
+	options.MaxOvaBufferBytes = o.config.MaxOvaBufferBytes
+
+	if o.config.Metrics != nil {
+		options.Metrics = o.config.Metrics
+	}
+
+	// Warnings are produced while converting the descriptor, which
+	// happens before the response header is written.
+	options.OnWarning = func(warning string) {
+		w.Header().Add(WarningHeader, warning)
+	}
+
+	body := bufio.NewReaderSize(http.MaxBytesReader(w, r.Body, o.config.maxRequestBytes()), ova.DetectionSize)
+
+	// Peek returns an error if the body is shorter than a tar header,
+	// in which case the body cannot be an .ova.
+	header, _ := body.Peek(ova.DetectionSize)
+	if !ova.IsOva(header) {
+		converted, err := vmwareify.BasicConvertReader(body, options)
+		if err != nil {
+			http.Error(w, err.Error(), statusCodeFor(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", ovfContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(converted.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(converted.Bytes())
+		return
+	}
+
+	// The .ova is streamed to the client as it is converted. The
+	// descriptor is the first file in an .ova, so most errors occur
+	// before anything has been written.
+	lazy := &lazyResponseWriter{
+		w:           w,
+		contentType: ovaContentType,
+	}
+
+	err = vmwareify.ConvertOva(body, lazy, options)
+	if err != nil {
+		if !lazy.wroteHeader {
+			http.Error(w, err.Error(), statusCodeFor(err))
+			return
+		}
+
+		// Abort the connection so that the client does not mistake
+		// the partial response for a complete .ova.
+		panic(http.ErrAbortHandler)
+	}
+
+	lazy.writeHeader()
+}
+
+// lazyResponseWriter delays writing a successful status code until the
+// first write, allowing an error status code to be returned if a failure
+// occurs before any data is produced.
+type lazyResponseWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	wroteHeader bool
+}
+
+func (o *lazyResponseWriter) writeHeader() {
+	if o.wroteHeader {
+		return
+	}
+
+	o.wroteHeader = true
+	o.w.Header().Set("Content-Type", o.contentType)
+	o.w.WriteHeader(http.StatusOK)
+}
+
+func (o *lazyResponseWriter) Write(p []byte) (int, error) {
+	o.writeHeader()
+
+	return o.w.Write(p)
+}
+
+// optionsFromRequest parses the conversion options specified in the
+// request's query parameters.
+func optionsFromRequest(r *http.Request) (vmwareify.BasicConvertOptions, error) {
+	query := r.URL.Query()
+
+	options := vmwareify.BasicConvertOptions{
+		GuestOSProfile:        query.Get(GuestOSParam),
+		NetworkAdapterSubType: query.Get(NicParam),
+		ScsiControllerSubType: query.Get(ScsiParam),
+		Firmware:              query.Get(FirmwareParam),
+		HardwareVersion:       query.Get(HardwareVersionParam),
+		FloppyDrives:          query.Get(FloppyParam),
+		AudioDevices:          query.Get(AudioParam),
+		DiskCapacity:          query.Get(DiskCapacityParam),
+		CpuAllocation:         query.Get(CpuAllocParam),
+		MemoryAllocation:      query.Get(MemoryAllocParam),
+		IpAssignmentSchemes:   query.Get(IpSchemesParam),
+		IpProtocols:           query.Get(IpProtocolsParam),
+		SourceDialect:         query.Get(SourceDialectParam),
+		Locale:                query.Get(LocaleParam),
+		RemoveNetworks:        query[RemoveNetworkParam],
+		AddNetworkAdapters:    query[AddNicParam],
+	}
+
+	bools := []struct {
+		param string
+		value *bool
+	}{
+		{param: AutoParam, value: &options.AutoDetectGuestOS},
+		{param: CpuHotAddParam, value: &options.CpuHotAdd},
+		{param: MemoryHotAddParam, value: &options.MemoryHotAdd},
+		{param: MapDisplayParam, value: &options.MapDisplay},
+		{param: BootOrderParam, value: &options.PreserveBootOrder},
+		{param: VirtualTPMParam, value: &options.VirtualTPM},
+		{param: StripSnapshotsParam, value: &options.StripSnapshotMetadata},
+		{param: IdeToSataParam, value: &options.MigrateIdeDevices},
+		{param: KeepIdeParam, value: &options.KeepIdeControllers},
+		{param: UsbTabletParam, value: &options.UsbTablet},
+		{param: NormalizeModesParam, value: &options.NormalizeOvaModes},
+		{param: StripOwnersParam, value: &options.StripOvaOwnership},
+		{param: ReproducibleParam, value: &options.Reproducible},
+		{param: ProvenanceParam, value: &options.EmbedProvenance},
+		{param: ScrubParam, value: &options.Scrub},
+		{param: AnnotateFoldersParam, value: &options.AnnotateSharedFolders},
+		{param: NormalizeHrefsParam, value: &options.NormalizeHrefs},
+		{param: SortItemsParam, value: &options.SortItems},
+		{param: ValidateParam, value: &options.Validate},
+		{param: KeepBomParam, value: &options.KeepByteOrderMark},
+		{param: PruneReferencesParam, value: &options.PruneReferences},
+	}
+
+	for _, mapping := range query[NetworkParam] {
+		oldName, newName, err := vmwareify.ParseNetworkMapping(mapping)
+		if err != nil {
+			return options, errors.New("invalid '" + NetworkParam + "' parameter value '" + mapping + "'")
+		}
+
+		if options.NetworkMappings == nil {
+			options.NetworkMappings = make(map[string]string)
+		}
+
+		options.NetworkMappings[oldName] = newName
+	}
+
+	for _, b := range bools {
+		raw := query.Get(b.param)
+		if len(raw) == 0 {
+			continue
+		}
+
+		var err error
+		*b.value, err = strconv.ParseBool(raw)
+		if err != nil {
+			return options, errors.New("invalid '" + b.param + "' parameter value '" + raw + "'")
+		}
+	}
+
+	return options, nil
+}
+
+// statusCodeFor maps a conversion error to a HTTP status code.
+func statusCodeFor(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	var limitErr *ovf.LimitError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, xmlutil.ErrDocumentTooLarge) ||
+		errors.As(err, &limitErr) || errors.Is(err, ova.ErrBufferLimit) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	if errors.Is(err, vmwareify.ErrUnsupportedInput) {
+		return http.StatusUnsupportedMediaType
+	}
+
+	var formattingErr *xmlutil.FormattingError
+	if errors.As(err, &formattingErr) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusUnprocessableEntity
+}
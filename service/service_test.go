@@ -0,0 +1,288 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testOvf = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <VirtualSystem ovf:id="test">
+    <Info>A virtual machine</Info>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements for a virtual machine</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>test</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Caption>Ethernet adapter on NAT</rasd:Caption>
+        <rasd:Connection>NAT</rasd:Connection>
+        <rasd:ElementName>Ethernet adapter on NAT</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+)
+
+func postConvert(t *testing.T, config Config, query string, body []byte) *http.Response {
+	server := httptest.NewServer(NewHandler(config))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+ConvertPath+query, ovfContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return resp
+}
+
+func TestConvert(t *testing.T) {
+	resp := postConvert(t, Config{}, "?nic=VmxNet3", []byte(testOvf))
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Got unexpected status code " + resp.Status + " - " + string(raw))
+	}
+
+	expected := strings.Replace(testOvf, "virtualbox-2.2", "vmx-10", 1)
+	expected = strings.Replace(expected, ">E1000<", ">VmxNet3<", 1)
+
+	if string(raw) != expected {
+		t.Fatal("Did not get expected result:\n'" + string(raw) + "'")
+	}
+}
+
+func testOva(t *testing.T, files map[string]string) []byte {
+	buff := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(buff)
+
+	for name, contents := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		tarWriter.Write([]byte(contents))
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return buff.Bytes()
+}
+
+func TestConvertOva(t *testing.T) {
+	resp := postConvert(t, Config{}, "", testOva(t, map[string]string{"test.ovf": testOvf}))
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Got unexpected status code " + resp.Status)
+	}
+
+	if resp.Header.Get("Content-Type") != ovaContentType {
+		t.Fatal("Got unexpected content type '" + resp.Header.Get("Content-Type") + "'")
+	}
+
+	tarReader := tar.NewReader(resp.Body)
+
+	header, err := tarReader.Next()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	raw, err := io.ReadAll(tarReader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if header.Name != "test.ovf" || !strings.Contains(string(raw), "vmx-10") {
+		t.Fatal("Did not get expected descriptor '" + header.Name + "':\n'" + string(raw) + "'")
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		query    string
+		body     []byte
+		expected int
+	}{
+		{
+			name:     "too large",
+			config:   Config{MaxRequestBytes: 512},
+			body:     []byte(testOvf),
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "descriptor too large",
+			config:   Config{MaxDescriptorBytes: 512},
+			body:     testOva(t, map[string]string{"test.ovf": testOvf}),
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "ova buffer too large",
+			config:   Config{MaxOvaBufferBytes: 512},
+			body:     testOva(t, map[string]string{"test.ovf": testOvf}),
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "not an ovf",
+			body:     []byte("<html></html>"),
+			expected: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "malformed",
+			body:     []byte(strings.Replace(testOvf, "</System>", "", 1)),
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid option",
+			query:    "?auto=junk",
+			body:     []byte(testOvf),
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid hot add option",
+			query:    "?cpu-hot-add=junk",
+			body:     []byte(testOvf),
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported option",
+			query:    "?firmware=junk",
+			body:     []byte(testOvf),
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "unsupported hardware version",
+			query:    "?vtpm=true&hardware-version=vmx-13",
+			body:     []byte(testOvf),
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "ova without descriptor",
+			body:     testOva(t, map[string]string{"test.mf": "SHA256(test.ovf)= 00\n"}),
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "ova with malformed descriptor",
+			body:     testOva(t, map[string]string{"test.ovf": "<Envelope>"}),
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		resp := postConvert(t, test.config, test.query, test.body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.expected {
+			t.Fatal("Test '" + test.name + "' got unexpected status code " + resp.Status)
+		}
+	}
+}
+
+func TestConvertMethodNotAllowed(t *testing.T) {
+	server := httptest.NewServer(NewHandler(Config{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + ConvertPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatal("Got unexpected status code " + resp.Status)
+	}
+}
+
+func TestConvertWarnings(t *testing.T) {
+	input := strings.Replace(testOvf, "    <VirtualHardwareSection>",
+		"    <vbox:Machine xmlns:vbox=\"http://www.virtualbox.org/ovf/machine\">\n"+
+			"      <Hardware>\n"+
+			"        <TrustedPlatformModule type=\"v2_0\"/>\n"+
+			"      </Hardware>\n"+
+			"    </vbox:Machine>\n"+
+			"    <VirtualHardwareSection>", 1)
+
+	resp := postConvert(t, Config{}, "", []byte(input))
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Got unexpected status code " + resp.Status)
+	}
+
+	warnings := resp.Header.Values(WarningHeader)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "TPM") {
+		t.Fatal("Did not get expected warning header")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	config := Config{Metrics: NewMetrics()}
+
+	resp := postConvert(t, config, "", []byte(testOvf))
+	resp.Body.Close()
+
+	resp = postConvert(t, config, "", testOva(t, map[string]string{"test.ovf": testOvf}))
+	resp.Body.Close()
+
+	resp = postConvert(t, config, "", []byte("<html></html>"))
+	resp.Body.Close()
+
+	server := httptest.NewServer(NewHandler(config))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + MetricsPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, expected := range []string{
+		"vmwareify_conversions_total{type=\"ovf\"} 2\n",
+		"vmwareify_conversions_total{type=\"ova\"} 1\n",
+		"vmwareify_conversion_failures_total{class=\"unsupported_media_type\"} 1\n",
+		"vmwareify_conversion_duration_seconds_count 3\n",
+	} {
+		if !strings.Contains(string(raw), expected) {
+			t.Fatal("Metrics do not contain '" + expected + "':\n" + string(raw))
+		}
+	}
+
+	if strings.Contains(string(raw), "vmwareify_read_bytes_total 0\n") {
+		t.Fatal("Expected bytes read to be counted:\n" + string(raw))
+	}
+}
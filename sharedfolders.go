@@ -0,0 +1,85 @@
+package vmwareify
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	sharedFoldersAnnotationInfo = "A description of the virtual machine"
+)
+
+// warnSharedFolders reports a warning if the VirtualBox machine shares
+// folders of its host with the guest, which are not converted.
+func warnSharedFolders(machine ovf.VboxMachine, options BasicConvertOptions) {
+	folders := machine.Hardware.SharedFolders
+	if len(folders) == 0 {
+		return
+	}
+
+	options.warn("the VirtualBox machine has shared folders (" + describeSharedFolders(folders, !options.Scrub) +
+		") that will not be converted - configure VMWare Shared Folders after the virtual machine is " +
+		"imported, which requires VMWare Tools (vmhgfs) in the guest")
+}
+
+// describeSharedFolders returns a description of VirtualBox shared
+// folders, optionally including their host paths.
+func describeSharedFolders(folders []ovf.VboxSharedFolder, includeHostPaths bool) string {
+	var descriptions []string
+	for _, folder := range folders {
+		description := "'" + folder.Name + "'"
+
+		var details []string
+		if includeHostPaths && len(folder.HostPath) > 0 {
+			details = append(details, "host path '"+folder.HostPath+"'")
+		}
+
+		if !strings.EqualFold(folder.Writable, "true") {
+			details = append(details, "read-only")
+		}
+
+		if len(details) > 0 {
+			description = description + " (" + strings.Join(details, ", ") + ")"
+		}
+
+		descriptions = append(descriptions, description)
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// annotateSharedFolders adds a note about the VirtualBox machine's shared
+// folders to the VirtualSystem's annotation, which VMWare shows as the
+// virtual machine's notes (see BasicConvertOptions.AnnotateSharedFolders).
+// The existing annotation is kept.
+func annotateSharedFolders(edited *bytes.Buffer, parsed ovf.Ovf, options BasicConvertOptions, recorder *editRecorder) (*bytes.Buffer, error) {
+	virtualSystem := parsed.Envelope.VirtualSystem
+
+	folders := virtualSystem.Machine.Hardware.SharedFolders
+	if len(folders) == 0 {
+		return edited, nil
+	}
+
+	// The host paths are omitted when scrubbing, as they identify the
+	// system the .ovf was exported from.
+	annotation := "The VirtualBox machine shared the following folders with the guest, which must be " +
+		"configured as VMWare Shared Folders (requires VMWare Tools): " +
+		describeSharedFolders(folders, !options.Scrub) + "."
+
+	info := virtualSystem.AnnotationSection.Info
+	if len(info) == 0 {
+		info = sharedFoldersAnnotationInfo
+	}
+
+	existing := strings.TrimSpace(virtualSystem.AnnotationSection.Annotation)
+	if len(existing) > 0 {
+		annotation = existing + "\n\n" + annotation
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.SetAnnotationFunc(info, annotation),
+			"the VirtualBox shared folders are described in the annotation, as a reminder to configure "+
+				"VMWare Shared Folders"), ovf.VirtualSystemName))
+}
@@ -0,0 +1,98 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+)
+
+func sharedFoldersOvf(t *testing.T) string {
+	input := strings.Replace(basicOvfFileContents, "      </Hardware>",
+		"        <SharedFolders>\n"+
+			"          <SharedFolder name=\"projects\" hostPath=\"/Users/someone/projects\" writable=\"true\" autoMount=\"true\"/>\n"+
+			"          <SharedFolder name=\"media\" hostPath=\"/Volumes/media\" writable=\"false\" autoMount=\"false\"/>\n"+
+			"        </SharedFolders>\n"+
+			"      </Hardware>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to add shared folders to the test input")
+	}
+
+	return input
+}
+
+func TestBasicConvertSharedFoldersWarning(t *testing.T) {
+	var warnings []string
+
+	b, err := basicConvertWithOptions(strings.NewReader(sharedFoldersOvf(t)), BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	found := false
+	for _, warning := range warnings {
+		if strings.Contains(warning, "shared folders ('projects' (host path '/Users/someone/projects'), "+
+			"'media' (host path '/Volumes/media', read-only))") {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("Did not get a warning about the shared folders - got: %q", warnings)
+	}
+
+	if strings.Contains(b.String(), "AnnotationSection") {
+		t.Fatal("The output was annotated without the option:\n" + b.String())
+	}
+}
+
+func TestBasicConvertAnnotateSharedFolders(t *testing.T) {
+	input := strings.Replace(sharedFoldersOvf(t), "    <vbox:Machine",
+		"    <AnnotationSection>\n"+
+			"      <Info>Notes</Info>\n"+
+			"      <Annotation>Build server</Annotation>\n"+
+			"    </AnnotationSection>\n"+
+			"    <vbox:Machine", 1)
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{
+		AnnotateSharedFolders: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := toOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	section := parsed.Envelope.VirtualSystem.AnnotationSection
+	if section.Info != "Notes" || !strings.HasPrefix(section.Annotation, "Build server\n\n") ||
+		!strings.Contains(section.Annotation, "'projects' (host path '/Users/someone/projects')") {
+		t.Fatalf("Got unexpected AnnotationSection: %+v", section)
+	}
+
+	if strings.Count(b.String(), "<AnnotationSection>") != 1 {
+		t.Fatal("The output does not contain exactly one AnnotationSection:\n" + b.String())
+	}
+
+	b, err = basicConvertWithOptions(strings.NewReader(sharedFoldersOvf(t)), BasicConvertOptions{
+		AnnotateSharedFolders: true,
+		Scrub:                 true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err = toOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	annotation := parsed.Envelope.VirtualSystem.AnnotationSection.Annotation
+	if !strings.Contains(annotation, "'projects', 'media' (read-only)") || strings.Contains(b.String(), "/Users/someone") {
+		t.Fatal("The scrubbed annotation is unexpected:\n" + b.String())
+	}
+}
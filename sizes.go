@@ -0,0 +1,102 @@
+package vmwareify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/stephen-fox/vmwareify/internal/vmdk"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// recomputeSizeEdits returns the explained edits that set the size of
+// each References File, and the explained edits that set the
+// populatedSize of each Disk, to the values of the referenced files. The
+// files are located in BasicConvertOptions.ReferencesDir. Files that
+// cannot be read are reported as warnings.
+func recomputeSizeEdits(parsed ovf.Ovf, options BasicConvertOptions) ([]explainedFunc, []explainedFunc) {
+	if !options.RecomputeSizes {
+		return nil, nil
+	}
+
+	if len(options.ReferencesDir) == 0 {
+		options.warn("file sizes cannot be recomputed because the directory containing the referenced " +
+			"files is unknown")
+		return nil, nil
+	}
+
+	var fileEdits []explainedFunc
+	var diskEdits []explainedFunc
+
+	for _, file := range parsed.Envelope.References.Files {
+		if ovf.IsRemoteHref(file.Href) {
+			continue
+		}
+
+		filePath := filepath.Join(options.ReferencesDir, filepath.FromSlash(file.Href))
+
+		size, populatedSize, err := referencedFileSizes(filePath)
+		if err != nil {
+			options.warn("the size of referenced file '" + file.Href + "' cannot be recomputed - " + err.Error())
+			continue
+		}
+
+		sizeStr := strconv.FormatInt(size, 10)
+		if file.Size != sizeStr {
+			fileEdits = append(fileEdits, explainedFunc{
+				f:      ovf.SetFileSizeFunc(file.Id, size),
+				reason: "the file's size is recomputed from '" + file.Href + "' (" + sizeStr + " bytes)",
+			})
+		}
+
+		if populatedSize < 0 {
+			continue
+		}
+
+		populatedSizeStr := strconv.FormatInt(populatedSize, 10)
+		for _, disk := range parsed.Envelope.DiskSection.Disks {
+			if disk.FileRef != file.Id || disk.PopulatedSize == populatedSizeStr {
+				continue
+			}
+
+			diskEdits = append(diskEdits, explainedFunc{
+				f: ovf.SetDiskPopulatedSizeFunc(disk.DiskId, populatedSize),
+				reason: "the disk's populated size is recomputed from '" + file.Href + "' (" +
+					populatedSizeStr + " bytes)",
+			})
+		}
+	}
+
+	return fileEdits, diskEdits
+}
+
+// referencedFileSizes returns the size of a referenced file, and the
+// populated size of the disk it contains. The populated size is -1 if the
+// file is not a VMDK sparse extent.
+func referencedFileSizes(filePath string) (int64, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return 0, 0, errors.New("file is not a regular file")
+	}
+
+	populatedSize, err := vmdk.PopulatedSize(f, info.Size())
+	if errors.Is(err, vmdk.ErrNotSparse) {
+		return info.Size(), -1, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return info.Size(), populatedSize, nil
+}
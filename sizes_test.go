@@ -0,0 +1,76 @@
+package vmwareify
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBasicConvertRecomputeSizes(t *testing.T) {
+	dir := t.TempDir()
+
+	// A stream-optimized sparse extent with a capacity of 128
+	// sectors, containing a single grain of 16 sectors.
+	disk := make([]byte, 3*512)
+	binary.LittleEndian.PutUint32(disk[0:], 0x564d444b)
+	binary.LittleEndian.PutUint32(disk[8:], 1<<16|1<<17)
+	binary.LittleEndian.PutUint64(disk[12:], 128)
+	binary.LittleEndian.PutUint64(disk[20:], 16)
+	binary.LittleEndian.PutUint32(disk[44:], 512)
+	binary.LittleEndian.PutUint64(disk[64:], 1)
+	binary.LittleEndian.PutUint32(disk[512+8:], 100)
+
+	err := os.WriteFile(filepath.Join(dir, "centos-0.0.1-disk001.vmdk"), disk, 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	existing := strings.Replace(basicOvfFileContents,
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk"/>`,
+		`<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk" ovf:size="1"/>
+    <File ovf:id="file2" ovf:href="missing.iso"/>`, 1)
+
+	var warnings []string
+	b, err := basicConvertWithOptions(strings.NewReader(existing), BasicConvertOptions{
+		RecomputeSizes: true,
+		ReferencesDir:  dir,
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+	if !strings.Contains(result, `<File ovf:id="file1" ovf:href="centos-0.0.1-disk001.vmdk" ovf:size="1536"/>`) {
+		t.Fatalf("file size was not recomputed - got:\n%s", result)
+	}
+
+	if !strings.Contains(result, `ovf:populatedSize="8192"`) {
+		t.Fatalf("disk populated size was not recomputed - got:\n%s", result)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "missing.iso") {
+		t.Fatalf("expected a warning for the missing file - got %q", warnings)
+	}
+}
+
+func TestBasicConvertRecomputeSizesWithoutReferencesDir(t *testing.T) {
+	var warnings []string
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		RecomputeSizes: true,
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "ovf:size") || len(warnings) != 1 {
+		t.Fatalf("expected sizes to be left as-is with a warning - got %q", warnings)
+	}
+}
@@ -0,0 +1,39 @@
+package vmwareify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ErrSnapshots is returned when the .ovf was exported along with
+// VirtualBox snapshots, which VMWare cannot import.
+var ErrSnapshots = errors.New("the virtual machine was exported with snapshots - " +
+	"export it without snapshots or flatten its disks")
+
+// checkSnapshots returns ErrSnapshots if the .ovf has differencing disks,
+// or if it has snapshot metadata that the BasicConvertOptions do not allow
+// to be stripped. It returns true if the snapshot metadata should be
+// stripped.
+func checkSnapshots(parsed ovf.Ovf, options BasicConvertOptions) (bool, error) {
+	disks := ovf.DifferencingDisks(parsed)
+	if len(disks) > 0 {
+		return false, fmt.Errorf("%w (differencing disks: '%s')", ErrSnapshots, strings.Join(disks, "', '"))
+	}
+
+	machine := parsed.Envelope.VirtualSystem.Machine
+	if !ovf.HasVboxSnapshots(machine) {
+		return false, nil
+	}
+
+	if !options.StripSnapshotMetadata {
+		return false, fmt.Errorf("%w, or strip the snapshot metadata if the disks are already flattened", ErrSnapshots)
+	}
+
+	options.warn("the VirtualBox snapshot metadata was removed - the virtual machine is converted " +
+		"in its current state, and its snapshots are lost")
+
+	return true, nil
+}
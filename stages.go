@@ -0,0 +1,542 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
+)
+
+const (
+	// ParseStage reads and checks the .ovf, fixes the known problems
+	// of the tool that exported it, and parses it.
+	ParseStage ConversionStage = "parse"
+
+	// PlanStage chooses the virtual hardware, and builds the
+	// EditScheme that converts the .ovf. IDE devices are migrated to
+	// SATA during the stage if requested, as the hardware is chosen
+	// from the migrated .ovf.
+	PlanStage ConversionStage = "plan"
+
+	// EditStage applies the EditScheme, followed by the edits that
+	// depend on its result (e.g., adding network adapters).
+	EditStage ConversionStage = "edit"
+
+	// FinalizeStage sorts the Items, embeds the provenance, and
+	// validates the converted .ovf.
+	FinalizeStage ConversionStage = "finalize"
+
+	// WriteStage writes the converted .ovf, along with the files it
+	// references, when converting a file using
+	// BasicConvertWithOptions. Otherwise, the converted .ovf is
+	// returned (or written to an .ova) once the stage is complete.
+	WriteStage ConversionStage = "write"
+)
+
+// ConversionStage is a stage of a conversion. Conversions run the
+// stages in the following order: ParseStage, PlanStage, EditStage,
+// FinalizeStage, and WriteStage.
+type ConversionStage string
+
+// ConversionState is the state of a conversion, which is shared by its
+// stages. It is provided to BasicConvertOptions.BeforeStage and
+// BasicConvertOptions.AfterStage, which may modify it to customize the
+// stages that follow.
+type ConversionState struct {
+	// Options are the options of the conversion. Changes affect the
+	// stages that follow.
+	Options BasicConvertOptions
+
+	// Descriptor is the .ovf descriptor. It is the original .ovf
+	// before ParseStage, the edited .ovf once EditStage is complete,
+	// and the converted .ovf once FinalizeStage is complete. Changes
+	// are used by the stages that follow (e.g., a custom edit may be
+	// made after EditStage).
+	Descriptor []byte
+
+	// Parsed is the .ovf as it was parsed during ParseStage, before
+	// it was edited.
+	Parsed ovf.Ovf
+
+	// EditScheme is the EditScheme built during PlanStage, which is
+	// applied during EditStage. Additional EditObjectFunc may be
+	// proposed after PlanStage.
+	EditScheme ovf.EditScheme
+
+	// OutputPath is the path of the converted .ovf written during
+	// WriteStage. It is empty if the conversion does not write a
+	// file.
+	OutputPath string
+}
+
+// StageHook is called before or after a stage of a conversion (see
+// BasicConvertOptions.BeforeStage). A non-nil error fails the
+// conversion.
+type StageHook func(stage ConversionStage, state *ConversionState) error
+
+// conversion is a conversion that is run in stages.
+type conversion struct {
+	state    ConversionState
+	recorder *editRecorder
+
+	// original is the .ovf as it was read.
+	original []byte
+
+	// hasByteOrderMark is true if the original .ovf started with a
+	// UTF-8 byte order mark.
+	hasByteOrderMark bool
+
+	// originalItems are the Items of the parsed .ovf.
+	originalItems []ovf.Item
+
+	hardware hardwareChoices
+
+	// write, if non-nil, writes the converted .ovf during WriteStage.
+	write func(state *ConversionState) error
+}
+
+// convert performs the conversion, recording each edit if the
+// *editRecorder is non-nil.
+func convert(existing io.Reader, options BasicConvertOptions, recorder *editRecorder) (*bytes.Buffer, error) {
+	return convertAndWrite(existing, options, recorder, "", nil)
+}
+
+// convertAndWrite performs the conversion, and calls the write func, if
+// it is non-nil, during WriteStage to write the converted .ovf to the
+// output path.
+func convertAndWrite(existing io.Reader, options BasicConvertOptions, recorder *editRecorder, outputPath string, write func(state *ConversionState) error) (*bytes.Buffer, error) {
+	if options.OnEdit != nil {
+		if recorder == nil {
+			recorder = &editRecorder{}
+		}
+
+		recorder.onEdit = options.OnEdit
+	}
+
+	raw, err := xmlutil.ReadAllLimit(existing, options.maxDescriptorBytes())
+	if err != nil {
+		return bytes.NewBuffer(nil), err
+	}
+
+	c := &conversion{
+		state: ConversionState{
+			Options:    options,
+			Descriptor: raw,
+			OutputPath: outputPath,
+		},
+		recorder: recorder,
+		write:    write,
+	}
+
+	stages := []struct {
+		stage ConversionStage
+		run   func() error
+	}{
+		{stage: ParseStage, run: c.parse},
+		{stage: PlanStage, run: c.plan},
+		{stage: EditStage, run: c.edit},
+		{stage: FinalizeStage, run: c.finalize},
+		{stage: WriteStage, run: c.writeConverted},
+	}
+
+	for _, s := range stages {
+		err := c.runStage(s.stage, s.run)
+		if err != nil {
+			return bytes.NewBuffer(nil), err
+		}
+	}
+
+	return bytes.NewBuffer(c.state.Descriptor), nil
+}
+
+// runStage runs a stage, along with its hooks.
+func (o *conversion) runStage(stage ConversionStage, run func() error) error {
+	if o.state.Options.BeforeStage != nil {
+		err := o.state.Options.BeforeStage(stage, &o.state)
+		if err != nil {
+			return fmt.Errorf("hook before %s stage failed - %w", stage, err)
+		}
+	}
+
+	err := run()
+	if err != nil {
+		return err
+	}
+
+	if o.state.Options.AfterStage != nil {
+		err := o.state.Options.AfterStage(stage, &o.state)
+		if err != nil {
+			return fmt.Errorf("hook after %s stage failed - %w", stage, err)
+		}
+	}
+
+	return nil
+}
+
+func (o *conversion) parse() error {
+	options := o.state.Options
+
+	err := checkIsOvf(o.state.Descriptor)
+	if err != nil {
+		return err
+	}
+
+	// The .ovf may be edited before the edit scheme is applied.
+	o.original = o.state.Descriptor
+
+	raw, hasByteOrderMark := xmlutil.TrimByteOrderMark(o.state.Descriptor)
+	o.hasByteOrderMark = hasByteOrderMark
+
+	// The .ovf is parsed before it is edited, so formatting errors
+	// must be found first to report their location.
+	err = xmlutil.ValidateFormatting(raw)
+	if err != nil {
+		return err
+	}
+
+	// The descriptor's size was limited when it was read.
+	err = ovf.CheckLimits(raw, ovf.Limits{MaxDescriptorBytes: -1})
+	if err != nil {
+		return err
+	}
+
+	dialect, err := resolveDialect(raw, options)
+	if err != nil {
+		return err
+	}
+
+	raw, err = fixDialectQuirks(raw, dialect, options, o.recorder)
+	if err != nil {
+		return err
+	}
+
+	o.state.Parsed, err = toOvf(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	// The .ovf is localized once it is parsed, so that the Files used
+	// by the Strings bundles are known (see pruneReferences).
+	if len(options.Locale) > 0 {
+		raw, err = localize(raw, options.Locale)
+		if err != nil {
+			return err
+		}
+	}
+
+	o.state.Descriptor = raw
+
+	return nil
+}
+
+func (o *conversion) plan() error {
+	options := o.state.Options
+	parsed := o.state.Parsed
+	recorder := o.recorder
+	raw := o.state.Descriptor
+
+	stripSnapshots, err := checkSnapshots(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	o.originalItems = parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	warnSharedDisks(o.originalItems, options)
+	warnSharedFolders(parsed.Envelope.VirtualSystem.Machine, options)
+
+	switch {
+	case options.MigrateIdeDevices && options.KeepIdeControllers:
+		return errors.New("IDE devices cannot be migrated to SATA when the IDE controllers are kept")
+	case options.MigrateIdeDevices:
+		raw, err = migrateIdeDevices(raw, recorder)
+		if err != nil {
+			return err
+		}
+
+		o.state.Descriptor = raw
+	case !options.KeepIdeControllers:
+		warnIdeDevices(o.originalItems, options)
+	}
+
+	hardware, err := resolveHardware(raw, options)
+	if err != nil {
+		return err
+	}
+
+	o.hardware = hardware
+
+	editScheme := ovf.NewEditScheme().
+		Propose(recorder.explain(SetVirtualSystemTypeFunc(hardware.virtualSystemType()),
+			"VMWare requires a VMWare virtual hardware version ('"+hardware.virtualSystemType()+"')"),
+			ovf.VirtualHardwareSystemName)
+
+	if options.KeepIdeControllers {
+		editScheme.Propose(recorder.explain(NormalizeIdeControllersFunc(),
+			"IDE controllers are kept, and their model is set to '"+esxiIdeControllerSubType+"', which ESXi supports"),
+			ovf.VirtualHardwareItemName)
+	} else {
+		editScheme.Propose(recorder.explain(RemoveIdeControllersFunc(-1),
+			"IDE controllers are removed (matched by the name prefix 'ideController', the description "+
+				"'IDE Controller', or resource type "+ovf.IdeControllerResourceType+")"),
+			ovf.VirtualHardwareItemName)
+	}
+
+	// virtio controllers are converted before SATA controllers,
+	// since they may have the same resource type.
+	for _, f := range virtioEdits(hardware) {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName)
+	}
+
+	editScheme.
+		Propose(recorder.explain(ConvertSataControllersFunc(),
+			"SATA controllers (resource type "+ovf.SataControllerResourceType+") are converted to the VMWare "+
+				"'vmware.sata.ahci' controller"),
+			ovf.VirtualHardwareItemName).
+		Propose(recorder.explain(DisableCdromAutomaticAllocationFunc(),
+			"automatic allocation is disabled for CD/DVD drives (resource type "+ovf.CdDriveResourceType+")"),
+			ovf.VirtualHardwareItemName)
+
+	for _, f := range hardware.profile.explainedEditObjectFuncs() {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName)
+	}
+
+	resourceFuncs, err := resourceAllocationEdits(options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range resourceFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName)
+	}
+
+	floppyFuncs, floppyFiles, err := floppyEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range floppyFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName)
+	}
+
+	for _, id := range floppyFiles {
+		editScheme.Propose(recorder.explain(ovf.DeleteFileFunc(id),
+			"the file is only used by a removed floppy drive"), ovf.ReferencesFileName)
+	}
+
+	audioFuncs, err := audioEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range audioFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName)
+	}
+
+	removeNetworkFuncs, err := removeNetworkEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range removeNetworkFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.NetworkName).
+			Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName).
+			Propose(recorder.explain(f.f, f.reason), ovf.EthernetPortItemName)
+	}
+
+	networkFuncs, connectionFuncs, err := networkMappingEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range networkFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.NetworkName)
+	}
+
+	for _, f := range connectionFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.VirtualHardwareItemName).
+			Propose(recorder.explain(f.f, f.reason), ovf.EthernetPortItemName)
+	}
+
+	diskCapacityFuncs, err := diskCapacityEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range diskCapacityFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.DiskName)
+	}
+
+	// Disk files are renamed, and their sizes are recomputed, after
+	// their hrefs are normalized.
+	hrefFuncs, err := normalizeHrefEdits(&parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range hrefFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.ReferencesFileName)
+	}
+
+	// Sizes are recomputed from the files before they are renamed.
+	fileSizeFuncs, diskSizeFuncs := recomputeSizeEdits(parsed, options)
+
+	for _, f := range fileSizeFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.ReferencesFileName)
+	}
+
+	for _, f := range diskSizeFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.DiskName)
+	}
+
+	diskFileFuncs, err := renameDiskFileEdits(parsed, options)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range diskFileFuncs {
+		editScheme.Propose(recorder.explain(f.f, f.reason), ovf.ReferencesFileName)
+	}
+
+	if stripSnapshots {
+		editScheme.Propose(recorder.explain(ovf.StripVboxSnapshotsFunc(),
+			"the VirtualBox snapshot metadata is removed"), ovf.VboxMachineName)
+	}
+
+	o.state.EditScheme = editScheme
+
+	return nil
+}
+
+func (o *conversion) edit() error {
+	options := o.state.Options
+	recorder := o.recorder
+	hardware := o.hardware
+
+	buff, err := editRawOvf(bytes.NewReader(o.state.Descriptor), o.state.EditScheme)
+	if err != nil {
+		return err
+	}
+
+	buff, err = reassignAddressesOnParent(buff, recorder)
+	if err != nil {
+		return err
+	}
+
+	buff, err = expandSataPortCount(buff, recorder)
+	if err != nil {
+		return err
+	}
+
+	if hardware.virtualTPM {
+		buff, err = addVirtualTPM(buff, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(options.AddNetworkAdapters) > 0 {
+		buff, err = addNetworkAdapters(buff, options.AddNetworkAdapters, hardware, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.UsbTablet {
+		buff, err = addUsbController(buff, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	configs := hardware.vmwConfigs()
+	if len(configs) > 0 {
+		buff, err = setVmwConfigs(buff, configs, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(options.IpAssignmentSchemes) > 0 {
+		buff, err = setIpAssignment(buff, options, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.AnnotateSharedFolders {
+		buff, err = annotateSharedFolders(buff, o.state.Parsed, options, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Scrub {
+		buff, err = scrub(buff, recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	buff, err = pruneReferences(buff, o.state.Parsed, options, recorder)
+	if err != nil {
+		return err
+	}
+
+	o.state.Descriptor = buff.Bytes()
+
+	return nil
+}
+
+func (o *conversion) finalize() error {
+	options := o.state.Options
+	buff := bytes.NewBuffer(o.state.Descriptor)
+
+	// Items are sorted once every Item has been added.
+	if options.SortItems {
+		sorted, _, err := ovf.SortItems(buff.Bytes())
+		if err != nil {
+			return err
+		}
+
+		buff = bytes.NewBuffer(sorted)
+	}
+
+	if options.EmbedProvenance {
+		var err error
+		buff, err = addProvenance(buff, provenanceProperties(o.original, o.hardware, options), o.recorder)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := checkSharedDisks(buff, o.originalItems)
+	if err != nil {
+		return err
+	}
+
+	if options.Validate {
+		err = validateConverted(buff, options.ValidationRules)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.hasByteOrderMark && options.KeepByteOrderMark {
+		buff = bytes.NewBuffer(append([]byte(xmlutil.ByteOrderMark), buff.Bytes()...))
+	}
+
+	o.state.Descriptor = buff.Bytes()
+
+	return nil
+}
+
+func (o *conversion) writeConverted() error {
+	if o.write == nil {
+		return nil
+	}
+
+	return o.write(&o.state)
+}
@@ -0,0 +1,100 @@
+package vmwareify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertStageHooks(t *testing.T) {
+	var stages []string
+
+	b, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		BeforeStage: func(stage ConversionStage, state *ConversionState) error {
+			stages = append(stages, "before "+string(stage))
+			return nil
+		},
+		AfterStage: func(stage ConversionStage, state *ConversionState) error {
+			stages = append(stages, "after "+string(stage))
+
+			if stage == PlanStage {
+				state.EditScheme.Propose(ovf.DeleteNetworkFunc("NAT"), ovf.NetworkName)
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "before parse,after parse,before plan,after plan,before edit,after edit," +
+		"before finalize,after finalize,before write,after write"
+	if strings.Join(stages, ",") != expected {
+		t.Fatalf("Got unexpected stages %q", stages)
+	}
+
+	if strings.Contains(b.String(), `<Network ovf:name="NAT">`) {
+		t.Fatal("Expected the network to be deleted by the proposed func:\n" + b.String())
+	}
+}
+
+func TestBasicConvertStageHookError(t *testing.T) {
+	hookErr := errors.New("custom step failed")
+
+	_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		BeforeStage: func(stage ConversionStage, state *ConversionState) error {
+			if stage == FinalizeStage {
+				return hookErr
+			}
+
+			return nil
+		},
+	})
+	if !errors.Is(err, hookErr) || !strings.Contains(err.Error(), "finalize") {
+		t.Fatalf("Got unexpected error - %v", err)
+	}
+}
+
+func TestBasicConvertWithOptionsWriteStage(t *testing.T) {
+	dir := t.TempDir()
+
+	ovfFilePath := filepath.Join(dir, "centos.ovf")
+	err := os.WriteFile(ovfFilePath, []byte(basicOvfFileContents), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	newFilePath := filepath.Join(dir, "centos-vmware.ovf")
+
+	err = BasicConvertWithOptions(ovfFilePath, newFilePath, BasicConvertOptions{
+		AfterStage: func(stage ConversionStage, state *ConversionState) error {
+			if stage != WriteStage {
+				return nil
+			}
+
+			written, err := os.ReadFile(state.OutputPath)
+			if err != nil {
+				return err
+			}
+
+			if string(written) != string(state.Descriptor) {
+				return errors.New("the written .ovf is not the converted .ovf")
+			}
+
+			return os.WriteFile(state.OutputPath+".manifest", []byte("custom"), 0600)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = os.Stat(newFilePath + ".manifest")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
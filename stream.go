@@ -0,0 +1,57 @@
+package vmwareify
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+// Convert reads a .ovf or .ova from in, and writes the VMWare friendly
+// result to out. The type of input is detected from its contents.
+//
+// An .ova is converted in a single pass (see ConvertOva), which allows
+// it to be piped from stdin to stdout without temporary disk space.
+// It returns true if the input was an .ova.
+func Convert(in io.Reader, out io.Writer, options BasicConvertOptions) (bool, error) {
+	buffered := bufio.NewReaderSize(in, ova.DetectionSize)
+
+	// Peek returns an error if the input is shorter than a tar
+	// header, in which case it cannot be an .ova.
+	header, _ := buffered.Peek(ova.DetectionSize)
+	if ova.IsOva(header) {
+		return true, ConvertOva(buffered, out, options)
+	}
+
+	converted, err := BasicConvertReader(buffered, options)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = out.Write(converted.Bytes())
+	return false, err
+}
+
+// ConvertOva reads an .ova from in, and writes a VMWare friendly .ova to
+// out. The .ovf descriptor is converted in the same manner as
+// BasicConvertWithOptions, and its digest is updated in the manifest.
+// Disk images are copied as-is without being buffered. The tar headers
+// are kept unless NormalizeOvaModes, StripOvaOwnership, or Reproducible
+// is set.
+func ConvertOva(in io.Reader, out io.Writer, options BasicConvertOptions) error {
+	if len(options.DiskFileSuffix) > 0 {
+		return errors.New("disk files cannot be renamed when converting an .ova")
+	}
+
+	meter, in := startMeter(options, in)
+	counting := &countingWriter{w: out}
+
+	err := ova.RewriteWithConfig(in, counting, func(descriptor io.Reader) (*bytes.Buffer, error) {
+		return basicConvertWithOptions(descriptor, options)
+	}, options.rewriteConfig())
+	meter.finish(true, counting.n, err)
+
+	return err
+}
@@ -0,0 +1,124 @@
+package vmwareify
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConvertOvf(t *testing.T) {
+	output := bytes.NewBuffer(nil)
+
+	isOva, err := Convert(strings.NewReader(basicOvfFileContents), output, BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if isOva {
+		t.Fatal("Descriptor was detected as an .ova")
+	}
+
+	expected, err := basicConvert(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if output.String() != expected.String() {
+		t.Fatal("Did not get expected result:\n'" + output.String() + "'")
+	}
+}
+
+func TestConvertOva(t *testing.T) {
+	input := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(input)
+	for _, name := range []string{"centos.ovf", "centos-0.0.1-disk001.vmdk"} {
+		contents := basicOvfFileContents
+		if strings.HasSuffix(name, ".vmdk") {
+			contents = "disk"
+		}
+
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		tarWriter.Write([]byte(contents))
+	}
+	tarWriter.Close()
+
+	output := bytes.NewBuffer(nil)
+
+	isOva, err := Convert(input, output, BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !isOva {
+		t.Fatal(".ova was not detected")
+	}
+
+	expected, err := basicConvert(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tarReader := tar.NewReader(output)
+	_, err = tarReader.Next()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	descriptor, err := io.ReadAll(tarReader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(descriptor) != expected.String() {
+		t.Fatal("Did not get expected descriptor:\n'" + string(descriptor) + "'")
+	}
+}
+
+func TestConvertOvaNormalizesHeaders(t *testing.T) {
+	input := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(input)
+	for _, name := range []string{"centos.ovf", "centos-0.0.1-disk001.vmdk"} {
+		contents := basicOvfFileContents
+		if strings.HasSuffix(name, ".vmdk") {
+			contents = "disk"
+		}
+
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Uid: 501, Gid: 20, Uname: "builder",
+			Gname: "staff", Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		tarWriter.Write([]byte(contents))
+	}
+	tarWriter.Close()
+
+	output := bytes.NewBuffer(nil)
+
+	err := ConvertOva(input, output, BasicConvertOptions{
+		NormalizeOvaModes: true,
+		StripOvaOwnership: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tarReader := tar.NewReader(output)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if header.Mode != 0644 || header.Uid != 0 || header.Gid != 0 || len(header.Uname) > 0 || len(header.Gname) > 0 {
+			t.Fatalf("Header of '%s' was not normalized - %+v", header.Name, header)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package vmwareify
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	// vmwUsbEhciSubType and vmwUsbXhciSubType are the ResourceSubTypes
+	// of the VMWare USB 2.0 (EHCI) and USB 3.0 (xHCI) controllers.
+	vmwUsbEhciSubType = "vmware.usb.ehci"
+	vmwUsbXhciSubType = "vmware.usb.xhci"
+
+	// vmwUsbSubTypePrefix is the prefix of the ResourceSubType of every
+	// VMWare USB controller.
+	vmwUsbSubTypePrefix = "vmware.usb."
+)
+
+// usbTabletExtraConfigs returns the .vmx settings that attach a USB
+// tablet to the virtual machine's USB controller. VMWare does not model
+// USB devices as Items.
+func usbTabletExtraConfigs() []vmwConfig {
+	return []vmwConfig{
+		{extra: true, key: "usb:1.present", value: "TRUE"},
+		{extra: true, key: "usb:1.deviceType", value: "tablet"},
+	}
+}
+
+// addUsbController adds a VMWare USB controller to an edited .ovf, unless
+// it already has one, so that a USB tablet can be attached to it (see
+// BasicConvertOptions.UsbTablet). USB controllers of other tools (e.g., a
+// VirtualBox 'OHCI' and 'EHCI' controller) are converted to a single
+// VMWare USB controller.
+func addUsbController(edited *bytes.Buffer, recorder *editRecorder) (*bytes.Buffer, error) {
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+
+	var controllers []ovf.Item
+	for _, item := range items {
+		if item.ResourceType != ovf.UsbControllerResourceType {
+			continue
+		}
+
+		if strings.HasPrefix(item.ResourceSubType, vmwUsbSubTypePrefix) {
+			return edited, nil
+		}
+
+		controllers = append(controllers, item)
+	}
+
+	if len(controllers) > 0 {
+		subType := vmwUsbSubType(controllers)
+
+		return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+			Propose(recorder.explain(convertUsbControllersFunc(controllers[0].InstanceID, subType),
+				"USB controllers are converted to a single VMWare USB controller ('"+subType+
+					"') for the USB tablet"),
+				ovf.VirtualHardwareItemName))
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.AddHardwareItemFunc(ovf.Item{
+			Address:         "0",
+			Caption:         "usb",
+			Description:     "USB Controller",
+			ElementName:     "usb",
+			InstanceID:      nextInstanceID(items),
+			ResourceSubType: vmwUsbEhciSubType,
+			ResourceType:    ovf.UsbControllerResourceType,
+		}), "a USB controller ('"+vmwUsbEhciSubType+"') is added for the USB tablet"),
+			ovf.VirtualHardwareSectionName))
+}
+
+// vmwUsbSubType returns the ResourceSubType of the VMWare USB controller
+// that replaces the specified USB controllers. A USB 3.0 controller is
+// kept as such, and other controllers (e.g., 'OHCI' or 'EHCI') become a
+// USB 2.0 controller, which VMWare pairs with a USB 1.1 controller.
+func vmwUsbSubType(controllers []ovf.Item) string {
+	for _, controller := range controllers {
+		if strings.Contains(strings.ToLower(controller.ResourceSubType), "xhci") {
+			return vmwUsbXhciSubType
+		}
+	}
+
+	return vmwUsbEhciSubType
+}
+
+// convertUsbControllersFunc returns an ovf.EditObjectFunc that converts
+// the USB controller with the specified InstanceID to a VMWare USB
+// controller with the specified ResourceSubType, and deletes the other
+// USB controllers.
+func convertUsbControllersFunc(instanceID string, subType string) ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		o, ok := i.(ovf.Item)
+		if !ok || o.ResourceType != ovf.UsbControllerResourceType {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		if o.InstanceID != instanceID {
+			return ovf.EditObjectResult{Action: ovf.Delete}
+		}
+
+		o.ResourceSubType = subType
+
+		return ovf.EditObjectResult{
+			Action: ovf.Replace,
+			Object: &o,
+		}
+	}
+}
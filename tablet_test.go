@@ -0,0 +1,145 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertUsbTablet(t *testing.T) {
+	b, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{UsbTablet: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := b.String()
+
+	for _, config := range []string{
+		`<vmw:ExtraConfig ovf:required="false" vmw:key="usb:1.present" vmw:value="TRUE"/>`,
+		`<vmw:ExtraConfig ovf:required="false" vmw:key="usb:1.deviceType" vmw:value="tablet"/>`,
+	} {
+		if !strings.Contains(output, config) {
+			t.Fatal("The output does not contain '" + config + "':\n" + output)
+		}
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var controllers []ovf.Item
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == ovf.UsbControllerResourceType {
+			controllers = append(controllers, item)
+		}
+	}
+
+	if len(controllers) != 1 || controllers[0].ResourceSubType != vmwUsbEhciSubType {
+		t.Fatalf("Expected a '%s' USB controller, got %+v", vmwUsbEhciSubType, controllers)
+	}
+
+	// An existing USB controller is used rather than adding another.
+	again, err := BasicConvertReader(strings.NewReader(output), BasicConvertOptions{UsbTablet: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Count(again.String(), "USB Controller") != 1 ||
+		strings.Count(again.String(), `vmw:key="usb:1.deviceType"`) != 1 {
+		t.Fatal("Converting again duplicated the USB tablet:\n" + again.String())
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(output), BasicConvertOptions{Validate: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// vboxUsbControllers are the USB 1.1 and 2.0 controllers exported by
+// VirtualBox.
+const vboxUsbControllers = `      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Caption>usb</rasd:Caption>
+        <rasd:Description>USB Controller</rasd:Description>
+        <rasd:ElementName>usb</rasd:ElementName>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:ResourceSubType>OHCI</rasd:ResourceSubType>
+        <rasd:ResourceType>23</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Address>1</rasd:Address>
+        <rasd:Caption>usb</rasd:Caption>
+        <rasd:Description>USB Controller</rasd:Description>
+        <rasd:ElementName>usb</rasd:ElementName>
+        <rasd:InstanceID>10</rasd:InstanceID>
+        <rasd:ResourceSubType>EHCI</rasd:ResourceSubType>
+        <rasd:ResourceType>23</rasd:ResourceType>
+      </Item>
+`
+
+func TestBasicConvertUsbTabletVirtualBoxController(t *testing.T) {
+	input := withHardwareItem(t, basicOvfFileContents, vboxUsbControllers)
+
+	tests := []struct {
+		name       string
+		input      string
+		expSubType string
+	}{
+		{
+			name:       "ohci and ehci",
+			input:      input,
+			expSubType: "vmware.usb.ehci",
+		},
+		{
+			name:       "xhci",
+			input:      strings.Replace(input, "<rasd:ResourceSubType>EHCI<", "<rasd:ResourceSubType>XHCI<", 1),
+			expSubType: "vmware.usb.xhci",
+		},
+	}
+
+	for _, test := range tests {
+		edits, err := Explain(strings.NewReader(test.input), BasicConvertOptions{UsbTablet: true})
+		if err != nil {
+			t.Fatalf("%s - %s", test.name, err)
+		}
+
+		var explained bool
+		for _, edit := range edits {
+			if strings.Contains(edit.Reason, "converted to a single VMWare USB controller ('"+test.expSubType+"')") {
+				explained = true
+			}
+		}
+
+		if !explained {
+			t.Fatalf("%s - USB controller conversion was not explained - got %+v", test.name, edits)
+		}
+
+		b, err := BasicConvertReader(strings.NewReader(test.input), BasicConvertOptions{UsbTablet: true})
+		if err != nil {
+			t.Fatalf("%s - %s", test.name, err)
+		}
+
+		parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("%s - %s", test.name, err)
+		}
+
+		var controllers []ovf.Item
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if item.ResourceType == ovf.UsbControllerResourceType {
+				controllers = append(controllers, item)
+			}
+		}
+
+		if len(controllers) != 1 || controllers[0].InstanceID != "9" || controllers[0].ResourceSubType != test.expSubType {
+			t.Fatalf("%s - expected USB controller 9 to be converted to '%s', got %+v",
+				test.name, test.expSubType, controllers)
+		}
+
+		if !strings.Contains(b.String(), `vmw:key="usb:1.deviceType" vmw:value="tablet"`) {
+			t.Fatalf("%s - USB tablet was not added:\n%s", test.name, b.String())
+		}
+	}
+}
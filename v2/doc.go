@@ -0,0 +1,8 @@
+// Package vmwareify converts non-VMWare .ovf and .ova files (i.e., files
+// generated by VirtualBox) to VMWare-compatible files.
+//
+// It replaces the v1 BasicConvert functions with Convert, which accepts a
+// context.Context and Option funcs. The conversion itself is performed
+// by v1, meaning the constants and types that configure it (e.g.,
+// RemoveFloppyDrives and ConvertEdit) are those of the v1 package.
+package vmwareify
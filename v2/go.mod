@@ -0,0 +1,5 @@
+module github.com/stephen-fox/vmwareify/v2
+
+go 1.20
+
+require github.com/stephen-fox/vmwareify v0.0.0-20261015222007-915606a32901
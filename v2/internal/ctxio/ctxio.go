@@ -0,0 +1,47 @@
+package ctxio
+
+import (
+	"context"
+	"io"
+)
+
+// NewReader returns an io.Reader that reads from r until ctx is done,
+// after which every Read returns ctx.Err(). A Read that is in progress
+// when ctx is done is not interrupted.
+func NewReader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, r: r}
+}
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (o *reader) Read(p []byte) (int, error) {
+	err := o.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return o.r.Read(p)
+}
+
+// NewWriter returns an io.Writer that writes to w until ctx is done,
+// after which every Write returns ctx.Err().
+func NewWriter(ctx context.Context, w io.Writer) io.Writer {
+	return &writer{ctx: ctx, w: w}
+}
+
+type writer struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (o *writer) Write(p []byte) (int, error) {
+	err := o.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return o.w.Write(p)
+}
@@ -0,0 +1,4 @@
+// Package ctxio provides readers and writers that stop once a
+// context.Context is done, which allows the v1 functions that do not
+// accept a context to be cancelled.
+package ctxio
@@ -0,0 +1,9 @@
+// Package encoding converts the objects returned by a v2 EditObjectFunc
+// into the form that the v1 editor marshals.
+//
+// The v1 editor requires each replacement object to implement
+// ovf.EditedObject, whose Marshallable method returns a struct with
+// namespaced field tags. The method only exists to work around
+// https://github.com/golang/go/issues/9519, so v2 keeps it out of the
+// public API and selects the marshallable form here instead.
+package encoding
@@ -0,0 +1,34 @@
+package encoding
+
+import (
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// EditedObject returns the ovf.EditedObject that marshals the object.
+// Items and Systems are marshalled with their namespaced bindings, and a
+// *ovf.RawObject is written as-is. Other objects are marshalled using
+// encoding/xml, meaning their field tags must include any namespaces.
+// It returns nil if the object is nil.
+func EditedObject(object interface{}) ovf.EditedObject {
+	switch v := object.(type) {
+	case nil:
+		return nil
+	case ovf.Item:
+		return &v
+	case ovf.System:
+		return &v
+	case ovf.EditedObject:
+		return v
+	default:
+		return &plainObject{object: v}
+	}
+}
+
+// plainObject is an object without a namespaced binding.
+type plainObject struct {
+	object interface{}
+}
+
+func (o *plainObject) Marshallable() interface{} {
+	return o.object
+}
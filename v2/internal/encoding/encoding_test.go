@@ -0,0 +1,41 @@
+package encoding
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestEditedObject(t *testing.T) {
+	if EditedObject(nil) != nil {
+		t.Fatal("nil object was not nil")
+	}
+
+	item := ovf.Item{InstanceID: "1", ResourceType: ovf.ProcessorResourceType}
+	for _, object := range []interface{}{item, &item} {
+		raw, err := xml.Marshal(EditedObject(object).Marshallable())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !strings.Contains(string(raw), "<rasd:InstanceID>1</rasd:InstanceID>") {
+			t.Fatalf("Item was not namespaced - got: %s", raw)
+		}
+	}
+
+	type plain struct {
+		XMLName xml.Name `xml:"Plain"`
+		Value   string   `xml:"Value"`
+	}
+
+	raw, err := xml.Marshal(EditedObject(plain{Value: "x"}).Marshallable())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(raw) != "<Plain><Value>x</Value></Plain>" {
+		t.Fatalf("unexpected plain object encoding: %s", raw)
+	}
+}
@@ -0,0 +1,411 @@
+package vmwareify
+
+import (
+	v1 "github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// Option configures how Convert converts a .ovf or .ova. Each Option sets
+// the v1 BasicConvertOptions field of the same name, whose documentation
+// describes the option in detail.
+type Option func(options *v1.BasicConvertOptions)
+
+// FromBasicConvertOptions uses the v1 BasicConvertOptions, which eases
+// migrating from v1. Options that follow it override its fields.
+func FromBasicConvertOptions(basic v1.BasicConvertOptions) Option {
+	return func(options *v1.BasicConvertOptions) {
+		*options = basic
+	}
+}
+
+// GuestOSProfile applies the named guest OS profile (see the v1
+// LookupGuestOSProfile).
+func GuestOSProfile(name string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.GuestOSProfile = name
+	}
+}
+
+// AutoDetectGuestOS chooses hardware based on the guest OS and firmware
+// declared in the .ovf. An explicit GuestOSProfile takes precedence.
+func AutoDetectGuestOS() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.AutoDetectGuestOS = true
+	}
+}
+
+// NetworkAdapterSubType overrides the ResourceSubType of Ethernet
+// adapters (e.g., 'E1000', 'E1000e', or 'VmxNet3').
+func NetworkAdapterSubType(subType string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.NetworkAdapterSubType = subType
+	}
+}
+
+// NetworkMappings connects the virtual machine to other networks. It maps
+// the name of each network to replace to the name of the network to use
+// instead.
+func NetworkMappings(mappings map[string]string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.NetworkMappings = mappings
+	}
+}
+
+// RemoveNetworks removes the named networks, along with the network
+// adapters connected to them.
+func RemoveNetworks(names ...string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.RemoveNetworks = append([]string(nil), names...)
+	}
+}
+
+// AddNetworkAdapters adds an Ethernet adapter connected to each of the
+// named networks.
+func AddNetworkAdapters(networks ...string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.AddNetworkAdapters = append([]string(nil), networks...)
+	}
+}
+
+// ScsiControllerSubType overrides the ResourceSubType of SCSI controllers
+// (e.g., 'lsilogic', 'lsilogicsas', or 'VirtualSCSI').
+func ScsiControllerSubType(subType string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.ScsiControllerSubType = subType
+	}
+}
+
+// Firmware overrides the virtual machine's firmware. It must be
+// v1.BiosFirmware or v1.EfiFirmware.
+func Firmware(firmware string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Firmware = firmware
+	}
+}
+
+// CpuHotAdd allows CPUs to be added to the virtual machine while it is
+// running.
+func CpuHotAdd() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.CpuHotAdd = true
+	}
+}
+
+// MemoryHotAdd allows memory to be added to the virtual machine while it
+// is running.
+func MemoryHotAdd() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MemoryHotAdd = true
+	}
+}
+
+// MapDisplay maps the VirtualBox display settings to their VMWare
+// equivalents.
+func MapDisplay() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MapDisplay = true
+	}
+}
+
+// PreserveBootOrder maps the VirtualBox boot order to the VMWare boot
+// order.
+func PreserveBootOrder() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.PreserveBootOrder = true
+	}
+}
+
+// VirtualTPM adds a VMWare virtual TPM to the virtual machine, which
+// requires EFI firmware.
+func VirtualTPM() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.VirtualTPM = true
+	}
+}
+
+// MigrateIdeDevices attaches the devices of IDE controllers to a SATA
+// controller before the IDE controllers are removed.
+func MigrateIdeDevices() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MigrateIdeDevices = true
+	}
+}
+
+// KeepIdeControllers keeps the IDE controllers rather than removing them.
+// It cannot be combined with MigrateIdeDevices.
+func KeepIdeControllers() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.KeepIdeControllers = true
+	}
+}
+
+// FloppyDrives chooses what happens to floppy drives. It must be
+// v1.RemoveFloppyDrives or v1.KeepFloppyDrives.
+func FloppyDrives(mode string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.FloppyDrives = mode
+	}
+}
+
+// AudioDevices chooses what happens to audio devices. It must be
+// v1.RemoveAudioDevices, v1.HdAudioDevices, or v1.Es1371AudioDevices.
+func AudioDevices(mode string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.AudioDevices = mode
+	}
+}
+
+// UsbTablet adds a USB tablet to the virtual machine, and a VMWare USB
+// controller if it does not have one.
+func UsbTablet() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.UsbTablet = true
+	}
+}
+
+// StripSnapshotMetadata removes the VirtualBox snapshot metadata from the
+// vbox:Machine. Otherwise, the conversion fails with v1.ErrSnapshots.
+func StripSnapshotMetadata() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.StripSnapshotMetadata = true
+	}
+}
+
+// NormalizeHrefs converts the href of each local file in the References
+// to a relative, forward slash separated path.
+func NormalizeHrefs() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.NormalizeHrefs = true
+	}
+}
+
+// ReferencesDir sets the directory that contains the files referenced by
+// the .ovf.
+func ReferencesDir(dirPath string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.ReferencesDir = dirPath
+	}
+}
+
+// RecomputeSizes sets the sizes in the References, and the populatedSize
+// of each Disk, to those of the files in the ReferencesDir.
+func RecomputeSizes() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.RecomputeSizes = true
+	}
+}
+
+// SortItems orders the Items so that every controller precedes the
+// devices attached to it.
+func SortItems() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.SortItems = true
+	}
+}
+
+// DiskCapacity sets the declared capacity of the disks (e.g., '100GiB' or
+// 'vmdisk1=100GiB,vmdisk2=1TiB').
+func DiskCapacity(capacity string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.DiskCapacity = capacity
+	}
+}
+
+// CpuAllocation sets the CPU resources guaranteed to, and available to,
+// the virtual machine (e.g., 'reservation=2000,shares=4000').
+func CpuAllocation(allocation string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.CpuAllocation = allocation
+	}
+}
+
+// MemoryAllocation sets the memory resources guaranteed to, and available
+// to, the virtual machine (e.g., 'reservation=4GiB').
+func MemoryAllocation(allocation string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MemoryAllocation = allocation
+	}
+}
+
+// IpAssignmentSchemes declares the comma separated IP assignment schemes
+// that the virtual machine supports.
+func IpAssignmentSchemes(schemes string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.IpAssignmentSchemes = schemes
+	}
+}
+
+// IpProtocols sets the comma separated IP protocols declared with
+// IpAssignmentSchemes.
+func IpProtocols(protocols string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.IpProtocols = protocols
+	}
+}
+
+// DiskFileSuffix appends a suffix to the name of each disk file in the
+// References. It cannot be used when converting an .ova.
+func DiskFileSuffix(suffix string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.DiskFileSuffix = suffix
+	}
+}
+
+// Scrub removes information that identifies the system the .ovf was
+// exported from.
+func Scrub() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Scrub = true
+	}
+}
+
+// AnnotateSharedFolders describes the VirtualBox shared folders in the
+// annotation of the converted .ovf.
+func AnnotateSharedFolders() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.AnnotateSharedFolders = true
+	}
+}
+
+// EmbedProvenance records the provenance of the conversion in a
+// ProductSection of the converted .ovf.
+func EmbedProvenance() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.EmbedProvenance = true
+	}
+}
+
+// NormalizeOvaModes sets the mode of every file in a converted .ova to
+// 0644.
+func NormalizeOvaModes() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.NormalizeOvaModes = true
+	}
+}
+
+// StripOvaOwnership removes the user and group IDs and names of every
+// file in a converted .ova.
+func StripOvaOwnership() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.StripOvaOwnership = true
+	}
+}
+
+// Reproducible converts an .ova such that the same input always produces
+// the same output.
+func Reproducible() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Reproducible = true
+	}
+}
+
+// Validate checks the converted .ovf using the specified rules, or those
+// of ovf.NewRuleSet if rules is nil. The conversion fails with an error
+// wrapping v1.ErrValidation if the .ovf is invalid.
+func Validate(rules *ovf.RuleSet) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Validate = true
+		options.ValidationRules = rules
+	}
+}
+
+// OnWarning calls f with a description of each problem that does not
+// prevent the conversion.
+func OnWarning(f func(warning string)) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.OnWarning = f
+	}
+}
+
+// OnEdit calls f with each edit made to the .ovf descriptor, in the order
+// the edits are made.
+func OnEdit(f func(edit v1.ConvertEdit)) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.OnEdit = f
+	}
+}
+
+// BeforeStage calls hook before each stage of the conversion.
+func BeforeStage(hook v1.StageHook) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.BeforeStage = hook
+	}
+}
+
+// AfterStage calls hook after each stage of the conversion.
+func AfterStage(hook v1.StageHook) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.AfterStage = hook
+	}
+}
+
+// SourceDialect sets the tool that wrote the .ovf (e.g.,
+// v1.ProxmoxDialect), rather than detecting it.
+func SourceDialect(dialect string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.SourceDialect = dialect
+	}
+}
+
+// HardwareVersion sets the VMWare virtual hardware version of the
+// converted virtual machine (e.g., 'vmx-13' or '13').
+func HardwareVersion(version string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.HardwareVersion = version
+	}
+}
+
+// PruneReferences deletes the References Files that are no longer
+// referenced once the .ovf is converted.
+func PruneReferences() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.PruneReferences = true
+	}
+}
+
+// Locale flattens the localization of the .ovf to the specified locale
+// (e.g., 'de-DE').
+func Locale(locale string) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Locale = locale
+	}
+}
+
+// KeepByteOrderMark starts the converted .ovf with a UTF-8 byte order
+// mark if the original .ovf started with one.
+func KeepByteOrderMark() Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.KeepByteOrderMark = true
+	}
+}
+
+// MaxDescriptorBytes sets the maximum size of the .ovf descriptor.
+func MaxDescriptorBytes(max int64) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MaxDescriptorBytes = max
+	}
+}
+
+// MaxOvaBufferBytes sets the maximum size of the descriptor and the
+// manifest of an .ova, which are held in memory.
+func MaxOvaBufferBytes(max int64) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.MaxOvaBufferBytes = max
+	}
+}
+
+// OvaHashProvider sets the HashProvider that creates the hash used to
+// update the descriptor's digest in the manifest of an .ova.
+func OvaHashProvider(provider ova.HashProvider) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.OvaHashProvider = provider
+	}
+}
+
+// Metrics sends a ConversionStats for each conversion to metrics.
+func Metrics(metrics v1.Metrics) Option {
+	return func(options *v1.BasicConvertOptions) {
+		options.Metrics = metrics
+	}
+}
@@ -0,0 +1,6 @@
+// Package ova rewrites .ova archives. It replaces the v1 ova.Rewrite and
+// ova.RewriteWithConfig with a Rewrite that accepts a context.Context and
+// RewriteOption funcs. The rest of the v1 ova package (e.g., Pack and
+// DigestFiles) has not been ported yet, and can be imported alongside
+// this package.
+package ova
@@ -0,0 +1,79 @@
+package ova
+
+import (
+	"context"
+	"io"
+
+	"github.com/stephen-fox/vmwareify/ova"
+	"github.com/stephen-fox/vmwareify/v2/internal/ctxio"
+)
+
+// DescriptorFunc receives the contents of an .ovf descriptor and returns
+// the replacement descriptor.
+type DescriptorFunc = ova.DescriptorFunc
+
+// HashProvider creates the hashes used to calculate digests.
+type HashProvider = ova.HashProvider
+
+// RewriteOption configures how Rewrite rewrites an .ova.
+type RewriteOption func(config *ova.RewriteConfig)
+
+// NormalizeModes sets the mode of every file in the rewritten .ova to
+// 0644 (0755 for directories).
+func NormalizeModes() RewriteOption {
+	return func(config *ova.RewriteConfig) {
+		config.NormalizeModes = true
+	}
+}
+
+// StripOwnership removes the user and group IDs and names from every file
+// in the rewritten .ova.
+func StripOwnership() RewriteOption {
+	return func(config *ova.RewriteConfig) {
+		config.StripOwnership = true
+	}
+}
+
+// Reproducible rewrites the .ova such that the same input always produces
+// the same output. It implies NormalizeModes and StripOwnership.
+func Reproducible() RewriteOption {
+	return func(config *ova.RewriteConfig) {
+		config.Reproducible = true
+	}
+}
+
+// MaxBufferBytes sets the maximum size of the descriptor and the manifest,
+// which are held in memory. The size is not limited if it is negative.
+func MaxBufferBytes(max int64) RewriteOption {
+	return func(config *ova.RewriteConfig) {
+		config.MaxBufferBytes = max
+	}
+}
+
+// WithHashProvider sets the HashProvider that creates the hash used to
+// update the descriptor's digest in the manifest.
+func WithHashProvider(provider HashProvider) RewriteOption {
+	return func(config *ova.RewriteConfig) {
+		config.HashProvider = provider
+	}
+}
+
+// Rewrite reads an .ova from r, and writes a copy of it to w in which the
+// .ovf descriptor has been replaced by the result of the DescriptorFunc.
+// The .ova is processed in the same manner as the v1 ova.Rewrite.
+//
+// ctx.Err() is returned if ctx is done before the .ova has been
+// rewritten, in which case w contains a partial .ova.
+func Rewrite(ctx context.Context, r io.Reader, w io.Writer, convert DescriptorFunc, options ...RewriteOption) error {
+	var config ova.RewriteConfig
+	for _, option := range options {
+		option(&config)
+	}
+
+	err := ova.RewriteWithConfig(ctxio.NewReader(ctx, r), ctxio.NewWriter(ctx, w), convert, config)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
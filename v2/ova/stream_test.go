@@ -0,0 +1,112 @@
+package ova
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ova"
+)
+
+func testOva(t *testing.T, files map[string]string, names ...string) []byte {
+	buff := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(buff)
+
+	for _, name := range names {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0600,
+			Uid:      1000,
+			Size:     int64(len(files[name])),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		tarWriter.Write([]byte(files[name]))
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return buff.Bytes()
+}
+
+func upperDescriptor(descriptor io.Reader) (*bytes.Buffer, error) {
+	raw, err := io.ReadAll(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBufferString(strings.ToUpper(string(raw))), nil
+}
+
+func TestRewrite(t *testing.T) {
+	input := testOva(t, map[string]string{
+		"test.ovf":        "<envelope/>",
+		"test-disk1.vmdk": "disk",
+	}, "test.ovf", "test-disk1.vmdk")
+
+	output := bytes.NewBuffer(nil)
+
+	err := Rewrite(context.Background(), bytes.NewReader(input), output, upperDescriptor, Reproducible())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tarReader := tar.NewReader(output)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if header.Mode != 0644 || header.Uid != 0 || !header.ModTime.Equal(ova.ReproducibleModTime) {
+			t.Fatalf("'%s' was not made reproducible - got mode %o, uid %d, and time %s",
+				header.Name, header.Mode, header.Uid, header.ModTime)
+		}
+
+		contents, _ := io.ReadAll(tarReader)
+		if header.Name == "test.ovf" && string(contents) != "<ENVELOPE/>" {
+			t.Fatalf("descriptor was not converted - got '%s'", contents)
+		}
+	}
+}
+
+func TestRewriteMaxBufferBytes(t *testing.T) {
+	input := testOva(t, map[string]string{
+		"test.ovf": "<envelope>" + strings.Repeat(" ", 1024) + "</envelope>",
+	}, "test.ovf")
+
+	err := Rewrite(context.Background(), bytes.NewReader(input), io.Discard, upperDescriptor, MaxBufferBytes(512))
+	if !errors.Is(err, ova.ErrBufferLimit) {
+		t.Fatalf("expected ErrBufferLimit - got: %v", err)
+	}
+}
+
+func TestRewriteCancelled(t *testing.T) {
+	input := testOva(t, map[string]string{
+		"test.ovf":        "<envelope/>",
+		"test-disk1.vmdk": "disk",
+	}, "test.ovf", "test-disk1.vmdk")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := Rewrite(ctx, bytes.NewReader(input), io.Discard, func(descriptor io.Reader) (*bytes.Buffer, error) {
+		cancel()
+		return upperDescriptor(descriptor)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled - got: %v", err)
+	}
+}
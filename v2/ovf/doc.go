@@ -0,0 +1,12 @@
+// Package ovf edits .ovf descriptors.
+//
+// It replaces the edit API of the v1 ovf package: an EditScheme is a
+// concrete type that also accepts the typed funcs of the v1 EditOptions,
+// editing is configured using EditOption funcs, and every function that
+// reads a descriptor accepts a context.Context. Replacement objects no
+// longer need to implement a Marshallable method.
+//
+// The OVF model (e.g., Item, System, and the CIM resource types) is
+// shared with the v1 ovf package, which can be imported alongside this
+// package for the identifiers that are not aliased here.
+package ovf
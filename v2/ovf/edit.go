@@ -0,0 +1,229 @@
+package ovf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/v2/internal/ctxio"
+	"github.com/stephen-fox/vmwareify/v2/internal/encoding"
+)
+
+// EditObjectFunc receives an OVF object (e.g., an Item, or a *RawObject
+// for objects that are not modeled) and returns the resulting object as
+// an EditObjectResult.
+type EditObjectFunc func(originalObject interface{}) EditObjectResult
+
+// EditObjectResult represents the result of editing an OVF object.
+type EditObjectResult struct {
+	Action EditAction
+
+	// Object is the replacement object. It is only required when
+	// the Action is Replace, and is ignored otherwise. Items and
+	// Systems (or pointers to them) are written with the proper XML
+	// namespaces, and a *RawObject is written as-is. Any other
+	// object is marshalled using encoding/xml.
+	Object interface{}
+}
+
+// FromV1Func returns an EditObjectFunc that calls a v1 EditObjectFunc,
+// which allows the funcs provided by the v1 packages (e.g.,
+// vmwareify.RemoveIdeControllersFunc) to be proposed.
+func FromV1Func(f ovf.EditObjectFunc) EditObjectFunc {
+	return func(originalObject interface{}) EditObjectResult {
+		result := f(originalObject)
+		return EditObjectResult{Action: result.Action, Object: result.Object}
+	}
+}
+
+// v1 returns the v1 equivalent of the EditObjectFunc.
+func (o EditObjectFunc) v1() ovf.EditObjectFunc {
+	return func(originalObject interface{}) ovf.EditObjectResult {
+		result := o(originalObject)
+		return ovf.EditObjectResult{
+			Action: result.Action,
+			Object: encoding.EditedObject(result.Object),
+		}
+	}
+}
+
+// EditScheme specifies how an OVF configuration should be modified. It
+// combines the v1 EditScheme, PathEditScheme, and EditOptions. The zero
+// value is an empty EditScheme that is ready to use.
+//
+// There is no guarantee that the specified edits will be executed as the
+// specified OVF object(s) may not be present in the file. Objects that are
+// described by the OVF specification (e.g., Items) are only edited in the
+// section the specification places them in.
+type EditScheme struct {
+	proposals []proposal
+}
+
+// proposal is a func proposed for an ObjectName.
+type proposal struct {
+	objectName ObjectName
+	parent     []string
+	f          ovf.EditObjectFunc
+}
+
+// NewEditScheme returns a new, empty EditScheme.
+func NewEditScheme() *EditScheme {
+	return &EditScheme{}
+}
+
+// Propose will execute the provided EditObjectFunc if it encounters the
+// specified ObjectName. If parent names are specified, the func is only
+// executed if the Path of the object's parent ends with them (e.g.,
+// 'VirtualSystem', 'VirtualHardwareSection').
+//
+// Funcs are executed in the order they were proposed. Each func receives
+// the object returned by the last func that returned Replace, and a func
+// that returns Delete stops the remaining funcs from executing (see
+// StopAtFirstReplace). Funcs proposed for a qualified ObjectName (see
+// QualifiedObjectName) are executed after the funcs proposed for its
+// local name.
+func (o *EditScheme) Propose(f EditObjectFunc, objectName ObjectName, parent ...string) *EditScheme {
+	return o.propose(f.v1(), objectName, parent)
+}
+
+func (o *EditScheme) propose(f ovf.EditObjectFunc, objectName ObjectName, parent []string) *EditScheme {
+	o.proposals = append(o.proposals, proposal{
+		objectName: objectName,
+		parent:     append([]string(nil), parent...),
+		f:          f,
+	})
+
+	return o
+}
+
+// OnSystem proposes a func that edits the System.
+func (o *EditScheme) OnSystem(f OnSystemFunc) *EditScheme {
+	return o.proposeTyped(ovf.EditOptions{OnSystem: []OnSystemFunc{f}}, VirtualHardwareSystemName)
+}
+
+// OnHardwareItem proposes a func that edits each hardware Item.
+func (o *EditScheme) OnHardwareItem(f OnHardwareItemFunc) *EditScheme {
+	return o.proposeTyped(ovf.EditOptions{OnHardwareItems: []OnHardwareItemFunc{f}}, VirtualHardwareItemName)
+}
+
+// OnDisk proposes a func that edits each Disk in the DiskSection. Only
+// the attributes of a Disk are replaced, and other attributes (e.g.,
+// 'vbox:uuid') are preserved.
+func (o *EditScheme) OnDisk(f OnDiskFunc) *EditScheme {
+	return o.proposeTyped(ovf.EditOptions{OnDisks: []OnDiskFunc{f}}, DiskName)
+}
+
+// OnNetwork proposes a func that edits each Network in the
+// NetworkSection. The same limitations as OnDisk apply.
+func (o *EditScheme) OnNetwork(f OnNetworkFunc) *EditScheme {
+	return o.proposeTyped(ovf.EditOptions{OnNetworks: []OnNetworkFunc{f}}, NetworkName)
+}
+
+// OnFile proposes a func that edits each File in the References. The
+// same limitations as OnDisk apply.
+func (o *EditScheme) OnFile(f OnFileFunc) *EditScheme {
+	return o.proposeTyped(ovf.EditOptions{OnFiles: []OnFileFunc{f}}, ReferencesFileName)
+}
+
+// proposeTyped proposes the func that the v1 EditOptions compiles a
+// single typed func into.
+func (o *EditScheme) proposeTyped(options ovf.EditOptions, objectName ObjectName) *EditScheme {
+	fns, _ := options.EditScheme().ShouldEditObject(objectName)
+	for _, f := range fns {
+		o.propose(f, objectName, nil)
+	}
+
+	return o
+}
+
+// ObjectNames returns the names of the OVF objects that have been
+// targeted for editing, in sorted order.
+func (o *EditScheme) ObjectNames() []ObjectName {
+	seen := make(map[ObjectName]bool)
+
+	var names []ObjectName
+	for _, p := range o.proposals {
+		if !seen[p.objectName] {
+			seen[p.objectName] = true
+			names = append(names, p.objectName)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// Merge proposes all of the funcs of another EditScheme, which are
+// executed after the funcs that were already proposed for the same
+// ObjectName. The other EditScheme is not modified.
+func (o *EditScheme) Merge(other *EditScheme) *EditScheme {
+	o.proposals = append(o.proposals, other.proposals...)
+	return o
+}
+
+// Clone returns a copy of the EditScheme that can be modified without
+// affecting the original. The funcs are not copied, so funcs that keep
+// state are shared by the copies.
+func (o *EditScheme) Clone() *EditScheme {
+	return &EditScheme{
+		proposals: append([]proposal(nil), o.proposals...),
+	}
+}
+
+// v1 returns the v1 equivalent of the EditScheme.
+func (o *EditScheme) v1() ovf.PathEditScheme {
+	scheme := ovf.NewPathEditScheme()
+
+	for _, p := range o.proposals {
+		scheme.ProposeUnder(p.f, p.objectName, p.parent...)
+	}
+
+	return scheme
+}
+
+// Edit edits an existing OVF configuration read from r as specified by the
+// EditScheme, and returns the edited OVF. ctx.Err() is returned if ctx is
+// done before the OVF has been edited.
+func Edit(ctx context.Context, r io.Reader, scheme *EditScheme, options ...EditOption) (*bytes.Buffer, error) {
+	var config ovf.EditConfig
+	for _, option := range options {
+		option(&config)
+	}
+
+	edited, err := ovf.EditRawOvfWithConfig(ctxio.NewReader(ctx, r), scheme.v1(), config)
+	if err != nil {
+		return edited, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return edited, nil
+}
+
+// Plan runs the EditScheme against an existing OVF configuration without
+// producing any output. It returns the objects that would be deleted or
+// replaced, in the order in which they appear.
+//
+// Be advised: the funcs are executed as they would be by Edit. Funcs that
+// maintain state will have that state consumed.
+func Plan(ctx context.Context, r io.Reader, scheme *EditScheme) ([]PlannedEdit, error) {
+	planned, err := ovf.Plan(ctxio.NewReader(ctx, r), scheme.v1())
+	if err != nil {
+		return planned, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return planned, nil
+}
@@ -0,0 +1,206 @@
+package ovf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+const (
+	editOvfFileContents = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <References>
+    <File ovf:id="file1" ovf:href="disk1.vmdk"/>
+  </References>
+  <VirtualSystem ovf:id="vm">
+    <Info>A virtual machine</Info>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements for a virtual machine</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemType>virtualbox-2.2</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Caption>ideController0</rasd:Caption>
+        <rasd:ElementName>ideController0</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
+        <rasd:ResourceType>5</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+)
+
+func TestEditTypedAndRawFuncs(t *testing.T) {
+	scheme := NewEditScheme().
+		OnSystem(func(system System) (System, EditAction) {
+			system.VirtualSystemType = "vmx-13"
+			return system, Replace
+		}).
+		OnFile(func(file File) (File, EditAction) {
+			file.Href = "disk1-vmware.vmdk"
+			return file, Replace
+		}).
+		Propose(func(i interface{}) EditObjectResult {
+			item := i.(Item)
+			item.ResourceSubType = "PIIX4-vmware"
+			return EditObjectResult{Action: Replace, Object: item}
+		}, VirtualHardwareItemName, "VirtualHardwareSection")
+
+	b, err := Edit(context.Background(), strings.NewReader(editOvfFileContents), scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+	for _, s := range []string{
+		"<vssd:VirtualSystemType>vmx-13</vssd:VirtualSystemType>",
+		`ovf:href="disk1-vmware.vmdk"`,
+		"<rasd:ResourceSubType>PIIX4-vmware</rasd:ResourceSubType>",
+	} {
+		if !strings.Contains(result, s) {
+			t.Fatalf("edited ovf does not contain '%s' - got:\n%s", s, result)
+		}
+	}
+}
+
+type annotationSection struct {
+	XMLName    xml.Name `xml:"AnnotationSection"`
+	Info       string   `xml:"Info"`
+	Annotation string   `xml:"Annotation"`
+}
+
+func TestEditPlainObject(t *testing.T) {
+	var scheme EditScheme
+	scheme.Propose(func(i interface{}) EditObjectResult {
+		return EditObjectResult{
+			Action: Replace,
+			Object: annotationSection{Info: "A human-readable annotation", Annotation: "Converted"},
+		}
+	}, "Info", "VirtualSystem")
+
+	b, err := Edit(context.Background(), strings.NewReader(editOvfFileContents), &scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	exp := "<AnnotationSection>\n      <Info>A human-readable annotation</Info>\n      <Annotation>Converted</Annotation>\n    </AnnotationSection>"
+	if !strings.Contains(b.String(), exp) {
+		t.Fatalf("edited ovf does not contain the annotation - got:\n%s", b.String())
+	}
+
+	// Only the VirtualSystem's Info is replaced.
+	if strings.Count(b.String(), "<AnnotationSection>") != 1 {
+		t.Fatalf("expected one annotation - got:\n%s", b.String())
+	}
+}
+
+func TestEditFromV1Func(t *testing.T) {
+	scheme := NewEditScheme().Propose(FromV1Func(ovf.DeleteHardwareItemsMatchFunc(ovf.ItemMatch{
+		ResourceType: ovf.IdeControllerResourceType,
+	}, -1)), VirtualHardwareItemName)
+
+	b, err := Edit(context.Background(), strings.NewReader(editOvfFileContents), scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "ideController0") {
+		t.Fatalf("IDE controller was not deleted - got:\n%s", b.String())
+	}
+}
+
+func TestEditOptions(t *testing.T) {
+	scheme := NewEditScheme().Propose(func(i interface{}) EditObjectResult {
+		return EditObjectResult{Action: Delete}
+	}, VirtualHardwareSectionName)
+
+	_, err := Edit(context.Background(), strings.NewReader(editOvfFileContents), scheme)
+	if !errors.Is(err, ErrRequiredSection) {
+		t.Fatalf("expected ErrRequiredSection - got: %v", err)
+	}
+
+	var warnings []string
+	b, err := Edit(context.Background(), strings.NewReader(editOvfFileContents), scheme,
+		AllowRequiredSectionDeletes(),
+		OnWarning(func(warning string) {
+			warnings = append(warnings, warning)
+		}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Contains(b.String(), "VirtualHardwareSection") {
+		t.Fatalf("section was not deleted - got:\n%s", b.String())
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected one warning - got: %v", warnings)
+	}
+}
+
+func TestEditSchemeMergeAndClone(t *testing.T) {
+	a := NewEditScheme().OnHardwareItem(func(item Item) (Item, EditAction) {
+		return item, NoOp
+	})
+
+	b := NewEditScheme().OnSystem(func(system System) (System, EditAction) {
+		return system, NoOp
+	})
+
+	clone := a.Clone()
+	a.Merge(b)
+
+	exp := []ObjectName{VirtualHardwareItemName, VirtualHardwareSystemName}
+	if !reflect.DeepEqual(a.ObjectNames(), exp) {
+		t.Fatalf("expected %v - got %v", exp, a.ObjectNames())
+	}
+
+	exp = []ObjectName{VirtualHardwareItemName}
+	if !reflect.DeepEqual(clone.ObjectNames(), exp) {
+		t.Fatalf("clone was modified by merge - got %v", clone.ObjectNames())
+	}
+
+	if !reflect.DeepEqual(b.ObjectNames(), []ObjectName{VirtualHardwareSystemName}) {
+		t.Fatalf("merged scheme was modified - got %v", b.ObjectNames())
+	}
+}
+
+func TestEditCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Edit(ctx, strings.NewReader(editOvfFileContents), NewEditScheme())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled - got: %v", err)
+	}
+
+	_, err = Plan(ctx, strings.NewReader(editOvfFileContents), NewEditScheme())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled - got: %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	scheme := NewEditScheme().OnHardwareItem(func(item Item) (Item, EditAction) {
+		return item, Delete
+	})
+
+	planned, err := Plan(context.Background(), strings.NewReader(editOvfFileContents), scheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(planned) != 1 || planned[0].Object != VirtualHardwareItemName || planned[0].Action != Delete {
+		t.Fatalf("unexpected plan: %+v", planned)
+	}
+}
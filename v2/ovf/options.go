@@ -0,0 +1,76 @@
+package ovf
+
+import (
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// EditOption configures how Edit edits an OVF.
+type EditOption func(config *ovf.EditConfig)
+
+// ContinueOnError causes editing to continue after an object fails to be
+// edited. The failing object is left unmodified, and all of the errors
+// are joined into a single error (see errors.Join) that is returned once
+// the entire OVF has been processed.
+func ContinueOnError() EditOption {
+	return func(config *ovf.EditConfig) {
+		config.ContinueOnError = true
+	}
+}
+
+// StopAtFirstReplace causes the first func that returns Replace to
+// determine the outcome, skipping the remaining funcs proposed for the
+// same ObjectName.
+func StopAtFirstReplace() EditOption {
+	return func(config *ovf.EditConfig) {
+		config.StopAtFirstReplace = true
+	}
+}
+
+// RawObjects causes every object to be provided to the funcs as a
+// *RawObject, including those that are modeled (e.g., an Item).
+func RawObjects() EditOption {
+	return func(config *ovf.EditConfig) {
+		config.RawObjects = true
+	}
+}
+
+// AllowRequiredSectionDeletes allows sections whose 'ovf:required'
+// attribute is not "false" to be deleted. Otherwise, deleting such a
+// section fails with ErrRequiredSection.
+func AllowRequiredSectionDeletes() EditOption {
+	return func(config *ovf.EditConfig) {
+		config.AllowRequiredSectionDeletes = true
+	}
+}
+
+// OnWarning calls f with a description of each edit that may produce an
+// invalid descriptor, and of each replaced Item or System that loses
+// elements which are not modeled.
+func OnWarning(f func(warning string)) EditOption {
+	return func(config *ovf.EditConfig) {
+		config.OnWarning = f
+	}
+}
+
+// InHardwareSection limits the edits of a VirtualHardwareSection, and the
+// objects inside of it, to the sections that match.
+func InHardwareSection(match HardwareSectionMatch) EditOption {
+	return func(config *ovf.EditConfig) {
+		config.HardwareSection = &match
+	}
+}
+
+// WithLimits bounds the size and complexity of the OVF.
+func WithLimits(limits Limits) EditOption {
+	return func(config *ovf.EditConfig) {
+		config.Limits = limits
+	}
+}
+
+// KeepByteOrderMark starts the edited OVF with a UTF-8 byte order mark if
+// the original OVF started with one.
+func KeepByteOrderMark() EditOption {
+	return func(config *ovf.EditConfig) {
+		config.KeepByteOrderMark = true
+	}
+}
@@ -0,0 +1,60 @@
+package ovf
+
+import (
+	v1ovf "github.com/stephen-fox/vmwareify/ovf"
+)
+
+// The v1 types are aliased so that objects can be passed between the
+// two packages without being converted.
+type (
+	ObjectName           = v1ovf.ObjectName
+	EditAction           = v1ovf.EditAction
+	Path                 = v1ovf.Path
+	Item                 = v1ovf.Item
+	System               = v1ovf.System
+	Disk                 = v1ovf.Disk
+	Network              = v1ovf.Network
+	File                 = v1ovf.File
+	RawObject            = v1ovf.RawObject
+	PlannedEdit          = v1ovf.PlannedEdit
+	EditError            = v1ovf.EditError
+	Limits               = v1ovf.Limits
+	HardwareSectionMatch = v1ovf.HardwareSectionMatch
+	OnSystemFunc         = v1ovf.OnSystemFunc
+	OnHardwareItemFunc   = v1ovf.OnHardwareItemFunc
+	OnDiskFunc           = v1ovf.OnDiskFunc
+	OnNetworkFunc        = v1ovf.OnNetworkFunc
+	OnFileFunc           = v1ovf.OnFileFunc
+)
+
+const (
+	NoOp    = v1ovf.NoOp
+	Delete  = v1ovf.Delete
+	Replace = v1ovf.Replace
+)
+
+const (
+	VirtualHardwareSystemName  = v1ovf.VirtualHardwareSystemName
+	VirtualHardwareItemName    = v1ovf.VirtualHardwareItemName
+	VboxStorageControllerName  = v1ovf.VboxStorageControllerName
+	VboxMachineName            = v1ovf.VboxMachineName
+	ReferencesFileName         = v1ovf.ReferencesFileName
+	DiskName                   = v1ovf.DiskName
+	NetworkName                = v1ovf.NetworkName
+	EthernetPortItemName       = v1ovf.EthernetPortItemName
+	EnvelopeName               = v1ovf.EnvelopeName
+	VirtualSystemName          = v1ovf.VirtualSystemName
+	VirtualHardwareSectionName = v1ovf.VirtualHardwareSectionName
+	NetworkSectionName         = v1ovf.NetworkSectionName
+)
+
+// ErrRequiredSection is returned when an EditObjectFunc deletes a section
+// that is required (see AllowRequiredSectionDeletes).
+var ErrRequiredSection = v1ovf.ErrRequiredSection
+
+// QualifiedObjectName returns an ObjectName that only matches elements
+// with the specified local name in the specified XML namespace (see the
+// v1 QualifiedObjectName).
+func QualifiedObjectName(namespace string, local string) ObjectName {
+	return v1ovf.QualifiedObjectName(namespace, local)
+}
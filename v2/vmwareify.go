@@ -0,0 +1,30 @@
+package vmwareify
+
+import (
+	"context"
+	"io"
+
+	v1 "github.com/stephen-fox/vmwareify"
+	"github.com/stephen-fox/vmwareify/v2/internal/ctxio"
+)
+
+// Convert reads a .ovf or .ova from in, and writes the VMWare friendly
+// result to out. The type of input is detected from its contents, and an
+// .ova is converted in a single pass. It returns true if the input was
+// an .ova.
+//
+// ctx.Err() is returned if ctx is done before the conversion is complete,
+// in which case out may contain a partial result.
+func Convert(ctx context.Context, in io.Reader, out io.Writer, options ...Option) (bool, error) {
+	var config v1.BasicConvertOptions
+	for _, option := range options {
+		option(&config)
+	}
+
+	isOva, err := v1.Convert(ctxio.NewReader(ctx, in), ctxio.NewWriter(ctx, out), config)
+	if err != nil {
+		return isOva, err
+	}
+
+	return isOva, ctx.Err()
+}
@@ -0,0 +1,40 @@
+package vmwareify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// ErrValidation is returned when BasicConvertOptions.Validate is true,
+// and the converted .ovf has problems that importers reject.
+var ErrValidation = errors.New("the converted .ovf failed validation")
+
+// validateConverted returns ErrValidation, along with a description of
+// each problem, if a converted .ovf violates the specified rules. The
+// rules of ovf.NewRuleSet are used if they are nil.
+func validateConverted(converted *bytes.Buffer, rules *ovf.RuleSet) error {
+	parsed, err := toOvf(bytes.NewReader(converted.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	if rules == nil {
+		rules = ovf.NewRuleSet()
+	}
+
+	findings := rules.Check(&parsed)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	var problems []string
+	for _, finding := range findings {
+		problems = append(problems, finding.Err.Error()+" (rule '"+finding.Rule+"')")
+	}
+
+	return fmt.Errorf("%w (%s)", ErrValidation, strings.Join(problems, "; "))
+}
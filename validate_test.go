@@ -0,0 +1,47 @@
+package vmwareify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+func TestBasicConvertValidate(t *testing.T) {
+	_, err := BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{Validate: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := strings.Replace(basicOvfFileContents, `ovf:fileRef="file1"`, `ovf:fileRef="file404"`, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find the disk's fileRef in test data")
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(input), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(input), BasicConvertOptions{Validate: true})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "'file404'") {
+		t.Fatalf("Expected a validation error about 'file404' - got %v", err)
+	}
+}
+
+func TestBasicConvertValidationRules(t *testing.T) {
+	rules := ovf.NewRuleSet()
+	err := rules.Add("no-memory", "Virtual machines have no memory", ovf.MaxMemoryRule(0))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		Validate:        true,
+		ValidationRules: rules,
+	})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "(rule 'no-memory')") {
+		t.Fatalf("Expected a validation error from the 'no-memory' rule - got %v", err)
+	}
+}
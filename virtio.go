@@ -0,0 +1,99 @@
+package vmwareify
+
+import (
+	"strings"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// defaultVirtioScsiSubType is the SCSI controller model that virtio
+// storage controllers are converted to when a model is not chosen. LSI
+// Logic is supported by most guests without additional drivers.
+const defaultVirtioScsiSubType = lsiLogicScsiSubType
+
+// isVirtioSubType returns true if a ResourceSubType is a virtio device
+// (e.g., 'virtio-scsi', 'VirtioSCSI', 'virtio-blk', or 'virtio'), which
+// libvirt and KVM based platforms use.
+func isVirtioSubType(subType string) bool {
+	return strings.Contains(strings.ToLower(subType), "virtio")
+}
+
+// isVirtioStorageController returns true if an Item is a virtio-scsi or
+// virtio-blk controller. Exporters describe them as SCSI controllers, or
+// as "other" storage devices.
+func isVirtioStorageController(item ovf.Item) bool {
+	if !isVirtioSubType(item.ResourceSubType) {
+		return false
+	}
+
+	switch item.ResourceType {
+	case ovf.OtherResourceType, ovf.ParallelScsiHbaResourceType, ovf.SataControllerResourceType:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConvertVirtioControllersFunc returns an ovf.EditObjectFunc that will
+// convert virtio storage controllers (e.g., 'virtio-scsi' or
+// 'virtio-blk'), which VMWare rejects, to SCSI controllers of the
+// specified model (e.g., 'lsilogic' or 'VirtualSCSI').
+func ConvertVirtioControllersFunc(scsiSubType string) ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		controller, ok := i.(ovf.Item)
+		if !ok || !isVirtioStorageController(controller) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		controller.ResourceType = ovf.ParallelScsiHbaResourceType
+		controller.ResourceSubType = scsiSubType
+		controller.Description = "SCSI Controller"
+
+		return ovf.EditObjectResult{
+			Action: ovf.Replace,
+			Object: &controller,
+		}
+	}
+}
+
+// ClearVirtioDiskSubTypesFunc returns an ovf.EditObjectFunc that will
+// remove the virtio ResourceSubType of disk drives (e.g., 'virtio-blk'),
+// which VMWare does not recognize. The model of a disk is determined by
+// its controller.
+func ClearVirtioDiskSubTypesFunc() ovf.EditObjectFunc {
+	return func(i interface{}) ovf.EditObjectResult {
+		disk, ok := i.(ovf.Item)
+		if !ok || disk.ResourceType != ovf.DiskDriveResourceType || !isVirtioSubType(disk.ResourceSubType) {
+			return ovf.EditObjectResult{Action: ovf.NoOp}
+		}
+
+		disk.ResourceSubType = ""
+
+		return ovf.EditObjectResult{
+			Action: ovf.Replace,
+			Object: &disk,
+		}
+	}
+}
+
+// virtioEdits returns the explained edits that convert virtio storage
+// devices to devices that VMWare supports. Controllers are converted to
+// the chosen SCSI controller model, or to defaultVirtioScsiSubType.
+func virtioEdits(hardware hardwareChoices) []explainedFunc {
+	scsiSubType := hardware.profile.ScsiControllerSubType
+	if len(scsiSubType) == 0 {
+		scsiSubType = defaultVirtioScsiSubType
+	}
+
+	return []explainedFunc{
+		{
+			f: ConvertVirtioControllersFunc(scsiSubType),
+			reason: "virtio storage controllers are not supported by VMWare, and are converted to '" +
+				scsiSubType + "' SCSI controllers (resource type " + ovf.ParallelScsiHbaResourceType + ")",
+		},
+		{
+			f:      ClearVirtioDiskSubTypesFunc(),
+			reason: "the virtio model of disk drives is not recognized by VMWare, and is removed",
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package vmwareify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+)
+
+// virtioOvfFileContents is basicOvfFileContents with its disk attached to
+// a virtio-scsi controller, as exported by libvirt based platforms.
+var virtioOvfFileContents = strings.Replace(strings.Replace(basicOvfFileContents, `        <rasd:Caption>sataController0</rasd:Caption>
+        <rasd:Description>SATA Controller</rasd:Description>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
+        <rasd:ResourceType>20</rasd:ResourceType>`, `        <rasd:Caption>scsi0</rasd:Caption>
+        <rasd:Description>virtio-scsi controller</rasd:Description>
+        <rasd:ElementName>scsi0</rasd:ElementName>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>virtio-scsi</rasd:ResourceSubType>
+        <rasd:ResourceType>20</rasd:ResourceType>`, 1),
+	`        <rasd:Parent>5</rasd:Parent>
+`, `        <rasd:Parent>5</rasd:Parent>
+        <rasd:ResourceSubType>virtio-blk</rasd:ResourceSubType>
+`, 1)
+
+func TestBasicConvertVirtio(t *testing.T) {
+	if !strings.Contains(virtioOvfFileContents, "virtio-scsi") || !strings.Contains(virtioOvfFileContents, "virtio-blk") {
+		t.Fatal("Failed to add virtio devices to test data")
+	}
+
+	tests := []struct {
+		options  BasicConvertOptions
+		expected string
+	}{
+		{
+			options:  BasicConvertOptions{},
+			expected: defaultVirtioScsiSubType,
+		},
+		{
+			options:  BasicConvertOptions{GuestOSProfile: "windows"},
+			expected: lsiLogicSasScsiSubType,
+		},
+		{
+			options:  BasicConvertOptions{ScsiControllerSubType: "VirtualSCSI"},
+			expected: "VirtualSCSI",
+		},
+	}
+
+	for _, test := range tests {
+		b, err := basicConvertWithOptions(strings.NewReader(virtioOvfFileContents), test.options)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		found := false
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if isVirtioSubType(item.ResourceSubType) {
+				t.Fatalf("virtio device was not converted - %+v", item)
+			}
+
+			if item.InstanceID != "5" {
+				continue
+			}
+
+			found = true
+			if item.ResourceType != ovf.ParallelScsiHbaResourceType || item.ResourceSubType != test.expected {
+				t.Fatalf("Got unexpected controller for %+v - %+v", test.options, item)
+			}
+		}
+
+		if !found {
+			t.Fatal("The virtio controller was removed")
+		}
+	}
+}
+
+func TestVirtioFuncsIgnoreOtherItems(t *testing.T) {
+	items := []ovf.Item{
+		{ResourceType: ovf.SataControllerResourceType, ResourceSubType: "AHCI"},
+		{ResourceType: ovf.ParallelScsiHbaResourceType, ResourceSubType: lsiLogicScsiSubType},
+		{ResourceType: ovf.DiskDriveResourceType},
+		{ResourceType: ovf.EthernetAdapterResourceType, ResourceSubType: "virtio"},
+	}
+
+	for _, item := range items {
+		for _, f := range []ovf.EditObjectFunc{ConvertVirtioControllersFunc(lsiLogicScsiSubType), ClearVirtioDiskSubTypesFunc()} {
+			if f(item).Action != ovf.NoOp {
+				t.Fatalf("Unexpected edit of %+v", item)
+			}
+		}
+	}
+}
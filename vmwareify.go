@@ -2,66 +2,260 @@ package vmwareify
 
 import (
 	"bytes"
+	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
 )
 
 // BasicConvert converts a non-VMWare .ovf file to a VMWare friendly .ovf
 // file. It does the following:
 //
-//  - Removes any IDE controllers
+//  - Removes any IDE controllers (see BasicConvertOptions.MigrateIdeDevices
+//    and BasicConvertOptions.KeepIdeControllers)
 //  - Converts any existing SATA controllers to the VMWare kind
 //  - Set the VMWare compatibility level to vmx-10
 //  - Disables automatic allocation of CD/DVD drives
+//  - Assigns devices unique addresses on their storage controllers
+//  - Expands the VirtualBox SATA controller's port count to fit its devices
+//
+// VMWare has no setting for the number of SATA ports; its SATA controllers
+// always provide 30, and the conversion fails if more devices are attached.
+// The VirtualBox port count only matters when the converted .ovf is
+// imported back into VirtualBox, and is ignored by ESXi.
+//
+// The compatibility level is raised when newer hardware is required (e.g.,
+// vmx-13 for EFI Secure Boot, or vmx-14 for a virtual TPM), and can be
+// overridden using BasicConvertOptions.
 func BasicConvert(ovfFilePath string, newFilePath string) error {
+	return BasicConvertWithOptions(ovfFilePath, newFilePath, BasicConvertOptions{})
+}
+
+// BasicConvertWithOptions performs the same conversion as BasicConvert,
+// customized by the provided BasicConvertOptions. If
+// BasicConvertOptions.DiskFileSuffix is set, the renamed disk files are
+// hard-linked (or copied) next to the new .ovf. If
+// BasicConvertOptions.CompanionFiles is set, every file referenced by
+// the .ovf is copied or linked next to the new .ovf. The files are
+// written during WriteStage.
+func BasicConvertWithOptions(ovfFilePath string, newFilePath string, options BasicConvertOptions) error {
 	if ovfFilePath == newFilePath {
 		return errors.New("output .ovf file path cannot be the same as the input file path")
 	}
 
-	existing, err := os.Open(ovfFilePath)
+	err := checkCompanionFiles(options.CompanionFiles)
 	if err != nil {
 		return err
 	}
-	defer existing.Close()
 
-	buff, err := basicConvert(existing)
+	if len(options.ReferencesDir) == 0 {
+		options.ReferencesDir = filepath.Dir(ovfFilePath)
+	}
+
+	existing, err := os.Open(ovfFilePath)
 	if err != nil {
 		return err
 	}
+	defer existing.Close()
 
 	info, err := existing.Stat()
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(newFilePath, buff.Bytes(), info.Mode())
+	meter, in := startMeter(options, existing)
+
+	buff, err := convertAndWrite(in, options, nil, newFilePath, func(state *ConversionState) error {
+		err := placeReferencedFiles(ovfFilePath, state.OutputPath, state.Options)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(state.OutputPath, state.Descriptor, info.Mode())
+	})
 	if err != nil {
+		meter.finish(false, 0, err)
 		return err
 	}
 
+	meter.finish(false, int64(buff.Len()), nil)
+
 	return nil
 }
 
-func basicConvert(existing io.Reader) (*bytes.Buffer, error) {
-	editScheme := ovf.NewEditScheme().
-		Propose(SetVirtualSystemTypeFunc("vmx-10"), ovf.VirtualHardwareSystemName).
-		Propose(RemoveIdeControllersFunc(-1), ovf.VirtualHardwareItemName).
-		Propose(ConvertSataControllersFunc(), ovf.VirtualHardwareItemName).
-		Propose(DisableCdromAutomaticAllocationFunc(), ovf.VirtualHardwareItemName)
+// BasicConvertReader performs the same conversion as
+// BasicConvertWithOptions, reading the .ovf from an io.Reader and
+// returning the converted .ovf.
+func BasicConvertReader(existing io.Reader, options BasicConvertOptions) (*bytes.Buffer, error) {
+	meter, existing := startMeter(options, existing)
 
-	buff, err := ovf.EditRawOvf(existing, editScheme)
+	buff, err := basicConvertWithOptions(existing, options)
 	if err != nil {
-		return bytes.NewBuffer(nil), err
+		meter.finish(false, 0, err)
+		return buff, err
 	}
 
+	meter.finish(false, int64(buff.Len()), nil)
+
 	return buff, nil
 }
 
+// ConvertReader performs the same conversion as BasicConvert, reading the
+// .ovf from an io.Reader and returning the converted .ovf. The specified
+// GuestOSProfile is applied, which may be one of GuestOSProfiles (e.g.,
+// as returned by LookupGuestOSProfile) or a custom profile. The zero
+// GuestOSProfile applies no profile. Use BasicConvertReader to specify
+// other options.
+func ConvertReader(r io.Reader, profile GuestOSProfile) (*bytes.Buffer, error) {
+	return BasicConvertReader(r, BasicConvertOptions{
+		NetworkAdapterSubType: profile.NetworkAdapterSubType,
+		ScsiControllerSubType: profile.ScsiControllerSubType,
+	})
+}
+
+// ErrUnsupportedInput is returned when the input is not an OVF descriptor
+// that can be converted.
+var ErrUnsupportedInput = errors.New("input is not a supported OVF descriptor")
+
+func basicConvert(existing io.Reader) (*bytes.Buffer, error) {
+	return ConvertReader(existing, GuestOSProfile{})
+}
+
+func basicConvertWithOptions(existing io.Reader, options BasicConvertOptions) (*bytes.Buffer, error) {
+	return convert(existing, options, nil)
+}
+
+// setVmwConfigs adds 'vmw:Config' and 'vmw:ExtraConfig' elements to the
+// VirtualHardwareSection, declaring the 'vmw' namespace if needed. The
+// namespace is declared in its own pass because the Envelope contains
+// the VirtualHardwareSection.
+func setVmwConfigs(edited *bytes.Buffer, configs []vmwConfig, recorder *editRecorder) (*bytes.Buffer, error) {
+	buff, err := editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.DeclareNamespaceFunc(ovf.VmwPrefix, ovf.VmwNamespace),
+			"the '"+ovf.VmwPrefix+"' namespace is declared for the VMWare settings"), ovf.EnvelopeName))
+	if err != nil {
+		return nil, err
+	}
+
+	editScheme := ovf.NewEditScheme()
+
+	for _, config := range configs {
+		f := ovf.SetVmwConfigFunc(config.key, config.value)
+		element := "vmw:Config"
+		if config.extra {
+			f = ovf.SetVmwExtraConfigFunc(config.key, config.value)
+			element = "vmw:ExtraConfig"
+		}
+
+		editScheme.Propose(recorder.explain(f, "the VMWare setting '"+config.key+"' is set to '"+
+			config.value+"' ("+element+")"), ovf.VirtualHardwareSectionName)
+	}
+
+	return editRawOvf(bytes.NewReader(buff.Bytes()), editScheme)
+}
+
+// expandSataPortCount ensures that the VirtualBox SATA controller has
+// enough ports for the devices attached to the SATA controller. This only
+// keeps the preserved vbox:Machine section valid for VirtualBox; ESXi
+// ignores it.
+func expandSataPortCount(edited *bytes.Buffer, recorder *editRecorder) (*bytes.Buffer, error) {
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	portCount := 0
+	for _, item := range items {
+		if item.ResourceType == ovf.SataControllerResourceType &&
+			strings.Contains(strings.ToLower(item.ResourceSubType), "ahci") {
+			inUse := ovf.StoragePortsInUse(items, item)
+			if inUse > portCount {
+				portCount = inUse
+			}
+		}
+	}
+
+	if portCount == 0 {
+		return edited, nil
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.ExpandVboxStorageControllerPortCountFunc("AHCI", portCount),
+			"the VirtualBox SATA controller's port count is expanded to fit its "+strconv.Itoa(portCount)+
+				" devices, so that VirtualBox can still import the .ovf (ESXi ignores it)"),
+			ovf.VboxStorageControllerName))
+}
+
+// reassignAddressesOnParent makes a second pass over an edited .ovf,
+// ensuring that devices have unique addresses on their controllers.
+func reassignAddressesOnParent(edited *bytes.Buffer, recorder *editRecorder) (*bytes.Buffer, error) {
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := ovf.ReassignAddressesOnParentFunc(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(f, "devices must have a unique, valid address on their storage controller"),
+			ovf.VirtualHardwareItemName))
+}
+
+// intermediateLimits disables the ovf.Limits when editing and parsing the
+// intermediate results of a conversion. The limits are checked once,
+// before the conversion begins, as the results may legitimately grow
+// (e.g., when devices are added).
+var intermediateLimits = ovf.Limits{
+	MaxDescriptorBytes: -1,
+	MaxItems:           -1,
+	MaxDepth:           -1,
+}
+
+func editRawOvf(r io.Reader, scheme ovf.EditScheme) (*bytes.Buffer, error) {
+	return ovf.EditRawOvfWithConfig(r, scheme, ovf.EditConfig{Limits: intermediateLimits})
+}
+
+func toOvf(r io.Reader) (ovf.Ovf, error) {
+	return ovf.ToOvfWithConfig(r, ovf.ToOvfConfig{Limits: intermediateLimits})
+}
+
+// checkIsOvf returns ErrUnsupportedInput if the document's root element
+// is not an OVF Envelope. Malformed documents are left to
+// xmlutil.ValidateFormatting, which reports the location of the
+// formatting error.
+func checkIsOvf(raw []byte) error {
+	d := xmlutil.NewDecoder(bytes.NewReader(raw))
+
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			return nil
+		}
+
+		if start, ok := t.(xml.StartElement); ok {
+			if start.Name.Local != "Envelope" {
+				return fmt.Errorf("%w - root element is '%s' rather than 'Envelope'",
+					ErrUnsupportedInput, start.Name.Local)
+			}
+
+			return nil
+		}
+	}
+}
+
 // SetVirtualSystemTypeFunc returns an ovf.EditObjectFunc that will set the
 // .ovf's VirtualSystemType to the specified value.
 func SetVirtualSystemTypeFunc(systemType string) ovf.EditObjectFunc {
@@ -69,9 +263,21 @@ func SetVirtualSystemTypeFunc(systemType string) ovf.EditObjectFunc {
 }
 
 // RemoveIdeControllersFunc returns an ovf.EditObjectFunc that will remove
-// the specified number of IDE controllers.
+// the specified number of IDE controllers. Controllers are identified by
+// their resource type if their names have been localized.
 func RemoveIdeControllersFunc(limit int) ovf.EditObjectFunc {
-	return ovf.DeleteHardwareItemsMatchingFunc("ideController", limit)
+	return ovf.DeleteHardwareItemsMatchFunc(ideControllerMatch, limit)
+}
+
+// NormalizeIdeControllersFunc returns an ovf.EditObjectFunc that will set
+// the ResourceSubType of IDE controllers to a model that ESXi supports.
+// VirtualBox emulates several IDE chipsets (e.g., 'PIIX3' or 'ICH6'),
+// which ESXi may reject.
+func NormalizeIdeControllersFunc() ovf.EditObjectFunc {
+	return ovf.ModifyHardwareItemsMatchFunc(ideControllerMatch, func(ideController ovf.Item) ovf.Item {
+		ideController.ResourceSubType = esxiIdeControllerSubType
+		return ideController
+	})
 }
 
 // ConvertSataControllersFunc returns an ovf.EditObjectFunc that
@@ -97,7 +303,7 @@ func ConvertSataControllersFunc() ovf.EditObjectFunc {
 		return sataController
 	}
 
-	return ovf.ModifyHardwareItemsOfResourceTypeFunc(ovf.OtherStorageDeviceResourceType, modifyFunc)
+	return ovf.ModifyHardwareItemsOfResourceTypeFunc(ovf.SataControllerResourceType, modifyFunc)
 }
 
 // DisableCdromAutomaticAllocationFunc returns an ovf.EditObjectFunc that
@@ -110,3 +316,23 @@ func DisableCdromAutomaticAllocationFunc() ovf.EditObjectFunc {
 
 	return ovf.ModifyHardwareItemsOfResourceTypeFunc(ovf.CdDriveResourceType, modifyFunc)
 }
+
+// addVirtualTPM adds a VMWare virtual TPM to an edited .ovf, unless it
+// already has one.
+func addVirtualTPM(edited *bytes.Buffer, recorder *editRecorder) (*bytes.Buffer, error) {
+	parsed, err := toOvf(bytes.NewReader(edited.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	items := parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items
+	for _, item := range items {
+		if item.ResourceSubType == ovf.VmwVirtualTPMSubType {
+			return edited, nil
+		}
+	}
+
+	return editRawOvf(bytes.NewReader(edited.Bytes()), ovf.NewEditScheme().
+		Propose(recorder.explain(ovf.AddVirtualTPMFunc(nextInstanceID(items)),
+			"a virtual TPM is added"), ovf.VirtualHardwareSectionName))
+}
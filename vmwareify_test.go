@@ -1,8 +1,14 @@
 package vmwareify
 
 import (
+	"bytes"
+	"errors"
+	"regexp"
 	"strings"
 	"testing"
+
+	"github.com/stephen-fox/vmwareify/ovf"
+	"github.com/stephen-fox/vmwareify/xmlutil"
 )
 
 const (
@@ -156,6 +162,18 @@ const (
 `
 )
 
+// withHardwareItem returns the OVF with an Item added to the end of its
+// VirtualHardwareSection. The Item must be indented, and end with a new
+// line.
+func withHardwareItem(t *testing.T, ovfContents string, item string) string {
+	edited := strings.Replace(ovfContents, "    </VirtualHardwareSection>", item+"    </VirtualHardwareSection>", 1)
+	if edited == ovfContents {
+		t.Fatal("Failed to add Item to test data")
+	}
+
+	return edited
+}
+
 func TestBasicConvert(t *testing.T) {
 	b, err := basicConvert(strings.NewReader(basicOvfFileContents))
 	if err != nil {
@@ -296,4 +314,395 @@ func TestBasicConvert(t *testing.T) {
 	if result != expected {
 		t.Fatal("Did not get expected result:\n'" + result + "'")
 	}
-}
\ No newline at end of file
+}
+
+func TestBasicConvertUnsupportedInput(t *testing.T) {
+	_, err := basicConvert(strings.NewReader("<?xml version=\"1.0\"?>\n<Junk>\n</Junk>\n"))
+	if !errors.Is(err, ErrUnsupportedInput) {
+		t.Fatalf("Expected ErrUnsupportedInput, got - %v", err)
+	}
+}
+
+func TestBasicConvertSnapshots(t *testing.T) {
+	snapshot := `      <Snapshot uuid="{11111111-0000-0000-0000-000000000000}" name="Before update">
+        <Hardware/>
+      </Snapshot>
+`
+	input := strings.Replace(basicOvfFileContents, `lastStateChange="2019-01-10T16:25:32Z">`,
+		`lastStateChange="2019-01-10T16:25:32Z" currentSnapshot="{11111111-0000-0000-0000-000000000000}">`, 1)
+	input = strings.Replace(input, "    </vbox:Machine>", snapshot+"    </vbox:Machine>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find vbox:Machine in test data")
+	}
+
+	_, err := basicConvert(strings.NewReader(input))
+	if !errors.Is(err, ErrSnapshots) {
+		t.Fatalf("Expected ErrSnapshots, got - %v", err)
+	}
+
+	var warnings []string
+	stripped, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		StripSnapshotMetadata: true,
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected, err := basicConvert(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if stripped.String() != expected.String() {
+		t.Fatal("Did not get expected result:\n'" + stripped.String() + "'")
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected a warning about the snapshots, got %v", warnings)
+	}
+
+	differencing := strings.Replace(basicOvfFileContents, `ovf:diskId="vmdisk1"`, `ovf:diskId="vmdisk1" ovf:parentRef="vmdisk0"`, 1)
+	if differencing == basicOvfFileContents {
+		t.Fatal("Failed to find Disk in test data")
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(differencing), BasicConvertOptions{StripSnapshotMetadata: true})
+	if !errors.Is(err, ErrSnapshots) {
+		t.Fatalf("Expected ErrSnapshots for a differencing disk, got - %v", err)
+	}
+}
+
+func TestBasicConvertSharedDisk(t *testing.T) {
+	disk := `      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:Caption>disk1</rasd:Caption>
+        <rasd:Description>Disk Image</rasd:Description>
+        <rasd:ElementName>disk1</rasd:ElementName>
+        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>6</rasd:InstanceID>
+        <rasd:Parent>5</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+`
+	shared := strings.Replace(strings.Replace(disk, "<rasd:InstanceID>6<", "<rasd:InstanceID>9<", 1),
+		"<rasd:AddressOnParent>0<", "<rasd:AddressOnParent>2<", 1)
+	input := strings.Replace(basicOvfFileContents, disk, disk+shared, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find disk in test data")
+	}
+
+	var warnings []string
+	b, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.Count(b.String(), "<rasd:HostResource>/disk/vmdisk1</rasd:HostResource>") != 2 {
+		t.Fatal("Shared disk is no longer attached twice:\n'" + b.String() + "'")
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "multi-writer") {
+		t.Fatalf("Expected a warning about the shared disk, got %v", warnings)
+	}
+
+	parsed, err := toOvf(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	detached := strings.Replace(b.String(), "<rasd:HostResource>/disk/vmdisk1</rasd:HostResource>", "", 1)
+
+	err = checkSharedDisks(bytes.NewBufferString(detached), parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items)
+	if err == nil {
+		t.Fatal("Expected an error when a shared disk is detached")
+	}
+}
+
+func TestBasicConvertMigrateIdeDevices(t *testing.T) {
+	cdrom := `        <rasd:AddressOnParent>1</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Caption>cdrom1</rasd:Caption>
+        <rasd:Description>CD-ROM Drive</rasd:Description>
+        <rasd:ElementName>cdrom1</rasd:ElementName>
+        <rasd:InstanceID>7</rasd:InstanceID>
+        <rasd:Parent>5</rasd:Parent>`
+	input := strings.Replace(basicOvfFileContents, cdrom, strings.Replace(strings.Replace(cdrom,
+		"<rasd:Parent>5<", "<rasd:Parent>4<", 1), "<rasd:AddressOnParent>1<", "<rasd:AddressOnParent>0<", 1), 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find CD-ROM drive in test data")
+	}
+
+	var warnings []string
+	_, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		OnWarning: func(warning string) {
+			warnings = append(warnings, warning)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'cdrom1'") {
+		t.Fatalf("Expected a warning about the IDE devices, got %v", warnings)
+	}
+
+	b, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{MigrateIdeDevices: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == ovf.IdeControllerResourceType {
+			t.Fatalf("IDE controller was not removed - %+v", item)
+		}
+
+		if item.InstanceID == "7" && (item.Parent != "5" || item.AddressOnParent != "1") {
+			t.Fatalf("CD-ROM drive was not moved to the SATA controller - %+v", item)
+		}
+	}
+
+	noSata := strings.Replace(input, "<rasd:ResourceType>20</rasd:ResourceType>", "<rasd:ResourceType>5</rasd:ResourceType>", 1)
+
+	b, err = BasicConvertReader(strings.NewReader(noSata), BasicConvertOptions{MigrateIdeDevices: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err = ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var sataController ovf.Item
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == ovf.SataControllerResourceType {
+			sataController = item
+		}
+	}
+
+	if sataController.ResourceSubType != "vmware.sata.ahci" {
+		t.Fatal("SATA controller was not added:\n'" + b.String() + "'")
+	}
+
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if (item.InstanceID == "6" || item.InstanceID == "7") && item.Parent != sataController.InstanceID {
+			t.Fatalf("Device was not moved to the added SATA controller - %+v", item)
+		}
+	}
+}
+
+func TestBasicConvertKeepIdeControllers(t *testing.T) {
+	input := strings.Replace(basicOvfFileContents, "<rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>",
+		"<rasd:ResourceSubType>ICH6</rasd:ResourceSubType>", 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find IDE controller in test data")
+	}
+
+	b, err := BasicConvertReader(strings.NewReader(input), BasicConvertOptions{KeepIdeControllers: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var ideControllers int
+	for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+		if item.ResourceType == ovf.IdeControllerResourceType {
+			ideControllers = ideControllers + 1
+
+			if item.ResourceSubType != "PIIX4" {
+				t.Fatalf("IDE controller was not normalized - %+v", item)
+			}
+		}
+	}
+
+	if ideControllers != 2 {
+		t.Fatalf("Expected 2 IDE controllers to be kept, got %d", ideControllers)
+	}
+
+	_, err = BasicConvertReader(strings.NewReader(input), BasicConvertOptions{
+		KeepIdeControllers: true,
+		MigrateIdeDevices:  true,
+	})
+	if err == nil {
+		t.Fatal("Expected an error when keeping and migrating IDE controllers")
+	}
+}
+
+func TestBasicConvertSortItems(t *testing.T) {
+	// The SATA controller that is added when migrating the IDE
+	// devices is declared after the devices that are moved to it.
+	noSata := strings.Replace(basicOvfFileContents, "<rasd:ResourceType>20</rasd:ResourceType>",
+		"<rasd:ResourceType>5</rasd:ResourceType>", 1)
+
+	for _, sortItems := range []bool{false, true} {
+		b, err := BasicConvertReader(strings.NewReader(noSata), BasicConvertOptions{
+			MigrateIdeDevices: true,
+			SortItems:         sortItems,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		parsed, err := ovf.ToOvf(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		declared := make(map[string]bool)
+		childFirst := false
+		for _, item := range parsed.Envelope.VirtualSystem.VirtualHardwareSection.Items {
+			if len(item.Parent) > 0 && !declared[item.Parent] {
+				childFirst = true
+			}
+
+			declared[item.InstanceID] = true
+		}
+
+		if childFirst == sortItems {
+			t.Fatalf("with SortItems %t, expected a device before its controller to be %t - got:\n%s",
+				sortItems, !sortItems, b.String())
+		}
+	}
+}
+
+func TestBasicConvertExpandsSataPortCount(t *testing.T) {
+	original := `<StorageController name="SATA Controller" type="AHCI" PortCount="2"`
+	input := strings.Replace(basicOvfFileContents, original,
+		`<StorageController name="SATA Controller" type="AHCI" PortCount="1"`, 1)
+	if input == basicOvfFileContents {
+		t.Fatal("Failed to find SATA controller in test data")
+	}
+
+	b, err := basicConvert(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(b.String(), original) {
+		t.Fatal("SATA controller port count was not expanded:\n'" + b.String() + "'")
+	}
+}
+
+func TestBasicConvertSingleLine(t *testing.T) {
+	singleLine := regexp.MustCompile(`>\s+<`).ReplaceAllString(basicOvfFileContents, "><")
+	if strings.Count(singleLine, "\n") != 1 {
+		t.Fatal("Failed to create single line test data")
+	}
+
+	expected, err := basicConvert(strings.NewReader(basicOvfFileContents))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := basicConvert(strings.NewReader(singleLine))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.String() != expected.String() {
+		t.Fatal("Did not get expected result:\n'" + b.String() + "'")
+	}
+}
+
+func TestBasicConvertPreservesDeclaration(t *testing.T) {
+	declaration := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n<!DOCTYPE Envelope>"
+	input := strings.Replace(basicOvfFileContents, `<?xml version="1.0"?>`, declaration, 1)
+
+	b, err := basicConvert(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasPrefix(b.String(), declaration+"\n<Envelope") {
+		t.Fatal("Declaration was not preserved:\n'" + b.String() + "'")
+	}
+
+	input = strings.Replace(input, "<!DOCTYPE Envelope>", `<!DOCTYPE Envelope SYSTEM "https://example.com/ovf.dtd">`, 1)
+
+	_, err = basicConvert(strings.NewReader(input))
+
+	var formattingErr *xmlutil.FormattingError
+	if !errors.As(err, &formattingErr) {
+		t.Fatalf("Expected a *xmlutil.FormattingError for an external DTD, got - %v", err)
+	}
+}
+
+func TestBasicConvertMaxDescriptorBytes(t *testing.T) {
+	_, err := basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		MaxDescriptorBytes: int64(len(basicOvfFileContents) - 1),
+	})
+	if !errors.Is(err, xmlutil.ErrDocumentTooLarge) {
+		t.Fatalf("Expected xmlutil.ErrDocumentTooLarge, got - %v", err)
+	}
+
+	_, err = basicConvertWithOptions(strings.NewReader(basicOvfFileContents), BasicConvertOptions{
+		MaxDescriptorBytes: int64(len(basicOvfFileContents)),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestEditRawOvfMultiFuncPipeline(t *testing.T) {
+	editScheme := ovf.NewEditScheme().
+		Propose(ConvertSataControllersFunc(), ovf.VirtualHardwareItemName).
+		Propose(ovf.ModifyHardwareItemsOfResourceTypeFunc(ovf.OtherStorageDeviceResourceType, func(i ovf.Item) ovf.Item {
+			if i.ResourceSubType == "vmware.sata.ahci" {
+				i.Caption = i.ElementName + " (converted)"
+			}
+			return i
+		}), ovf.VirtualHardwareItemName).
+		Propose(DisableCdromAutomaticAllocationFunc(), ovf.VirtualHardwareItemName)
+
+	b, err := ovf.EditRawOvf(strings.NewReader(basicOvfFileContents), editScheme)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := b.String()
+	if !strings.Contains(result, "<rasd:Caption>SATAController0 (converted)</rasd:Caption>") {
+		t.Fatal("Second func did not receive the converted SATA controller:\n'" + result + "'")
+	}
+
+	if !strings.Contains(result, "<rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>") {
+		t.Fatal("SATA controller was not converted:\n'" + result + "'")
+	}
+}
+
+func TestBasicConvertByteOrderMark(t *testing.T) {
+	input := xmlutil.ByteOrderMark + basicOvfFileContents
+
+	b, err := basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if strings.HasPrefix(b.String(), xmlutil.ByteOrderMark) || !strings.Contains(b.String(), "vmx-10") {
+		t.Fatal("Got unexpected result:\n" + b.String())
+	}
+
+	b, err = basicConvertWithOptions(strings.NewReader(input), BasicConvertOptions{KeepByteOrderMark: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasPrefix(b.String(), xmlutil.ByteOrderMark+"<?xml") {
+		t.Fatal("Expected the byte order mark to be kept")
+	}
+}
@@ -0,0 +1,109 @@
+package xmlutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ByteOrderMark is the UTF-8 byte order mark, which some Windows tools
+// write at the start of a document.
+const ByteOrderMark = "\xef\xbb\xbf"
+
+// ErrDocumentTooLarge is returned when a document exceeds the maximum
+// allowed size.
+var ErrDocumentTooLarge = errors.New("document exceeds the maximum allowed size")
+
+// NewDecoder returns a *xml.Decoder that is configured for decoding
+// untrusted documents. It should be used instead of xml.NewDecoder.
+//
+// The decoder is strict, and only recognizes the predefined XML entities
+// (e.g., '&amp;') and character references. Entities declared in a
+// DOCTYPE are never expanded, and external entities and DTDs are never
+// resolved. As a result, each reference expands to at most a single
+// character, meaning the decoded size of a document is bounded by its
+// raw size (see ReadAllLimit). Documents must be encoded as UTF-8 (or
+// US-ASCII, which is a subset of UTF-8).
+//
+// The decoder does not reject DOCTYPE declarations by itself. Use
+// ValidateFormatting or CheckDirective to do so.
+func NewDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.Strict = true
+	d.Entity = nil
+	d.CharsetReader = charsetReader
+
+	return d
+}
+
+// Unmarshal is the equivalent of xml.Unmarshal using a decoder created by
+// NewDecoder.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// ReadAllLimit reads all of the data from the io.Reader. An error
+// wrapping ErrDocumentTooLarge is returned if more than maxBytes are
+// available. The size is not limited if maxBytes is less than one.
+func ReadAllLimit(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes < 1 {
+		return io.ReadAll(r)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(raw)) > maxBytes {
+		return nil, fmt.Errorf("%w (%d bytes)", ErrDocumentTooLarge, maxBytes)
+	}
+
+	return raw, nil
+}
+
+// TrimByteOrderMark returns the document without its leading
+// ByteOrderMark, and true if it had one. The encoding/xml package treats
+// the mark as character data outside of the root element.
+func TrimByteOrderMark(raw []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(raw, []byte(ByteOrderMark)) {
+		return raw, false
+	}
+
+	return raw[len(ByteOrderMark):], true
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "us-ascii", "ascii":
+		return input, nil
+	}
+
+	return nil, errors.New("unsupported document encoding '" + charset + "' - only UTF-8 is supported")
+}
+
+// CheckDirective returns a non-nil error if the provided directive is not
+// safe to pass through to other XML parsers. Only a DOCTYPE declaration
+// that names the root element is allowed (e.g., '<!DOCTYPE Envelope>').
+//
+// A DOCTYPE declaration that contains an internal subset (which may
+// declare entities) or an external identifier (SYSTEM or PUBLIC) is
+// rejected. Such declarations can cause a downstream parser to expand
+// entities recursively, or to read local files and URLs (i.e., XML
+// external entity attacks).
+func CheckDirective(directive xml.Directive) error {
+	fields := strings.Fields(string(directive))
+	if len(fields) == 0 || fields[0] != "DOCTYPE" {
+		return errors.New("unsupported directive '<!" + string(directive) + ">'")
+	}
+
+	if len(fields) != 2 || strings.ContainsAny(fields[1], "[\"'") {
+		return errors.New("DOCTYPE declarations containing entities, an internal subset, " +
+			"or an external DTD are not supported")
+	}
+
+	return nil
+}
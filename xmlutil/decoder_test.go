@@ -0,0 +1,121 @@
+package xmlutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckDirective(t *testing.T) {
+	err := CheckDirective([]byte("DOCTYPE Envelope"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, directive := range []string{
+		`DOCTYPE Envelope SYSTEM "file:///etc/passwd"`,
+		`DOCTYPE Envelope PUBLIC "-//Example//DTD//EN" "https://example.com/ovf.dtd"`,
+		`DOCTYPE Envelope [<!ENTITY xxe SYSTEM "file:///etc/passwd">]`,
+		`DOCTYPE Envelope[<!ENTITY a "aaaa">]`,
+		`ENTITY xxe SYSTEM "file:///etc/passwd"`,
+		``,
+	} {
+		err := CheckDirective([]byte(directive))
+		if err == nil {
+			t.Fatal("Expected an error for directive '" + directive + "'")
+		}
+	}
+}
+
+func TestValidateFormattingRejectsExternalEntities(t *testing.T) {
+	document := `<?xml version="1.0"?>
+<!DOCTYPE Envelope [
+  <!ENTITY xxe SYSTEM "file:///etc/passwd">
+]>
+<Envelope>
+  <Info>&xxe;</Info>
+</Envelope>
+`
+
+	err := ValidateFormatting([]byte(document))
+
+	var formattingErr *FormattingError
+	if !errors.As(err, &formattingErr) {
+		t.Fatalf("Expected a *FormattingError, got - %v", err)
+	}
+
+	if formattingErr.Line != 2 {
+		t.Fatal("Got unexpected error location - " + err.Error())
+	}
+
+	err = ValidateFormatting([]byte("<?xml version=\"1.0\"?>\n<!DOCTYPE Envelope>\n<Envelope/>\n"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestNewDecoderEncodings(t *testing.T) {
+	var v struct {
+		Info string `xml:"Info"`
+	}
+
+	err := Unmarshal([]byte(`<?xml version="1.0" encoding="US-ASCII"?><Envelope><Info>a</Info></Envelope>`), &v)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if v.Info != "a" {
+		t.Fatal("Got unexpected value '" + v.Info + "'")
+	}
+
+	err = Unmarshal([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><Envelope/>`), &v)
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported encoding")
+	}
+
+	err = Unmarshal([]byte(`<Envelope><Info>&nbsp;</Info></Envelope>`), &v)
+	if err == nil {
+		t.Fatal("Expected an error for an undeclared entity")
+	}
+}
+
+func TestReadAllLimit(t *testing.T) {
+	raw, err := ReadAllLimit(strings.NewReader("<Envelope/>"), 11)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(raw) != "<Envelope/>" {
+		t.Fatal("Got unexpected data '" + string(raw) + "'")
+	}
+
+	_, err = ReadAllLimit(strings.NewReader("<Envelope/>"), 10)
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("Expected ErrDocumentTooLarge, got - %v", err)
+	}
+}
+
+func TestValidateFormattingRejectsEntityExpansion(t *testing.T) {
+	document := `<?xml version="1.0"?>
+<!DOCTYPE Envelope [
+  <!ENTITY a "aaaaaaaaaa">
+  <!ENTITY b "&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;">
+  <!ENTITY c "&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;">
+]>
+<Envelope>&c;</Envelope>
+`
+
+	err := ValidateFormatting([]byte(document))
+	if err == nil {
+		t.Fatal("Expected an error for a document that declares entities")
+	}
+
+	var v struct {
+		Text string `xml:",chardata"`
+	}
+
+	err = Unmarshal([]byte(document), &v)
+	if err == nil {
+		t.Fatal("Expected the decoder to refuse to expand declared entities")
+	}
+}
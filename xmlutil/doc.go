@@ -0,0 +1,13 @@
+// Package xmlutil provides helpful XML function extensions.
+//
+// The package is geared towards format-preserving edits of XML documents.
+// Rather than unmarshalling and re-marshalling an entire document (which
+// loses comments, indentation, and namespace prefixes), callers scan the
+// document line by line, locate objects of interest using IsStartElement
+// and FindObject, and then rewrite only those objects. Documents that do
+// not place each element on its own line can be prepared for editing
+// using Normalize.
+//
+// The exported API of this package is considered stable. New functionality
+// will be added in a backwards compatible manner.
+package xmlutil
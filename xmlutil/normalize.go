@@ -0,0 +1,244 @@
+package xmlutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+const (
+	normalizedIndent = "  "
+)
+
+// rawToken is a token along with the raw XML that produced it.
+type rawToken struct {
+	token xml.Token
+	raw   []byte
+
+	// offset is the index of the token's raw XML in the document.
+	offset int
+
+	// lineStart is true if only whitespace precedes the token on
+	// its line.
+	lineStart bool
+}
+
+// IsLineOriented returns true if the document can be edited one line at a
+// time, meaning that every element containing other elements starts (and
+// ends) on its own line. Elements that only contain text, such as
+// '<rasd:InstanceID>1</rasd:InstanceID>', may appear on a single line.
+//
+// Documents produced by some exporters (e.g., ovftool) contain every
+// element on one or two lines, and must be normalized using Normalize
+// before they can be edited.
+func IsLineOriented(raw []byte) (bool, error) {
+	tokens, err := rawTokens(raw)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range tokens {
+		switch tokens[i].token.(type) {
+		case xml.StartElement:
+			if !tokens[i].lineStart {
+				return false, nil
+			}
+		case xml.EndElement:
+			if !tokens[i].lineStart && len(tokens[i].raw) > 0 && !closesLeaf(tokens, i) {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// Normalize re-indents a document that is not line-oriented (see
+// IsLineOriented) so that each element starts on its own line. Elements
+// that only contain text are kept on a single line. The document is
+// returned unmodified if it is already line-oriented.
+//
+// Markup that precedes the root element on the same line (e.g., an XML
+// declaration written as '<?xml version="1.0"?><Envelope>') is separated
+// from the root element first, so that a document that is otherwise
+// line-oriented keeps its formatting.
+//
+// The raw XML of each token (e.g., attribute quoting and character
+// escapes) is preserved. Whitespace between elements is replaced.
+func Normalize(raw []byte) ([]byte, error) {
+	raw, err := separateRoot(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	lineOriented, err := IsLineOriented(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if lineOriented {
+		return raw, nil
+	}
+
+	tokens, err := rawTokens(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	eol := []byte("\n")
+	if bytes.Contains(raw, []byte("\r\n")) {
+		eol = []byte("\r\n")
+	}
+
+	buff := bytes.NewBuffer(nil)
+	depth := 0
+
+	writeLine := func(data ...[]byte) {
+		if buff.Len() > 0 {
+			buff.Write(eol)
+		}
+
+		for i := 0; i < depth; i++ {
+			buff.WriteString(normalizedIndent)
+		}
+
+		for _, d := range data {
+			buff.Write(d)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch v := tokens[i].token.(type) {
+		case xml.StartElement:
+			end, isLeaf := leafEnd(tokens, i)
+			if isLeaf {
+				var line [][]byte
+				for j := i; j <= end; j++ {
+					line = append(line, tokens[j].raw)
+				}
+				writeLine(line...)
+				i = end
+				continue
+			}
+
+			writeLine(tokens[i].raw)
+			depth = depth + 1
+		case xml.EndElement:
+			depth = depth - 1
+			writeLine(tokens[i].raw)
+		case xml.CharData:
+			trimmed := bytes.TrimSpace(v)
+			if len(trimmed) > 0 {
+				writeLine(bytes.TrimSpace(tokens[i].raw))
+			}
+		default:
+			writeLine(tokens[i].raw)
+		}
+	}
+
+	if bytes.HasSuffix(raw, []byte("\n")) {
+		buff.Write(eol)
+	}
+
+	return buff.Bytes(), nil
+}
+
+// separateRoot moves the root element's start tag to its own line if it
+// is preceded by other markup on its line (e.g., the XML declaration).
+func separateRoot(raw []byte) ([]byte, error) {
+	tokens, err := rawTokens(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		if _, isStart := token.token.(xml.StartElement); !isStart {
+			continue
+		}
+
+		if token.lineStart {
+			return raw, nil
+		}
+
+		eol := "\n"
+		if bytes.Contains(raw, []byte("\r\n")) {
+			eol = "\r\n"
+		}
+
+		separated := make([]byte, 0, len(raw)+len(eol))
+		separated = append(separated, raw[:token.offset]...)
+		separated = append(separated, eol...)
+
+		return append(separated, raw[token.offset:]...), nil
+	}
+
+	return raw, nil
+}
+
+// leafEnd returns the index of the EndElement that closes the
+// StartElement at index i if the element does not contain other
+// elements, comments, or processing instructions.
+func leafEnd(tokens []rawToken, i int) (int, bool) {
+	for j := i + 1; j < len(tokens); j++ {
+		switch tokens[j].token.(type) {
+		case xml.CharData:
+			continue
+		case xml.EndElement:
+			return j, true
+		}
+
+		return 0, false
+	}
+
+	return 0, false
+}
+
+// closesLeaf returns true if the EndElement at index i closes an element
+// that does not contain other elements.
+func closesLeaf(tokens []rawToken, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch tokens[j].token.(type) {
+		case xml.CharData:
+			continue
+		case xml.StartElement:
+			return true
+		}
+
+		return false
+	}
+
+	return false
+}
+
+// rawTokens returns the tokens of a document along with their raw XML.
+// The EndElement of a self-closing element has no raw XML.
+func rawTokens(raw []byte) ([]rawToken, error) {
+	d := NewDecoder(bytes.NewReader(raw))
+
+	var tokens []rawToken
+
+	for {
+		start := d.InputOffset()
+
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		end := d.InputOffset()
+
+		lineStart := bytes.LastIndexByte(raw[:start], '\n') + 1
+
+		tokens = append(tokens, rawToken{
+			token:     xml.CopyToken(t),
+			raw:       raw[start:end],
+			offset:    int(start),
+			lineStart: len(bytes.TrimLeft(raw[lineStart:start], " \t")) == 0,
+		})
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,103 @@
+package xmlutil
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	singleLine := `<?xml version="1.0"?><Envelope xmlns:rasd="rasd"><!-- Hardware --><Section><Item>` +
+		`<rasd:Caption>Ethernet adapter on &apos;NAT&apos;</rasd:Caption><rasd:InstanceID>1</rasd:InstanceID>` +
+		`<rasd:Connection/></Item></Section></Envelope>` + "\n"
+
+	expected := `<?xml version="1.0"?>
+<Envelope xmlns:rasd="rasd">
+  <!-- Hardware -->
+  <Section>
+    <Item>
+      <rasd:Caption>Ethernet adapter on &apos;NAT&apos;</rasd:Caption>
+      <rasd:InstanceID>1</rasd:InstanceID>
+      <rasd:Connection/>
+    </Item>
+  </Section>
+</Envelope>
+`
+
+	lineOriented, err := IsLineOriented([]byte(singleLine))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if lineOriented {
+		t.Fatal("Expected a single line document to not be line-oriented")
+	}
+
+	result, err := Normalize([]byte(singleLine))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(result) != expected {
+		t.Fatal("Got unexpected result: \n'" + string(result) + "'")
+	}
+
+	lineOriented, err = IsLineOriented(result)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !lineOriented {
+		t.Fatal("Expected a normalized document to be line-oriented")
+	}
+}
+
+func TestNormalizeTwoLines(t *testing.T) {
+	twoLines := "<?xml version=\"1.0\"?>\r\n<Envelope><Section><Item>  text  </Item></Section></Envelope>"
+
+	result, err := Normalize([]byte(twoLines))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "<?xml version=\"1.0\"?>\r\n<Envelope>\r\n  <Section>\r\n    <Item>  text  </Item>\r\n  </Section>\r\n</Envelope>"
+
+	if string(result) != expected {
+		t.Fatal("Got unexpected result: \n'" + string(result) + "'")
+	}
+}
+
+func TestNormalizeLineOriented(t *testing.T) {
+	lineOriented := `<?xml version="1.0"?>
+<Envelope>
+        <Section attr="a"
+                 other="b">
+            <Info>Text</Info>
+        </Section>
+    <Item><!-- Odd, but editable -->
+    </Item>
+</Envelope>
+`
+
+	result, err := Normalize([]byte(lineOriented))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(result) != lineOriented {
+		t.Fatal("Expected a line-oriented document to be unmodified, got: \n'" + string(result) + "'")
+	}
+}
+
+func TestNormalizeRootOnDeclarationLine(t *testing.T) {
+	sameLine := "<?xml version=\"1.0\"?><!-- Exported --><Envelope>\r\n\t<Section>\r\n\t\t<Info>Text</Info>\r\n\t</Section>\r\n</Envelope>\r\n"
+
+	result, err := Normalize([]byte(sameLine))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "<?xml version=\"1.0\"?><!-- Exported -->\r\n<Envelope>\r\n\t<Section>\r\n\t\t<Info>Text</Info>\r\n\t</Section>\r\n</Envelope>\r\n"
+
+	if string(result) != expected {
+		t.Fatal("Got unexpected result: \n'" + string(result) + "'")
+	}
+}
@@ -0,0 +1,443 @@
+package xmlutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+func (o *defaultRawObject) SetChildText(localName string, text string) error {
+	lines := o.lines()
+
+	index, end, start, err := o.findChild(lines, localName)
+	if err != nil {
+		return err
+	}
+
+	if index != end {
+		return errors.New("child element '" + localName + "' spans multiple lines")
+	}
+
+	line := lines[index]
+	prefix := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+	startTag, selfClosing, ok := startTagOf(bytes.TrimSpace(line))
+	if !ok {
+		return errors.New("failed to parse start tag of child element '" + localName + "'")
+	}
+
+	if selfClosing {
+		startTag = append(bytes.TrimRight(startTag[:len(startTag)-2], " \t"), '>')
+	}
+
+	escaped := bytes.NewBuffer(nil)
+	err = xml.EscapeText(escaped, []byte(text))
+	if err != nil {
+		return err
+	}
+
+	newLine := bytes.NewBuffer(nil)
+	newLine.Write(prefix)
+	newLine.Write(startTag)
+	newLine.Write(escaped.Bytes())
+	newLine.WriteString("</" + qualifiedName(start.Name) + ">")
+
+	lines[index] = newLine.Bytes()
+
+	o.setLines(lines)
+
+	return nil
+}
+
+func (o *defaultRawObject) AddChild(name string, text string) error {
+	escaped := bytes.NewBuffer(nil)
+	err := xml.EscapeText(escaped, []byte(text))
+	if err != nil {
+		return err
+	}
+
+	return o.InsertChild([]byte("<" + name + ">" + escaped.String() + "</" + name + ">"))
+}
+
+func (o *defaultRawObject) InsertChild(raw []byte) error {
+	err := ValidateFormatting(raw)
+	if err != nil {
+		return err
+	}
+
+	lines := o.lines()
+	if len(lines) == 1 {
+		expanded, err := o.expandSingleLine(lines[0])
+		if err != nil {
+			return err
+		}
+		lines = expanded
+	}
+
+	prefix := o.BodyPrefix()
+	if o.bodyIndentCount <= o.initialIndentCount {
+		prefix = o.StartAndEndLinePrefix() + strings.Repeat(string(o.indentChar), defaultIndentWidth(o.indentChar))
+	}
+
+	last := len(lines) - 1
+	updated := make([][]byte, 0, len(lines)+1)
+	updated = append(updated, lines[:last]...)
+	for _, line := range bytes.Split(bytes.TrimRight(raw, "\r\n"), []byte{'\n'}) {
+		updated = append(updated, append([]byte(prefix), bytes.TrimRight(line, "\r")...))
+	}
+	updated = append(updated, lines[last])
+
+	o.setLines(updated)
+
+	return nil
+}
+
+func (o *defaultRawObject) DeleteChild(localName string) error {
+	return o.deleteChild(localName, func(xml.StartElement) bool {
+		return true
+	})
+}
+
+func (o *defaultRawObject) DeleteChildWithAttr(localName string, attrLocalName string, attrValue string) error {
+	return o.deleteChild(localName, func(start xml.StartElement) bool {
+		for _, attr := range start.Attr {
+			if attr.Name.Local == attrLocalName && attr.Value == attrValue {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+func (o *defaultRawObject) deleteChild(localName string, match func(xml.StartElement) bool) error {
+	lines := o.lines()
+
+	index, end, _, err := o.findChildMatching(lines, localName, match)
+	if err != nil {
+		return err
+	}
+
+	updated := make([][]byte, 0, len(lines))
+	updated = append(updated, lines[:index]...)
+	updated = append(updated, lines[end+1:]...)
+
+	o.setLines(updated)
+
+	return nil
+}
+
+func (o *defaultRawObject) SetAttr(name string, value string) error {
+	lines := o.lines()
+
+	line := lines[0]
+	trimmed := bytes.TrimLeft(line, " \t")
+	prefix := line[:len(line)-len(trimmed)]
+
+	startTag, selfClosing, ok := startTagOf(trimmed)
+	if !ok {
+		return errors.New("failed to parse start tag of object")
+	}
+
+	escaped := bytes.NewBuffer(nil)
+	err := xml.EscapeText(escaped, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	newTag := bytes.NewBuffer(nil)
+	valueStart, valueEnd, found := attrValueBounds(startTag, name)
+	if found {
+		newTag.Write(startTag[:valueStart])
+		newTag.Write(escaped.Bytes())
+		newTag.Write(startTag[valueEnd:])
+	} else {
+		end := len(startTag) - 1
+		if selfClosing {
+			end = end - 1
+		}
+		body := bytes.TrimRight(startTag[:end], " \t")
+		newTag.Write(body)
+		newTag.WriteString(" " + name + "=\"" + escaped.String() + "\"")
+		newTag.Write(startTag[end:])
+	}
+
+	newLine := bytes.NewBuffer(nil)
+	newLine.Write(prefix)
+	newLine.Write(newTag.Bytes())
+	newLine.Write(trimmed[len(startTag):])
+
+	lines[0] = newLine.Bytes()
+
+	o.setLines(lines)
+
+	return nil
+}
+
+func (o *defaultRawObject) RemoveAttr(name string) error {
+	lines := o.lines()
+
+	line := lines[0]
+	trimmed := bytes.TrimLeft(line, " \t")
+	prefix := line[:len(line)-len(trimmed)]
+
+	startTag, _, ok := startTagOf(trimmed)
+	if !ok {
+		return errors.New("failed to parse start tag of object")
+	}
+
+	nameStart, _, valueEnd, found := attrBounds(startTag, name)
+	if !found {
+		return nil
+	}
+
+	// Remove the whitespace preceding the attribute along with it.
+	removeFrom := nameStart
+	for removeFrom > 0 && isXmlSpace(startTag[removeFrom-1]) {
+		removeFrom = removeFrom - 1
+	}
+
+	newLine := bytes.NewBuffer(nil)
+	newLine.Write(prefix)
+	newLine.Write(startTag[:removeFrom])
+	newLine.Write(startTag[valueEnd+1:])
+	newLine.Write(trimmed[len(startTag):])
+
+	lines[0] = newLine.Bytes()
+
+	o.setLines(lines)
+
+	return nil
+}
+
+// attrValueBounds returns the start and end indexes of the specified
+// attribute's value within a raw start tag (excluding the quotes).
+func attrValueBounds(startTag []byte, name string) (int, int, bool) {
+	_, valueStart, valueEnd, found := attrBounds(startTag, name)
+
+	return valueStart, valueEnd, found
+}
+
+// attrBounds returns the index of the specified attribute's name, and
+// the start and end indexes of its value (excluding the quotes), within
+// a raw start tag.
+func attrBounds(startTag []byte, name string) (int, int, int, bool) {
+	i := 1
+
+	// Skip the element's name.
+	for i < len(startTag) && !isXmlSpace(startTag[i]) && startTag[i] != '>' && startTag[i] != '/' {
+		i = i + 1
+	}
+
+	for i < len(startTag) {
+		for i < len(startTag) && isXmlSpace(startTag[i]) {
+			i = i + 1
+		}
+
+		nameStart := i
+		for i < len(startTag) && startTag[i] != '=' && !isXmlSpace(startTag[i]) && startTag[i] != '>' && startTag[i] != '/' {
+			i = i + 1
+		}
+		attrName := string(startTag[nameStart:i])
+
+		for i < len(startTag) && (isXmlSpace(startTag[i]) || startTag[i] == '=') {
+			i = i + 1
+		}
+
+		if i >= len(startTag) || (startTag[i] != '"' && startTag[i] != '\'') {
+			return 0, 0, 0, false
+		}
+
+		quote := startTag[i]
+		valueStart := i + 1
+		valueEnd := bytes.IndexByte(startTag[valueStart:], quote)
+		if valueEnd < 0 {
+			return 0, 0, 0, false
+		}
+		valueEnd = valueStart + valueEnd
+
+		if attrName == name {
+			return nameStart, valueStart, valueEnd, true
+		}
+
+		i = valueEnd + 1
+	}
+
+	return 0, 0, 0, false
+}
+
+func isXmlSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// expandSingleLine splits an empty object written on a single line
+// (e.g., '<System/>' or '<Item></Item>') into a start and end line.
+func (o *defaultRawObject) expandSingleLine(line []byte) ([][]byte, error) {
+	start, _ := lineDepthChange(line)
+	startTag, selfClosing, ok := startTagOf(bytes.TrimSpace(line))
+	if start == nil || !ok {
+		return nil, errors.New("failed to parse start tag of single line object")
+	}
+
+	endTag := "</" + qualifiedName(start.Name) + ">"
+
+	if selfClosing {
+		startTag = append(bytes.TrimRight(startTag[:len(startTag)-2], " \t"), '>')
+	} else if string(bytes.TrimSpace(line)) != string(startTag)+endTag {
+		return nil, errors.New("cannot add a child to a single line object that contains data")
+	}
+
+	prefix := o.StartAndEndLinePrefix()
+
+	return [][]byte{
+		append([]byte(prefix), startTag...),
+		[]byte(prefix + endTag),
+	}, nil
+}
+
+// findChild returns the first and last line indexes of the first direct
+// child element with the specified local name.
+func (o *defaultRawObject) findChild(lines [][]byte, localName string) (int, int, *xml.StartElement, error) {
+	return o.findChildMatching(lines, localName, func(xml.StartElement) bool {
+		return true
+	})
+}
+
+// findChildMatching is the same as findChild, but additionally requires
+// that the child's start element satisfies the provided match func.
+func (o *defaultRawObject) findChildMatching(lines [][]byte, localName string, match func(xml.StartElement) bool) (int, int, *xml.StartElement, error) {
+	depth := 0
+
+	for i := 1; i < len(lines)-1; i++ {
+		start, change := lineDepthChange(lines[i])
+
+		if depth == 0 && start != nil && start.Name.Local == localName && match(*start) {
+			end := i
+			for childDepth := change; childDepth > 0 && end < len(lines)-2; {
+				end = end + 1
+				_, c := lineDepthChange(lines[end])
+				childDepth = childDepth + c
+			}
+
+			return i, end, start, nil
+		}
+
+		depth = depth + change
+	}
+
+	return 0, 0, nil, errors.New("failed to find child element '" + localName + "'")
+}
+
+func (o *defaultRawObject) lines() [][]byte {
+	eol := o.eol
+	if len(eol) == 0 {
+		eol = []byte{'\n'}
+	}
+
+	var lines [][]byte
+	for _, line := range bytes.Split(o.data.Bytes(), eol) {
+		lines = append(lines, append([]byte(nil), line...))
+	}
+
+	return lines
+}
+
+func (o *defaultRawObject) setLines(lines [][]byte) {
+	eol := o.eol
+	if len(eol) == 0 {
+		eol = []byte{'\n'}
+	}
+
+	o.data.Reset()
+	o.data.Write(bytes.Join(lines, eol))
+}
+
+// lineDepthChange returns the first xml.StartElement found on the line
+// (if any) and the difference between the number of start and end
+// elements on the line.
+func lineDepthChange(line []byte) (*xml.StartElement, int) {
+	d := NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
+
+	var first *xml.StartElement
+	change := 0
+
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			return first, change
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			if first == nil {
+				start := v.Copy()
+				first = &start
+			}
+			change = change + 1
+		case xml.EndElement:
+			change = change - 1
+		}
+	}
+}
+
+// nameDepthChange returns the difference between the number of start
+// and end elements on the line that have the specified local name.
+// Self-closing elements do not change the depth.
+func nameDepthChange(line []byte, localName string) int {
+	d := NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
+
+	change := 0
+
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			return change
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			if v.Name.Local == localName {
+				change = change + 1
+			}
+		case xml.EndElement:
+			if v.Name.Local == localName {
+				change = change - 1
+			}
+		}
+	}
+}
+
+// startTagOf returns the raw start tag at the beginning of the line,
+// and whether the tag is self-closing.
+func startTagOf(line []byte) ([]byte, bool, bool) {
+	if len(line) == 0 || line[0] != '<' {
+		return nil, false, false
+	}
+
+	var quote byte
+
+	for i := 1; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			tag := append([]byte(nil), line[:i+1]...)
+			return tag, i > 0 && line[i-1] == '/', true
+		}
+	}
+
+	return nil, false, false
+}
+
+func qualifiedName(name xml.Name) string {
+	if len(name.Space) > 0 {
+		return name.Space + ":" + name.Local
+	}
+
+	return name.Local
+}
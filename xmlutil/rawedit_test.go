@@ -0,0 +1,263 @@
+package xmlutil
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+const (
+	rawEditDocument = `<VirtualHardwareSection>
+    <Info>Virtual hardware requirements for a virtual machine</Info>
+    <Item>
+        <rasd:Caption>sataController0</rasd:Caption>
+        <rasd:Description>SATA Controller</rasd:Description>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <vbox:Extra>
+            <rasd:Caption>nested</rasd:Caption>
+        </vbox:Extra>
+        <rasd:ResourceSubType/>
+    </Item>
+</VirtualHardwareSection>
+`
+)
+
+func findTestRawObject(t *testing.T, document string, name string) EditableRawObject {
+	scanner := bufio.NewScanner(strings.NewReader(document))
+
+	for scanner.Scan() {
+		start, isStart := IsStartElement(scanner.Bytes())
+		if isStart && start.Name.Local == name {
+			config, err := NewFindObjectConfig(start, scanner, testEol)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			rawObject, err := FindObject(config)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			editable, ok := rawObject.(EditableRawObject)
+			if !ok {
+				t.Fatal("FindObject did not return an EditableRawObject")
+			}
+
+			return editable
+		}
+	}
+
+	t.Fatal("Could not find target object '" + name + "'")
+
+	return nil
+}
+
+func TestRawObjectSetChildText(t *testing.T) {
+	rawObject := findTestRawObject(t, rawEditDocument, "Item")
+
+	err := rawObject.SetChildText("Caption", "SATA <Controller>")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rawObject.SetChildText("ResourceSubType", "vmware.sata.ahci")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `    <Item>
+        <rasd:Caption>SATA &lt;Controller&gt;</rasd:Caption>
+        <rasd:Description>SATA Controller</rasd:Description>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <vbox:Extra>
+            <rasd:Caption>nested</rasd:Caption>
+        </vbox:Extra>
+        <rasd:ResourceSubType>vmware.sata.ahci</rasd:ResourceSubType>
+    </Item>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	err = rawObject.SetChildText("Extra", "junk")
+	if err == nil {
+		t.Fatal("Expected an error when setting the text of a multi-line child")
+	}
+
+	err = rawObject.SetChildText("Missing", "junk")
+	if err == nil {
+		t.Fatal("Expected an error when setting the text of a missing child")
+	}
+}
+
+func TestRawObjectAddChild(t *testing.T) {
+	rawObject := findTestRawObject(t, rawEditDocument, "Item")
+
+	err := rawObject.AddChild("rasd:InstanceID", "5")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `    <Item>
+        <rasd:Caption>sataController0</rasd:Caption>
+        <rasd:Description>SATA Controller</rasd:Description>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <vbox:Extra>
+            <rasd:Caption>nested</rasd:Caption>
+        </vbox:Extra>
+        <rasd:ResourceSubType/>
+        <rasd:InstanceID>5</rasd:InstanceID>
+    </Item>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
+
+func TestRawObjectDeleteChild(t *testing.T) {
+	rawObject := findTestRawObject(t, rawEditDocument, "Item")
+
+	err := rawObject.DeleteChild("Extra")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rawObject.DeleteChild("Description")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `    <Item>
+        <rasd:Caption>sataController0</rasd:Caption>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <rasd:ResourceSubType/>
+    </Item>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	err = rawObject.DeleteChild("Extra")
+	if err == nil {
+		t.Fatal("Expected an error when deleting a missing child")
+	}
+}
+
+func TestRawObjectAddChildSelfClosing(t *testing.T) {
+	rawObject := findTestRawObject(t, "<Section>\n    <System/>\n</Section>\n", "System")
+
+	err := rawObject.AddChild("vssd:InstanceID", "0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `    <System>
+      <vssd:InstanceID>0</vssd:InstanceID>
+    </System>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
+
+func TestRawObjectSetAttr(t *testing.T) {
+	document := `<StorageControllers>
+  <StorageController name="SATA Controller" type="AHCI" PortCount="1" note='a > b'>
+    <AttachedDevice type="HardDisk" port="0" device="0"/>
+  </StorageController>
+  <StorageController name="IDE Controller" type="PIIX4"/>
+</StorageControllers>
+`
+
+	rawObject := findTestRawObject(t, document, "StorageController")
+
+	err := rawObject.SetAttr("PortCount", "2")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rawObject.SetAttr("Bootable", "true")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `  <StorageController name="SATA Controller" type="AHCI" PortCount="2" note='a > b' Bootable="true">
+    <AttachedDevice type="HardDisk" port="0" device="0"/>
+  </StorageController>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	ide := findTestRawObject(t, "<StorageControllers>\n  <StorageController name=\"IDE Controller\"/>\n</StorageControllers>\n", "StorageController")
+
+	err = ide.SetAttr("PortCount", "2")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if ide.Data().String() != `  <StorageController name="IDE Controller" PortCount="2"/>` {
+		t.Fatal("Got unexpected result: \n'" + ide.Data().String() + "'")
+	}
+}
+
+func TestRawObjectRemoveAttr(t *testing.T) {
+	document := `<VirtualSystem>
+  <vbox:Machine name="test" currentSnapshot="{1}"  OSType="RedHat_64">
+    <Snapshot uuid="{1}"/>
+  </vbox:Machine>
+</VirtualSystem>
+`
+
+	rawObject := findTestRawObject(t, document, "Machine")
+
+	err := rawObject.RemoveAttr("currentSnapshot")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rawObject.RemoveAttr("junk")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `  <vbox:Machine name="test"  OSType="RedHat_64">
+    <Snapshot uuid="{1}"/>
+  </vbox:Machine>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
+
+func TestRawObjectInsertChild(t *testing.T) {
+	rawObject := findTestRawObject(t, rawEditDocument, "VirtualHardwareSection")
+
+	err := rawObject.InsertChild([]byte(`<vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rawObject.DeleteChildWithAttr("Config", "key", "junk")
+	if err == nil {
+		t.Fatal("Expected an error when deleting a child with a non-matching attribute")
+	}
+
+	if !strings.HasSuffix(rawObject.Data().String(), "    </Item>\n    <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"efi\"/>\n</VirtualHardwareSection>") {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	err = rawObject.DeleteChildWithAttr("Config", "key", "firmware")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if rawObject.Data().String() != strings.TrimSuffix(rawEditDocument, "\n") {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	err = rawObject.InsertChild([]byte("<unclosed>"))
+	if err == nil {
+		t.Fatal("Expected an error when inserting malformed data")
+	}
+}
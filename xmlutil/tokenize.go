@@ -0,0 +1,76 @@
+package xmlutil
+
+import (
+	"bytes"
+)
+
+// LineTracker tracks XML markup that may span multiple lines, such as
+// comments, CDATA sections, processing instructions, and start tags
+// whose attributes are split across lines.
+//
+// Line-oriented helpers like IsStartElement only see a single line at
+// a time. A line such as '<Item>' that appears inside a multi-line
+// comment would otherwise be misdetected as a start element.
+type LineTracker struct {
+	terminator []byte
+	quote      byte
+}
+
+// Next updates the tracker's state using the provided line. It returns
+// true if the line begins inside markup that was opened on a previous
+// line, meaning that the line should be treated as literal data.
+func (o *LineTracker) Next(line []byte) bool {
+	literal := o.InMarkup()
+
+	for i := 0; i < len(line); {
+		switch {
+		case o.quote != 0:
+			if line[i] == o.quote {
+				o.quote = 0
+			}
+			i = i + 1
+		case len(o.terminator) == 1 && o.terminator[0] == '>':
+			// Inside of a tag. Attribute values may contain '>'.
+			switch line[i] {
+			case '"', '\'':
+				o.quote = line[i]
+			case '>':
+				o.terminator = nil
+			}
+			i = i + 1
+		case len(o.terminator) > 0:
+			index := bytes.Index(line[i:], o.terminator)
+			if index < 0 {
+				return literal
+			}
+			i = i + index + len(o.terminator)
+			o.terminator = nil
+		case line[i] == '<':
+			rest := line[i:]
+			switch {
+			case bytes.HasPrefix(rest, []byte("<!--")):
+				o.terminator = []byte("-->")
+				i = i + len("<!--")
+			case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+				o.terminator = []byte("]]>")
+				i = i + len("<![CDATA[")
+			case bytes.HasPrefix(rest, []byte("<?")):
+				o.terminator = []byte("?>")
+				i = i + len("<?")
+			default:
+				o.terminator = []byte{'>'}
+				i = i + 1
+			}
+		default:
+			i = i + 1
+		}
+	}
+
+	return literal
+}
+
+// InMarkup returns true if the tracker is currently inside of markup
+// that has not been terminated.
+func (o *LineTracker) InMarkup() bool {
+	return len(o.terminator) > 0 || o.quote != 0
+}
@@ -0,0 +1,72 @@
+package xmlutil
+
+import (
+	"testing"
+)
+
+func TestLineTracker(t *testing.T) {
+	lines := []struct {
+		line    string
+		literal bool
+	}{
+		{line: `<Envelope>`, literal: false},
+		{line: `  <!-- Start of a comment`, literal: false},
+		{line: `  <Item>`, literal: true},
+		{line: `  end of comment -->`, literal: true},
+		{line: `  <Item note="a > b">`, literal: false},
+		{line: `    <![CDATA[ some`, literal: false},
+		{line: `    </Item> data ]]>`, literal: true},
+		{line: `  </Item>`, literal: false},
+		{line: `  <Item name="split`, literal: false},
+		{line: `    across > lines">`, literal: true},
+		{line: `  </Item>`, literal: false},
+		{line: `</Envelope>`, literal: false},
+	}
+
+	tracker := &LineTracker{}
+
+	for i, l := range lines {
+		literal := tracker.Next([]byte(l.line))
+		if literal != l.literal {
+			t.Fatalf("line %d ('%s') - expected literal to be %t", i, l.line, l.literal)
+		}
+	}
+
+	if tracker.InMarkup() {
+		t.Fatal("Tracker should not be in markup at the end of the document")
+	}
+}
+
+func TestFindObjectCommentsAndCData(t *testing.T) {
+	junk := `<VirtualHardwareSection>
+    <Item note="a > b &lt;c&gt;">
+        <!-- The following is commented out:
+        </Item>
+        -->
+        <Description><![CDATA[
+        </Item>
+        ]]></Description>
+        <InstanceID>1</InstanceID>
+    </Item>
+    <Item>
+        <InstanceID>2</InstanceID>
+    </Item>
+</VirtualHardwareSection>
+`
+
+	rawObject := findTestRawObject(t, junk, "Item")
+
+	expected := `    <Item note="a > b &lt;c&gt;">
+        <!-- The following is commented out:
+        </Item>
+        -->
+        <Description><![CDATA[
+        </Item>
+        ]]></Description>
+        <InstanceID>1</InstanceID>
+    </Item>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"encoding/xml"
 	"errors"
+	"io"
+	"strconv"
 	"strings"
 )
 
@@ -66,8 +68,54 @@ type RawObject interface {
 	RelativeBodyPrefix() string
 }
 
+// EditableRawObject is a RawObject that can be modified in place. It is
+// separate from RawObject so that existing implementations of RawObject
+// are not broken. The RawObject returned by FindObject implements it.
+type EditableRawObject interface {
+	RawObject
+
+	// SetChildText replaces the character data of the first direct
+	// child element with the specified local name. The child must
+	// be written on a single line. A non-nil error is returned if
+	// no such child exists.
+	SetChildText(localName string, text string) error
+
+	// AddChild appends a new child element containing the specified
+	// character data to the end of the object's body. The name may
+	// include a namespace prefix (e.g., 'rasd:Caption').
+	AddChild(name string, text string) error
+
+	// DeleteChild removes the first direct child element with the
+	// specified local name, including any of its descendants.
+	// A non-nil error is returned if no such child exists.
+	DeleteChild(localName string) error
+
+	// SetAttr sets the value of an attribute on the object's start
+	// element, adding the attribute if it does not exist. The name
+	// may include a namespace prefix (e.g., 'ovf:required').
+	SetAttr(name string, value string) error
+
+	// InsertChild appends raw XML data (e.g., '<vmw:Config ... />')
+	// to the end of the object's body. Each line of the data is
+	// prefixed with the object's body prefix.
+	InsertChild(raw []byte) error
+
+	// DeleteChildWithAttr removes the first direct child element with
+	// the specified local name that has an attribute with the specified
+	// local name and value. A non-nil error is returned if no such
+	// child exists.
+	DeleteChildWithAttr(localName string, attrLocalName string, attrValue string) error
+
+	// RemoveAttr removes an attribute from the object's start element.
+	// The name must match the attribute as it is written, including
+	// any namespace prefix. Nothing happens if the attribute does not
+	// exist.
+	RemoveAttr(name string) error
+}
+
 type defaultRawObject struct {
 	data               *bytes.Buffer
+	eol                []byte
 	initialIndentCount int
 	bodyIndentCount    int
 	indentChar         rune
@@ -95,23 +143,130 @@ func (o defaultRawObject) RelativeBodyPrefix() string {
 	return strings.Repeat(string(o.indentChar), difference)
 }
 
+// FormattingError describes the first formatting error found in an XML
+// document, along with its location.
+type FormattingError struct {
+	// Line is the 1-based line number of the error.
+	Line int
+
+	// Column is the 1-based column number of the error.
+	Column int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (o *FormattingError) Error() string {
+	return "xml formatting error at line " + strconv.Itoa(o.Line) +
+		", column " + strconv.Itoa(o.Column) + " - " + o.Err.Error()
+}
+
+func (o *FormattingError) Unwrap() error {
+	return o.Err
+}
+
 // ValidateFormatting returns a non-nil error if the provided slice of bytes
-// is not a valid XML document.
+// is not a valid XML document, or if it contains a directive that is
+// rejected by CheckDirective. A leading ByteOrderMark is ignored. The error is a *FormattingError that
+// reports the location of the first problem.
 func ValidateFormatting(raw []byte) error {
-	var temp struct{}
+	return ValidateFormattingReader(bytes.NewReader(raw))
+}
 
-	err := xml.Unmarshal(raw, &temp)
-	if err != nil {
-		return err
+// ValidateFormattingReader is the streaming variant of ValidateFormatting.
+// The document is checked token by token, meaning it is never fully
+// loaded into memory.
+func ValidateFormattingReader(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	mark, err := br.Peek(len(ByteOrderMark))
+	if err == nil && string(mark) == ByteOrderMark {
+		_, _ = br.Discard(len(ByteOrderMark))
+	}
+
+	d := NewDecoder(br)
+
+	depth := 0
+	roots := 0
+
+	for {
+		line, column := d.InputPos()
+
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, column = d.InputPos()
+			return &FormattingError{
+				Line:   line,
+				Column: column,
+				Err:    err,
+			}
+		}
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots = roots + 1
+				if roots > 1 {
+					return &FormattingError{
+						Line:   line,
+						Column: column,
+						Err:    errors.New("found more than one root element ('" + v.Name.Local + "')"),
+					}
+				}
+			}
+			depth = depth + 1
+		case xml.EndElement:
+			depth = depth - 1
+		case xml.Directive:
+			err := CheckDirective(v)
+			if err != nil {
+				return &FormattingError{
+					Line:   line,
+					Column: column,
+					Err:    err,
+				}
+			}
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(v)) > 0 {
+				leading := v[:len(v)-len(bytes.TrimLeft(v, " \t\r\n"))]
+				if newLines := bytes.Count(leading, []byte{'\n'}); newLines > 0 {
+					line = line + newLines
+					column = len(leading) - bytes.LastIndexByte(leading, '\n')
+				} else {
+					column = column + len(leading)
+				}
+
+				return &FormattingError{
+					Line:   line,
+					Column: column,
+					Err:    errors.New("found character data outside of the root element"),
+				}
+			}
+		}
+	}
+
+	if roots == 0 {
+		line, column := d.InputPos()
+		return &FormattingError{
+			Line:   line,
+			Column: column,
+			Err:    errors.New("document does not contain a root element"),
+		}
 	}
 
 	return nil
 }
 
 // IsStartElement returns true and a pointer to the xml.StartElement if the
-// provided line is a valid XML start element.
+// provided line is a valid XML start element. A leading ByteOrderMark is
+// ignored.
 func IsStartElement(line []byte) (*xml.StartElement, bool) {
-	d := xml.NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
+	line, _ = TrimByteOrderMark(line)
+
+	d := NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
 
 	// TODO: Use xml.Decoder.Token() instead of RawToken().
 	t, err := d.RawToken()
@@ -158,7 +313,7 @@ func FindAndDeserializeObject(config FindObjectConfig, pointer interface{}) (Raw
 		return rawObject, err
 	}
 
-	err = xml.Unmarshal(rawObject.Data().Bytes(), pointer)
+	err = Unmarshal(rawObject.Data().Bytes(), pointer)
 	if err != nil {
 		return rawObject, err
 	}
@@ -174,11 +329,29 @@ func FindObject(config FindObjectConfig) (RawObject, error) {
 	indentChar, count := lineIndentInfo(firstLine)
 	rawObject := &defaultRawObject{
 		data:               bytes.NewBuffer(nil),
+		eol:                config.Eol(),
 		initialIndentCount: count,
 		indentChar:         indentChar,
 	}
 
 	rawObject.data.Write(firstLine)
+
+	tracker := &LineTracker{}
+	tracker.Next(firstLine)
+
+	// Objects such as '<System/>' or '<Item></Item>' begin and end
+	// on the same line. There is no body to search for.
+	if _, change := lineDepthChange(firstLine); change <= 0 {
+		rawObject.bodyIndentCount = count + defaultIndentWidth(indentChar)
+
+		err := ValidateFormatting(rawObject.data.Bytes())
+		if err != nil {
+			return rawObject, err
+		}
+
+		return rawObject, nil
+	}
+
 	rawObject.data.Write(config.Eol())
 
 	checkedBodyIntent := false
@@ -198,17 +371,10 @@ func FindObject(config FindObjectConfig) (RawObject, error) {
 		// TODO: Need to verify that the tokens match using
 		//  URL / namespace in addition to the token name.
 		//  This will require a fair amount of reworking.
-		if start, isStart := IsStartElement(line); isStart {
-			if start.Name.Local == config.Start().Name.Local {
-				requireEndCount = requireEndCount + 1
-			}
-		} else if end, isEnd := IsEndElement(line); isEnd {
-			if end.Name.Local == config.Start().Name.Local {
-				if requireEndCount <= 1 {
-					break
-				} else {
-					requireEndCount = requireEndCount - 1
-				}
+		if !tracker.Next(line) {
+			requireEndCount = requireEndCount + nameDepthChange(line, config.Start().Name.Local)
+			if requireEndCount <= 0 {
+				break
 			}
 		}
 
@@ -234,6 +400,9 @@ func lineIndentInfo(line []byte) (indentChar rune, count int) {
 	}
 
 	indentChar = rune(line[0])
+	if indentChar != ' ' && indentChar != '\t' {
+		return ' ', 0
+	}
 
 	indents := 0
 
@@ -248,10 +417,18 @@ func lineIndentInfo(line []byte) (indentChar rune, count int) {
 	return indentChar, indents
 }
 
+func defaultIndentWidth(indentChar rune) int {
+	if indentChar == '\t' {
+		return 1
+	}
+
+	return 2
+}
+
 // IsEndElement returns true and a pointer to the xml.EndElement if the
 // provided line is a valid XML end element.
 func IsEndElement(line []byte) (*xml.EndElement, bool) {
-	d := xml.NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
+	d := NewDecoder(bytes.NewReader(bytes.TrimSpace(line)))
 
 	// TODO: Use xml.Decoder.Token() instead of RawToken().
 	t, err := d.RawToken()
@@ -246,3 +246,121 @@ func TestFindAndDeserializeObject(t *testing.T) {
 
 	t.Fatal("Could not find target object")
 }
+
+func TestFindObjectSelfClosing(t *testing.T) {
+	junk := `<VirtualHardwareSection>
+    <Info>Virtual hardware requirements for a virtual machine</Info>
+    <System/>
+    <Item>
+        <InstanceID>1</InstanceID>
+    </Item>
+</VirtualHardwareSection>
+`
+
+	rawObject := findTestRawObject(t, junk, "System")
+
+	if rawObject.Data().String() != "    <System/>" {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+
+	if rawObject.RelativeBodyPrefix() != "  " {
+		t.Fatal("Got unexpected relative body prefix of '" + rawObject.RelativeBodyPrefix() + "'")
+	}
+}
+
+func TestFindObjectEmptyOnOneLine(t *testing.T) {
+	junk := `<VirtualHardwareSection>
+    <Item></Item>
+    <Item>
+        <InstanceID>1</InstanceID>
+    </Item>
+</VirtualHardwareSection>
+`
+
+	rawObject := findTestRawObject(t, junk, "Item")
+
+	if rawObject.Data().String() != "    <Item></Item>" {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
+
+func TestFindObjectNestedSelfClosing(t *testing.T) {
+	junk := `<VirtualHardwareSection>
+    <Item>
+        <Item/>
+        <Item></Item>
+        <InstanceID>1</InstanceID>
+    </Item>
+    <Item>
+        <InstanceID>2</InstanceID>
+    </Item>
+</VirtualHardwareSection>
+`
+
+	rawObject := findTestRawObject(t, junk, "Item")
+
+	expected := `    <Item>
+        <Item/>
+        <Item></Item>
+        <InstanceID>1</InstanceID>
+    </Item>`
+
+	if rawObject.Data().String() != expected {
+		t.Fatal("Got unexpected result: \n'" + rawObject.Data().String() + "'")
+	}
+}
+
+func TestValidateFormatting(t *testing.T) {
+	valid := `<?xml version="1.0"?>
+<!-- A comment -->
+<Envelope>
+    <System/>
+</Envelope>
+`
+
+	err := ValidateFormatting([]byte(valid))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestValidateFormattingByteOrderMark(t *testing.T) {
+	err := ValidateFormatting([]byte(ByteOrderMark + "<?xml version=\"1.0\"?>\n<Envelope/>\n"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, ok := IsStartElement([]byte(ByteOrderMark + "<Envelope>"))
+	if !ok {
+		t.Fatal("Expected a start element after the byte order mark")
+	}
+}
+
+func TestValidateFormattingErrorLocation(t *testing.T) {
+	tests := []struct {
+		document string
+		line     int
+	}{
+		{document: "<Envelope>\n    <System>\n    </Item>\n</Envelope>\n", line: 3},
+		{document: "<Envelope>\n    <System>\n", line: 3},
+		{document: "<Envelope>\n</Envelope>\n<Envelope/>\n", line: 3},
+		{document: "<Envelope>\n</Envelope>\njunk\n", line: 3},
+		{document: "\n\n", line: 3},
+	}
+
+	for _, test := range tests {
+		err := ValidateFormatting([]byte(test.document))
+		if err == nil {
+			t.Fatal("Expected an error for document:\n" + test.document)
+		}
+
+		formattingErr, ok := err.(*FormattingError)
+		if !ok {
+			t.Fatalf("Got unexpected error type %T", err)
+		}
+
+		if formattingErr.Line != test.line {
+			t.Fatalf("Expected error on line %d, got - %s", test.line, err.Error())
+		}
+	}
+}